@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"llamactl/pkg/backends"
 	"os/exec"
@@ -30,9 +31,12 @@ var utilityFlags = map[string]bool{
 }
 
 func main() {
+	bin := flag.String("bin", "llama-server", "llama-server binary to check, e.g. a build pinned to a specific llama.cpp release")
+	flag.Parse()
+
 	// Run llama-server --help to get all flags
-	fmt.Println("Running llama-server --help to extract flags...")
-	helpOutput, err := runLlamaServerHelp()
+	fmt.Printf("Running %s --help to extract flags...\n", *bin)
+	helpOutput, err := runLlamaServerHelp(*bin)
 	if err != nil {
 		fmt.Printf("Error running llama-server --help: %v\n", err)
 		return
@@ -104,8 +108,8 @@ func main() {
 	fmt.Printf("Conflicts: %d\n", len(conflicts))
 }
 
-func runLlamaServerHelp() (string, error) {
-	cmd := exec.Command("llama-server", "--help")
+func runLlamaServerHelp(bin string) (string, error) {
+	cmd := exec.Command(bin, "--help")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		// llama-server --help may return non-zero exit code, but we still get output
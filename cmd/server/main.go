@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"fmt"
+	"llamactl/pkg/auth"
+	"llamactl/pkg/backends"
 	"llamactl/pkg/config"
 	"llamactl/pkg/database"
 	"llamactl/pkg/manager"
@@ -41,9 +43,23 @@ func main() {
 	}
 
 	configPath := os.Getenv("LLAMACTL_CONFIG_PATH")
+
+	// --config-check flag to validate the configuration and exit
+	if len(os.Args) > 1 && os.Args[1] == "--config-check" {
+		os.Exit(checkConfig(configPath))
+	}
+
+	// migrate subcommand to move JSON instance files into the database
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(configPath, os.Args[2:]))
+	}
+
 	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
-		log.Printf("Error loading config: %v\nUsing default configuration.", err)
+		log.Fatalf("Error loading config: %v", err)
+	}
+	for _, warning := range cfg.Warnings() {
+		log.Printf("Warning: %s", warning)
 	}
 
 	// Set version information
@@ -66,7 +82,9 @@ func main() {
 
 	// Initialize database
 	db, err := database.Open(&database.Config{
+		Driver:             cfg.Database.Driver,
 		Path:               cfg.Database.Path,
+		DSN:                cfg.Database.DSN,
 		MaxOpenConnections: cfg.Database.MaxOpenConnections,
 		MaxIdleConnections: cfg.Database.MaxIdleConnections,
 		ConnMaxLifetime:    cfg.Database.ConnMaxLifetime,
@@ -80,14 +98,42 @@ func main() {
 		log.Fatalf("Failed to run database migrations: %v", err)
 	}
 
+	// Merge read-only keys from the keys file into the auth store
+	var authStore database.AuthStore = db
+	if cfg.Auth.KeysFile != "" {
+		fileKeys, err := auth.LoadKeysFile(cfg.Auth.KeysFile)
+		if err != nil {
+			log.Fatalf("Failed to load keys file: %v", err)
+		}
+		authStore = database.WithFileKeys(db, fileKeys)
+		log.Printf("Loaded %d API keys from %s", len(fileKeys), cfg.Auth.KeysFile)
+	}
+
 	// Initialize the instance manager with dependency injection
 	instanceManager := manager.New(&cfg, db)
 
+	// Bring instances in line with the declarative directory, again on SIGHUP
+	if cfg.Instances.DeclarativeDir != "" {
+		reconcileDeclarative(instanceManager)
+
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				log.Printf("Received SIGHUP, reconciling instances with %s", cfg.Instances.DeclarativeDir)
+				reconcileDeclarative(instanceManager)
+			}
+		}()
+	}
+
+	// Find missing backends now rather than at the first instance start
+	go logBackendChecks(&cfg.Backends)
+
 	// Initialize model manager
 	modelManager := models.NewManager(cfg.Backends.LlamaCpp.CacheDir, cfg.Backends.LlamaCpp.DownloadTimeout, cfg.Version)
 
 	// Create a new handler with the instance manager
-	handler := server.NewHandler(instanceManager, modelManager, cfg, db)
+	handler := server.NewHandler(instanceManager, modelManager, cfg, authStore)
 
 	// Setup the router with the handler
 	r := server.SetupRouter(handler)
@@ -112,6 +158,17 @@ func main() {
 	<-stop
 	fmt.Println("Shutting down server...")
 
+	// Keep serving in-flight requests but turn new proxy requests away, so
+	// upstream load balancers can deregister this server first
+	if drain := cfg.Server.ShutdownDrainPeriod; drain > 0 {
+		handler.BeginShutdown()
+		fmt.Printf("Draining for %s, send another signal to skip...\n", drain)
+		select {
+		case <-time.After(drain):
+		case <-stop:
+		}
+	}
+
 	// Create shutdown context with timeout
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer shutdownCancel()
@@ -124,7 +181,7 @@ func main() {
 	}
 
 	// Stop all instances and cleanup
-	instanceManager.Shutdown()
+	instanceManager.ShutdownWithContext(shutdownCtx)
 
 	// Stop model manager background jobs
 	modelManager.Close()
@@ -135,3 +192,53 @@ func main() {
 
 	fmt.Println("Exiting llamactl.")
 }
+
+// reconcileDeclarative applies instances.declarative_dir and logs what changed
+func reconcileDeclarative(im manager.InstanceManager) {
+	result, err := im.ReconcileDeclarative()
+	if err != nil {
+		log.Printf("Failed to reconcile declarative instances: %v", err)
+	}
+	if result == nil {
+		return
+	}
+	log.Printf("Declarative instances reconciled: %d created, %d updated, %d deleted",
+		len(result.Created), len(result.Updated), len(result.Deleted))
+	for name, msg := range result.Errors {
+		log.Printf("Declarative instance %s: %s", name, msg)
+	}
+}
+
+// logBackendChecks probes the configured backend commands and logs a warning
+// for each one that can't be run
+func logBackendChecks(backendConfig *config.BackendConfig) {
+	for _, check := range backends.CheckCommands(context.Background(), backendConfig) {
+		if check.OK {
+			log.Printf("Backend %s: %s is available (%s)", check.Backend, check.Command, check.Output)
+		} else {
+			log.Printf("Warning: backend %s is not usable: %s", check.Backend, check.Error)
+		}
+	}
+}
+
+// checkConfig loads and validates the configuration, printing any errors and
+// warnings. It returns the process exit code: 1 if the config is invalid.
+func checkConfig(configPath string) int {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+
+	warnings := cfg.Warnings()
+	for _, warning := range warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+
+	if len(warnings) > 0 {
+		fmt.Printf("Configuration is valid with %d warning(s)\n", len(warnings))
+	} else {
+		fmt.Println("Configuration is valid")
+	}
+	return 0
+}
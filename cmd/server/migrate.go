@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"llamactl/pkg/config"
+	"llamactl/pkg/database"
+	"llamactl/pkg/instance"
+	"llamactl/pkg/validation"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// migratedSuffix is appended to instance JSON files archived by migrate
+const migratedSuffix = ".migrated"
+
+// runMigrate moves instances stored as <instances_dir>/<name>.json by older
+// llamactl versions into the database. It returns the process exit code.
+func runMigrate(configPath string, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	archive := flags.Bool("archive", false, "rename migrated JSON files to <name>.json"+migratedSuffix)
+	dryRun := flags.Bool("dry-run", false, "report what would be migrated without changing the database or files")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		fmt.Printf("Configuration error: %v\n", err)
+		return 1
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.Instances.InstancesDir, "*.json"))
+	if err != nil {
+		fmt.Printf("Failed to list instance files: %v\n", err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Printf("No JSON instance files found in %s\n", cfg.Instances.InstancesDir)
+		return 0
+	}
+
+	db, err := database.Open(&database.Config{
+		Driver:             cfg.Database.Driver,
+		Path:               cfg.Database.Path,
+		DSN:                cfg.Database.DSN,
+		MaxOpenConnections: cfg.Database.MaxOpenConnections,
+		MaxIdleConnections: cfg.Database.MaxIdleConnections,
+		ConnMaxLifetime:    cfg.Database.ConnMaxLifetime,
+	})
+	if err != nil {
+		fmt.Printf("Failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := database.RunMigrations(db); err != nil {
+		fmt.Printf("Failed to run database migrations: %v\n", err)
+		return 1
+	}
+
+	existing, err := db.LoadAll()
+	if err != nil {
+		fmt.Printf("Failed to load instances from database: %v\n", err)
+		return 1
+	}
+	known := make(map[string]bool, len(existing))
+	for _, inst := range existing {
+		known[inst.Name] = true
+	}
+
+	var migrated, skipped, failed int
+	for _, file := range files {
+		inst, err := readInstanceFile(file)
+		if err != nil {
+			fmt.Printf("  %s: %v\n", filepath.Base(file), err)
+			failed++
+			continue
+		}
+
+		// Never overwrite an instance that is already in the database
+		if known[inst.Name] {
+			fmt.Printf("  %s: instance %s already exists in the database, skipped\n", filepath.Base(file), inst.Name)
+			skipped++
+			continue
+		}
+
+		if *dryRun {
+			known[inst.Name] = true
+			migrated++
+			fmt.Printf("  %s: would migrate instance %s\n", filepath.Base(file), inst.Name)
+			continue
+		}
+
+		if err := db.Save(inst); err != nil {
+			fmt.Printf("  %s: failed to save instance %s: %v\n", filepath.Base(file), inst.Name, err)
+			failed++
+			continue
+		}
+		known[inst.Name] = true
+		migrated++
+		fmt.Printf("  %s: migrated instance %s\n", filepath.Base(file), inst.Name)
+
+		if *archive {
+			if err := os.Rename(file, file+migratedSuffix); err != nil {
+				fmt.Printf("  %s: failed to archive: %v\n", filepath.Base(file), err)
+			}
+		}
+	}
+
+	if *dryRun {
+		fmt.Printf("Would migrate %d instance(s), skip %d, fail %d\n", migrated, skipped, failed)
+	} else {
+		fmt.Printf("Migrated %d instance(s), skipped %d, failed %d\n", migrated, skipped, failed)
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// readInstanceFile parses an instance from its legacy JSON file. The file
+// name is used when the JSON doesn't carry one.
+func readInstanceFile(path string) (*instance.Instance, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	inst := &instance.Instance{}
+	if err := json.Unmarshal(data, inst); err != nil {
+		return nil, fmt.Errorf("failed to parse instance: %w", err)
+	}
+
+	if inst.Name == "" {
+		inst.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	if _, err := validation.ValidateInstanceName(inst.Name); err != nil {
+		return nil, fmt.Errorf("invalid instance name %q: %w", inst.Name, err)
+	}
+	if inst.GetOptions() == nil {
+		return nil, fmt.Errorf("instance %s has no options", inst.Name)
+	}
+	if inst.Created == 0 {
+		if info, err := os.Stat(path); err == nil {
+			inst.Created = info.ModTime().Unix()
+		}
+	}
+
+	return inst, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"llamactl/pkg/database"
+	"llamactl/pkg/instance"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupMigrate writes a config file pointing at a temporary instances
+// directory and database, and the given instance files. It returns the
+// config path, the instances directory and the database path.
+func setupMigrate(t *testing.T, files map[string]string) (string, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	instancesDir := filepath.Join(dir, "instances")
+	dbPath := filepath.Join(dir, "llamactl.db")
+	if err := os.MkdirAll(instancesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(dir, "llamactl.yaml")
+	config := "data_dir: " + dir + "\n" +
+		"instances:\n  instances_dir: " + instancesDir + "\n  logs_dir: " + filepath.Join(dir, "logs") + "\n" +
+		"database:\n  path: " + dbPath + "\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(instancesDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return configPath, instancesDir, dbPath
+}
+
+func openMigrateDB(t *testing.T, dbPath string) database.InstanceStore {
+	t.Helper()
+	db, err := database.Open(&database.Config{Path: dbPath, MaxOpenConnections: 1})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// loadModels returns the model of every instance in the database by name
+func loadModels(t *testing.T, dbPath string) map[string]string {
+	t.Helper()
+	instances, err := openMigrateDB(t, dbPath).LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	models := make(map[string]string, len(instances))
+	for _, inst := range instances {
+		models[inst.Name] = inst.GetOptions().BackendOptions.LlamaServerOptions.Model
+	}
+	return models
+}
+
+func instanceJSON(name, model string) string {
+	return `{"name": "` + name + `", "options": {"backend_type": "llama_cpp", "backend_options": {"model": "` + model + `"}}}`
+}
+
+func TestRunMigrate(t *testing.T) {
+	configPath, instancesDir, dbPath := setupMigrate(t, map[string]string{
+		"first.json": instanceJSON("first", "/models/first.gguf"),
+		// The name is taken from the file when the JSON has none
+		"second.json": `{"options": {"backend_type": "llama_cpp", "backend_options": {"model": "/models/second.gguf"}}}`,
+	})
+
+	if code := runMigrate(configPath, []string{"--archive"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	models := loadModels(t, dbPath)
+	if len(models) != 2 || models["first"] != "/models/first.gguf" || models["second"] != "/models/second.gguf" {
+		t.Errorf("Unexpected instances in the database: %v", models)
+	}
+	for _, name := range []string{"first.json", "second.json"} {
+		if _, err := os.Stat(filepath.Join(instancesDir, name+migratedSuffix)); err != nil {
+			t.Errorf("Expected %s to be archived: %v", name, err)
+		}
+	}
+}
+
+func TestRunMigrate_DryRun(t *testing.T) {
+	configPath, instancesDir, dbPath := setupMigrate(t, map[string]string{
+		"first.json": instanceJSON("first", "/models/first.gguf"),
+	})
+
+	if code := runMigrate(configPath, []string{"--dry-run", "--archive"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	if models := loadModels(t, dbPath); len(models) != 0 {
+		t.Errorf("Expected a dry run to leave the database empty, got %v", models)
+	}
+	if _, err := os.Stat(filepath.Join(instancesDir, "first.json")); err != nil {
+		t.Errorf("Expected a dry run to leave the file in place: %v", err)
+	}
+}
+
+func TestRunMigrate_SkipsExistingInstances(t *testing.T) {
+	configPath, instancesDir, dbPath := setupMigrate(t, map[string]string{
+		"taken.json": instanceJSON("taken", "/models/from-file.gguf"),
+	})
+
+	db := openMigrateDB(t, dbPath)
+	existing := &instance.Instance{}
+	if err := json.Unmarshal([]byte(instanceJSON("taken", "/models/from-db.gguf")), existing); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Save(existing); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	db.Close()
+
+	if code := runMigrate(configPath, []string{"--archive"}); code != 0 {
+		t.Fatalf("Expected exit code 0 when instances are skipped, got %d", code)
+	}
+
+	if models := loadModels(t, dbPath); models["taken"] != "/models/from-db.gguf" {
+		t.Errorf("Expected the existing instance to be kept, got %v", models)
+	}
+	if _, err := os.Stat(filepath.Join(instancesDir, "taken.json")); err != nil {
+		t.Errorf("Expected a skipped file not to be archived: %v", err)
+	}
+}
+
+func TestRunMigrate_KeepsFilesThatFail(t *testing.T) {
+	configPath, instancesDir, dbPath := setupMigrate(t, map[string]string{
+		"good.json":    instanceJSON("good", "/models/good.gguf"),
+		"broken.json":  `{"name": "broken", "options": `,
+		"no-opts.json": `{"name": "no-opts"}`,
+	})
+
+	if code := runMigrate(configPath, []string{"--archive"}); code != 1 {
+		t.Fatalf("Expected exit code 1 when a file fails, got %d", code)
+	}
+
+	if models := loadModels(t, dbPath); len(models) != 1 || models["good"] == "" {
+		t.Errorf("Expected only the valid instance to be migrated, got %v", models)
+	}
+	for _, name := range []string{"broken.json", "no-opts.json"} {
+		if _, err := os.Stat(filepath.Join(instancesDir, name)); err != nil {
+			t.Errorf("Expected %s to be left in place: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Package apierrors defines typed errors shared between the instance manager
+// and the HTTP layer. Each error kind carries a machine-readable code and the
+// HTTP status it maps to, so handlers can classify failures with errors.Is/As
+// instead of inspecting error messages.
+package apierrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code is a machine-readable error code returned in API error responses
+type Code string
+
+const (
+	CodeInstanceNotFound     Code = "instance_not_found"
+	CodeInstanceExists       Code = "instance_already_exists"
+	CodeInstanceRunning      Code = "instance_running"
+	CodeInvalidOptions       Code = "invalid_options"
+	CodePortInUse            Code = "port_in_use"
+	CodeNoPortsAvailable     Code = "no_ports_available"
+	CodeMaxInstances         Code = "max_instances_reached"
+	CodeMaxRunningInstances  Code = "max_running_instances_reached"
+	CodeVRAMBudgetExceeded   Code = "vram_budget_exceeded"
+	CodeNodeNotFound         Code = "node_not_found"
+	CodeRemoteRequestFailed  Code = "remote_request_failed"
+	CodeInstanceNotRunning   Code = "instance_not_running"
+	CodeUnsupportedBackend   Code = "unsupported_backend"
+	CodeBackendRequestFailed Code = "backend_request_failed"
+	CodeNodeDraining         Code = "node_draining"
+	CodeHasDependents        Code = "instance_has_dependents"
+	CodeInternal             Code = "internal_error"
+)
+
+// Error is an error kind with an associated code and HTTP status
+type Error struct {
+	Code    Code
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrInstanceNotFound     = &Error{Code: CodeInstanceNotFound, Status: http.StatusNotFound, Message: "instance not found"}
+	ErrInstanceExists       = &Error{Code: CodeInstanceExists, Status: http.StatusConflict, Message: "instance already exists"}
+	ErrInstanceRunning      = &Error{Code: CodeInstanceRunning, Status: http.StatusConflict, Message: "instance is running"}
+	ErrInvalidOptions       = &Error{Code: CodeInvalidOptions, Status: http.StatusBadRequest, Message: "invalid instance options"}
+	ErrPortInUse            = &Error{Code: CodePortInUse, Status: http.StatusConflict, Message: "port is already in use"}
+	ErrNoPortsAvailable     = &Error{Code: CodeNoPortsAvailable, Status: http.StatusServiceUnavailable, Message: "no available ports"}
+	ErrMaxInstances         = &Error{Code: CodeMaxInstances, Status: http.StatusConflict, Message: "maximum number of instances reached"}
+	ErrMaxRunningInstances  = &Error{Code: CodeMaxRunningInstances, Status: http.StatusConflict, Message: "maximum number of running instances reached"}
+	ErrVRAMBudgetExceeded   = &Error{Code: CodeVRAMBudgetExceeded, Status: http.StatusConflict, Message: "VRAM budget exceeded"}
+	ErrNodeNotFound         = &Error{Code: CodeNodeNotFound, Status: http.StatusNotFound, Message: "node not found"}
+	ErrRemoteRequestFailed  = &Error{Code: CodeRemoteRequestFailed, Status: http.StatusBadGateway, Message: "remote node request failed"}
+	ErrInstanceNotRunning   = &Error{Code: CodeInstanceNotRunning, Status: http.StatusConflict, Message: "instance is not running"}
+	ErrUnsupportedBackend   = &Error{Code: CodeUnsupportedBackend, Status: http.StatusBadRequest, Message: "operation not supported by backend"}
+	ErrBackendRequestFailed = &Error{Code: CodeBackendRequestFailed, Status: http.StatusBadGateway, Message: "backend request failed"}
+	ErrNodeDraining         = &Error{Code: CodeNodeDraining, Status: http.StatusConflict, Message: "node is draining"}
+	ErrHasDependents        = &Error{Code: CodeHasDependents, Status: http.StatusConflict, Message: "instance has dependents"}
+)
+
+// kindError attaches an error kind to a message and an optional cause
+type kindError struct {
+	kind  *Error
+	msg   string
+	cause error
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.kind}
+	}
+	return []error{e.kind, e.cause}
+}
+
+// Newf returns an error of the given kind with a formatted message.
+// A %w verb in the format is honoured, so the result also wraps that cause.
+func Newf(kind *Error, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	return &kindError{kind: kind, msg: err.Error(), cause: errors.Unwrap(err)}
+}
+
+// Wrap tags err with the given kind while keeping its original message
+func Wrap(kind *Error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, msg: err.Error(), cause: err}
+}
+
+// As returns the error kind found in err's chain, if any
+func As(err error) (*Error, bool) {
+	var kind *Error
+	if errors.As(err, &kind) {
+		return kind, true
+	}
+	return nil, false
+}
@@ -0,0 +1,26 @@
+package auth
+
+// AuditEntry records a successful mutating API request
+type AuditEntry struct {
+	ID        int
+	CreatedAt int64
+	// ID of the database API key that made the request; nil for config
+	// management keys and when management auth is disabled
+	KeyID  *int
+	Actor  string
+	Action string
+	Target string
+	Method string
+	Path   string
+	Status int
+}
+
+// AuditFilter narrows an audit log listing; zero values match everything
+type AuditFilter struct {
+	Action string
+	Target string
+	Actor  string
+	Since  int64
+	Until  int64
+	Limit  int
+}
@@ -4,6 +4,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"path"
+	"strings"
 )
 
 type PermissionMode string
@@ -11,6 +13,9 @@ type PermissionMode string
 const (
 	PermissionModeAllowAll    PermissionMode = "allow_all"
 	PermissionModePerInstance PermissionMode = "per_instance"
+	// PermissionModeReadOnly allows inference on every instance and read-only
+	// access to the management API, e.g. for monitoring
+	PermissionModeReadOnly PermissionMode = "read_only"
 )
 
 type APIKey struct {
@@ -28,6 +33,50 @@ type APIKey struct {
 type KeyPermission struct {
 	KeyID      int
 	InstanceID int
+	// Backend path patterns the key may request on the instance; empty allows all
+	AllowedPaths []string
+}
+
+// AllowsPath reports whether the permission allows a request to the backend
+// path p. Patterns use path.Match syntax, so "*" matches within one path
+// segment; a pattern ending in "/**" also matches everything below it.
+// The proxy forwards p as is, so paths with dot segments or repeated slashes
+// are rejected rather than matched in their cleaned form.
+func (kp KeyPermission) AllowsPath(p string) bool {
+	if len(kp.AllowedPaths) == 0 {
+		return true
+	}
+
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	if cleaned := path.Clean(p); cleaned != p && cleaned+"/" != p {
+		return false
+	}
+	p = path.Clean(p)
+	for _, pattern := range kp.AllowedPaths {
+		if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+			if p == prefix || strings.HasPrefix(p, prefix+"/") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePathPattern checks that pattern is an absolute, well-formed path pattern
+func ValidatePathPattern(pattern string) error {
+	if !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("path pattern %q must start with /", pattern)
+	}
+	if _, err := path.Match(strings.TrimSuffix(pattern, "/**"), ""); err != nil {
+		return fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+	}
+	return nil
 }
 
 // GenerateKey generates a cryptographically secure API key with the given prefix
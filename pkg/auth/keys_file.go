@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileKey is an API key defined in the keys file. Only the Argon2id hash is
+// stored there, as produced by HashKey.
+type FileKey struct {
+	Name           string         `yaml:"name" json:"name"`
+	KeyHash        string         `yaml:"key_hash" json:"key_hash"`
+	PermissionMode PermissionMode `yaml:"permission_mode" json:"permission_mode"`
+	// Instance names the key may use, for per_instance keys
+	Instances []string `yaml:"instances,omitempty" json:"instances,omitempty"`
+	// Backend path patterns the key may request, for per_instance keys
+	AllowedPaths []string `yaml:"allowed_paths,omitempty" json:"allowed_paths,omitempty"`
+	// Unix timestamp after which the key is rejected; 0 never expires
+	ExpiresAt int64 `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
+}
+
+type keysFile struct {
+	Keys []FileKey `yaml:"keys" json:"keys"`
+}
+
+// LoadKeysFile reads and validates a keys file. Files ending in .json are
+// parsed as JSON, anything else as YAML.
+func LoadKeysFile(path string) ([]FileKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys file: %w", err)
+	}
+
+	var file keysFile
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse keys file %s: %w", path, err)
+	}
+
+	names := make(map[string]bool, len(file.Keys))
+	for i, key := range file.Keys {
+		if err := key.validate(); err != nil {
+			return nil, fmt.Errorf("keys file %s: key %d: %w", path, i+1, err)
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("keys file %s: duplicate key name %q", path, key.Name)
+		}
+		names[key.Name] = true
+	}
+
+	return file.Keys, nil
+}
+
+func (k FileKey) validate() error {
+	if k.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !strings.HasPrefix(k.KeyHash, "$argon2id$") {
+		return fmt.Errorf("key %q: key_hash must be an Argon2id hash", k.Name)
+	}
+
+	switch k.PermissionMode {
+	case PermissionModeAllowAll, PermissionModeReadOnly:
+		if len(k.Instances) > 0 || len(k.AllowedPaths) > 0 {
+			return fmt.Errorf("key %q: instances and allowed_paths require permission mode %q", k.Name, PermissionModePerInstance)
+		}
+	case PermissionModePerInstance:
+		if len(k.Instances) == 0 {
+			return fmt.Errorf("key %q: instances are required for permission mode %q", k.Name, PermissionModePerInstance)
+		}
+		for _, pattern := range k.AllowedPaths {
+			if err := ValidatePathPattern(pattern); err != nil {
+				return fmt.Errorf("key %q: %w", k.Name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("key %q: invalid permission mode %q", k.Name, k.PermissionMode)
+	}
+
+	return nil
+}
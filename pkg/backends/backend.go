@@ -6,6 +6,11 @@ import (
 	"llamactl/pkg/config"
 	"llamactl/pkg/validation"
 	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
 )
 
 type BackendType string
@@ -14,6 +19,7 @@ const (
 	BackendTypeLlamaCpp BackendType = "llama_cpp"
 	BackendTypeMlxLm    BackendType = "mlx_lm"
 	BackendTypeVllm     BackendType = "vllm"
+	BackendTypeLlamaRpc BackendType = "llama_rpc"
 	BackendTypeUnknown  BackendType = "unknown"
 )
 
@@ -32,6 +38,7 @@ var backendConstructors = map[BackendType]func() backend{
 	BackendTypeLlamaCpp: func() backend { return &LlamaServerOptions{} },
 	BackendTypeMlxLm:    func() backend { return &MlxServerOptions{} },
 	BackendTypeVllm:     func() backend { return &VllmServerOptions{} },
+	BackendTypeLlamaRpc: func() backend { return &LlamaRpcServerOptions{} },
 }
 
 type Options struct {
@@ -42,6 +49,8 @@ type Options struct {
 	LlamaServerOptions *LlamaServerOptions `json:"-"`
 	MlxServerOptions   *MlxServerOptions   `json:"-"`
 	VllmServerOptions  *VllmServerOptions  `json:"-"`
+	// rpc-server worker for distributed llama.cpp inference
+	LlamaRpcServerOptions *LlamaRpcServerOptions `json:"-"`
 }
 
 func (o *Options) UnmarshalJSON(data []byte) error {
@@ -117,6 +126,8 @@ func (o *Options) setBackendOptions(bcknd backend) {
 		o.MlxServerOptions = v
 	case *VllmServerOptions:
 		o.VllmServerOptions = v
+	case *LlamaRpcServerOptions:
+		o.LlamaRpcServerOptions = v
 	}
 }
 
@@ -128,6 +139,8 @@ func (o *Options) getBackendSettings(backendConfig *config.BackendConfig) *confi
 		return &backendConfig.MLX
 	case BackendTypeVllm:
 		return &backendConfig.VLLM
+	case BackendTypeLlamaRpc:
+		return &backendConfig.LlamaRpc
 	default:
 		return nil
 	}
@@ -142,6 +155,8 @@ func (o *Options) getBackend() backend {
 		return o.MlxServerOptions
 	case BackendTypeVllm:
 		return o.VllmServerOptions
+	case BackendTypeLlamaRpc:
+		return o.LlamaRpcServerOptions
 	default:
 		return nil
 	}
@@ -154,8 +169,8 @@ func (o *Options) isDockerEnabled(backend *config.BackendSettings, dockerEnabled
 		return false
 	}
 
-	// MLX doesn't support Docker
-	if o.BackendType == BackendTypeMlxLm {
+	// MLX and rpc-server workers don't support Docker
+	if o.BackendType == BackendTypeMlxLm || o.BackendType == BackendTypeLlamaRpc {
 		return false
 	}
 
@@ -173,6 +188,16 @@ func (o *Options) IsDockerEnabled(backendConfig *config.BackendConfig, dockerEna
 	return o.isDockerEnabled(backendSettings, dockerEnabled)
 }
 
+// GetDockerSettings returns the Docker settings of the backend, or nil if
+// the backend has none
+func (o *Options) GetDockerSettings(backendConfig *config.BackendConfig) *config.DockerSettings {
+	backendSettings := o.getBackendSettings(backendConfig)
+	if backendSettings == nil {
+		return nil
+	}
+	return backendSettings.Docker
+}
+
 // GetCommand builds the command to run the backend
 func (o *Options) GetCommand(backendConfig *config.BackendConfig, dockerEnabled *bool, commandOverride string) string {
 	backendSettings := o.getBackendSettings(backendConfig)
@@ -181,7 +206,7 @@ func (o *Options) GetCommand(backendConfig *config.BackendConfig, dockerEnabled
 	useDocker := o.isDockerEnabled(backendSettings, dockerEnabled)
 
 	if useDocker {
-		return "docker"
+		return backendSettings.Docker.GetRuntime()
 	}
 
 	// Check for command override (only applies when not in Docker mode)
@@ -206,19 +231,217 @@ func (o *Options) BuildCommandArgs(backendConfig *config.BackendConfig, dockerEn
 
 	if o.isDockerEnabled(backendSettings, dockerEnabled) {
 		// For Docker, start with Docker args
-		args = append(args, backendSettings.Docker.Args...)
+		dockerArgs := WithDockerGPUs(backendSettings.Docker.Args, backendSettings.Docker.GPUs)
+		if backendSettings.Docker.GetRuntime() == config.ContainerRuntimePodman {
+			dockerArgs = podmanArgs(dockerArgs)
+		}
+		args = append(args, dockerArgs...)
+		args = append(args, DockerVolumeArgs(backendSettings.Docker.Args, backendSettings.Docker.Volumes)...)
 		args = append(args, backendSettings.Docker.Image)
 		args = append(args, backend.BuildDockerArgs()...)
 
 	} else {
 		// For native execution, start with backend args
 		args = append(args, backendSettings.Args...)
-		args = append(args, backend.BuildCommandArgs()...)
+		if o.BackendType == BackendTypeVllm && backendSettings.Invocation == config.VllmInvocationModule {
+			args = append(args, o.VllmServerOptions.BuildModuleArgs()...)
+		} else {
+			args = append(args, backend.BuildCommandArgs()...)
+		}
+	}
+
+	return args
+}
+
+// DockerContainerName returns the container name used for an instance when
+// it runs under Docker, so containers are identifiable in `docker ps`
+func DockerContainerName(instanceName string) string {
+	return "llamactl-" + instanceName
+}
+
+// WithDockerContainerName inserts `--name <container>` right after the `run`
+// subcommand in Docker args. Args that already name the container, or that
+// don't start with `run`, are returned unchanged.
+func WithDockerContainerName(args []string, instanceName string) []string {
+	if len(args) == 0 || args[0] != "run" {
+		return args
+	}
+	for _, arg := range args {
+		if arg == "--name" || strings.HasPrefix(arg, "--name=") {
+			return args
+		}
+	}
+
+	named := make([]string, 0, len(args)+2)
+	named = append(named, args[0], "--name", DockerContainerName(instanceName))
+	return append(named, args[1:]...)
+}
+
+// DockerRunContainerName returns the container name that `--name` sets in
+// Docker run args, or "" if the args don't name the container
+func DockerRunContainerName(args []string) string {
+	for i, arg := range args {
+		if arg == "--name" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if name, ok := strings.CutPrefix(arg, "--name="); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// WithDockerGPUs applies docker.gpus to the Docker args, replacing any
+// `--gpus` flag already there (such as the `--gpus all` in the default
+// args). Docker parses the flag value as CSV, so a device list is quoted to
+// keep its commas together.
+func WithDockerGPUs(dockerArgs []string, gpus string) []string {
+	if gpus == "" {
+		return slices.Clone(dockerArgs)
+	}
+	if strings.Contains(gpus, ",") {
+		gpus = `"` + gpus + `"`
 	}
 
+	args := make([]string, 0, len(dockerArgs)+2)
+	replaced := false
+	for i := 0; i < len(dockerArgs); i++ {
+		arg := dockerArgs[i]
+		if arg != "--gpus" && !strings.HasPrefix(arg, "--gpus=") {
+			args = append(args, arg)
+			continue
+		}
+		if arg == "--gpus" {
+			i++
+		}
+		if !replaced {
+			args = append(args, "--gpus", gpus)
+			replaced = true
+		}
+	}
+	if !replaced {
+		args = append(args, "--gpus", gpus)
+	}
 	return args
 }
 
+// DockerVolumeArgs turns docker.volumes into `-v` flags, leaving out any
+// volume whose container path is already mounted by a -v or --volume in the
+// raw Docker args, so the explicit arg wins
+func DockerVolumeArgs(dockerArgs, volumes []string) []string {
+	mounted := make(map[string]bool)
+	for i := 0; i < len(dockerArgs); i++ {
+		var spec string
+		switch arg := dockerArgs[i]; {
+		case (arg == "-v" || arg == "--volume") && i+1 < len(dockerArgs):
+			spec = dockerArgs[i+1]
+			i++
+		case strings.HasPrefix(arg, "--volume="):
+			spec = strings.TrimPrefix(arg, "--volume=")
+		case strings.HasPrefix(arg, "-v="):
+			spec = strings.TrimPrefix(arg, "-v=")
+		default:
+			continue
+		}
+		if container, err := config.VolumeContainerPath(spec); err == nil {
+			mounted[container] = true
+		}
+	}
+
+	var args []string
+	for _, volume := range volumes {
+		container, err := config.VolumeContainerPath(volume)
+		if err != nil || mounted[container] {
+			continue
+		}
+		mounted[container] = true
+		args = append(args, "-v", volume)
+	}
+	return args
+}
+
+// WithDockerWorkdir inserts `-w <dir>` right after the `run` subcommand,
+// unless dir is empty or the args already set the working directory
+func WithDockerWorkdir(args []string, dir string) []string {
+	if dir == "" || len(args) == 0 || args[0] != "run" {
+		return args
+	}
+	for _, arg := range args {
+		if arg == "-w" || arg == "--workdir" || strings.HasPrefix(arg, "-w=") || strings.HasPrefix(arg, "--workdir=") {
+			return args
+		}
+	}
+
+	withDir := make([]string, 0, len(args)+2)
+	withDir = append(withDir, args[0], "-w", dir)
+	return append(withDir, args[1:]...)
+}
+
+// WithDockerMount inserts a read-only `-v <path>:<path>:ro` bind mount right
+// after the `run` subcommand, so a file llamactl writes on the host is
+// visible at the same path inside the container
+func WithDockerMount(args []string, path string) []string {
+	if path == "" || len(args) == 0 || args[0] != "run" {
+		return args
+	}
+
+	withMount := make([]string, 0, len(args)+2)
+	withMount = append(withMount, args[0], "-v", path+":"+path+":ro")
+	return append(withMount, args[1:]...)
+}
+
+// shortFlagsPattern matches one or more combined single-letter flags
+var shortFlagsPattern = regexp.MustCompile(`^-[A-Za-z]+$`)
+
+// WithDockerInteractive inserts `-i` right after the `run` subcommand so the
+// container receives the process's stdin, unless the args already keep it
+// open, including combined flags like -it
+func WithDockerInteractive(args []string) []string {
+	if len(args) == 0 || args[0] != "run" {
+		return args
+	}
+	for _, arg := range args {
+		if arg == "--interactive" || (shortFlagsPattern.MatchString(arg) && strings.Contains(arg, "i")) {
+			return args
+		}
+	}
+
+	interactive := make([]string, 0, len(args)+1)
+	interactive = append(interactive, args[0], "-i")
+	return append(interactive, args[1:]...)
+}
+
+// podmanArgs translates Docker-specific run flags to their podman equivalents.
+// Podman exposes NVIDIA GPUs through CDI devices rather than --gpus.
+func podmanArgs(args []string) []string {
+	translated := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		var gpus string
+		switch {
+		case arg == "--gpus" && i+1 < len(args):
+			gpus = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--gpus="):
+			gpus = strings.TrimPrefix(arg, "--gpus=")
+		default:
+			translated = append(translated, arg)
+			continue
+		}
+
+		gpus = strings.Trim(gpus, `"`)
+		if gpus == "all" {
+			translated = append(translated, "--device", "nvidia.com/gpu=all")
+			continue
+		}
+		for _, id := range strings.Split(strings.TrimPrefix(gpus, "device="), ",") {
+			translated = append(translated, "--device", "nvidia.com/gpu="+id)
+		}
+	}
+	return translated
+}
+
 // BuildEnvironment builds the environment variables for the backend process
 func (o *Options) BuildEnvironment(backendConfig *config.BackendConfig, dockerEnabled *bool, environment map[string]string) map[string]string {
 
@@ -278,6 +501,35 @@ func (o *Options) GetResponseHeaders(backendConfig *config.BackendConfig) map[st
 	return backendSettings.ResponseHeaders
 }
 
+// GetStopSignal returns the signal used to gracefully stop the backend process.
+// Without an explicit stop_signal, vLLM gets SIGTERM, which it handles more
+// reliably than SIGINT, and the other backends get SIGINT.
+func (o *Options) GetStopSignal(backendConfig *config.BackendConfig) os.Signal {
+	stopSignal := ""
+	if backendSettings := o.getBackendSettings(backendConfig); backendSettings != nil {
+		stopSignal = backendSettings.StopSignal
+	}
+
+	switch stopSignal {
+	case config.StopSignalSIGTERM:
+		return syscall.SIGTERM
+	case config.StopSignalSIGINT:
+		return syscall.SIGINT
+	}
+
+	if o.BackendType == BackendTypeVllm {
+		return syscall.SIGTERM
+	}
+	return syscall.SIGINT
+}
+
+// ServesHTTP reports whether the backend runs an HTTP server. rpc-server
+// workers speak llama.cpp's binary RPC protocol instead, so they can't be
+// proxied to or health checked over HTTP.
+func (o *Options) ServesHTTP() bool {
+	return o.BackendType != BackendTypeLlamaRpc
+}
+
 // ValidateInstanceOptions performs validation based on backend type
 func (o *Options) ValidateInstanceOptions() error {
 	backend := o.getBackend()
@@ -3,7 +3,9 @@ package backends
 import (
 	"fmt"
 	"llamactl/pkg/config"
+	"maps"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -73,11 +75,32 @@ func BuildCommandArgs(options any, multipleFlags map[string]struct{}) []string {
 	return args
 }
 
+// JoinCommandLine formats args as a single command line that can be pasted
+// into a shell and read back by ParseCommand. Args containing whitespace or
+// quotes are single-quoted, or double-quoted when they contain a single quote.
+func JoinCommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`"):
+			quoted[i] = arg
+		case !strings.Contains(arg, "'"):
+			quoted[i] = "'" + arg + "'"
+		default:
+			quoted[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`").Replace(arg) + `"`
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
 // BuildDockerCommand builds a Docker command with the specified configuration and arguments
 func BuildDockerCommand(backendConfig *config.BackendSettings, instanceArgs []string) (string, []string, error) {
 	// Start with configured Docker arguments (should include "run", "--rm", etc.)
-	dockerArgs := make([]string, len(backendConfig.Docker.Args))
-	copy(dockerArgs, backendConfig.Docker.Args)
+	// with GPUs applied
+	dockerArgs := WithDockerGPUs(backendConfig.Docker.Args, backendConfig.Docker.GPUs)
+
+	// Add volume mounts
+	dockerArgs = append(dockerArgs, DockerVolumeArgs(backendConfig.Docker.Args, backendConfig.Docker.Volumes)...)
 
 	// Add environment variables
 	for key, value := range backendConfig.Docker.Environment {
@@ -97,10 +120,14 @@ func BuildDockerCommand(backendConfig *config.BackendSettings, instanceArgs []st
 // convertExtraArgsToFlags converts map[string]string to command flags
 // Empty values become boolean flags: {"flag": ""} → ["--flag"]
 // Non-empty values: {"flag": "value"} → ["--flag", "value"]
+// Keys are emitted in kebab-case ({"log_file": ...} → "--log-file") and in
+// sorted order so the resulting command line is stable.
 func convertExtraArgsToFlags(extraArgs map[string]string) []string {
 	var args []string
 
-	for key, value := range extraArgs {
+	for _, key := range slices.Sorted(maps.Keys(extraArgs)) {
+		value := extraArgs[key]
+		key = strings.ReplaceAll(key, "_", "-")
 		if value == "" {
 			// Boolean flag
 			args = append(args, "--"+key)
@@ -112,3 +139,40 @@ func convertExtraArgsToFlags(extraArgs map[string]string) []string {
 
 	return args
 }
+
+// canonicalizeOptions normalizes options in place to the form ParseCommand
+// produces: empty lists and extra args become nil, and extra arg keys use
+// snake_case like the parsed flag names.
+func canonicalizeOptions(options any) {
+	v := reflect.ValueOf(options).Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.Slice:
+			if field.Len() == 0 {
+				field.SetZero()
+			} else {
+				field.Set(reflect.AppendSlice(reflect.MakeSlice(field.Type(), 0, field.Len()), field))
+			}
+		case reflect.Map:
+			extraArgs, ok := field.Interface().(map[string]string)
+			if !ok {
+				continue
+			}
+			if len(extraArgs) == 0 {
+				field.SetZero()
+				continue
+			}
+			normalized := make(map[string]string, len(extraArgs))
+			for key, value := range extraArgs {
+				normalized[strings.ReplaceAll(key, "-", "_")] = value
+			}
+			field.Set(reflect.ValueOf(normalized))
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package backends
+
+import (
+	"context"
+	"fmt"
+	"llamactl/pkg/config"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// commandCheckTimeout bounds a single probe. vLLM and MLX start a Python
+// interpreter and import their package before printing anything.
+const commandCheckTimeout = 30 * time.Second
+
+// CommandCheck is the result of probing one backend's command
+type CommandCheck struct {
+	Backend BackendType `json:"backend"`
+	Command string      `json:"command"`          // the backend command, or the container runtime for Docker
+	Docker  bool        `json:"docker"`           // true if the container runtime was checked instead
+	OK      bool        `json:"ok"`               // the command ran and exited successfully
+	Output  string      `json:"output,omitempty"` // the version line of the output, or its first line
+	Error   string      `json:"error,omitempty"`
+}
+
+// probeArgs are the arguments each backend command is run with. They print
+// something and exit without loading a model; rpc-server and mlx_lm.server
+// have no --version.
+var probeArgs = map[BackendType][]string{
+	BackendTypeLlamaCpp: {"--version"},
+	BackendTypeVllm:     {"--version"},
+	BackendTypeMlxLm:    {"--help"},
+	BackendTypeLlamaRpc: {"--help"},
+}
+
+// CheckCommands runs each configured backend command once to confirm that it
+// is installed and executable. Backends without a command are skipped. For
+// backends that run in Docker, the container runtime is checked instead with
+// `version`, which also fails when the daemon isn't reachable. The checks
+// run in parallel; results are in backend order.
+func CheckCommands(ctx context.Context, backendConfig *config.BackendConfig) []CommandCheck {
+	types := []BackendType{BackendTypeLlamaCpp, BackendTypeVllm, BackendTypeMlxLm, BackendTypeLlamaRpc}
+
+	results := make([]*CommandCheck, len(types))
+	var wg sync.WaitGroup
+	for i, backendType := range types {
+		settings := (&Options{BackendType: backendType}).getBackendSettings(backendConfig)
+		docker := settings.Docker != nil && settings.Docker.Enabled
+		if settings.Command == "" && !docker {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if docker {
+				results[i] = checkCommand(ctx, backendType, settings.Docker.GetRuntime(), []string{"version"}, nil)
+				results[i].Docker = true
+			} else {
+				results[i] = checkCommand(ctx, backendType, settings.Command, probeArgs[backendType], settings.Environment)
+			}
+		}()
+	}
+	wg.Wait()
+
+	checks := make([]CommandCheck, 0, len(results))
+	for _, result := range results {
+		if result != nil {
+			checks = append(checks, *result)
+		}
+	}
+	return checks
+}
+
+func checkCommand(ctx context.Context, backendType BackendType, command string, args []string, env map[string]string) *CommandCheck {
+	check := &CommandCheck{Backend: backendType, Command: command}
+
+	path, err := exec.LookPath(command)
+	if err != nil {
+		check.Error = fmt.Sprintf("%s not found: %v", command, err)
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandCheckTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	output, err := cmd.CombinedOutput()
+	check.Output = summarizeOutput(string(output))
+	if ctx.Err() == context.DeadlineExceeded {
+		check.Error = fmt.Sprintf("%s %s did not finish within %s", command, strings.Join(args, " "), commandCheckTimeout)
+		return check
+	}
+	if err != nil {
+		check.Error = fmt.Sprintf("%s %s failed: %v", command, strings.Join(args, " "), err)
+		return check
+	}
+
+	check.OK = true
+	return check
+}
+
+// summarizeOutput picks the line mentioning the version, since llama-server
+// prints device initialization logs before it, or else the first line
+func summarizeOutput(output string) string {
+	first := ""
+	for line := range strings.Lines(output) {
+		line = strings.TrimSpace(line)
+		if strings.Contains(strings.ToLower(line), "version") {
+			return line
+		}
+		if first == "" {
+			first = line
+		}
+	}
+	return first
+}
@@ -0,0 +1,62 @@
+package backends_test
+
+import (
+	"context"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
+	"strings"
+	"testing"
+)
+
+func TestCheckCommands(t *testing.T) {
+	cfg := &config.BackendConfig{
+		LlamaCpp: config.BackendSettings{Command: "echo"},
+		VLLM: config.BackendSettings{
+			Command: "vllm",
+			Docker:  &config.DockerSettings{Enabled: true, Runtime: "echo"},
+		},
+		MLX:      config.BackendSettings{Command: "llamactl-missing-command"},
+		LlamaRpc: config.BackendSettings{Command: "false"},
+	}
+
+	checks := backends.CheckCommands(context.Background(), cfg)
+	if len(checks) != 4 {
+		t.Fatalf("Expected 4 checks, got %d: %+v", len(checks), checks)
+	}
+
+	tests := []struct {
+		backend   backends.BackendType
+		command   string
+		docker    bool
+		ok        bool
+		errSubstr string
+	}{
+		{backends.BackendTypeLlamaCpp, "echo", false, true, ""},
+		{backends.BackendTypeVllm, "echo", true, true, ""},
+		{backends.BackendTypeMlxLm, "llamactl-missing-command", false, false, "not found"},
+		{backends.BackendTypeLlamaRpc, "false", false, false, "failed"},
+	}
+	for i, tt := range tests {
+		got := checks[i]
+		if got.Backend != tt.backend || got.Command != tt.command || got.Docker != tt.docker || got.OK != tt.ok {
+			t.Errorf("Check %d: expected %+v, got %+v", i, tt, got)
+		}
+		if tt.ok && got.Output == "" {
+			t.Errorf("Check %d: expected the command output, got none", i)
+		}
+		if tt.errSubstr != "" && !strings.Contains(got.Error, tt.errSubstr) {
+			t.Errorf("Check %d: expected error containing %q, got %q", i, tt.errSubstr, got.Error)
+		}
+	}
+
+	// Backends without a command are skipped
+	cfg.LlamaRpc.Command = ""
+	if checks := backends.CheckCommands(context.Background(), cfg); len(checks) != 3 {
+		t.Errorf("Expected the backend without a command to be skipped, got %+v", checks)
+	}
+
+	// No backends gives an empty list, not nil
+	if checks := backends.CheckCommands(context.Background(), &config.BackendConfig{}); checks == nil || len(checks) != 0 {
+		t.Errorf("Expected an empty list without backends, got %#v", checks)
+	}
+}
@@ -78,6 +78,7 @@ type LlamaServerOptions struct {
 	SplitMode               string   `json:"split_mode,omitempty"`                 // -sm, --split-mode {none,layer,row}
 	TensorSplit             string   `json:"tensor_split,omitempty"`               // -ts, --tensor-split N0,N1,N2,...
 	MainGPU                 int      `json:"main_gpu,omitempty"`                   // -mg, --main-gpu INDEX
+	Rpc                     []string `json:"rpc,omitempty"`                        // --rpc SERVERS, host:port of rpc-server workers
 	Fit                     string   `json:"fit,omitempty"`                        // -fit, --fit [on|off]
 	FitTarget               string   `json:"fit_target,omitempty"`                 // -fitt, --fit-target MiB0,MiB1,MiB2,...
 	FitCtx                  int      `json:"fit_ctx,omitempty"`                    // -fitc, --fit-ctx N
@@ -147,7 +148,7 @@ type LlamaServerOptions struct {
 	BackendSampling    bool     `json:"backend_sampling,omitempty"`     // -bs, --backend-sampling
 
 	// Server-specific params
-	CtxCheckpoints       int      `json:"ctx_checkpoints,omitempty"`        // --ctx-checkpoints, --swa-checkpoints N
+	CtxCheckpoints       int      `json:"ctx_checkpoints,omitempty"`        // -ctxcp, --ctx-checkpoints, --swa-checkpoints N
 	CacheRAM             int      `json:"cache_ram,omitempty"`              // -cram, --cache-ram N
 	LookupCacheStatic    bool     `json:"lookup_cache_static,omitempty"`    // -lcs, --lookup-cache-static
 	LookupCacheDynamic   bool     `json:"lookup_cache_dynamic,omitempty"`   // -lcd, --lookup-cache-dynamic
@@ -238,18 +239,21 @@ type LlamaServerOptions struct {
 	TTSUseGuideTokens    bool     `json:"tts_use_guide_tokens,omitempty"`
 
 	// Default model params
-	EmbdGemmaDefault      bool `json:"embd_gemma_default,omitempty"`       // --embd-gemma-default
-	FIMQwen1B             bool `json:"fim_qwen_1b,omitempty"`              // --fim-qwen-1
-	FIMQwen1_5BDefault    bool `json:"fim_qwen_1_5b_default,omitempty"`    // --fim-qwen-1.5b-default
-	FIMQwen3BDefault      bool `json:"fim_qwen_3b_default,omitempty"`      // --fim-qwen-3b-default
-	FIMQwen7BDefault      bool `json:"fim_qwen_7b_default,omitempty"`      // --fim-qwen-7b-default
-	FIMQwen7BSpec         bool `json:"fim_qwen_7b_spec,omitempty"`         // --fim-qwen-7b-spec
-	FIMQwen14BSpec        bool `json:"fim_qwen_14b_spec,omitempty"`        // --fim-qwen-14b-spec
-	FIMQwen30BDefault     bool `json:"fim_qwen_30b_default,omitempty"`     // --fim-qwen-30b-default
-	GPTOss20BDefault      bool `json:"gpt_oss_20b_default,omitempty"`      // --gpt-oss-20b-default
-	GPTOss120BDefault     bool `json:"gpt_oss_120b_default,omitempty"`     // --gpt-oss-120b-default
-	VisionGemma4BDefault  bool `json:"vision_gemma_4b_default,omitempty"`  // --vision-gemma-4b-default
-	VisionGemma12BDefault bool `json:"vision_gemma_12b_default,omitempty"` // --vision-gemma-12b-default
+	EmbdBGESmallEnDefault bool `json:"embd_bge_small_en_default,omitempty"` // --embd-bge-small-en-default
+	EmbdE5SmallEnDefault  bool `json:"embd_e5_small_en_default,omitempty"`  // --embd-e5-small-en-default
+	EmbdGTESmallDefault   bool `json:"embd_gte_small_default,omitempty"`    // --embd-gte-small-default
+	EmbdGemmaDefault      bool `json:"embd_gemma_default,omitempty"`        // --embd-gemma-default
+	FIMQwen1B             bool `json:"fim_qwen_1b,omitempty"`               // --fim-qwen-1
+	FIMQwen1_5BDefault    bool `json:"fim_qwen_1_5b_default,omitempty"`     // --fim-qwen-1.5b-default
+	FIMQwen3BDefault      bool `json:"fim_qwen_3b_default,omitempty"`       // --fim-qwen-3b-default
+	FIMQwen7BDefault      bool `json:"fim_qwen_7b_default,omitempty"`       // --fim-qwen-7b-default
+	FIMQwen7BSpec         bool `json:"fim_qwen_7b_spec,omitempty"`          // --fim-qwen-7b-spec
+	FIMQwen14BSpec        bool `json:"fim_qwen_14b_spec,omitempty"`         // --fim-qwen-14b-spec
+	FIMQwen30BDefault     bool `json:"fim_qwen_30b_default,omitempty"`      // --fim-qwen-30b-default
+	GPTOss20BDefault      bool `json:"gpt_oss_20b_default,omitempty"`       // --gpt-oss-20b-default
+	GPTOss120BDefault     bool `json:"gpt_oss_120b_default,omitempty"`      // --gpt-oss-120b-default
+	VisionGemma4BDefault  bool `json:"vision_gemma_4b_default,omitempty"`   // --vision-gemma-4b-default
+	VisionGemma12BDefault bool `json:"vision_gemma_12b_default,omitempty"`  // --vision-gemma-12b-default
 
 	// ExtraArgs are additional command line arguments.
 	// Example: {"verbose": "", "log-file": "/logs/llama.log"}
@@ -310,7 +314,7 @@ func (o *LlamaServerOptions) UnmarshalJSON(data []byte) error {
 	}
 	for key, value := range raw {
 		if !processedFields[key] {
-			o.ExtraArgs[key] = fmt.Sprintf("%v", value)
+			o.ExtraArgs[key] = extraArgValue(value)
 		}
 	}
 
@@ -357,9 +361,95 @@ func (o *LlamaServerOptions) Validate() error {
 		return validation.ValidationError(fmt.Errorf("invalid port range: %d", o.Port))
 	}
 
+	if err := validation.ValidateHost(o.Host); err != nil {
+		return err
+	}
+
+	for _, c := range o.conflictingOptions() {
+		if c.conflict {
+			return validation.ValidationError(fmt.Errorf("options %s and %s cannot be used together", c.a, c.b))
+		}
+	}
+
+	for _, f := range []struct {
+		name string
+		ref  *string
+	}{
+		{"hf_repo", &o.HFRepo},
+		{"hf_repo_draft", &o.HFRepoDraft},
+		{"hf_repo_v", &o.HFRepoV},
+	} {
+		if err := validateLlamaHFRepo(f.name, f.ref); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// validateLlamaHFRepo checks a -hf style reference and normalizes it in
+// place, since llama-server only takes <user>/<model>[:quant], not URLs. It
+// has no way to pin a revision.
+func validateLlamaHFRepo(option string, ref *string) error {
+	if *ref == "" {
+		return nil
+	}
+	r, err := validation.ParseHFRef(*ref)
+	if err != nil {
+		return validation.ValidationError(fmt.Errorf("%s: %w", option, err))
+	}
+	if r.Revision != "" {
+		return validation.ValidationError(fmt.Errorf("%s: llama-server does not support pinning a revision (@%s)", option, r.Revision))
+	}
+	*ref = r.String()
+	return nil
+}
+
+// optionConflict is a pair of options llama-server can't honour together
+type optionConflict struct {
+	a, b     string
+	conflict bool
+}
+
+// conflictingOptions lists known incompatible option combinations. Most are
+// flag/negation pairs where llama-server silently lets the last flag win.
+func (o *LlamaServerOptions) conflictingOptions() []optionConflict {
+	return []optionConflict{
+		{"embedding", "reranking", o.Embedding && o.Reranking},
+		{"chat_template", "chat_template_file", o.ChatTemplate != "" && o.ChatTemplateFile != ""},
+		{"webui_config", "webui_config_file", o.WebUIConfig != "" && o.WebUIConfigFile != ""},
+		{"mmproj", "no_mmproj", o.MMProj != "" && o.NoMMProj},
+		{"mmproj_url", "no_mmproj", o.MMProjURL != "" && o.NoMMProj},
+		{"jinja", "no_jinja", o.Jinja && o.NoJinja},
+		{"webui", "no_webui", o.WebUI && o.NoWebUI},
+		{"mmap", "no_mmap", o.Mmap && o.NoMmap},
+		{"direct_io", "no_direct_io", o.DirectIO && o.NoDirectIO},
+		{"kv_offload", "no_kv_offload", o.KVOffload && o.NoKVOffload},
+		{"repack", "no_repack", o.Repack && o.NoRepack},
+		{"op_offload", "no_op_offload", o.OpOffload && o.NoOpOffload},
+		{"kv_unified", "no_kv_unified", o.KVUnified && o.NoKVUnified},
+		{"context_shift", "no_context_shift", o.ContextShift && o.NoContextShift},
+		{"warmup", "no_warmup", o.Warmup && o.NoWarmup},
+		{"cont_batching", "no_cont_batching", o.ContBatching && o.NoContBatching},
+		{"mmproj_auto", "no_mmproj_auto", o.MMProjAuto && o.NoMMProjAuto},
+		{"mmproj_offload", "no_mmproj_offload", o.MMProjOffload && o.NoMMProjOffload},
+		{"cache_prompt", "no_cache_prompt", o.CachePrompt && o.NoCachePrompt},
+		{"slots", "no_slots", o.Slots && o.NoSlots},
+		{"models_autoload", "no_models_autoload", o.ModelsAutoload && o.NoModelsAutoload},
+		{"prefill_assistant", "no_prefill_assistant", o.PrefillAssistant && o.NoPrefillAssistant},
+		{"perf", "no_perf", o.Perf && o.NoPerf},
+		{"escape", "no_escape", o.Escape && o.NoEscape},
+	}
+}
+
+// Canonical returns a copy of the options in the form ParseCommand produces,
+// so options can be compared after a round trip through the command line
+func (o *LlamaServerOptions) Canonical() *LlamaServerOptions {
+	c := *o
+	canonicalizeOptions(&c)
+	return &c
+}
+
 // BuildCommandArgs converts InstanceOptions to command line arguments
 func (o *LlamaServerOptions) BuildCommandArgs() []string {
 	if o == nil {
@@ -447,6 +537,7 @@ var llamaFieldMappings = map[string]string{
 	"bs":          "backend_sampling", // -bs, --backend-sampling
 
 	// Server-specific params
+	"ctxcp":              "ctx_checkpoints",        // -ctxcp, --ctx-checkpoints N
 	"swa_checkpoints":    "ctx_checkpoints",        // --swa-checkpoints N
 	"cram":               "cache_ram",              // -cram, --cache-ram N
 	"lcs":                "lookup_cache_static",    // -lcs, --lookup-cache-static
@@ -460,7 +551,6 @@ var llamaFieldMappings = map[string]string{
 	"nocb":               "no_cont_batching",       // -nocb, --no-cont-batching
 	"mm":                 "mmproj",                 // -mm, --mmproj FILE
 	"mmu":                "mmproj_url",             // -mmu, --mmproj-url URL
-	"no_mmproj":          "no_mmproj_auto",         // --no-mmproj (alias for --no-mmproj-auto)
 	"otd":                "override_tensor_draft",  // -otd, --override-tensor-draft
 	"cmoed":              "cpu_moe_draft",          // -cmoed, --cpu-moe-draft
 	"ncmoed":             "n_cpu_moe_draft",        // -ncmoed, --n-cpu-moe-draft N
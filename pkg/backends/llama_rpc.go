@@ -0,0 +1,133 @@
+package backends
+
+import (
+	"encoding/json"
+	"fmt"
+	"llamactl/pkg/validation"
+)
+
+// LlamaRpcServerOptions configures a llama.cpp rpc-server worker. Workers
+// hold no model of their own; a llama-server instance lists them in its rpc
+// option and offloads layers to their devices over the network.
+type LlamaRpcServerOptions struct {
+	Host    string `json:"host,omitempty"`    // -H, --host HOST
+	Port    int    `json:"port,omitempty"`    // -p, --port PORT
+	Device  string `json:"device,omitempty"`  // -d, --device <dev1,dev2,..>
+	Threads int    `json:"threads,omitempty"` // -t, --threads N
+	Cache   bool   `json:"cache,omitempty"`   // -c, --cache
+
+	// ExtraArgs are additional command line arguments.
+	// Example: {"mem": "8192"}
+	ExtraArgs map[string]string `json:"extra_args,omitempty"`
+}
+
+// llamaRpcFieldMappings maps rpc-server's short flags to canonical names
+var llamaRpcFieldMappings = map[string]string{
+	"H": "host",    // -H, --host HOST
+	"p": "port",    // -p, --port PORT
+	"d": "device",  // -d, --device <dev1,dev2,..>
+	"t": "threads", // -t, --threads N
+	"c": "cache",   // -c, --cache
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling to collect unknown fields into ExtraArgs
+func (o *LlamaRpcServerOptions) UnmarshalJSON(data []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	type tempOptions LlamaRpcServerOptions
+	temp := tempOptions{}
+	if err := json.Unmarshal(data, &temp); err != nil {
+		return err
+	}
+	*o = LlamaRpcServerOptions(temp)
+
+	knownFields := getKnownFieldNames(o)
+
+	if o.ExtraArgs == nil {
+		o.ExtraArgs = make(map[string]string)
+	}
+	for key, value := range raw {
+		if !knownFields[key] {
+			o.ExtraArgs[key] = extraArgValue(value)
+		}
+	}
+
+	return nil
+}
+
+// GetModel returns an empty name; workers serve whatever model the main
+// llama-server sends them
+func (o *LlamaRpcServerOptions) GetModel() string {
+	return ""
+}
+
+func (o *LlamaRpcServerOptions) GetPort() int {
+	return o.Port
+}
+
+func (o *LlamaRpcServerOptions) SetPort(port int) {
+	o.Port = port
+}
+
+func (o *LlamaRpcServerOptions) GetHost() string {
+	return o.Host
+}
+
+func (o *LlamaRpcServerOptions) Validate() error {
+	if o == nil {
+		return validation.ValidationError(fmt.Errorf("rpc-server options cannot be nil for llama.cpp RPC backend"))
+	}
+
+	if o.Port < 0 || o.Port > 65535 {
+		return validation.ValidationError(fmt.Errorf("invalid port range: %d", o.Port))
+	}
+
+	if err := validation.ValidateHost(o.Host); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Canonical returns a copy of the options in the form ParseCommand produces,
+// so options can be compared after a round trip through the command line
+func (o *LlamaRpcServerOptions) Canonical() *LlamaRpcServerOptions {
+	c := *o
+	canonicalizeOptions(&c)
+	return &c
+}
+
+// BuildCommandArgs converts to command line arguments
+func (o *LlamaRpcServerOptions) BuildCommandArgs() []string {
+	args := BuildCommandArgs(o, map[string]struct{}{})
+
+	// Append extra args at the end
+	args = append(args, convertExtraArgsToFlags(o.ExtraArgs)...)
+
+	return args
+}
+
+func (o *LlamaRpcServerOptions) BuildDockerArgs() []string {
+	return []string{}
+}
+
+// ParseCommand parses an rpc-server command string into LlamaRpcServerOptions
+// Supports multiple formats:
+// 1. Full command: "rpc-server --host 0.0.0.0 --port 50052"
+// 2. Full path: "/usr/local/bin/rpc-server -H 0.0.0.0 -p 50052"
+// 3. Args only: "--port 50052 --cache"
+func (o *LlamaRpcServerOptions) ParseCommand(command string) (any, error) {
+	executableNames := []string{"rpc-server"}
+	var subcommandNames []string              // rpc-server has no subcommands
+	multiValuedFlags := map[string]struct{}{} // rpc-server has no multi-valued flags
+
+	var rpcOptions LlamaRpcServerOptions
+	if err := parseCommandWithAliases(command, executableNames, subcommandNames, multiValuedFlags, llamaRpcFieldMappings, &rpcOptions); err != nil {
+		return nil, err
+	}
+
+	return &rpcOptions, nil
+}
@@ -0,0 +1,139 @@
+package backends_test
+
+import (
+	"encoding/json"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
+	"llamactl/pkg/testutil"
+	"testing"
+)
+
+func TestParseLlamaRpcCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		expectErr bool
+		validate  func(*testing.T, *backends.LlamaRpcServerOptions)
+	}{
+		{
+			name:    "long flags",
+			command: "rpc-server --host 0.0.0.0 --port 50052 --cache",
+			validate: func(t *testing.T, opts *backends.LlamaRpcServerOptions) {
+				if opts.Host != "0.0.0.0" {
+					t.Errorf("expected host '0.0.0.0', got '%s'", opts.Host)
+				}
+				if opts.Port != 50052 {
+					t.Errorf("expected port 50052, got %d", opts.Port)
+				}
+				if !opts.Cache {
+					t.Error("expected cache to be true")
+				}
+			},
+		},
+		{
+			name:    "short flags with path",
+			command: "/opt/llama.cpp/bin/rpc-server -H 10.0.0.2 -p 50053 -d CUDA0,CUDA1 -t 8",
+			validate: func(t *testing.T, opts *backends.LlamaRpcServerOptions) {
+				if opts.Host != "10.0.0.2" || opts.Port != 50053 {
+					t.Errorf("expected 10.0.0.2:50053, got %s:%d", opts.Host, opts.Port)
+				}
+				if opts.Device != "CUDA0,CUDA1" {
+					t.Errorf("expected device 'CUDA0,CUDA1', got '%s'", opts.Device)
+				}
+				if opts.Threads != 8 {
+					t.Errorf("expected threads 8, got %d", opts.Threads)
+				}
+			},
+		},
+		{
+			name:      "empty command",
+			command:   "",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts backends.LlamaRpcServerOptions
+			result, err := opts.ParseCommand(tt.command)
+
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			tt.validate(t, result.(*backends.LlamaRpcServerOptions))
+		})
+	}
+}
+
+func TestLlamaRpcBuildCommandArgs(t *testing.T) {
+	options := backends.Options{
+		BackendType: backends.BackendTypeLlamaRpc,
+		LlamaRpcServerOptions: &backends.LlamaRpcServerOptions{
+			Host:      "0.0.0.0",
+			Port:      50052,
+			Cache:     true,
+			ExtraArgs: map[string]string{"mem": "8192"},
+		},
+	}
+	backendConfig := &config.BackendConfig{
+		LlamaRpc: config.BackendSettings{Command: "rpc-server"},
+	}
+
+	if cmd := options.GetCommand(backendConfig, testutil.BoolPtr(true), ""); cmd != "rpc-server" {
+		t.Errorf("Expected rpc-server even with docker_enabled, got %q", cmd)
+	}
+
+	args := options.BuildCommandArgs(backendConfig, nil)
+	if !testutil.ContainsFlagWithValue(args, "--host", "0.0.0.0") {
+		t.Errorf("Expected --host 0.0.0.0 in %v", args)
+	}
+	if !testutil.ContainsFlagWithValue(args, "--port", "50052") {
+		t.Errorf("Expected --port 50052 in %v", args)
+	}
+	if !testutil.Contains(args, "--cache") {
+		t.Errorf("Expected --cache in %v", args)
+	}
+	if !testutil.ContainsFlagWithValue(args, "--mem", "8192") {
+		t.Errorf("Expected --mem 8192 from extra args in %v", args)
+	}
+	if options.ServesHTTP() {
+		t.Error("Expected RPC workers not to serve HTTP")
+	}
+}
+
+func TestLlamaServerRpcOption(t *testing.T) {
+	options := backends.LlamaServerOptions{
+		Model: "/models/llama.gguf",
+		Rpc:   []string{"10.0.0.2:50052", "10.0.0.3:50052"},
+	}
+
+	args := options.BuildCommandArgs()
+	if !testutil.ContainsFlagWithValue(args, "--rpc", "10.0.0.2:50052,10.0.0.3:50052") {
+		t.Errorf("Expected comma-separated --rpc in %v", args)
+	}
+
+	var parser backends.LlamaServerOptions
+	result, err := parser.ParseCommand("llama-server -m /models/llama.gguf --rpc 10.0.0.2:50052,10.0.0.3:50052")
+	if err != nil {
+		t.Fatalf("ParseCommand failed: %v", err)
+	}
+	parsed := result.(*backends.LlamaServerOptions)
+	if len(parsed.Rpc) != 2 || parsed.Rpc[1] != "10.0.0.3:50052" {
+		t.Errorf("Expected two rpc servers, got %v", parsed.Rpc)
+	}
+
+	var fromJSON backends.LlamaServerOptions
+	if err := json.Unmarshal([]byte(`{"rpc": ["10.0.0.2:50052"]}`), &fromJSON); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(fromJSON.Rpc) != 1 || fromJSON.Rpc[0] != "10.0.0.2:50052" {
+		t.Errorf("Expected rpc list from JSON, got %v", fromJSON.Rpc)
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"llamactl/pkg/backends"
 	"llamactl/pkg/config"
 	"llamactl/pkg/testutil"
+	"os"
 	"reflect"
+	"slices"
+	"syscall"
 	"testing"
 )
 
@@ -479,8 +482,8 @@ func TestParseLlamaCommand_ExtraArgs(t *testing.T) {
 				if val, ok := opts.ExtraArgs["unknown_flag"]; !ok || val != "value" {
 					t.Errorf("expected extra_args[unknown_flag]='value', got '%s'", val)
 				}
-				if val, ok := opts.ExtraArgs["another_bool_flag"]; !ok || val != "true" {
-					t.Errorf("expected extra_args[another_bool_flag]='true', got '%s'", val)
+				if val, ok := opts.ExtraArgs["another_bool_flag"]; !ok || val != "" {
+					t.Errorf("expected extra_args[another_bool_flag]='', got '%s'", val)
 				}
 			},
 		},
@@ -503,8 +506,8 @@ func TestParseLlamaCommand_ExtraArgs(t *testing.T) {
 				if val, ok := opts.ExtraArgs["custom_arg"]; !ok || val != "test" {
 					t.Errorf("expected extra_args[custom_arg]='test', got '%s'", val)
 				}
-				if val, ok := opts.ExtraArgs["new_feature"]; !ok || val != "true" {
-					t.Errorf("expected extra_args[new_feature]='true', got '%s'", val)
+				if val, ok := opts.ExtraArgs["new_feature"]; !ok || val != "" {
+					t.Errorf("expected extra_args[new_feature]='', got '%s'", val)
 				}
 			},
 		},
@@ -516,8 +519,8 @@ func TestParseLlamaCommand_ExtraArgs(t *testing.T) {
 				if opts.ExtraArgs == nil {
 					t.Fatal("expected extra_args to be non-nil")
 				}
-				if val, ok := opts.ExtraArgs["experimental_feature"]; !ok || val != "true" {
-					t.Errorf("expected extra_args[experimental_feature]='true', got '%s'", val)
+				if val, ok := opts.ExtraArgs["experimental_feature"]; !ok || val != "" {
+					t.Errorf("expected extra_args[experimental_feature]='', got '%s'", val)
 				}
 				if val, ok := opts.ExtraArgs["beta_mode"]; !ok || val != "enabled" {
 					t.Errorf("expected extra_args[beta_mode]='enabled', got '%s'", val)
@@ -550,6 +553,32 @@ func TestParseLlamaCommand_ExtraArgs(t *testing.T) {
 		})
 	}
 }
+func TestParseLlamaCommand_RecentFlags(t *testing.T) {
+	var opts backends.LlamaServerOptions
+	result, err := opts.ParseCommand("llama-server -ctxcp 8 --embd-bge-small-en-default --embd-e5-small-en-default --embd-gte-small-default")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	llamaOpts := result.(*backends.LlamaServerOptions)
+
+	if llamaOpts.CtxCheckpoints != 8 {
+		t.Errorf("expected ctx_checkpoints 8, got %d", llamaOpts.CtxCheckpoints)
+	}
+	if !llamaOpts.EmbdBGESmallEnDefault || !llamaOpts.EmbdE5SmallEnDefault || !llamaOpts.EmbdGTESmallDefault {
+		t.Errorf("expected embedding presets to be set, got %+v", llamaOpts)
+	}
+	if len(llamaOpts.ExtraArgs) != 0 {
+		t.Errorf("expected no extra args, got %v", llamaOpts.ExtraArgs)
+	}
+
+	args := llamaOpts.BuildCommandArgs()
+	for _, want := range []string{"--ctx-checkpoints", "--embd-bge-small-en-default", "--embd-e5-small-en-default", "--embd-gte-small-default"} {
+		if !slices.Contains(args, want) {
+			t.Errorf("expected %q in args %v", want, args)
+		}
+	}
+}
+
 func TestLlamaCppGetCommand_WithOverrides(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -626,3 +655,483 @@ func TestLlamaCppGetCommand_WithOverrides(t *testing.T) {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func TestWithDockerContainerName(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expected     []string
+		expectedName string
+	}{
+		{
+			name:         "inserts name after run",
+			args:         []string{"run", "--rm", "--network", "host", "image"},
+			expected:     []string{"run", "--name", "llamactl-test", "--rm", "--network", "host", "image"},
+			expectedName: "llamactl-test",
+		},
+		{
+			name:         "keeps user supplied name",
+			args:         []string{"run", "--name", "custom", "image"},
+			expected:     []string{"run", "--name", "custom", "image"},
+			expectedName: "custom",
+		},
+		{
+			name:         "keeps user supplied name with equals",
+			args:         []string{"run", "--name=custom", "image"},
+			expected:     []string{"run", "--name=custom", "image"},
+			expectedName: "custom",
+		},
+		{
+			name:     "ignores args without run",
+			args:     []string{"container", "run", "image"},
+			expected: []string{"container", "run", "image"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := backends.WithDockerContainerName(tt.args, "test")
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("WithDockerContainerName() = %v, want %v", result, tt.expected)
+			}
+			// The effective name can be read back from the args
+			if got := backends.DockerRunContainerName(result); got != tt.expectedName {
+				t.Errorf("DockerRunContainerName() = %q, want %q", got, tt.expectedName)
+			}
+		})
+	}
+}
+
+func TestWithDockerWorkdir(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		dir      string
+		expected []string
+	}{
+		{
+			name:     "inserts workdir after run",
+			args:     []string{"run", "--rm", "image"},
+			dir:      "/workspace",
+			expected: []string{"run", "-w", "/workspace", "--rm", "image"},
+		},
+		{
+			name:     "no workdir",
+			args:     []string{"run", "--rm", "image"},
+			expected: []string{"run", "--rm", "image"},
+		},
+		{
+			name:     "keeps user supplied workdir",
+			args:     []string{"run", "--workdir=/data", "image"},
+			dir:      "/workspace",
+			expected: []string{"run", "--workdir=/data", "image"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := backends.WithDockerWorkdir(tt.args, tt.dir)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("WithDockerWorkdir() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWithDockerMount(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		path     string
+		expected []string
+	}{
+		{
+			name:     "inserts mount after run",
+			args:     []string{"run", "--rm", "image"},
+			path:     "/data/instances/a/chat_template.jinja",
+			expected: []string{"run", "-v", "/data/instances/a/chat_template.jinja:/data/instances/a/chat_template.jinja:ro", "--rm", "image"},
+		},
+		{
+			name:     "no path",
+			args:     []string{"run", "--rm", "image"},
+			expected: []string{"run", "--rm", "image"},
+		},
+		{
+			name:     "not a run command",
+			args:     []string{"--port", "8080"},
+			path:     "/tmp/template.jinja",
+			expected: []string{"--port", "8080"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := backends.WithDockerMount(tt.args, tt.path)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("WithDockerMount() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWithDockerInteractive(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "inserts -i after run",
+			args:     []string{"run", "--rm", "-v=/models/ai:/models", "image"},
+			expected: []string{"run", "-i", "--rm", "-v=/models/ai:/models", "image"},
+		},
+		{
+			name:     "keeps --interactive",
+			args:     []string{"run", "--interactive", "image"},
+			expected: []string{"run", "--interactive", "image"},
+		},
+		{
+			name:     "keeps combined flags",
+			args:     []string{"run", "-it", "image"},
+			expected: []string{"run", "-it", "image"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := backends.WithDockerInteractive(tt.args)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("WithDockerInteractive() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPodmanRuntime(t *testing.T) {
+	backendConfig := &config.BackendConfig{
+		LlamaCpp: config.BackendSettings{
+			Command: "/usr/bin/llama-server",
+			Docker: &config.DockerSettings{
+				Enabled: true,
+				Runtime: config.ContainerRuntimePodman,
+				Image:   "test-image",
+				Args:    []string{"run", "--rm", "--gpus", "all", "--network", "host"},
+			},
+		},
+	}
+
+	opts := backends.Options{
+		BackendType: backends.BackendTypeLlamaCpp,
+		LlamaServerOptions: &backends.LlamaServerOptions{
+			Model: "test-model.gguf",
+		},
+	}
+
+	if cmd := opts.GetCommand(backendConfig, nil, ""); cmd != "podman" {
+		t.Errorf("GetCommand() = %v, want podman", cmd)
+	}
+
+	args := opts.BuildCommandArgs(backendConfig, nil)
+	expected := []string{"run", "--rm", "--device", "nvidia.com/gpu=all", "--network", "host", "test-image"}
+	if !reflect.DeepEqual(args[:len(expected)], expected) {
+		t.Errorf("BuildCommandArgs() = %v, want prefix %v", args, expected)
+	}
+
+	backendConfig.LlamaCpp.Docker.Args = []string{"run", "--gpus=device=0,1"}
+	args = opts.BuildCommandArgs(backendConfig, nil)
+	expected = []string{"run", "--device", "nvidia.com/gpu=0", "--device", "nvidia.com/gpu=1", "test-image"}
+	if !reflect.DeepEqual(args[:len(expected)], expected) {
+		t.Errorf("BuildCommandArgs() = %v, want prefix %v", args, expected)
+	}
+}
+
+func TestDockerGPUs(t *testing.T) {
+	tests := []struct {
+		name     string
+		runtime  string
+		args     []string
+		gpus     string
+		expected []string
+	}{
+		{
+			name:     "no gpus",
+			args:     []string{"run", "--rm"},
+			expected: []string{"run", "--rm", "test-image"},
+		},
+		{
+			name:     "all gpus",
+			args:     []string{"run", "--rm"},
+			gpus:     "all",
+			expected: []string{"run", "--rm", "--gpus", "all", "test-image"},
+		},
+		{
+			name:     "selected devices",
+			args:     []string{"run"},
+			gpus:     "device=0,1",
+			expected: []string{"run", "--gpus", `"device=0,1"`, "test-image"},
+		},
+		{
+			name:     "replaces gpus in raw args",
+			args:     []string{"run", "--gpus=1", "--rm"},
+			gpus:     "all",
+			expected: []string{"run", "--gpus", "all", "--rm", "test-image"},
+		},
+		{
+			name:     "raw args kept without gpus setting",
+			args:     []string{"run", "--gpus", "1"},
+			expected: []string{"run", "--gpus", "1", "test-image"},
+		},
+		{
+			name:     "podman devices",
+			runtime:  config.ContainerRuntimePodman,
+			args:     []string{"run"},
+			gpus:     "device=0,1",
+			expected: []string{"run", "--device", "nvidia.com/gpu=0", "--device", "nvidia.com/gpu=1", "test-image"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backendConfig := &config.BackendConfig{
+				LlamaCpp: config.BackendSettings{
+					Docker: &config.DockerSettings{
+						Enabled: true,
+						Runtime: tt.runtime,
+						Image:   "test-image",
+						Args:    tt.args,
+						GPUs:    tt.gpus,
+					},
+				},
+			}
+			opts := backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "test-model.gguf",
+				},
+			}
+
+			args := opts.BuildCommandArgs(backendConfig, nil)
+			if len(args) < len(tt.expected) || !reflect.DeepEqual(args[:len(tt.expected)], tt.expected) {
+				t.Errorf("BuildCommandArgs() = %v, want prefix %v", args, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDockerGPUs_OverridesDefaultArgs(t *testing.T) {
+	// The default Docker args already pass --gpus all
+	defaults, err := config.LoadConfig("nonexistent-file.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	backendConfig := defaults.Backends
+	backendConfig.LlamaCpp.Docker.Enabled = true
+	backendConfig.LlamaCpp.Docker.GPUs = "device=1"
+
+	opts := backends.Options{
+		BackendType:        backends.BackendTypeLlamaCpp,
+		LlamaServerOptions: &backends.LlamaServerOptions{Model: "test-model.gguf"},
+	}
+	args := opts.BuildCommandArgs(&backendConfig, nil)
+
+	var gpus []string
+	for i, arg := range args {
+		if arg == "--gpus" && i+1 < len(args) {
+			gpus = append(gpus, args[i+1])
+		}
+	}
+	if !reflect.DeepEqual(gpus, []string{"device=1"}) {
+		t.Errorf("expected a single --gpus device=1, got %v in %v", gpus, args)
+	}
+}
+
+func TestDockerVolumes(t *testing.T) {
+	backendConfig := &config.BackendConfig{
+		LlamaCpp: config.BackendSettings{
+			Docker: &config.DockerSettings{
+				Enabled: true,
+				Image:   "test-image",
+				Args:    []string{"run", "--rm", "-v", "/data/models:/models"},
+				Volumes: []string{"/srv/models:/models/", "hf-cache:/root/.cache/huggingface:ro"},
+			},
+		},
+	}
+
+	opts := backends.Options{
+		BackendType: backends.BackendTypeLlamaCpp,
+		LlamaServerOptions: &backends.LlamaServerOptions{
+			Model: "test-model.gguf",
+		},
+	}
+
+	args := opts.BuildCommandArgs(backendConfig, nil)
+	expected := []string{"run", "--rm", "-v", "/data/models:/models", "-v", "hf-cache:/root/.cache/huggingface:ro", "test-image"}
+	if !reflect.DeepEqual(args[:len(expected)], expected) {
+		t.Errorf("BuildCommandArgs() = %v, want prefix %v", args, expected)
+	}
+}
+
+func TestDockerVolumeArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		volumes  []string
+		expected []string
+	}{
+		{
+			name:     "no volumes",
+			args:     []string{"run", "--rm"},
+			expected: nil,
+		},
+		{
+			name:     "adds volumes",
+			volumes:  []string{"/models:/models", "/cache:/cache:ro"},
+			expected: []string{"-v", "/models:/models", "-v", "/cache:/cache:ro"},
+		},
+		{
+			name:     "skips path mounted with --volume=",
+			args:     []string{"run", "--volume=/other:/models:ro"},
+			volumes:  []string{"/models:/models", "/cache:/cache"},
+			expected: []string{"-v", "/cache:/cache"},
+		},
+		{
+			name:     "skips path mounted with -v",
+			args:     []string{"run", "-v", "/other:/cache"},
+			volumes:  []string{"/models:/models", "/cache:/cache"},
+			expected: []string{"-v", "/models:/models"},
+		},
+		{
+			name:     "dedupes volumes",
+			volumes:  []string{"/a:/models", "/b:/models"},
+			expected: []string{"-v", "/a:/models"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := backends.DockerVolumeArgs(tt.args, tt.volumes)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("DockerVolumeArgs() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetStopSignal(t *testing.T) {
+	tests := []struct {
+		name          string
+		backendType   backends.BackendType
+		backendConfig config.BackendConfig
+		expected      os.Signal
+	}{
+		{
+			name:        "llama.cpp defaults to SIGINT",
+			backendType: backends.BackendTypeLlamaCpp,
+			expected:    syscall.SIGINT,
+		},
+		{
+			name:        "mlx defaults to SIGINT",
+			backendType: backends.BackendTypeMlxLm,
+			expected:    syscall.SIGINT,
+		},
+		{
+			name:        "vllm defaults to SIGTERM",
+			backendType: backends.BackendTypeVllm,
+			expected:    syscall.SIGTERM,
+		},
+		{
+			name:        "llama.cpp configured SIGTERM",
+			backendType: backends.BackendTypeLlamaCpp,
+			backendConfig: config.BackendConfig{
+				LlamaCpp: config.BackendSettings{StopSignal: config.StopSignalSIGTERM},
+			},
+			expected: syscall.SIGTERM,
+		},
+		{
+			name:        "vllm configured SIGINT",
+			backendType: backends.BackendTypeVllm,
+			backendConfig: config.BackendConfig{
+				VLLM: config.BackendSettings{StopSignal: config.StopSignalSIGINT},
+			},
+			expected: syscall.SIGINT,
+		},
+		{
+			name:        "setting on another backend is ignored",
+			backendType: backends.BackendTypeMlxLm,
+			backendConfig: config.BackendConfig{
+				LlamaCpp: config.BackendSettings{StopSignal: config.StopSignalSIGTERM},
+			},
+			expected: syscall.SIGINT,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := backends.Options{BackendType: tt.backendType}
+			if got := opts.GetStopSignal(&tt.backendConfig); got != tt.expected {
+				t.Errorf("GetStopSignal() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLlamaCppValidate_ConflictingOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options *backends.LlamaServerOptions
+		wantErr string
+	}{
+		{
+			name:    "embedding alone",
+			options: &backends.LlamaServerOptions{Model: "/m.gguf", Embedding: true},
+		},
+		{
+			name:    "reranking alone",
+			options: &backends.LlamaServerOptions{Model: "/m.gguf", Reranking: true},
+		},
+		{
+			name:    "embedding and reranking",
+			options: &backends.LlamaServerOptions{Model: "/m.gguf", Embedding: true, Reranking: true},
+			wantErr: "options embedding and reranking cannot be used together",
+		},
+		{
+			name:    "inline and file chat template",
+			options: &backends.LlamaServerOptions{Model: "/m.gguf", ChatTemplate: "chatml", ChatTemplateFile: "/t.jinja"},
+			wantErr: "options chat_template and chat_template_file cannot be used together",
+		},
+		{
+			name:    "webui and no_webui",
+			options: &backends.LlamaServerOptions{Model: "/m.gguf", WebUI: true, NoWebUI: true},
+			wantErr: "options webui and no_webui cannot be used together",
+		},
+		{
+			name:    "mmproj with no_mmproj",
+			options: &backends.LlamaServerOptions{Model: "/m.gguf", MMProj: "/p.gguf", NoMMProj: true},
+			wantErr: "options mmproj and no_mmproj cannot be used together",
+		},
+		{
+			name:    "jinja and no_jinja",
+			options: &backends.LlamaServerOptions{Model: "/m.gguf", Jinja: true, NoJinja: true},
+			wantErr: "options jinja and no_jinja cannot be used together",
+		},
+		{
+			name:    "negated flag alone",
+			options: &backends.LlamaServerOptions{Model: "/m.gguf", NoMmap: true, NoWebUI: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -65,7 +65,7 @@ func (o *MlxServerOptions) UnmarshalJSON(data []byte) error {
 	}
 	for key, value := range raw {
 		if !knownFields[key] {
-			o.ExtraArgs[key] = fmt.Sprintf("%v", value)
+			o.ExtraArgs[key] = extraArgValue(value)
 		}
 	}
 
@@ -97,9 +97,21 @@ func (o *MlxServerOptions) Validate() error {
 		return validation.ValidationError(fmt.Errorf("invalid port range: %d", o.Port))
 	}
 
+	if err := validation.ValidateHost(o.Host); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// Canonical returns a copy of the options in the form ParseCommand produces,
+// so options can be compared after a round trip through the command line
+func (o *MlxServerOptions) Canonical() *MlxServerOptions {
+	c := *o
+	canonicalizeOptions(&c)
+	return &c
+}
+
 // BuildCommandArgs converts to command line arguments
 func (o *MlxServerOptions) BuildCommandArgs() []string {
 	multipleFlags := map[string]struct{}{} // MLX doesn't currently have []string fields
@@ -204,6 +204,23 @@ func TestMlxBuildCommandArgs_ZeroValues(t *testing.T) {
 	}
 }
 
+func TestMlxBuildCommandArgs_ExtraArgs(t *testing.T) {
+	var opts backends.MlxServerOptions
+	result, err := opts.ParseCommand("mlx_lm.server --model test-model --prompt-cache-size 4 --new-bool-flag")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	args := result.(*backends.MlxServerOptions).BuildCommandArgs()
+
+	if !testutil.Contains(args, "--prompt-cache-size") || !testutil.Contains(args, "4") {
+		t.Errorf("expected --prompt-cache-size 4 in args %v", args)
+	}
+	if !testutil.Contains(args, "--new-bool-flag") || testutil.Contains(args, "true") {
+		t.Errorf("expected bare --new-bool-flag in args %v", args)
+	}
+}
+
 func TestParseMlxCommand_ExtraArgs(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -228,8 +245,8 @@ func TestParseMlxCommand_ExtraArgs(t *testing.T) {
 				if val, ok := opts.ExtraArgs["unknown_flag"]; !ok || val != "value" {
 					t.Errorf("expected extra_args[unknown_flag]='value', got '%s'", val)
 				}
-				if val, ok := opts.ExtraArgs["new_bool_flag"]; !ok || val != "true" {
-					t.Errorf("expected extra_args[new_bool_flag]='true', got '%s'", val)
+				if val, ok := opts.ExtraArgs["new_bool_flag"]; !ok || val != "" {
+					t.Errorf("expected extra_args[new_bool_flag]='', got '%s'", val)
 				}
 			},
 		},
@@ -241,8 +258,8 @@ func TestParseMlxCommand_ExtraArgs(t *testing.T) {
 				if opts.ExtraArgs == nil {
 					t.Fatal("expected extra_args to be non-nil")
 				}
-				if val, ok := opts.ExtraArgs["experimental_feature"]; !ok || val != "true" {
-					t.Errorf("expected extra_args[experimental_feature]='true', got '%s'", val)
+				if val, ok := opts.ExtraArgs["experimental_feature"]; !ok || val != "" {
+					t.Errorf("expected extra_args[experimental_feature]='', got '%s'", val)
 				}
 				if val, ok := opts.ExtraArgs["custom_param"]; !ok || val != "test" {
 					t.Errorf("expected extra_args[custom_param]='test', got '%s'", val)
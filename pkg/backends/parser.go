@@ -34,6 +34,7 @@ func parseCommandWithAliases(command string, executableNames []string, subcomman
 	if err != nil {
 		return err
 	}
+	coerceOptionTypes(options, target)
 
 	// If we found a positional model and no --model flag was provided, set the model
 	if modelFromPositional != "" {
@@ -58,20 +59,68 @@ func parseCommandWithAliases(command string, executableNames []string, subcomman
 // normalizeCommand handles multiline commands with backslashes
 func normalizeCommand(command string) string {
 	re := regexp.MustCompile(`\\\s*\n\s*`)
-	normalized := re.ReplaceAllString(command, " ")
-	re = regexp.MustCompile(`\s+`)
-	return strings.TrimSpace(re.ReplaceAllString(normalized, " "))
+	return strings.TrimSpace(re.ReplaceAllString(command, " "))
+}
+
+// splitCommand splits a command into tokens like a POSIX shell would for the
+// common cases: whitespace separates tokens, single quotes are literal and
+// double quotes allow \", \\, \$ and \` escapes. Backslashes outside quotes are kept
+// as is so Windows paths survive.
+func splitCommand(command string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inToken := false
+	var quote byte
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				current.WriteByte(c)
+			}
+		case quote == '"':
+			if c == '\\' && i+1 < len(command) && strings.IndexByte("\"\\$`", command[i+1]) >= 0 {
+				i++
+				current.WriteByte(command[i])
+			} else if c == '"' {
+				quote = 0
+			} else {
+				current.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inToken = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteByte(c)
+			inToken = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
 }
 
 // extractArgs extracts arguments from command, removing executable and subcommands
 // Returns: args, modelFromPositional, error
 func extractArgs(command string, executableNames []string, subcommandNames []string) ([]string, string, error) {
-	// Check for unterminated quotes
-	if strings.Count(command, `"`)%2 != 0 || strings.Count(command, `'`)%2 != 0 {
-		return nil, "", fmt.Errorf("unterminated quoted string")
+	tokens, err := splitCommand(command)
+	if err != nil {
+		return nil, "", err
 	}
-
-	tokens := strings.Fields(command)
 	if len(tokens) == 0 {
 		return nil, "", fmt.Errorf("no tokens found")
 	}
@@ -157,7 +206,7 @@ func parseFlags(args []string, multiValuedFlags map[string]struct{}, flagAliases
 			hasValue = true
 		} else {
 			flagName = strings.TrimLeft(arg, "-")
-			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			if i+1 < len(args) && (!strings.HasPrefix(args[i+1], "-") || isNumber(args[i+1])) {
 				value = args[i+1]
 				hasValue = true
 				i++ // Skip next arg since we consumed it
@@ -183,7 +232,7 @@ func parseFlags(args []string, multiValuedFlags map[string]struct{}, flagAliases
 					options[flagName] = []string{value}
 				}
 			} else {
-				options[flagName] = parseValue(value)
+				options[flagName] = value
 			}
 		} else {
 			// Boolean flag
@@ -194,6 +243,44 @@ func parseFlags(args []string, multiValuedFlags map[string]struct{}, flagAliases
 	return options, nil
 }
 
+// isNumber reports whether s is a number, so negative values such as
+// "--seed -1" are not mistaken for flags
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// coerceOptionTypes converts parsed flag values to the types of the target's
+// fields. Flag values are kept as strings until the field type is known so
+// that string options like "--alias 123" stay strings, while numeric and
+// boolean options are converted and comma-separated lists are split.
+// Unknown flags keep their raw string value for ExtraArgs.
+func coerceOptionTypes(options map[string]any, target any) {
+	fieldTypes := make(map[string]reflect.Type)
+	t := reflect.TypeOf(target).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		fieldTypes[name] = t.Field(i).Type
+	}
+
+	for key, value := range options {
+		str, ok := value.(string)
+		fieldType, known := fieldTypes[key]
+		if !ok || !known {
+			continue
+		}
+
+		switch fieldType.Kind() {
+		case reflect.String:
+			// Keep as is
+		case reflect.Slice:
+			options[key] = strings.Split(str, ",")
+		default:
+			options[key] = parseValue(str)
+		}
+	}
+}
+
 // parseValue converts string to appropriate type
 func parseValue(value string) any {
 	// Remove quotes
@@ -286,3 +373,20 @@ func getKnownFieldNames(v any) map[string]bool {
 	}
 	return fields
 }
+
+// extraArgValue converts an unknown JSON option into its ExtraArgs form.
+// true becomes "" so the option is emitted as a bare boolean flag, matching
+// what ParseCommand produces for flags given without a value.
+func extraArgValue(value any) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return ""
+		}
+		return "false"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,123 @@
+package backends_test
+
+import (
+	"llamactl/pkg/backends"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTripper is implemented by every backend options type whose command
+// line can be parsed back into options.
+type roundTripper[T any] interface {
+	*T
+	BuildCommandArgs() []string
+	ParseCommand(command string) (any, error)
+	Canonical() *T
+}
+
+// assertRoundTrip checks that ParseCommand(BuildCommandArgs(opts)) reproduces opts
+func assertRoundTrip[T any, P roundTripper[T]](t *testing.T, executable string, opts P) {
+	t.Helper()
+
+	command := executable + " " + backends.JoinCommandLine(opts.BuildCommandArgs())
+	result, err := opts.ParseCommand(command)
+	if err != nil {
+		t.Fatalf("ParseCommand(%q) failed: %v", command, err)
+	}
+
+	want := opts.Canonical()
+	got := P(result.(*T)).Canonical()
+	if reflect.DeepEqual(want, got) {
+		return
+	}
+
+	wv, gv := reflect.ValueOf(want).Elem(), reflect.ValueOf(got).Elem()
+	for i := 0; i < wv.NumField(); i++ {
+		if !reflect.DeepEqual(wv.Field(i).Interface(), gv.Field(i).Interface()) {
+			t.Errorf("%s: want %#v, got %#v", wv.Type().Field(i).Name, wv.Field(i).Interface(), gv.Field(i).Interface())
+		}
+	}
+	t.Logf("command: %s", command)
+}
+
+// populate sets every option field to a non-zero value derived from its name
+func populate(v any) {
+	rv := reflect.ValueOf(v).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Field(i)
+		name := strings.Split(rv.Type().Field(i).Tag.Get("json"), ",")[0]
+		switch field.Kind() {
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Int:
+			field.SetInt(int64(i + 1))
+		case reflect.Float64:
+			field.SetFloat(float64(i) + 0.25)
+		case reflect.String:
+			field.SetString("val-" + name)
+		case reflect.Slice:
+			field.Set(reflect.ValueOf([]string{"a-" + name, "b-" + name}))
+		case reflect.Map:
+			field.Set(reflect.ValueOf(map[string]string{"custom_flag": "x", "bare_flag": ""}))
+		}
+	}
+}
+
+func TestRoundTrip_AllFields(t *testing.T) {
+	t.Run("llama", func(t *testing.T) {
+		opts := &backends.LlamaServerOptions{}
+		populate(opts)
+		assertRoundTrip(t, "llama-server", opts)
+	})
+	t.Run("vllm", func(t *testing.T) {
+		opts := &backends.VllmServerOptions{}
+		populate(opts)
+		assertRoundTrip(t, "vllm serve", opts)
+	})
+	t.Run("mlx", func(t *testing.T) {
+		opts := &backends.MlxServerOptions{}
+		populate(opts)
+		assertRoundTrip(t, "mlx_lm.server", opts)
+	})
+	t.Run("llama_rpc", func(t *testing.T) {
+		opts := &backends.LlamaRpcServerOptions{}
+		populate(opts)
+		assertRoundTrip(t, "rpc-server", opts)
+	})
+}
+
+func TestRoundTrip_EdgeValues(t *testing.T) {
+	t.Run("llama negative numbers", func(t *testing.T) {
+		assertRoundTrip(t, "llama-server", &backends.LlamaServerOptions{Seed: -1, Predict: -1, RopeFreqBase: -0.5})
+	})
+	t.Run("llama numeric and boolean looking strings", func(t *testing.T) {
+		assertRoundTrip(t, "llama-server", &backends.LlamaServerOptions{Alias: "123", APIKey: "true", Device: "0"})
+	})
+	t.Run("llama values with spaces and quotes", func(t *testing.T) {
+		assertRoundTrip(t, "llama-server", &backends.LlamaServerOptions{
+			ChatTemplateKwargs: `{"enable_thinking": false}`,
+			ReversePrompt:      "Ünïcode  user: ",
+			Model:              "/models/my model.gguf",
+			ChatTemplate:       `it's "{{ messages }}" $HOME \n`,
+		})
+	})
+	t.Run("llama comma separated list", func(t *testing.T) {
+		assertRoundTrip(t, "llama-server", &backends.LlamaServerOptions{Rpc: []string{"10.0.0.2:50052", "10.0.0.3:50052"}})
+	})
+	t.Run("llama single repeated value", func(t *testing.T) {
+		assertRoundTrip(t, "llama-server", &backends.LlamaServerOptions{Lora: []string{"/adapters/a.gguf"}})
+	})
+	t.Run("extra args", func(t *testing.T) {
+		assertRoundTrip(t, "llama-server", &backends.LlamaServerOptions{
+			ExtraArgs: map[string]string{"new-flag": "", "new_value": "-3", "literal": "true"},
+		})
+	})
+	t.Run("vllm positional model", func(t *testing.T) {
+		assertRoundTrip(t, "vllm serve", &backends.VllmServerOptions{
+			Model:        "microsoft/DialoGPT-medium",
+			ChatTemplate: "{{ messages }}",
+			Seed:         -1,
+		})
+	})
+}
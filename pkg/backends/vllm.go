@@ -15,8 +15,6 @@ var vllmMultiValuedFlags = map[string]struct{}{
 	"allowed_methods": {}, // --allowed-methods (List type)
 	"allowed_headers": {}, // --allowed-headers (List type)
 	"middleware":      {}, // --middleware (action='append')
-	"lora_modules":    {}, // --lora-modules (custom LoRAParserAction, accepts multiple)
-	"prompt_adapters": {}, // --prompt-adapters (similar to lora-modules, accepts multiple)
 }
 
 type VllmServerOptions struct {
@@ -178,7 +176,7 @@ func (o *VllmServerOptions) UnmarshalJSON(data []byte) error {
 	}
 	for key, value := range raw {
 		if !knownFields[key] {
-			o.ExtraArgs[key] = fmt.Sprintf("%v", value)
+			o.ExtraArgs[key] = extraArgValue(value)
 		}
 	}
 
@@ -210,9 +208,21 @@ func (o *VllmServerOptions) Validate() error {
 		return validation.ValidationError(fmt.Errorf("invalid port range: %d", o.Port))
 	}
 
+	if err := validation.ValidateHost(o.Host); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// Canonical returns a copy of the options in the form ParseCommand produces,
+// so options can be compared after a round trip through the command line
+func (o *VllmServerOptions) Canonical() *VllmServerOptions {
+	c := *o
+	canonicalizeOptions(&c)
+	return &c
+}
+
 // BuildCommandArgs converts VllmServerOptions to command line arguments
 // For vLLM native, model is a positional argument after "serve"
 func (o *VllmServerOptions) BuildCommandArgs() []string {
@@ -238,6 +248,13 @@ func (o *VllmServerOptions) BuildCommandArgs() []string {
 	return args
 }
 
+// BuildModuleArgs converts VllmServerOptions to command line arguments for
+// `python -m vllm.entrypoints.openai.api_server`. The module has no serve
+// subcommand and takes the model as a --model flag, the same as the Docker image.
+func (o *VllmServerOptions) BuildModuleArgs() []string {
+	return o.BuildDockerArgs()
+}
+
 func (o *VllmServerOptions) BuildDockerArgs() []string {
 	var args []string
 
@@ -2,6 +2,7 @@ package backends_test
 
 import (
 	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
 	"llamactl/pkg/testutil"
 	"testing"
 )
@@ -322,6 +323,109 @@ func TestVllmBuildCommandArgs_PositionalModel(t *testing.T) {
 	}
 }
 
+func TestVllmBuildCommandArgs_Invocation(t *testing.T) {
+	options := backends.Options{
+		BackendType: backends.BackendTypeVllm,
+		VllmServerOptions: &backends.VllmServerOptions{
+			Model: "Qwen/Qwen3-8B",
+			Port:  8000,
+		},
+	}
+
+	tests := []struct {
+		name       string
+		settings   config.BackendSettings
+		expected   []string
+		unexpected string
+	}{
+		{
+			name:       "serve passes the model positionally",
+			settings:   config.BackendSettings{Command: "vllm", Args: []string{"serve"}, Invocation: config.VllmInvocationServe},
+			expected:   []string{"serve", "Qwen/Qwen3-8B"},
+			unexpected: "--model",
+		},
+		{
+			name:     "default is serve",
+			settings: config.BackendSettings{Command: "vllm", Args: []string{"serve"}},
+			expected: []string{"serve", "Qwen/Qwen3-8B"},
+		},
+		{
+			name:     "module passes the model as a flag",
+			settings: config.BackendSettings{Command: "python", Args: []string{"-m", "vllm.entrypoints.openai.api_server"}, Invocation: config.VllmInvocationModule},
+			expected: []string{"-m", "vllm.entrypoints.openai.api_server"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := options.BuildCommandArgs(&config.BackendConfig{VLLM: tt.settings}, nil)
+
+			if len(args) < len(tt.expected) {
+				t.Fatalf("Expected args to start with %v, got %v", tt.expected, args)
+			}
+			for i, arg := range tt.expected {
+				if args[i] != arg {
+					t.Fatalf("Expected args to start with %v, got %v", tt.expected, args)
+				}
+			}
+			if tt.unexpected != "" && testutil.Contains(args, tt.unexpected) {
+				t.Errorf("Unexpected %s in %v", tt.unexpected, args)
+			}
+			if !testutil.ContainsFlagWithValue(args, "--port", "8000") {
+				t.Errorf("Expected --port 8000 in %v", args)
+			}
+		})
+	}
+
+	moduleArgs := options.VllmServerOptions.BuildModuleArgs()
+	if !testutil.ContainsFlagWithValue(moduleArgs, "--model", "Qwen/Qwen3-8B") {
+		t.Errorf("Expected --model Qwen/Qwen3-8B in module args %v", moduleArgs)
+	}
+	if testutil.Contains(moduleArgs[:1], "Qwen/Qwen3-8B") {
+		t.Errorf("Expected no positional model in module args %v", moduleArgs)
+	}
+}
+
+func TestVllmBuildCommandArgs_ExtraArgs(t *testing.T) {
+	options := backends.VllmServerOptions{
+		Model: "microsoft/DialoGPT-medium",
+		ExtraArgs: map[string]string{
+			"enable_sleep_mode": "",    // boolean flag
+			"kv-cache-dtype":    "fp8", // value flag
+			"max_num_seqs":      "128", // snake_case key
+		},
+	}
+
+	args := options.BuildCommandArgs()
+
+	if !testutil.Contains(args, "--enable-sleep-mode") {
+		t.Errorf("expected --enable-sleep-mode in args %v", args)
+	}
+	if !testutil.Contains(args, "--kv-cache-dtype") || !testutil.Contains(args, "fp8") {
+		t.Errorf("expected --kv-cache-dtype fp8 in args %v", args)
+	}
+	if !testutil.Contains(args, "--max-num-seqs") || !testutil.Contains(args, "128") {
+		t.Errorf("expected --max-num-seqs 128 in args %v", args)
+	}
+}
+
+func TestVllmExtraArgs_RoundTrip(t *testing.T) {
+	var opts backends.VllmServerOptions
+	result, err := opts.ParseCommand("vllm serve microsoft/DialoGPT-medium --new-bool-flag --unknown-flag value")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	args := result.(*backends.VllmServerOptions).BuildCommandArgs()
+
+	if !testutil.Contains(args, "--new-bool-flag") || testutil.Contains(args, "true") {
+		t.Errorf("expected bare --new-bool-flag in args %v", args)
+	}
+	if !testutil.Contains(args, "--unknown-flag") || !testutil.Contains(args, "value") {
+		t.Errorf("expected --unknown-flag value in args %v", args)
+	}
+}
+
 func TestParseVllmCommand_ExtraArgs(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -346,8 +450,8 @@ func TestParseVllmCommand_ExtraArgs(t *testing.T) {
 				if val, ok := opts.ExtraArgs["unknown_flag"]; !ok || val != "value" {
 					t.Errorf("expected extra_args[unknown_flag]='value', got '%s'", val)
 				}
-				if val, ok := opts.ExtraArgs["new_bool_flag"]; !ok || val != "true" {
-					t.Errorf("expected extra_args[new_bool_flag]='true', got '%s'", val)
+				if val, ok := opts.ExtraArgs["new_bool_flag"]; !ok || val != "" {
+					t.Errorf("expected extra_args[new_bool_flag]='', got '%s'", val)
 				}
 			},
 		},
@@ -359,8 +463,8 @@ func TestParseVllmCommand_ExtraArgs(t *testing.T) {
 				if opts.ExtraArgs == nil {
 					t.Fatal("expected extra_args to be non-nil")
 				}
-				if val, ok := opts.ExtraArgs["experimental_feature"]; !ok || val != "true" {
-					t.Errorf("expected extra_args[experimental_feature]='true', got '%s'", val)
+				if val, ok := opts.ExtraArgs["experimental_feature"]; !ok || val != "" {
+					t.Errorf("expected extra_args[experimental_feature]='', got '%s'", val)
 				}
 				if val, ok := opts.ExtraArgs["custom_param"]; !ok || val != "test" {
 					t.Errorf("expected extra_args[custom_param]='test', got '%s'", val)
@@ -381,8 +485,8 @@ func TestParseVllmCommand_ExtraArgs(t *testing.T) {
 				if val, ok := opts.ExtraArgs["new_feature"]; !ok || val != "enabled" {
 					t.Errorf("expected extra_args[new_feature]='enabled', got '%s'", val)
 				}
-				if val, ok := opts.ExtraArgs["beta_flag"]; !ok || val != "true" {
-					t.Errorf("expected extra_args[beta_flag]='true', got '%s'", val)
+				if val, ok := opts.ExtraArgs["beta_flag"]; !ok || val != "" {
+					t.Errorf("expected extra_args[beta_flag]='', got '%s'", val)
 				}
 			},
 		},
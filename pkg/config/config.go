@@ -3,9 +3,12 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"llamactl/pkg/validation"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -24,25 +27,31 @@ func LoadConfig(configPath string) (AppConfig, error) {
 	loadDotEnv(configPath)
 
 	// 3. Load from config file with env var expansion
-	data, err := readConfigFile(configPath)
+	data, path, err := readConfigFile(configPath)
 	if err != nil {
 		return cfg, err
 	}
 	if data != nil {
 		data = expandEnvVars(data)
-		if err := yaml.Unmarshal(data, &cfg); err != nil {
+		if err := unmarshalConfig(path, data, &cfg); err != nil {
 			return cfg, err
 		}
 	}
 
-	// If local node is not defined in nodes, add it with default config
-	if _, ok := cfg.Nodes[cfg.LocalNode]; !ok {
-		cfg.Nodes[cfg.LocalNode] = NodeConfig{}
-	}
-
 	// 4. Override with environment variables
 	loadEnvVars(&cfg)
 
+	// If local node is not defined in nodes, it may be listed under another
+	// name with this server's address; otherwise add it with default config
+	if _, ok := cfg.Nodes[cfg.LocalNode]; !ok {
+		if self := cfg.SelfReferencingNodes(); len(self) == 1 {
+			log.Printf("Node %q points at this server, using it as the local node instead of %q", self[0], cfg.LocalNode)
+			cfg.LocalNode = self[0]
+		} else {
+			cfg.Nodes[cfg.LocalNode] = NodeConfig{}
+		}
+	}
+
 	// Set default directories if not specified
 	if cfg.Instances.LogsDir == "" {
 		cfg.Instances.LogsDir = filepath.Join(cfg.DataDir, "logs")
@@ -54,17 +63,160 @@ func LoadConfig(configPath string) (AppConfig, error) {
 		cfg.Database.Path = filepath.Join(cfg.DataDir, "llamactl.db")
 	}
 
+	cfg.Server.BasePath = normalizeBasePath(cfg.Server.BasePath)
+
+	if cfg.Server.ShutdownDrainPeriod < 0 {
+		return AppConfig{}, fmt.Errorf("invalid server.shutdown_drain_period %s (must not be negative)", cfg.Server.ShutdownDrainPeriod)
+	}
+	if cfg.Proxy.UnavailableRetryAfter < 0 {
+		return AppConfig{}, fmt.Errorf("invalid proxy.unavailable_retry_after %s (must not be negative)", cfg.Proxy.UnavailableRetryAfter)
+	}
+
 	// Validate port range
 	if cfg.Instances.PortRange[0] <= 0 || cfg.Instances.PortRange[1] <= 0 || cfg.Instances.PortRange[0] >= cfg.Instances.PortRange[1] {
 		return AppConfig{}, fmt.Errorf("invalid port range: %v", cfg.Instances.PortRange)
 	}
 
+	// Validate database driver
+	switch cfg.Database.Driver {
+	case DatabaseDriverSQLite:
+	case DatabaseDriverPostgres:
+		if cfg.Database.DSN == "" {
+			return AppConfig{}, fmt.Errorf("database.dsn is required for the %s driver", DatabaseDriverPostgres)
+		}
+	default:
+		return AppConfig{}, fmt.Errorf("invalid database driver %q (expected %q or %q)", cfg.Database.Driver, DatabaseDriverSQLite, DatabaseDriverPostgres)
+	}
+
+	// Validate port allocation strategy
+	switch cfg.Instances.PortAllocation {
+	case PortAllocationSequential, PortAllocationRandom:
+	default:
+		return AppConfig{}, fmt.Errorf("invalid port allocation strategy %q (expected %q or %q)", cfg.Instances.PortAllocation, PortAllocationSequential, PortAllocationRandom)
+	}
+
+	// Validate backend stop signals
+	for _, backend := range []struct {
+		name       string
+		stopSignal string
+	}{
+		{"llama-cpp", cfg.Backends.LlamaCpp.StopSignal},
+		{"vllm", cfg.Backends.VLLM.StopSignal},
+		{"mlx", cfg.Backends.MLX.StopSignal},
+		{"llama-rpc", cfg.Backends.LlamaRpc.StopSignal},
+	} {
+		switch backend.stopSignal {
+		case "", StopSignalSIGINT, StopSignalSIGTERM:
+		default:
+			return AppConfig{}, fmt.Errorf("invalid stop signal %q for %s backend (expected %q or %q)", backend.stopSignal, backend.name, StopSignalSIGINT, StopSignalSIGTERM)
+		}
+	}
+
+	// Validate Docker runtimes, GPUs and volume mounts
+	for _, backend := range []struct {
+		name   string
+		docker *DockerSettings
+	}{
+		{"llama-cpp", cfg.Backends.LlamaCpp.Docker},
+		{"vllm", cfg.Backends.VLLM.Docker},
+	} {
+		if backend.docker == nil {
+			continue
+		}
+		switch backend.docker.Runtime {
+		case "", ContainerRuntimeDocker, ContainerRuntimePodman:
+		default:
+			return AppConfig{}, fmt.Errorf("invalid docker.runtime %q for %s backend (expected %q or %q)", backend.docker.Runtime, backend.name, ContainerRuntimeDocker, ContainerRuntimePodman)
+		}
+		if err := ValidateGPUs(backend.docker.GPUs, backend.docker.GetRuntime()); err != nil {
+			return AppConfig{}, fmt.Errorf("invalid docker.gpus for %s backend: %w", backend.name, err)
+		}
+		if err := ValidateVolumes(backend.docker.Volumes); err != nil {
+			return AppConfig{}, fmt.Errorf("invalid docker.volumes for %s backend: %w", backend.name, err)
+		}
+	}
+
+	switch cfg.Backends.VLLM.Invocation {
+	case "", VllmInvocationServe, VllmInvocationModule:
+	default:
+		return AppConfig{}, fmt.Errorf("invalid vllm invocation %q (expected %q or %q)", cfg.Backends.VLLM.Invocation, VllmInvocationServe, VllmInvocationModule)
+	}
+
+	// Validate log file template with sample values
+	if _, err := ExpandLogFileTemplate(cfg.Logging.FileTemplate, "instance", "llama_cpp", time.Now()); err != nil {
+		return AppConfig{}, fmt.Errorf("invalid logging.file_template: %w", err)
+	}
+
+	// Validate notification format
+	switch cfg.Notifications.Format {
+	case NotificationFormatRaw, NotificationFormatSlack, NotificationFormatDiscord:
+	default:
+		return AppConfig{}, fmt.Errorf("invalid notification format %q (expected %q, %q or %q)", cfg.Notifications.Format, NotificationFormatRaw, NotificationFormatSlack, NotificationFormatDiscord)
+	}
+
+	// Validate chat template library
+	for name, template := range cfg.ChatTemplates {
+		if strings.TrimSpace(name) == "" {
+			return AppConfig{}, fmt.Errorf("chat template name cannot be empty")
+		}
+		if strings.TrimSpace(template) == "" {
+			return AppConfig{}, fmt.Errorf("chat template %q is empty", name)
+		}
+	}
+
+	// Validate and normalize model aliases
+	for name, target := range cfg.ModelAliases {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, "/:@") {
+			return AppConfig{}, fmt.Errorf("invalid model alias %q: names cannot be empty or contain '/', ':' or '@'", name)
+		}
+		ref, err := validation.ParseHFRef(target)
+		if err != nil {
+			return AppConfig{}, fmt.Errorf("invalid model alias %q: %w", name, err)
+		}
+		if ref.Revision != "" {
+			return AppConfig{}, fmt.Errorf("invalid model alias %q: revisions are not supported", name)
+		}
+		cfg.ModelAliases[name] = ref.String()
+	}
+
 	return cfg, nil
 }
 
+// Warnings returns configuration problems that don't prevent startup but
+// are likely to cause failures later on
+func (cfg *AppConfig) Warnings() []string {
+	var warnings []string
+
+	ports := cfg.Instances.PortRange[1] - cfg.Instances.PortRange[0] + 1
+	if cfg.Instances.MaxInstances != -1 && cfg.Instances.MaxInstances > ports {
+		warnings = append(warnings, fmt.Sprintf(
+			"max_instances (%d) is larger than the %d ports in port_range %d-%d; instances beyond that will fail with no available ports",
+			cfg.Instances.MaxInstances, ports, cfg.Instances.PortRange[0], cfg.Instances.PortRange[1]))
+	}
+
+	for _, name := range cfg.SelfReferencingNodes() {
+		warnings = append(warnings, fmt.Sprintf(
+			"node %q (%s) points back at this server, so requests to it would loop; requests to it are refused. Remove it, or set local_node: %q if it is meant to be this server",
+			name, cfg.Nodes[name].Address, name))
+	}
+
+	return warnings
+}
+
+// normalizeBasePath returns the base path with a single leading slash and no
+// trailing slash, so "llamactl/" becomes "/llamactl" and "/" becomes ""
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
 // readConfigFile attempts to read config from file with fallback locations.
-// Returns nil data if no config file is found (not an error).
-func readConfigFile(configPath string) ([]byte, error) {
+// Returns nil data if no config file is found (not an error), otherwise the
+// file contents and the path they were read from.
+func readConfigFile(configPath string) ([]byte, string, error) {
 	var configLocations []string
 
 	if configPath != "" {
@@ -76,14 +228,96 @@ func readConfigFile(configPath string) ([]byte, error) {
 	for _, path := range configLocations {
 		if data, err := os.ReadFile(path); err == nil {
 			log.Printf("Read config at %s", path)
-			return data, nil
+			return data, path, nil
 		}
 	}
 
-	return nil, nil
+	return nil, "", nil
+}
+
+// unmarshalConfig decodes a config file as JSON when it has a .json extension
+// and as YAML otherwise. JSON is decoded through the YAML decoder too, since
+// YAML is a superset of JSON and only it understands string durations like
+// "30s"; nanosecond integers, as returned by GET /config, fall back to the
+// JSON decoder.
+func unmarshalConfig(path string, data []byte, cfg *AppConfig) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if !json.Valid(data) {
+			var v any
+			err := json.Unmarshal(data, &v)
+			return fmt.Errorf("failed to parse JSON config %s: %w", path, err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			if jsonErr := json.Unmarshal(data, cfg); jsonErr != nil {
+				return fmt.Errorf("failed to parse JSON config %s: %w", path, err)
+			}
+		}
+		return nil
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("failed to parse YAML config %s: %w", path, err)
+	}
+	return nil
+}
+
+// RedactedValue replaces secret values in sanitized config output
+const RedactedValue = "[REDACTED]"
+
+// sensitiveNameParts mark environment variables and command-line flags whose
+// values are treated as secrets
+var sensitiveNameParts = []string{"key", "token", "secret", "password", "credential"}
+
+// IsSensitiveName reports whether an environment variable or flag name
+// looks like it holds a secret
+func IsSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range sensitiveNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
 }
 
-// SanitizedCopy returns a copy of the AppConfig with sensitive information removed
+// redactEnvironment redacts values of sensitive-looking variables in place
+func redactEnvironment(env map[string]string) {
+	for k := range env {
+		if IsSensitiveName(k) {
+			env[k] = RedactedValue
+		}
+	}
+}
+
+// RedactArgs redacts the values of sensitive-looking flags in place, in both
+// "--flag value" and "--flag=value" form, as well as sensitive variables
+// passed to a container as "-e NAME=value"
+func RedactArgs(args []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if (arg == "-e" || arg == "--env") && i+1 < len(args) {
+			if name, _, ok := strings.Cut(args[i+1], "="); ok && IsSensitiveName(name) {
+				args[i+1] = name + "=" + RedactedValue
+			}
+			i++
+			continue
+		}
+		if flag, _, ok := strings.Cut(arg, "="); ok {
+			if IsSensitiveName(flag) {
+				args[i] = flag + "=" + RedactedValue
+			}
+			continue
+		}
+		if IsSensitiveName(arg) && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			args[i+1] = RedactedValue
+			i++
+		}
+	}
+}
+
+// SanitizedCopy returns a copy of the AppConfig with sensitive information redacted
 func (cfg *AppConfig) SanitizedCopy() (AppConfig, error) {
 	// Deep copy via JSON marshal/unmarshal to avoid concurrent map access
 	data, err := json.Marshal(cfg)
@@ -98,14 +332,38 @@ func (cfg *AppConfig) SanitizedCopy() (AppConfig, error) {
 		return AppConfig{}, err
 	}
 
-	// Clear sensitive information
-	sanitized.Auth.ManagementKeys = []string{}
+	// Replace secrets with a marker rather than dropping them, so operators
+	// can still see which ones were resolved
+	for i := range sanitized.Auth.ManagementKeys {
+		sanitized.Auth.ManagementKeys[i] = RedactedValue
+	}
+
+	// A Postgres DSN usually carries the password
+	if sanitized.Database.DSN != "" {
+		sanitized.Database.DSN = RedactedValue
+	}
+
+	// Webhook URLs commonly embed a secret token
+	if sanitized.Notifications.WebhookURL != "" {
+		sanitized.Notifications.WebhookURL = RedactedValue
+	}
 
-	// Clear API keys from nodes
 	for nodeName, node := range sanitized.Nodes {
-		node.APIKey = ""
+		if node.APIKey != "" {
+			node.APIKey = RedactedValue
+		}
 		sanitized.Nodes[nodeName] = node
 	}
 
+	// Backend tokens (e.g. HF_TOKEN) usually arrive via environment or args
+	for _, backend := range []*BackendSettings{&sanitized.Backends.LlamaCpp, &sanitized.Backends.VLLM, &sanitized.Backends.MLX, &sanitized.Backends.LlamaRpc} {
+		redactEnvironment(backend.Environment)
+		RedactArgs(backend.Args)
+		if backend.Docker != nil {
+			redactEnvironment(backend.Docker.Environment)
+			RedactArgs(backend.Docker.Args)
+		}
+	}
+
 	return sanitized, nil
 }
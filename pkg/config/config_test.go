@@ -4,7 +4,9 @@ import (
 	"llamactl/pkg/config"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 // GetBackendSettings resolves backend settings
@@ -123,6 +125,108 @@ instances:
 	}
 }
 
+func TestLoadConfig_FromJSONFile(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "test-config.json")
+
+	configContent := `{
+  "server": {"host": "localhost", "port": 9090},
+  "backends": {"llama-cpp": {"command": "/opt/llama-server"}},
+  "instances": {
+    "port_range": [7000, 8000],
+    "max_instances": 5,
+    "log_rotation_max_size": 50
+  }
+}`
+
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Server.Host != "localhost" || cfg.Server.Port != 9090 {
+		t.Errorf("Expected server localhost:9090, got %s:%d", cfg.Server.Host, cfg.Server.Port)
+	}
+	if cfg.Backends.LlamaCpp.Command != "/opt/llama-server" {
+		t.Errorf("Expected llama-cpp command '/opt/llama-server', got %q", cfg.Backends.LlamaCpp.Command)
+	}
+	if cfg.Instances.PortRange != [2]int{7000, 8000} {
+		t.Errorf("Expected port range [7000, 8000], got %v", cfg.Instances.PortRange)
+	}
+	if cfg.Instances.MaxInstances != 5 {
+		t.Errorf("Expected max instances 5, got %d", cfg.Instances.MaxInstances)
+	}
+	if cfg.Instances.LogRotationMaxSize != 50 {
+		t.Errorf("Expected log rotation max size 50, got %d", cfg.Instances.LogRotationMaxSize)
+	}
+	// Defaults still apply to fields the JSON file leaves out
+	if cfg.Server.AllowedOrigins == nil {
+		t.Error("Expected default allowed origins to be kept")
+	}
+
+	// A .json file is always parsed as JSON, even when it holds YAML
+	badFile := filepath.Join(tempDir, "bad.json")
+	if err := os.WriteFile(badFile, []byte("server:\n  port: 9090\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+	if _, err := config.LoadConfig(badFile); err == nil {
+		t.Error("Expected error for YAML content in a .json config file")
+	}
+}
+
+func TestLoadConfig_JSONDurations(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "config.json")
+
+	// Durations in their documented string form, tab-indented as many
+	// editors write JSON
+	configContent := "{\n\t\"server\": {\"shutdown_drain_period\": \"30s\"},\n" +
+		"\t\"backends\": {\"llama-cpp\": {\"download_timeout\": \"1h\"}},\n" +
+		"\t\"database\": {\"connection_max_lifetime\": \"2h\"},\n" +
+		"\t\"proxy\": {\"response_header_timeout\": \"45s\", \"unavailable_retry_after\": \"10s\"}\n}"
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Server.ShutdownDrainPeriod != 30*time.Second {
+		t.Errorf("Expected shutdown drain period 30s, got %s", cfg.Server.ShutdownDrainPeriod)
+	}
+	if cfg.Backends.LlamaCpp.DownloadTimeout != time.Hour {
+		t.Errorf("Expected download timeout 1h, got %s", cfg.Backends.LlamaCpp.DownloadTimeout)
+	}
+	if cfg.Database.ConnMaxLifetime != 2*time.Hour {
+		t.Errorf("Expected connection max lifetime 2h, got %s", cfg.Database.ConnMaxLifetime)
+	}
+	if cfg.Proxy.ResponseHeaderTimeout != 45*time.Second {
+		t.Errorf("Expected response header timeout 45s, got %s", cfg.Proxy.ResponseHeaderTimeout)
+	}
+	if cfg.Proxy.UnavailableRetryAfter != 10*time.Second {
+		t.Errorf("Expected unavailable retry after 10s, got %s", cfg.Proxy.UnavailableRetryAfter)
+	}
+
+	// Nanosecond integers, as GET /config returns them, are still accepted
+	numericFile := filepath.Join(tempDir, "numeric.json")
+	if err := os.WriteFile(numericFile, []byte(`{"server": {"shutdown_drain_period": 30000000000}}`), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+	cfg, err = config.LoadConfig(numericFile)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Server.ShutdownDrainPeriod != 30*time.Second {
+		t.Errorf("Expected shutdown drain period 30s, got %s", cfg.Server.ShutdownDrainPeriod)
+	}
+}
+
 func TestLoadConfig_EnvironmentOverrides(t *testing.T) {
 	// Set environment variables
 	envVars := map[string]string{
@@ -223,6 +327,341 @@ instances:
 	}
 }
 
+func TestLoadConfig_DatabaseDriver(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  string
+		dsn     string
+		wantErr bool
+	}{
+		{"sqlite", "sqlite", "", false},
+		{"postgres with dsn", "postgres", "postgres://llamactl@db/llamactl", false},
+		{"postgres without dsn", "postgres", "", true},
+		{"unknown driver", "mysql", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LLAMACTL_DATABASE_DRIVER", tt.driver)
+			t.Setenv("LLAMACTL_DATABASE_DSN", tt.dsn)
+
+			cfg, err := config.LoadConfig("nonexistent-file.yaml")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if cfg.Database.Driver != tt.driver {
+				t.Errorf("Expected driver %q, got %q", tt.driver, cfg.Database.Driver)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_BasePath(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"/", ""},
+		{"llamactl", "/llamactl"},
+		{"/llamactl/", "/llamactl"},
+		{"/tools/llamactl", "/tools/llamactl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("LLAMACTL_BASE_PATH", tt.value)
+
+			cfg, err := config.LoadConfig("nonexistent-file.yaml")
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if cfg.Server.BasePath != tt.want {
+				t.Errorf("Expected base path %q, got %q", tt.want, cfg.Server.BasePath)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_ShutdownDrainPeriod(t *testing.T) {
+	t.Setenv("LLAMACTL_SHUTDOWN_DRAIN_PERIOD", "15s")
+	cfg, err := config.LoadConfig("nonexistent-file.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Server.ShutdownDrainPeriod != 15*time.Second {
+		t.Errorf("Expected drain period 15s, got %s", cfg.Server.ShutdownDrainPeriod)
+	}
+
+	t.Setenv("LLAMACTL_SHUTDOWN_DRAIN_PERIOD", "-1s")
+	if _, err := config.LoadConfig("nonexistent-file.yaml"); err == nil {
+		t.Error("Expected an error for a negative drain period")
+	}
+}
+
+func TestLoadConfig_PortAllocation(t *testing.T) {
+	t.Run("defaults to sequential", func(t *testing.T) {
+		cfg, err := config.LoadConfig("nonexistent-file.yaml")
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		if cfg.Instances.PortAllocation != config.PortAllocationSequential {
+			t.Errorf("Expected port allocation %q, got %q", config.PortAllocationSequential, cfg.Instances.PortAllocation)
+		}
+	})
+
+	t.Run("environment override", func(t *testing.T) {
+		t.Setenv("LLAMACTL_PORT_ALLOCATION", "random")
+		cfg, err := config.LoadConfig("nonexistent-file.yaml")
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		if cfg.Instances.PortAllocation != config.PortAllocationRandom {
+			t.Errorf("Expected port allocation %q, got %q", config.PortAllocationRandom, cfg.Instances.PortAllocation)
+		}
+	})
+
+	t.Run("rejects unknown strategy", func(t *testing.T) {
+		t.Setenv("LLAMACTL_PORT_ALLOCATION", "round-robin")
+		if _, err := config.LoadConfig("nonexistent-file.yaml"); err == nil {
+			t.Error("Expected error for unknown port allocation strategy")
+		}
+	})
+}
+
+func TestLoadConfig_VllmInvocation(t *testing.T) {
+	t.Run("environment override", func(t *testing.T) {
+		t.Setenv("LLAMACTL_VLLM_INVOCATION", "module")
+		cfg, err := config.LoadConfig("nonexistent-file.yaml")
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		if cfg.Backends.VLLM.Invocation != config.VllmInvocationModule {
+			t.Errorf("Expected vllm invocation %q, got %q", config.VllmInvocationModule, cfg.Backends.VLLM.Invocation)
+		}
+	})
+
+	t.Run("rejects unknown style", func(t *testing.T) {
+		t.Setenv("LLAMACTL_VLLM_INVOCATION", "docker")
+		if _, err := config.LoadConfig("nonexistent-file.yaml"); err == nil {
+			t.Error("Expected error for unknown vllm invocation")
+		}
+	})
+}
+
+func TestAppConfigWarnings_PortRangeVsMaxInstances(t *testing.T) {
+	tests := []struct {
+		name         string
+		portRange    [2]int
+		maxInstances int
+		wantWarning  bool
+	}{
+		{"unlimited instances", [2]int{8000, 8009}, -1, false},
+		{"fits in range", [2]int{8000, 8009}, 10, false},
+		{"exceeds range", [2]int{8000, 8009}, 11, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.AppConfig{
+				Instances: config.InstancesConfig{PortRange: tt.portRange, MaxInstances: tt.maxInstances},
+			}
+			warnings := cfg.Warnings()
+			if got := len(warnings) > 0; got != tt.wantWarning {
+				t.Errorf("Warnings() = %v, want warning: %v", warnings, tt.wantWarning)
+			}
+			if tt.wantWarning && !strings.Contains(warnings[0], "max_instances (11)") {
+				t.Errorf("Expected warning to name max_instances, got %q", warnings[0])
+			}
+		})
+	}
+}
+
+func TestLoadConfig_NotificationFormat(t *testing.T) {
+	t.Run("defaults to raw", func(t *testing.T) {
+		cfg, err := config.LoadConfig("nonexistent-file.yaml")
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		if cfg.Notifications.Format != config.NotificationFormatRaw {
+			t.Errorf("Expected notification format %q, got %q", config.NotificationFormatRaw, cfg.Notifications.Format)
+		}
+	})
+
+	t.Run("environment override", func(t *testing.T) {
+		t.Setenv("LLAMACTL_NOTIFICATIONS_FORMAT", "slack")
+		cfg, err := config.LoadConfig("nonexistent-file.yaml")
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		if cfg.Notifications.Format != config.NotificationFormatSlack {
+			t.Errorf("Expected notification format %q, got %q", config.NotificationFormatSlack, cfg.Notifications.Format)
+		}
+	})
+
+	t.Run("rejects unknown format", func(t *testing.T) {
+		t.Setenv("LLAMACTL_NOTIFICATIONS_FORMAT", "teams")
+		if _, err := config.LoadConfig("nonexistent-file.yaml"); err == nil {
+			t.Error("Expected error for unknown notification format")
+		}
+	})
+}
+
+func TestLoadConfig_StopSignal(t *testing.T) {
+	t.Run("environment override", func(t *testing.T) {
+		t.Setenv("LLAMACTL_VLLM_STOP_SIGNAL", "SIGINT")
+		cfg, err := config.LoadConfig("nonexistent-file.yaml")
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		if cfg.Backends.VLLM.StopSignal != config.StopSignalSIGINT {
+			t.Errorf("Expected vllm stop signal %q, got %q", config.StopSignalSIGINT, cfg.Backends.VLLM.StopSignal)
+		}
+	})
+
+	t.Run("rejects unknown signal", func(t *testing.T) {
+		t.Setenv("LLAMACTL_LLAMACPP_STOP_SIGNAL", "SIGKILL")
+		if _, err := config.LoadConfig("nonexistent-file.yaml"); err == nil {
+			t.Error("Expected error for unknown stop signal")
+		}
+	})
+}
+
+func TestLoadConfig_DockerRuntime(t *testing.T) {
+	t.Run("environment override", func(t *testing.T) {
+		t.Setenv("LLAMACTL_LLAMACPP_DOCKER_RUNTIME", "podman")
+		cfg, err := config.LoadConfig("nonexistent-file.yaml")
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		if got := cfg.Backends.LlamaCpp.Docker.GetRuntime(); got != config.ContainerRuntimePodman {
+			t.Errorf("Expected runtime %q, got %q", config.ContainerRuntimePodman, got)
+		}
+	})
+
+	t.Run("rejects unknown runtime", func(t *testing.T) {
+		t.Setenv("LLAMACTL_VLLM_DOCKER_RUNTIME", "nerdctl")
+		if _, err := config.LoadConfig("nonexistent-file.yaml"); err == nil {
+			t.Error("Expected error for unknown docker runtime")
+		}
+	})
+}
+
+func TestLoadConfig_DockerVolumes(t *testing.T) {
+	tests := []struct {
+		name    string
+		volumes string
+		wantErr bool
+	}{
+		{"host path", "/models:/models", false},
+		{"named volume with options", "hf-cache:/root/.cache/huggingface:ro,z /models:/models", false},
+		{"missing container path", "/models", true},
+		{"relative container path", "/models:models", true},
+		{"empty host", ":/models", true},
+		{"unknown option", "/models:/models:readonly", true},
+		{"duplicate container path", "/a:/models /b:/models/", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LLAMACTL_LLAMACPP_DOCKER_VOLUMES", tt.volumes)
+			cfg, err := config.LoadConfig("nonexistent-file.yaml")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for volumes %q", tt.volumes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if got := strings.Join(cfg.Backends.LlamaCpp.Docker.Volumes, " "); got != tt.volumes {
+				t.Errorf("Expected volumes %q, got %q", tt.volumes, got)
+			}
+		})
+	}
+}
+
+func TestValidateGPUs(t *testing.T) {
+	tests := []struct {
+		gpus    string
+		runtime string
+		wantErr bool
+	}{
+		{"", config.ContainerRuntimeDocker, false},
+		{"all", config.ContainerRuntimeDocker, false},
+		{"2", config.ContainerRuntimeDocker, false},
+		{"device=0,1", config.ContainerRuntimeDocker, false},
+		{"device=GPU-3a23c669", config.ContainerRuntimePodman, false},
+		{"0", config.ContainerRuntimeDocker, true},
+		{"some", config.ContainerRuntimeDocker, true},
+		{"device=0,", config.ContainerRuntimeDocker, true},
+		{"2", config.ContainerRuntimePodman, true},
+	}
+
+	for _, tt := range tests {
+		err := config.ValidateGPUs(tt.gpus, tt.runtime)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateGPUs(%q, %q) error = %v, wantErr %v", tt.gpus, tt.runtime, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExpandLogFileTemplate(t *testing.T) {
+	now := time.Date(2026, 3, 14, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		template string
+		expected string
+		wantErr  bool
+	}{
+		{"empty uses default", "", "llama.log", false},
+		{"default", "{name}.log", "llama.log", false},
+		{"backend directory", "{backend}/{name}.log", filepath.Join("llama_cpp", "llama.log"), false},
+		{"date", "{date}/{name}-{backend}.log", filepath.Join("2026-03-14", "llama-llama_cpp.log"), false},
+		{"unknown placeholder", "{host}/{name}.log", "", true},
+		{"parent escape", "../{name}.log", "", true},
+		{"nested escape", "{backend}/../../{name}.log", "", true},
+		{"absolute path", "/var/log/{name}.log", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := config.ExpandLogFileTemplate(tt.template, "llama", "llama_cpp", now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for template %q, got %q", tt.template, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandLogFileTemplate failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	// A name made of dots must not be able to climb out either
+	if _, err := config.ExpandLogFileTemplate("{name}/x.log", "..", "llama_cpp", now); err == nil {
+		t.Error("Expected error for instance name escaping the logs directory")
+	}
+}
+
+func TestLoadConfig_RejectsEscapingLogFileTemplate(t *testing.T) {
+	t.Setenv("LLAMACTL_LOGGING_FILE_TEMPLATE", "../{name}.log")
+	if _, err := config.LoadConfig("nonexistent-file.yaml"); err == nil {
+		t.Error("Expected error for log file template outside the logs directory")
+	}
+}
+
 func TestParsePortRange(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -316,6 +755,7 @@ func TestLoadConfig_BackendEnvironmentVariables(t *testing.T) {
 		"LLAMACTL_LLAMACPP_DOCKER_IMAGE":   "env-llama:latest",
 		"LLAMACTL_LLAMACPP_DOCKER_ARGS":    "run --rm --network host --gpus all",
 		"LLAMACTL_LLAMACPP_DOCKER_ENV":     "CUDA_VISIBLE_DEVICES=0,OMP_NUM_THREADS=4",
+		"LLAMACTL_LLAMACPP_DOCKER_RUNTIME": "podman",
 		"LLAMACTL_VLLM_COMMAND":            "env-vllm",
 		"LLAMACTL_VLLM_DOCKER_ENABLED":     "false",
 		"LLAMACTL_VLLM_DOCKER_IMAGE":       "env-vllm:latest",
@@ -348,6 +788,9 @@ func TestLoadConfig_BackendEnvironmentVariables(t *testing.T) {
 	if cfg.Backends.LlamaCpp.Docker.Image != "env-llama:latest" {
 		t.Errorf("Expected llama Docker image 'env-llama:latest', got %q", cfg.Backends.LlamaCpp.Docker.Image)
 	}
+	if cfg.Backends.LlamaCpp.Docker.Runtime != "podman" {
+		t.Errorf("Expected llama Docker runtime 'podman', got %q", cfg.Backends.LlamaCpp.Docker.Runtime)
+	}
 	expectedDockerArgs := []string{"run", "--rm", "--network", "host", "--gpus", "all"}
 	if len(cfg.Backends.LlamaCpp.Docker.Args) != len(expectedDockerArgs) {
 		t.Errorf("Expected llama Docker args %v, got %v", expectedDockerArgs, cfg.Backends.LlamaCpp.Docker.Args)
@@ -505,6 +948,143 @@ nodes:
 	})
 }
 
+func TestAppConfig_PointsToSelf(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		address string
+		want    bool
+	}{
+		{"localhost on all interfaces", "", "http://localhost:8080", true},
+		{"loopback ip on all interfaces", "0.0.0.0", "http://127.0.0.1:8080", true},
+		{"ipv6 loopback", "::", "http://[::1]:8080", true},
+		{"same bind host", "10.0.0.5", "http://10.0.0.5:8080", true},
+		{"loopback aliases", "127.0.0.1", "http://localhost:8080", true},
+		{"different port", "", "http://localhost:9090", false},
+		{"default http port", "", "http://localhost", false},
+		{"other host", "", "http://192.0.2.10:8080", false},
+		{"bound elsewhere", "10.0.0.5", "http://localhost:8080", false},
+		{"invalid address", "", "://nope", false},
+	}
+
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		tests = append(tests, struct {
+			name    string
+			host    string
+			address string
+			want    bool
+		}{"own hostname on all interfaces", "", "http://" + hostname + ":8080", true})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.AppConfig{Server: config.ServerConfig{Host: tt.host, Port: 8080}}
+			if got := cfg.PointsToSelf(tt.address); got != tt.want {
+				t.Errorf("PointsToSelf(%q) with host %q = %v, want %v", tt.address, tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_SelfReferencingNode(t *testing.T) {
+	writeConfig := func(t *testing.T, content string) string {
+		t.Helper()
+		configFile := filepath.Join(t.TempDir(), "test-config.yaml")
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test config file: %v", err)
+		}
+		return configFile
+	}
+
+	t.Run("adopted as local node", func(t *testing.T) {
+		cfg, err := config.LoadConfig(writeConfig(t, `
+server:
+  port: 8080
+nodes:
+  gpu-box:
+    address: "http://localhost:8080"
+  worker:
+    address: "http://192.0.2.10:8080"
+`))
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+
+		if cfg.LocalNode != "gpu-box" {
+			t.Errorf("Expected local node 'gpu-box', got %q", cfg.LocalNode)
+		}
+		if _, exists := cfg.Nodes["main"]; exists {
+			t.Error("Default 'main' node should not be added when a node points at this server")
+		}
+		if warnings := cfg.Warnings(); len(warnings) != 0 {
+			t.Errorf("Expected no warnings, got %v", warnings)
+		}
+	})
+
+	t.Run("warned when local node is explicit", func(t *testing.T) {
+		cfg, err := config.LoadConfig(writeConfig(t, `
+server:
+  port: 8080
+local_node: main
+nodes:
+  main: {}
+  loop:
+    address: "http://127.0.0.1:8080"
+`))
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+
+		if cfg.LocalNode != "main" {
+			t.Errorf("Expected local node 'main', got %q", cfg.LocalNode)
+		}
+		if got := cfg.SelfReferencingNodes(); len(got) != 1 || got[0] != "loop" {
+			t.Errorf("SelfReferencingNodes() = %v, want [loop]", got)
+		}
+		warnings := cfg.Warnings()
+		if len(warnings) != 1 || !strings.Contains(warnings[0], `node "loop"`) {
+			t.Errorf("Expected a warning naming node 'loop', got %v", warnings)
+		}
+	})
+}
+
+func TestLoadConfig_ModelAliases(t *testing.T) {
+	load := func(t *testing.T, content string) (config.AppConfig, error) {
+		t.Helper()
+		configFile := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test config file: %v", err)
+		}
+		return config.LoadConfig(configFile)
+	}
+
+	t.Run("normalizes targets", func(t *testing.T) {
+		cfg, err := load(t, `
+model_aliases:
+  qwen-coder: "https://huggingface.co/Qwen/Qwen2.5-Coder-7B-Instruct-GGUF:Q4_K_M"
+`)
+		if err != nil {
+			t.Fatalf("LoadConfig failed: %v", err)
+		}
+		if got := cfg.ModelAliases["qwen-coder"]; got != "Qwen/Qwen2.5-Coder-7B-Instruct-GGUF:Q4_K_M" {
+			t.Errorf("alias target = %q, want normalized reference", got)
+		}
+	})
+
+	for name, content := range map[string]string{
+		"invalid target":   "model_aliases:\n  coder: not-a-repo\n",
+		"pinned revision":  "model_aliases:\n  coder: org/model@main\n",
+		"name with slash":  "model_aliases:\n  org/coder: org/model\n",
+		"empty alias name": "model_aliases:\n  \"\": org/model\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := load(t, content); err == nil {
+				t.Error("expected LoadConfig to fail")
+			}
+		})
+	}
+}
+
 func TestLoadConfig_DotEnvAndExpansion(t *testing.T) {
 	tempDir := t.TempDir()
 
@@ -583,3 +1163,107 @@ server:
 		t.Errorf("Expected process env to take precedence, got %q", cfg.Server.Host)
 	}
 }
+
+func TestSanitizedCopy_RedactsSecrets(t *testing.T) {
+	cfg := config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Args:        []string{"--api-key", "sk-backend", "--hf-token=hf_abc", "--threads", "4"},
+				Environment: map[string]string{"HF_TOKEN": "hf_abc", "CUDA_VISIBLE_DEVICES": "0"},
+				Docker: &config.DockerSettings{
+					Args: []string{"run", "--rm", "-e", "HF_TOKEN=hf_abc", "--env", "LOG_LEVEL=debug"},
+				},
+			},
+		},
+		Auth:     config.AuthConfig{ManagementKeys: []string{"sk-management"}},
+		Database: config.DatabaseConfig{DSN: "postgres://llamactl:secret@db/llamactl"},
+		Nodes: map[string]config.NodeConfig{
+			"worker": {Address: "http://worker:8080", APIKey: "sk-node"},
+		},
+	}
+
+	sanitized, err := cfg.SanitizedCopy()
+	if err != nil {
+		t.Fatalf("SanitizedCopy failed: %v", err)
+	}
+
+	if len(sanitized.Auth.ManagementKeys) != 1 || sanitized.Auth.ManagementKeys[0] != config.RedactedValue {
+		t.Errorf("Expected management key to be redacted, got %v", sanitized.Auth.ManagementKeys)
+	}
+	if got := sanitized.Nodes["worker"].APIKey; got != config.RedactedValue {
+		t.Errorf("Expected node API key to be redacted, got %q", got)
+	}
+	if sanitized.Database.DSN != config.RedactedValue {
+		t.Errorf("Expected database DSN to be redacted, got %q", sanitized.Database.DSN)
+	}
+
+	llama := sanitized.Backends.LlamaCpp
+	wantArgs := []string{"--api-key", config.RedactedValue, "--hf-token=" + config.RedactedValue, "--threads", "4"}
+	if strings.Join(llama.Args, " ") != strings.Join(wantArgs, " ") {
+		t.Errorf("Expected args %v, got %v", wantArgs, llama.Args)
+	}
+	if llama.Environment["HF_TOKEN"] != config.RedactedValue {
+		t.Errorf("Expected HF_TOKEN to be redacted, got %q", llama.Environment["HF_TOKEN"])
+	}
+	wantDockerArgs := []string{"run", "--rm", "-e", "HF_TOKEN=" + config.RedactedValue, "--env", "LOG_LEVEL=debug"}
+	if strings.Join(llama.Docker.Args, " ") != strings.Join(wantDockerArgs, " ") {
+		t.Errorf("Expected docker args %v, got %v", wantDockerArgs, llama.Docker.Args)
+	}
+	if llama.Environment["CUDA_VISIBLE_DEVICES"] != "0" {
+		t.Errorf("Expected non-secret variable to be kept, got %q", llama.Environment["CUDA_VISIBLE_DEVICES"])
+	}
+
+	// The original config must be left untouched
+	if cfg.Auth.ManagementKeys[0] != "sk-management" || cfg.Backends.LlamaCpp.Args[1] != "sk-backend" {
+		t.Error("SanitizedCopy modified the original config")
+	}
+}
+
+func TestEnvVars_ListsEffectiveValues(t *testing.T) {
+	t.Setenv("LLAMACTL_PORT", "3000")
+	t.Setenv("LLAMACTL_MANAGEMENT_KEYS", "sk-secret")
+	t.Setenv("LLAMACTL_GROUP_LIMITS", "gpu=2, cpu=1")
+
+	cfg, err := config.LoadConfig("nonexistent-file.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Instances.GroupLimits["gpu"] != 2 || cfg.Instances.GroupLimits["cpu"] != 1 {
+		t.Errorf("Expected group limits from environment, got %v", cfg.Instances.GroupLimits)
+	}
+
+	envVars, err := cfg.EnvVars()
+	if err != nil {
+		t.Fatalf("EnvVars failed: %v", err)
+	}
+
+	byName := make(map[string]config.EnvVarInfo, len(envVars))
+	for _, v := range envVars {
+		byName[v.Name] = v
+	}
+
+	port, ok := byName["LLAMACTL_PORT"]
+	if !ok {
+		t.Fatal("Expected LLAMACTL_PORT to be listed")
+	}
+	if port.ConfigPath != "server.port" || !port.Set || port.Value != float64(3000) {
+		t.Errorf("Unexpected LLAMACTL_PORT entry: %+v", port)
+	}
+
+	keys := byName["LLAMACTL_MANAGEMENT_KEYS"]
+	if values, ok := keys.Value.([]any); !ok || len(values) != 1 || values[0] != config.RedactedValue {
+		t.Errorf("Expected management keys to be redacted, got %v", keys.Value)
+	}
+
+	if host := byName["LLAMACTL_HOST"]; host.Set || host.Value != cfg.Server.Host {
+		t.Errorf("Expected unset LLAMACTL_HOST with default value, got %+v", host)
+	}
+
+	if _, ok := byName["LLAMACTL_VLLM_DOCKER_IMAGE"]; !ok {
+		t.Error("Expected backend docker variables to be listed")
+	}
+	if _, ok := byName["LLAMACTL_MLX_DOCKER_IMAGE"]; ok {
+		t.Error("MLX backend has no docker variables")
+	}
+}
@@ -26,8 +26,10 @@ func getDefaultConfig(dataDir string) AppConfig {
 				Environment:     map[string]string{},
 				CacheDir:        getDefaultLlamaCacheDir(),
 				DownloadTimeout: 3600 * time.Second,
+				StopSignal:      StopSignalSIGINT,
 				Docker: &DockerSettings{
 					Enabled: false,
+					Runtime: ContainerRuntimeDocker,
 					Image:   "ghcr.io/ggml-org/llama.cpp:server",
 					Args: []string{
 						"run", "--rm", "--network", "host", "--gpus", "all",
@@ -36,10 +38,12 @@ func getDefaultConfig(dataDir string) AppConfig {
 				},
 			},
 			VLLM: BackendSettings{
-				Command: "vllm",
-				Args:    []string{"serve"},
+				Command:    "vllm",
+				Args:       []string{"serve"},
+				StopSignal: StopSignalSIGTERM,
 				Docker: &DockerSettings{
 					Enabled: false,
+					Runtime: ContainerRuntimeDocker,
 					Image:   "vllm/vllm-openai:latest",
 					Args: []string{
 						"run", "--rm", "--network", "host", "--gpus", "all", "--shm-size", "1g",
@@ -49,42 +53,68 @@ func getDefaultConfig(dataDir string) AppConfig {
 				},
 			},
 			MLX: BackendSettings{
-				Command: "mlx_lm.server",
-				Args:    []string{},
+				Command:    "mlx_lm.server",
+				Args:       []string{},
+				StopSignal: StopSignalSIGINT,
 				// No Docker section for MLX - not supported
 			},
+			LlamaRpc: BackendSettings{
+				Command:    "rpc-server",
+				Args:       []string{},
+				StopSignal: StopSignalSIGINT,
+			},
 		},
 		Instances: InstancesConfig{
-			PortRange:            [2]int{8000, 9000},
-			AutoCreateDirs:       true,
-			MaxInstances:         -1, // -1 means unlimited
-			MaxRunningInstances:  -1, // -1 means unlimited
-			GroupLimits:          map[string]int{},
-			EnableLRUEviction:    true,
-			DefaultIdleTimeout:   30, // Default idle timeout of 30 minutes
-			DefaultAutoRestart:   true,
-			DefaultMaxRestarts:   3,
-			DefaultRestartDelay:  5,
-			DefaultOnDemandStart: true,
-			OnDemandStartTimeout: 120, // 2 minutes
-			TimeoutCheckInterval: 5,   // Check timeouts every 5 minutes
-			LogsDir:              "",  // Will be set to data_dir/logs if empty
-			InstancesDir:         "",  // Will be set to data_dir/instances if empty
-			LogRotationEnabled:   true,
-			LogRotationMaxSize:   100,
-			LogRotationCompress:  false,
+			PortRange:             [2]int{8000, 9000},
+			PortAllocation:        PortAllocationSequential,
+			AutoCreateDirs:        true,
+			MaxInstances:          -1, // -1 means unlimited
+			MaxRunningInstances:   -1, // -1 means unlimited
+			GroupLimits:           map[string]int{},
+			EnableLRUEviction:     true,
+			DefaultIdleTimeout:    30, // Default idle timeout of 30 minutes
+			DefaultAutoRestart:    true,
+			DefaultMaxRestarts:    3,
+			DefaultRestartDelay:   5,
+			DefaultOnDemandStart:  true,
+			OnDemandStartTimeout:  120, // 2 minutes
+			ShutdownParallelism:   4,
+			CleanupOrphansOnStart: false,
+			TimeoutCheckInterval:  5,  // Check timeouts every 5 minutes
+			LogsDir:               "", // Will be set to data_dir/logs if empty
+			InstancesDir:          "", // Will be set to data_dir/instances if empty
+			LogRotationEnabled:    true,
+			LogRotationMaxSize:    100,
+			LogRotationCompress:   false,
 		},
 		Database: DatabaseConfig{
+			Driver:             DatabaseDriverSQLite,
 			Path:               "", // Will be set to data_dir/llamactl.db if empty
 			MaxOpenConnections: 25,
 			MaxIdleConnections: 5,
 			ConnMaxLifetime:    5 * time.Minute,
 		},
+		Proxy: ProxyConfig{
+			ConnectTimeout:        10 * time.Second,
+			ResponseHeaderTimeout: 0, // Prompt processing can take minutes
+			IdleConnTimeout:       90 * time.Second,
+			StreamTimeout:         0, // Long generations are streamed for as long as they run
+			UnavailableRetryAfter: 5 * time.Second,
+			MaxIdleConnsPerHost:   64,
+			DisableKeepAlives:     false,
+		},
 		Auth: AuthConfig{
 			RequireInferenceAuth:  true,
 			RequireManagementAuth: true,
 			ManagementKeys:        []string{},
 		},
+		Logging: LoggingConfig{
+			FileTemplate:  DefaultLogFileTemplate,
+			MaxLineLength: 16384,
+		},
+		Notifications: NotificationsConfig{
+			Format: NotificationFormatRaw,
+		},
 	}
 }
 
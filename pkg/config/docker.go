@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ValidateGPUs checks a docker.gpus value: empty, "all", a positive count,
+// or "device=" followed by comma-separated device IDs or UUIDs. Podman's CDI
+// devices have no notion of a count.
+func ValidateGPUs(gpus, runtime string) error {
+	switch {
+	case gpus == "" || gpus == "all":
+		return nil
+	case strings.HasPrefix(gpus, "device="):
+		for _, id := range strings.Split(strings.TrimPrefix(gpus, "device="), ",") {
+			if strings.TrimSpace(id) == "" {
+				return fmt.Errorf("gpus %q has an empty device ID", gpus)
+			}
+		}
+		return nil
+	}
+	if n, err := strconv.Atoi(gpus); err != nil || n <= 0 {
+		return fmt.Errorf("gpus %q must be \"all\", a positive count or \"device=<ids>\"", gpus)
+	}
+	if runtime == ContainerRuntimePodman {
+		return fmt.Errorf("gpus %q: podman needs \"all\" or \"device=<ids>\", not a count", gpus)
+	}
+	return nil
+}
+
+// volumeOptions are the mount options accepted after the container path
+var volumeOptions = map[string]bool{
+	"ro": true, "rw": true,
+	"z": true, "Z": true,
+	"shared": true, "slave": true, "private": true,
+	"rshared": true, "rslave": true, "rprivate": true,
+	"nocopy": true, "cached": true, "delegated": true, "consistent": true,
+}
+
+// VolumeContainerPath parses a `-v` style mount spec, host:container[:options],
+// and returns the cleaned container path. The host side may be a path or a
+// named volume; options are comma separated.
+func VolumeContainerPath(spec string) (string, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", fmt.Errorf("volume %q must be host:container[:options]", spec)
+	}
+	host, container := parts[0], parts[1]
+	if host == "" {
+		return "", fmt.Errorf("volume %q has an empty host path", spec)
+	}
+	if !path.IsAbs(container) {
+		return "", fmt.Errorf("volume %q: container path must be absolute", spec)
+	}
+	if len(parts) == 3 {
+		for _, opt := range strings.Split(parts[2], ",") {
+			if !volumeOptions[opt] {
+				return "", fmt.Errorf("volume %q has unknown option %q", spec, opt)
+			}
+		}
+	}
+	return path.Clean(container), nil
+}
+
+// ValidateVolumes checks each mount spec and that no container path is
+// mounted twice
+func ValidateVolumes(volumes []string) error {
+	seen := make(map[string]string, len(volumes))
+	for _, spec := range volumes {
+		container, err := VolumeContainerPath(spec)
+		if err != nil {
+			return err
+		}
+		if prev, dup := seen[container]; dup {
+			return fmt.Errorf("volumes %q and %q both mount %s", prev, spec, container)
+		}
+		seen[container] = spec
+	}
+	return nil
+}
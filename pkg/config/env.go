@@ -1,292 +1,314 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// loadEnvVars overrides config with environment variables
-func loadEnvVars(cfg *AppConfig) {
-	// Server config
-	if host := os.Getenv("LLAMACTL_HOST"); host != "" {
-		cfg.Server.Host = host
-	}
-	if port := os.Getenv("LLAMACTL_PORT"); port != "" {
-		if p, err := strconv.Atoi(port); err == nil {
-			cfg.Server.Port = p
+// envVar binds one environment variable to the config setting it overrides
+type envVar struct {
+	name  string
+	path  string // dotted JSON path of the setting in AppConfig
+	apply func(cfg *AppConfig, value string)
+}
+
+// EnvVarInfo describes a recognized environment variable and the effective
+// value of the setting it maps to
+type EnvVarInfo struct {
+	Name       string `json:"name"`
+	ConfigPath string `json:"config_path"`
+	// Whether the variable is set in llamactl's environment
+	Set bool `json:"set"`
+	// Effective value of the config setting, with secrets redacted
+	Value any `json:"value"`
+}
+
+func stringEnv(name, path string, field func(*AppConfig) *string) envVar {
+	return envVar{name, path, func(cfg *AppConfig, value string) {
+		*field(cfg) = value
+	}}
+}
+
+func intEnv(name, path string, field func(*AppConfig) *int) envVar {
+	return envVar{name, path, func(cfg *AppConfig, value string) {
+		if n, err := strconv.Atoi(value); err == nil {
+			*field(cfg) = n
 		}
-	}
-	if allowedOrigins := os.Getenv("LLAMACTL_ALLOWED_ORIGINS"); allowedOrigins != "" {
-		cfg.Server.AllowedOrigins = strings.Split(allowedOrigins, ",")
-	}
-	if enableSwagger := os.Getenv("LLAMACTL_ENABLE_SWAGGER"); enableSwagger != "" {
-		if b, err := strconv.ParseBool(enableSwagger); err == nil {
-			cfg.Server.EnableSwagger = b
+	}}
+}
+
+func boolEnv(name, path string, field func(*AppConfig) *bool) envVar {
+	return envVar{name, path, func(cfg *AppConfig, value string) {
+		if b, err := strconv.ParseBool(value); err == nil {
+			*field(cfg) = b
 		}
-	}
+	}}
+}
 
-	// Data config
-	if dataDir := os.Getenv("LLAMACTL_DATA_DIRECTORY"); dataDir != "" {
-		cfg.DataDir = dataDir
-	}
-	if logsDir := os.Getenv("LLAMACTL_LOGS_DIR"); logsDir != "" {
-		cfg.Instances.LogsDir = logsDir
-	}
-	if instancesDir := os.Getenv("LLAMACTL_INSTANCES_DIR"); instancesDir != "" {
-		cfg.Instances.InstancesDir = instancesDir
-	}
-	if autoCreate := os.Getenv("LLAMACTL_AUTO_CREATE_DATA_DIR"); autoCreate != "" {
-		if b, err := strconv.ParseBool(autoCreate); err == nil {
-			cfg.Instances.AutoCreateDirs = b
+func listEnv(name, path, sep string, field func(*AppConfig) *[]string) envVar {
+	return envVar{name, path, func(cfg *AppConfig, value string) {
+		*field(cfg) = strings.Split(value, sep)
+	}}
+}
+
+func durationEnv(name, path string, field func(*AppConfig) *time.Duration) envVar {
+	return envVar{name, path, func(cfg *AppConfig, value string) {
+		if d, err := time.ParseDuration(value); err == nil {
+			*field(cfg) = d
 		}
+	}}
+}
+
+// mapEnv merges parsed entries into the existing map rather than replacing it
+func mapEnv(name, path string, field func(*AppConfig) *map[string]string, parse func(string, map[string]string)) envVar {
+	return envVar{name, path, func(cfg *AppConfig, value string) {
+		m := field(cfg)
+		if *m == nil {
+			*m = make(map[string]string)
+		}
+		parse(value, *m)
+	}}
+}
+
+// ensureDocker returns the backend's docker settings, creating them if needed
+func ensureDocker(b *BackendSettings) *DockerSettings {
+	if b.Docker == nil {
+		b.Docker = &DockerSettings{}
 	}
+	return b.Docker
+}
+
+// backendEnvVars returns the variables shared by all backends, e.g.
+// LLAMACTL_VLLM_COMMAND for prefix "VLLM"
+func backendEnvVars(prefix, key string, backend func(*AppConfig) *BackendSettings, withDocker bool) []envVar {
+	name := func(suffix string) string { return "LLAMACTL_" + prefix + "_" + suffix }
+	path := func(field string) string { return "backends." + key + "." + field }
+
+	vars := []envVar{
+		stringEnv(name("COMMAND"), path("command"), func(c *AppConfig) *string { return &backend(c).Command }),
+		listEnv(name("ARGS"), path("args"), " ", func(c *AppConfig) *[]string { return &backend(c).Args }),
+		stringEnv(name("STOP_SIGNAL"), path("stop_signal"), func(c *AppConfig) *string { return &backend(c).StopSignal }),
+		mapEnv(name("ENV"), path("environment"), func(c *AppConfig) *map[string]string { return &backend(c).Environment }, parseEnvVars),
+	}
+	if withDocker {
+		docker := func(c *AppConfig) *DockerSettings { return ensureDocker(backend(c)) }
+		vars = append(vars,
+			envVar{name("DOCKER_ENABLED"), path("docker.enabled"), func(cfg *AppConfig, value string) {
+				// Only create docker settings for a valid boolean
+				if b, err := strconv.ParseBool(value); err == nil {
+					docker(cfg).Enabled = b
+				}
+			}},
+			stringEnv(name("DOCKER_RUNTIME"), path("docker.runtime"), func(c *AppConfig) *string { return &docker(c).Runtime }),
+			stringEnv(name("DOCKER_IMAGE"), path("docker.image"), func(c *AppConfig) *string { return &docker(c).Image }),
+			envVar{name("DOCKER_AUTO_PULL"), path("docker.auto_pull"), func(cfg *AppConfig, value string) {
+				if b, err := strconv.ParseBool(value); err == nil {
+					docker(cfg).AutoPull = b
+				}
+			}},
+			listEnv(name("DOCKER_ARGS"), path("docker.args"), " ", func(c *AppConfig) *[]string { return &docker(c).Args }),
+			stringEnv(name("DOCKER_GPUS"), path("docker.gpus"), func(c *AppConfig) *string { return &docker(c).GPUs }),
+			listEnv(name("DOCKER_VOLUMES"), path("docker.volumes"), " ", func(c *AppConfig) *[]string { return &docker(c).Volumes }),
+			mapEnv(name("DOCKER_ENV"), path("docker.environment"), func(c *AppConfig) *map[string]string { return &docker(c).Environment }, parseEnvVars),
+		)
+	}
+	vars = append(vars,
+		mapEnv(name("RESPONSE_HEADERS"), path("response_headers"), func(c *AppConfig) *map[string]string { return &backend(c).ResponseHeaders }, parseHeaders),
+	)
+	return vars
+}
+
+// envVars lists every recognized environment variable in the order they are
+// applied; later entries win when two map to the same setting
+var envVars = func() []envVar {
+	var vars []envVar
+
+	// Server config
+	vars = append(vars,
+		stringEnv("LLAMACTL_HOST", "server.host", func(c *AppConfig) *string { return &c.Server.Host }),
+		intEnv("LLAMACTL_PORT", "server.port", func(c *AppConfig) *int { return &c.Server.Port }),
+		stringEnv("LLAMACTL_BASE_PATH", "server.base_path", func(c *AppConfig) *string { return &c.Server.BasePath }),
+		listEnv("LLAMACTL_ALLOWED_ORIGINS", "server.allowed_origins", ",", func(c *AppConfig) *[]string { return &c.Server.AllowedOrigins }),
+		boolEnv("LLAMACTL_ENABLE_SWAGGER", "server.enable_swagger", func(c *AppConfig) *bool { return &c.Server.EnableSwagger }),
+		durationEnv("LLAMACTL_SHUTDOWN_DRAIN_PERIOD", "server.shutdown_drain_period", func(c *AppConfig) *time.Duration { return &c.Server.ShutdownDrainPeriod }),
+	)
+
+	// Data config
+	vars = append(vars,
+		stringEnv("LLAMACTL_DATA_DIRECTORY", "data_dir", func(c *AppConfig) *string { return &c.DataDir }),
+		stringEnv("LLAMACTL_LOGS_DIR", "instances.logs_dir", func(c *AppConfig) *string { return &c.Instances.LogsDir }),
+		stringEnv("LLAMACTL_INSTANCES_DIR", "instances.instances_dir", func(c *AppConfig) *string { return &c.Instances.InstancesDir }),
+		stringEnv("LLAMACTL_DECLARATIVE_DIR", "instances.declarative_dir", func(c *AppConfig) *string { return &c.Instances.DeclarativeDir }),
+		boolEnv("LLAMACTL_AUTO_CREATE_DATA_DIR", "instances.auto_create_dirs", func(c *AppConfig) *bool { return &c.Instances.AutoCreateDirs }),
+	)
 
 	// Instance config
-	if portRange := os.Getenv("LLAMACTL_INSTANCE_PORT_RANGE"); portRange != "" {
-		if ports := ParsePortRange(portRange); ports != [2]int{0, 0} {
-			cfg.Instances.PortRange = ports
-		}
-	}
-	if maxInstances := os.Getenv("LLAMACTL_MAX_INSTANCES"); maxInstances != "" {
-		if m, err := strconv.Atoi(maxInstances); err == nil {
-			cfg.Instances.MaxInstances = m
-		}
-	}
-	if maxRunning := os.Getenv("LLAMACTL_MAX_RUNNING_INSTANCES"); maxRunning != "" {
-		if m, err := strconv.Atoi(maxRunning); err == nil {
-			cfg.Instances.MaxRunningInstances = m
-		}
-	}
-	if enableLRUEviction := os.Getenv("LLAMACTL_ENABLE_LRU_EVICTION"); enableLRUEviction != "" {
-		if b, err := strconv.ParseBool(enableLRUEviction); err == nil {
-			cfg.Instances.EnableLRUEviction = b
-		}
-	}
-	// Backend config
-	// LlamaCpp backend
-	if llamaCmd := os.Getenv("LLAMACTL_LLAMACPP_COMMAND"); llamaCmd != "" {
-		cfg.Backends.LlamaCpp.Command = llamaCmd
-	}
-	if llamaArgs := os.Getenv("LLAMACTL_LLAMACPP_ARGS"); llamaArgs != "" {
-		cfg.Backends.LlamaCpp.Args = strings.Split(llamaArgs, " ")
-	}
-	if llamaEnv := os.Getenv("LLAMACTL_LLAMACPP_ENV"); llamaEnv != "" {
-		if cfg.Backends.LlamaCpp.Environment == nil {
-			cfg.Backends.LlamaCpp.Environment = make(map[string]string)
-		}
-		parseEnvVars(llamaEnv, cfg.Backends.LlamaCpp.Environment)
-	}
-	if llamaDockerEnabled := os.Getenv("LLAMACTL_LLAMACPP_DOCKER_ENABLED"); llamaDockerEnabled != "" {
-		if b, err := strconv.ParseBool(llamaDockerEnabled); err == nil {
-			if cfg.Backends.LlamaCpp.Docker == nil {
-				cfg.Backends.LlamaCpp.Docker = &DockerSettings{}
+	vars = append(vars,
+		envVar{"LLAMACTL_INSTANCE_PORT_RANGE", "instances.port_range", func(cfg *AppConfig, value string) {
+			if ports := ParsePortRange(value); ports != [2]int{0, 0} {
+				cfg.Instances.PortRange = ports
 			}
-			cfg.Backends.LlamaCpp.Docker.Enabled = b
-		}
-	}
-	if llamaDockerImage := os.Getenv("LLAMACTL_LLAMACPP_DOCKER_IMAGE"); llamaDockerImage != "" {
-		if cfg.Backends.LlamaCpp.Docker == nil {
-			cfg.Backends.LlamaCpp.Docker = &DockerSettings{}
-		}
-		cfg.Backends.LlamaCpp.Docker.Image = llamaDockerImage
-	}
-	if llamaDockerArgs := os.Getenv("LLAMACTL_LLAMACPP_DOCKER_ARGS"); llamaDockerArgs != "" {
-		if cfg.Backends.LlamaCpp.Docker == nil {
-			cfg.Backends.LlamaCpp.Docker = &DockerSettings{}
-		}
-		cfg.Backends.LlamaCpp.Docker.Args = strings.Split(llamaDockerArgs, " ")
-	}
-	if llamaDockerEnv := os.Getenv("LLAMACTL_LLAMACPP_DOCKER_ENV"); llamaDockerEnv != "" {
-		if cfg.Backends.LlamaCpp.Docker == nil {
-			cfg.Backends.LlamaCpp.Docker = &DockerSettings{}
-		}
-		if cfg.Backends.LlamaCpp.Docker.Environment == nil {
-			cfg.Backends.LlamaCpp.Docker.Environment = make(map[string]string)
-		}
-		parseEnvVars(llamaDockerEnv, cfg.Backends.LlamaCpp.Docker.Environment)
-	}
-	if llamaEnv := os.Getenv("LLAMACTL_LLAMACPP_RESPONSE_HEADERS"); llamaEnv != "" {
-		if cfg.Backends.LlamaCpp.ResponseHeaders == nil {
-			cfg.Backends.LlamaCpp.ResponseHeaders = make(map[string]string)
-		}
-		parseHeaders(llamaEnv, cfg.Backends.LlamaCpp.ResponseHeaders)
-	}
-	if llamaCacheDir := os.Getenv("LLAMACTL_LLAMACPP_CACHE_DIR"); llamaCacheDir != "" {
-		cfg.Backends.LlamaCpp.CacheDir = llamaCacheDir
-	}
-	// Handle default llama.cpp env var
-	if llamaCacheDir := os.Getenv("LLAMA_CACHE"); llamaCacheDir != "" {
-		cfg.Backends.LlamaCpp.CacheDir = llamaCacheDir
-	}
-	if llamaDownloadTimeout := os.Getenv("LLAMACTL_LLAMACPP_DOWNLOAD_TIMEOUT"); llamaDownloadTimeout != "" {
-		if t, err := strconv.Atoi(llamaDownloadTimeout); err == nil {
-			cfg.Backends.LlamaCpp.DownloadTimeout = time.Duration(t) * time.Second
-		}
-	}
-
-	// vLLM backend
-	if vllmCmd := os.Getenv("LLAMACTL_VLLM_COMMAND"); vllmCmd != "" {
-		cfg.Backends.VLLM.Command = vllmCmd
-	}
-	if vllmArgs := os.Getenv("LLAMACTL_VLLM_ARGS"); vllmArgs != "" {
-		cfg.Backends.VLLM.Args = strings.Split(vllmArgs, " ")
-	}
-	if vllmEnv := os.Getenv("LLAMACTL_VLLM_ENV"); vllmEnv != "" {
-		if cfg.Backends.VLLM.Environment == nil {
-			cfg.Backends.VLLM.Environment = make(map[string]string)
-		}
-		parseEnvVars(vllmEnv, cfg.Backends.VLLM.Environment)
-	}
-	if vllmDockerEnabled := os.Getenv("LLAMACTL_VLLM_DOCKER_ENABLED"); vllmDockerEnabled != "" {
-		if b, err := strconv.ParseBool(vllmDockerEnabled); err == nil {
-			if cfg.Backends.VLLM.Docker == nil {
-				cfg.Backends.VLLM.Docker = &DockerSettings{}
+		}},
+		stringEnv("LLAMACTL_PORT_ALLOCATION", "instances.port_allocation", func(c *AppConfig) *string { return &c.Instances.PortAllocation }),
+		intEnv("LLAMACTL_MAX_INSTANCES", "instances.max_instances", func(c *AppConfig) *int { return &c.Instances.MaxInstances }),
+		intEnv("LLAMACTL_MAX_RUNNING_INSTANCES", "instances.max_running_instances", func(c *AppConfig) *int { return &c.Instances.MaxRunningInstances }),
+		intEnv("LLAMACTL_VRAM_BUDGET_MB", "instances.vram_budget_mb", func(c *AppConfig) *int { return &c.Instances.VRAMBudgetMB }),
+		boolEnv("LLAMACTL_ENABLE_LRU_EVICTION", "instances.enable_lru_eviction", func(c *AppConfig) *bool { return &c.Instances.EnableLRUEviction }),
+		envVar{"LLAMACTL_GROUP_LIMITS", "instances.group_limits", func(cfg *AppConfig, value string) {
+			if cfg.Instances.GroupLimits == nil {
+				cfg.Instances.GroupLimits = make(map[string]int)
 			}
-			cfg.Backends.VLLM.Docker.Enabled = b
-		}
-	}
-	if vllmDockerImage := os.Getenv("LLAMACTL_VLLM_DOCKER_IMAGE"); vllmDockerImage != "" {
-		if cfg.Backends.VLLM.Docker == nil {
-			cfg.Backends.VLLM.Docker = &DockerSettings{}
-		}
-		cfg.Backends.VLLM.Docker.Image = vllmDockerImage
-	}
-	if vllmDockerArgs := os.Getenv("LLAMACTL_VLLM_DOCKER_ARGS"); vllmDockerArgs != "" {
-		if cfg.Backends.VLLM.Docker == nil {
-			cfg.Backends.VLLM.Docker = &DockerSettings{}
-		}
-		cfg.Backends.VLLM.Docker.Args = strings.Split(vllmDockerArgs, " ")
-	}
-	if vllmDockerEnv := os.Getenv("LLAMACTL_VLLM_DOCKER_ENV"); vllmDockerEnv != "" {
-		if cfg.Backends.VLLM.Docker == nil {
-			cfg.Backends.VLLM.Docker = &DockerSettings{}
-		}
-		if cfg.Backends.VLLM.Docker.Environment == nil {
-			cfg.Backends.VLLM.Docker.Environment = make(map[string]string)
-		}
-		parseEnvVars(vllmDockerEnv, cfg.Backends.VLLM.Docker.Environment)
-	}
-	if llamaEnv := os.Getenv("LLAMACTL_VLLM_RESPONSE_HEADERS"); llamaEnv != "" {
-		if cfg.Backends.VLLM.ResponseHeaders == nil {
-			cfg.Backends.VLLM.ResponseHeaders = make(map[string]string)
-		}
-		parseHeaders(llamaEnv, cfg.Backends.VLLM.ResponseHeaders)
-	}
+			parseGroupLimits(value, cfg.Instances.GroupLimits)
+		}},
+	)
 
-	// MLX backend
-	if mlxCmd := os.Getenv("LLAMACTL_MLX_COMMAND"); mlxCmd != "" {
-		cfg.Backends.MLX.Command = mlxCmd
-	}
-	if mlxArgs := os.Getenv("LLAMACTL_MLX_ARGS"); mlxArgs != "" {
-		cfg.Backends.MLX.Args = strings.Split(mlxArgs, " ")
-	}
-	if mlxEnv := os.Getenv("LLAMACTL_MLX_ENV"); mlxEnv != "" {
-		if cfg.Backends.MLX.Environment == nil {
-			cfg.Backends.MLX.Environment = make(map[string]string)
-		}
-		parseEnvVars(mlxEnv, cfg.Backends.MLX.Environment)
-	}
-	if llamaEnv := os.Getenv("LLAMACTL_MLX_RESPONSE_HEADERS"); llamaEnv != "" {
-		if cfg.Backends.MLX.ResponseHeaders == nil {
-			cfg.Backends.MLX.ResponseHeaders = make(map[string]string)
-		}
-		parseHeaders(llamaEnv, cfg.Backends.MLX.ResponseHeaders)
-	}
+	// Backend config
+	llamaCpp := func(c *AppConfig) *BackendSettings { return &c.Backends.LlamaCpp }
+	vars = append(vars, backendEnvVars("LLAMACPP", "llama-cpp", llamaCpp, true)...)
+	vars = append(vars,
+		stringEnv("LLAMACTL_LLAMACPP_CACHE_DIR", "backends.llama-cpp.cache_dir", func(c *AppConfig) *string { return &c.Backends.LlamaCpp.CacheDir }),
+		// Default llama.cpp env var
+		stringEnv("LLAMA_CACHE", "backends.llama-cpp.cache_dir", func(c *AppConfig) *string { return &c.Backends.LlamaCpp.CacheDir }),
+		envVar{"LLAMACTL_LLAMACPP_DOWNLOAD_TIMEOUT", "backends.llama-cpp.download_timeout", func(cfg *AppConfig, value string) {
+			if t, err := strconv.Atoi(value); err == nil {
+				cfg.Backends.LlamaCpp.DownloadTimeout = time.Duration(t) * time.Second
+			}
+		}},
+	)
+	vars = append(vars, backendEnvVars("VLLM", "vllm", func(c *AppConfig) *BackendSettings { return &c.Backends.VLLM }, true)...)
+	vars = append(vars, stringEnv("LLAMACTL_VLLM_INVOCATION", "backends.vllm.invocation", func(c *AppConfig) *string { return &c.Backends.VLLM.Invocation }))
+	vars = append(vars, backendEnvVars("MLX", "mlx", func(c *AppConfig) *BackendSettings { return &c.Backends.MLX }, false)...)
+	vars = append(vars, backendEnvVars("LLAMA_RPC", "llama-rpc", func(c *AppConfig) *BackendSettings { return &c.Backends.LlamaRpc }, false)...)
 
 	// Instance defaults
-	if idleTimeout := os.Getenv("LLAMACTL_DEFAULT_IDLE_TIMEOUT"); idleTimeout != "" {
-		if minutes, err := strconv.Atoi(idleTimeout); err == nil {
-			cfg.Instances.DefaultIdleTimeout = minutes
-		}
-	}
-	if autoRestart := os.Getenv("LLAMACTL_DEFAULT_AUTO_RESTART"); autoRestart != "" {
-		if b, err := strconv.ParseBool(autoRestart); err == nil {
-			cfg.Instances.DefaultAutoRestart = b
-		}
-	}
-	if maxRestarts := os.Getenv("LLAMACTL_DEFAULT_MAX_RESTARTS"); maxRestarts != "" {
-		if m, err := strconv.Atoi(maxRestarts); err == nil {
-			cfg.Instances.DefaultMaxRestarts = m
-		}
-	}
-	if restartDelay := os.Getenv("LLAMACTL_DEFAULT_RESTART_DELAY"); restartDelay != "" {
-		if seconds, err := strconv.Atoi(restartDelay); err == nil {
-			cfg.Instances.DefaultRestartDelay = seconds
-		}
-	}
-	if onDemandStart := os.Getenv("LLAMACTL_DEFAULT_ON_DEMAND_START"); onDemandStart != "" {
-		if b, err := strconv.ParseBool(onDemandStart); err == nil {
-			cfg.Instances.DefaultOnDemandStart = b
-		}
-	}
-	if onDemandTimeout := os.Getenv("LLAMACTL_ON_DEMAND_START_TIMEOUT"); onDemandTimeout != "" {
-		if seconds, err := strconv.Atoi(onDemandTimeout); err == nil {
-			cfg.Instances.OnDemandStartTimeout = seconds
-		}
-	}
-	if timeoutCheckInterval := os.Getenv("LLAMACTL_TIMEOUT_CHECK_INTERVAL"); timeoutCheckInterval != "" {
-		if minutes, err := strconv.Atoi(timeoutCheckInterval); err == nil {
-			cfg.Instances.TimeoutCheckInterval = minutes
-		}
-	}
+	vars = append(vars,
+		intEnv("LLAMACTL_DEFAULT_IDLE_TIMEOUT", "instances.default_idle_timeout", func(c *AppConfig) *int { return &c.Instances.DefaultIdleTimeout }),
+		boolEnv("LLAMACTL_DEFAULT_AUTO_RESTART", "instances.default_auto_restart", func(c *AppConfig) *bool { return &c.Instances.DefaultAutoRestart }),
+		intEnv("LLAMACTL_DEFAULT_MAX_RESTARTS", "instances.default_max_restarts", func(c *AppConfig) *int { return &c.Instances.DefaultMaxRestarts }),
+		intEnv("LLAMACTL_DEFAULT_RESTART_DELAY", "instances.default_restart_delay", func(c *AppConfig) *int { return &c.Instances.DefaultRestartDelay }),
+		intEnv("LLAMACTL_MAX_RESTARTS_PER_MINUTE", "instances.max_restarts_per_minute", func(c *AppConfig) *int { return &c.Instances.MaxRestartsPerMinute }),
+		boolEnv("LLAMACTL_DEFAULT_ON_DEMAND_START", "instances.default_on_demand_start", func(c *AppConfig) *bool { return &c.Instances.DefaultOnDemandStart }),
+		intEnv("LLAMACTL_DEFAULT_CTX_SIZE", "instances.default_ctx_size", func(c *AppConfig) *int { return &c.Instances.DefaultCtxSize }),
+		intEnv("LLAMACTL_MAX_CTX_SIZE", "instances.max_ctx_size", func(c *AppConfig) *int { return &c.Instances.MaxCtxSize }),
+		intEnv("LLAMACTL_ON_DEMAND_START_TIMEOUT", "instances.on_demand_start_timeout", func(c *AppConfig) *int { return &c.Instances.OnDemandStartTimeout }),
+		intEnv("LLAMACTL_AUTO_START_DELAY", "instances.auto_start_delay", func(c *AppConfig) *int { return &c.Instances.AutoStartDelay }),
+		boolEnv("LLAMACTL_AUTO_START_WAIT_HEALTHY", "instances.auto_start_wait_healthy", func(c *AppConfig) *bool { return &c.Instances.AutoStartWaitHealthy }),
+		intEnv("LLAMACTL_SHUTDOWN_PARALLELISM", "instances.shutdown_parallelism", func(c *AppConfig) *int { return &c.Instances.ShutdownParallelism }),
+		boolEnv("LLAMACTL_CLEANUP_ORPHANS_ON_START", "instances.cleanup_orphans_on_start", func(c *AppConfig) *bool { return &c.Instances.CleanupOrphansOnStart }),
+		intEnv("LLAMACTL_TIMEOUT_CHECK_INTERVAL", "instances.timeout_check_interval", func(c *AppConfig) *int { return &c.Instances.TimeoutCheckInterval }),
+	)
+
 	// Auth config
-	if requireInferenceAuth := os.Getenv("LLAMACTL_REQUIRE_INFERENCE_AUTH"); requireInferenceAuth != "" {
-		if b, err := strconv.ParseBool(requireInferenceAuth); err == nil {
-			cfg.Auth.RequireInferenceAuth = b
-		}
-	}
-	if requireManagementAuth := os.Getenv("LLAMACTL_REQUIRE_MANAGEMENT_AUTH"); requireManagementAuth != "" {
-		if b, err := strconv.ParseBool(requireManagementAuth); err == nil {
-			cfg.Auth.RequireManagementAuth = b
-		}
-	}
-	if managementKeys := os.Getenv("LLAMACTL_MANAGEMENT_KEYS"); managementKeys != "" {
-		cfg.Auth.ManagementKeys = strings.Split(managementKeys, ",")
-	}
+	vars = append(vars,
+		boolEnv("LLAMACTL_REQUIRE_INFERENCE_AUTH", "auth.require_inference_auth", func(c *AppConfig) *bool { return &c.Auth.RequireInferenceAuth }),
+		boolEnv("LLAMACTL_REQUIRE_MANAGEMENT_AUTH", "auth.require_management_auth", func(c *AppConfig) *bool { return &c.Auth.RequireManagementAuth }),
+		listEnv("LLAMACTL_MANAGEMENT_KEYS", "auth.management_keys", ",", func(c *AppConfig) *[]string { return &c.Auth.ManagementKeys }),
+		stringEnv("LLAMACTL_KEYS_FILE", "auth.keys_file", func(c *AppConfig) *string { return &c.Auth.KeysFile }),
+	)
+
+	// Logging config
+	vars = append(vars,
+		stringEnv("LLAMACTL_LOGGING_FILE_TEMPLATE", "logging.file_template", func(c *AppConfig) *string { return &c.Logging.FileTemplate }),
+		boolEnv("LLAMACTL_LOGGING_MIRROR_TO_STDOUT", "logging.mirror_to_stdout", func(c *AppConfig) *bool { return &c.Logging.MirrorToStdout }),
+		intEnv("LLAMACTL_LOGGING_MAX_LINE_LENGTH", "logging.max_line_length", func(c *AppConfig) *int { return &c.Logging.MaxLineLength }),
+		boolEnv("LLAMACTL_LOGGING_REQUIRED", "logging.required", func(c *AppConfig) *bool { return &c.Logging.Required }),
+	)
+
+	// Notifications config
+	vars = append(vars,
+		stringEnv("LLAMACTL_NOTIFICATIONS_WEBHOOK_URL", "notifications.webhook_url", func(c *AppConfig) *string { return &c.Notifications.WebhookURL }),
+		stringEnv("LLAMACTL_NOTIFICATIONS_FORMAT", "notifications.format", func(c *AppConfig) *string { return &c.Notifications.Format }),
+	)
 
 	// Local node config
-	if localNode := os.Getenv("LLAMACTL_LOCAL_NODE"); localNode != "" {
-		cfg.LocalNode = localNode
-	}
+	vars = append(vars,
+		stringEnv("LLAMACTL_LOCAL_NODE", "local_node", func(c *AppConfig) *string { return &c.LocalNode }),
+	)
 
 	// Database config
-	if dbPath := os.Getenv("LLAMACTL_DATABASE_PATH"); dbPath != "" {
-		cfg.Database.Path = dbPath
-	}
-	if maxOpenConns := os.Getenv("LLAMACTL_DATABASE_MAX_OPEN_CONNECTIONS"); maxOpenConns != "" {
-		if m, err := strconv.Atoi(maxOpenConns); err == nil {
-			cfg.Database.MaxOpenConnections = m
-		}
-	}
-	if maxIdleConns := os.Getenv("LLAMACTL_DATABASE_MAX_IDLE_CONNECTIONS"); maxIdleConns != "" {
-		if m, err := strconv.Atoi(maxIdleConns); err == nil {
-			cfg.Database.MaxIdleConnections = m
-		}
-	}
-	if connMaxLifetime := os.Getenv("LLAMACTL_DATABASE_CONN_MAX_LIFETIME"); connMaxLifetime != "" {
-		if d, err := time.ParseDuration(connMaxLifetime); err == nil {
-			cfg.Database.ConnMaxLifetime = d
-		}
-	}
+	vars = append(vars,
+		stringEnv("LLAMACTL_DATABASE_DRIVER", "database.driver", func(c *AppConfig) *string { return &c.Database.Driver }),
+		stringEnv("LLAMACTL_DATABASE_PATH", "database.path", func(c *AppConfig) *string { return &c.Database.Path }),
+		stringEnv("LLAMACTL_DATABASE_DSN", "database.dsn", func(c *AppConfig) *string { return &c.Database.DSN }),
+		intEnv("LLAMACTL_DATABASE_MAX_OPEN_CONNECTIONS", "database.max_open_connections", func(c *AppConfig) *int { return &c.Database.MaxOpenConnections }),
+		intEnv("LLAMACTL_DATABASE_MAX_IDLE_CONNECTIONS", "database.max_idle_connections", func(c *AppConfig) *int { return &c.Database.MaxIdleConnections }),
+		durationEnv("LLAMACTL_DATABASE_CONN_MAX_LIFETIME", "database.connection_max_lifetime", func(c *AppConfig) *time.Duration { return &c.Database.ConnMaxLifetime }),
+	)
+
+	// Proxy config
+	vars = append(vars,
+		durationEnv("LLAMACTL_PROXY_CONNECT_TIMEOUT", "proxy.connect_timeout", func(c *AppConfig) *time.Duration { return &c.Proxy.ConnectTimeout }),
+		durationEnv("LLAMACTL_PROXY_RESPONSE_HEADER_TIMEOUT", "proxy.response_header_timeout", func(c *AppConfig) *time.Duration { return &c.Proxy.ResponseHeaderTimeout }),
+		durationEnv("LLAMACTL_PROXY_IDLE_CONN_TIMEOUT", "proxy.idle_conn_timeout", func(c *AppConfig) *time.Duration { return &c.Proxy.IdleConnTimeout }),
+		durationEnv("LLAMACTL_PROXY_STREAM_TIMEOUT", "proxy.stream_timeout", func(c *AppConfig) *time.Duration { return &c.Proxy.StreamTimeout }),
+		durationEnv("LLAMACTL_PROXY_UNAVAILABLE_RETRY_AFTER", "proxy.unavailable_retry_after", func(c *AppConfig) *time.Duration { return &c.Proxy.UnavailableRetryAfter }),
+		intEnv("LLAMACTL_PROXY_MAX_IDLE_CONNS_PER_HOST", "proxy.max_idle_conns_per_host", func(c *AppConfig) *int { return &c.Proxy.MaxIdleConnsPerHost }),
+		boolEnv("LLAMACTL_PROXY_DISABLE_KEEP_ALIVES", "proxy.disable_keep_alives", func(c *AppConfig) *bool { return &c.Proxy.DisableKeepAlives }),
+	)
 
 	// Log rotation config
-	if logRotationEnabled := os.Getenv("LLAMACTL_LOG_ROTATION_ENABLED"); logRotationEnabled != "" {
-		if b, err := strconv.ParseBool(logRotationEnabled); err == nil {
-			cfg.Instances.LogRotationEnabled = b
-		}
-	}
-	if logRotationMaxSize := os.Getenv("LLAMACTL_LOG_ROTATION_MAX_SIZE"); logRotationMaxSize != "" {
-		if m, err := strconv.Atoi(logRotationMaxSize); err == nil {
-			cfg.Instances.LogRotationMaxSize = m
+	vars = append(vars,
+		boolEnv("LLAMACTL_LOG_ROTATION_ENABLED", "instances.log_rotation_enabled", func(c *AppConfig) *bool { return &c.Instances.LogRotationEnabled }),
+		intEnv("LLAMACTL_LOG_ROTATION_MAX_SIZE", "instances.log_rotation_max_size", func(c *AppConfig) *int { return &c.Instances.LogRotationMaxSize }),
+		boolEnv("LLAMACTL_LOG_ROTATION_COMPRESS", "instances.log_rotation_compress", func(c *AppConfig) *bool { return &c.Instances.LogRotationCompress }),
+	)
+
+	return vars
+}()
+
+// loadEnvVars overrides config with environment variables
+func loadEnvVars(cfg *AppConfig) {
+	for _, v := range envVars {
+		if value := os.Getenv(v.name); value != "" {
+			v.apply(cfg, value)
 		}
 	}
-	if logRotationCompress := os.Getenv("LLAMACTL_LOG_ROTATION_COMPRESS"); logRotationCompress != "" {
-		if b, err := strconv.ParseBool(logRotationCompress); err == nil {
-			cfg.Instances.LogRotationCompress = b
-		}
+}
+
+// EnvVars lists the recognized environment variables together with the
+// effective value of the setting each one maps to, taken from the sanitized
+// config so secrets stay redacted
+func (cfg *AppConfig) EnvVars() ([]EnvVarInfo, error) {
+	sanitized, err := cfg.SanitizedCopy()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(sanitized)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
 	}
+
+	infos := make([]EnvVarInfo, 0, len(envVars))
+	for _, v := range envVars {
+		infos = append(infos, EnvVarInfo{
+			Name:       v.name,
+			ConfigPath: v.path,
+			Set:        os.Getenv(v.name) != "",
+			Value:      lookupPath(tree, v.path),
+		})
+	}
+	return infos, nil
+}
+
+// lookupPath walks a decoded JSON object along a dotted path, returning nil
+// when any segment is missing
+func lookupPath(tree map[string]any, path string) any {
+	var current any = tree
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = obj[key]
+	}
+	return current
 }
 
 // ParsePortRange parses port range from string formats like "8000-9000" or "8000,9000"
@@ -337,3 +359,15 @@ func parseHeaders(envString string, envMap map[string]string) {
 		}
 	}
 }
+
+// parseGroupLimits parses group limits in format "group1=2,group2=1"
+// and populates the provided limits map
+func parseGroupLimits(limitsString string, limits map[string]int) {
+	for _, pair := range strings.Split(limitsString, ",") {
+		if parts := strings.SplitN(strings.TrimSpace(pair), "=", 2); len(parts) == 2 {
+			if n, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil {
+				limits[strings.TrimSpace(parts[0])] = n
+			}
+		}
+	}
+}
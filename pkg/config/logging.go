@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// DefaultLogFileTemplate names instance log files after the instance
+const DefaultLogFileTemplate = "{name}.log"
+
+var logPlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ExpandLogFileTemplate resolves a logging.file_template for an instance into
+// a path relative to the logs directory. Supported placeholders are {name},
+// {backend} and {date} (YYYY-MM-DD). Unknown placeholders and paths that
+// would escape the logs directory are rejected.
+func ExpandLogFileTemplate(template, name, backend string, now time.Time) (string, error) {
+	if template == "" {
+		template = DefaultLogFileTemplate
+	}
+
+	var unknown string
+	path := logPlaceholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		switch placeholder {
+		case "{name}":
+			return name
+		case "{backend}":
+			return backend
+		case "{date}":
+			return now.Format("2006-01-02")
+		}
+		if unknown == "" {
+			unknown = placeholder
+		}
+		return placeholder
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("unknown placeholder %s in log file template %q", unknown, template)
+	}
+
+	path = filepath.Clean(filepath.FromSlash(path))
+	if !filepath.IsLocal(path) || path == "." {
+		return "", fmt.Errorf("log file template %q resolves to %q, which is outside the logs directory", template, path)
+	}
+	return path, nil
+}
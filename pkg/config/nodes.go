@@ -0,0 +1,104 @@
+package config
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PointsToSelf reports whether a node address reaches this llamactl server,
+// i.e. it names a local host and the port the server listens on. Requests to
+// such a node would be proxied back to ourselves.
+func (cfg *AppConfig) PointsToSelf(address string) bool {
+	u, err := url.Parse(address)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	if port != strconv.Itoa(cfg.Server.Port) {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	listen := strings.ToLower(cfg.Server.Host)
+	switch {
+	case host == listen:
+		return true
+	case listen == "" || isUnspecified(listen):
+		// Listening on all interfaces, so any address of this machine reaches us
+		return isLocalHost(host)
+	default:
+		return isLoopback(listen) && isLoopback(host)
+	}
+}
+
+// SelfReferencingNodes returns the remote nodes whose address points back at
+// this server, sorted by name
+func (cfg *AppConfig) SelfReferencingNodes() []string {
+	var names []string
+	for name, node := range cfg.Nodes {
+		if name != cfg.LocalNode && node.Address != "" && cfg.PointsToSelf(node.Address) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func isUnspecified(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// localAddresses holds this machine's hostname and interface addresses.
+// They are looked up once, since every node request checks them.
+var localAddresses = sync.OnceValues(func() (string, []net.IP) {
+	hostname, _ := os.Hostname()
+
+	var ips []net.IP
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				ips = append(ips, ipNet.IP)
+			}
+		}
+	}
+	return hostname, ips
+})
+
+// isLocalHost reports whether host is a loopback address, this machine's
+// hostname or one of its interface addresses
+func isLocalHost(host string) bool {
+	if isLoopback(host) || isUnspecified(host) {
+		return true
+	}
+	hostname, ips := localAddresses()
+	if hostname != "" && strings.EqualFold(host, hostname) {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	return slices.ContainsFunc(ips, ip.Equal)
+}
@@ -0,0 +1,72 @@
+package cron_test
+
+import (
+	"llamactl/pkg/cron"
+	"testing"
+	"time"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@sometimes",
+	}
+
+	for _, expr := range tests {
+		if _, err := cron.Parse(expr); err == nil {
+			t.Errorf("Parse(%q) expected error", expr)
+		}
+	}
+}
+
+func TestSchedule_Matches(t *testing.T) {
+	// 2026-03-02 is a Monday
+	monday9 := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	sunday := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr  string
+		t     time.Time
+		match bool
+	}{
+		{"* * * * *", monday9, true},
+		{"0 9 * * 1-5", monday9, true},
+		{"0 9 * * 1-5", monday9.Add(time.Minute), false},
+		{"0 9 * * 1-5", sunday.Add(9 * time.Hour), false},
+		{"*/15 * * * *", monday9.Add(30 * time.Minute), true},
+		{"*/15 * * * *", monday9.Add(31 * time.Minute), false},
+		{"5/20 * * * *", monday9.Add(25 * time.Minute), true},
+		{"0 8,9,10 * * *", monday9, true},
+		{"0 0 * * 7", sunday, true}, // 7 is Sunday
+		{"0 0 * * 0", sunday, true},
+		{"@daily", sunday, true},
+		{"@daily", monday9, false},
+		{"0 9 * 4 *", monday9, false},
+		// Restricted day-of-month and day-of-week match if either does
+		{"0 9 15 * 1", monday9, true},
+		{"0 9 2 * 5", monday9, true},
+		{"0 9 15 * 5", monday9, false},
+		// With one of them unrestricted, both must match
+		{"0 9 15 * *", monday9, false},
+	}
+
+	for _, tt := range tests {
+		s, err := cron.Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.expr, err)
+		}
+		if got := s.Matches(tt.t); got != tt.match {
+			t.Errorf("Parse(%q).Matches(%s) = %v, expected %v", tt.expr, tt.t.Format(time.RFC3339), got, tt.match)
+		}
+	}
+}
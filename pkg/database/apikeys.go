@@ -3,13 +3,17 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"llamactl/pkg/auth"
 	"time"
 )
 
-// CreateKey inserts a new API key with permissions (transactional)
-func (db *sqliteDB) CreateKey(ctx context.Context, key *auth.APIKey, permissions []auth.KeyPermission) error {
+// ErrKeyNotFound is returned when no API key has the requested ID
+var ErrKeyNotFound = errors.New("API key not found")
+
+// createKey inserts a new API key with permissions (transactional)
+func (db *sqlStore) createKey(ctx context.Context, key *auth.APIKey, permissions []auth.KeyPermission) error {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -20,6 +24,7 @@ func (db *sqliteDB) CreateKey(ctx context.Context, key *auth.APIKey, permissions
 	query := `
 		INSERT INTO api_keys (key_hash, name, user_id, permission_mode, expires_at, created_at, updated_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
 	`
 
 	var expiresAt sql.NullInt64
@@ -27,28 +32,26 @@ func (db *sqliteDB) CreateKey(ctx context.Context, key *auth.APIKey, permissions
 		expiresAt = sql.NullInt64{Int64: *key.ExpiresAt, Valid: true}
 	}
 
-	result, err := tx.ExecContext(ctx, query,
+	err = tx.QueryRowContext(ctx, db.rebind(query),
 		key.KeyHash, key.Name, key.UserID, key.PermissionMode,
 		expiresAt, key.CreatedAt, key.UpdatedAt,
-	)
+	).Scan(&key.ID)
 	if err != nil {
 		return fmt.Errorf("failed to insert API key: %w", err)
 	}
 
-	keyID, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert ID: %w", err)
-	}
-	key.ID = int(keyID)
-
 	// Insert permissions if per-instance mode
 	if key.PermissionMode == auth.PermissionModePerInstance {
 		for _, perm := range permissions {
+			allowedPaths, err := encodeAllowedPaths(perm.AllowedPaths)
+			if err != nil {
+				return err
+			}
 			query := `
-				INSERT INTO key_permissions (key_id, instance_id)
-				VALUES (?, ?)
+				INSERT INTO key_permissions (key_id, instance_id, allowed_paths)
+				VALUES (?, ?, ?)
 			`
-			_, err := tx.ExecContext(ctx, query, key.ID, perm.InstanceID)
+			_, err = tx.ExecContext(ctx, db.rebind(query), key.ID, perm.InstanceID, allowedPaths)
 			if err != nil {
 				return fmt.Errorf("failed to insert permission for instance %d: %w", perm.InstanceID, err)
 			}
@@ -58,8 +61,8 @@ func (db *sqliteDB) CreateKey(ctx context.Context, key *auth.APIKey, permissions
 	return tx.Commit()
 }
 
-// GetKeyByID retrieves an API key by ID
-func (db *sqliteDB) GetKeyByID(ctx context.Context, id int) (*auth.APIKey, error) {
+// getKeyByID retrieves an API key by ID
+func (db *sqlStore) getKeyByID(ctx context.Context, id int) (*auth.APIKey, error) {
 	query := `
 		SELECT id, key_hash, name, user_id, permission_mode, expires_at, created_at, updated_at, last_used_at
 		FROM api_keys
@@ -76,7 +79,7 @@ func (db *sqliteDB) GetKeyByID(ctx context.Context, id int) (*auth.APIKey, error
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("API key not found")
+			return nil, ErrKeyNotFound
 		}
 		return nil, fmt.Errorf("failed to query API key: %w", err)
 	}
@@ -91,8 +94,8 @@ func (db *sqliteDB) GetKeyByID(ctx context.Context, id int) (*auth.APIKey, error
 	return &key, nil
 }
 
-// GetUserKeys retrieves all API keys for a user
-func (db *sqliteDB) GetUserKeys(ctx context.Context, userID string) ([]*auth.APIKey, error) {
+// getUserKeys retrieves all API keys for a user
+func (db *sqlStore) getUserKeys(ctx context.Context, userID string) ([]*auth.APIKey, error) {
 	query := `
 		SELECT id, key_hash, name, user_id, permission_mode, expires_at, created_at, updated_at, last_used_at
 		FROM api_keys
@@ -133,8 +136,8 @@ func (db *sqliteDB) GetUserKeys(ctx context.Context, userID string) ([]*auth.API
 	return keys, nil
 }
 
-// GetActiveKeys retrieves all non-expired API keys
-func (db *sqliteDB) GetActiveKeys(ctx context.Context) ([]*auth.APIKey, error) {
+// getActiveKeys retrieves all non-expired API keys
+func (db *sqlStore) getActiveKeys(ctx context.Context) ([]*auth.APIKey, error) {
 	query := `
 		SELECT id, key_hash, name, user_id, permission_mode, expires_at, created_at, updated_at, last_used_at
 		FROM api_keys
@@ -176,8 +179,8 @@ func (db *sqliteDB) GetActiveKeys(ctx context.Context) ([]*auth.APIKey, error) {
 	return keys, nil
 }
 
-// DeleteKey removes an API key (cascades to permissions)
-func (db *sqliteDB) DeleteKey(ctx context.Context, id int) error {
+// deleteKey removes an API key (cascades to permissions)
+func (db *sqlStore) deleteKey(ctx context.Context, id int) error {
 	query := `DELETE FROM api_keys WHERE id = ?`
 
 	result, err := db.ExecContext(ctx, query, id)
@@ -191,14 +194,14 @@ func (db *sqliteDB) DeleteKey(ctx context.Context, id int) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("API key not found")
+		return ErrKeyNotFound
 	}
 
 	return nil
 }
 
-// TouchKey updates the last_used_at timestamp
-func (db *sqliteDB) TouchKey(ctx context.Context, id int) error {
+// touchKey updates the last_used_at timestamp
+func (db *sqlStore) touchKey(ctx context.Context, id int) error {
 	query := `UPDATE api_keys SET last_used_at = ?, updated_at = ? WHERE id = ?`
 
 	now := time.Now().Unix()
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"llamactl/pkg/auth"
+	"strings"
+)
+
+// defaultAuditLimit caps audit listings when the filter sets no limit
+const defaultAuditLimit = 100
+
+// recordAudit appends an entry to the audit log
+func (db *sqlStore) recordAudit(ctx context.Context, entry *auth.AuditEntry) error {
+	query := `
+		INSERT INTO audit_log (created_at, key_id, actor, action, target, method, path, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`
+
+	err := db.QueryRowContext(ctx, query,
+		entry.CreatedAt, entry.KeyID, entry.Actor, entry.Action, entry.Target,
+		entry.Method, entry.Path, entry.Status,
+	).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// listAudit returns audit entries matching filter, newest first
+func (db *sqlStore) listAudit(ctx context.Context, filter auth.AuditFilter) ([]*auth.AuditEntry, error) {
+	var conditions []string
+	var args []any
+
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.Target != "" {
+		conditions = append(conditions, "target = ?")
+		args = append(args, filter.Target)
+	}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Since > 0 {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if filter.Until > 0 {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, filter.Until)
+	}
+
+	query := `
+		SELECT id, created_at, key_id, actor, action, target, method, path, status
+		FROM audit_log
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*auth.AuditEntry
+	for rows.Next() {
+		var entry auth.AuditEntry
+		var keyID sql.NullInt64
+
+		err := rows.Scan(
+			&entry.ID, &entry.CreatedAt, &keyID, &entry.Actor, &entry.Action,
+			&entry.Target, &entry.Method, &entry.Path, &entry.Status,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+
+		if keyID.Valid {
+			id := int(keyID.Int64)
+			entry.KeyID = &id
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
@@ -8,8 +8,11 @@ import (
 	"llamactl/pkg/instance"
 	"log"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -31,49 +34,86 @@ type AuthStore interface {
 	TouchKey(ctx context.Context, id int) error
 	GetPermissions(ctx context.Context, keyID int) ([]auth.KeyPermission, error)
 	HasPermission(ctx context.Context, keyID, instanceID int) (bool, error)
+	GetPermission(ctx context.Context, keyID, instanceID int) (*auth.KeyPermission, error)
+	RecordAudit(ctx context.Context, entry *auth.AuditEntry) error
+	ListAudit(ctx context.Context, filter auth.AuditFilter) ([]*auth.AuditEntry, error)
 }
 
+// HealthChecker is implemented by stores that can report whether the
+// database behind them is reachable
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// Supported database drivers
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
 // Config contains database configuration settings
 type Config struct {
-	// Database file path (relative to data_dir or absolute)
+	// Driver is DriverSQLite (the default when empty) or DriverPostgres
+	Driver string
+
+	// Database file path (relative to data_dir or absolute), used by SQLite
 	Path string
 
+	// Connection string, used by Postgres
+	DSN string
+
 	// Connection settings
 	MaxOpenConnections int
 	MaxIdleConnections int
 	ConnMaxLifetime    time.Duration
 }
 
-// sqliteDB wraps database connection with configuration
-type sqliteDB struct {
+// sqlStore wraps database connection with configuration
+type sqlStore struct {
 	*sql.DB
 	config *Config
+	driver string
 }
 
 // Open creates a new database connection with provided configuration
-func Open(config *Config) (*sqliteDB, error) {
+func Open(config *Config) (*sqlStore, error) {
 	if config == nil {
 		return nil, fmt.Errorf("database config cannot be nil")
 	}
 
-	if config.Path == "" {
-		return nil, fmt.Errorf("database path cannot be empty")
-	}
+	var driverName, dsn, target string
+	switch config.Driver {
+	case "", DriverSQLite:
+		if config.Path == "" {
+			return nil, fmt.Errorf("database path cannot be empty")
+		}
 
-	// Ensure that database directory exists
-	dbDir := filepath.Dir(config.Path)
-	if dbDir != "." && dbDir != "/" {
-		// Directory will be created by manager if auto_create_dirs is enabled
-		log.Printf("Database will be created at: %s", config.Path)
-	}
+		// Ensure that database directory exists
+		dbDir := filepath.Dir(config.Path)
+		if dbDir != "." && dbDir != "/" {
+			// Directory will be created by manager if auto_create_dirs is enabled
+			log.Printf("Database will be created at: %s", config.Path)
+		}
 
-	// Open SQLite database with proper options
-	// - _journal_mode=WAL: Write-Ahead Logging for better concurrency
-	// - _busy_timeout=5000: Wait up to 5 seconds if database is locked
-	// - _foreign_keys=1: Enable foreign key constraints
-	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=1", config.Path)
+		// Open SQLite database with proper options
+		// - _journal_mode=WAL: Write-Ahead Logging for better concurrency
+		// - _busy_timeout=5000: Wait up to 5 seconds if database is locked
+		// - _foreign_keys=1: Enable foreign key constraints
+		driverName = "sqlite3"
+		dsn = fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=1", config.Path)
+		target = config.Path
+	case DriverPostgres:
+		if config.DSN == "" {
+			return nil, fmt.Errorf("database dsn cannot be empty for the %s driver", DriverPostgres)
+		}
+		driverName = "postgres"
+		dsn = config.DSN
+		target = "postgres"
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", config.Driver)
+	}
 
-	sqlDB, err := sql.Open("sqlite3", dsn)
+	sqlDB, err := sql.Open(driverName, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -95,22 +135,64 @@ func Open(config *Config) (*sqliteDB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Printf("Database connection established: %s", config.Path)
+	log.Printf("Database connection established: %s", target)
 
-	return &sqliteDB{
+	store := &sqlStore{
 		DB:     sqlDB,
 		config: config,
-	}, nil
+		driver: DriverSQLite,
+	}
+	if config.Driver == DriverPostgres {
+		store.driver = DriverPostgres
+	}
+	return store, nil
+}
+
+// rebind rewrites the ? placeholders the queries are written with into the
+// $1, $2, ... form Postgres expects. The queries never contain a literal ?.
+func (db *sqlStore) rebind(query string) string {
+	if db.driver != DriverPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ExecContext runs a statement written with ? placeholders
+func (db *sqlStore) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.rebind(query), args...)
+}
+
+// QueryContext runs a query written with ? placeholders
+func (db *sqlStore) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.rebind(query), args...)
+}
+
+// QueryRowContext runs a single-row query written with ? placeholders
+func (db *sqlStore) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.rebind(query), args...)
 }
 
 // Close closes database connection
-func (db *sqliteDB) Close() error {
+func (db *sqlStore) Close() error {
 	if db.DB != nil {
 		log.Println("Closing database connection")
 
 		// Checkpoint WAL to merge changes back to main database file
-		if _, err := db.DB.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
-			log.Printf("Warning: Failed to checkpoint WAL: %v", err)
+		if db.driver == DriverSQLite {
+			if _, err := db.DB.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+				log.Printf("Warning: Failed to checkpoint WAL: %v", err)
+			}
 		}
 
 		return db.DB.Close()
@@ -119,9 +201,9 @@ func (db *sqliteDB) Close() error {
 }
 
 // HealthCheck verifies that database is accessible
-func (db *sqliteDB) HealthCheck() error {
+func (db *sqlStore) HealthCheck(ctx context.Context) error {
 	if db.DB == nil {
 		return fmt.Errorf("database connection is nil")
 	}
-	return db.DB.Ping()
+	return db.DB.PingContext(ctx)
 }
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"llamactl/pkg/auth"
+	"time"
+)
+
+// fileKeysUserID is the owner of keys loaded from the keys file. They are
+// not listed with the "system" keys managed through the API.
+const fileKeysUserID = "file"
+
+// fileKeyStore merges read-only keys from the keys file into the lookups of
+// the database key store. File keys get negative IDs so they never collide
+// with database keys; their permissions name instances and are resolved to
+// instance IDs on each check, so they follow instances that are re-created.
+type fileKeyStore struct {
+	*sqlStore
+	keys  []*auth.APIKey
+	files map[int]auth.FileKey
+}
+
+// WithFileKeys returns an AuthStore that also accepts the given file keys
+func WithFileKeys(db *sqlStore, keys []auth.FileKey) AuthStore {
+	store := &fileKeyStore{sqlStore: db, files: make(map[int]auth.FileKey, len(keys))}
+	now := time.Now().Unix()
+	for i, fk := range keys {
+		key := &auth.APIKey{
+			ID:             -(i + 1),
+			KeyHash:        fk.KeyHash,
+			Name:           fk.Name,
+			UserID:         fileKeysUserID,
+			PermissionMode: fk.PermissionMode,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if fk.ExpiresAt > 0 {
+			expiresAt := fk.ExpiresAt
+			key.ExpiresAt = &expiresAt
+		}
+		store.keys = append(store.keys, key)
+		store.files[key.ID] = fk
+	}
+	return store
+}
+
+func isFileKeyID(id int) bool {
+	return id < 0
+}
+
+// GetActiveKeys returns the active database keys followed by the active file keys
+func (s *fileKeyStore) GetActiveKeys(ctx context.Context) ([]*auth.APIKey, error) {
+	keys, err := s.sqlStore.GetActiveKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	for _, key := range s.keys {
+		if key.ExpiresAt == nil || *key.ExpiresAt > now {
+			copied := *key
+			keys = append(keys, &copied)
+		}
+	}
+	return keys, nil
+}
+
+// GetKeyByID looks up file keys by their negative ID
+func (s *fileKeyStore) GetKeyByID(ctx context.Context, id int) (*auth.APIKey, error) {
+	if !isFileKeyID(id) {
+		return s.sqlStore.GetKeyByID(ctx, id)
+	}
+	for _, key := range s.keys {
+		if key.ID == id {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// DeleteKey refuses to delete file keys; remove them from the file instead
+func (s *fileKeyStore) DeleteKey(ctx context.Context, id int) error {
+	if isFileKeyID(id) {
+		return fmt.Errorf("API key %d is defined in the keys file and cannot be deleted through the API", id)
+	}
+	return s.sqlStore.DeleteKey(ctx, id)
+}
+
+// TouchKey does not track usage of file keys
+func (s *fileKeyStore) TouchKey(ctx context.Context, id int) error {
+	if isFileKeyID(id) {
+		return nil
+	}
+	return s.sqlStore.TouchKey(ctx, id)
+}
+
+// GetPermissions lists a file key's permissions for the instances that exist
+func (s *fileKeyStore) GetPermissions(ctx context.Context, keyID int) ([]auth.KeyPermission, error) {
+	if !isFileKeyID(keyID) {
+		return s.sqlStore.GetPermissions(ctx, keyID)
+	}
+
+	fk, ok := s.files[keyID]
+	if !ok || fk.PermissionMode != auth.PermissionModePerInstance {
+		return nil, nil
+	}
+
+	var permissions []auth.KeyPermission
+	for _, name := range fk.Instances {
+		id, found, err := s.instanceID(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			permissions = append(permissions, auth.KeyPermission{KeyID: keyID, InstanceID: id, AllowedPaths: fk.AllowedPaths})
+		}
+	}
+	return permissions, nil
+}
+
+// HasPermission checks if key has inference permission for instance
+func (s *fileKeyStore) HasPermission(ctx context.Context, keyID, instanceID int) (bool, error) {
+	perm, err := s.GetPermission(ctx, keyID, instanceID)
+	if err != nil {
+		return false, err
+	}
+	return perm != nil, nil
+}
+
+// GetPermission resolves a file key's instance names to find its permission
+// for the instance, or nil if it has none
+func (s *fileKeyStore) GetPermission(ctx context.Context, keyID, instanceID int) (*auth.KeyPermission, error) {
+	if !isFileKeyID(keyID) {
+		return s.sqlStore.GetPermission(ctx, keyID, instanceID)
+	}
+
+	permissions, err := s.GetPermissions(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	for _, perm := range permissions {
+		if perm.InstanceID == instanceID {
+			return &perm, nil
+		}
+	}
+	return nil, nil
+}
+
+// instanceID returns the ID of the named instance
+func (s *fileKeyStore) instanceID(ctx context.Context, name string) (int, bool, error) {
+	var id int
+	err := s.QueryRowContext(ctx, `SELECT id FROM instances WHERE name = ?`, name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to look up instance %s: %w", name, err)
+	}
+	return id, true, nil
+}
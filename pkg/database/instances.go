@@ -4,25 +4,35 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"llamactl/pkg/instance"
 	"log"
 	"time"
 )
 
+// errInstanceNotFound is returned by GetByName when no instance has the name
+var errInstanceNotFound = errors.New("instance not found")
+
 // instanceRow represents a row in the instances table
 type instanceRow struct {
-	ID          int
-	Name        string
-	Status      string
-	CreatedAt   int64
-	UpdatedAt   int64
-	OptionsJSON string
-	OwnerUserID sql.NullString
+	ID           int
+	Name         string
+	Status       string
+	DesiredState string
+	CreatedAt    int64
+	UpdatedAt    int64
+	OptionsJSON  string
+	OwnerUserID  sql.NullString
+
+	// State change timestamps, 0 when the transition hasn't happened
+	LastStartedAt   int64
+	LastStoppedAt   int64
+	StatusChangedAt int64
 }
 
 // Create inserts a new instance into the database
-func (db *sqliteDB) Create(ctx context.Context, inst *instance.Instance) error {
+func (db *sqlStore) Create(ctx context.Context, inst *instance.Instance) error {
 	if inst == nil {
 		return fmt.Errorf("instance cannot be nil")
 	}
@@ -38,47 +48,72 @@ func (db *sqliteDB) Create(ctx context.Context, inst *instance.Instance) error {
 		return fmt.Errorf("failed to convert instance to row: %w", err)
 	}
 
-	// Insert into database
-	query := `
-		INSERT INTO instances (
-			name, status, created_at, updated_at, options_json, owner_user_id
-		) VALUES (?, ?, ?, ?, ?, ?)
-	`
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	result, err := db.DB.ExecContext(ctx, query,
-		row.Name, row.Status, row.CreatedAt, row.UpdatedAt, row.OptionsJSON, row.OwnerUserID,
-	)
+	// An instance recreated under the name of a deleted one gets its old ID
+	// back, so key permissions granted to it apply again
+	var retiredID sql.NullInt64
+	err = tx.QueryRowContext(ctx, db.rebind(`SELECT id FROM retired_instances WHERE name = ?`), row.Name).Scan(&retiredID)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to look up retired instance ID: %w", err)
+	}
 
-	if err != nil {
+	// Insert into database, letting the database pick the ID unless an old
+	// one is reused
+	columns := "name, status, desired_state, created_at, updated_at, options_json, owner_user_id, last_started_at, last_stopped_at, status_changed_at"
+	values := "?, ?, ?, ?, ?, ?, ?, ?, ?, ?"
+	args := []any{
+		row.Name, row.Status, row.DesiredState, row.CreatedAt, row.UpdatedAt, row.OptionsJSON, row.OwnerUserID,
+		row.LastStartedAt, row.LastStoppedAt, row.StatusChangedAt,
+	}
+	if retiredID.Valid {
+		columns = "id, " + columns
+		values = "?, " + values
+		args = append([]any{retiredID.Int64}, args...)
+	}
+	query := fmt.Sprintf("INSERT INTO instances (%s) VALUES (%s) RETURNING id", columns, values)
+
+	var id int
+	if err := tx.QueryRowContext(ctx, db.rebind(query), args...).Scan(&id); err != nil {
 		return fmt.Errorf("failed to insert instance: %w", err)
 	}
 
-	// Get the auto-generated ID and set it on the instance
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get last insert ID: %w", err)
+	if retiredID.Valid {
+		if _, err := tx.ExecContext(ctx, db.rebind(`DELETE FROM retired_instances WHERE name = ?`), row.Name); err != nil {
+			return fmt.Errorf("failed to clear retired instance ID: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit instance: %w", err)
 	}
 
-	inst.ID = int(id)
+	inst.ID = id
 
 	return nil
 }
 
 // GetByName retrieves an instance by name
-func (db *sqliteDB) GetByName(ctx context.Context, name string) (*instance.Instance, error) {
+func (db *sqlStore) GetByName(ctx context.Context, name string) (*instance.Instance, error) {
 	query := `
-		SELECT id, name, status, created_at, updated_at, options_json, owner_user_id
+		SELECT id, name, status, desired_state, created_at, updated_at, options_json, owner_user_id,
+			last_started_at, last_stopped_at, status_changed_at
 		FROM instances
 		WHERE name = ?
 	`
 
 	var row instanceRow
-	err := db.DB.QueryRowContext(ctx, query, name).Scan(
-		&row.ID, &row.Name, &row.Status, &row.CreatedAt, &row.UpdatedAt, &row.OptionsJSON, &row.OwnerUserID,
+	err := db.QueryRowContext(ctx, query, name).Scan(
+		&row.ID, &row.Name, &row.Status, &row.DesiredState, &row.CreatedAt, &row.UpdatedAt, &row.OptionsJSON, &row.OwnerUserID,
+		&row.LastStartedAt, &row.LastStoppedAt, &row.StatusChangedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("instance not found: %s", name)
+		return nil, fmt.Errorf("%w: %s", errInstanceNotFound, name)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to query instance: %w", err)
@@ -88,14 +123,15 @@ func (db *sqliteDB) GetByName(ctx context.Context, name string) (*instance.Insta
 }
 
 // GetAll retrieves all instances from the database
-func (db *sqliteDB) GetAll(ctx context.Context) ([]*instance.Instance, error) {
+func (db *sqlStore) GetAll(ctx context.Context) ([]*instance.Instance, error) {
 	query := `
-		SELECT id, name, status, created_at, updated_at, options_json, owner_user_id
+		SELECT id, name, status, desired_state, created_at, updated_at, options_json, owner_user_id,
+			last_started_at, last_stopped_at, status_changed_at
 		FROM instances
 		ORDER BY created_at ASC
 	`
 
-	rows, err := db.DB.QueryContext(ctx, query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query instances: %w", err)
 	}
@@ -105,7 +141,8 @@ func (db *sqliteDB) GetAll(ctx context.Context) ([]*instance.Instance, error) {
 	for rows.Next() {
 		var row instanceRow
 		err := rows.Scan(
-			&row.ID, &row.Name, &row.Status, &row.CreatedAt, &row.UpdatedAt, &row.OptionsJSON, &row.OwnerUserID,
+			&row.ID, &row.Name, &row.Status, &row.DesiredState, &row.CreatedAt, &row.UpdatedAt, &row.OptionsJSON, &row.OwnerUserID,
+			&row.LastStartedAt, &row.LastStoppedAt, &row.StatusChangedAt,
 		)
 		if err != nil {
 			log.Printf("Failed to scan instance row: %v", err)
@@ -129,7 +166,7 @@ func (db *sqliteDB) GetAll(ctx context.Context) ([]*instance.Instance, error) {
 }
 
 // Update updates an existing instance
-func (db *sqliteDB) Update(ctx context.Context, inst *instance.Instance) error {
+func (db *sqlStore) Update(ctx context.Context, inst *instance.Instance) error {
 	if inst == nil {
 		return fmt.Errorf("instance cannot be nil")
 	}
@@ -148,12 +185,14 @@ func (db *sqliteDB) Update(ctx context.Context, inst *instance.Instance) error {
 	// Update in database
 	query := `
 		UPDATE instances SET
-			status = ?, updated_at = ?, options_json = ?
+			status = ?, desired_state = ?, updated_at = ?, options_json = ?,
+			last_started_at = ?, last_stopped_at = ?, status_changed_at = ?
 		WHERE name = ?
 	`
 
-	result, err := db.DB.ExecContext(ctx, query,
-		row.Status, row.UpdatedAt, row.OptionsJSON, row.Name,
+	result, err := db.ExecContext(ctx, query,
+		row.Status, row.DesiredState, row.UpdatedAt, row.OptionsJSON,
+		row.LastStartedAt, row.LastStoppedAt, row.StatusChangedAt, row.Name,
 	)
 
 	if err != nil {
@@ -173,7 +212,7 @@ func (db *sqliteDB) Update(ctx context.Context, inst *instance.Instance) error {
 }
 
 // UpdateStatus updates only the status of an instance (optimized operation)
-func (db *sqliteDB) UpdateStatus(ctx context.Context, name string, status instance.Status) error {
+func (db *sqlStore) UpdateStatus(ctx context.Context, name string, status instance.Status) error {
 	// Convert status to string
 	statusJSON, err := status.MarshalJSON()
 	if err != nil {
@@ -191,7 +230,7 @@ func (db *sqliteDB) UpdateStatus(ctx context.Context, name string, status instan
 		WHERE name = ?
 	`
 
-	result, err := db.DB.ExecContext(ctx, query, statusStr, time.Now().Unix(), name)
+	result, err := db.ExecContext(ctx, query, statusStr, time.Now().Unix(), name)
 	if err != nil {
 		return fmt.Errorf("failed to update instance status: %w", err)
 	}
@@ -209,10 +248,25 @@ func (db *sqliteDB) UpdateStatus(ctx context.Context, name string, status instan
 }
 
 // DeleteInstance removes an instance from the database
-func (db *sqliteDB) DeleteInstance(ctx context.Context, name string) error {
-	query := `DELETE FROM instances WHERE name = ?`
+func (db *sqlStore) DeleteInstance(ctx context.Context, name string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Remember the ID for when the name is reused. Key permissions for the
+	// instance are kept and become active again at that point.
+	_, err = tx.ExecContext(ctx, db.rebind(`
+		INSERT INTO retired_instances (name, id)
+		SELECT name, id FROM instances WHERE name = ?
+		ON CONFLICT (name) DO UPDATE SET id = excluded.id
+	`), name)
+	if err != nil {
+		return fmt.Errorf("failed to retire instance ID: %w", err)
+	}
 
-	result, err := db.DB.ExecContext(ctx, query, name)
+	result, err := tx.ExecContext(ctx, db.rebind(`DELETE FROM instances WHERE name = ?`), name)
 	if err != nil {
 		return fmt.Errorf("failed to delete instance: %w", err)
 	}
@@ -226,11 +280,11 @@ func (db *sqliteDB) DeleteInstance(ctx context.Context, name string) error {
 		return fmt.Errorf("instance not found: %s", name)
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // instanceToRow converts an Instance to a database row
-func (db *sqliteDB) instanceToRow(inst *instance.Instance) (*instanceRow, error) {
+func (db *sqlStore) instanceToRow(inst *instance.Instance) (*instanceRow, error) {
 	opts := inst.GetOptions()
 	if opts == nil {
 		return nil, fmt.Errorf("instance options cannot be nil")
@@ -252,17 +306,23 @@ func (db *sqliteDB) instanceToRow(inst *instance.Instance) (*instanceRow, error)
 		return nil, fmt.Errorf("failed to unmarshal status string: %w", err)
 	}
 
+	timestamps := inst.GetStateTimestamps()
+
 	return &instanceRow{
-		Name:        inst.Name,
-		Status:      statusStr,
-		CreatedAt:   inst.Created,
-		UpdatedAt:   time.Now().Unix(),
-		OptionsJSON: string(optionsJSON),
+		Name:            inst.Name,
+		Status:          statusStr,
+		DesiredState:    string(inst.GetDesiredState()),
+		CreatedAt:       inst.Created,
+		UpdatedAt:       time.Now().Unix(),
+		OptionsJSON:     string(optionsJSON),
+		LastStartedAt:   timestamps.LastStartedAt,
+		LastStoppedAt:   timestamps.LastStoppedAt,
+		StatusChangedAt: timestamps.StatusChangedAt,
 	}, nil
 }
 
 // rowToInstance converts a database row to an Instance
-func (db *sqliteDB) rowToInstance(row *instanceRow) (*instance.Instance, error) {
+func (db *sqlStore) rowToInstance(row *instanceRow) (*instance.Instance, error) {
 	// Unmarshal options from JSON using the existing UnmarshalJSON method
 	var opts instance.Options
 	if err := json.Unmarshal([]byte(row.OptionsJSON), &opts); err != nil {
@@ -271,11 +331,15 @@ func (db *sqliteDB) rowToInstance(row *instanceRow) (*instance.Instance, error)
 
 	// Build complete instance JSON with all fields
 	instanceJSON, err := json.Marshal(map[string]any{
-		"id":      row.ID,
-		"name":    row.Name,
-		"created": row.CreatedAt,
-		"status":  row.Status,
-		"options": json.RawMessage(row.OptionsJSON),
+		"id":                row.ID,
+		"name":              row.Name,
+		"created":           row.CreatedAt,
+		"status":            row.Status,
+		"desired_state":     row.DesiredState,
+		"last_started_at":   row.LastStartedAt,
+		"last_stopped_at":   row.LastStoppedAt,
+		"status_changed_at": row.StatusChangedAt,
+		"options":           json.RawMessage(row.OptionsJSON),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal instance: %w", err)
@@ -297,32 +361,32 @@ func (db *sqliteDB) rowToInstance(row *instanceRow) (*instance.Instance, error)
 // Database interface implementation
 
 // Save saves an instance to the database (insert or update)
-func (db *sqliteDB) Save(inst *instance.Instance) error {
+func (db *sqlStore) Save(inst *instance.Instance) error {
 	ctx := context.Background()
+	return retryErr(ctx, "save instance", func() error {
+		// Try to get existing instance
+		_, err := db.GetByName(ctx, inst.Name)
+		if errors.Is(err, errInstanceNotFound) {
+			// Instance doesn't exist, create it
+			return db.Create(ctx, inst)
+		}
+		if err != nil {
+			return err
+		}
 
-	// Try to get existing instance
-	existing, err := db.GetByName(ctx, inst.Name)
-	if err != nil {
-		// Instance doesn't exist, create it
-		return db.Create(ctx, inst)
-	}
-
-	// Instance exists, update it
-	if existing != nil {
+		// Instance exists, update it
 		return db.Update(ctx, inst)
-	}
-
-	return db.Create(ctx, inst)
+	})
 }
 
 // Delete removes an instance from the database
-func (db *sqliteDB) Delete(name string) error {
+func (db *sqlStore) Delete(name string) error {
 	ctx := context.Background()
-	return db.DeleteInstance(ctx, name)
+	return retryErr(ctx, "delete instance", func() error { return db.DeleteInstance(ctx, name) })
 }
 
 // LoadAll loads all instances from the database
-func (db *sqliteDB) LoadAll() ([]*instance.Instance, error) {
+func (db *sqlStore) LoadAll() ([]*instance.Instance, error) {
 	ctx := context.Background()
-	return db.GetAll(ctx)
+	return retry(ctx, "load instances", func() ([]*instance.Instance, error) { return db.GetAll(ctx) })
 }
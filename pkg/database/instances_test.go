@@ -0,0 +1,55 @@
+package database
+
+import (
+	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
+	"llamactl/pkg/instance"
+	"path/filepath"
+	"testing"
+)
+
+func TestSave_PersistsStateTimestamps(t *testing.T) {
+	db, err := Open(&Config{Path: filepath.Join(t.TempDir(), "llamactl.db")})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+
+	options := &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+			},
+		},
+	}
+	inst := instance.New("llama", &config.AppConfig{}, options, nil)
+
+	want := instance.StateTimestamps{LastStartedAt: 100, LastStoppedAt: 200, StatusChangedAt: 200}
+	inst.SetStateTimestamps(want)
+	if err := db.Save(inst); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// Updates must keep the timestamps current too
+	want.LastStartedAt = 300
+	want.StatusChangedAt = 300
+	inst.SetStateTimestamps(want)
+	if err := db.Save(inst); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := db.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(loaded) != 1 {
+		t.Fatalf("Expected 1 instance, got %d", len(loaded))
+	}
+	if got := loaded[0].GetStateTimestamps(); got != want {
+		t.Errorf("Expected timestamps %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"llamactl/pkg/instance"
+	"sync"
+)
+
+// MemoryStore is an InstanceStore that keeps instances in memory, for tests
+// that need a manager but not a database. Instances are stored as JSON, so
+// like the database it returns copies and only keeps what is persisted.
+type MemoryStore struct {
+	mu        sync.Mutex
+	instances map[string][]byte
+	order     []string // names in insertion order, like the database's created_at ordering
+	ids       map[string]int
+	nextID    int
+}
+
+var _ InstanceStore = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty in-memory instance store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		instances: make(map[string][]byte),
+		ids:       make(map[string]int),
+		nextID:    1,
+	}
+}
+
+// Save inserts or updates an instance, assigning an ID on first save
+func (s *MemoryStore) Save(inst *instance.Instance) error {
+	if inst == nil {
+		return fmt.Errorf("instance cannot be nil")
+	}
+	if inst.GetOptions() == nil {
+		return fmt.Errorf("instance options cannot be nil")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Deleted names keep their ID, as in the database
+	id, ok := s.ids[inst.Name]
+	if !ok {
+		id = s.nextID
+		s.nextID++
+		s.ids[inst.Name] = id
+	}
+	inst.ID = id
+
+	data, err := json.Marshal(inst)
+	if err != nil {
+		return fmt.Errorf("failed to marshal instance: %w", err)
+	}
+
+	if _, exists := s.instances[inst.Name]; !exists {
+		s.order = append(s.order, inst.Name)
+	}
+	s.instances[inst.Name] = data
+	return nil
+}
+
+// Delete removes an instance
+func (s *MemoryStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.instances[name]; !exists {
+		return fmt.Errorf("instance not found: %s", name)
+	}
+	delete(s.instances, name)
+	for i, n := range s.order {
+		if n == name {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// LoadAll returns copies of all saved instances in the order they were added
+func (s *MemoryStore) LoadAll() ([]*instance.Instance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	instances := make([]*instance.Instance, 0, len(s.order))
+	for _, name := range s.order {
+		var inst instance.Instance
+		if err := json.Unmarshal(s.instances[name], &inst); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal instance %s: %w", name, err)
+		}
+		instances = append(instances, &inst)
+	}
+	return instances, nil
+}
+
+// Close is a no-op
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
+	"llamactl/pkg/instance"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	newInstance := func(name string) *instance.Instance {
+		return instance.New(name, &config.AppConfig{}, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}, nil)
+	}
+
+	first, second := newInstance("first"), newInstance("second")
+	for _, inst := range []*instance.Instance{first, second} {
+		if err := store.Save(inst); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+	if first.ID != 1 || second.ID != 2 {
+		t.Errorf("Expected IDs 1 and 2, got %d and %d", first.ID, second.ID)
+	}
+
+	// Changes after saving aren't visible until the next save
+	first.SetDesiredState(instance.DesiredRunning)
+	loaded, err := store.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if len(loaded) != 2 || loaded[0].Name != "first" || loaded[1].Name != "second" {
+		t.Fatalf("Expected first and second in order, got %v", loaded)
+	}
+	if loaded[0].GetDesiredState() != instance.DesiredStopped {
+		t.Error("Expected the store to keep the saved state, not the live instance")
+	}
+	if loaded[0].GetOptions().BackendOptions.LlamaServerOptions.Model != "/path/to/model.gguf" {
+		t.Error("Expected backend options to be restored")
+	}
+
+	if err := store.Delete("first"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Delete("first"); err == nil {
+		t.Error("Expected deleting a missing instance to fail")
+	}
+
+	// A recreated instance gets its old ID back
+	recreated := newInstance("first")
+	if err := store.Save(recreated); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if recreated.ID != 1 {
+		t.Errorf("Expected recreated instance to reuse ID 1, got %d", recreated.ID)
+	}
+}
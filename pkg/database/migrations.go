@@ -6,33 +6,46 @@ import (
 	"log"
 
 	"github.com/golang-migrate/migrate/v4"
+	migratedb "github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-//go:embed migrations/*.sql
+// SQLite migrations live in migrations/, their Postgres equivalents under
+// the same version numbers in migrations/postgres/
+//
+//go:embed migrations/*.sql migrations/postgres/*.sql
 var migrationFiles embed.FS
 
 // RunMigrations applies all pending database migrations
-func RunMigrations(db *sqliteDB) error {
+func RunMigrations(db *sqlStore) error {
 	if db == nil || db.DB == nil {
 		return fmt.Errorf("database connection is nil")
 	}
 
-	// Create migration source from embedded files
-	sourceDriver, err := iofs.New(migrationFiles, "migrations")
+	// Create migration source and database driver for the configured database
+	migrationsDir := "migrations"
+	var dbDriver migratedb.Driver
+	var err error
+	switch db.driver {
+	case DriverPostgres:
+		migrationsDir = "migrations/postgres"
+		dbDriver, err = postgres.WithInstance(db.DB, &postgres.Config{})
+	default:
+		dbDriver, err = sqlite3.WithInstance(db.DB, &sqlite3.Config{})
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create migration source: %w", err)
+		return fmt.Errorf("failed to create database driver: %w", err)
 	}
 
-	// Create database driver for migrations
-	dbDriver, err := sqlite3.WithInstance(db.DB, &sqlite3.Config{})
+	sourceDriver, err := iofs.New(migrationFiles, migrationsDir)
 	if err != nil {
-		return fmt.Errorf("failed to create database driver: %w", err)
+		return fmt.Errorf("failed to create migration source: %w", err)
 	}
 
 	// Create migrator
-	migrator, err := migrate.NewWithInstance("iofs", sourceDriver, "sqlite3", dbDriver)
+	migrator, err := migrate.NewWithInstance("iofs", sourceDriver, db.driver, dbDriver)
 	if err != nil {
 		return fmt.Errorf("failed to create migrator: %w", err)
 	}
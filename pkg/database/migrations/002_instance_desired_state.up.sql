@@ -0,0 +1,8 @@
+-- -----------------------------------------------------------------------------
+-- Desired state: what the user asked for, persisted separately from the
+-- observed status so intent survives crashes and restarts
+-- -----------------------------------------------------------------------------
+ALTER TABLE instances ADD COLUMN desired_state TEXT NOT NULL CHECK(desired_state IN ('stopped', 'running')) DEFAULT 'stopped';
+
+-- Instances persisted as running were meant to be running
+UPDATE instances SET desired_state = 'running' WHERE status = 'running';
@@ -0,0 +1,30 @@
+-- Read-only keys have no equivalent in the old schema; turn them into
+-- per-instance keys without permissions so they lose access rather than gain it
+CREATE TEMP TABLE key_permissions_backup AS SELECT key_id, instance_id FROM key_permissions;
+
+CREATE TABLE api_keys_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    key_hash TEXT NOT NULL,
+    name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    permission_mode TEXT NOT NULL CHECK(permission_mode IN ('allow_all', 'per_instance')) DEFAULT 'per_instance',
+    expires_at INTEGER NULL,
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL,
+    last_used_at INTEGER NULL
+);
+
+INSERT INTO api_keys_new (id, key_hash, name, user_id, permission_mode, expires_at, created_at, updated_at, last_used_at)
+SELECT id, key_hash, name, user_id,
+       CASE WHEN permission_mode = 'read_only' THEN 'per_instance' ELSE permission_mode END,
+       expires_at, created_at, updated_at, last_used_at
+FROM api_keys;
+
+DROP TABLE api_keys;
+ALTER TABLE api_keys_new RENAME TO api_keys;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_expires_at ON api_keys(expires_at);
+
+INSERT INTO key_permissions (key_id, instance_id) SELECT key_id, instance_id FROM key_permissions_backup;
+DROP TABLE key_permissions_backup;
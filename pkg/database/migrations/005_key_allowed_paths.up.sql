@@ -0,0 +1,5 @@
+-- -----------------------------------------------------------------------------
+-- Backend path allowlist for per-instance key permissions, stored as a JSON
+-- array of patterns. NULL allows every path.
+-- -----------------------------------------------------------------------------
+ALTER TABLE key_permissions ADD COLUMN allowed_paths TEXT NULL;
@@ -0,0 +1,20 @@
+CREATE TABLE key_permissions_old (
+    key_id INTEGER NOT NULL,
+    instance_id INTEGER NOT NULL,
+    allowed_paths TEXT NULL,
+    PRIMARY KEY (key_id, instance_id),
+    FOREIGN KEY (key_id) REFERENCES api_keys (id) ON DELETE CASCADE,
+    FOREIGN KEY (instance_id) REFERENCES instances (id) ON DELETE CASCADE
+);
+
+-- Permissions of deleted instances have nothing to reference any more
+INSERT INTO key_permissions_old (key_id, instance_id, allowed_paths)
+SELECT key_id, instance_id, allowed_paths FROM key_permissions
+WHERE instance_id IN (SELECT id FROM instances);
+
+DROP TABLE key_permissions;
+ALTER TABLE key_permissions_old RENAME TO key_permissions;
+
+CREATE INDEX IF NOT EXISTS idx_key_permissions_instance_id ON key_permissions(instance_id);
+
+DROP TABLE IF EXISTS retired_instances;
@@ -0,0 +1,27 @@
+-- -----------------------------------------------------------------------------
+-- Keep key permissions across instance deletion. A deleted instance's ID is
+-- remembered by name in retired_instances and reused when an instance with
+-- that name is created again, so keys granted to it keep working. SQLite can't
+-- drop a foreign key, so key_permissions is rebuilt without the cascading
+-- reference to instances.
+-- -----------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS retired_instances (
+    name TEXT PRIMARY KEY,
+    id INTEGER NOT NULL UNIQUE
+);
+
+CREATE TABLE key_permissions_new (
+    key_id INTEGER NOT NULL,
+    instance_id INTEGER NOT NULL,
+    allowed_paths TEXT NULL,
+    PRIMARY KEY (key_id, instance_id),
+    FOREIGN KEY (key_id) REFERENCES api_keys (id) ON DELETE CASCADE
+);
+
+INSERT INTO key_permissions_new (key_id, instance_id, allowed_paths)
+SELECT key_id, instance_id, allowed_paths FROM key_permissions;
+
+DROP TABLE key_permissions;
+ALTER TABLE key_permissions_new RENAME TO key_permissions;
+
+CREATE INDEX IF NOT EXISTS idx_key_permissions_instance_id ON key_permissions(instance_id);
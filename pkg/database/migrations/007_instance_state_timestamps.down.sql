@@ -0,0 +1,3 @@
+ALTER TABLE instances DROP COLUMN status_changed_at;
+ALTER TABLE instances DROP COLUMN last_stopped_at;
+ALTER TABLE instances DROP COLUMN last_started_at;
@@ -0,0 +1,7 @@
+-- Read-only keys have no equivalent in the old schema; turn them into
+-- per-instance keys without permissions so they lose access rather than gain it
+UPDATE api_keys SET permission_mode = 'per_instance' WHERE permission_mode = 'read_only';
+
+ALTER TABLE api_keys DROP CONSTRAINT IF EXISTS api_keys_permission_mode_check;
+ALTER TABLE api_keys ADD CONSTRAINT api_keys_permission_mode_check
+    CHECK(permission_mode IN ('allow_all', 'per_instance'));
@@ -0,0 +1,6 @@
+-- -----------------------------------------------------------------------------
+-- Read-only API keys: allow 'read_only' as a permission mode
+-- -----------------------------------------------------------------------------
+ALTER TABLE api_keys DROP CONSTRAINT IF EXISTS api_keys_permission_mode_check;
+ALTER TABLE api_keys ADD CONSTRAINT api_keys_permission_mode_check
+    CHECK(permission_mode IN ('allow_all', 'per_instance', 'read_only'));
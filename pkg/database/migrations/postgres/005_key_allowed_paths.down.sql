@@ -0,0 +1 @@
+ALTER TABLE key_permissions DROP COLUMN allowed_paths;
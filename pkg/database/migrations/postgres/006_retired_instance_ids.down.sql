@@ -0,0 +1,7 @@
+-- Permissions of deleted instances have nothing to reference any more
+DELETE FROM key_permissions WHERE instance_id NOT IN (SELECT id FROM instances);
+
+ALTER TABLE key_permissions ADD CONSTRAINT key_permissions_instance_id_fkey
+    FOREIGN KEY (instance_id) REFERENCES instances (id) ON DELETE CASCADE;
+
+DROP TABLE IF EXISTS retired_instances;
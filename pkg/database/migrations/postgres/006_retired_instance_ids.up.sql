@@ -0,0 +1,11 @@
+-- -----------------------------------------------------------------------------
+-- Keep key permissions across instance deletion. A deleted instance's ID is
+-- remembered by name in retired_instances and reused when an instance with
+-- that name is created again, so keys granted to it keep working.
+-- -----------------------------------------------------------------------------
+CREATE TABLE IF NOT EXISTS retired_instances (
+    name TEXT PRIMARY KEY,
+    id INTEGER NOT NULL UNIQUE
+);
+
+ALTER TABLE key_permissions DROP CONSTRAINT IF EXISTS key_permissions_instance_id_fkey;
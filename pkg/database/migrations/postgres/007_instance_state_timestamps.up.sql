@@ -0,0 +1,7 @@
+-- -----------------------------------------------------------------------------
+-- State change timestamps (Unix seconds, 0 = never), so uptime and time since
+-- the last crash survive restarts
+-- -----------------------------------------------------------------------------
+ALTER TABLE instances ADD COLUMN last_started_at BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE instances ADD COLUMN last_stopped_at BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE instances ADD COLUMN status_changed_at BIGINT NOT NULL DEFAULT 0;
@@ -3,14 +3,15 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"llamactl/pkg/auth"
 )
 
-// GetPermissions retrieves all permissions for a key
-func (db *sqliteDB) GetPermissions(ctx context.Context, keyID int) ([]auth.KeyPermission, error) {
+// getPermissions retrieves all permissions for a key
+func (db *sqlStore) getPermissions(ctx context.Context, keyID int) ([]auth.KeyPermission, error) {
 	query := `
-		SELECT key_id, instance_id
+		SELECT key_id, instance_id, allowed_paths
 		FROM key_permissions
 		WHERE key_id = ?
 		ORDER BY instance_id
@@ -25,10 +26,14 @@ func (db *sqliteDB) GetPermissions(ctx context.Context, keyID int) ([]auth.KeyPe
 	var permissions []auth.KeyPermission
 	for rows.Next() {
 		var perm auth.KeyPermission
-		err := rows.Scan(&perm.KeyID, &perm.InstanceID)
+		var allowedPaths sql.NullString
+		err := rows.Scan(&perm.KeyID, &perm.InstanceID, &allowedPaths)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan key permission: %w", err)
 		}
+		if perm.AllowedPaths, err = decodeAllowedPaths(allowedPaths); err != nil {
+			return nil, err
+		}
 		permissions = append(permissions, perm)
 	}
 
@@ -36,22 +41,59 @@ func (db *sqliteDB) GetPermissions(ctx context.Context, keyID int) ([]auth.KeyPe
 }
 
 // HasPermission checks if key has inference permission for instance
-func (db *sqliteDB) HasPermission(ctx context.Context, keyID, instanceID int) (bool, error) {
+func (db *sqlStore) HasPermission(ctx context.Context, keyID, instanceID int) (bool, error) {
+	perm, err := db.GetPermission(ctx, keyID, instanceID)
+	if err != nil {
+		return false, err
+	}
+	return perm != nil, nil
+}
+
+// getPermission retrieves the key's permission for one instance, or nil if
+// the key has no access to it
+func (db *sqlStore) getPermission(ctx context.Context, keyID, instanceID int) (*auth.KeyPermission, error) {
 	query := `
-		SELECT 1
+		SELECT key_id, instance_id, allowed_paths
 		FROM key_permissions
 		WHERE key_id = ? AND instance_id = ?
 	`
 
-	var exists int
-	err := db.QueryRowContext(ctx, query, keyID, instanceID).Scan(&exists)
+	var perm auth.KeyPermission
+	var allowedPaths sql.NullString
+	err := db.QueryRowContext(ctx, query, keyID, instanceID).Scan(&perm.KeyID, &perm.InstanceID, &allowedPaths)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// No permission record found, deny access
-			return false, nil
+			return nil, nil
 		}
-		return false, fmt.Errorf("failed to check key permission: %w", err)
+		return nil, fmt.Errorf("failed to check key permission: %w", err)
+	}
+	if perm.AllowedPaths, err = decodeAllowedPaths(allowedPaths); err != nil {
+		return nil, err
 	}
 
-	return true, nil
+	return &perm, nil
+}
+
+// encodeAllowedPaths stores an empty allowlist as NULL
+func encodeAllowedPaths(paths []string) (sql.NullString, error) {
+	if len(paths) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(paths)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode allowed paths: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+func decodeAllowedPaths(value sql.NullString) ([]string, error) {
+	if !value.Valid || value.String == "" {
+		return nil, nil
+	}
+	var paths []string
+	if err := json.Unmarshal([]byte(value.String), &paths); err != nil {
+		return nil, fmt.Errorf("failed to decode allowed paths: %w", err)
+	}
+	return paths, nil
 }
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"llamactl/pkg/auth"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Store operations failing with a transient error are retried up to
+// retryAttempts times in total, waiting retryBaseDelay before the second
+// attempt and doubling the wait after each further failure.
+var (
+	retryAttempts  = 4
+	retryBaseDelay = 50 * time.Millisecond
+)
+
+// isTransient reports whether err is likely to go away when the operation is
+// retried: a locked database, a lost connection, or a Postgres serialization
+// failure or deadlock. Errors such as a closed handle or a constraint
+// violation are permanent.
+func isTransient(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "40": // connection exception, transaction rollback
+			return true
+		}
+		return pqErr.Code == "57P03" // cannot_connect_now, e.g. while starting up
+	}
+	return errors.Is(err, driver.ErrBadConn)
+}
+
+// retry runs op until it succeeds, fails with a permanent error, runs out of
+// attempts or ctx is done
+func retry[T any](ctx context.Context, name string, op func() (T, error)) (T, error) {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		result, err := op()
+		if err == nil || !isTransient(err) || attempt == retryAttempts {
+			return result, err
+		}
+
+		log.Printf("Database %s failed (attempt %d/%d), retrying in %v: %v", name, attempt, retryAttempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return result, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// retryErr is retry for operations without a result
+func retryErr(ctx context.Context, name string, op func() error) error {
+	_, err := retry(ctx, name, func() (struct{}, error) { return struct{}{}, op() })
+	return err
+}
+
+// AuthStore implementation, with retries around the queries in apikeys.go,
+// permissions.go and audit.go
+
+// CreateKey inserts a new API key with permissions (transactional)
+func (db *sqlStore) CreateKey(ctx context.Context, key *auth.APIKey, permissions []auth.KeyPermission) error {
+	return retryErr(ctx, "create key", func() error { return db.createKey(ctx, key, permissions) })
+}
+
+// GetKeyByID retrieves an API key by ID
+func (db *sqlStore) GetKeyByID(ctx context.Context, id int) (*auth.APIKey, error) {
+	return retry(ctx, "get key", func() (*auth.APIKey, error) { return db.getKeyByID(ctx, id) })
+}
+
+// GetUserKeys retrieves all API keys for a user
+func (db *sqlStore) GetUserKeys(ctx context.Context, userID string) ([]*auth.APIKey, error) {
+	return retry(ctx, "list keys", func() ([]*auth.APIKey, error) { return db.getUserKeys(ctx, userID) })
+}
+
+// GetActiveKeys retrieves all non-expired API keys
+func (db *sqlStore) GetActiveKeys(ctx context.Context) ([]*auth.APIKey, error) {
+	return retry(ctx, "list active keys", func() ([]*auth.APIKey, error) { return db.getActiveKeys(ctx) })
+}
+
+// DeleteKey removes an API key (cascades to permissions)
+func (db *sqlStore) DeleteKey(ctx context.Context, id int) error {
+	return retryErr(ctx, "delete key", func() error { return db.deleteKey(ctx, id) })
+}
+
+// TouchKey updates the last_used_at timestamp
+func (db *sqlStore) TouchKey(ctx context.Context, id int) error {
+	return retryErr(ctx, "touch key", func() error { return db.touchKey(ctx, id) })
+}
+
+// GetPermissions retrieves all permissions for a key
+func (db *sqlStore) GetPermissions(ctx context.Context, keyID int) ([]auth.KeyPermission, error) {
+	return retry(ctx, "list permissions", func() ([]auth.KeyPermission, error) { return db.getPermissions(ctx, keyID) })
+}
+
+// GetPermission retrieves the key's permission for one instance, or nil if
+// the key has no access to it
+func (db *sqlStore) GetPermission(ctx context.Context, keyID, instanceID int) (*auth.KeyPermission, error) {
+	return retry(ctx, "get permission", func() (*auth.KeyPermission, error) { return db.getPermission(ctx, keyID, instanceID) })
+}
+
+// RecordAudit appends an entry to the audit log
+func (db *sqlStore) RecordAudit(ctx context.Context, entry *auth.AuditEntry) error {
+	return retryErr(ctx, "record audit", func() error { return db.recordAudit(ctx, entry) })
+}
+
+// ListAudit returns audit entries matching filter, newest first
+func (db *sqlStore) ListAudit(ctx context.Context, filter auth.AuditFilter) ([]*auth.AuditEntry, error) {
+	return retry(ctx, "list audit", func() ([]*auth.AuditEntry, error) { return db.listAudit(ctx, filter) })
+}
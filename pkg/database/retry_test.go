@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestRetry(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = 50 * time.Millisecond })
+
+	busy := sqlite3.Error{Code: sqlite3.ErrBusy}
+	permanent := errors.New("UNIQUE constraint failed")
+
+	tests := []struct {
+		name      string
+		failures  []error
+		wantCalls int
+		wantErr   error
+	}{
+		{"succeeds first time", nil, 1, nil},
+		{"recovers from busy database", []error{busy, busy}, 3, nil},
+		{"gives up after max attempts", []error{busy, busy, busy, busy, busy}, retryAttempts, busy},
+		{"permanent error is not retried", []error{permanent}, 1, permanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retryErr(context.Background(), "test", func() error {
+				calls++
+				if calls <= len(tt.failures) {
+					return tt.failures[calls-1]
+				}
+				return nil
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("Expected %d calls, got %d", tt.wantCalls, calls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT id FROM instances WHERE name = ? AND status = ?"
+
+	sqlite := &sqlStore{driver: DriverSQLite}
+	if got := sqlite.rebind(query); got != query {
+		t.Errorf("Expected sqlite query to be unchanged, got %q", got)
+	}
+
+	postgres := &sqlStore{driver: DriverPostgres}
+	want := "SELECT id FROM instances WHERE name = $1 AND status = $2"
+	if got := postgres.rebind(query); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
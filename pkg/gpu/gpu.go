@@ -0,0 +1,160 @@
+// Package gpu reports GPU memory and utilization by querying vendor tools
+// (nvidia-smi for NVIDIA, rocm-smi for AMD) available on the local host.
+package gpu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Device describes a single GPU and its current memory usage
+type Device struct {
+	Index              int    `json:"index"`
+	Vendor             string `json:"vendor"`
+	Name               string `json:"name"`
+	UUID               string `json:"uuid,omitempty"`
+	MemoryTotalMB      int64  `json:"memory_total_mb"`
+	MemoryUsedMB       int64  `json:"memory_used_mb"`
+	MemoryFreeMB       int64  `json:"memory_free_mb"`
+	UtilizationPercent int    `json:"utilization_percent"`
+}
+
+const (
+	VendorNvidia = "nvidia"
+	VendorAMD    = "amd"
+)
+
+var nvidiaQueryFields = []string{
+	"index", "name", "uuid", "memory.total", "memory.used", "memory.free", "utilization.gpu",
+}
+
+// List returns all GPUs reported by the available vendor tools.
+// Hosts without any supported tool yield an empty list rather than an error.
+func List(ctx context.Context) ([]Device, error) {
+	devices := []Device{}
+	var errs []error
+
+	if path, err := exec.LookPath("nvidia-smi"); err == nil {
+		output, err := exec.CommandContext(ctx, path,
+			"--query-gpu="+strings.Join(nvidiaQueryFields, ","),
+			"--format=csv,noheader,nounits",
+		).Output()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("nvidia-smi failed: %w", err))
+		} else if nvidia, err := parseNvidiaSmi(string(output)); err != nil {
+			errs = append(errs, err)
+		} else {
+			devices = append(devices, nvidia...)
+		}
+	}
+
+	if path, err := exec.LookPath("rocm-smi"); err == nil {
+		output, err := exec.CommandContext(ctx, path,
+			"--showproductname", "--showmeminfo", "vram", "--showuse", "--json",
+		).Output()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rocm-smi failed: %w", err))
+		} else if amd, err := parseRocmSmi(output); err != nil {
+			errs = append(errs, err)
+		} else {
+			devices = append(devices, amd...)
+		}
+	}
+
+	// Only fail when a tool was present but nothing could be read
+	if len(devices) == 0 && len(errs) > 0 {
+		return devices, errors.Join(errs...)
+	}
+	return devices, nil
+}
+
+// parseNvidiaSmi parses `nvidia-smi --format=csv,noheader,nounits` output
+func parseNvidiaSmi(output string) ([]Device, error) {
+	var devices []Device
+	for line := range strings.SplitSeq(strings.TrimSpace(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		if len(fields) != len(nvidiaQueryFields) {
+			return nil, fmt.Errorf("unexpected nvidia-smi output line: %q", line)
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPU index %q: %w", fields[0], err)
+		}
+
+		devices = append(devices, Device{
+			Index:              index,
+			Vendor:             VendorNvidia,
+			Name:               fields[1],
+			UUID:               fields[2],
+			MemoryTotalMB:      parseInt(fields[3]),
+			MemoryUsedMB:       parseInt(fields[4]),
+			MemoryFreeMB:       parseInt(fields[5]),
+			UtilizationPercent: int(parseInt(fields[6])),
+		})
+	}
+	return devices, nil
+}
+
+// parseRocmSmi parses `rocm-smi --json` output, which is keyed by card name
+// ("card0", "card1", ...) with human-readable field names as values.
+func parseRocmSmi(output []byte) ([]Device, error) {
+	var cards map[string]map[string]string
+	if err := json.Unmarshal(output, &cards); err != nil {
+		return nil, fmt.Errorf("failed to parse rocm-smi output: %w", err)
+	}
+
+	var devices []Device
+	for card, fields := range cards {
+		index, err := strconv.Atoi(strings.TrimPrefix(card, "card"))
+		if err != nil {
+			continue // Skip non-card entries such as "system"
+		}
+
+		const mib = 1024 * 1024
+		total := parseInt(fields["VRAM Total Memory (B)"]) / mib
+		used := parseInt(fields["VRAM Total Used Memory (B)"]) / mib
+
+		name := fields["Card Series"]
+		if name == "" {
+			name = fields["Card series"]
+		}
+
+		devices = append(devices, Device{
+			Index:              index,
+			Vendor:             VendorAMD,
+			Name:               name,
+			UUID:               fields["Unique ID"],
+			MemoryTotalMB:      total,
+			MemoryUsedMB:       used,
+			MemoryFreeMB:       total - used,
+			UtilizationPercent: int(parseInt(fields["GPU use (%)"])),
+		})
+	}
+
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Index < devices[j].Index })
+	return devices, nil
+}
+
+// parseInt parses an integer field, treating values such as "[N/A]" as zero
+func parseInt(s string) int64 {
+	v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
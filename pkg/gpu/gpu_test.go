@@ -0,0 +1,67 @@
+package gpu
+
+import "testing"
+
+func TestParseNvidiaSmi(t *testing.T) {
+	output := `0, NVIDIA GeForce RTX 3090, GPU-1234, 24576, 1024, 23552, 7
+1, NVIDIA A100-SXM4-80GB, GPU-5678, 81920, [N/A], 81920, 0
+`
+	devices, err := parseNvidiaSmi(output)
+	if err != nil {
+		t.Fatalf("parseNvidiaSmi failed: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("expected 2 devices, got %d", len(devices))
+	}
+
+	first := devices[0]
+	if first.Name != "NVIDIA GeForce RTX 3090" || first.UUID != "GPU-1234" {
+		t.Errorf("unexpected device identity: %+v", first)
+	}
+	if first.MemoryTotalMB != 24576 || first.MemoryUsedMB != 1024 || first.MemoryFreeMB != 23552 {
+		t.Errorf("unexpected memory values: %+v", first)
+	}
+	if first.UtilizationPercent != 7 || first.Vendor != VendorNvidia {
+		t.Errorf("unexpected utilization or vendor: %+v", first)
+	}
+
+	if devices[1].Index != 1 || devices[1].MemoryUsedMB != 0 {
+		t.Errorf("expected N/A memory to parse as 0, got %+v", devices[1])
+	}
+}
+
+func TestParseNvidiaSmi_Malformed(t *testing.T) {
+	if _, err := parseNvidiaSmi("0, only, three"); err == nil {
+		t.Error("expected error for malformed output")
+	}
+}
+
+func TestParseRocmSmi(t *testing.T) {
+	output := []byte(`{
+		"card1": {"Card Series": "Radeon RX 7900 XTX", "VRAM Total Memory (B)": "25753026560", "VRAM Total Used Memory (B)": "1073741824", "GPU use (%)": "12"},
+		"card0": {"Card Series": "Radeon RX 6800", "VRAM Total Memory (B)": "17163091968", "VRAM Total Used Memory (B)": "0", "GPU use (%)": "0"},
+		"system": {"Driver version": "6.7.0"}
+	}`)
+
+	devices, err := parseRocmSmi(output)
+	if err != nil {
+		t.Fatalf("parseRocmSmi failed: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("expected 2 devices, got %d", len(devices))
+	}
+	if devices[0].Index != 0 || devices[1].Index != 1 {
+		t.Errorf("expected devices sorted by index, got %d, %d", devices[0].Index, devices[1].Index)
+	}
+
+	rx := devices[1]
+	if rx.Name != "Radeon RX 7900 XTX" || rx.Vendor != VendorAMD {
+		t.Errorf("unexpected device identity: %+v", rx)
+	}
+	if rx.MemoryTotalMB != 24560 || rx.MemoryUsedMB != 1024 || rx.MemoryFreeMB != 23536 {
+		t.Errorf("unexpected memory values: %+v", rx)
+	}
+	if rx.UtilizationPercent != 12 {
+		t.Errorf("expected 12%% utilization, got %d", rx.UtilizationPercent)
+	}
+}
@@ -0,0 +1,110 @@
+package instance
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+)
+
+// debugBodyLimit is how much of each body is kept for the debug log
+const debugBodyLimit = 4096
+
+// secretFieldPattern matches JSON string fields whose name suggests a secret.
+// A regexp rather than a JSON decoder, so truncated bodies are redacted too.
+var secretFieldPattern = regexp.MustCompile(`(?i)("[^"]*(?:key|token|secret|password|credential|authorization)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// bodyCapture keeps the first debugBodyLimit bytes written through it and
+// counts the rest
+type bodyCapture struct {
+	buf   bytes.Buffer
+	total int
+}
+
+func (c *bodyCapture) Write(p []byte) (int, error) {
+	c.total += len(p)
+	if room := debugBodyLimit - c.buf.Len(); room > 0 {
+		c.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
+
+// String returns the captured body as a single redacted log line
+func (c *bodyCapture) String() string {
+	if c.total == 0 {
+		return "(empty)"
+	}
+	body := secretFieldPattern.ReplaceAll(c.buf.Bytes(), []byte(`$1"[REDACTED]"`))
+	body = bytes.ReplaceAll(body, []byte("\n"), []byte(`\n`))
+	line := sanitizeLine(body)
+	if dropped := c.total - c.buf.Len(); dropped > 0 {
+		line += fmt.Sprintf("... [truncated %d bytes]", dropped)
+	}
+	return line
+}
+
+// teeReadCloser copies what the proxy reads from a request body into a capture
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureResponseWriter copies the response body into a capture while
+// passing everything through, including flushes for streamed responses
+type captureResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	capture *bodyCapture
+}
+
+func (w *captureResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.capture.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *captureResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *captureResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// shouldLogBodies reports whether proxied bodies go to the instance log.
+// Remote instances log on their own node.
+func (p *proxy) shouldLogBodies() bool {
+	if p.instance.IsRemote() || p.instance.logger == nil {
+		return false
+	}
+	opts := p.instance.GetOptions()
+	return opts != nil && opts.DebugLogBodies != nil && *opts.DebugLogBodies
+}
+
+// serveWithBodyLog proxies the request while capturing both bodies, then
+// writes them to the instance log
+func (p *proxy) serveWithBodyLog(reverseProxy http.Handler, w http.ResponseWriter, r *http.Request) {
+	reqCapture := &bodyCapture{}
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = teeReadCloser{io.TeeReader(r.Body, reqCapture), r.Body}
+	}
+	cw := &captureResponseWriter{ResponseWriter: w, capture: &bodyCapture{}}
+
+	reverseProxy.ServeHTTP(cw, r)
+
+	p.instance.logger.writeLine(fmt.Sprintf("[proxy] %s %s request body: %s", r.Method, r.URL.Path, reqCapture))
+	p.instance.logger.writeLine(fmt.Sprintf("[proxy] %s %s response %d body: %s", r.Method, r.URL.Path, cw.status, cw.capture))
+}
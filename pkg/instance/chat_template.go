@@ -0,0 +1,61 @@
+package instance
+
+import (
+	"fmt"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/validation"
+	"os"
+	"path/filepath"
+)
+
+// ValidateChatTemplateRef checks that chat_template_ref names a template in
+// the library and doesn't conflict with an inline or file-based template.
+func (c *Options) ValidateChatTemplateRef(templates map[string]string) error {
+	if c.ChatTemplateRef == "" {
+		return nil
+	}
+
+	if c.BackendOptions.BackendType != backends.BackendTypeLlamaCpp {
+		return validation.ValidationError(fmt.Errorf("chat_template_ref is only supported for the %s backend", backends.BackendTypeLlamaCpp))
+	}
+
+	if lo := c.BackendOptions.LlamaServerOptions; lo != nil && (lo.ChatTemplate != "" || lo.ChatTemplateFile != "") {
+		return validation.ValidationError(fmt.Errorf("chat_template_ref cannot be combined with chat_template or chat_template_file"))
+	}
+
+	if _, ok := templates[c.ChatTemplateRef]; !ok {
+		return validation.ValidationError(fmt.Errorf("chat template %q is not defined in chat_templates", c.ChatTemplateRef))
+	}
+
+	return nil
+}
+
+// chatTemplatePath returns where the referenced chat template is written for the backend
+func (i *Instance) chatTemplatePath() string {
+	return filepath.Join(i.globalInstanceSettings.InstancesDir, i.Name, "chat_template.jinja")
+}
+
+// writeChatTemplateFile resolves chat_template_ref and writes the template to
+// the instance directory. It is rewritten on every start so edits to the
+// library take effect on the next restart.
+func (i *Instance) writeChatTemplateFile() error {
+	opts := i.GetOptions()
+	if opts == nil || opts.ChatTemplateRef == "" || opts.BackendOptions.BackendType != backends.BackendTypeLlamaCpp {
+		return nil
+	}
+
+	template, ok := i.globalChatTemplates[opts.ChatTemplateRef]
+	if !ok {
+		return fmt.Errorf("chat template %q referenced by instance %s is not defined", opts.ChatTemplateRef, i.Name)
+	}
+
+	path := i.chatTemplatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create instance directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(template), 0644); err != nil {
+		return fmt.Errorf("failed to write chat template: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package instance
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// imagePullTimeout bounds a single image pull; backend images are several
+// gigabytes
+const imagePullTimeout = time.Hour
+
+// presentImages remembers the images known to be present, keyed by runtime
+// and image, so they are only checked once per llamactl run
+var presentImages sync.Map
+
+// pullMu serializes pulls, so instances sharing an image don't pull it twice
+var pullMu sync.Mutex
+
+// pullImageForStart runs ensureImage with a context that cancelPull can
+// cancel, so stopping or shutting down doesn't wait for a pull
+func (p *process) pullImageForStart() error {
+	ctx, cancel := context.WithTimeout(context.Background(), imagePullTimeout)
+	defer cancel()
+
+	p.pullCancelMu.Lock()
+	p.pullCancel = cancel
+	p.pullCancelMu.Unlock()
+
+	defer func() {
+		p.pullCancelMu.Lock()
+		p.pullCancel = nil
+		p.pullCancelMu.Unlock()
+	}()
+
+	return p.ensureImage(ctx)
+}
+
+// cancelPull aborts an image pull in progress for a start, if any
+func (p *process) cancelPull() {
+	p.pullCancelMu.Lock()
+	defer p.pullCancelMu.Unlock()
+	if p.pullCancel != nil {
+		p.pullCancel()
+	}
+}
+
+// ensureImage pulls the instance's Docker image if docker.auto_pull is set
+// and the container runtime doesn't have it yet
+func (p *process) ensureImage(ctx context.Context) error {
+	opts := p.instance.GetOptions()
+	if opts == nil {
+		return nil
+	}
+	docker := opts.BackendOptions.GetDockerSettings(p.instance.globalBackendSettings)
+	if docker == nil || !docker.AutoPull || docker.Image == "" {
+		return nil
+	}
+
+	runtime := docker.GetRuntime()
+	key := runtime + " " + docker.Image
+	if _, ok := presentImages.Load(key); ok {
+		return nil
+	}
+
+	pullMu.Lock()
+	defer pullMu.Unlock()
+
+	if _, ok := presentImages.Load(key); ok {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("pull of image %s cancelled: %w", docker.Image, err)
+	}
+
+	if exec.CommandContext(ctx, runtime, "image", "inspect", docker.Image).Run() == nil {
+		presentImages.Store(key, struct{}{})
+		return nil
+	}
+
+	p.instance.Logf(LogLevelInfo, "Pulling image %s for instance %s", docker.Image, p.instance.Name)
+	start := time.Now()
+	if err := p.pullImage(ctx, runtime, docker.Image); err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", docker.Image, err)
+	}
+	p.instance.Logf(LogLevelInfo, "Pulled image %s in %s", docker.Image, time.Since(start).Round(time.Second))
+
+	presentImages.Store(key, struct{}{})
+	return nil
+}
+
+// pullImage runs `<runtime> pull <image>`, logging its progress lines
+func (p *process) pullImage(ctx context.Context, runtime, image string) error {
+	cmd := exec.CommandContext(ctx, runtime, "pull", image)
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var last string
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			last = line
+			p.instance.Logf(LogLevelInfo, "Pulling %s: %s", image, line)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if last != "" {
+			return fmt.Errorf("%w: %s", err, last)
+		}
+		return err
+	}
+	return nil
+}
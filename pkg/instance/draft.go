@@ -0,0 +1,52 @@
+package instance
+
+import (
+	"llamactl/pkg/backends"
+	"strconv"
+)
+
+// DraftModel is the model configuration of another instance used as the
+// draft model for speculative decoding
+type DraftModel struct {
+	Model     string
+	HFRepo    string
+	CtxSize   int
+	GPULayers int
+	Device    string
+}
+
+// SetDraftModel sets the draft model passed to the backend when it is next started
+func (i *Instance) SetDraftModel(draft *DraftModel) {
+	i.draftModel.Store(draft)
+}
+
+// draftModelArgs returns the --*-draft flags for the resolved draft model.
+// Draft settings the instance configures itself take precedence.
+func (i *Instance) draftModelArgs(lo *backends.LlamaServerOptions) []string {
+	draft := i.draftModel.Load()
+	if draft == nil {
+		return nil
+	}
+	if lo == nil {
+		lo = &backends.LlamaServerOptions{}
+	}
+
+	var args []string
+	if lo.ModelDraft == "" && lo.HFRepoDraft == "" {
+		if draft.Model != "" {
+			args = append(args, "--model-draft", draft.Model)
+		} else if draft.HFRepo != "" {
+			args = append(args, "--hf-repo-draft", draft.HFRepo)
+		}
+	}
+	if lo.CtxSizeDraft == 0 && draft.CtxSize != 0 {
+		args = append(args, "--ctx-size-draft", strconv.Itoa(draft.CtxSize))
+	}
+	if lo.GPULayersDraft == 0 && draft.GPULayers != 0 {
+		args = append(args, "--gpu-layers-draft", strconv.Itoa(draft.GPULayers))
+	}
+	if lo.DeviceDraft == "" && draft.Device != "" {
+		args = append(args, "--device-draft", draft.Device)
+	}
+	return args
+}
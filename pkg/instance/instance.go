@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"llamactl/pkg/backends"
@@ -22,6 +25,9 @@ type Instance struct {
 	// Global configuration
 	globalInstanceSettings *config.InstancesConfig
 	globalBackendSettings  *config.BackendConfig
+	globalLoggingSettings  *config.LoggingConfig
+	globalProxySettings    *config.ProxyConfig
+	globalChatTemplates    map[string]string
 	globalNodesConfig      map[string]config.NodeConfig
 	localNodeName          string `json:"-"` // Name of the local node for remote detection
 
@@ -32,6 +38,15 @@ type Instance struct {
 	process *process `json:"-"`
 	proxy   *proxy   `json:"-"`
 	logger  *logger  `json:"-"`
+
+	// Backend metadata, cached until the next start
+	metadata atomic.Pointer[Metadata]
+
+	// Draft model resolved from draft_instance by the manager
+	draftModel atomic.Pointer[DraftModel]
+
+	// Shared auto-restart budget, nil when restarts aren't rationed
+	restartLimiter RestartLimiter
 }
 
 // New creates a new instance with the given name, log path, options and local node name
@@ -58,6 +73,9 @@ func New(name string, globalConfig *config.AppConfig, opts *Options, onStatusCha
 		options:                options,
 		globalInstanceSettings: globalInstanceSettings,
 		globalBackendSettings:  globalBackendSettings,
+		globalLoggingSettings:  &globalConfig.Logging,
+		globalProxySettings:    &globalConfig.Proxy,
+		globalChatTemplates:    globalConfig.ChatTemplates,
 		globalNodesConfig:      globalNodesConfig,
 		localNodeName:          localNodeName,
 		Created:                time.Now().Unix(),
@@ -80,6 +98,7 @@ func New(name string, globalConfig *config.AppConfig, opts *Options, onStatusCha
 		instance.logger = newLogger(
 			name,
 			globalInstanceSettings.LogsDir,
+			&globalConfig.Logging,
 			logRotationConfig,
 		)
 		instance.process = newProcess(instance)
@@ -92,9 +111,14 @@ func New(name string, globalConfig *config.AppConfig, opts *Options, onStatusCha
 	return instance
 }
 
-// writePresetIni writes the preset.ini file if provided in options and updates models_preset
+// writePresetIni writes the preset.ini file if provided in options or
+// generated from router models
 func writePresetIni(name string, opts *Options, instancesDir string) error {
-	if opts == nil || opts.PresetIni == nil || *opts.PresetIni == "" {
+	if opts == nil {
+		return nil
+	}
+	content := opts.presetIni()
+	if content == "" {
 		return nil
 	}
 
@@ -104,7 +128,7 @@ func writePresetIni(name string, opts *Options, instancesDir string) error {
 	}
 
 	presetPath := filepath.Join(instanceDir, "preset.ini")
-	if err := os.WriteFile(presetPath, []byte(*opts.PresetIni), 0644); err != nil {
+	if err := os.WriteFile(presetPath, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write preset.ini: %w", err)
 	}
 
@@ -128,6 +152,23 @@ func (i *Instance) Stop() error {
 	return i.process.stop()
 }
 
+// CancelStart aborts a Docker image pull in progress for a start of the
+// instance, which makes that start fail. Callers that need the instance lock
+// held by the start use this so they don't wait for the pull.
+func (i *Instance) CancelStart() {
+	if i.process != nil {
+		i.process.cancelPull()
+	}
+}
+
+// Kill force kills the instance process without a graceful shutdown
+func (i *Instance) Kill() error {
+	if i.process == nil {
+		return fmt.Errorf("instance %s has no process component (remote instances cannot be killed locally)", i.Name)
+	}
+	return i.process.kill()
+}
+
 // Restart restarts the instance
 func (i *Instance) Restart() error {
 	if i.process == nil {
@@ -175,6 +216,53 @@ func (i *Instance) SetStatus(s Status) {
 	}
 }
 
+// GetDesiredState returns the state the instance should be in
+func (i *Instance) GetDesiredState() DesiredState {
+	if i.status == nil {
+		return DesiredStopped
+	}
+	return i.status.getDesired()
+}
+
+// SetDesiredState records the state the instance should be in
+func (i *Instance) SetDesiredState(desired DesiredState) {
+	if i.status != nil {
+		i.status.setDesired(desired)
+	}
+}
+
+// GetStateTimestamps returns when the instance last started, stopped and
+// changed status
+func (i *Instance) GetStateTimestamps() StateTimestamps {
+	if i.status == nil {
+		return StateTimestamps{}
+	}
+	return i.status.getTimestamps()
+}
+
+// SetStateTimestamps restores state change timestamps, e.g. from persistence
+// or a remote node
+func (i *Instance) SetStateTimestamps(ts StateTimestamps) {
+	if i.status != nil {
+		i.status.setTimestamps(ts)
+	}
+}
+
+// UptimeSeconds returns how long the instance has been running, or 0 when
+// it isn't running. Remote stubs carry their node's timestamps, so this works
+// for them as well.
+func (i *Instance) UptimeSeconds() int64 {
+	if i.GetStatus() != Running {
+		return 0
+	}
+	started := i.GetStateTimestamps().LastStartedAt
+	if started == 0 {
+		return 0
+	}
+	// Clocks of remote nodes may be ahead of ours
+	return max(time.Now().Unix()-started, 0)
+}
+
 // IsRunning returns true if the status is Running
 func (i *Instance) IsRunning() bool {
 	if i.status == nil {
@@ -202,12 +290,28 @@ func (i *Instance) SetOptions(opts *Options) {
 		i.options.set(opts)
 	}
 
+	if !i.IsRemote() && i.globalInstanceSettings != nil {
+		if err := writePresetIni(i.Name, opts, i.globalInstanceSettings.InstancesDir); err != nil {
+			i.Logf(LogLevelWarn, "Warning: Failed to write preset.ini for instance %s: %v", i.Name, err)
+		}
+	}
+
 	// Clear the proxy so it gets recreated with new options
 	if i.proxy != nil {
 		i.proxy.clear()
 	}
 }
 
+// Warnings returns non-fatal problems with the instance's options, such as a
+// context size above the configured maximum
+func (i *Instance) Warnings() []string {
+	opts := i.GetOptions()
+	if opts == nil {
+		return nil
+	}
+	return opts.warnings(i.globalInstanceSettings)
+}
+
 // SetTimeProvider sets a custom time provider for testing
 func (i *Instance) SetTimeProvider(tp TimeProvider) {
 	if i.proxy != nil {
@@ -222,6 +326,16 @@ func (i *Instance) GetHost() string {
 	return i.options.GetHost()
 }
 
+// BackendURL returns the base URL used to reach the local backend. Backends
+// bound to all interfaces are reached over loopback.
+func (i *Instance) BackendURL() string {
+	host := i.GetHost()
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(i.GetPort()))
+}
+
 func (i *Instance) GetPort() int {
 	if i.options == nil {
 		return 0
@@ -254,7 +368,16 @@ func (i *Instance) GetLogs(num_lines int) (string, error) {
 	if i.logger == nil {
 		return "", fmt.Errorf("instance %s has no logger (remote instances don't have logs)", i.Name)
 	}
-	return i.logger.getLogs(num_lines)
+	return i.logger.getLogs(num_lines, string(i.GetBackendType()))
+}
+
+// LastExit returns details of the last crash of the instance process,
+// or nil if it has not crashed since llamactl started
+func (i *Instance) LastExit() *ExitInfo {
+	if i.process == nil {
+		return nil
+	}
+	return i.process.lastExit.Load()
 }
 
 // LastRequestTime returns the last request time as a Unix timestamp
@@ -272,6 +395,34 @@ func (i *Instance) UpdateLastRequestTime() {
 	}
 }
 
+// IsPinned reports whether the instance is exempt from idle timeout and LRU eviction
+func (i *Instance) IsPinned() bool {
+	opts := i.GetOptions()
+	return opts != nil && opts.Pinned != nil && *opts.Pinned
+}
+
+// Alias returns the model name set with llama.cpp's alias option, or "" when
+// the instance has none
+func (i *Instance) Alias() string {
+	opts := i.GetOptions()
+	if opts == nil || opts.BackendOptions.BackendType != backends.BackendTypeLlamaCpp {
+		return ""
+	}
+	if lo := opts.BackendOptions.LlamaServerOptions; lo != nil {
+		return lo.Alias
+	}
+	return ""
+}
+
+// ServedModelName returns the model id clients see for the instance: its
+// alias if set, otherwise the instance name
+func (i *Instance) ServedModelName() string {
+	if alias := i.Alias(); alias != "" {
+		return alias
+	}
+	return i.Name
+}
+
 // ShouldTimeout checks if the instance should timeout based on idle time
 func (i *Instance) ShouldTimeout() bool {
 	if i.proxy == nil {
@@ -288,6 +439,22 @@ func (i *Instance) GetInflightRequests() int32 {
 	return i.proxy.getInflightRequests()
 }
 
+// GetRequestStats returns the instance's proxied request counters
+func (i *Instance) GetRequestStats() RequestStats {
+	if i.proxy == nil {
+		return (&requestCounters{}).snapshot()
+	}
+	return i.proxy.counters.snapshot()
+}
+
+// GetQueuedRequests returns the number of requests waiting for a concurrency slot
+func (i *Instance) GetQueuedRequests() int32 {
+	if i.proxy == nil {
+		return 0
+	}
+	return i.proxy.getQueuedRequests()
+}
+
 // ServeHTTP serves HTTP requests through the proxy with request tracking and shutdown handling
 func (i *Instance) ServeHTTP(w http.ResponseWriter, r *http.Request) error {
 	if i.proxy == nil {
@@ -305,6 +472,33 @@ func (i *Instance) getCommand() string {
 	return opts.BackendOptions.GetCommand(i.globalBackendSettings, opts.DockerEnabled, opts.CommandOverride)
 }
 
+func (i *Instance) isDockerEnabled() bool {
+	opts := i.GetOptions()
+	if opts == nil {
+		return false
+	}
+
+	return opts.BackendOptions.IsDockerEnabled(i.globalBackendSettings, opts.DockerEnabled)
+}
+
+// mirrorLogsToStdout reports whether backend output should also go to
+// llamactl's stdout, with the instance option overriding the global setting
+func (i *Instance) mirrorLogsToStdout() bool {
+	if opts := i.GetOptions(); opts != nil && opts.MirrorLogsToStdout != nil {
+		return *opts.MirrorLogsToStdout
+	}
+	return i.globalLoggingSettings != nil && i.globalLoggingSettings.MirrorToStdout
+}
+
+func (i *Instance) getStopSignal() os.Signal {
+	opts := i.GetOptions()
+	if opts == nil {
+		return os.Interrupt
+	}
+
+	return opts.BackendOptions.GetStopSignal(i.globalBackendSettings)
+}
+
 func (i *Instance) buildCommandArgs() []string {
 	opts := i.GetOptions()
 	if opts == nil {
@@ -313,16 +507,40 @@ func (i *Instance) buildCommandArgs() []string {
 
 	args := opts.BackendOptions.BuildCommandArgs(i.globalBackendSettings, opts.DockerEnabled)
 
+	// Name Docker containers after the instance so a stale container from a
+	// previous run can be found and removed before starting a new one
+	if i.isDockerEnabled() {
+		args = backends.WithDockerContainerName(args, i.Name)
+		args = backends.WithDockerWorkdir(args, opts.WorkingDir)
+		// docker run only forwards stdin with -i
+		if opts.StdinData != "" {
+			args = backends.WithDockerInteractive(args)
+		}
+	}
+
 	// Add --models-preset flag if preset.ini exists and models_preset is not set
 	// This handles router mode without auto-setting the backend options
 	if opts.BackendOptions.BackendType == backends.BackendTypeLlamaCpp &&
-		opts.PresetIni != nil && *opts.PresetIni != "" &&
+		opts.presetIni() != "" &&
 		opts.BackendOptions.LlamaServerOptions != nil &&
 		opts.BackendOptions.LlamaServerOptions.ModelsPreset == "" {
 
 		presetPath := filepath.Join(i.globalInstanceSettings.InstancesDir, i.Name, "preset.ini")
 		args = append(args, "--models-preset", presetPath)
-		log.Printf("Adding --models-preset %s to command for instance %s", presetPath, i.Name)
+		i.Logf(LogLevelInfo, "Adding --models-preset %s to command for instance %s", presetPath, i.Name)
+	}
+
+	if opts.ChatTemplateRef != "" && opts.BackendOptions.BackendType == backends.BackendTypeLlamaCpp {
+		templatePath := i.chatTemplatePath()
+		// The template lives on the host, so containers need it mounted
+		if i.isDockerEnabled() {
+			args = backends.WithDockerMount(args, templatePath)
+		}
+		args = append(args, "--chat-template-file", templatePath)
+	}
+
+	if opts.DraftInstance != "" && opts.BackendOptions.BackendType == backends.BackendTypeLlamaCpp {
+		args = append(args, i.draftModelArgs(opts.BackendOptions.LlamaServerOptions)...)
 	}
 
 	return args
@@ -340,17 +558,25 @@ func (i *Instance) buildEnvironment() map[string]string {
 // MarshalJSON implements json.Marshaler for Instance
 func (i *Instance) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		ID      int      `json:"id"`
-		Name    string   `json:"name"`
-		Status  *status  `json:"status"`
-		Created int64    `json:"created,omitempty"`
-		Options *options `json:"options,omitempty"`
+		ID           int          `json:"id"`
+		Name         string       `json:"name"`
+		Status       *status      `json:"status"`
+		DesiredState DesiredState `json:"desired_state,omitempty"`
+		Created      int64        `json:"created,omitempty"`
+		StateTimestamps
+		UptimeSeconds int64    `json:"uptime_seconds,omitempty"` // derived from last_started_at while running
+		Port          int      `json:"port,omitempty"`           // derived from backend options, for convenience
+		Options       *options `json:"options,omitempty"`
 	}{
-		ID:      i.ID,
-		Name:    i.Name,
-		Status:  i.status,
-		Created: i.Created,
-		Options: i.options,
+		ID:              i.ID,
+		Name:            i.Name,
+		Status:          i.status,
+		DesiredState:    i.GetDesiredState(),
+		Created:         i.Created,
+		StateTimestamps: i.GetStateTimestamps(),
+		UptimeSeconds:   i.UptimeSeconds(),
+		Port:            i.GetPort(),
+		Options:         i.options,
 	})
 }
 
@@ -358,10 +584,12 @@ func (i *Instance) MarshalJSON() ([]byte, error) {
 func (i *Instance) UnmarshalJSON(data []byte) error {
 	// Explicitly deserialize to match MarshalJSON format
 	aux := &struct {
-		ID      int      `json:"id"`
-		Name    string   `json:"name"`
-		Status  *status  `json:"status"`
-		Created int64    `json:"created,omitempty"`
+		ID           int          `json:"id"`
+		Name         string       `json:"name"`
+		Status       *status      `json:"status"`
+		DesiredState DesiredState `json:"desired_state,omitempty"`
+		Created      int64        `json:"created,omitempty"`
+		StateTimestamps
 		Options *options `json:"options,omitempty"`
 	}{}
 
@@ -376,5 +604,19 @@ func (i *Instance) UnmarshalJSON(data []byte) error {
 	i.status = aux.Status
 	i.options = aux.Options
 
+	if aux.DesiredState == DesiredRunning {
+		if i.status == nil {
+			i.status = newStatus(Stopped)
+		}
+		i.status.setDesired(DesiredRunning)
+	}
+
+	if aux.StateTimestamps != (StateTimestamps{}) {
+		if i.status == nil {
+			i.status = newStatus(Stopped)
+		}
+		i.status.setTimestamps(aux.StateTimestamps)
+	}
+
 	return nil
 }
@@ -1,12 +1,28 @@
 package instance_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"llamactl/pkg/backends"
 	"llamactl/pkg/config"
 	"llamactl/pkg/instance"
 	"llamactl/pkg/testutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -218,6 +234,9 @@ func TestMarshalJSON(t *testing.T) {
 	if result["options"] == nil {
 		t.Error("Expected options to be included in JSON")
 	}
+	if result["port"] != float64(8080) {
+		t.Errorf("Expected top-level port 8080, got %v", result["port"])
+	}
 }
 
 func TestUnmarshalJSON(t *testing.T) {
@@ -359,6 +378,53 @@ func TestCreateOptionsValidation(t *testing.T) {
 	}
 }
 
+func TestCtxSizeDefaultAndWarning(t *testing.T) {
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{
+			LogsDir:        "/tmp/test",
+			DefaultCtxSize: 8192,
+			MaxCtxSize:     32768,
+		},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	tests := []struct {
+		name            string
+		ctxSize         int
+		expectedCtxSize int
+		expectWarning   bool
+	}{
+		{"unset uses default", 0, 8192, false},
+		{"explicit value is kept", 4096, 4096, false},
+		{"above maximum warns", 131072, 131072, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := instance.New("test", globalConfig, &instance.Options{
+				BackendOptions: backends.Options{
+					BackendType: backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{
+						Model:   "/path/to/model.gguf",
+						CtxSize: tt.ctxSize,
+					},
+				},
+			}, nil)
+
+			if got := inst.GetOptions().BackendOptions.LlamaServerOptions.CtxSize; got != tt.expectedCtxSize {
+				t.Errorf("Expected ctx_size %d, got %d", tt.expectedCtxSize, got)
+			}
+			if warnings := inst.Warnings(); (len(warnings) > 0) != tt.expectWarning {
+				t.Errorf("Expected warning %v, got %v", tt.expectWarning, warnings)
+			}
+		})
+	}
+}
+
 func TestStatusChangeCallback(t *testing.T) {
 	globalConfig := &config.AppConfig{
 		Backends: config.BackendConfig{
@@ -401,6 +467,162 @@ func TestStatusChangeCallback(t *testing.T) {
 	}
 }
 
+func TestStateTimestamps(t *testing.T) {
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	options := &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+			},
+		},
+	}
+
+	inst := instance.New("test", globalConfig, options, nil)
+	if ts := inst.GetStateTimestamps(); ts != (instance.StateTimestamps{}) {
+		t.Fatalf("Expected no timestamps for a new instance, got %+v", ts)
+	}
+
+	inst.SetStatus(instance.Running)
+	ts := inst.GetStateTimestamps()
+	if ts.LastStartedAt == 0 || ts.StatusChangedAt != ts.LastStartedAt {
+		t.Errorf("Expected start to set last_started_at and status_changed_at, got %+v", ts)
+	}
+	if ts.LastStoppedAt != 0 {
+		t.Errorf("Expected last_stopped_at to be unset, got %d", ts.LastStoppedAt)
+	}
+
+	inst.SetStatus(instance.Failed)
+	ts = inst.GetStateTimestamps()
+	if ts.LastStoppedAt == 0 {
+		t.Error("Expected a crash to set last_stopped_at")
+	}
+
+	// Timestamps round trip through JSON
+	inst.SetStateTimestamps(instance.StateTimestamps{LastStartedAt: 100, LastStoppedAt: 200, StatusChangedAt: 200})
+	data, err := json.Marshal(inst)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var restored instance.Instance
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	want := instance.StateTimestamps{LastStartedAt: 100, LastStoppedAt: 200, StatusChangedAt: 200}
+	if got := restored.GetStateTimestamps(); got != want {
+		t.Errorf("Expected timestamps %+v after round trip, got %+v", want, got)
+	}
+}
+
+func TestUptimeSeconds(t *testing.T) {
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	options := &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+			},
+		},
+	}
+
+	inst := instance.New("test", globalConfig, options, nil)
+	started := time.Now().Unix() - 60
+	inst.SetStateTimestamps(instance.StateTimestamps{LastStartedAt: started, StatusChangedAt: started})
+
+	if got := inst.UptimeSeconds(); got != 0 {
+		t.Errorf("Expected no uptime while stopped, got %d", got)
+	}
+
+	inst.SetStatus(instance.Running)
+	// SetStatus stamps the current time, so restore the earlier start
+	inst.SetStateTimestamps(instance.StateTimestamps{LastStartedAt: started, StatusChangedAt: started})
+	if got := inst.UptimeSeconds(); got < 60 || got > 62 {
+		t.Errorf("Expected uptime of about 60s, got %d", got)
+	}
+
+	data, err := json.Marshal(inst)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if _, ok := fields["uptime_seconds"]; !ok {
+		t.Error("Expected uptime_seconds in the JSON of a running instance")
+	}
+
+	// A start time in the future, e.g. from a remote node with a fast clock
+	inst.SetStateTimestamps(instance.StateTimestamps{LastStartedAt: time.Now().Unix() + 30})
+	if got := inst.UptimeSeconds(); got != 0 {
+		t.Errorf("Expected uptime to be clamped to 0, got %d", got)
+	}
+}
+
+func TestDiffOptions(t *testing.T) {
+	base := func() *instance.Options {
+		return &instance.Options{
+			AutoRestart: testutil.BoolPtr(true),
+			MaxRestarts: testutil.IntPtr(3),
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model:     "/path/to/model.gguf",
+					Port:      8080,
+					ExtraArgs: map[string]string{},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*instance.Options)
+		want   []string
+	}{
+		{"no changes", func(o *instance.Options) {}, nil},
+		{"nil and empty maps are equal", func(o *instance.Options) { o.BackendOptions.LlamaServerOptions.ExtraArgs = nil }, nil},
+		{"instance and backend fields", func(o *instance.Options) {
+			o.MaxRestarts = testutil.IntPtr(5)
+			o.IdleTimeout = testutil.IntPtr(10)
+			o.BackendOptions.LlamaServerOptions.CtxSize = 4096
+			o.BackendOptions.LlamaServerOptions.Port = 8081
+		}, []string{"max_restarts", "idle_timeout", "backend_options.ctx_size", "backend_options.port"}},
+		{"backend type", func(o *instance.Options) {
+			o.BackendOptions = backends.Options{
+				BackendType:       backends.BackendTypeVllm,
+				VllmServerOptions: &backends.VllmServerOptions{Model: "org/model"},
+			}
+		}, []string{"backend_type", "backend_options"}},
+		{"nodes", func(o *instance.Options) { o.Nodes = map[string]struct{}{"worker": {}} }, []string{"nodes"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := base()
+			tt.modify(updated)
+			got := instance.DiffOptions(base(), updated)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected changes %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
 func TestSetOptions_NodesPreserved(t *testing.T) {
 	globalConfig := &config.AppConfig{
 		Backends: config.BackendConfig{
@@ -642,90 +864,2708 @@ func TestIdleTimeout(t *testing.T) {
 	})
 }
 
-// mockTimeProvider for timeout testing
-type mockTimeProvider struct {
-	currentTime int64 // Unix timestamp
-}
+func TestServeHTTPUpdatesLastRequestTime(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	}))
+	defer backend.Close()
 
-func (m *mockTimeProvider) Now() time.Time {
-	return time.Unix(m.currentTime, 0)
-}
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
 
-func TestWritePresetIni(t *testing.T) {
 	globalConfig := &config.AppConfig{
 		Backends: config.BackendConfig{
-			LlamaCpp: config.BackendSettings{
-				Command: "llama-server",
-				Args:    []string{},
-			},
-			MLX: config.BackendSettings{
-				Command: "mlx_lm.server",
-				Args:    []string{},
-			},
-			VLLM: config.BackendSettings{
-				Command: "vllm",
-				Args:    []string{"serve"},
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("test", globalConfig, &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+				Host:  backendURL.Hostname(),
+				Port:  port,
 			},
 		},
-		Instances: config.InstancesConfig{
-			LogsDir:             "/tmp/test-logs",
-			InstancesDir:        "/tmp/test-instances",
-			DefaultAutoRestart:  true,
-			DefaultMaxRestarts:  3,
-			DefaultRestartDelay: 5,
-			DefaultIdleTimeout:  30,
+	}, nil)
+
+	start := time.Now().Add(-time.Hour).Unix()
+	mockTime := &atomicTimeProvider{}
+	mockTime.now.Store(start)
+	inst.SetTimeProvider(mockTime)
+
+	done := make(chan struct{})
+	go func() {
+		inst.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/completion", nil))
+		close(done)
+	}()
+
+	<-entered
+	if got := inst.LastRequestTime(); got != start {
+		t.Errorf("Expected last request time %d when the request started, got %d", start, got)
+	}
+
+	// The request runs for ten minutes
+	end := start + 600
+	mockTime.now.Store(end)
+	close(release)
+	<-done
+
+	if got := inst.LastRequestTime(); got != end {
+		t.Errorf("Expected last request time %d when the request finished, got %d", end, got)
+	}
+}
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
 		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
 		Nodes:     map[string]config.NodeConfig{},
 		LocalNode: "main",
 	}
 
-	mockOnStatusChange := func(oldStatus, newStatus instance.Status) {}
+	maxConcurrent := 1
+	inst := instance.New("test", globalConfig, &instance.Options{
+		MaxConcurrentRequests: &maxConcurrent,
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+				Host:  backendURL.Hostname(),
+				Port:  port,
+			},
+		},
+	}, nil)
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		inst.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/health", nil))
+	}()
+	<-entered
+
+	second := httptest.NewRecorder()
+	if err := inst.ServeHTTP(second, httptest.NewRequest(http.MethodGet, "/health", nil)); err != nil {
+		t.Fatalf("ServeHTTP returned error: %v", err)
+	}
+	if second.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 while at capacity, got %d", second.Code)
+	}
 
-	t.Run("preset_ini with content creates file", func(t *testing.T) {
-		presetContent := "[model1]\nmodel = /path/to/model1.gguf\ngpu-layers = 35\n"
-		options := &instance.Options{
-			PresetIni: &presetContent,
+	close(release)
+	<-done
+	if first.Code != http.StatusOK {
+		t.Errorf("Expected first request to succeed, got %d", first.Code)
+	}
+
+	third := httptest.NewRecorder()
+	inst.ServeHTTP(third, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if third.Code != http.StatusOK {
+		t.Errorf("Expected request after release to succeed, got %d", third.Code)
+	}
+}
+
+func TestMaxConcurrentRequests_KeptAcrossOptionsUpdate(t *testing.T) {
+	// Only the first request blocks, so a second one let through fails the
+	// test instead of hanging it
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	var requests atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			entered <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	newOptions := func() *instance.Options {
+		maxConcurrent := 1
+		return &instance.Options{
+			MaxConcurrentRequests: &maxConcurrent,
 			BackendOptions: backends.Options{
 				BackendType: backends.BackendTypeLlamaCpp,
 				LlamaServerOptions: &backends.LlamaServerOptions{
 					Model: "/path/to/model.gguf",
-					Port:  8080,
+					Host:  backendURL.Hostname(),
+					Port:  port,
 				},
 			},
 		}
+	}
+	inst := instance.New("test", globalConfig, newOptions(), nil)
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		inst.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/health", nil))
+	}()
+	<-entered
+
+	// Updating the options rebuilds the proxy while the first request holds
+	// the only slot
+	inst.SetOptions(newOptions())
+
+	second := httptest.NewRecorder()
+	inst.ServeHTTP(second, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if second.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 while the first request still runs, got %d", second.Code)
+	}
 
-		_ = instance.New("test-preset", globalConfig, options, mockOnStatusChange)
+	close(release)
+	<-done
 
-		// Verify preset.ini file was created
-		presetPath := "/tmp/test-instances/test-preset/preset.ini"
-		content, err := os.ReadFile(presetPath)
-		if err != nil {
-			t.Fatalf("Failed to read preset.ini file: %v", err)
-		}
-		if string(content) != presetContent {
-			t.Errorf("Expected preset content '%s', got '%s'", presetContent, string(content))
-		}
-	})
+	third := httptest.NewRecorder()
+	inst.ServeHTTP(third, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if third.Code != http.StatusOK {
+		t.Errorf("Expected request after release to succeed, got %d", third.Code)
+	}
+}
 
-	t.Run("empty preset_ini does not create file", func(t *testing.T) {
-		emptyPreset := ""
-		options := &instance.Options{
-			PresetIni: &emptyPreset,
+func TestQueueTimeout(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	maxConcurrent := 1
+	queueTimeout := 1
+	inst := instance.New("test", globalConfig, &instance.Options{
+		MaxConcurrentRequests: &maxConcurrent,
+		QueueTimeout:          &queueTimeout,
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+				Host:  backendURL.Hostname(),
+				Port:  port,
+			},
+		},
+	}, nil)
+
+	first := httptest.NewRecorder()
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		inst.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/health", nil))
+	}()
+	<-entered
+
+	// A queued request is forwarded once the slot frees up
+	queued := httptest.NewRecorder()
+	queuedDone := make(chan struct{})
+	go func() {
+		defer close(queuedDone)
+		inst.ServeHTTP(queued, httptest.NewRequest(http.MethodGet, "/health", nil))
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for inst.GetQueuedRequests() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := inst.GetQueuedRequests(); got != 1 {
+		t.Fatalf("Expected 1 queued request, got %d", got)
+	}
+
+	close(release)
+	<-firstDone
+	<-queuedDone
+	if queued.Code != http.StatusOK {
+		t.Errorf("Expected queued request to succeed, got %d", queued.Code)
+	}
+	if got := inst.GetQueuedRequests(); got != 0 {
+		t.Errorf("Expected empty queue, got %d", got)
+	}
+}
+
+func TestQueueTimeout_RaisedLimitStartsQueuedRequest(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 2)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	newOptions := func(maxConcurrent int) *instance.Options {
+		queueTimeout := 5
+		return &instance.Options{
+			MaxConcurrentRequests: &maxConcurrent,
+			QueueTimeout:          &queueTimeout,
 			BackendOptions: backends.Options{
 				BackendType: backends.BackendTypeLlamaCpp,
 				LlamaServerOptions: &backends.LlamaServerOptions{
 					Model: "/path/to/model.gguf",
-					Port:  8080,
+					Host:  backendURL.Hostname(),
+					Port:  port,
 				},
 			},
 		}
+	}
+	inst := instance.New("test", globalConfig, newOptions(1), nil)
 
-		_ = instance.New("test-empty", globalConfig, options, mockOnStatusChange)
+	for range 2 {
+		go inst.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	}
+	<-entered
 
-		// Verify preset.ini file was NOT created
-		presetPath := "/tmp/test-instances/test-empty/preset.ini"
-		if _, err := os.Stat(presetPath); err == nil {
-			t.Error("Expected preset.ini file to NOT exist when preset_ini is empty, but it does")
+	deadline := time.Now().Add(time.Second)
+	for inst.GetQueuedRequests() != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := inst.GetQueuedRequests(); got != 1 {
+		t.Fatalf("Expected 1 queued request, got %d", got)
+	}
+
+	// Raising the limit starts the queued request without waiting for the
+	// first one to finish
+	inst.SetOptions(newOptions(2))
+	select {
+	case <-entered:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the queued request to start after the limit was raised")
+	}
+}
+
+func TestMaxConcurrentRequests_OptionsUpdatedUnderLoad(t *testing.T) {
+	var active, peak atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		active.Add(-1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	newOptions := func() *instance.Options {
+		maxConcurrent := 2
+		queueTimeout := 30
+		return &instance.Options{
+			MaxConcurrentRequests: &maxConcurrent,
+			QueueTimeout:          &queueTimeout,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+					Host:  backendURL.Hostname(),
+					Port:  port,
+				},
+			},
+		}
+	}
+	inst := instance.New("test", globalConfig, newOptions(), nil)
+
+	// Rebuild the proxy over and over while requests run and queue
+	stop := make(chan struct{})
+	updaterDone := make(chan struct{})
+	go func() {
+		defer close(updaterDone)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				inst.SetOptions(newOptions())
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	codes := make(chan int, 10)
+	for range 10 {
+		go func() {
+			rec := httptest.NewRecorder()
+			inst.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+			codes <- rec.Code
+		}()
+	}
+	for range 10 {
+		if code := <-codes; code != http.StatusOK {
+			t.Errorf("Expected every request to succeed, got %d", code)
+		}
+	}
+	close(stop)
+	<-updaterDone
+
+	if got := peak.Load(); got > 2 {
+		t.Errorf("Expected at most 2 concurrent requests at the backend, got %d", got)
+	}
+	if got := inst.GetQueuedRequests(); got != 0 {
+		t.Errorf("Expected empty queue, got %d", got)
+	}
+}
+
+func TestQueueTimeout_Expires(t *testing.T) {
+	release := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	maxConcurrent := 1
+	queueTimeout := 1
+	inst := instance.New("test", globalConfig, &instance.Options{
+		MaxConcurrentRequests: &maxConcurrent,
+		QueueTimeout:          &queueTimeout,
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+				Host:  backendURL.Hostname(),
+				Port:  port,
+			},
+		},
+	}, nil)
+
+	go inst.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	<-entered
+
+	rec := httptest.NewRecorder()
+	inst.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 after queue timeout, got %d", rec.Code)
+	}
+}
+
+func TestProxyTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stream" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Proxy: config.ProxyConfig{
+			ConnectTimeout:        time.Second,
+			ResponseHeaderTimeout: 100 * time.Millisecond,
+			StreamTimeout:         300 * time.Millisecond,
+		},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	inst := instance.New("test", globalConfig, &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+				Host:  backendURL.Hostname(),
+				Port:  port,
+			},
+		},
+	}, nil)
+
+	t.Run("response header timeout", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		inst.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("Expected status 504 when headers time out, got %d", rec.Code)
+		}
+		if got := decodeProxyError(t, rec); got != "upstream_timeout" {
+			t.Errorf("Expected error upstream_timeout, got %q", got)
+		}
+	})
+
+	t.Run("stream timeout", func(t *testing.T) {
+		done := make(chan struct{})
+		rec := httptest.NewRecorder()
+		go func() {
+			inst.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected streamed request to end at the stream timeout")
+		}
+	})
+}
+
+// newBlockingProxyInstance returns an instance proxying to a backend that
+// holds every request until it is cancelled, reporting on the returned
+// channels when a request arrives and when its context is done
+func newBlockingProxyInstance(t *testing.T, opts *instance.Options) (*instance.Instance, <-chan struct{}, <-chan struct{}) {
+	t.Helper()
+	entered := make(chan struct{}, 1)
+	cancelled := make(chan struct{}, 1)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a dropped connection once the body is read
+		io.Copy(io.Discard, r.Body)
+		entered <- struct{}{}
+		<-r.Context().Done()
+		cancelled <- struct{}{}
+	}))
+	t.Cleanup(backend.Close)
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Proxy:     config.ProxyConfig{StreamTimeout: time.Minute},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	opts.BackendOptions = backends.Options{
+		BackendType: backends.BackendTypeLlamaCpp,
+		LlamaServerOptions: &backends.LlamaServerOptions{
+			Model: "/path/to/model.gguf",
+			Host:  backendURL.Hostname(),
+			Port:  port,
+		},
+	}
+	return instance.New("test", globalConfig, opts, nil), entered, cancelled
+}
+
+func TestProxyPropagatesClientCancel(t *testing.T) {
+	inst, entered, cancelled := newBlockingProxyInstance(t, &instance.Options{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader("{}")).WithContext(ctx)
+		inst.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+
+	<-entered
+	cancel()
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the upstream request to be cancelled with the client")
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected ServeHTTP to return after the client cancelled")
+	}
+}
+
+func TestMaxRequestDuration(t *testing.T) {
+	maxDuration := 1
+	inst, _, cancelled := newBlockingProxyInstance(t, &instance.Options{MaxRequestDuration: &maxDuration})
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	inst.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504 after the max request duration, got %d", rec.Code)
+	}
+	// The instance setting overrides the minute-long proxy.stream_timeout
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the request to be aborted after about 1s, took %s", elapsed)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the upstream request to be aborted")
+	}
+}
+
+func TestProxyConnectionRefused(t *testing.T) {
+	// Grab a free port and close it again, so connecting to it is refused
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	tests := []struct {
+		name           string
+		retryAfter     time.Duration
+		wantCode       int
+		wantError      string
+		wantRetryAfter string
+	}{
+		{"grace response", 4500 * time.Millisecond, http.StatusServiceUnavailable, "backend_unavailable", "5"},
+		{"disabled", 0, http.StatusBadGateway, "upstream_unreachable", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			globalConfig := &config.AppConfig{
+				Backends: config.BackendConfig{
+					LlamaCpp: config.BackendSettings{Command: "llama-server"},
+				},
+				Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+				Proxy:     config.ProxyConfig{UnavailableRetryAfter: tt.retryAfter},
+				Nodes:     map[string]config.NodeConfig{},
+				LocalNode: "main",
+			}
+			inst := instance.New("test", globalConfig, &instance.Options{
+				BackendOptions: backends.Options{
+					BackendType: backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{
+						Model: "/path/to/model.gguf",
+						Host:  "127.0.0.1",
+						Port:  port,
+					},
+				},
+			}, nil)
+
+			rec := httptest.NewRecorder()
+			inst.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Expected Retry-After %q, got %q", tt.wantRetryAfter, got)
+			}
+			if got := decodeProxyError(t, rec); got != tt.wantError {
+				t.Errorf("Expected error %q, got %q", tt.wantError, got)
+			}
+		})
+	}
+}
+
+func TestProxyUpstreamErrors(t *testing.T) {
+	t.Run("connection dropped", func(t *testing.T) {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}))
+		defer backend.Close()
+		backendURL, _ := url.Parse(backend.URL)
+		port, _ := strconv.Atoi(backendURL.Port())
+
+		globalConfig := &config.AppConfig{
+			Backends: config.BackendConfig{
+				LlamaCpp: config.BackendSettings{Command: "llama-server"},
+			},
+			Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+			Nodes:     map[string]config.NodeConfig{},
+			LocalNode: "main",
+		}
+		inst := instance.New("test", globalConfig, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+					Host:  backendURL.Hostname(),
+					Port:  port,
+				},
+			},
+		}, nil)
+
+		rec := httptest.NewRecorder()
+		inst.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader("{}")))
+		if rec.Code != http.StatusBadGateway {
+			t.Errorf("Expected status 502, got %d", rec.Code)
+		}
+		if got := decodeProxyError(t, rec); got != "upstream_unreachable" {
+			t.Errorf("Expected error upstream_unreachable, got %q", got)
+		}
+	})
+
+	t.Run("request duration exceeded", func(t *testing.T) {
+		maxDuration := 1
+		inst, _, _ := newBlockingProxyInstance(t, &instance.Options{MaxRequestDuration: &maxDuration})
+
+		rec := httptest.NewRecorder()
+		inst.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("Expected status 504, got %d", rec.Code)
+		}
+		if got := decodeProxyError(t, rec); got != "upstream_timeout" {
+			t.Errorf("Expected error upstream_timeout, got %q", got)
+		}
+	})
+}
+
+// decodeProxyError returns the error code of a JSON proxy error response
+func decodeProxyError(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected a JSON error, got Content-Type %q", ct)
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode error response %q: %v", rec.Body.String(), err)
+	}
+	return body.Error
+}
+
+func TestProxyCompressesResponses(t *testing.T) {
+	payload := strings.Repeat(`{"embedding":[0.1,0.2,0.3]}`, 100)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "br")
+		case "/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+		default:
+			w.Header().Set("Content-Type", "application/json")
+		}
+		io.WriteString(w, payload)
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	newInstance := func(compress bool) *instance.Instance {
+		globalConfig := &config.AppConfig{
+			Backends: config.BackendConfig{
+				LlamaCpp: config.BackendSettings{Command: "llama-server"},
+			},
+			Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+			Nodes:     map[string]config.NodeConfig{},
+			LocalNode: "main",
+		}
+		return instance.New("test", globalConfig, &instance.Options{
+			CompressResponses: &compress,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+					Host:  backendURL.Hostname(),
+					Port:  port,
+				},
+			},
+		}, nil)
+	}
+
+	tests := []struct {
+		name           string
+		compress       bool
+		path           string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{"compressed", true, "/v1/embeddings", "gzip, deflate", "gzip"},
+		{"option disabled", false, "/v1/embeddings", "gzip", ""},
+		{"client doesn't accept gzip", true, "/v1/embeddings", "", ""},
+		{"gzip refused with q=0", true, "/v1/embeddings", "gzip;q=0, br", ""},
+		{"backend already encoded", true, "/encoded", "gzip, br", "br"},
+		{"event stream", true, "/events", "gzip", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader("{}"))
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			if err := newInstance(tt.compress).ServeHTTP(rec, req); err != nil {
+				t.Fatalf("ServeHTTP failed: %v", err)
+			}
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+			body := rec.Body.Bytes()
+			if tt.wantEncoding == "gzip" {
+				if rec.Header().Get("Content-Length") != "" {
+					t.Error("Expected Content-Length to be dropped for a compressed response")
+				}
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("Failed to open gzip body: %v", err)
+				}
+				if body, err = io.ReadAll(gz); err != nil {
+					t.Fatalf("Failed to decompress body: %v", err)
+				}
+			}
+			if string(body) != payload {
+				t.Errorf("Expected the backend body to come through unchanged, got %d bytes", len(body))
+			}
+		})
+	}
+}
+
+func TestProxyReusesConnections(t *testing.T) {
+	var conns atomic.Int32
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	backend.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	backend.Start()
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	newInstance := func(disableKeepAlives bool) *instance.Instance {
+		globalConfig := &config.AppConfig{
+			Backends: config.BackendConfig{
+				LlamaCpp: config.BackendSettings{Command: "llama-server"},
+			},
+			Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+			Proxy: config.ProxyConfig{
+				MaxIdleConnsPerHost: 8,
+				DisableKeepAlives:   disableKeepAlives,
+			},
+			Nodes:     map[string]config.NodeConfig{},
+			LocalNode: "main",
+		}
+		return instance.New("test", globalConfig, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+					Host:  backendURL.Hostname(),
+					Port:  port,
+				},
+			},
+		}, nil)
+	}
+
+	serve := func(inst *instance.Instance, n int) {
+		for range n {
+			rec := httptest.NewRecorder()
+			if err := inst.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil)); err != nil {
+				t.Fatalf("ServeHTTP failed: %v", err)
+			}
+		}
+	}
+
+	serve(newInstance(false), 5)
+	if got := conns.Load(); got != 1 {
+		t.Errorf("Expected sequential requests to share one connection, got %d", got)
+	}
+
+	conns.Store(0)
+	serve(newInstance(true), 3)
+	if got := conns.Load(); got != 3 {
+		t.Errorf("Expected a connection per request with keep-alives disabled, got %d", got)
+	}
+}
+
+// mockTimeProvider for timeout testing
+type mockTimeProvider struct {
+	currentTime int64 // Unix timestamp
+}
+
+func (m *mockTimeProvider) Now() time.Time {
+	return time.Unix(m.currentTime, 0)
+}
+
+// atomicTimeProvider is a mockTimeProvider that is safe to move forward
+// while a request is being proxied
+type atomicTimeProvider struct {
+	now atomic.Int64 // Unix timestamp
+}
+
+func (m *atomicTimeProvider) Now() time.Time {
+	return time.Unix(m.now.Load(), 0)
+}
+
+func TestWritePresetIni(t *testing.T) {
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "llama-server",
+				Args:    []string{},
+			},
+			MLX: config.BackendSettings{
+				Command: "mlx_lm.server",
+				Args:    []string{},
+			},
+			VLLM: config.BackendSettings{
+				Command: "vllm",
+				Args:    []string{"serve"},
+			},
+		},
+		Instances: config.InstancesConfig{
+			LogsDir:             "/tmp/test-logs",
+			InstancesDir:        "/tmp/test-instances",
+			DefaultAutoRestart:  true,
+			DefaultMaxRestarts:  3,
+			DefaultRestartDelay: 5,
+			DefaultIdleTimeout:  30,
+		},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	mockOnStatusChange := func(oldStatus, newStatus instance.Status) {}
+
+	t.Run("preset_ini with content creates file", func(t *testing.T) {
+		presetContent := "[model1]\nmodel = /path/to/model1.gguf\ngpu-layers = 35\n"
+		options := &instance.Options{
+			PresetIni: &presetContent,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+					Port:  8080,
+				},
+			},
+		}
+
+		_ = instance.New("test-preset", globalConfig, options, mockOnStatusChange)
+
+		// Verify preset.ini file was created
+		presetPath := "/tmp/test-instances/test-preset/preset.ini"
+		content, err := os.ReadFile(presetPath)
+		if err != nil {
+			t.Fatalf("Failed to read preset.ini file: %v", err)
+		}
+		if string(content) != presetContent {
+			t.Errorf("Expected preset content '%s', got '%s'", presetContent, string(content))
+		}
+	})
+
+	t.Run("empty preset_ini does not create file", func(t *testing.T) {
+		emptyPreset := ""
+		options := &instance.Options{
+			PresetIni: &emptyPreset,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+					Port:  8080,
+				},
+			},
+		}
+
+		_ = instance.New("test-empty", globalConfig, options, mockOnStatusChange)
+
+		// Verify preset.ini file was NOT created
+		presetPath := "/tmp/test-instances/test-empty/preset.ini"
+		if _, err := os.Stat(presetPath); err == nil {
+			t.Error("Expected preset.ini file to NOT exist when preset_ini is empty, but it does")
+		}
+	})
+}
+
+func TestStop_DockerStopsContainer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake docker binary is a shell script")
+	}
+
+	// Fake docker binary: `run` records its pid and blocks, `stop` kills it,
+	// and every invocation is appended to a log for inspection
+	binDir := t.TempDir()
+	callLog := filepath.Join(binDir, "calls.log")
+	pidFile := filepath.Join(binDir, "run.pid")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %q
+case "$1" in
+  run) echo $$ > %q; exec sleep 30 ;;
+  stop) kill "$(cat %q)" ;;
+esac
+`, callLog, pidFile, pidFile)
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake docker: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "llama-server",
+				Docker: &config.DockerSettings{
+					Enabled: true,
+					Image:   "test-image",
+					Args:    []string{"run", "--rm"},
+				},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("test", globalConfig, &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+			},
+		},
+	}, nil)
+
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// Wait for the fake container to come up before stopping it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(pidFile); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("fake docker run never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	if err := inst.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Stop took %v, expected docker stop to end the container promptly", elapsed)
+	}
+
+	data, err := os.ReadFile(callLog)
+	if err != nil {
+		t.Fatalf("failed to read call log: %v", err)
+	}
+	calls := string(data)
+	if !strings.Contains(calls, "run --name llamactl-test --rm test-image") {
+		t.Errorf("expected named docker run, got calls:\n%s", calls)
+	}
+	if !strings.Contains(calls, "stop llamactl-test") {
+		t.Errorf("expected docker stop for the instance container, got calls:\n%s", calls)
+	}
+}
+
+func TestStart_DockerAutoPull(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the container runtime")
+	}
+
+	binDir := t.TempDir()
+	callLog := filepath.Join(binDir, "calls.log")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %q
+case "$1" in
+  image) [ -f %q/"pulled-$3" ] ;;
+  pull)
+    case "$2" in
+      missing-*) echo "manifest unknown"; exit 1 ;;
+    esac
+    echo "latest: Pulling from test"; touch %q/"pulled-$2" ;;
+esac
+`, callLog, binDir, binDir)
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake docker: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	newInstance := func(image string) *instance.Instance {
+		globalConfig := &config.AppConfig{
+			Backends: config.BackendConfig{
+				LlamaCpp: config.BackendSettings{
+					Command: "llama-server",
+					Docker: &config.DockerSettings{
+						Enabled:  true,
+						Image:    image,
+						Args:     []string{"run", "--rm"},
+						AutoPull: true,
+					},
+				},
+			},
+			Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+			Nodes:     map[string]config.NodeConfig{},
+			LocalNode: "main",
+		}
+		return instance.New("test", globalConfig, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}, nil)
+	}
+
+	// Images are remembered for the whole process, so use a fresh name
+	image := fmt.Sprintf("autopull-%d", time.Now().UnixNano())
+	inst := newInstance(image)
+	for range 2 {
+		if err := inst.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		// The fake container exits right away
+		deadline := time.Now().Add(5 * time.Second)
+		for inst.IsRunning() && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	data, err := os.ReadFile(callLog)
+	if err != nil {
+		t.Fatalf("failed to read call log: %v", err)
+	}
+	calls := string(data)
+	if n := strings.Count(calls, "pull "+image); n != 1 {
+		t.Errorf("expected one pull of %s, got %d, calls:\n%s", image, n, calls)
+	}
+	if n := strings.Count(calls, "image inspect "+image); n != 1 {
+		t.Errorf("expected the image to be checked once and then remembered, got %d checks, calls:\n%s", n, calls)
+	}
+	if strings.Index(calls, "pull "+image) > strings.Index(calls, "run --name") {
+		t.Errorf("expected the pull before docker run, calls:\n%s", calls)
+	}
+
+	err = newInstance("missing-image").Start()
+	if err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Errorf("expected the failed pull to fail the start, got: %v", err)
+	}
+}
+
+func TestStart_DockerAutoPullCancelledByStop(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the container runtime")
+	}
+
+	binDir := t.TempDir()
+	callLog := filepath.Join(binDir, "calls.log")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %q
+case "$1" in
+  image) exit 1 ;;
+  pull) exec sleep 30 ;;
+esac
+`, callLog)
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake docker: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	image := fmt.Sprintf("slow-%d", time.Now().UnixNano())
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "llama-server",
+				Docker: &config.DockerSettings{
+					Enabled:  true,
+					Image:    image,
+					Args:     []string{"run", "--rm"},
+					AutoPull: true,
+				},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("test", globalConfig, &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+			},
+		},
+	}, nil)
+
+	startErr := make(chan error, 1)
+	go func() { startErr <- inst.Start() }()
+
+	// Wait for the pull to begin
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := os.ReadFile(callLog); strings.Contains(string(data), "pull "+image) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		inst.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case err := <-startErr:
+		if err == nil {
+			t.Error("expected the cancelled pull to fail the start")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after Stop cancelled the pull")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop blocked on the pull")
+	}
+	if inst.IsRunning() {
+		t.Error("instance should not be running after its pull was cancelled")
+	}
+}
+
+func TestLogFileTemplate(t *testing.T) {
+	logsDir := t.TempDir()
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "sh",
+				Args:    []string{"-c", "echo hello-from-backend; sleep 0.3"},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: logsDir},
+		Logging:   config.LoggingConfig{FileTemplate: "{backend}/{name}.log"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	newInstance := func() *instance.Instance {
+		return instance.New("test", globalConfig, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}, nil)
+	}
+
+	inst := newInstance()
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	logPath := filepath.Join(logsDir, "llama_cpp", "test.log")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, _ := os.ReadFile(logPath)
+		if strings.Contains(string(data), "hello-from-backend") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected backend output in %s, got %q", logPath, data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A fresh instance, as after a llamactl restart, finds the same file
+	logs, err := newInstance().GetLogs(0)
+	if err != nil {
+		t.Fatalf("GetLogs failed: %v", err)
+	}
+	if !strings.Contains(logs, "hello-from-backend") {
+		t.Errorf("expected GetLogs to read the templated log file, got %q", logs)
+	}
+}
+
+func TestLogFileFailure(t *testing.T) {
+	// A regular file where the logs directory should be makes creating the
+	// log file fail, even when running as root
+	logsDir := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(logsDir, nil, 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	for _, required := range []bool{false, true} {
+		t.Run(fmt.Sprintf("required=%v", required), func(t *testing.T) {
+			globalConfig := &config.AppConfig{
+				Backends: config.BackendConfig{
+					LlamaCpp: config.BackendSettings{
+						Command: "sh",
+						Args:    []string{"-c", "sleep 5"},
+					},
+				},
+				Instances: config.InstancesConfig{LogsDir: logsDir},
+				Logging:   config.LoggingConfig{FileTemplate: "{name}.log", Required: required},
+				Nodes:     map[string]config.NodeConfig{},
+				LocalNode: "main",
+			}
+			inst := instance.New("test", globalConfig, &instance.Options{
+				BackendOptions: backends.Options{
+					BackendType: backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{
+						Model: "/path/to/model.gguf",
+					},
+				},
+			}, nil)
+
+			err := inst.Start()
+			if required {
+				if err == nil {
+					inst.Stop()
+					t.Fatal("Expected Start to fail when the log file is required")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected Start to fall back to stdout, got: %v", err)
+			}
+			defer inst.Stop()
+			if !inst.IsRunning() {
+				t.Error("Expected instance to be running")
+			}
+		})
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	newInstance := func(level instance.LogLevel) *instance.Instance {
+		return instance.New("test", globalConfig, &instance.Options{
+			LogLevel: level,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}, nil)
+	}
+
+	tests := []struct {
+		level instance.LogLevel
+		want  []string
+	}{
+		{"", []string{"info", "warn", "error"}},
+		{instance.LogLevelWarn, []string{"warn", "error"}},
+		{instance.LogLevelError, []string{"error"}},
+		{instance.LogLevelOff, nil},
+	}
+
+	for _, tt := range tests {
+		inst := newInstance(tt.level)
+		buf.Reset()
+		inst.Logf(instance.LogLevelInfo, "info")
+		inst.Logf(instance.LogLevelWarn, "warn")
+		inst.Logf(instance.LogLevelError, "error")
+
+		var got []string
+		for line := range strings.Lines(buf.String()) {
+			fields := strings.Fields(line)
+			got = append(got, fields[len(fields)-1])
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("level %q: expected messages %v, got %v", tt.level, tt.want, got)
+		}
+	}
+
+	// Unknown levels fall back to the default
+	if got := newInstance("verbose").GetOptions().LogLevel; got != "" {
+		t.Errorf("Expected invalid log_level to be cleared, got %q", got)
+	}
+}
+
+func TestMirrorLogsToStdout(t *testing.T) {
+	tests := []struct {
+		name     string
+		global   bool
+		override *bool
+		expected bool
+	}{
+		{"global disabled", false, nil, false},
+		{"global enabled", true, nil, true},
+		{"instance enables", false, testutil.BoolPtr(true), true},
+		{"instance disables", true, testutil.BoolPtr(false), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logsDir := t.TempDir()
+			globalConfig := &config.AppConfig{
+				Backends: config.BackendConfig{
+					LlamaCpp: config.BackendSettings{
+						Command: "sh",
+						Args:    []string{"-c", "echo hello-from-backend; sleep 0.3"},
+					},
+				},
+				Instances: config.InstancesConfig{LogsDir: logsDir},
+				Logging:   config.LoggingConfig{MirrorToStdout: tt.global},
+				Nodes:     map[string]config.NodeConfig{},
+				LocalNode: "main",
+			}
+
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatalf("failed to create pipe: %v", err)
+			}
+			origStdout := os.Stdout
+			os.Stdout = w
+			defer func() { os.Stdout = origStdout }()
+
+			inst := instance.New("mirror", globalConfig, &instance.Options{
+				MirrorLogsToStdout: tt.override,
+				BackendOptions: backends.Options{
+					BackendType: backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{
+						Model: "/path/to/model.gguf",
+					},
+				},
+			}, nil)
+			if err := inst.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+
+			// The file always receives the output, mirrored or not
+			logPath := filepath.Join(logsDir, "mirror.log")
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				data, _ := os.ReadFile(logPath)
+				if strings.Contains(string(data), "hello-from-backend") {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("expected backend output in %s, got %q", logPath, data)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			// The line reaches the file before stdout; give the reader a
+			// moment to finish once the backend has exited
+			for inst.IsRunning() && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			time.Sleep(100 * time.Millisecond)
+
+			os.Stdout = origStdout
+			w.Close()
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("failed to read stdout: %v", err)
+			}
+
+			mirrored := strings.Contains(string(out), "[mirror] hello-from-backend")
+			if mirrored != tt.expected {
+				t.Errorf("expected mirrored=%v, got stdout %q", tt.expected, out)
+			}
+		})
+	}
+}
+
+func TestLogOutputSanitization(t *testing.T) {
+	tests := []struct {
+		name          string
+		script        string
+		maxLineLength int
+		expected      []string
+	}{
+		{
+			name:          "long line is truncated",
+			script:        `printf '%*s' 100000 '' | tr ' ' a; echo; echo next-line`,
+			maxLineLength: 10,
+			expected:      []string{"aaaaaaaaaa... [truncated 99990 bytes]", "next-line"},
+		},
+		{
+			name:          "line longer than the read buffer is kept without limit",
+			script:        `printf '%*s' 100000 '' | tr ' ' a; echo; echo next-line`,
+			maxLineLength: 0,
+			expected:      []string{strings.Repeat("a", 100000), "next-line"},
+		},
+		{
+			name:          "truncation does not split characters",
+			script:        `printf 'ab\303\251cd\n'`,
+			maxLineLength: 3,
+			expected:      []string{"ab... [truncated 4 bytes]"},
+		},
+		{
+			name:          "binary runs are replaced",
+			script:        `printf 'start\001\002\377\376middle\033end\ttab\n'`,
+			maxLineLength: 1024,
+			expected:      []string{"start[binary]middle[binary]end\ttab"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			globalConfig := &config.AppConfig{
+				Backends: config.BackendConfig{
+					LlamaCpp: config.BackendSettings{
+						Command: "sh",
+						Args:    []string{"-c", tt.script + "; sleep 0.3"},
+					},
+				},
+				Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+				Logging:   config.LoggingConfig{MaxLineLength: tt.maxLineLength},
+				Nodes:     map[string]config.NodeConfig{},
+				LocalNode: "main",
+			}
+			inst := instance.New("test", globalConfig, &instance.Options{
+				BackendOptions: backends.Options{
+					BackendType: backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{
+						Model: "/path/to/model.gguf",
+					},
+				},
+			}, nil)
+			if err := inst.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+
+			last := tt.expected[len(tt.expected)-1]
+			deadline := time.Now().Add(5 * time.Second)
+			var logs string
+			for {
+				logs, _ = inst.GetLogs(0)
+				if strings.Contains(logs, last+"\n") || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			for _, want := range tt.expected {
+				if !strings.Contains(logs, "\n"+want+"\n") {
+					t.Errorf("expected log line %.60q..., logs:\n%.500s", want, logs)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMetadata(t *testing.T) {
+	var propsRequests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/props", func(w http.ResponseWriter, r *http.Request) {
+		propsRequests.Add(1)
+		fmt.Fprint(w, `{"default_generation_settings":{"n_ctx":4096},"total_slots":2,`+
+			`"model_path":"/models/test.gguf","chat_template":"{{ messages }}",`+
+			`"modalities":{"vision":true,"audio":false},"build_info":"b1234-abcdef"}`)
+	})
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":[{"id":"test.gguf","meta":{"n_ctx_train":131072}}]}`)
+	})
+	backend := httptest.NewServer(mux)
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "sh",
+				Args:    []string{"-c", "sleep 999999"},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("test", globalConfig, &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/models/test.gguf",
+				Host:  "127.0.0.1",
+				Port:  port,
+			},
+		},
+	}, nil)
+
+	if _, err := inst.GetMetadata(context.Background()); err == nil {
+		t.Error("expected error for stopped instance")
+	}
+
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer inst.Stop()
+
+	md, err := inst.GetMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	expected := &instance.Metadata{
+		BackendType:  backends.BackendTypeLlamaCpp,
+		Model:        "/models/test.gguf",
+		ContextSize:  131072,
+		NCtx:         4096,
+		TotalSlots:   2,
+		ChatTemplate: "{{ messages }}",
+		Modalities:   map[string]bool{"vision": true, "audio": false},
+		BuildInfo:    "b1234-abcdef",
+	}
+	if !reflect.DeepEqual(md, expected) {
+		t.Errorf("metadata = %+v, want %+v", md, expected)
+	}
+
+	if _, err := inst.GetMetadata(context.Background()); err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if got := propsRequests.Load(); got != 1 {
+		t.Errorf("expected metadata to be cached, backend queried %d times", got)
+	}
+
+	// A restart must drop the cached metadata
+	if err := inst.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if _, err := inst.GetMetadata(context.Background()); err != nil {
+		t.Fatalf("GetMetadata failed: %v", err)
+	}
+	if got := propsRequests.Load(); got != 2 {
+		t.Errorf("expected metadata to be refetched after restart, backend queried %d times", got)
+	}
+}
+
+func TestChatTemplateRef(t *testing.T) {
+	instancesDir := t.TempDir()
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				// Print the file passed as the last argument (--chat-template-file PATH)
+				Command: "sh",
+				Args:    []string{"-c", `for a in "$0" "$@"; do last=$a; done; cat "$last"; echo; sleep 0.3`},
+			},
+		},
+		Instances: config.InstancesConfig{
+			LogsDir:      t.TempDir(),
+			InstancesDir: instancesDir,
+		},
+		ChatTemplates: map[string]string{
+			"chatml": "{% for message in messages %}<|im_start|>{{ message.role }}{% endfor %}",
+		},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("test", globalConfig, &instance.Options{
+		ChatTemplateRef: "chatml",
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+			},
+		},
+	}, nil)
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	want := globalConfig.ChatTemplates["chatml"]
+	deadline := time.Now().Add(5 * time.Second)
+	var logs string
+	for {
+		logs, _ = inst.GetLogs(0)
+		if strings.Contains(logs, want) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(logs, want) {
+		t.Errorf("expected backend to receive the chat template file, logs:\n%s", logs)
+	}
+
+	data, err := os.ReadFile(filepath.Join(instancesDir, "test", "chat_template.jinja"))
+	if err != nil {
+		t.Fatalf("failed to read chat template file: %v", err)
+	}
+	if string(data) != want {
+		t.Errorf("chat template file = %q, want %q", data, want)
+	}
+}
+
+func TestChatTemplateRef_MountedInDocker(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the container runtime")
+	}
+
+	// The fake runtime prints its arguments for docker run
+	binDir := t.TempDir()
+	script := `#!/bin/sh
+[ "$1" = "run" ] && echo "$@" && sleep 0.3
+exit 0
+`
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake docker: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	instancesDir := t.TempDir()
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "llama-server",
+				Docker: &config.DockerSettings{
+					Enabled: true,
+					Image:   "llama",
+					Args:    []string{"run", "--rm"},
+				},
+			},
+		},
+		Instances: config.InstancesConfig{
+			LogsDir:      t.TempDir(),
+			InstancesDir: instancesDir,
+		},
+		ChatTemplates: map[string]string{"chatml": "{{ messages }}"},
+		Nodes:         map[string]config.NodeConfig{},
+		LocalNode:     "main",
+	}
+	inst := instance.New("test", globalConfig, &instance.Options{
+		ChatTemplateRef: "chatml",
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+			},
+		},
+	}, nil)
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	templatePath := filepath.Join(instancesDir, "test", "chat_template.jinja")
+	want := "-v " + templatePath + ":" + templatePath + ":ro"
+	deadline := time.Now().Add(5 * time.Second)
+	var logs string
+	for {
+		logs, _ = inst.GetLogs(0)
+		if strings.Contains(logs, want) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(logs, want) {
+		t.Errorf("expected the chat template to be mounted with %q, logs:\n%s", want, logs)
+	}
+	if !strings.Contains(logs, "--chat-template-file "+templatePath) {
+		t.Errorf("expected --chat-template-file %s, logs:\n%s", templatePath, logs)
+	}
+}
+
+func TestValidateChatTemplateRef(t *testing.T) {
+	templates := map[string]string{"chatml": "{{ messages }}"}
+
+	tests := []struct {
+		name    string
+		options *instance.Options
+		wantErr bool
+	}{
+		{
+			name: "no reference",
+			options: &instance.Options{
+				BackendOptions: backends.Options{BackendType: backends.BackendTypeVllm},
+			},
+		},
+		{
+			name: "known template",
+			options: &instance.Options{
+				ChatTemplateRef: "chatml",
+				BackendOptions: backends.Options{
+					BackendType:        backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{Model: "/m.gguf"},
+				},
+			},
+		},
+		{
+			name: "unknown template",
+			options: &instance.Options{
+				ChatTemplateRef: "missing",
+				BackendOptions: backends.Options{
+					BackendType:        backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{Model: "/m.gguf"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "conflicts with inline template",
+			options: &instance.Options{
+				ChatTemplateRef: "chatml",
+				BackendOptions: backends.Options{
+					BackendType:        backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{Model: "/m.gguf", ChatTemplate: "chatml"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported backend",
+			options: &instance.Options{
+				ChatTemplateRef: "chatml",
+				BackendOptions: backends.Options{
+					BackendType:       backends.BackendTypeVllm,
+					VllmServerOptions: &backends.VllmServerOptions{Model: "org/model"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.options.ValidateChatTemplateRef(templates)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateChatTemplateRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRouterModels_NormalizesHFRepo(t *testing.T) {
+	opts := &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{},
+		},
+		RouterModels: []instance.RouterModel{
+			{Name: "coder", HFRepo: " https://huggingface.co/Qwen/Qwen2.5-Coder-7B-Instruct-GGUF:Q4_K_M "},
+		},
+	}
+	if err := opts.ValidateRouterModels(); err != nil {
+		t.Fatalf("ValidateRouterModels() failed: %v", err)
+	}
+	if got := opts.RouterModels[0].HFRepo; got != "Qwen/Qwen2.5-Coder-7B-Instruct-GGUF:Q4_K_M" {
+		t.Errorf("expected hf_repo to be normalized, got %q", got)
+	}
+}
+
+func TestResolveModelAliases(t *testing.T) {
+	aliases := map[string]string{
+		"qwen-coder": "Qwen/Qwen2.5-Coder-7B-Instruct-GGUF:Q4_K_M",
+		"llama":      "meta-llama/Llama-3.2-3B",
+	}
+
+	t.Run("llama.cpp model becomes hf_repo", func(t *testing.T) {
+		opts := &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "qwen-coder"},
+			},
+			RouterModels: []instance.RouterModel{{Name: "coder", Model: "qwen-coder"}, {Name: "local", Model: "/m.gguf"}},
+		}
+		if err := opts.ResolveModelAliases(aliases); err != nil {
+			t.Fatalf("ResolveModelAliases() failed: %v", err)
+		}
+		lo := opts.BackendOptions.LlamaServerOptions
+		if lo.Model != "" || lo.HFRepo != aliases["qwen-coder"] {
+			t.Errorf("got model=%q hf_repo=%q, want hf_repo %q", lo.Model, lo.HFRepo, aliases["qwen-coder"])
+		}
+		if opts.RouterModels[0].HFRepo != aliases["qwen-coder"] || opts.RouterModels[0].Model != "" {
+			t.Errorf("router model not resolved: %+v", opts.RouterModels[0])
+		}
+		if opts.RouterModels[1].Model != "/m.gguf" {
+			t.Errorf("non-alias router model changed: %+v", opts.RouterModels[1])
+		}
+	})
+
+	t.Run("vllm model becomes repo", func(t *testing.T) {
+		opts := &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:       backends.BackendTypeVllm,
+				VllmServerOptions: &backends.VllmServerOptions{Model: "llama"},
+			},
+		}
+		if err := opts.ResolveModelAliases(aliases); err != nil {
+			t.Fatalf("ResolveModelAliases() failed: %v", err)
+		}
+		if got := opts.BackendOptions.VllmServerOptions.Model; got != "meta-llama/Llama-3.2-3B" {
+			t.Errorf("model = %q, want meta-llama/Llama-3.2-3B", got)
+		}
+	})
+
+	t.Run("tagged alias rejected for mlx", func(t *testing.T) {
+		opts := &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:      backends.BackendTypeMlxLm,
+				MlxServerOptions: &backends.MlxServerOptions{Model: "qwen-coder"},
+			},
+		}
+		if err := opts.ResolveModelAliases(aliases); err == nil {
+			t.Error("expected error for alias with a tag on mlx")
+		}
+	})
+
+	t.Run("alias conflicts with hf_repo", func(t *testing.T) {
+		opts := &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "qwen-coder", HFRepo: "org/other"},
+			},
+		}
+		if err := opts.ResolveModelAliases(aliases); err == nil {
+			t.Error("expected error when alias is combined with hf_repo")
+		}
+	})
+
+	t.Run("unknown names are left alone", func(t *testing.T) {
+		opts := &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "/models/qwen-coder.gguf"},
+			},
+		}
+		if err := opts.ResolveModelAliases(aliases); err != nil {
+			t.Fatalf("ResolveModelAliases() failed: %v", err)
+		}
+		if got := opts.BackendOptions.LlamaServerOptions.Model; got != "/models/qwen-coder.gguf" {
+			t.Errorf("model = %q, want unchanged", got)
+		}
+	})
+}
+
+func TestWarmup(t *testing.T) {
+	requests := make(chan map[string]any, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/completions", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		requests <- body
+		fmt.Fprint(w, `{"choices":[{"text":"!"}]}`)
+	})
+	backend := httptest.NewServer(mux)
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "sh",
+				Args:    []string{"-c", "sleep 999999"},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("test", globalConfig, &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/models/test.gguf",
+				Host:  "127.0.0.1",
+				Port:  port,
+			},
+		},
+	}, nil)
+
+	if err := inst.Warmup(context.Background()); err == nil {
+		t.Error("expected error for stopped instance")
+	}
+
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer inst.Stop()
+
+	if err := inst.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup failed: %v", err)
+	}
+	body := <-requests
+	if body["max_tokens"] != float64(1) {
+		t.Errorf("expected a one-token completion, got %v", body)
+	}
+}
+
+func TestDebugLogBodies(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model not found"}`)
+	}))
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	logsDir := t.TempDir()
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "sh",
+				Args:    []string{"-c", "sleep 999999"},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: logsDir},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("debug", globalConfig, &instance.Options{
+		DebugLogBodies: testutil.BoolPtr(true),
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/models/test.gguf",
+				Host:  "127.0.0.1",
+				Port:  port,
+			},
+		},
+	}, nil)
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer inst.Stop()
+
+	body := `{"model":"mlx-community/test","api_key":"sk-secret","prompt":"` + strings.Repeat("x", 5000) + `"}`
+	rec := httptest.NewRecorder()
+	if err := inst.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(body))); err != nil {
+		t.Fatalf("ServeHTTP failed: %v", err)
+	}
+	if rec.Code != http.StatusNotFound || rec.Body.String() != `{"error":"model not found"}` {
+		t.Fatalf("expected backend response to pass through, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	data, err := os.ReadFile(filepath.Join(logsDir, "debug.log"))
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	logs := string(data)
+	for _, want := range []string{
+		`[proxy] POST /v1/completions request body: {"model":"mlx-community/test","api_key":"[REDACTED]"`,
+		"... [truncated",
+		`[proxy] POST /v1/completions response 404 body: {"error":"model not found"}`,
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("expected log to contain %q, got:\n%s", want, logs)
+		}
+	}
+	if strings.Contains(logs, "sk-secret") {
+		t.Error("expected secret to be redacted from the log")
+	}
+}
+
+func TestRetryAfterRestart(t *testing.T) {
+	var dropNext atomic.Bool
+	var completions atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			return
+		}
+		completions.Add(1)
+		if dropNext.CompareAndSwap(true, false) {
+			// Simulate the backend dying mid-request
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	// The process crashes once, shortly after its first start
+	marker := filepath.Join(t.TempDir(), "crashed")
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "sh",
+				Args:    []string{"-c", fmt.Sprintf("if [ -e %[1]s ]; then sleep 999999; else touch %[1]s; sleep 0.5; exit 1; fi", marker)},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: t.TempDir(), OnDemandStartTimeout: 10},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("retry", globalConfig, &instance.Options{
+		AutoRestart:  testutil.BoolPtr(true),
+		MaxRestarts:  testutil.IntPtr(3),
+		RestartDelay: testutil.IntPtr(0),
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/models/test.gguf",
+				Host:  "127.0.0.1",
+				Port:  port,
+			},
+		},
+	}, nil)
+
+	t.Run("POST without opt-in is not retried", func(t *testing.T) {
+		dropNext.Store(true)
+		completions.Store(0)
+		rec := httptest.NewRecorder()
+		inst.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(`{"prompt":"hi"}`)))
+
+		if rec.Code != http.StatusBadGateway {
+			t.Errorf("expected 502 without retry, got %d", rec.Code)
+		}
+		if got := completions.Load(); got != 1 {
+			t.Errorf("expected a single attempt, got %d", got)
+		}
+	})
+
+	t.Run("opted-in request is retried after restart", func(t *testing.T) {
+		if err := inst.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer inst.Stop()
+
+		dropNext.Store(true)
+		completions.Store(0)
+		req := httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(`{"prompt":"hi"}`))
+		req.Header.Set(instance.RetryHeader, "true")
+		rec := httptest.NewRecorder()
+		inst.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+			t.Errorf("expected retried request to succeed, got %d %q", rec.Code, rec.Body.String())
+		}
+		if got := completions.Load(); got != 2 {
+			t.Errorf("expected 2 attempts, got %d", got)
+		}
+	})
+}
+
+func TestAutoRestart_ExitCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		exitCode   int
+		restartOn  []int
+		noRestart  []int
+		wantStarts int
+	}{
+		{"any failure restarts by default", 1, nil, nil, 3},
+		{"listed exit code restarts", 1, []int{1, 3}, nil, 3},
+		{"unlisted exit code does not restart", 2, []int{1}, nil, 1},
+		{"excluded exit code does not restart", 2, nil, []int{2}, 1},
+		{"exclusion wins over inclusion", 2, []int{2}, []int{2}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every start appends a line, then the process exits with the code under test
+			counter := filepath.Join(t.TempDir(), "starts")
+			globalConfig := &config.AppConfig{
+				Backends: config.BackendConfig{
+					LlamaCpp: config.BackendSettings{
+						Command: "sh",
+						Args:    []string{"-c", fmt.Sprintf("echo start >> %s; exit %d", counter, tt.exitCode)},
+					},
+				},
+				Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+				Nodes:     map[string]config.NodeConfig{},
+				LocalNode: "main",
+			}
+			inst := instance.New("exit-codes", globalConfig, &instance.Options{
+				AutoRestart:          testutil.BoolPtr(true),
+				MaxRestarts:          testutil.IntPtr(2),
+				RestartDelay:         testutil.IntPtr(0),
+				RestartOnExitCodes:   tt.restartOn,
+				NoRestartOnExitCodes: tt.noRestart,
+				BackendOptions: backends.Options{
+					BackendType:        backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{Model: "/models/test.gguf", Port: 8080},
+				},
+			}, nil)
+
+			if err := inst.Start(); err != nil {
+				t.Fatalf("Start failed: %v", err)
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for inst.GetStatus() != instance.Failed && time.Now().Before(deadline) {
+				time.Sleep(20 * time.Millisecond)
+			}
+			if status := inst.GetStatus(); status != instance.Failed {
+				t.Fatalf("status = %v, want failed", status)
+			}
+
+			data, _ := os.ReadFile(counter)
+			if got := strings.Count(string(data), "start"); got != tt.wantStarts {
+				t.Errorf("process started %d times, want %d", got, tt.wantStarts)
+			}
+			if exit := inst.LastExit(); exit == nil || exit.ExitCode != tt.exitCode {
+				t.Errorf("LastExit() = %+v, want exit code %d", exit, tt.exitCode)
+			}
+		})
+	}
+}
+
+func TestValidatePlacement(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		opts := &instance.Options{CPUAffinity: "0"}
+		if err := opts.ValidatePlacement(&config.BackendConfig{}); err == nil {
+			t.Error("expected cpu_affinity to be rejected outside Linux")
+		}
+		return
+	}
+
+	llama := backends.Options{
+		BackendType:        backends.BackendTypeLlamaCpp,
+		LlamaServerOptions: &backends.LlamaServerOptions{Model: "/m.gguf"},
+	}
+	tests := []struct {
+		name    string
+		options *instance.Options
+		wantErr bool
+	}{
+		{"nothing set", &instance.Options{BackendOptions: llama}, false},
+		{"single cpu", &instance.Options{CPUAffinity: "3", BackendOptions: llama}, false},
+		{"ranges and lists", &instance.Options{CPUAffinity: "0-3, 8,10-11", BackendOptions: llama}, false},
+		{"nice bounds", &instance.Options{Nice: testutil.IntPtr(-20), BackendOptions: llama}, false},
+		{"reversed range", &instance.Options{CPUAffinity: "3-1", BackendOptions: llama}, true},
+		{"not a number", &instance.Options{CPUAffinity: "a", BackendOptions: llama}, true},
+		{"empty list item", &instance.Options{CPUAffinity: "0,,1", BackendOptions: llama}, true},
+		{"cpu out of range", &instance.Options{CPUAffinity: "1024", BackendOptions: llama}, true},
+		{"huge range", &instance.Options{CPUAffinity: "0-99999999", BackendOptions: llama}, true},
+		{"nice too high", &instance.Options{Nice: testutil.IntPtr(20), BackendOptions: llama}, true},
+		{"docker", &instance.Options{Nice: testutil.IntPtr(5), DockerEnabled: testutil.BoolPtr(true), BackendOptions: llama}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backendConfig := &config.BackendConfig{
+				LlamaCpp: config.BackendSettings{Docker: &config.DockerSettings{Image: "llama"}},
+			}
+			err := tt.options.ValidatePlacement(backendConfig)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePlacement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPlacement_AppliedToProcess(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("cpu_affinity and nice are only supported on Linux")
+	}
+
+	// Placement is applied before exec, so the backend sees it from its first instruction
+	dir := t.TempDir()
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "sh",
+				Args:    []string{"-c", fmt.Sprintf("cat /proc/$$/status > %[1]s/status; cat /proc/$$/stat > %[1]s/stat; sleep 999999", dir)},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: dir},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("placed", globalConfig, &instance.Options{
+		CPUAffinity: "0",
+		Nice:        testutil.IntPtr(5),
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/models/test.gguf", Port: 8080},
+		},
+	}, nil)
+
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer inst.Stop()
+
+	var stat []byte
+	deadline := time.Now().Add(5 * time.Second)
+	for len(stat) == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		stat, _ = os.ReadFile(filepath.Join(dir, "stat"))
+	}
+	status, _ := os.ReadFile(filepath.Join(dir, "status"))
+
+	if !strings.Contains(string(status), "Cpus_allowed_list:\t0\n") {
+		t.Errorf("expected the process to be pinned to CPU 0, status:\n%s", status)
+	}
+	// Fields after the parenthesised command name start at field 3 (state); nice is field 19
+	_, rest, _ := strings.Cut(string(stat), ") ")
+	if fields := strings.Fields(rest); len(fields) < 17 || fields[16] != "5" {
+		t.Errorf("expected nice 5, stat: %s", stat)
+	}
+}
+
+func TestValidateWorkingDir(t *testing.T) {
+	existing := t.TempDir()
+	file := filepath.Join(existing, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(existing, "missing")
+
+	llama := backends.Options{
+		BackendType:        backends.BackendTypeLlamaCpp,
+		LlamaServerOptions: &backends.LlamaServerOptions{Model: "/m.gguf"},
+	}
+	tests := []struct {
+		name       string
+		options    *instance.Options
+		autoCreate bool
+		wantErr    bool
+	}{
+		{"not set", &instance.Options{BackendOptions: llama}, false, false},
+		{"existing directory", &instance.Options{WorkingDir: existing, BackendOptions: llama}, false, false},
+		{"relative path", &instance.Options{WorkingDir: "models", BackendOptions: llama}, true, true},
+		{"missing directory", &instance.Options{WorkingDir: missing, BackendOptions: llama}, false, true},
+		{"missing directory with auto create", &instance.Options{WorkingDir: missing, BackendOptions: llama}, true, false},
+		{"not a directory", &instance.Options{WorkingDir: file, BackendOptions: llama}, true, true},
+		{"docker path is not checked", &instance.Options{WorkingDir: "/workspace", DockerEnabled: testutil.BoolPtr(true), BackendOptions: llama}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backendConfig := &config.BackendConfig{
+				LlamaCpp: config.BackendSettings{Docker: &config.DockerSettings{Image: "llama"}},
+			}
+			err := tt.options.ValidateWorkingDir(backendConfig, tt.autoCreate)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWorkingDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorkingDir_AppliedToProcess(t *testing.T) {
+	// The backend records the directory it was started in
+	dir := t.TempDir()
+	workDir := filepath.Join(dir, "work")
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "sh",
+				Args:    []string{"-c", fmt.Sprintf("pwd > %s/pwd; sleep 999999", dir)},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: dir, AutoCreateDirs: true},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("workdir", globalConfig, &instance.Options{
+		WorkingDir: workDir,
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/models/test.gguf", Port: 8080},
+		},
+	}, nil)
+
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer inst.Stop()
+
+	var pwd []byte
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pwd) == 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		pwd, _ = os.ReadFile(filepath.Join(dir, "pwd"))
+	}
+	if got := strings.TrimSpace(string(pwd)); got != workDir {
+		t.Errorf("expected the backend to run in %s, got %q", workDir, got)
+	}
+}
+
+func TestStdinData_WrittenToProcess(t *testing.T) {
+	// The backend copies its stdin to a file once llamactl closes it
+	dir := t.TempDir()
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command: "sh",
+				Args:    []string{"-c", fmt.Sprintf("cat > %[1]s/stdin.tmp && mv %[1]s/stdin.tmp %[1]s/stdin; sleep 999999", dir)},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: dir},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	data := "model: /models/test.gguf\nctx_size: 4096\n"
+	inst := instance.New("stdin", globalConfig, &instance.Options{
+		StdinData: data,
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/models/test.gguf", Port: 8080},
+		},
+	}, nil)
+
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer inst.Stop()
+
+	var got []byte
+	var err error
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, err = os.ReadFile(filepath.Join(dir, "stdin")); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if string(got) != data {
+		t.Errorf("expected the backend to read %q from stdin, got %q (%v)", data, got, err)
+	}
+}
+
+func TestValidateStdinData(t *testing.T) {
+	opts := &instance.Options{StdinData: strings.Repeat("x", 1<<20)}
+	if err := opts.ValidateStdinData(); err != nil {
+		t.Errorf("expected 1 MiB of stdin_data to be accepted, got %v", err)
+	}
+	opts.StdinData += "x"
+	if err := opts.ValidateStdinData(); err == nil {
+		t.Error("expected stdin_data over 1 MiB to be rejected")
+	}
+}
+
+func TestGetOpenAPISpec(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"openapi":"3.1.0","info":{"title":"vLLM","version":"0.9.0"},"paths":{"/v1/models":{}}}`)
+	})
+	backend := httptest.NewServer(mux)
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	sleepBackend := config.BackendSettings{Command: "sh", Args: []string{"-c", "sleep 999999"}}
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: sleepBackend,
+			VLLM:     sleepBackend,
+		},
+		Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	t.Run("vllm spec is passed through", func(t *testing.T) {
+		inst := instance.New("vllm", globalConfig, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeVllm,
+				VllmServerOptions: &backends.VllmServerOptions{
+					Model: "org/model",
+					Host:  "127.0.0.1",
+					Port:  port,
+				},
+			},
+		}, nil)
+		if err := inst.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer inst.Stop()
+
+		spec, err := inst.GetOpenAPISpec(context.Background())
+		if err != nil {
+			t.Fatalf("GetOpenAPISpec failed: %v", err)
+		}
+		if info, _ := spec["info"].(map[string]any); info["title"] != "vLLM" {
+			t.Errorf("expected backend spec to be returned, got %v", spec)
+		}
+	})
+
+	t.Run("llama.cpp spec is synthesized", func(t *testing.T) {
+		inst := instance.New("llama", globalConfig, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "/models/test.gguf"},
+			},
+		}, nil)
+		if err := inst.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		defer inst.Stop()
+
+		spec, err := inst.GetOpenAPISpec(context.Background())
+		if err != nil {
+			t.Fatalf("GetOpenAPISpec failed: %v", err)
+		}
+		paths, _ := spec["paths"].(map[string]any)
+		chat, _ := paths["/v1/chat/completions"].(map[string]any)
+		if _, ok := chat["post"]; !ok {
+			t.Errorf("expected POST /v1/chat/completions in synthesized spec, got %v", paths)
+		}
+		if info, _ := spec["info"].(map[string]any); info["x-llamactl-synthesized"] != true {
+			t.Errorf("expected synthesized spec to be marked, got %v", spec["info"])
 		}
 	})
 }
+
+func TestProxyTargetUsesCustomHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		listenAddr string
+		host       string
+	}{
+		{"custom loopback address", "127.0.0.2:0", "127.0.0.2"},
+		{"wildcard address is reached over loopback", "127.0.0.1:0", "0.0.0.0"},
+		{"IPv6 address", "[::1]:0", "::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", tt.listenAddr)
+			if err != nil {
+				t.Skipf("cannot listen on %s: %v", tt.listenAddr, err)
+			}
+			backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			backend.Listener.Close()
+			backend.Listener = listener
+			backend.Start()
+			defer backend.Close()
+
+			globalConfig := &config.AppConfig{
+				Backends: config.BackendConfig{
+					LlamaCpp: config.BackendSettings{Command: "llama-server"},
+				},
+				Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+				Nodes:     map[string]config.NodeConfig{},
+				LocalNode: "main",
+			}
+			inst := instance.New("test", globalConfig, &instance.Options{
+				BackendOptions: backends.Options{
+					BackendType: backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{
+						Model: "/path/to/model.gguf",
+						Host:  tt.host,
+						Port:  listener.Addr().(*net.TCPAddr).Port,
+					},
+				},
+			}, nil)
+
+			rec := httptest.NewRecorder()
+			if err := inst.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil)); err != nil {
+				t.Fatalf("ServeHTTP returned error: %v", err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Errorf("Expected request to reach the backend on %s, got status %d", tt.host, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRequestStats(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "llama-server"},
+		},
+		Instances: config.InstancesConfig{LogsDir: "/tmp/test"},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+
+	inst := instance.New("test", globalConfig, &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+				Host:  backendURL.Hostname(),
+				Port:  port,
+			},
+		},
+	}, nil)
+
+	if stats := inst.GetRequestStats(); stats.TotalRequests != 0 || stats.AverageLatencyMs != 0 {
+		t.Fatalf("Expected empty stats before any request, got %+v", stats)
+	}
+
+	for _, path := range []string{"/ok", "/ok", "/ok", "/fail"} {
+		if err := inst.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil)); err != nil {
+			t.Fatalf("ServeHTTP failed: %v", err)
+		}
+	}
+
+	stats := inst.GetRequestStats()
+	if stats.TotalRequests != 4 {
+		t.Errorf("Expected 4 total requests, got %d", stats.TotalRequests)
+	}
+	if stats.ErrorRequests != 1 {
+		t.Errorf("Expected 1 error request, got %d", stats.ErrorRequests)
+	}
+	if stats.AverageLatencyMs <= 0 {
+		t.Errorf("Expected a positive average latency, got %v", stats.AverageLatencyMs)
+	}
+
+	var bucketed int64
+	for _, bucket := range stats.LatencyHistogram {
+		bucketed += bucket.Count
+	}
+	if bucketed != 4 {
+		t.Errorf("Expected histogram to hold 4 requests, got %d", bucketed)
+	}
+	if last := stats.LatencyHistogram[len(stats.LatencyHistogram)-1]; last.Le != "+Inf" {
+		t.Errorf("Expected last bucket to be +Inf, got %q", last.Le)
+	}
+}
+
+func TestLlamaRpcWorker(t *testing.T) {
+	// Stand in for rpc-server, which accepts raw TCP connections only
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaRpc: config.BackendSettings{
+				Command: "sh",
+				Args:    []string{"-c", "sleep 999999"},
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: t.TempDir(), DefaultIdleTimeout: 30},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("worker", globalConfig, &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaRpc,
+			LlamaRpcServerOptions: &backends.LlamaRpcServerOptions{
+				Host: "127.0.0.1",
+				Port: port,
+			},
+		},
+	}, nil)
+
+	if idle := inst.GetOptions().IdleTimeout; idle == nil || *idle != 0 {
+		t.Errorf("Expected idle timeout to be disabled for RPC workers, got %v", idle)
+	}
+
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer inst.Stop()
+
+	if err := inst.WaitForHealthy(5); err != nil {
+		t.Errorf("Expected TCP health check to pass: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := inst.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil)); err != nil {
+		t.Fatalf("ServeHTTP failed: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 when proxying to an RPC worker, got %d", rec.Code)
+	}
+}
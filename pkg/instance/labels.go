@@ -0,0 +1,85 @@
+package instance
+
+import (
+	"fmt"
+	"llamactl/pkg/validation"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	maxDescriptionLength = 1024
+	maxLabelValueLength  = 256
+)
+
+// labelKeyPattern allows keys like "team", "env" or "example.com/owner". Keys
+// can't contain "=", so they are unambiguous in a label.key=value filter.
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]{0,61}[A-Za-z0-9])?$`)
+
+// annotationOptions are the options that only describe an instance; changing
+// them doesn't require restarting its backend
+var annotationOptions = []string{"description", "labels"}
+
+// ValidateLabels checks the description and labels of the instance
+func (c *Options) ValidateLabels() error {
+	if len(c.Description) > maxDescriptionLength {
+		return validation.ValidationError(fmt.Errorf("description is longer than %d characters", maxDescriptionLength))
+	}
+	for _, key := range slices.Sorted(maps.Keys(c.Labels)) {
+		if !labelKeyPattern.MatchString(key) {
+			return validation.ValidationError(fmt.Errorf("invalid label key %q: use up to 63 letters, digits, '.', '_', '-' or '/', starting and ending with a letter or digit", key))
+		}
+		value := c.Labels[key]
+		if len(value) > maxLabelValueLength {
+			return validation.ValidationError(fmt.Errorf("label %s is longer than %d characters", key, maxLabelValueLength))
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return validation.ValidationError(fmt.Errorf("label %s contains a newline", key))
+		}
+	}
+	return nil
+}
+
+// MatchesLabels reports whether the instance has every label in selector
+// with the given value
+func (i *Instance) MatchesLabels(selector map[string]string) bool {
+	if len(selector) == 0 {
+		return true
+	}
+	opts := i.GetOptions()
+	if opts == nil {
+		return false
+	}
+	for key, value := range selector {
+		if got, ok := opts.Labels[key]; !ok || got != value {
+			return false
+		}
+	}
+	return true
+}
+
+// RequiresRestart reports whether replacing the instance's options with opts
+// needs the backend restarted, which is the case unless only the description
+// or labels change. Like SetOptions, it applies the defaults to opts first,
+// so they don't count as changes.
+func (i *Instance) RequiresRestart(opts *Options) bool {
+	current := i.GetOptions()
+	if current == nil {
+		return true
+	}
+	opts.Nodes = current.Nodes
+	opts.validateAndApplyDefaults(i.Name, i.globalInstanceSettings)
+
+	changes := DiffOptions(current, opts)
+	if len(changes) == 0 {
+		return true
+	}
+	for _, change := range changes {
+		if !slices.Contains(annotationOptions, change) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package instance
+
+import (
+	"log"
+	"slices"
+)
+
+// LogLevel sets how much llamactl itself logs about an instance: starts,
+// stops, restarts and proxy errors. The backend's own output is not affected
+// and still goes to the instance log file.
+type LogLevel string
+
+const (
+	LogLevelInfo  LogLevel = "info"  // Everything (default)
+	LogLevelWarn  LogLevel = "warn"  // Warnings and errors
+	LogLevelError LogLevel = "error" // Errors such as crashes and failed restarts
+	LogLevelOff   LogLevel = "off"   // Nothing
+)
+
+// logLevels is ordered from most to least verbose
+var logLevels = []LogLevel{LogLevelInfo, LogLevelWarn, LogLevelError, LogLevelOff}
+
+// validLogLevel reports whether level is empty or one of the known levels
+func validLogLevel(level LogLevel) bool {
+	return level == "" || slices.Contains(logLevels, level)
+}
+
+// Logf logs a message about the instance, unless its log_level is set above
+// the message's level
+func (i *Instance) Logf(level LogLevel, format string, args ...any) {
+	if slices.Index(logLevels, level) < slices.Index(logLevels, i.logLevel()) {
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// logLevel returns the instance's log level, defaulting to info
+func (i *Instance) logLevel() LogLevel {
+	opts := i.GetOptions()
+	if opts == nil || opts.LogLevel == "" {
+		return LogLevelInfo
+	}
+	return opts.LogLevel
+}
@@ -4,10 +4,15 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"llamactl/pkg/config"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	timber "github.com/DeRuina/timberjack"
 )
@@ -22,34 +27,86 @@ type LogRotationConfig struct {
 type logger struct {
 	name        string
 	logDir      string
+	settings    *config.LoggingConfig
 	logFile     *timber.Logger
 	logFilePath string
+	stdout      io.Writer // set while output is mirrored to llamactl's stdout
 	mu          sync.RWMutex
 	cfg         *LogRotationConfig
 }
 
-func newLogger(name, logDir string, cfg *LogRotationConfig) *logger {
+const (
+	// binaryPlaceholder replaces each run of non-printable bytes in a line
+	binaryPlaceholder = "[binary]"
+	// readBufferSize is the chunk size output is read in; longer lines are
+	// assembled from several chunks
+	readBufferSize = 64 * 1024
+)
+
+// stdoutMu keeps lines mirrored by different instances from interleaving
+var stdoutMu sync.Mutex
+
+func newLogger(name, logDir string, settings *config.LoggingConfig, cfg *LogRotationConfig) *logger {
 	return &logger{
-		name:   name,
-		logDir: logDir,
-		cfg:    cfg,
+		name:     name,
+		logDir:   logDir,
+		settings: settings,
+		cfg:      cfg,
+	}
+}
+
+// resolvePath expands the log file template for the given backend
+func (l *logger) resolvePath(backend string) (string, error) {
+	if l.logDir == "" {
+		return "", fmt.Errorf("logDir empty for instance %s", l.name)
+	}
+
+	rel, err := config.ExpandLogFileTemplate(l.settings.FileTemplate, l.name, backend, time.Now())
+	if err != nil {
+		return "", err
 	}
+	return filepath.Join(l.logDir, rel), nil
 }
 
-func (l *logger) create() error {
+// create opens the log file for a new run. When the file can't be opened
+// and logging.required is off, output goes to llamactl's stdout instead so a
+// filesystem problem doesn't keep the instance from starting.
+func (l *logger) create(backend string, mirrorToStdout bool) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	if l.logDir == "" {
-		return fmt.Errorf("logDir empty for instance %s", l.name)
+	l.stdout = nil
+	if mirrorToStdout {
+		l.stdout = os.Stdout
 	}
 
-	if err := os.MkdirAll(l.logDir, 0755); err != nil {
-		return fmt.Errorf("failed to create log directory: %w", err)
+	logPath, t, err := l.openLogFile(backend)
+	if err != nil {
+		if l.settings.Required {
+			return err
+		}
+		log.Printf("Warning: instance %s: %v, writing its output to stdout instead", l.name, err)
+		l.logFile = nil
+		l.logFilePath = ""
+		l.stdout = os.Stdout
+		return nil
 	}
 
-	logPath := fmt.Sprintf("%s/%s.log", l.logDir, l.name)
+	l.logFile = t
 	l.logFilePath = logPath
+	return nil
+}
+
+// openLogFile resolves the log path and opens it by writing the startup marker
+func (l *logger) openLogFile(backend string) (string, *timber.Logger, error) {
+	logPath, err := l.resolvePath(backend)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
 
 	// Build the timber logger
 	t := &timber.Logger{
@@ -72,26 +129,124 @@ func (l *logger) create() error {
 		t.MaxSize = 0
 	}
 
-	l.logFile = t
-
-	// Write a startup marker
+	// Write a startup marker; timber opens the file on the first write
 	ts := time.Now().Format("2006-01-02 15:04:05")
-	fmt.Fprintf(t, "\n=== Instance %s started at %s ===\n", l.name, ts)
+	if _, err := fmt.Fprintf(t, "\n=== Instance %s started at %s ===\n", l.name, ts); err != nil {
+		return "", nil, fmt.Errorf("failed to open log file: %w", err)
+	}
 
-	return nil
+	return logPath, t, nil
 }
 
 func (l *logger) readOutput(rc io.ReadCloser) {
 	defer rc.Close()
-	scanner := bufio.NewScanner(rc)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if lg := l.logFile; lg != nil {
-			fmt.Fprintln(lg, line)
+
+	l.mu.RLock()
+	stdout := l.stdout
+	l.mu.RUnlock()
+
+	reader := bufio.NewReaderSize(rc, readBufferSize)
+	for {
+		raw, dropped, err := readLine(reader, l.settings.MaxLineLength)
+		if len(raw) > 0 || dropped > 0 || err == nil {
+			if dropped > 0 {
+				// Don't leave half a multi-byte character at the cut
+				kept := trimPartialRune(raw)
+				dropped += len(raw) - len(kept)
+				raw = kept
+			}
+			line := sanitizeLine(raw)
+			if dropped > 0 {
+				line += fmt.Sprintf("... [truncated %d bytes]", dropped)
+			}
+
+			if lg := l.logFile; lg != nil {
+				fmt.Fprintln(lg, line)
+			}
+			if stdout != nil {
+				stdoutMu.Lock()
+				fmt.Fprintf(stdout, "[%s] %s\n", l.name, line)
+				stdoutMu.Unlock()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readLine reads one line without its line ending. At most maxLen bytes are
+// kept (no limit if maxLen <= 0); the remainder of a longer line is consumed
+// and only counted, so a runaway line never has to fit in memory.
+func readLine(r *bufio.Reader, maxLen int) (line []byte, dropped int, err error) {
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return line, dropped, err
+		}
+
+		keep := len(chunk)
+		if maxLen > 0 {
+			keep = min(keep, max(maxLen-len(line), 0))
+		}
+		line = append(line, chunk[:keep]...)
+		dropped += len(chunk) - keep
+
+		if !isPrefix {
+			return line, dropped, nil
 		}
 	}
 }
 
+// trimPartialRune drops an incomplete UTF-8 sequence from the end of b
+func trimPartialRune(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			return b
+		}
+	}
+	return b
+}
+
+// sanitizeLine returns line as text with every run of non-printable bytes,
+// including invalid UTF-8, replaced by a single placeholder. Tabs are kept.
+func sanitizeLine(line []byte) string {
+	var sb strings.Builder
+	inBinary := false
+	for len(line) > 0 {
+		r, size := utf8.DecodeRune(line)
+		printable := (r != utf8.RuneError || size > 1) && (r == '\t' || unicode.IsGraphic(r))
+		if printable {
+			sb.WriteRune(r)
+			inBinary = false
+		} else if !inBinary {
+			sb.WriteString(binaryPlaceholder)
+			inBinary = true
+		}
+		line = line[size:]
+	}
+	return sb.String()
+}
+
+// writeLine writes a line generated by llamactl, rather than the backend, to
+// the log file and, when mirroring, to stdout
+func (l *logger) writeLine(line string) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.logFile != nil {
+		fmt.Fprintln(l.logFile, line)
+	}
+	if l.stdout != nil {
+		stdoutMu.Lock()
+		fmt.Fprintf(l.stdout, "[%s] %s\n", l.name, line)
+		stdoutMu.Unlock()
+	}
+}
+
 func (l *logger) close() {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -108,16 +263,22 @@ func (l *logger) close() {
 	l.logFile = nil
 }
 
-// getLogs retrieves the last n lines of logs from the instance
-func (l *logger) getLogs(num_lines int) (string, error) {
+// getLogs retrieves the last n lines of logs from the instance. Before the
+// instance has been started by this llamactl process, the file is located by
+// resolving the template, so logs from earlier runs remain readable.
+func (l *logger) getLogs(num_lines int, backend string) (string, error) {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	if l.logFilePath == "" {
-		return "", fmt.Errorf("log file not created for instance %s", l.name)
+	logPath := l.logFilePath
+	if logPath == "" {
+		var err error
+		if logPath, err = l.resolvePath(backend); err != nil {
+			return "", fmt.Errorf("log file not created for instance %s: %w", l.name, err)
+		}
 	}
 
-	file, err := os.Open(l.logFilePath)
+	file, err := os.Open(logPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open log file: %w", err)
 	}
@@ -0,0 +1,149 @@
+package instance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"llamactl/pkg/backends"
+	"net/http"
+	"time"
+)
+
+// ErrMetadataNotSupported is returned for backends without a metadata source
+var ErrMetadataNotSupported = errors.New("metadata is not supported for this backend")
+
+const metadataTimeout = 10 * time.Second
+
+// Metadata is model information reported by a running backend, normalized
+// across backend types. Fields a backend doesn't report are omitted.
+type Metadata struct {
+	BackendType backends.BackendType `json:"backend_type"`
+	// Model name or path as reported by the backend
+	Model string `json:"model,omitempty"`
+	// Maximum context length the model supports
+	ContextSize int `json:"context_size,omitempty"`
+	// Context length the backend was started with
+	NCtx int `json:"n_ctx,omitempty"`
+	// Number of parallel sequence slots (llama.cpp)
+	TotalSlots int `json:"total_slots,omitempty"`
+	// Jinja chat template used to format chat requests (llama.cpp)
+	ChatTemplate string `json:"chat_template,omitempty"`
+	// Input modalities supported by the loaded model, e.g. vision and audio (llama.cpp)
+	Modalities map[string]bool `json:"modalities,omitempty"`
+	// Backend build information (llama.cpp)
+	BuildInfo string `json:"build_info,omitempty"`
+}
+
+// llamaCppProps is the subset of llama-server's /props response we use
+type llamaCppProps struct {
+	DefaultGenerationSettings struct {
+		NCtx int `json:"n_ctx"`
+	} `json:"default_generation_settings"`
+	TotalSlots   int             `json:"total_slots"`
+	ModelPath    string          `json:"model_path"`
+	ChatTemplate string          `json:"chat_template"`
+	Modalities   map[string]bool `json:"modalities"`
+	BuildInfo    string          `json:"build_info"`
+}
+
+// openAIModels is an OpenAI-style /v1/models response with the backend
+// specific extensions of llama-server (meta) and vLLM (max_model_len)
+type openAIModels struct {
+	Data []struct {
+		ID          string `json:"id"`
+		MaxModelLen int    `json:"max_model_len"`
+		Meta        struct {
+			NCtxTrain int `json:"n_ctx_train"`
+		} `json:"meta"`
+	} `json:"data"`
+}
+
+// GetMetadata returns model metadata from the running backend. The result is
+// cached until the instance is started again.
+func (i *Instance) GetMetadata(ctx context.Context) (*Metadata, error) {
+	if !i.IsRunning() {
+		return nil, fmt.Errorf("instance %s is not running", i.Name)
+	}
+	if md := i.metadata.Load(); md != nil {
+		return md, nil
+	}
+
+	md, err := i.fetchMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i.metadata.Store(md)
+	return md, nil
+}
+
+func (i *Instance) fetchMetadata(ctx context.Context) (*Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	baseURL := i.BackendURL()
+	md := &Metadata{BackendType: i.GetBackendType()}
+
+	switch md.BackendType {
+	case backends.BackendTypeLlamaCpp:
+		var props llamaCppProps
+		if err := getBackendJSON(ctx, baseURL+"/props", &props); err != nil {
+			return nil, err
+		}
+		md.Model = props.ModelPath
+		md.NCtx = props.DefaultGenerationSettings.NCtx
+		md.TotalSlots = props.TotalSlots
+		md.ChatTemplate = props.ChatTemplate
+		md.Modalities = props.Modalities
+		md.BuildInfo = props.BuildInfo
+
+		// The training context length is only exposed through /v1/models;
+		// older llama-server builds lack it, so it is best effort
+		var models openAIModels
+		if err := getBackendJSON(ctx, baseURL+"/v1/models", &models); err == nil && len(models.Data) > 0 {
+			md.ContextSize = models.Data[0].Meta.NCtxTrain
+		}
+
+	case backends.BackendTypeVllm:
+		var models openAIModels
+		if err := getBackendJSON(ctx, baseURL+"/v1/models", &models); err != nil {
+			return nil, err
+		}
+		if len(models.Data) > 0 {
+			// max_model_len is both the model limit and what vLLM serves
+			md.Model = models.Data[0].ID
+			md.ContextSize = models.Data[0].MaxModelLen
+			md.NCtx = models.Data[0].MaxModelLen
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrMetadataNotSupported, md.BackendType)
+	}
+
+	return md, nil
+}
+
+// getBackendJSON fetches url from the backend and decodes the JSON response
+func getBackendJSON(ctx context.Context, url string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to query backend: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("backend returned status %d for %s: %s", resp.StatusCode, req.URL.Path, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode backend response from %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
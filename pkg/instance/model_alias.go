@@ -0,0 +1,69 @@
+package instance
+
+import (
+	"fmt"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/validation"
+)
+
+// ResolveModelAliases replaces a model given as an alias from the
+// model_aliases library with the Hugging Face reference it stands for. The
+// options are rewritten in place, so later edits to the library don't move
+// existing instances to a different model.
+func (c *Options) ResolveModelAliases(aliases map[string]string) error {
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	switch c.BackendOptions.BackendType {
+	case backends.BackendTypeLlamaCpp:
+		if lo := c.BackendOptions.LlamaServerOptions; lo != nil {
+			if target, ok := aliases[lo.Model]; ok {
+				if lo.HFRepo != "" {
+					return validation.ValidationError(fmt.Errorf("model alias %q cannot be combined with hf_repo", lo.Model))
+				}
+				lo.Model, lo.HFRepo = "", target
+			}
+		}
+		for i, m := range c.RouterModels {
+			if target, ok := aliases[m.Model]; ok && m.HFRepo == "" {
+				c.RouterModels[i].Model, c.RouterModels[i].HFRepo = "", target
+			}
+		}
+	case backends.BackendTypeVllm:
+		if vo := c.BackendOptions.VllmServerOptions; vo != nil {
+			repo, err := repoOnlyAlias(aliases, vo.Model)
+			if err != nil {
+				return err
+			}
+			vo.Model = repo
+		}
+	case backends.BackendTypeMlxLm:
+		if mo := c.BackendOptions.MlxServerOptions; mo != nil {
+			repo, err := repoOnlyAlias(aliases, mo.Model)
+			if err != nil {
+				return err
+			}
+			mo.Model = repo
+		}
+	}
+
+	return nil
+}
+
+// repoOnlyAlias resolves an alias for backends that take a bare repository
+// as the model and have no notion of a quantization tag
+func repoOnlyAlias(aliases map[string]string, model string) (string, error) {
+	target, ok := aliases[model]
+	if !ok {
+		return model, nil
+	}
+	ref, err := validation.ParseHFRef(target)
+	if err != nil {
+		return "", validation.ValidationError(fmt.Errorf("model alias %q: %w", model, err))
+	}
+	if ref.Tag != "" {
+		return "", validation.ValidationError(fmt.Errorf("model alias %q points to %s, but tags are only supported by the %s backend", model, target, backends.BackendTypeLlamaCpp))
+	}
+	return ref.Repo, nil
+}
@@ -0,0 +1,100 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"llamactl/pkg/backends"
+	"net/http"
+	"strings"
+)
+
+// openAPIOperation describes one endpoint in a synthesized OpenAPI spec
+type openAPIOperation struct {
+	method  string
+	path    string
+	summary string
+}
+
+// Endpoints served by backends that don't publish their own OpenAPI document
+var synthesizedOperations = map[backends.BackendType][]openAPIOperation{
+	backends.BackendTypeLlamaCpp: {
+		{http.MethodGet, "/health", "Health check"},
+		{http.MethodGet, "/props", "Server properties"},
+		{http.MethodGet, "/v1/models", "List models"},
+		{http.MethodPost, "/v1/completions", "Create completion"},
+		{http.MethodPost, "/v1/chat/completions", "Create chat completion"},
+		{http.MethodPost, "/v1/embeddings", "Create embeddings"},
+		{http.MethodPost, "/v1/rerank", "Rerank documents"},
+		{http.MethodPost, "/tokenize", "Tokenize text"},
+		{http.MethodPost, "/detokenize", "Detokenize tokens"},
+	},
+	backends.BackendTypeMlxLm: {
+		{http.MethodGet, "/health", "Health check"},
+		{http.MethodGet, "/v1/models", "List models"},
+		{http.MethodPost, "/v1/completions", "Create completion"},
+		{http.MethodPost, "/v1/chat/completions", "Create chat completion"},
+	},
+}
+
+// GetOpenAPISpec returns the OpenAPI document of the running backend. vLLM
+// serves its own at /openapi.json; for other backends a minimal spec listing
+// their OpenAI-compatible endpoints is synthesized.
+func (i *Instance) GetOpenAPISpec(ctx context.Context) (map[string]any, error) {
+	if !i.IsRunning() {
+		return nil, fmt.Errorf("instance %s is not running", i.Name)
+	}
+
+	backendType := i.GetBackendType()
+	if backendType == backends.BackendTypeVllm {
+		ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+		defer cancel()
+
+		var spec map[string]any
+		if err := getBackendJSON(ctx, i.BackendURL()+"/openapi.json", &spec); err != nil {
+			return nil, err
+		}
+		return spec, nil
+	}
+
+	return synthesizeOpenAPISpec(i.Name, backendType, synthesizedOperations[backendType]), nil
+}
+
+func synthesizeOpenAPISpec(name string, backendType backends.BackendType, operations []openAPIOperation) map[string]any {
+	paths := map[string]any{}
+	for _, op := range operations {
+		operation := map[string]any{
+			"summary": op.summary,
+			"responses": map[string]any{
+				"200": map[string]any{"description": "Successful response"},
+			},
+		}
+		if op.method == http.MethodPost {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": map[string]any{"type": "object"},
+					},
+				},
+			}
+		}
+
+		item, _ := paths[op.path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[op.path] = item
+		}
+		item[strings.ToLower(op.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":                  fmt.Sprintf("%s (%s)", name, backendType),
+			"version":                "unknown",
+			"description":            "Minimal specification generated by llamactl; the backend does not publish an OpenAPI document.",
+			"x-llamactl-synthesized": true,
+		},
+		"paths": paths,
+	}
+}
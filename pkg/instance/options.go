@@ -18,21 +18,72 @@ type Options struct {
 	AutoRestart  *bool `json:"auto_restart,omitempty"`
 	MaxRestarts  *int  `json:"max_restarts,omitempty"`
 	RestartDelay *int  `json:"restart_delay,omitempty"` // seconds
+	// Only restart after these exit codes; empty restarts after any failure
+	RestartOnExitCodes []int `json:"restart_on_exit_codes,omitempty"`
+	// Never restart after these exit codes, e.g. a backend's config error code
+	NoRestartOnExitCodes []int `json:"no_restart_on_exit_codes,omitempty"`
 	// On demand start
 	OnDemandStart *bool `json:"on_demand_start,omitempty"`
+	// Send a one-token completion once the instance is healthy after a start
+	WarmupOnStart *bool `json:"warmup_on_start,omitempty"`
 	// Idle timeout
 	IdleTimeout *int `json:"idle_timeout,omitempty"` // minutes
+	// Never stop automatically, neither on idle timeout nor to make room through eviction
+	Pinned *bool `json:"pinned,omitempty"`
+	// Maximum number of requests proxied to the backend at once (0 = unlimited)
+	MaxConcurrentRequests *int `json:"max_concurrent_requests,omitempty"`
+	// How long a request waits for a free slot before giving up (0 = reject immediately)
+	QueueTimeout *int `json:"queue_timeout,omitempty"` // seconds
+	// Abort proxied requests that run longer than this; nil follows proxy.stream_timeout, 0 = no limit
+	MaxRequestDuration *int `json:"max_request_duration,omitempty"` // seconds
+	// Gzip responses for clients that accept it, unless the backend already encoded them
+	CompressResponses *bool `json:"compress_responses,omitempty"`
+	// Declared GPU memory usage in MiB, checked against the node's VRAM budget on start
+	VRAMMB *int `json:"vram_mb,omitempty"`
+	// Order for auto-starting on boot; higher priorities start first
+	StartPriority *int `json:"start_priority,omitempty"`
+	// Mirror backend output to llamactl's stdout; nil follows logging.mirror_to_stdout
+	MirrorLogsToStdout *bool `json:"mirror_logs_to_stdout,omitempty"`
+	// Write proxied request and response bodies to the instance log for debugging
+	DebugLogBodies *bool `json:"debug_log_bodies,omitempty"`
+	// How much llamactl logs about this instance: info, warn, error or off
+	LogLevel LogLevel `json:"log_level,omitempty"`
 	// Environment variables
 	Environment map[string]string `json:"environment,omitempty"`
 	// Preset configuration
 	PresetIni *string `json:"preset_ini,omitempty"`
+	// Models served by a llama.cpp router instance, written to preset.ini
+	RouterModels []RouterModel `json:"router_models,omitempty"`
+	// Name of a template in the chat_templates library, passed to llama.cpp as --chat-template-file
+	ChatTemplateRef string `json:"chat_template_ref,omitempty"`
+
+	// CPUs the backend process may run on, as a cpuset list like "0-3,8" (Linux only)
+	CPUAffinity string `json:"cpu_affinity,omitempty"`
+	// Scheduling priority of the backend process, from -20 to 19 (Linux only)
+	Nice *int `json:"nice,omitempty"`
 
 	// Execution context overrides
 	DockerEnabled   *bool  `json:"docker_enabled,omitempty"`
 	CommandOverride string `json:"command_override,omitempty"`
+	// Directory the backend runs in; a path inside the container for Docker
+	WorkingDir string `json:"working_dir,omitempty"`
+	// Written to the backend's stdin when it starts, for launchers that read
+	// their config from stdin
+	StdinData string `json:"stdin_data,omitempty"`
 
 	// Instance group for hierarchical eviction
 	Group string `json:"group,omitempty"`
+	// Instances that must be healthy before this one starts
+	DependsOn []string `json:"depends_on,omitempty"`
+	// llama.cpp instance whose model is used as the draft model for speculative decoding
+	DraftInstance string `json:"draft_instance,omitempty"`
+	// Cron expressions for starting and stopping the instance automatically
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	// Free-form notes and key/value labels for operators; llamactl only
+	// uses labels to filter the instance list
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
 
 	// Assigned nodes
 	Nodes map[string]struct{} `json:"-"`
@@ -221,6 +272,26 @@ func (c *Options) validateAndApplyDefaults(name string, globalSettings *config.I
 		*c.IdleTimeout = 0
 	}
 
+	if c.MaxConcurrentRequests != nil && *c.MaxConcurrentRequests < 0 {
+		log.Printf("Instance %s MaxConcurrentRequests value (%d) cannot be negative, setting to 0 (unlimited)", name, *c.MaxConcurrentRequests)
+		*c.MaxConcurrentRequests = 0
+	}
+
+	if c.VRAMMB != nil && *c.VRAMMB < 0 {
+		log.Printf("Instance %s VRAMMB value (%d) cannot be negative, setting to 0", name, *c.VRAMMB)
+		*c.VRAMMB = 0
+	}
+
+	if c.QueueTimeout != nil && *c.QueueTimeout < 0 {
+		log.Printf("Instance %s QueueTimeout value (%d) cannot be negative, setting to 0 seconds", name, *c.QueueTimeout)
+		*c.QueueTimeout = 0
+	}
+
+	if c.MaxRequestDuration != nil && *c.MaxRequestDuration < 0 {
+		log.Printf("Instance %s MaxRequestDuration value (%d) cannot be negative, using proxy.stream_timeout", name, *c.MaxRequestDuration)
+		c.MaxRequestDuration = nil
+	}
+
 	// Validate docker_enabled and command_override relationship
 	if c.DockerEnabled != nil && *c.DockerEnabled && c.CommandOverride != "" {
 		log.Printf("Instance %s: command_override cannot be set when docker_enabled is true, ignoring command_override", name)
@@ -235,11 +306,26 @@ func (c *Options) validateAndApplyDefaults(name string, globalSettings *config.I
 		}
 	}
 
+	// RPC workers never see requests through llamactl, so an idle timeout
+	// would stop them while the llama-server using them is busy
+	if c.BackendOptions.BackendType == backends.BackendTypeLlamaRpc {
+		if c.IdleTimeout != nil && *c.IdleTimeout > 0 {
+			log.Printf("Instance %s: idle_timeout is not supported for llama.cpp RPC workers, ignoring", name)
+		}
+		zero := 0
+		c.IdleTimeout = &zero
+	}
+
 	if _, err := validation.ValidateInstanceName(c.Group); err != nil && c.Group != "" {
 		log.Printf("Instance %s: invalid group name: %v, clearing value", name, err)
 		c.Group = ""
 	}
 
+	if !validLogLevel(c.LogLevel) {
+		log.Printf("Instance %s: invalid log_level %q, using %q", name, c.LogLevel, LogLevelInfo)
+		c.LogLevel = ""
+	}
+
 	// Apply defaults from global settings for nil fields
 	if globalSettings != nil {
 		if c.AutoRestart == nil {
@@ -257,5 +343,32 @@ func (c *Options) validateAndApplyDefaults(name string, globalSettings *config.I
 		if c.IdleTimeout == nil {
 			c.IdleTimeout = &globalSettings.DefaultIdleTimeout
 		}
+
+		// Without ctx_size llama-server uses the model's training context,
+		// which can be far larger than the memory available for the KV cache
+		if llama := c.BackendOptions.LlamaServerOptions; llama != nil &&
+			c.BackendOptions.BackendType == backends.BackendTypeLlamaCpp &&
+			llama.CtxSize == 0 && globalSettings.DefaultCtxSize > 0 {
+			llama.CtxSize = globalSettings.DefaultCtxSize
+		}
 	}
 }
+
+// warnings returns problems with the options that are worth reporting but
+// don't prevent the instance from being created
+func (c *Options) warnings(globalSettings *config.InstancesConfig) []string {
+	var warnings []string
+	if globalSettings == nil {
+		return warnings
+	}
+
+	if llama := c.BackendOptions.LlamaServerOptions; llama != nil &&
+		c.BackendOptions.BackendType == backends.BackendTypeLlamaCpp &&
+		globalSettings.MaxCtxSize > 0 && llama.CtxSize > globalSettings.MaxCtxSize {
+		warnings = append(warnings, fmt.Sprintf(
+			"ctx_size %d exceeds the configured maximum of %d and may run out of memory",
+			llama.CtxSize, globalSettings.MaxCtxSize))
+	}
+
+	return warnings
+}
@@ -0,0 +1,100 @@
+package instance
+
+import (
+	"llamactl/pkg/backends"
+	"maps"
+	"reflect"
+	"strings"
+)
+
+// DiffOptions lists the options that differ between old and new by their
+// JSON names, in field order. Backend options are prefixed with
+// "backend_options."; if the backend type changed, "backend_options" is
+// reported as a whole.
+func DiffOptions(old, new *Options) []string {
+	if old == nil {
+		old = &Options{}
+	}
+	if new == nil {
+		new = &Options{}
+	}
+
+	var changes []string
+	changes = diffFields(changes, "", reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem())
+
+	if !maps.Equal(old.Nodes, new.Nodes) {
+		changes = append(changes, "nodes")
+	}
+
+	if old.BackendOptions.BackendType != new.BackendOptions.BackendType {
+		return append(changes, "backend_type", "backend_options")
+	}
+	oldBackend := backendOptionsValue(old.BackendOptions)
+	newBackend := backendOptionsValue(new.BackendOptions)
+	if oldBackend.IsValid() && newBackend.IsValid() {
+		changes = diffFields(changes, "backend_options.", oldBackend, newBackend)
+	}
+
+	return changes
+}
+
+// diffFields appends the JSON names of the exported fields that differ
+// between two values of the same struct type. Fields hidden from JSON are
+// skipped.
+func diffFields(changes []string, prefix string, a, b reflect.Value) []string {
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if !equalValues(a.Field(i), b.Field(i)) {
+			changes = append(changes, prefix+name)
+		}
+	}
+	return changes
+}
+
+// equalValues is reflect.DeepEqual, except that nil and empty maps or slices
+// are equal, as they are once marshaled
+func equalValues(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Map, reflect.Slice:
+		if a.Len() == 0 && b.Len() == 0 {
+			return true
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// backendOptionsValue returns the typed options struct for the backend, or
+// its zero value when none is set
+func backendOptionsValue(o backends.Options) reflect.Value {
+	var ptr any
+	switch o.BackendType {
+	case backends.BackendTypeLlamaCpp:
+		ptr = o.LlamaServerOptions
+	case backends.BackendTypeMlxLm:
+		ptr = o.MlxServerOptions
+	case backends.BackendTypeVllm:
+		ptr = o.VllmServerOptions
+	case backends.BackendTypeLlamaRpc:
+		ptr = o.LlamaRpcServerOptions
+	default:
+		return reflect.Value{}
+	}
+
+	v := reflect.ValueOf(ptr)
+	if v.IsNil() {
+		return reflect.New(v.Type().Elem()).Elem()
+	}
+	return v.Elem()
+}
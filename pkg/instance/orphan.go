@@ -0,0 +1,125 @@
+package instance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pidFileName is written to the instance directory while the backend runs,
+// so a process left behind by an unclean llamactl exit can be found again
+const pidFileName = "process.pid"
+
+// pidFilePath returns where the running backend's PID is recorded, or ""
+// when no instances directory is configured
+func (i *Instance) pidFilePath() string {
+	if i.globalInstanceSettings == nil || i.globalInstanceSettings.InstancesDir == "" {
+		return ""
+	}
+	return filepath.Join(i.globalInstanceSettings.InstancesDir, i.Name, pidFileName)
+}
+
+// processInfo identifies a running process beyond its PID, which the OS
+// may reuse once the process is gone
+type processInfo struct {
+	Args    string // command line, starting with argv[0]
+	Started string // start time as reported by ps
+}
+
+// writePidFile records the PID, argv[0] and start time of a freshly started
+// backend. Together they let a later run tell the process apart from an
+// unrelated one that reused the PID.
+func (i *Instance) writePidFile(pid int, argv0 string) {
+	path := i.pidFilePath()
+	if path == "" {
+		return
+	}
+
+	info, alive := inspectProcess(pid)
+	if !alive {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		i.Logf(LogLevelWarn, "Warning: Failed to create directory for pid file of instance %s: %v", i.Name, err)
+		return
+	}
+	content := fmt.Sprintf("%d\n%s\n%s\n", pid, argv0, info.Started)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		i.Logf(LogLevelWarn, "Warning: Failed to write pid file for instance %s: %v", i.Name, err)
+	}
+}
+
+func (i *Instance) removePidFile() {
+	path := i.pidFilePath()
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		i.Logf(LogLevelWarn, "Warning: Failed to remove pid file for instance %s: %v", i.Name, err)
+	}
+}
+
+// DetectOrphan returns the PID of a backend process recorded by a previous
+// llamactl run that is still alive, or 0 if there is none. The process must
+// have the recorded argv[0] and start time; a pid file that doesn't match
+// the process now holding the PID, or whose process is gone, is removed.
+func (i *Instance) DetectOrphan() int {
+	path := i.pidFilePath()
+	if path == "" || i.IsRunning() {
+		return 0
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		i.Logf(LogLevelWarn, "Warning: Ignoring malformed pid file for instance %s", i.Name)
+		i.removePidFile()
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	argv0, started := strings.TrimSpace(lines[1]), strings.TrimSpace(lines[2])
+	if err != nil || pid <= 0 || argv0 == "" || started == "" {
+		i.Logf(LogLevelWarn, "Warning: Ignoring malformed pid file for instance %s", i.Name)
+		i.removePidFile()
+		return 0
+	}
+
+	info, alive := inspectProcess(pid)
+	sameCommand := info.Args == argv0 || strings.HasPrefix(info.Args, argv0+" ")
+	if !alive || !sameCommand || info.Started != started {
+		i.removePidFile()
+		return 0
+	}
+	return pid
+}
+
+// KillOrphan force kills the orphaned backend with the given PID, along with
+// its child processes, and waits briefly for it to exit so its port and GPU
+// memory are released before the instance is started again
+func (i *Instance) KillOrphan(pid int) error {
+	if err := killProcessGroup(pid); err != nil {
+		return fmt.Errorf("failed to kill orphaned process %d of instance %s: %w", pid, i.Name, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, alive := inspectProcess(pid); !alive {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("orphaned process %d of instance %s did not exit after being killed", pid, i.Name)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	i.removePidFile()
+	return nil
+}
@@ -0,0 +1,84 @@
+package instance
+
+import (
+	"fmt"
+	"llamactl/pkg/config"
+	"llamactl/pkg/validation"
+	"strconv"
+	"strings"
+)
+
+// ValidatePlacement checks cpu_affinity and nice. Both are applied to the
+// backend process by the OS, so they need Linux and a process llamactl
+// starts itself rather than a container.
+func (c *Options) ValidatePlacement(backendSettings *config.BackendConfig) error {
+	if c.CPUAffinity == "" && c.Nice == nil {
+		return nil
+	}
+
+	if !placementSupported {
+		return validation.ValidationError(fmt.Errorf("cpu_affinity and nice are only supported on Linux"))
+	}
+	if c.BackendOptions.IsDockerEnabled(backendSettings, c.DockerEnabled) {
+		return validation.ValidationError(fmt.Errorf("cpu_affinity and nice are not supported for Docker instances"))
+	}
+
+	if c.CPUAffinity != "" {
+		if _, err := parseCPUSet(c.CPUAffinity); err != nil {
+			return validation.ValidationError(fmt.Errorf("invalid cpu_affinity %q: %w", c.CPUAffinity, err))
+		}
+	}
+	if c.Nice != nil && (*c.Nice < -20 || *c.Nice > 19) {
+		return validation.ValidationError(fmt.Errorf("nice must be between -20 and 19, got %d", *c.Nice))
+	}
+
+	return nil
+}
+
+// maxCPUs bounds CPU numbers to the size of the kernel's default cpu_set_t,
+// which is all sched_setaffinity accepts through unix.CPUSet
+const maxCPUs = 1024
+
+// parseCPUSet parses a cpuset list such as "0-3,8,10-11" into CPU numbers
+func parseCPUSet(s string) ([]int, error) {
+	var cpus []int
+	for part := range strings.SplitSeq(s, ",") {
+		lo, hi, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("%q is not a CPU number or range", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, fmt.Errorf("%q is not a valid CPU range", part)
+			}
+		}
+		if last >= maxCPUs {
+			return nil, fmt.Errorf("CPU %d is out of range (must be below %d)", last, maxCPUs)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// startCmd starts the backend process pinned to its cpu_affinity and with
+// its niceness. Both are set before exec, so every thread and child the
+// backend creates inherits them.
+func (p *process) startCmd() error {
+	opts := p.instance.GetOptions()
+	if opts == nil || (opts.CPUAffinity == "" && opts.Nice == nil) {
+		return p.cmd.Start()
+	}
+
+	var cpus []int
+	if opts.CPUAffinity != "" {
+		var err error
+		if cpus, err = parseCPUSet(opts.CPUAffinity); err != nil {
+			return err
+		}
+	}
+	return startPlaced(p.cmd, cpus, opts.Nice)
+}
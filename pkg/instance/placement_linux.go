@@ -0,0 +1,45 @@
+//go:build linux
+
+package instance
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+
+	"golang.org/x/sys/unix"
+)
+
+const placementSupported = true
+
+// startPlaced starts cmd from a dedicated OS thread that carries the CPU
+// affinity and niceness, so the forked process inherits both before it
+// execs. The thread is never unlocked and so exits with its goroutine,
+// rather than returning to the scheduler with the changed settings.
+func startPlaced(cmd *exec.Cmd, cpus []int, nice *int) error {
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+
+		if len(cpus) > 0 {
+			var set unix.CPUSet
+			for _, cpu := range cpus {
+				set.Set(cpu)
+			}
+			if err := unix.SchedSetaffinity(0, &set); err != nil {
+				errCh <- fmt.Errorf("failed to set cpu affinity: %w", err)
+				return
+			}
+		}
+		if nice != nil {
+			// PRIO_PROCESS with who 0 applies to the calling thread on Linux
+			if err := unix.Setpriority(unix.PRIO_PROCESS, 0, *nice); err != nil {
+				errCh <- fmt.Errorf("failed to set nice value %d: %w", *nice, err)
+				return
+			}
+		}
+
+		errCh <- cmd.Start()
+	}()
+	return <-errCh
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package instance
+
+import (
+	"errors"
+	"os/exec"
+)
+
+const placementSupported = false
+
+// startPlaced is only implemented on Linux; ValidatePlacement rejects the
+// options elsewhere
+func startPlaced(cmd *exec.Cmd, cpus []int, nice *int) error {
+	return errors.New("cpu_affinity and nice are only supported on Linux")
+}
@@ -2,15 +2,19 @@ package instance
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"llamactl/pkg/backends"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"runtime"
+	"slices"
+	"strings"
 	"sync"
-	"syscall"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,6 +32,29 @@ type process struct {
 	restarts      int
 	restartCancel context.CancelFunc
 	monitorDone   chan struct{}
+
+	// Name of the Docker container started by cmd, which may come from a
+	// user-supplied --name in the docker args
+	containerName string
+
+	// Cancels an image pull in progress for a start. The pull runs before
+	// mu is taken, so it has its own lock.
+	pullCancelMu sync.Mutex
+	pullCancel   context.CancelFunc
+
+	// lastExit is read from status-change callbacks that run while mu is
+	// held, so it is kept outside of mu
+	lastExit atomic.Pointer[ExitInfo]
+
+	// Number of times the process has been started, including auto-restarts
+	starts atomic.Int64
+}
+
+// ExitInfo describes the most recent unexpected exit of an instance process
+type ExitInfo struct {
+	Error    string
+	ExitCode int
+	Time     time.Time
 }
 
 // newProcess creates a new process component for the given instance
@@ -39,6 +66,14 @@ func newProcess(instance *Instance) *process {
 
 // start starts the OS process and returns an error if it fails.
 func (p *process) start() error {
+	// Pulling can take a long time, so it happens before taking mu to keep
+	// stop and kill responsive; both cancel the pull
+	if p.instance.isDockerEnabled() {
+		if err := p.pullImageForStart(); err != nil {
+			return fmt.Errorf("failed to start instance %s: %w", p.instance.Name, err)
+		}
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -66,16 +101,30 @@ func (p *process) start() error {
 	p.ctx, p.cancel = context.WithCancel(context.Background())
 
 	// Create log files
-	if err := p.instance.logger.create(); err != nil {
+	if err := p.instance.logger.create(string(p.instance.GetBackendType()), p.instance.mirrorLogsToStdout()); err != nil {
 		return fmt.Errorf("failed to create log files: %w", err)
 	}
 
+	if err := p.instance.writeChatTemplateFile(); err != nil {
+		p.instance.logger.close()
+		return err
+	}
+
+	if err := p.instance.prepareWorkingDir(); err != nil {
+		p.instance.logger.close()
+		return err
+	}
+
 	// Build command using backend-specific methods
 	cmd, cmdErr := p.buildCommand()
 	if cmdErr != nil {
 		return fmt.Errorf("failed to build command: %w", cmdErr)
 	}
 	p.cmd = cmd
+	p.containerName = ""
+	if p.instance.isDockerEnabled() {
+		p.containerName = backends.DockerRunContainerName(cmd.Args[1:])
+	}
 
 	if runtime.GOOS != "windows" {
 		setProcAttrs(p.cmd)
@@ -94,10 +143,33 @@ func (p *process) start() error {
 		return fmt.Errorf("failed to get stderr pipe: %w", err)
 	}
 
-	if err := p.cmd.Start(); err != nil {
+	var stdin io.WriteCloser
+	stdinData := p.instance.stdinData()
+	if stdinData != "" {
+		stdin, err = p.cmd.StdinPipe()
+		if err != nil {
+			p.stdout.Close()
+			p.stderr.Close()
+			p.instance.logger.close()
+			return fmt.Errorf("failed to get stdin pipe: %w", err)
+		}
+	}
+
+	if p.instance.isDockerEnabled() {
+		p.removeStaleContainer()
+	}
+
+	if err := p.startCmd(); err != nil {
 		return fmt.Errorf("failed to start instance %s: %w", p.instance.Name, err)
 	}
 
+	if stdin != nil {
+		go p.feedStdin(stdin, stdinData)
+	}
+
+	p.instance.writePidFile(p.cmd.Process.Pid, p.cmd.Args[0])
+	p.instance.metadata.Store(nil)
+	p.starts.Add(1)
 	p.instance.SetStatus(Running)
 
 	// Create channel for monitor completion signaling
@@ -113,6 +185,7 @@ func (p *process) start() error {
 
 // stop terminates the subprocess without restarting
 func (p *process) stop() error {
+	p.cancelPull()
 	p.mu.Lock()
 
 	if !p.instance.IsRunning() {
@@ -120,7 +193,7 @@ func (p *process) stop() error {
 		if p.restartCancel != nil {
 			p.restartCancel()
 			p.restartCancel = nil
-			log.Printf("Cancelled pending restart for instance %s", p.instance.Name)
+			p.instance.Logf(LogLevelInfo, "Cancelled pending restart for instance %s", p.instance.Name)
 		}
 		p.mu.Unlock()
 		return fmt.Errorf("instance %s is not running", p.instance.Name)
@@ -137,11 +210,12 @@ func (p *process) stop() error {
 
 	// Get the monitor done channel before releasing the lock
 	monitorDone := p.monitorDone
+	containerName := p.containerName
 
 	p.mu.Unlock()
 
 	// Wait for inflight requests to complete (max 30 seconds)
-	log.Printf("Instance %s shutting down, waiting for inflight requests to complete...", p.instance.Name)
+	p.instance.Logf(LogLevelInfo, "Instance %s shutting down, waiting for inflight requests to complete...", p.instance.Name)
 	deadline := time.Now().Add(30 * time.Second)
 	for time.Now().Before(deadline) {
 		inflight := p.instance.GetInflightRequests()
@@ -154,10 +228,16 @@ func (p *process) stop() error {
 	// Now set status to stopped to signal intentional stop
 	p.instance.SetStatus(Stopped)
 
-	// Stop the process with SIGINT if cmd exists
+	// Stop the process group with the backend's stop signal if cmd exists.
+	// Dockerized instances are stopped through the daemon instead, since
+	// signalling the `docker run` client doesn't reliably stop the container;
+	// the stop signal stays as fallback.
 	if p.cmd != nil && p.cmd.Process != nil {
-		if err := p.cmd.Process.Signal(syscall.SIGINT); err != nil {
-			log.Printf("Failed to send SIGINT to instance %s: %v", p.instance.Name, err)
+		if !p.instance.isDockerEnabled() || !p.stopContainer(containerName) {
+			sig := p.instance.getStopSignal()
+			if err := signalProcessGroup(p.cmd, sig); err != nil {
+				p.instance.Logf(LogLevelError, "Failed to send %v to instance %s: %v", sig, p.instance.Name, err)
+			}
 		}
 	}
 
@@ -170,22 +250,22 @@ func (p *process) stop() error {
 	select {
 	case <-monitorDone:
 		// Process exited normally
-		log.Printf("Instance %s shut down gracefully", p.instance.Name)
+		p.instance.Logf(LogLevelInfo, "Instance %s shut down gracefully", p.instance.Name)
 	case <-time.After(30 * time.Second):
 		// Force kill if it doesn't exit within 30 seconds
 		if p.cmd != nil && p.cmd.Process != nil {
-			killErr := p.cmd.Process.Kill()
+			killErr := signalProcessGroup(p.cmd, os.Kill)
 			if killErr != nil {
-				log.Printf("Failed to force kill instance %s: %v", p.instance.Name, killErr)
+				p.instance.Logf(LogLevelError, "Failed to force kill instance %s: %v", p.instance.Name, killErr)
 			}
-			log.Printf("Instance %s did not stop in time, force killed", p.instance.Name)
+			p.instance.Logf(LogLevelWarn, "Instance %s did not stop in time, force killed", p.instance.Name)
 
 			// Wait a bit more for the monitor to finish after force kill
 			select {
 			case <-monitorDone:
 				// Monitor completed after force kill
 			case <-time.After(2 * time.Second):
-				log.Printf("Warning: Monitor goroutine did not complete after force kill for instance %s", p.instance.Name)
+				p.instance.Logf(LogLevelWarn, "Warning: Monitor goroutine did not complete after force kill for instance %s", p.instance.Name)
 			}
 		}
 	}
@@ -195,6 +275,31 @@ func (p *process) stop() error {
 	return nil
 }
 
+// kill force kills the process without waiting for inflight requests or a
+// graceful exit. It is a no-op if the process is not running.
+func (p *process) kill() error {
+	p.cancelPull()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.restartCancel != nil {
+		p.restartCancel()
+		p.restartCancel = nil
+	}
+
+	if p.cmd == nil || p.cmd.Process == nil || p.monitorDone == nil {
+		return nil
+	}
+
+	// Mark as stopped first so the monitor doesn't treat the exit as a crash
+	p.instance.SetStatus(Stopped)
+
+	if err := signalProcessGroup(p.cmd, os.Kill); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to kill instance %s: %w", p.instance.Name, err)
+	}
+	return nil
+}
+
 // restart manually restarts the process (resets restart counter)
 func (p *process) restart() error {
 	// Stop the process first
@@ -227,10 +332,7 @@ func (p *process) waitForHealthy(timeout int) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 	defer cancel()
 
-	// Get host/port from instance
-	host := p.instance.options.GetHost()
-	port := p.instance.options.GetPort()
-	healthURL := fmt.Sprintf("http://%s:%d/health", host, port)
+	healthURL := p.instance.BackendURL() + "/health"
 
 	// Create a dedicated HTTP client for health checks
 	client := &http.Client{
@@ -239,6 +341,17 @@ func (p *process) waitForHealthy(timeout int) error {
 
 	// Helper function to check health directly
 	checkHealth := func() bool {
+		// rpc-server has no HTTP endpoint; accepting connections is all it can report
+		if opts := p.instance.GetOptions(); opts != nil && !opts.BackendOptions.ServesHTTP() {
+			address := strings.TrimPrefix(p.instance.BackendURL(), "http://")
+			conn, err := (&net.Dialer{Timeout: 5 * time.Second}).DialContext(ctx, "tcp", address)
+			if err != nil {
+				return false
+			}
+			conn.Close()
+			return true
+		}
+
 		req, err := http.NewRequestWithContext(ctx, "GET", healthURL, nil)
 		if err != nil {
 			return false
@@ -287,6 +400,7 @@ func (p *process) monitorProcess() {
 	}()
 
 	err := p.cmd.Wait()
+	p.instance.removePidFile()
 
 	p.mu.Lock()
 
@@ -307,36 +421,52 @@ func (p *process) monitorProcess() {
 
 	// Log the exit
 	if err != nil {
-		log.Printf("Instance %s crashed with error: %v", p.instance.Name, err)
+		p.instance.Logf(LogLevelError, "Instance %s crashed with error: %v", p.instance.Name, err)
+		p.lastExit.Store(&ExitInfo{
+			Error:    err.Error(),
+			ExitCode: p.cmd.ProcessState.ExitCode(),
+			Time:     time.Now(),
+		})
 		// Handle auto-restart logic
-		p.handleAutoRestart(err)
+		p.handleAutoRestart(p.cmd.ProcessState.ExitCode())
 	} else {
-		log.Printf("Instance %s exited cleanly", p.instance.Name)
+		p.instance.Logf(LogLevelInfo, "Instance %s exited cleanly", p.instance.Name)
 		p.mu.Unlock()
 	}
 }
 
-// shouldAutoRestart checks if the process should auto-restart
-func (p *process) shouldAutoRestart() bool {
+// shouldAutoRestart checks if the process should auto-restart after exiting
+// with exitCode (-1 when it was killed by a signal)
+func (p *process) shouldAutoRestart(exitCode int) bool {
 	opts := p.instance.GetOptions()
 	if opts == nil {
-		log.Printf("Instance %s not restarting: options are nil", p.instance.Name)
+		p.instance.Logf(LogLevelInfo, "Instance %s not restarting: options are nil", p.instance.Name)
 		return false
 	}
 
 	if opts.AutoRestart == nil || !*opts.AutoRestart {
-		log.Printf("Instance %s not restarting: AutoRestart is disabled", p.instance.Name)
+		p.instance.Logf(LogLevelInfo, "Instance %s not restarting: AutoRestart is disabled", p.instance.Name)
 		return false
 	}
 
 	if opts.MaxRestarts == nil {
-		log.Printf("Instance %s not restarting: MaxRestarts is nil", p.instance.Name)
+		p.instance.Logf(LogLevelInfo, "Instance %s not restarting: MaxRestarts is nil", p.instance.Name)
+		return false
+	}
+
+	if slices.Contains(opts.NoRestartOnExitCodes, exitCode) {
+		p.instance.Logf(LogLevelInfo, "Instance %s not restarting: exit code %d is in no_restart_on_exit_codes", p.instance.Name, exitCode)
+		return false
+	}
+
+	if len(opts.RestartOnExitCodes) > 0 && !slices.Contains(opts.RestartOnExitCodes, exitCode) {
+		p.instance.Logf(LogLevelInfo, "Instance %s not restarting: exit code %d is not in restart_on_exit_codes", p.instance.Name, exitCode)
 		return false
 	}
 
 	maxRestarts := *opts.MaxRestarts
 	if p.restarts >= maxRestarts {
-		log.Printf("Instance %s exceeded max restart attempts (%d)", p.instance.Name, maxRestarts)
+		p.instance.Logf(LogLevelWarn, "Instance %s exceeded max restart attempts (%d)", p.instance.Name, maxRestarts)
 		return false
 	}
 
@@ -344,9 +474,9 @@ func (p *process) shouldAutoRestart() bool {
 }
 
 // handleAutoRestart manages the auto-restart process
-func (p *process) handleAutoRestart(err error) {
+func (p *process) handleAutoRestart(exitCode int) {
 	// Check if should restart
-	if !p.shouldAutoRestart() {
+	if !p.shouldAutoRestart(exitCode) {
 		p.instance.SetStatus(Failed)
 		p.mu.Unlock()
 		return
@@ -355,22 +485,29 @@ func (p *process) handleAutoRestart(err error) {
 	// Get restart parameters
 	opts := p.instance.GetOptions()
 	if opts.RestartDelay == nil {
-		log.Printf("Instance %s not restarting: RestartDelay is nil", p.instance.Name)
+		p.instance.Logf(LogLevelInfo, "Instance %s not restarting: RestartDelay is nil", p.instance.Name)
 		p.instance.SetStatus(Failed)
 		p.mu.Unlock()
 		return
 	}
 
-	restartDelay := *opts.RestartDelay
+	restartDelay := time.Duration(*opts.RestartDelay) * time.Second
 	maxRestarts := *opts.MaxRestarts
 
+	if limiter := p.instance.restartLimiter; limiter != nil {
+		if wait := limiter.Reserve(); wait > restartDelay {
+			p.instance.Logf(LogLevelWarn, "Instance %s restart deferred by %v: restart budget exhausted", p.instance.Name, wait.Round(time.Second))
+			restartDelay = wait
+		}
+	}
+
 	p.restarts++
 
 	// Set status to Restarting instead of leaving as Stopped
 	p.instance.SetStatus(Restarting)
 
-	log.Printf("Auto-restarting instance %s (attempt %d/%d) in %v",
-		p.instance.Name, p.restarts, maxRestarts, time.Duration(restartDelay)*time.Second)
+	p.instance.Logf(LogLevelInfo, "Auto-restarting instance %s (attempt %d/%d) in %v",
+		p.instance.Name, p.restarts, maxRestarts, restartDelay)
 
 	// Create a cancellable context for the restart delay
 	restartCtx, cancel := context.WithCancel(context.Background())
@@ -381,19 +518,19 @@ func (p *process) handleAutoRestart(err error) {
 
 	// Use context-aware sleep so it can be cancelled
 	select {
-	case <-time.After(time.Duration(restartDelay) * time.Second):
+	case <-time.After(restartDelay):
 		// Sleep completed normally, continue with restart
 	case <-restartCtx.Done():
 		// Restart was cancelled
-		log.Printf("Restart cancelled for instance %s", p.instance.Name)
+		p.instance.Logf(LogLevelInfo, "Restart cancelled for instance %s", p.instance.Name)
 		return
 	}
 
 	// Restart the instance
 	if err := p.start(); err != nil {
-		log.Printf("Failed to restart instance %s: %v", p.instance.Name, err)
+		p.instance.Logf(LogLevelError, "Failed to restart instance %s: %v", p.instance.Name, err)
 	} else {
-		log.Printf("Successfully restarted instance %s", p.instance.Name)
+		p.instance.Logf(LogLevelInfo, "Successfully restarted instance %s", p.instance.Name)
 		// Clear the cancel function
 		p.mu.Lock()
 		p.restartCancel = nil
@@ -401,6 +538,50 @@ func (p *process) handleAutoRestart(err error) {
 	}
 }
 
+// removeStaleContainer removes a leftover container with the name the new
+// container is about to take, e.g. one orphaned by a crash, which would
+// otherwise make `docker run` fail with a name conflict. getCommand resolves
+// to the container runtime binary.
+func (p *process) removeStaleContainer() {
+	name := p.containerName
+	if name == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, p.instance.getCommand(), "rm", "-f", name).CombinedOutput()
+	if err != nil {
+		// Docker says "No such container", podman "no such container"
+		if !strings.Contains(strings.ToLower(string(output)), "no such container") {
+			p.instance.Logf(LogLevelWarn, "Warning: failed to remove stale container %s: %v: %s", name, err, strings.TrimSpace(string(output)))
+		}
+		return
+	}
+	if len(strings.TrimSpace(string(output))) > 0 {
+		p.instance.Logf(LogLevelInfo, "Removed stale container %s for instance %s", name, p.instance.Name)
+	}
+}
+
+// stopContainer asks the container runtime to stop the named container and
+// reports whether it succeeded
+func (p *process) stopContainer(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, p.instance.getCommand(), "stop", name).CombinedOutput()
+	if err != nil {
+		p.instance.Logf(LogLevelError, "Failed to stop container %s for instance %s: %v: %s", name, p.instance.Name, err, strings.TrimSpace(string(output)))
+		return false
+	}
+	return true
+}
+
 // buildCommand builds the command to execute using backend-specific logic
 func (p *process) buildCommand() (*exec.Cmd, error) {
 
@@ -415,6 +596,7 @@ func (p *process) buildCommand() (*exec.Cmd, error) {
 
 	// Create the exec.Cmd
 	cmd := exec.CommandContext(p.ctx, command, args...)
+	cmd.Dir = p.instance.workingDir()
 
 	// Start with host environment variables
 	cmd.Env = os.Environ()
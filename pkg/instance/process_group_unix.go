@@ -3,7 +3,11 @@
 package instance
 
 import (
+	"errors"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 	"syscall"
 )
 
@@ -13,3 +17,50 @@ func setProcAttrs(cmd *exec.Cmd) {
 	}
 	cmd.SysProcAttr.Setpgid = true
 }
+
+// signalProcessGroup sends sig to the whole process group of cmd, so
+// children forked by the backend (vLLM workers, python wrappers) receive it
+// too instead of being orphaned while holding GPU memory. setProcAttrs makes
+// the process a group leader, so the group ID is its PID; using it directly
+// also reaches children after the leader itself has been reaped.
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok || cmd.SysProcAttr == nil || !cmd.SysProcAttr.Setpgid {
+		return cmd.Process.Signal(sig)
+	}
+
+	if err := syscall.Kill(-cmd.Process.Pid, s); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return os.ErrProcessDone
+		}
+		return err
+	}
+	return nil
+}
+
+// inspectProcess describes pid and reports whether it is still running.
+// Zombies count as not running since they hold no resources.
+func inspectProcess(pid int) (processInfo, bool) {
+	out, err := exec.Command("ps", "-o", "stat=", "-o", "lstart=", "-o", "args=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return processInfo{}, false
+	}
+
+	// lstart is always five fields, e.g. "Thu Oct 15 20:38:02 2026"
+	fields := strings.Fields(string(out))
+	if len(fields) < 7 || strings.HasPrefix(fields[0], "Z") {
+		return processInfo{}, false
+	}
+	return processInfo{
+		Started: strings.Join(fields[1:6], " "),
+		Args:    strings.Join(fields[6:], " "),
+	}, true
+}
+
+// killProcessGroup force kills the process group led by pid
+func killProcessGroup(pid int) error {
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return nil
+}
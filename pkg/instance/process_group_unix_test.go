@@ -0,0 +1,147 @@
+//go:build !windows
+
+package instance_test
+
+import (
+	"fmt"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
+	"llamactl/pkg/instance"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// processAlive reports whether pid refers to a live, non-zombie process
+func processAlive(pid int) bool {
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		// The state follows the parenthesised command name
+		stat := string(data)
+		if i := strings.LastIndex(stat, ")"); i >= 0 && i+2 < len(stat) {
+			return stat[i+2] != 'Z'
+		}
+	}
+	return syscall.Kill(pid, 0) == nil
+}
+
+func TestStop_SignalsProcessGroup(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+
+	// The shell forks a child and waits on it, like a python wrapper around
+	// worker processes. Signalling only the shell would orphan the child.
+	globalConfig := &config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{
+				Command:    "sh",
+				Args:       []string{"-c", fmt.Sprintf("sleep 30 & echo $! > %q; wait", pidFile)},
+				StopSignal: config.StopSignalSIGTERM,
+			},
+		},
+		Instances: config.InstancesConfig{LogsDir: t.TempDir()},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("test", globalConfig, &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+			},
+		},
+	}, nil)
+
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	var childPid int
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, err := os.ReadFile(pidFile); err == nil {
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+				childPid = pid
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("child process never started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Cleanup(func() { syscall.Kill(childPid, syscall.SIGKILL) })
+
+	if err := inst.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+
+	deadline = time.Now().Add(5 * time.Second)
+	for processAlive(childPid) {
+		if time.Now().After(deadline) {
+			t.Fatalf("child process %d survived Stop", childPid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDetectOrphan_IgnoresReusedPID(t *testing.T) {
+	// An unrelated process now holds the recorded PID
+	other := exec.Command("sleep", "30")
+	if err := other.Start(); err != nil {
+		t.Fatalf("failed to start process: %v", err)
+	}
+	t.Cleanup(func() {
+		other.Process.Kill()
+		other.Wait()
+	})
+	pid := other.Process.Pid
+
+	instancesDir := t.TempDir()
+	globalConfig := &config.AppConfig{
+		Instances: config.InstancesConfig{LogsDir: t.TempDir(), InstancesDir: instancesDir},
+		Nodes:     map[string]config.NodeConfig{},
+		LocalNode: "main",
+	}
+	inst := instance.New("test", globalConfig, &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+		},
+	}, nil)
+
+	pidPath := filepath.Join(instancesDir, "test", "process.pid")
+	if err := os.MkdirAll(filepath.Dir(pidPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		// "sleep" contains "sl" but is not the recorded command
+		{"different command", fmt.Sprintf("%d\nsl\nThu Jan  1 00:00:00 2026\n", pid)},
+		{"different start time", fmt.Sprintf("%d\nsleep\nThu Jan  1 00:00:00 2026\n", pid)},
+		{"no start time", fmt.Sprintf("%d\nsleep\n", pid)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(pidPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got := inst.DetectOrphan(); got != 0 {
+				t.Errorf("DetectOrphan() = %d, want 0", got)
+			}
+			if _, err := os.Stat(pidPath); !os.IsNotExist(err) {
+				t.Error("expected the mismatched pid file to be removed")
+			}
+		})
+	}
+
+	if !processAlive(pid) {
+		t.Error("unrelated process should be left alone")
+	}
+}
@@ -2,8 +2,32 @@
 
 package instance
 
-import "os/exec"
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
 
 func setProcAttrs(cmd *exec.Cmd) {
 	// No-op on Windows
 }
+
+// signalProcessGroup signals only the process itself. Windows has no POSIX
+// process groups, so children spawned by the backend are not reached; doing
+// that would require assigning the process to a job object.
+func signalProcessGroup(cmd *exec.Cmd, sig os.Signal) error {
+	if sig == os.Kill {
+		return cmd.Process.Kill()
+	}
+	return cmd.Process.Signal(sig)
+}
+
+// inspectProcess is not implemented on Windows, so orphaned backend
+// processes are never detected there
+func inspectProcess(pid int) (processInfo, bool) {
+	return processInfo{}, false
+}
+
+func killProcessGroup(pid int) error {
+	return errors.New("killing orphaned processes is not supported on Windows")
+}
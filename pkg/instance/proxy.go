@@ -1,7 +1,10 @@
 package instance
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -37,9 +40,18 @@ type proxy struct {
 	proxyOnce sync.Once
 	proxyErr  error
 
+	// slots counts proxied requests against the concurrency limit. It is
+	// kept when the proxy is cleared, so requests still running when the
+	// options change count against the new limit.
+	slots *requestSlots
+
 	lastRequestTime  atomic.Int64
 	inflightRequests atomic.Int32
+	queuedRequests   atomic.Int32
 	timeProvider     TimeProvider
+
+	// Totals since llamactl started; they survive proxy rebuilds
+	counters requestCounters
 }
 
 // newProxy creates a new Proxy for the given instance
@@ -47,6 +59,7 @@ func newProxy(instance *Instance) (*proxy, error) {
 
 	p := &proxy{
 		instance:     instance,
+		slots:        newRequestSlots(),
 		timeProvider: realTimeProvider{},
 	}
 
@@ -82,13 +95,10 @@ func newProxy(instance *Instance) (*proxy, error) {
 
 		p.apiKey = node.APIKey
 	} else {
-		// Get host/port from process
-		host := p.instance.options.GetHost()
-		port := p.instance.options.GetPort()
-		if port == 0 {
+		if p.instance.options.GetPort() == 0 {
 			return nil, fmt.Errorf("instance %s has no port assigned", p.instance.Name)
 		}
-		p.targetURL, err = url.Parse(fmt.Sprintf("http://%s:%d", host, port))
+		p.targetURL, err = url.Parse(p.instance.BackendURL())
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse target URL for instance %s: %w", p.instance.Name, err)
 		}
@@ -104,6 +114,10 @@ func newProxy(instance *Instance) (*proxy, error) {
 // get returns the reverse proxy for this instance, creating it if needed.
 // Uses sync.Once to ensure thread-safe one-time initialization.
 func (p *proxy) get() (*httputil.ReverseProxy, error) {
+	// The read lock keeps clear() from resetting the Once mid-build
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	// sync.Once guarantees buildProxy() is called exactly once
 	// Other callers block until first initialization completes
 	p.proxyOnce.Do(func() {
@@ -117,6 +131,8 @@ func (p *proxy) get() (*httputil.ReverseProxy, error) {
 func (p *proxy) build() (*httputil.ReverseProxy, error) {
 
 	proxy := httputil.NewSingleHostReverseProxy(p.targetURL)
+	proxy.Transport = p.buildTransport()
+	proxy.ErrorHandler = p.handleProxyError
 
 	// Modify the request before sending it to the backend
 	originalDirector := proxy.Director
@@ -127,9 +143,6 @@ func (p *proxy) build() (*httputil.ReverseProxy, error) {
 		if p.instance.IsRemote() && p.apiKey != "" {
 			req.Header.Set("Authorization", "Bearer "+p.apiKey)
 		}
-
-		// Update last request time
-		p.updateLastRequestTime()
 	}
 
 	if !p.instance.IsRemote() {
@@ -154,31 +167,239 @@ func (p *proxy) build() (*httputil.ReverseProxy, error) {
 	return proxy, nil
 }
 
+// buildTransport creates the proxy's own backend transport from the global
+// proxy settings. Connecting is bounded separately from the response, so an
+// unreachable backend fails fast while a slow generation is left to finish.
+// Every request from this proxy goes to one host, so the idle pool is sized
+// per host; http.DefaultTransport keeps only two idle connections per host,
+// which makes busy instances open a new connection for most requests.
+func (p *proxy) buildTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	settings := p.instance.globalProxySettings
+	if settings == nil {
+		return transport
+	}
+
+	if settings.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: settings.ConnectTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+	}
+	transport.ResponseHeaderTimeout = settings.ResponseHeaderTimeout
+	if settings.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = settings.IdleConnTimeout
+	}
+	if settings.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = settings.MaxIdleConnsPerHost
+		transport.MaxIdleConns = max(transport.MaxIdleConns, settings.MaxIdleConnsPerHost)
+	}
+	transport.DisableKeepAlives = settings.DisableKeepAlives
+
+	return transport
+}
+
+// maxConcurrentRequests returns the instance's concurrency limit, 0 meaning
+// no limit
+func (p *proxy) maxConcurrentRequests() int {
+	options := p.instance.GetOptions()
+	if options == nil || options.MaxConcurrentRequests == nil || *options.MaxConcurrentRequests <= 0 {
+		return 0
+	}
+	return *options.MaxConcurrentRequests
+}
+
 // serveHTTP handles HTTP requests with inflight tracking
-func (p *proxy) serveHTTP(w http.ResponseWriter, r *http.Request) error {
+func (p *proxy) serveHTTP(w http.ResponseWriter, r *http.Request) (err error) {
+	// Count the request once it is done, queueing and streaming included
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w}
+	w = recorder
+	defer func() {
+		p.counters.record(recorder.status, err != nil, time.Since(start))
+	}()
+
+	if opts := p.instance.GetOptions(); opts != nil && !opts.BackendOptions.ServesHTTP() {
+		writeProxyError(w, http.StatusBadRequest, "no_http_api",
+			fmt.Sprintf("Instance %s is a llama.cpp RPC worker and has no HTTP API", p.instance.Name))
+		return nil
+	}
+
+	// Every handler proxies through here, so this is the one place that
+	// records access for idle timeout and LRU eviction. The time is stamped
+	// again when the request ends, so a long generation doesn't look idle.
+	p.updateLastRequestTime()
+	defer p.updateLastRequestTime()
+
 	// Get the reverse proxy
 	reverseProxy, err := p.get()
 	if err != nil {
 		return err
 	}
 
+	// Enforce the per-instance concurrency limit
+	if !p.acquireSlot(w, r) {
+		return nil
+	}
+	defer p.slots.release()
+
 	// Track inflight requests
 	p.incInflightRequests()
 	defer p.decInflightRequests()
 
+	// Bound the whole request, streamed body included. The reverse proxy
+	// sends the backend request with this context, so both the deadline and
+	// a client disconnect abort the upstream call.
+	if maxDuration := p.maxRequestDuration(); maxDuration > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), maxDuration)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	if p.shouldCompress(r) {
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		w = gw
+	}
+
 	// Serve the request
-	reverseProxy.ServeHTTP(w, r)
+	serve := reverseProxy.ServeHTTP
+	if p.shouldLogBodies() {
+		serve = func(w http.ResponseWriter, r *http.Request) {
+			p.serveWithBodyLog(reverseProxy, w, r)
+		}
+	}
+	if p.shouldRetry(r) {
+		p.serveWithRetry(serve, w, r)
+		return nil
+	}
+	serve(w, r)
 	return nil
 }
 
+// maxRequestDuration returns how long a proxied request may run, 0 meaning
+// no limit. The instance's max_request_duration overrides proxy.stream_timeout.
+func (p *proxy) maxRequestDuration() time.Duration {
+	if options := p.instance.GetOptions(); options != nil && options.MaxRequestDuration != nil {
+		return time.Duration(*options.MaxRequestDuration) * time.Second
+	}
+	if settings := p.instance.globalProxySettings; settings != nil {
+		return settings.StreamTimeout
+	}
+	return 0
+}
+
+// acquireSlot reserves a request slot, waiting up to the instance's queue timeout
+// when all slots are busy. On failure the error response has already been written.
+func (p *proxy) acquireSlot(w http.ResponseWriter, r *http.Request) bool {
+	limit := p.maxConcurrentRequests()
+	acquired, changed := p.slots.tryAcquire(limit)
+	if acquired {
+		return true
+	}
+
+	var queueTimeout time.Duration
+	if options := p.instance.GetOptions(); options != nil && options.QueueTimeout != nil {
+		queueTimeout = time.Duration(*options.QueueTimeout) * time.Second
+	}
+
+	if queueTimeout <= 0 {
+		writeProxyError(w, http.StatusTooManyRequests, "too_many_requests",
+			fmt.Sprintf("Instance %s is at its limit of %d concurrent requests", p.instance.Name, limit))
+		return false
+	}
+
+	p.queuedRequests.Add(1)
+	defer p.queuedRequests.Add(-1)
+
+	timer := time.NewTimer(queueTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-changed:
+			if acquired, changed = p.slots.tryAcquire(p.maxConcurrentRequests()); acquired {
+				return true
+			}
+		case <-timer.C:
+			writeProxyError(w, http.StatusServiceUnavailable, "queue_timeout",
+				fmt.Sprintf("Timed out after %s waiting for a free slot on instance %s", queueTimeout, p.instance.Name))
+			return false
+		case <-r.Context().Done():
+			// Client went away while queued, nothing left to respond to
+			return false
+		}
+	}
+}
+
+// requestSlots counts requests in use against a concurrency limit that may
+// change while they run
+type requestSlots struct {
+	mu      sync.Mutex
+	used    int
+	changed chan struct{} // closed and replaced when a slot is released or the limit may have changed
+}
+
+func newRequestSlots() *requestSlots {
+	return &requestSlots{changed: make(chan struct{})}
+}
+
+// tryAcquire takes a slot when fewer than limit are in use, limit <= 0
+// meaning no limit. Otherwise it returns a channel that is closed when it is
+// worth trying again.
+func (s *requestSlots) tryAcquire(limit int) (bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit <= 0 || s.used < limit {
+		s.used++
+		return true, nil
+	}
+	return false, s.changed
+}
+
+// release frees a slot and wakes the queued requests
+func (s *requestSlots) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.used--
+	s.wakeLocked()
+}
+
+// wake makes queued requests check the limit again
+func (s *requestSlots) wake() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wakeLocked()
+}
+
+func (s *requestSlots) wakeLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// writeProxyError writes a JSON error response in the same shape as the API handlers
+func writeProxyError(w http.ResponseWriter, status int, code, details string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "details": details})
+}
+
 // clear resets the proxy, allowing it to be recreated when options change.
 func (p *proxy) clear() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	// Release the old transport's keep-alive connections
+	if p.proxy != nil {
+		if transport, ok := p.proxy.Transport.(*http.Transport); ok {
+			transport.CloseIdleConnections()
+		}
+	}
 	p.proxy = nil
 	p.proxyErr = nil
 	p.proxyOnce = sync.Once{}
+
+	// Let queued requests pick up a changed concurrency limit
+	p.slots.wake()
 }
 
 // updateLastRequestTime updates the last request access time for the instance
@@ -202,6 +423,9 @@ func (p *proxy) shouldTimeout() bool {
 	if options == nil || options.IdleTimeout == nil || *options.IdleTimeout <= 0 {
 		return false
 	}
+	if p.instance.IsPinned() {
+		return false
+	}
 
 	// Check if the last request time exceeds the idle timeout
 	lastRequest := p.lastRequestTime.Load()
@@ -232,3 +456,8 @@ func (p *proxy) decInflightRequests() {
 func (p *proxy) getInflightRequests() int32 {
 	return p.inflightRequests.Load()
 }
+
+// getQueuedRequests returns the number of requests waiting for a free slot
+func (p *proxy) getQueuedRequests() int32 {
+	return p.queuedRequests.Load()
+}
@@ -0,0 +1,115 @@
+package instance
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the request latency histogram.
+// Requests slower than the last bound land in a final overflow bucket.
+var latencyBuckets = []time.Duration{
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+}
+
+// RequestStats is a snapshot of an instance's proxied request counters
+type RequestStats struct {
+	TotalRequests    int64           `json:"total_requests"`
+	ErrorRequests    int64           `json:"error_requests"`
+	AverageLatencyMs float64         `json:"average_latency_ms"`
+	LatencyHistogram []LatencyBucket `json:"latency_histogram"`
+}
+
+// LatencyBucket counts the requests that took longer than the previous
+// bucket's bound and at most Le, which is "+Inf" for the last bucket
+type LatencyBucket struct {
+	Le    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+// requestCounters accumulates request counts and latencies with atomics, so
+// recording never contends with the requests being measured
+type requestCounters struct {
+	total     atomic.Int64
+	errors    atomic.Int64
+	latencyNs atomic.Int64
+	buckets   [8]atomic.Int64 // len(latencyBuckets) + overflow
+}
+
+// record counts one finished request. Server errors, including the ones
+// llamactl writes itself when the backend is unreachable or the queue times
+// out, count as errors.
+func (c *requestCounters) record(status int, failed bool, latency time.Duration) {
+	c.total.Add(1)
+	if failed || status >= http.StatusInternalServerError {
+		c.errors.Add(1)
+	}
+	c.latencyNs.Add(int64(latency))
+
+	bucket := len(latencyBuckets)
+	for i, bound := range latencyBuckets {
+		if latency <= bound {
+			bucket = i
+			break
+		}
+	}
+	c.buckets[bucket].Add(1)
+}
+
+// snapshot returns the current counter values
+func (c *requestCounters) snapshot() RequestStats {
+	stats := RequestStats{
+		TotalRequests:    c.total.Load(),
+		ErrorRequests:    c.errors.Load(),
+		LatencyHistogram: make([]LatencyBucket, 0, len(c.buckets)),
+	}
+	if stats.TotalRequests > 0 {
+		average := time.Duration(c.latencyNs.Load() / stats.TotalRequests)
+		stats.AverageLatencyMs = float64(average) / float64(time.Millisecond)
+	}
+	for i := range c.buckets {
+		le := "+Inf"
+		if i < len(latencyBuckets) {
+			le = latencyBuckets[i].String()
+		}
+		stats.LatencyHistogram = append(stats.LatencyHistogram, LatencyBucket{Le: le, Count: c.buckets[i].Load()})
+	}
+	return stats
+}
+
+// statusRecorder remembers the response status for the request counters
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
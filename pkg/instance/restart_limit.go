@@ -0,0 +1,16 @@
+package instance
+
+import "time"
+
+// RestartLimiter rations auto-restarts across instances, so a failure
+// shared by many instances can't turn into a storm of process launches
+type RestartLimiter interface {
+	// Reserve books a restart and returns how long to wait before doing it
+	Reserve() time.Duration
+}
+
+// SetRestartLimiter sets the limiter consulted before each auto-restart.
+// It must be called before the instance is started.
+func (i *Instance) SetRestartLimiter(l RestartLimiter) {
+	i.restartLimiter = l
+}
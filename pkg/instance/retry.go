@@ -0,0 +1,202 @@
+package instance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryHeader lets a client opt a non-GET request into being retried once
+// if the backend crashes before responding and is auto-restarted
+const RetryHeader = "X-Llamactl-Retry"
+
+// maxRetryBodySize is the largest request body buffered for a retry; larger
+// requests are proxied without one
+const maxRetryBodySize = 10 << 20
+
+// restartDetectWindow is how long a failed request waits for the crash to
+// be noticed before concluding the backend is not restarting
+const restartDetectWindow = 2 * time.Second
+
+// retryAttemptKey marks a first attempt whose transport error should be
+// recorded for a retry instead of being answered with 502
+type retryAttemptKey struct{}
+
+type retryAttempt struct {
+	err error
+}
+
+// handleProxyError is the reverse proxy's error handler. It answers with a
+// JSON error like the rest of the API and logs the underlying error. Errors
+// of a first attempt that may still be retried are only recorded.
+func (p *proxy) handleProxyError(w http.ResponseWriter, r *http.Request, err error) {
+	if attempt, ok := r.Context().Value(retryAttemptKey{}).(*retryAttempt); ok {
+		attempt.err = err
+		return
+	}
+	switch r.Context().Err() {
+	case context.DeadlineExceeded:
+		p.instance.Logf(LogLevelWarn, "%s %s on instance %s exceeded the maximum request duration", r.Method, r.URL.Path, p.instance.Name)
+		writeProxyError(w, http.StatusGatewayTimeout, "upstream_timeout",
+			fmt.Sprintf("Instance %s did not finish the request within the maximum request duration", p.instance.Name))
+	case context.Canceled:
+		// The client went away and the backend request was aborted with it;
+		// there is nobody left to answer
+		p.instance.Logf(LogLevelInfo, "%s %s on instance %s cancelled by the client", r.Method, r.URL.Path, p.instance.Name)
+	default:
+		// A transport timeout, e.g. proxy.response_header_timeout, means the
+		// backend was reached but too slow, not that it is unreachable
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			p.instance.Logf(LogLevelWarn, "%s %s on instance %s timed out: %v", r.Method, r.URL.Path, p.instance.Name, err)
+			writeProxyError(w, http.StatusGatewayTimeout, "upstream_timeout",
+				fmt.Sprintf("Instance %s did not respond in time", p.instance.Name))
+			return
+		}
+		if retryAfter := p.unavailableRetryAfter(); retryAfter > 0 && errors.Is(err, syscall.ECONNREFUSED) {
+			// The process is up but not listening yet, most likely still
+			// loading the model; tell the client to come back shortly
+			p.instance.Logf(LogLevelWarn, "Instance %s refused the connection for %s %s, asking the client to retry in %s", p.instance.Name, r.Method, r.URL.Path, retryAfter)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeProxyError(w, http.StatusServiceUnavailable, "backend_unavailable",
+				fmt.Sprintf("Instance %s is not accepting connections yet, retry later", p.instance.Name))
+			return
+		}
+		p.instance.Logf(LogLevelError, "Proxy error for %s %s on instance %s: %v", r.Method, r.URL.Path, p.instance.Name, err)
+		writeProxyError(w, http.StatusBadGateway, "upstream_unreachable",
+			fmt.Sprintf("Failed to reach instance %s", p.instance.Name))
+	}
+}
+
+// unavailableRetryAfter returns proxy.unavailable_retry_after, 0 meaning a
+// refused connection is answered with a plain 502
+func (p *proxy) unavailableRetryAfter() time.Duration {
+	if settings := p.instance.globalProxySettings; settings != nil {
+		return settings.UnavailableRetryAfter
+	}
+	return 0
+}
+
+// shouldRetry reports whether a request failing because the backend died may
+// be retried once it has been auto-restarted: GET and HEAD requests, or any
+// request sent with the retry header, to a local instance with auto-restart
+func (p *proxy) shouldRetry(r *http.Request) bool {
+	if p.instance.IsRemote() || p.instance.process == nil {
+		return false
+	}
+	opts := p.instance.GetOptions()
+	if opts == nil || opts.AutoRestart == nil || !*opts.AutoRestart {
+		return false
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	return strings.EqualFold(r.Header.Get(RetryHeader), "true")
+}
+
+// serveWithRetry serves the request and, if it failed before any response
+// was written and the backend has since been restarted, serves it once more
+func (p *proxy) serveWithRetry(serve http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	body, ok := bufferRetryBody(r)
+	if !ok {
+		serve(w, r)
+		return
+	}
+
+	starts := p.instance.process.starts.Load()
+	attempt := &retryAttempt{}
+	serve(w, withBody(r.WithContext(context.WithValue(r.Context(), retryAttemptKey{}, attempt)), body))
+	if attempt.err == nil {
+		return
+	}
+
+	if r.Context().Err() != nil || !p.waitForRestart(r.Context(), starts) {
+		p.handleProxyError(w, r, attempt.err)
+		return
+	}
+
+	p.instance.Logf(LogLevelInfo, "Retrying %s %s on instance %s after it was restarted", r.Method, r.URL.Path, p.instance.Name)
+	serve(w, withBody(r, body))
+}
+
+// waitForRestart waits for the instance to be restarted after starts and
+// to become healthy. It gives up if the instance fails or is stopped, or if
+// no crash is noticed shortly after the request failed.
+func (p *proxy) waitForRestart(ctx context.Context, starts int64) bool {
+	timeout := 120
+	if settings := p.instance.globalInstanceSettings; settings != nil && settings.OnDemandStartTimeout > 0 {
+		timeout = settings.OnDemandStartTimeout
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	detectDeadline := time.Now().Add(restartDetectWindow)
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	stoppedPolls := 0
+	for time.Now().Before(deadline) {
+		switch p.instance.GetStatus() {
+		case Failed, ShuttingDown:
+			return false
+		case Stopped:
+			// A crashed process is briefly Stopped before it is marked
+			// Restarting; seeing it twice means it was stopped for good
+			if stoppedPolls++; stoppedPolls > 1 {
+				return false
+			}
+		case Running:
+			stoppedPolls = 0
+			if p.instance.process.starts.Load() != starts {
+				remaining := int(time.Until(deadline).Seconds())
+				return p.instance.WaitForHealthy(max(remaining, 1)) == nil
+			}
+			if time.Now().After(detectDeadline) {
+				return false
+			}
+		default:
+			stoppedPolls = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return false
+}
+
+// bufferRetryBody reads the request body into memory so it can be sent
+// again. It returns false, leaving the body readable, if it is too large.
+func bufferRetryBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRetryBodySize+1))
+	if err != nil || len(body) > maxRetryBodySize {
+		r.Body = teeReadCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		return nil, false
+	}
+	r.Body.Close()
+	return body, true
+}
+
+// withBody returns r with a fresh reader over body
+func withBody(r *http.Request, body []byte) *http.Request {
+	if body == nil {
+		return r
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return r
+}
@@ -0,0 +1,123 @@
+package instance
+
+import (
+	"fmt"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/validation"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// RouterModel is a model served by a llama.cpp router instance. Router
+// models are written to the instance's preset.ini, and requests select one
+// by sending its name as the model.
+type RouterModel struct {
+	Name   string `json:"name"`
+	Model  string `json:"model,omitempty"`   // local GGUF path
+	HFRepo string `json:"hf_repo,omitempty"` // Hugging Face repo, optionally with :quant
+	// Per-model llama-server options, e.g. {"ctx_size": "8192"}
+	Args map[string]string `json:"args,omitempty"`
+}
+
+// ValidateRouterModels checks that router models are only used with
+// llama.cpp router instances and that every model is uniquely named and
+// has exactly one source. Hugging Face repos are normalized in place.
+func (c *Options) ValidateRouterModels() error {
+	if len(c.RouterModels) == 0 {
+		return nil
+	}
+
+	if c.BackendOptions.BackendType != backends.BackendTypeLlamaCpp {
+		return validation.ValidationError(fmt.Errorf("router_models is only supported for the %s backend", backends.BackendTypeLlamaCpp))
+	}
+	if c.PresetIni != nil && *c.PresetIni != "" {
+		return validation.ValidationError(fmt.Errorf("router_models cannot be combined with preset_ini"))
+	}
+	if lo := c.BackendOptions.LlamaServerOptions; lo != nil && (lo.Model != "" || lo.HFRepo != "" || lo.ModelsPreset != "") {
+		return validation.ValidationError(fmt.Errorf("router_models cannot be combined with model, hf_repo or models_preset"))
+	}
+
+	seen := make(map[string]bool, len(c.RouterModels))
+	for i, m := range c.RouterModels {
+		if m.Name == "" {
+			return validation.ValidationError(fmt.Errorf("router model name cannot be empty"))
+		}
+		if strings.ContainsAny(m.Name, "/[]\r\n") {
+			return validation.ValidationError(fmt.Errorf("router model name %q cannot contain '/', '[', ']' or newlines", m.Name))
+		}
+		if seen[m.Name] {
+			return validation.ValidationError(fmt.Errorf("duplicate router model %q", m.Name))
+		}
+		seen[m.Name] = true
+
+		if (m.Model == "") == (m.HFRepo == "") {
+			return validation.ValidationError(fmt.Errorf("router model %q must set exactly one of model or hf_repo", m.Name))
+		}
+		// Sources and options are written into preset.ini, where a newline or
+		// bracket could start a new section or key
+		if strings.ContainsAny(m.Model+m.HFRepo, "[]\r\n") {
+			return validation.ValidationError(fmt.Errorf("router model %q: model and hf_repo cannot contain '[', ']' or newlines", m.Name))
+		}
+		if m.HFRepo != "" {
+			ref, err := validation.ParseHFRef(m.HFRepo)
+			if err != nil {
+				return validation.ValidationError(fmt.Errorf("router model %q: %w", m.Name, err))
+			}
+			if ref.Revision != "" {
+				return validation.ValidationError(fmt.Errorf("router model %q: hf_repo cannot pin a revision", m.Name))
+			}
+			// llama-server only understands the normalized form, not URLs
+			c.RouterModels[i].HFRepo = ref.String()
+		}
+		for key, value := range m.Args {
+			if strings.ContainsAny(key+value, "\r\n") || strings.ContainsAny(key, "[]=") {
+				return validation.ValidationError(fmt.Errorf("router model %q has an option with a newline, or a name with '[', ']' or '='", m.Name))
+			}
+		}
+	}
+
+	return nil
+}
+
+// presetIni returns the preset.ini content for the instance: preset_ini
+// as given, or one generated from router_models
+func (c *Options) presetIni() string {
+	if c.PresetIni != nil && *c.PresetIni != "" {
+		return *c.PresetIni
+	}
+	if len(c.RouterModels) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for i, m := range c.RouterModels {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]\n", m.Name)
+		if m.Model != "" {
+			fmt.Fprintf(&b, "model = %s\n", m.Model)
+		}
+		if m.HFRepo != "" {
+			fmt.Fprintf(&b, "hf-repo = %s\n", m.HFRepo)
+		}
+		for _, key := range slices.Sorted(maps.Keys(m.Args)) {
+			fmt.Fprintf(&b, "%s = %s\n", strings.ReplaceAll(key, "_", "-"), m.Args[key])
+		}
+	}
+	return b.String()
+}
+
+// RouterModelNames returns the names of the instance's declared router models
+func (i *Instance) RouterModelNames() []string {
+	opts := i.GetOptions()
+	if opts == nil {
+		return nil
+	}
+	names := make([]string, 0, len(opts.RouterModels))
+	for _, m := range opts.RouterModels {
+		names = append(names, m.Name)
+	}
+	return names
+}
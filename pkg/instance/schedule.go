@@ -0,0 +1,35 @@
+package instance
+
+import (
+	"fmt"
+	"llamactl/pkg/cron"
+	"llamactl/pkg/validation"
+)
+
+// Schedule starts and stops an instance at times given by cron expressions,
+// evaluated in the server's local time zone
+type Schedule struct {
+	StartCron string `json:"start_cron,omitempty"`
+	StopCron  string `json:"stop_cron,omitempty"`
+}
+
+// ValidateSchedule checks that the schedule's cron expressions parse
+func (c *Options) ValidateSchedule() error {
+	if c.Schedule == nil {
+		return nil
+	}
+
+	for _, expr := range []struct{ field, value string }{
+		{"start_cron", c.Schedule.StartCron},
+		{"stop_cron", c.Schedule.StopCron},
+	} {
+		if expr.value == "" {
+			continue
+		}
+		if _, err := cron.Parse(expr.value); err != nil {
+			return validation.ValidationError(fmt.Errorf("invalid schedule.%s: %w", expr.field, err))
+		}
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"sync"
+	"time"
 )
 
 // Status is the enum for status values (exported).
@@ -33,6 +34,12 @@ var statusToName = map[Status]string{
 	ShuttingDown: "shutting_down",
 }
 
+// ParseStatus returns the status with the given name, as used in JSON
+func ParseStatus(name string) (Status, bool) {
+	status, ok := nameToStatus[name]
+	return status, ok
+}
+
 // Status enum JSON marshaling methods
 func (s Status) MarshalJSON() ([]byte, error) {
 	name, ok := statusToName[s]
@@ -59,10 +66,30 @@ func (s *Status) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// DesiredState is the state the user asked an instance to be in. It is
+// persisted separately from the observed Status, so the intent to keep an
+// instance running survives crashes and llamactl restarts.
+type DesiredState string
+
+const (
+	DesiredStopped DesiredState = "stopped"
+	DesiredRunning DesiredState = "running"
+)
+
+// StateTimestamps records when an instance last changed state, as Unix
+// timestamps. Zero means the transition hasn't happened yet.
+type StateTimestamps struct {
+	LastStartedAt   int64 `json:"last_started_at,omitempty"`   // Last transition to running
+	LastStoppedAt   int64 `json:"last_stopped_at,omitempty"`   // Last transition out of running, including crashes
+	StatusChangedAt int64 `json:"status_changed_at,omitempty"` // Last status change of any kind
+}
+
 // status represents the instance status with thread-safe access (unexported).
 type status struct {
-	mu sync.RWMutex
-	s  Status
+	mu         sync.RWMutex
+	s          Status
+	desired    DesiredState
+	timestamps StateTimestamps
 
 	// Callback for status changes
 	onStatusChange func(oldStatus, newStatus Status)
@@ -71,7 +98,8 @@ type status struct {
 // newStatus creates a new status wrapper with the given initial status
 func newStatus(initial Status) *status {
 	return &status{
-		s: initial,
+		s:       initial,
+		desired: DesiredStopped,
 	}
 }
 
@@ -87,6 +115,15 @@ func (st *status) set(newStatus Status) {
 	st.mu.Lock()
 	oldStatus := st.s
 	st.s = newStatus
+	if oldStatus != newStatus {
+		now := time.Now().Unix()
+		st.timestamps.StatusChangedAt = now
+		if newStatus == Running {
+			st.timestamps.LastStartedAt = now
+		} else if oldStatus == Running {
+			st.timestamps.LastStoppedAt = now
+		}
+	}
 	callback := st.onStatusChange
 	st.mu.Unlock()
 
@@ -96,6 +133,37 @@ func (st *status) set(newStatus Status) {
 	}
 }
 
+// getDesired returns the desired state
+func (st *status) getDesired() DesiredState {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	if st.desired == DesiredRunning {
+		return DesiredRunning
+	}
+	return DesiredStopped
+}
+
+// setDesired updates the desired state
+func (st *status) setDesired(desired DesiredState) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.desired = desired
+}
+
+// getTimestamps returns the state change timestamps
+func (st *status) getTimestamps() StateTimestamps {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.timestamps
+}
+
+// setTimestamps replaces the state change timestamps
+func (st *status) setTimestamps(ts StateTimestamps) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.timestamps = ts
+}
+
 // isRunning returns true if the status is Running
 func (st *status) isRunning() bool {
 	st.mu.RLock()
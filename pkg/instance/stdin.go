@@ -0,0 +1,40 @@
+package instance
+
+import (
+	"fmt"
+	"io"
+	"llamactl/pkg/validation"
+)
+
+// maxStdinDataSize caps stdin_data. It is stored with the instance and
+// returned by the API, so it is meant for a config file, not a dataset.
+const maxStdinDataSize = 1 << 20
+
+// ValidateStdinData checks the size of stdin_data
+func (c *Options) ValidateStdinData() error {
+	if len(c.StdinData) > maxStdinDataSize {
+		return validation.ValidationError(fmt.Errorf("stdin_data is %d bytes, the limit is %d", len(c.StdinData), maxStdinDataSize))
+	}
+	return nil
+}
+
+// stdinData returns the data to write to the backend's stdin on start, or
+// "" to leave stdin connected to the null device
+func (i *Instance) stdinData() string {
+	opts := i.GetOptions()
+	if opts == nil {
+		return ""
+	}
+	return opts.StdinData
+}
+
+// feedStdin writes data to the started process and closes its stdin, so the
+// backend sees EOF. A backend that exits or closes stdin without reading
+// everything only produces a warning; the exit itself is handled by the
+// monitor.
+func (p *process) feedStdin(stdin io.WriteCloser, data string) {
+	defer stdin.Close()
+	if _, err := io.WriteString(stdin, data); err != nil {
+		p.instance.Logf(LogLevelWarn, "Failed to write stdin_data to instance %s: %v", p.instance.Name, err)
+	}
+}
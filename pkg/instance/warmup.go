@@ -0,0 +1,70 @@
+package instance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"llamactl/pkg/backends"
+	"net/http"
+)
+
+// warmupRequest is a minimal OpenAI-style completion: one prompt token in,
+// one token out
+type warmupRequest struct {
+	Model     string `json:"model,omitempty"`
+	Prompt    string `json:"prompt"`
+	MaxTokens int    `json:"max_tokens"`
+}
+
+// Warmup sends a one-token completion to the running backend so it finishes
+// lazy initialization (CUDA graphs, KV cache allocation, etc.) before the
+// first real request arrives
+func (i *Instance) Warmup(ctx context.Context) error {
+	if !i.IsRunning() {
+		return fmt.Errorf("instance %s is not running", i.Name)
+	}
+
+	if opts := i.GetOptions(); opts != nil && !opts.BackendOptions.ServesHTTP() {
+		return fmt.Errorf("instance %s has no HTTP API to warm up", i.Name)
+	}
+
+	baseURL := i.BackendURL()
+	body := warmupRequest{Prompt: "Hello", MaxTokens: 1}
+
+	// vLLM only answers for the model name it serves
+	if i.GetBackendType() == backends.BackendTypeVllm {
+		var models openAIModels
+		if err := getBackendJSON(ctx, baseURL+"/v1/models", &models); err != nil {
+			return err
+		}
+		if len(models.Data) > 0 {
+			body.Model = models.Data[0].ID
+		}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/v1/completions", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send warmup request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("backend returned status %d for warmup request: %s", resp.StatusCode, msg)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,63 @@
+package instance
+
+import (
+	"fmt"
+	"llamactl/pkg/config"
+	"llamactl/pkg/validation"
+	"os"
+	"path/filepath"
+)
+
+// ValidateWorkingDir checks working_dir. For a native process it is a host
+// directory that must exist, unless instances.auto_create_dirs lets it be
+// created on start. For Docker it is a path inside the container, which the
+// runtime creates.
+func (c *Options) ValidateWorkingDir(backendSettings *config.BackendConfig, autoCreate bool) error {
+	if c.WorkingDir == "" {
+		return nil
+	}
+
+	if !filepath.IsAbs(c.WorkingDir) {
+		return validation.ValidationError(fmt.Errorf("working_dir must be an absolute path, got %q", c.WorkingDir))
+	}
+	if c.BackendOptions.IsDockerEnabled(backendSettings, c.DockerEnabled) {
+		return nil
+	}
+
+	info, err := os.Stat(c.WorkingDir)
+	switch {
+	case os.IsNotExist(err) && autoCreate:
+		return nil
+	case err != nil:
+		return validation.ValidationError(fmt.Errorf("working_dir %s: %w", c.WorkingDir, err))
+	case !info.IsDir():
+		return validation.ValidationError(fmt.Errorf("working_dir %s is not a directory", c.WorkingDir))
+	}
+	return nil
+}
+
+// workingDir returns the directory the backend process runs in on the host,
+// or "" for the llamactl working directory. Docker instances get theirs
+// through -w instead.
+func (i *Instance) workingDir() string {
+	opts := i.GetOptions()
+	if opts == nil || i.isDockerEnabled() {
+		return ""
+	}
+	return opts.WorkingDir
+}
+
+// prepareWorkingDir creates the working directory before a start if it has
+// gone missing, e.g. after a reboot cleared it, and auto_create_dirs is set
+func (i *Instance) prepareWorkingDir() error {
+	dir := i.workingDir()
+	if dir == "" {
+		return nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) && i.globalInstanceSettings != nil && i.globalInstanceSettings.AutoCreateDirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create working directory: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"llamactl/pkg/instance"
+	"llamactl/pkg/validation"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// declarativeStateFile records, in the data directory, which instances came
+// from instances.declarative_dir and the hash of the file each was last
+// applied from. Only instances listed here are deleted when their file goes.
+const declarativeStateFile = "declarative-state.json"
+
+// DeclarativeResult summarizes one reconciliation against instances.declarative_dir
+type DeclarativeResult struct {
+	Created []string          `json:"created,omitempty"`
+	Updated []string          `json:"updated,omitempty"`
+	Deleted []string          `json:"deleted,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"` // instance name -> error
+}
+
+func (r *DeclarativeResult) addError(name string, err error) {
+	if r.Errors == nil {
+		r.Errors = make(map[string]string)
+	}
+	r.Errors[name] = err.Error()
+}
+
+// declaredInstance is an instance defined by a file in the declarative directory
+type declaredInstance struct {
+	options *instance.Options
+	hash    string
+	err     error // set when the file couldn't be read or parsed
+}
+
+// ReconcileDeclarative makes the instances match the YAML files in
+// instances.declarative_dir. Each <name>.yaml file holds the options of the
+// instance <name>. Instances without a file are created, those whose file
+// changed since it was last applied are updated, and instances that came from
+// a file that has been removed are stopped and deleted. Instances created
+// through the API are left alone unless a file with their name appears.
+func (im *instanceManager) ReconcileDeclarative() (*DeclarativeResult, error) {
+	dir := im.globalConfig.Instances.DeclarativeDir
+	if dir == "" {
+		return nil, fmt.Errorf("instances.declarative_dir is not set")
+	}
+
+	im.declarativeMu.Lock()
+	defer im.declarativeMu.Unlock()
+
+	declared, err := readDeclarativeDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	statePath := filepath.Join(im.globalConfig.DataDir, declarativeStateFile)
+	applied, err := loadDeclarativeState(statePath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DeclarativeResult{}
+
+	for _, name := range slices.Sorted(maps.Keys(declared)) {
+		decl := declared[name]
+		if decl.err != nil {
+			// Keep the instance as it is until the file is fixed
+			result.addError(name, decl.err)
+			continue
+		}
+
+		if _, exists := im.registry.get(name); !exists {
+			if _, err := im.CreateInstance(name, decl.options); err != nil {
+				result.addError(name, err)
+				continue
+			}
+			result.Created = append(result.Created, name)
+		} else if applied[name] != decl.hash {
+			if _, _, err := im.UpdateInstance(name, decl.options); err != nil {
+				result.addError(name, err)
+				continue
+			}
+			result.Updated = append(result.Updated, name)
+		}
+		applied[name] = decl.hash
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(applied)) {
+		if _, ok := declared[name]; ok {
+			continue
+		}
+		if err := im.removeDeclaredInstance(name); err != nil {
+			result.addError(name, err)
+			continue
+		}
+		delete(applied, name)
+		result.Deleted = append(result.Deleted, name)
+	}
+
+	if err := saveDeclarativeState(statePath, applied); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// removeDeclaredInstance stops and deletes an instance whose file was removed.
+// An instance that is already gone counts as removed.
+func (im *instanceManager) removeDeclaredInstance(name string) error {
+	inst, exists := im.registry.get(name)
+	if !exists {
+		return nil
+	}
+	if inst.IsRunning() {
+		if _, err := im.StopInstance(name); err != nil {
+			return err
+		}
+	}
+	return im.DeleteInstance(name)
+}
+
+// readDeclarativeDir parses every .yaml and .yml file in dir, keyed by the
+// instance name taken from the file name
+func readDeclarativeDir(dir string) (map[string]declaredInstance, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read declarative directory: %w", err)
+	}
+
+	declared := make(map[string]declaredInstance)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if _, dup := declared[name]; dup {
+			declared[name] = declaredInstance{err: fmt.Errorf("instance %s is defined by both %s.yaml and %s.yml", name, name, name)}
+			continue
+		}
+		if _, err := validation.ValidateInstanceName(name); err != nil {
+			log.Printf("Ignoring declarative file %s: %v", entry.Name(), err)
+			continue
+		}
+
+		declared[name] = parseDeclarativeFile(filepath.Join(dir, entry.Name()))
+	}
+	return declared, nil
+}
+
+// parseDeclarativeFile reads instance options from a YAML file. The YAML is
+// converted to JSON first, so the options decode exactly as they do from
+// the API.
+func parseDeclarativeFile(path string) declaredInstance {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return declaredInstance{err: err}
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return declaredInstance{err: fmt.Errorf("invalid YAML in %s: %w", filepath.Base(path), err)}
+	}
+	if raw == nil {
+		return declaredInstance{err: fmt.Errorf("%s is empty", filepath.Base(path))}
+	}
+
+	jsonData, err := json.Marshal(raw)
+	if err != nil {
+		return declaredInstance{err: fmt.Errorf("invalid options in %s: %w", filepath.Base(path), err)}
+	}
+	var options instance.Options
+	if err := json.Unmarshal(jsonData, &options); err != nil {
+		return declaredInstance{err: fmt.Errorf("invalid options in %s: %w", filepath.Base(path), err)}
+	}
+
+	sum := sha256.Sum256(data)
+	return declaredInstance{options: &options, hash: hex.EncodeToString(sum[:])}
+}
+
+func loadDeclarativeState(path string) (map[string]string, error) {
+	applied := make(map[string]string)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return applied, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read declarative state: %w", err)
+	}
+	if err := json.Unmarshal(data, &applied); err != nil {
+		return nil, fmt.Errorf("failed to parse declarative state %s: %w", path, err)
+	}
+	return applied, nil
+}
+
+func saveDeclarativeState(path string, applied map[string]string) error {
+	data, err := json.MarshalIndent(applied, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write declarative state: %w", err)
+	}
+	return nil
+}
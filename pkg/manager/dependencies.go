@@ -0,0 +1,273 @@
+package manager
+
+import (
+	"fmt"
+	"llamactl/pkg/apierrors"
+	"llamactl/pkg/instance"
+	"llamactl/pkg/validation"
+	"slices"
+	"strings"
+)
+
+// isLocalOptions reports whether options place an instance on the local node
+func (im *instanceManager) isLocalOptions(options *instance.Options) bool {
+	_, isLocal := options.Nodes[im.globalConfig.LocalNode]
+	return isLocal || len(options.Nodes) == 0
+}
+
+// validateDependencies checks depends_on for a local instance: every
+// dependency must be an existing instance on this node and the resulting
+// graph must stay acyclic. Remote instances are checked by their node.
+func (im *instanceManager) validateDependencies(name string, options *instance.Options) error {
+	if len(options.DependsOn) == 0 || !im.isLocalOptions(options) {
+		return nil
+	}
+
+	for _, dep := range options.DependsOn {
+		if _, err := validation.ValidateInstanceName(dep); err != nil {
+			return apierrors.Newf(apierrors.ErrInvalidOptions, "invalid dependency name %q: %w", dep, err)
+		}
+		if dep == name {
+			return apierrors.Newf(apierrors.ErrInvalidOptions, "instance %s cannot depend on itself", name)
+		}
+		depInst, exists := im.registry.get(dep)
+		if !exists {
+			return apierrors.Newf(apierrors.ErrInvalidOptions, "dependency %s of instance %s not found", dep, name)
+		}
+		if depInst.IsRemote() {
+			return apierrors.Newf(apierrors.ErrInvalidOptions, "dependency %s of instance %s runs on another node", dep, name)
+		}
+	}
+
+	// The existing graph is acyclic, so any new cycle has to pass through name
+	deps := func(n string) []string {
+		if n == name {
+			return options.DependsOn
+		}
+		return dependsOn(im.registry, n)
+	}
+	if cycle := findCycle(name, deps); cycle != nil {
+		return apierrors.Newf(apierrors.ErrInvalidOptions, "dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// dependsOn returns the names an instance depends on
+func dependsOn(registry *instanceRegistry, name string) []string {
+	inst, exists := registry.get(name)
+	if !exists {
+		return nil
+	}
+	opts := inst.GetOptions()
+	if opts == nil {
+		return nil
+	}
+	return opts.DependsOn
+}
+
+// findCycle returns a dependency path leading from start back to itself, or nil
+func findCycle(start string, deps func(string) []string) []string {
+	visited := map[string]bool{}
+	path := []string{}
+
+	var visit func(n string) bool
+	visit = func(n string) bool {
+		path = append(path, n)
+		for _, d := range deps(n) {
+			if d == start {
+				path = append(path, d)
+				return true
+			}
+			if !visited[d] {
+				visited[d] = true
+				if visit(d) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+
+	if visit(start) {
+		return path
+	}
+	return nil
+}
+
+// dependencyOrder returns the transitive dependencies of inst in the order
+// they have to be started, deepest first
+func (im *instanceManager) dependencyOrder(inst *instance.Instance) ([]*instance.Instance, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var order []*instance.Instance
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		for _, dep := range dependsOn(im.registry, name) {
+			switch state[dep] {
+			case done:
+				continue
+			case visiting:
+				return fmt.Errorf("dependency cycle detected at instance %s", dep)
+			}
+
+			depInst, exists := im.registry.get(dep)
+			if !exists {
+				return fmt.Errorf("dependency %s of instance %s not found", dep, name)
+			}
+
+			state[dep] = visiting
+			if err := visit(dep); err != nil {
+				return err
+			}
+			state[dep] = done
+			order = append(order, depInst)
+		}
+		return nil
+	}
+
+	state[inst.Name] = visiting
+	if err := visit(inst.Name); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// startDependencies starts the dependencies of inst that aren't running and
+// waits for all of them to become healthy
+func (im *instanceManager) startDependencies(inst *instance.Instance) error {
+	deps, err := im.dependencyOrder(inst)
+	if err != nil {
+		return err
+	}
+
+	for _, dep := range deps {
+		if dep.IsRemote() {
+			return fmt.Errorf("dependency %s of instance %s runs on another node", dep.Name, inst.Name)
+		}
+		if err := im.startLocalInstance(dep); err != nil {
+			return fmt.Errorf("failed to start dependency %s of instance %s: %w", dep.Name, inst.Name, err)
+		}
+		if err := dep.WaitForHealthy(im.globalConfig.Instances.OnDemandStartTimeout); err != nil {
+			return fmt.Errorf("dependency %s of instance %s did not become healthy: %w", dep.Name, inst.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// stopDependents stops running local instances that depend on inst, so a
+// dependency is never stopped from under the instances using it
+func (im *instanceManager) stopDependents(inst *instance.Instance) error {
+	for _, other := range im.registry.listRunning() {
+		if other.IsRemote() || !dependsOnInstance(other, inst.Name) {
+			continue
+		}
+		if _, err := im.StopInstance(other.Name); err != nil {
+			return fmt.Errorf("failed to stop dependent instance %s of %s: %w", other.Name, inst.Name, err)
+		}
+	}
+	return nil
+}
+
+// dependentsOf returns the names of the instances that directly depend on
+// name, in sorted order
+func (im *instanceManager) dependentsOf(name string) []string {
+	var dependents []string
+	for _, other := range im.registry.list() {
+		if dependsOnInstance(other, name) {
+			dependents = append(dependents, other.Name)
+		}
+	}
+	slices.Sort(dependents)
+	return dependents
+}
+
+// dependsOnInstance reports whether inst directly depends on name
+func dependsOnInstance(inst *instance.Instance, name string) bool {
+	opts := inst.GetOptions()
+	return opts != nil && slices.Contains(opts.DependsOn, name)
+}
+
+// sortByDependencies reorders instances so each comes after the instances it
+// depends on, keeping the existing order otherwise. Instances caught in a
+// cycle are left in their original order at the end.
+func sortByDependencies(insts []*instance.Instance) []*instance.Instance {
+	pending := map[string]bool{}
+	for _, inst := range insts {
+		pending[inst.Name] = true
+	}
+
+	sorted := make([]*instance.Instance, 0, len(insts))
+	remaining := insts
+	for len(remaining) > 0 {
+		var next []*instance.Instance
+		for _, inst := range remaining {
+			ready := true
+			if opts := inst.GetOptions(); opts != nil {
+				for _, dep := range opts.DependsOn {
+					if pending[dep] {
+						ready = false
+						break
+					}
+				}
+			}
+			if !ready {
+				next = append(next, inst)
+				continue
+			}
+			sorted = append(sorted, inst)
+			delete(pending, inst.Name)
+		}
+
+		if len(next) == len(remaining) {
+			return append(sorted, next...)
+		}
+		remaining = next
+	}
+
+	return sorted
+}
+
+// shutdownBatches groups instances so that each one is stopped in an earlier
+// batch than any instance it depends on. The order within a batch is kept.
+func shutdownBatches(insts []*instance.Instance) [][]*instance.Instance {
+	byName := map[string]*instance.Instance{}
+	for _, inst := range insts {
+		byName[inst.Name] = inst
+	}
+
+	// depth is the length of the longest chain of dependents above an instance
+	depth := map[string]int{}
+	var depthOf func(inst *instance.Instance, seen map[string]bool) int
+	depthOf = func(inst *instance.Instance, seen map[string]bool) int {
+		if d, ok := depth[inst.Name]; ok {
+			return d
+		}
+		seen[inst.Name] = true
+		d := 0
+		for _, other := range insts {
+			if !seen[other.Name] && dependsOnInstance(other, inst.Name) {
+				d = max(d, depthOf(other, seen)+1)
+			}
+		}
+		delete(seen, inst.Name)
+		depth[inst.Name] = d
+		return d
+	}
+
+	var batches [][]*instance.Instance
+	for _, inst := range insts {
+		d := depthOf(inst, map[string]bool{})
+		for len(batches) <= d {
+			batches = append(batches, nil)
+		}
+		batches[d] = append(batches[d], inst)
+	}
+	return batches
+}
@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"fmt"
+	"llamactl/pkg/apierrors"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/instance"
+)
+
+// validateDraftInstance checks that draft_instance names a local llama.cpp
+// instance with a model, and that the draft model isn't also set directly.
+func (im *instanceManager) validateDraftInstance(name string, options *instance.Options) error {
+	if options.DraftInstance == "" || !im.isLocalOptions(options) {
+		return nil
+	}
+
+	if options.BackendOptions.BackendType != backends.BackendTypeLlamaCpp {
+		return apierrors.Newf(apierrors.ErrInvalidOptions, "draft_instance is only supported for the %s backend", backends.BackendTypeLlamaCpp)
+	}
+	if lo := options.BackendOptions.LlamaServerOptions; lo != nil && (lo.ModelDraft != "" || lo.HFRepoDraft != "") {
+		return apierrors.Newf(apierrors.ErrInvalidOptions, "draft_instance cannot be combined with model_draft or hf_repo_draft")
+	}
+	if options.DraftInstance == name {
+		return apierrors.Newf(apierrors.ErrInvalidOptions, "instance %s cannot be its own draft instance", name)
+	}
+
+	draft, exists := im.registry.get(options.DraftInstance)
+	if !exists {
+		return apierrors.Newf(apierrors.ErrInvalidOptions, "draft instance %s not found", options.DraftInstance)
+	}
+	if _, err := draftModelOf(draft); err != nil {
+		return apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	return nil
+}
+
+// resolveDraftInstance hands the current model settings of the instance's
+// draft_instance to it before a start, so changes to the draft instance are
+// picked up without editing every instance that uses it.
+func (im *instanceManager) resolveDraftInstance(inst *instance.Instance) error {
+	opts := inst.GetOptions()
+	if opts == nil || opts.DraftInstance == "" {
+		inst.SetDraftModel(nil)
+		return nil
+	}
+
+	draft, exists := im.registry.get(opts.DraftInstance)
+	if !exists {
+		return fmt.Errorf("draft instance %s of instance %s not found", opts.DraftInstance, inst.Name)
+	}
+
+	draftModel, err := draftModelOf(draft)
+	if err != nil {
+		return err
+	}
+	inst.SetDraftModel(draftModel)
+	return nil
+}
+
+// draftModelOf extracts the draft model settings from a llama.cpp instance
+func draftModelOf(draft *instance.Instance) (*instance.DraftModel, error) {
+	if draft.IsRemote() {
+		return nil, fmt.Errorf("draft instance %s runs on another node", draft.Name)
+	}
+
+	opts := draft.GetOptions()
+	if opts == nil || opts.BackendOptions.BackendType != backends.BackendTypeLlamaCpp || opts.BackendOptions.LlamaServerOptions == nil {
+		return nil, fmt.Errorf("draft instance %s is not a llama.cpp instance", draft.Name)
+	}
+
+	lo := opts.BackendOptions.LlamaServerOptions
+	if lo.Model == "" && lo.HFRepo == "" {
+		return nil, fmt.Errorf("draft instance %s has no model or hf_repo set", draft.Name)
+	}
+
+	return &instance.DraftModel{
+		Model:     lo.Model,
+		HFRepo:    lo.HFRepo,
+		CtxSize:   lo.CtxSize,
+		GPULayers: lo.GPULayers,
+		Device:    lo.Device,
+	}, nil
+}
@@ -0,0 +1,477 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"llamactl/pkg/apierrors"
+	"llamactl/pkg/instance"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Drain states reported in DrainStatus.State
+const (
+	DrainStateMigrating = "migrating"
+	DrainStateDrained   = "drained"
+)
+
+// Per-instance migration states reported in InstanceMigration.Status
+const (
+	MigrationPending  = "pending"
+	MigrationMigrated = "migrated"
+	MigrationFailed   = "failed"
+)
+
+// DrainOptions controls what happens to the instances on a drained node
+type DrainOptions struct {
+	// Re-create the node's instances on another node
+	Migrate bool `json:"migrate"`
+	// Node to migrate to; defaults to the node with the fewest instances
+	TargetNode string `json:"target_node,omitempty"`
+}
+
+// InstanceMigration is the progress of moving one instance off a drained node
+type InstanceMigration struct {
+	Name       string `json:"name"`
+	TargetNode string `json:"target_node"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// DrainStatus reports a node drain and, when migrating, its progress
+type DrainStatus struct {
+	Node       string              `json:"node"`
+	State      string              `json:"state"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"`
+	Instances  []InstanceMigration `json:"instances,omitempty"`
+}
+
+// drainTracker keeps the drained nodes and their status. Draining is held
+// in memory only, so a restart makes every node accept placements again.
+type drainTracker struct {
+	mu     sync.Mutex
+	drains map[string]*nodeDrain
+	// Source nodes with a migration still running. An undrained node stays
+	// here until its migration finishes the instance it is moving.
+	migrating map[string]bool
+}
+
+type nodeDrain struct {
+	status DrainStatus
+	cancel context.CancelFunc // stops a running migration; nil once finished
+}
+
+func newDrainTracker() *drainTracker {
+	return &drainTracker{
+		drains:    make(map[string]*nodeDrain),
+		migrating: make(map[string]bool),
+	}
+}
+
+func (d *drainTracker) isDraining(node string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.drains[node]
+	return ok
+}
+
+// snapshot returns a copy of the node's drain status
+func (d *drainTracker) snapshot(node string) (*DrainStatus, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	drain, ok := d.drains[node]
+	if !ok {
+		return nil, false
+	}
+	status := drain.status
+	status.Instances = slices.Clone(drain.status.Instances)
+	return &status, true
+}
+
+// update applies fn to the drain's status under the lock
+func (d *drainTracker) update(drain *nodeDrain, fn func(*DrainStatus)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(&drain.status)
+}
+
+// instanceNode returns the name of the node an instance runs on
+func (im *instanceManager) instanceNode(inst *instance.Instance) string {
+	opts := inst.GetOptions()
+	if opts == nil || im.isLocalOptions(opts) {
+		return im.globalConfig.LocalNode
+	}
+	for node := range opts.Nodes {
+		return node
+	}
+	return im.globalConfig.LocalNode
+}
+
+// checkPlacement rejects placing a new instance on a draining node
+func (im *instanceManager) checkPlacement(options *instance.Options) error {
+	node := im.globalConfig.LocalNode
+	if !im.isLocalOptions(options) {
+		for n := range options.Nodes {
+			node = n
+			break
+		}
+	}
+	if im.drains.isDraining(node) {
+		return apierrors.Newf(apierrors.ErrNodeDraining, "node %s is draining and does not accept new instances", node)
+	}
+	return nil
+}
+
+// DrainNode stops new instances from being placed on a node. With
+// opts.Migrate, the node's instances are re-created on another node in the
+// background; GetDrainStatus reports the progress.
+func (im *instanceManager) DrainNode(node string, opts DrainOptions) (*DrainStatus, error) {
+	if _, ok := im.globalConfig.Nodes[node]; !ok {
+		return nil, apierrors.Newf(apierrors.ErrNodeNotFound, "node %s not found", node)
+	}
+
+	var toMigrate []*instance.Instance
+	target := opts.TargetNode
+	if opts.Migrate {
+		for _, inst := range im.registry.list() {
+			if im.instanceNode(inst) == node {
+				toMigrate = append(toMigrate, inst)
+			}
+		}
+		var err error
+		if target, err = im.pickTargetNode(node, opts.TargetNode); err != nil {
+			return nil, err
+		}
+	}
+
+	im.drains.mu.Lock()
+	if im.drains.migrating[node] {
+		im.drains.mu.Unlock()
+		return nil, apierrors.Newf(apierrors.ErrNodeDraining, "node %s is already being drained", node)
+	}
+	drain := &nodeDrain{status: DrainStatus{
+		Node:      node,
+		State:     DrainStateDrained,
+		StartedAt: time.Now(),
+	}}
+	if len(toMigrate) > 0 {
+		drain.status.State = DrainStateMigrating
+		for _, inst := range toMigrate {
+			drain.status.Instances = append(drain.status.Instances, InstanceMigration{
+				Name: inst.Name, TargetNode: target, Status: MigrationPending,
+			})
+		}
+		var ctx context.Context
+		ctx, drain.cancel = context.WithCancel(context.Background())
+		im.drains.migrating[node] = true
+		go im.migrateInstances(ctx, drain, target, toMigrate)
+	} else {
+		finished := drain.status.StartedAt
+		drain.status.FinishedAt = &finished
+	}
+	im.drains.drains[node] = drain
+	im.drains.mu.Unlock()
+
+	status, _ := im.drains.snapshot(node)
+	return status, nil
+}
+
+// GetDrainStatus returns the drain status of a node
+func (im *instanceManager) GetDrainStatus(node string) (*DrainStatus, error) {
+	if _, ok := im.globalConfig.Nodes[node]; !ok {
+		return nil, apierrors.Newf(apierrors.ErrNodeNotFound, "node %s not found", node)
+	}
+	status, ok := im.drains.snapshot(node)
+	if !ok {
+		return nil, apierrors.Newf(apierrors.ErrNodeNotFound, "node %s is not draining", node)
+	}
+	return status, nil
+}
+
+// UndrainNode lets the node accept new instances again. A migration still in
+// progress stops after the instance it is currently moving; until then the
+// node can't be drained again.
+func (im *instanceManager) UndrainNode(node string) error {
+	if _, ok := im.globalConfig.Nodes[node]; !ok {
+		return apierrors.Newf(apierrors.ErrNodeNotFound, "node %s not found", node)
+	}
+	im.drains.mu.Lock()
+	defer im.drains.mu.Unlock()
+	if drain, ok := im.drains.drains[node]; ok && drain.cancel != nil {
+		drain.cancel()
+	}
+	delete(im.drains.drains, node)
+	return nil
+}
+
+// pickTargetNode validates the requested target node, or picks the
+// non-draining node with the fewest instances. All of a drain's instances go
+// to the same node so dependencies and draft instances stay together.
+func (im *instanceManager) pickTargetNode(drained, requested string) (string, error) {
+	if requested != "" {
+		if _, ok := im.globalConfig.Nodes[requested]; !ok {
+			return "", apierrors.Newf(apierrors.ErrNodeNotFound, "target node %s not found", requested)
+		}
+		if requested == drained || im.drains.isDraining(requested) {
+			return "", apierrors.Newf(apierrors.ErrNodeDraining, "target node %s is draining", requested)
+		}
+		return requested, nil
+	}
+
+	counts := make(map[string]int)
+	for _, inst := range im.registry.list() {
+		counts[im.instanceNode(inst)]++
+	}
+
+	best := ""
+	for node := range im.globalConfig.Nodes {
+		if node == drained || im.drains.isDraining(node) {
+			continue
+		}
+		if best == "" || counts[node] < counts[best] || (counts[node] == counts[best] && node < best) {
+			best = node
+		}
+	}
+	if best == "" {
+		return "", apierrors.Newf(apierrors.ErrNodeNotFound, "no other node available to migrate instances from %s to", drained)
+	}
+	return best, nil
+}
+
+// migrateInstances moves insts off the drained node to target. Instances
+// linked by depends_on or draft_instance move together as one group, since a
+// dependency can't be removed while its users still exist and a user can't be
+// created before its dependency.
+func (im *instanceManager) migrateInstances(ctx context.Context, drain *nodeDrain, target string, insts []*instance.Instance) {
+	node := drain.status.Node
+
+	// Draft instances first, then dependency order, which is the order to
+	// create and start them in
+	slices.SortStableFunc(insts, func(a, b *instance.Instance) int {
+		return boolToInt(!isDraftFor(b, insts)) - boolToInt(!isDraftFor(a, insts))
+	})
+	insts = sortByDependencies(insts)
+
+	// Remember what was running before stopping one instance stops its dependents
+	wasRunning := make(map[string]bool, len(insts))
+	for _, inst := range insts {
+		wasRunning[inst.Name] = inst.IsRunning()
+	}
+
+	for _, group := range migrationGroups(insts) {
+		if ctx.Err() != nil {
+			break
+		}
+		errs := im.migrateGroup(group, node, target, wasRunning)
+		im.drains.update(drain, func(status *DrainStatus) {
+			for i := range status.Instances {
+				err, ok := errs[status.Instances[i].Name]
+				if !ok {
+					continue
+				}
+				status.Instances[i].Status = MigrationMigrated
+				if err != nil {
+					status.Instances[i].Status = MigrationFailed
+					status.Instances[i].Error = err.Error()
+				}
+			}
+		})
+		for _, inst := range group {
+			if err := errs[inst.Name]; err != nil {
+				log.Printf("Failed to migrate instance %s from node %s to %s: %v", inst.Name, node, target, err)
+			} else {
+				log.Printf("Migrated instance %s from node %s to %s", inst.Name, node, target)
+			}
+		}
+	}
+
+	im.drains.mu.Lock()
+	defer im.drains.mu.Unlock()
+	now := time.Now()
+	drain.status.State = DrainStateDrained
+	drain.status.FinishedAt = &now
+	drain.cancel = nil
+	delete(im.drains.migrating, node)
+}
+
+// migrationGroups splits insts into groups of instances linked by depends_on
+// or draft_instance, keeping the order of insts within and across groups
+func migrationGroups(insts []*instance.Instance) [][]*instance.Instance {
+	index := make(map[string]int, len(insts))
+	parent := make([]int, len(insts))
+	for i, inst := range insts {
+		index[inst.Name] = i
+		parent[i] = i
+	}
+	root := func(i int) int {
+		for parent[i] != i {
+			i = parent[i]
+		}
+		return i
+	}
+
+	for i, inst := range insts {
+		opts := inst.GetOptions()
+		if opts == nil {
+			continue
+		}
+		linked := slices.Clone(opts.DependsOn)
+		if opts.DraftInstance != "" {
+			linked = append(linked, opts.DraftInstance)
+		}
+		for _, name := range linked {
+			if j, ok := index[name]; ok {
+				a, b := root(i), root(j)
+				parent[max(a, b)] = min(a, b)
+			}
+		}
+	}
+
+	var groups [][]*instance.Instance
+	groupOf := make(map[int]int)
+	for i, inst := range insts {
+		r := root(i)
+		g, ok := groupOf[r]
+		if !ok {
+			g = len(groups)
+			groupOf[r] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], inst)
+	}
+	return groups
+}
+
+// migrateGroup re-creates a group of linked instances on target: they are
+// stopped and removed from their node with users before their dependencies,
+// created on target in the opposite order with fresh ports, and started again
+// if they were running. If removing or creating any of them fails, the group
+// is put back. It returns the outcome for every instance of the group.
+func (im *instanceManager) migrateGroup(group []*instance.Instance, node, target string, wasRunning map[string]bool) map[string]error {
+	errs := make(map[string]error, len(group))
+	failAll := func(err error) map[string]error {
+		for _, inst := range group {
+			errs[inst.Name] = err
+		}
+		return errs
+	}
+
+	originals := make([]*instance.Options, len(group))
+	for i, inst := range group {
+		opts, err := copyOptions(inst.GetOptions())
+		if err != nil {
+			return failAll(fmt.Errorf("failed to copy options of %s: %w", inst.Name, err))
+		}
+		originals[i] = opts
+	}
+
+	for i := len(group) - 1; i >= 0; i-- {
+		if group[i].IsRunning() {
+			if _, err := im.StopInstance(group[i].Name); err != nil {
+				return failAll(fmt.Errorf("failed to stop instance %s: %w", group[i].Name, err))
+			}
+		}
+	}
+
+	// Put back the removed instances, which are group[removed:], on node
+	restore := func(removed int, cause error) map[string]error {
+		for i := removed; i < len(group); i++ {
+			original := originals[i]
+			original.Nodes = map[string]struct{}{node: {}}
+			original.BackendOptions.SetPort(0)
+			// The source node is draining, so this bypasses the placement check
+			if _, err := im.createInstance(group[i].Name, original); err != nil {
+				cause = fmt.Errorf("%w (restoring %s on %s also failed: %v)", cause, group[i].Name, node, err)
+			}
+		}
+		im.startGroup(group, wasRunning, func(name string, err error) {
+			log.Printf("Failed to restart instance %s on node %s after failed migration: %v", name, node, err)
+		})
+		return failAll(cause)
+	}
+
+	removed := len(group)
+	for i := len(group) - 1; i >= 0; i-- {
+		if err := im.DeleteInstance(group[i].Name); err != nil {
+			return restore(removed, fmt.Errorf("failed to remove instance %s from node %s: %w", group[i].Name, node, err))
+		}
+		removed = i
+	}
+
+	for i, inst := range group {
+		moved, err := copyOptions(originals[i])
+		if err != nil {
+			err = fmt.Errorf("failed to copy options of %s: %w", inst.Name, err)
+		} else {
+			moved.Nodes = map[string]struct{}{target: {}}
+			moved.BackendOptions.SetPort(0)
+			if _, err = im.createInstance(inst.Name, moved); err != nil {
+				err = fmt.Errorf("failed to create instance %s on node %s: %w", inst.Name, target, err)
+			}
+		}
+		if err != nil {
+			// Take the ones already created off target again, users first
+			for j := i - 1; j >= 0; j-- {
+				if delErr := im.DeleteInstance(group[j].Name); delErr != nil {
+					err = fmt.Errorf("%w (removing %s from %s again also failed: %v)", err, group[j].Name, target, delErr)
+				}
+			}
+			return restore(0, err)
+		}
+	}
+
+	for _, inst := range group {
+		errs[inst.Name] = nil
+	}
+	im.startGroup(group, wasRunning, func(name string, err error) {
+		errs[name] = fmt.Errorf("instance was created on node %s but failed to start: %w", target, err)
+	})
+	return errs
+}
+
+// startGroup starts the instances of group that were running, dependencies
+// first, reporting each failure to onError
+func (im *instanceManager) startGroup(group []*instance.Instance, wasRunning map[string]bool, onError func(name string, err error)) {
+	for _, inst := range group {
+		if !wasRunning[inst.Name] {
+			continue
+		}
+		if _, err := im.StartInstance(inst.Name); err != nil {
+			onError(inst.Name, err)
+		}
+	}
+}
+
+// isDraftFor reports whether inst is the draft instance of any of insts
+func isDraftFor(inst *instance.Instance, insts []*instance.Instance) bool {
+	for _, other := range insts {
+		if opts := other.GetOptions(); opts != nil && opts.DraftInstance == inst.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// copyOptions returns a deep copy of opts
+func copyOptions(opts *instance.Options) (*instance.Options, error) {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	var copied instance.Options
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
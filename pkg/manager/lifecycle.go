@@ -3,7 +3,6 @@ package manager
 import (
 	"fmt"
 	"llamactl/pkg/instance"
-	"log"
 	"sync"
 	"time"
 )
@@ -76,10 +75,10 @@ func (l *lifecycleManager) timeoutCheckLoop() {
 
 // checkTimeouts checks all instances for timeout and stops those that have timed out.
 func (l *lifecycleManager) checkTimeouts() {
-	// Get all instances from registry
-	instances := l.registry.list()
+	// Only running instances can time out
+	instances := l.registry.listRunning()
 
-	var timeoutInstances []string
+	var timeoutInstances []*instance.Instance
 
 	// Identify instances that should timeout
 	for _, inst := range instances {
@@ -88,23 +87,18 @@ func (l *lifecycleManager) checkTimeouts() {
 			continue
 		}
 
-		// Only check running instances
-		if !l.registry.isRunning(inst.Name) {
-			continue
-		}
-
 		if inst.ShouldTimeout() {
-			timeoutInstances = append(timeoutInstances, inst.Name)
+			timeoutInstances = append(timeoutInstances, inst)
 		}
 	}
 
 	// Stop the timed-out instances
-	for _, name := range timeoutInstances {
-		log.Printf("Instance %s has timed out, stopping it", name)
-		if _, err := l.manager.StopInstance(name); err != nil {
-			log.Printf("Error stopping instance %s: %v", name, err)
+	for _, inst := range timeoutInstances {
+		inst.Logf(instance.LogLevelInfo, "Instance %s has timed out, stopping it", inst.Name)
+		if _, err := l.manager.StopInstance(inst.Name); err != nil {
+			inst.Logf(instance.LogLevelError, "Error stopping instance %s: %v", inst.Name, err)
 		} else {
-			log.Printf("Instance %s stopped successfully", name)
+			inst.Logf(instance.LogLevelInfo, "Instance %s stopped successfully", inst.Name)
 		}
 	}
 }
@@ -114,6 +108,19 @@ func (l *lifecycleManager) checkTimeouts() {
 // If groupLabel is provided, only instances in that group are considered for eviction.
 // If groupLabel is empty, all running instances are considered.
 func (l *lifecycleManager) evictLRU(groupLabel string) error {
+	if groupLabel == "" {
+		return l.evictLRUMatching("", nil)
+	}
+	return l.evictLRUMatching("in group "+groupLabel, func(inst *instance.Instance) bool {
+		opts := inst.GetOptions()
+		return opts != nil && opts.Group == groupLabel
+	})
+}
+
+// evictLRUMatching stops the least recently used running instance for which
+// match returns true, or any running instance if match is nil. scope
+// describes the candidates in error messages.
+func (l *lifecycleManager) evictLRUMatching(scope string, match func(*instance.Instance) bool) error {
 	if !l.enableLRU {
 		return fmt.Errorf("LRU eviction is not enabled")
 	}
@@ -122,6 +129,7 @@ func (l *lifecycleManager) evictLRU(groupLabel string) error {
 	runningInstances := l.registry.listRunning()
 
 	var lruInstance *instance.Instance
+	pinned := 0
 
 	for _, inst := range runningInstances {
 		// Skip remote instances - they are managed by their respective nodes
@@ -134,15 +142,15 @@ func (l *lifecycleManager) evictLRU(groupLabel string) error {
 			continue
 		}
 
-		// If groupLabel is specified, only consider instances in that group
-		if groupLabel != "" {
-			instGroup := ""
-			if inst.GetOptions() != nil {
-				instGroup = inst.GetOptions().Group
-			}
-			if instGroup != groupLabel {
-				continue
-			}
+		if match != nil && !match(inst) {
+			continue
+		}
+
+		// Pinned instances are never evicted, but are counted so the
+		// error below can say why nothing was found
+		if inst.IsPinned() {
+			pinned++
+			continue
 		}
 
 		if lruInstance == nil {
@@ -155,14 +163,20 @@ func (l *lifecycleManager) evictLRU(groupLabel string) error {
 	}
 
 	if lruInstance == nil {
-		if groupLabel != "" {
-			return fmt.Errorf("failed to find lru instance in group %s", groupLabel)
+		if pinned > 0 {
+			if scope != "" {
+				return fmt.Errorf("no instance %s can be evicted, all %d candidates are pinned", scope, pinned)
+			}
+			return fmt.Errorf("no instance can be evicted, all %d candidates are pinned", pinned)
+		}
+		if scope != "" {
+			return fmt.Errorf("failed to find lru instance %s", scope)
 		}
 		return fmt.Errorf("failed to find lru instance")
 	}
 
 	// Evict the LRU instance
-	log.Printf("Evicting LRU instance %s", lruInstance.Name)
+	lruInstance.Logf(instance.LogLevelInfo, "Evicting LRU instance %s", lruInstance.Name)
 	_, err := l.manager.StopInstance(lruInstance.Name)
 	return err
 }
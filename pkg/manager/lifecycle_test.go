@@ -4,6 +4,7 @@ import (
 	"llamactl/pkg/backends"
 	"llamactl/pkg/instance"
 	"llamactl/pkg/manager"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -176,6 +177,67 @@ func TestEvictLRUInstance_OnlyEvictsTimeoutEnabledInstances(t *testing.T) {
 }
 
 // Helper function to create instances with different timeout configurations
+func TestEvictLRUInstance_SkipsPinnedInstances(t *testing.T) {
+	manager := createTestManager(t)
+	defer manager.Shutdown()
+
+	timeout := 1
+	pinnedInst := createInstanceWithTimeout(t, manager, "pinned", "/path/to/model-pinned.gguf", &timeout)
+	pinned := true
+	if _, _, err := manager.UpdateInstance("pinned", &instance.Options{
+		IdleTimeout:    &timeout,
+		Pinned:         &pinned,
+		BackendOptions: pinnedInst.GetOptions().BackendOptions,
+	}); err != nil {
+		t.Fatalf("UpdateInstance failed: %v", err)
+	}
+	otherInst := createInstanceWithTimeout(t, manager, "other", "/path/to/model-other.gguf", &timeout)
+
+	mockTime := NewMockTimeProvider(time.Now())
+	instances := []*instance.Instance{pinnedInst, otherInst}
+	for _, inst := range instances {
+		inst.SetTimeProvider(mockTime)
+		inst.SetStatus(instance.Running)
+	}
+	defer func() {
+		for _, inst := range instances {
+			if inst.IsRunning() {
+				inst.SetStatus(instance.Stopped)
+			}
+		}
+	}()
+
+	// The pinned instance is the least recently used one
+	pinnedInst.UpdateLastRequestTime()
+	mockTime.SetTime(mockTime.Now().Add(time.Minute))
+	otherInst.UpdateLastRequestTime()
+
+	if err := manager.EvictLRUInstance(""); err != nil {
+		t.Fatalf("EvictLRUInstance failed: %v", err)
+	}
+	if !pinnedInst.IsRunning() {
+		t.Error("Expected pinned instance to still be running")
+	}
+	if otherInst.IsRunning() {
+		t.Error("Expected unpinned instance to be evicted")
+	}
+
+	// Only the pinned instance is left, so nothing can be evicted
+	err := manager.EvictLRUInstance("")
+	if err == nil {
+		t.Fatal("Expected an error when all candidates are pinned")
+	}
+	if !strings.Contains(err.Error(), "pinned") {
+		t.Errorf("Expected error to mention pinned instances, got: %v", err)
+	}
+
+	// Nor does it time out when idle
+	mockTime.SetTime(mockTime.Now().Add(time.Hour))
+	if pinnedInst.ShouldTimeout() {
+		t.Error("Pinned instance should never time out")
+	}
+}
+
 func createInstanceWithTimeout(t *testing.T, manager manager.InstanceManager, name, model string, timeout *int) *instance.Instance {
 	t.Helper()
 	options := &instance.Options{
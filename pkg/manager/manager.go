@@ -1,12 +1,16 @@
 package manager
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"llamactl/pkg/config"
 	"llamactl/pkg/database"
 	"llamactl/pkg/instance"
+	"llamactl/pkg/notify"
 	"log"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -14,18 +18,30 @@ import (
 // InstanceManager defines the interface for managing instances of the llama server.
 type InstanceManager interface {
 	ListInstances() ([]*instance.Instance, error)
+	ListInstancesByStatus(status instance.Status) ([]*instance.Instance, error)
 	CreateInstance(name string, options *instance.Options) (*instance.Instance, error)
 	GetInstance(name string) (*instance.Instance, error)
-	UpdateInstance(name string, options *instance.Options) (*instance.Instance, error)
+	GetInstanceByID(id int) (*instance.Instance, error)
+	UpdateInstance(name string, options *instance.Options) (*instance.Instance, []string, error)
 	DeleteInstance(name string) error
 	StartInstance(name string) (*instance.Instance, error)
 	AtMaxRunning() bool
 	CountRunningInGroup(group string) int
 	StopInstance(name string) (*instance.Instance, error)
+	StopAllInstances() ([]StopResult, error)
 	EvictLRUInstance(group string) error
 	RestartInstance(name string) (*instance.Instance, error)
 	GetInstanceLogs(name string, numLines int) (string, error)
+	GetInstanceMetadata(name string) (*instance.Metadata, error)
+	GetInstanceOpenAPISpec(name string) (map[string]any, error)
+	ResolveModel(model string) (*instance.Instance, error)
+	DrainNode(node string, opts DrainOptions) (*DrainStatus, error)
+	GetDrainStatus(node string) (*DrainStatus, error)
+	UndrainNode(node string) error
+	ProbeNode(node string) (*NodeProbeResult, error)
+	ReconcileDeclarative() (*DeclarativeResult, error)
 	Shutdown()
+	ShutdownWithContext(ctx context.Context)
 }
 
 type instanceManager struct {
@@ -35,13 +51,20 @@ type instanceManager struct {
 	db        database.InstanceStore
 	remote    *remoteManager
 	lifecycle *lifecycleManager
+	scheduler *scheduler
+	drains    *drainTracker
+	webhook   *notify.Webhook // nil when no webhook is configured
+	restarts  *restartBudget  // nil when max_restarts_per_minute is unset
 
 	// Configuration
 	globalConfig *config.AppConfig
 
 	// Synchronization
-	instanceLocks sync.Map // map[string]*sync.Mutex - per-instance locks for concurrent operations
+	instanceLocks sync.Map   // map[string]*sync.Mutex - per-instance locks for concurrent operations
+	createMu      sync.Mutex // guards limit checks, port allocation and registry add on create
+	declarativeMu sync.Mutex // serializes reconciliations against instances.declarative_dir
 	shutdownOnce  sync.Once
+	shutdown      chan struct{} // closed on Shutdown to abort a paced auto-start
 }
 
 // New creates a new instance of InstanceManager with dependency injection.
@@ -56,10 +79,14 @@ func New(globalConfig *config.AppConfig, db database.InstanceStore) InstanceMana
 
 	// Initialize port allocator
 	portRange := globalConfig.Instances.PortRange
-	ports := newPortAllocator(portRange[0], portRange[1])
+	ports := newPortAllocator(portRange[0], portRange[1], globalConfig.Instances.PortAllocation)
 
 	// Initialize remote manager
 	remote := newRemoteManager(globalConfig.Nodes, 30*time.Second)
+	remote.selfAddresses = make(map[string]bool)
+	for _, name := range globalConfig.SelfReferencingNodes() {
+		remote.selfAddresses[globalConfig.Nodes[name].Address] = true
+	}
 
 	// Create manager instance
 	im := &instanceManager{
@@ -68,54 +95,138 @@ func New(globalConfig *config.AppConfig, db database.InstanceStore) InstanceMana
 		db:           db,
 		remote:       remote,
 		globalConfig: globalConfig,
+		shutdown:     make(chan struct{}),
+		drains:       newDrainTracker(),
+		webhook:      notify.NewWebhook(globalConfig.Notifications),
+	}
+	if n := globalConfig.Instances.MaxRestartsPerMinute; n > 0 {
+		im.restarts = newRestartBudget(n)
 	}
 
 	// Initialize lifecycle manager (needs reference to manager for Stop/Evict operations)
 	checkInterval := time.Duration(globalConfig.Instances.TimeoutCheckInterval) * time.Minute
 	im.lifecycle = newLifecycleManager(registry, im, checkInterval, true)
+	im.scheduler = newScheduler(registry, im)
 
 	// Load existing instances from disk
 	if err := im.loadInstances(); err != nil {
 		log.Printf("Error loading instances: %v", err)
 	}
 
-	// Start the lifecycle manager
+	// Start the lifecycle manager and scheduler
 	im.lifecycle.start()
+	im.scheduler.start()
 
 	return im
 }
 
+// applyRestartBudget makes a local instance's auto-restarts count against
+// the manager-wide restart budget
+func (im *instanceManager) applyRestartBudget(inst *instance.Instance) {
+	if im.restarts != nil && !inst.IsRemote() {
+		inst.SetRestartLimiter(im.restarts)
+	}
+}
+
 // persistInstance saves an instance using the persistence layer
 func (im *instanceManager) persistInstance(inst *instance.Instance) error {
 	return im.db.Save(inst)
 }
 
 func (im *instanceManager) Shutdown() {
+	im.ShutdownWithContext(context.Background())
+}
+
+// ShutdownWithContext stops all local instances, in reverse start order and
+// at most shutdown_parallelism at a time, with dependents stopped before the
+// instances they depend on. Instances still running when ctx expires are
+// force killed so no backend processes are left behind.
+func (im *instanceManager) ShutdownWithContext(ctx context.Context) {
 	im.shutdownOnce.Do(func() {
-		// 1. Stop lifecycle manager (stops timeout checker)
-		im.lifecycle.stop()
+		close(im.shutdown)
 
-		// 2. Get running instances (no lock needed - registry handles it)
-		running := im.registry.listRunning()
+		// 1. Stop lifecycle manager (stops timeout checker) and scheduler
+		im.lifecycle.stop()
+		im.scheduler.stop()
 
-		// 3. Stop local instances concurrently
-		var wg sync.WaitGroup
-		for _, inst := range running {
-			if inst.IsRemote() {
-				continue // Skip remote instances
+		// 2. Abort starts still pulling their image, then collect running
+		// local instances, lowest start priority first
+		for _, inst := range im.registry.list() {
+			if !inst.IsRemote() {
+				inst.CancelStart()
+			}
+		}
+		var running []*instance.Instance
+		for _, inst := range im.registry.listRunning() {
+			if !inst.IsRemote() {
+				running = append(running, inst)
 			}
-			wg.Add(1)
-			go func(inst *instance.Instance) {
-				defer wg.Done()
+		}
+		slices.SortStableFunc(running, func(a, b *instance.Instance) int {
+			return compareStartOrder(b, a)
+		})
+
+		// 3. Stop them batch by batch, dependents before their dependencies
+		for _, batch := range shutdownBatches(running) {
+			if !im.stopInstances(ctx, batch) {
+				log.Printf("Shutdown deadline reached, force killing remaining instances")
+				for _, inst := range running {
+					if err := inst.Kill(); err != nil {
+						log.Printf("Error killing instance %s: %v\n", inst.Name, err)
+					}
+				}
+				return
+			}
+		}
+		fmt.Println("All instances stopped.")
+	})
+}
+
+// stopInstances stops instances with a worker pool bounded by
+// shutdown_parallelism. It returns false if ctx expires first.
+func (im *instanceManager) stopInstances(ctx context.Context, insts []*instance.Instance) bool {
+	workers := im.globalConfig.Instances.ShutdownParallelism
+	if workers <= 0 || workers > len(insts) {
+		workers = len(insts)
+	}
+
+	queue := make(chan *instance.Instance)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for inst := range queue {
 				fmt.Printf("Stopping instance %s...\n", inst.Name)
 				if err := inst.Stop(); err != nil {
 					log.Printf("Error stopping instance %s: %v\n", inst.Name, err)
 				}
-			}(inst)
+			}
+		}()
+	}
+
+enqueue:
+	for _, inst := range insts {
+		select {
+		case queue <- inst:
+		case <-ctx.Done():
+			break enqueue
 		}
+	}
+	close(queue)
+
+	stopped := make(chan struct{})
+	go func() {
 		wg.Wait()
-		fmt.Println("All instances stopped.")
-	})
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 // loadInstances restores all instances from the persistence layer
@@ -175,11 +286,21 @@ func (im *instanceManager) loadInstance(persistedInst *instance.Instance) error
 
 	// Create new inst using NewInstance (handles validation, defaults, setup)
 	inst := instance.New(name, im.globalConfig, options, statusCallback)
+	im.applyRestartBudget(inst)
 
 	// Restore persisted fields that NewInstance doesn't set
 	inst.ID = persistedInst.ID
 	inst.Created = persistedInst.Created
-	inst.SetStatus(persistedInst.GetStatus())
+	inst.SetDesiredState(persistedInst.GetDesiredState())
+	inst.SetStateTimestamps(persistedInst.GetStateTimestamps())
+
+	// A local process can't have survived llamactl going away, so the
+	// persisted status is stale; autoStartInstances reconciles it with the
+	// desired state. Remote stubs mirror their node and keep the last status.
+	if isRemote {
+		inst.SetStatus(persistedInst.GetStatus())
+		inst.SetStateTimestamps(persistedInst.GetStateTimestamps())
+	}
 
 	// Handle remote instance mapping
 	if isRemote {
@@ -188,6 +309,8 @@ func (im *instanceManager) loadInstance(persistedInst *instance.Instance) error
 			return fmt.Errorf("failed to set instance node: %w", err)
 		}
 	} else {
+		im.cleanupOrphan(inst)
+
 		// Allocate port for local instances
 		if inst.GetPort() > 0 {
 			port := inst.GetPort()
@@ -205,40 +328,81 @@ func (im *instanceManager) loadInstance(persistedInst *instance.Instance) error
 	return nil
 }
 
-// autoStartInstances starts instances that were running when persisted and have auto-restart enabled
-// For instances with auto-restart disabled, it sets their status to Stopped
+// cleanupOrphan deals with a backend process that a previous llamactl left
+// running, e.g. after a crash. Left alone it would keep holding the
+// instance's port and GPU memory, so starting the instance again would fail.
+func (im *instanceManager) cleanupOrphan(inst *instance.Instance) {
+	pid := inst.DetectOrphan()
+	if pid == 0 {
+		return
+	}
+
+	if !im.globalConfig.Instances.CleanupOrphansOnStart {
+		log.Printf("Warning: instance %s has a backend process (pid %d) left over from a previous run; enable cleanup_orphans_on_start to kill it automatically", inst.Name, pid)
+		return
+	}
+
+	if err := inst.KillOrphan(pid); err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	log.Printf("Killed orphaned backend process %d of instance %s", pid, inst.Name)
+}
+
+// autoStartInstances drives loaded instances towards their desired state:
+// instances that should be running are started if auto-restart is enabled.
+// Those with auto-restart disabled are left stopped and their intent cleared.
 func (im *instanceManager) autoStartInstances() {
 	instances := im.registry.list()
 
 	var instancesToStart []*instance.Instance
-	var instancesToStop []*instance.Instance
 
 	for _, inst := range instances {
-		if inst.IsRunning() && // Was running when persisted
-			inst.GetOptions() != nil &&
-			inst.GetOptions().AutoRestart != nil {
-			if *inst.GetOptions().AutoRestart {
-				instancesToStart = append(instancesToStart, inst)
-			} else {
-				// Instance was running but auto-restart is disabled, mark as stopped
-				instancesToStop = append(instancesToStop, inst)
+		if inst.GetDesiredState() != instance.DesiredRunning {
+			continue
+		}
+
+		opts := inst.GetOptions()
+		if opts == nil || opts.AutoRestart == nil || !*opts.AutoRestart {
+			log.Printf("Instance %s should be running but auto-restart is disabled, leaving it stopped", inst.Name)
+			inst.SetStatus(instance.Stopped)
+			im.registry.markStopped(inst.Name)
+			inst.SetDesiredState(instance.DesiredStopped)
+			if err := im.persistInstance(inst); err != nil {
+				log.Printf("Warning: failed to persist instance %s: %v", inst.Name, err)
 			}
+			continue
 		}
+
+		instancesToStart = append(instancesToStart, inst)
 	}
 
-	// Stop instances that have auto-restart disabled
-	for _, inst := range instancesToStop {
-		log.Printf("Instance %s was running but auto-restart is disabled, setting status to stopped", inst.Name)
+	// Reset running state up front (Start() expects a stopped instance), so
+	// instances still waiting their turn don't report a stale running status
+	for _, inst := range instancesToStart {
 		inst.SetStatus(instance.Stopped)
 		im.registry.markStopped(inst.Name)
 	}
 
-	// Start instances that have auto-restart enabled
-	for _, inst := range instancesToStart {
+	// Start higher priority instances first; ties are broken by name so the
+	// order is stable across restarts. Dependencies always come first.
+	slices.SortStableFunc(instancesToStart, compareStartOrder)
+	instancesToStart = sortByDependencies(instancesToStart)
+
+	delay := time.Duration(im.globalConfig.Instances.AutoStartDelay) * time.Second
+
+	// Start instances that have auto-restart enabled, one at a time so many
+	// instances coming up on boot don't all compete for the GPUs at once
+	for i, inst := range instancesToStart {
+		if i > 0 && delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-im.shutdown:
+				return
+			}
+		}
+
 		log.Printf("Auto-starting instance %s", inst.Name)
-		// Reset running state before starting (since Start() expects stopped instance)
-		inst.SetStatus(instance.Stopped)
-		im.registry.markStopped(inst.Name)
 
 		// Check if this is a remote instance
 		if node, exists := im.remote.getNodeForInstance(inst.Name); exists && node != nil {
@@ -248,20 +412,89 @@ func (im *instanceManager) autoStartInstances() {
 				log.Printf("Failed to auto-start remote instance %s: %v", inst.Name, err)
 			}
 		} else {
-			// Local instance - call Start() directly
+			// Local instance - make sure its dependencies are healthy, then
+			// call Start() directly
+			if err := im.startDependencies(inst); err != nil {
+				log.Printf("Failed to auto-start instance %s: %v", inst.Name, err)
+				continue
+			}
+			if err := im.ensureVRAMBudget(inst); err != nil {
+				log.Printf("Failed to auto-start instance %s: %v", inst.Name, err)
+				continue
+			}
+			if err := im.resolveDraftInstance(inst); err != nil {
+				log.Printf("Failed to auto-start instance %s: %v", inst.Name, err)
+				continue
+			}
 			if err := inst.Start(); err != nil {
 				log.Printf("Failed to auto-start instance %s: %v", inst.Name, err)
+				continue
+			}
+			im.warmupAfterStart(inst)
+			if im.globalConfig.Instances.AutoStartWaitHealthy {
+				if err := inst.WaitForHealthy(im.globalConfig.Instances.OnDemandStartTimeout); err != nil {
+					log.Printf("Auto-started instance %s did not become healthy: %v", inst.Name, err)
+				}
 			}
 		}
 	}
 }
 
-func (im *instanceManager) onStatusChange(name string, _, newStatus instance.Status) {
+// compareStartOrder orders instances by descending start priority, then name
+func compareStartOrder(a, b *instance.Instance) int {
+	if c := cmp.Compare(startPriority(b), startPriority(a)); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Name, b.Name)
+}
+
+// startPriority returns the instance's auto-start priority (default 0)
+func startPriority(inst *instance.Instance) int {
+	opts := inst.GetOptions()
+	if opts == nil || opts.StartPriority == nil {
+		return 0
+	}
+	return *opts.StartPriority
+}
+
+func (im *instanceManager) onStatusChange(name string, oldStatus, newStatus instance.Status) {
 	if newStatus == instance.Running {
 		im.registry.markRunning(name)
 	} else {
 		im.registry.markStopped(name)
 	}
+
+	if newStatus == instance.Failed && oldStatus != instance.Failed {
+		im.notifyFailed(name)
+	}
+
+	// Persist the state change timestamps, including for crashes that no
+	// API call would otherwise save. Deleted instances are skipped so this
+	// can't bring them back.
+	if inst, ok := im.registry.get(name); ok {
+		if err := im.persistInstance(inst); err != nil {
+			log.Printf("Warning: failed to persist status of instance %s: %v", name, err)
+		}
+	}
+}
+
+// notifyFailed sends a webhook notification for an instance that crashed
+// and will not be restarted. Runs from the status-change path, so it must
+// not block: the webhook delivers in the background.
+func (im *instanceManager) notifyFailed(name string) {
+	if im.webhook == nil {
+		return
+	}
+
+	ev := notify.Event{Event: notify.EventInstanceFailed, Instance: name}
+	if inst, ok := im.registry.get(name); ok {
+		if exit := inst.LastExit(); exit != nil {
+			ev.Error = exit.Error
+			ev.ExitCode = &exit.ExitCode
+			ev.Timestamp = exit.Time.UTC()
+		}
+	}
+	im.webhook.Send(ev)
 }
 
 // getNodeForInstance returns the node configuration for a remote instance
@@ -1,13 +1,24 @@
 package manager_test
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"llamactl/pkg/backends"
 	"llamactl/pkg/config"
 	"llamactl/pkg/database"
 	"llamactl/pkg/instance"
 	"llamactl/pkg/manager"
+	"llamactl/pkg/testutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -33,6 +44,7 @@ func TestManager_PersistsAndLoadsInstances(t *testing.T) {
 	}
 	manager1 := manager.New(appConfig, db1)
 	options := &instance.Options{
+		WorkingDir: tempDir,
 		BackendOptions: backends.Options{
 			BackendType: backends.BackendTypeLlamaCpp,
 			LlamaServerOptions: &backends.LlamaServerOptions{
@@ -74,6 +86,9 @@ func TestManager_PersistsAndLoadsInstances(t *testing.T) {
 	if instances[0].Name != "test-instance" {
 		t.Errorf("Expected loaded instance name 'test-instance', got %q", instances[0].Name)
 	}
+	if got := instances[0].GetOptions().WorkingDir; got != tempDir {
+		t.Errorf("Expected loaded working_dir %q, got %q", tempDir, got)
+	}
 
 	manager2.Shutdown()
 }
@@ -82,18 +97,7 @@ func TestDeleteInstance_RemovesFromDatabase(t *testing.T) {
 	tempDir := t.TempDir()
 	appConfig := createTestAppConfig(tempDir)
 
-	db, err := database.Open(&database.Config{
-		Path:               appConfig.Database.Path,
-		MaxOpenConnections: appConfig.Database.MaxOpenConnections,
-		MaxIdleConnections: appConfig.Database.MaxIdleConnections,
-		ConnMaxLifetime:    appConfig.Database.ConnMaxLifetime,
-	})
-	if err != nil {
-		t.Fatalf("Failed to open database: %v", err)
-	}
-	if err := database.RunMigrations(db); err != nil {
-		t.Fatalf("Failed to run migrations: %v", err)
-	}
+	db := database.NewMemoryStore()
 	mgr := manager.New(appConfig, db)
 	defer mgr.Shutdown()
 
@@ -107,7 +111,7 @@ func TestDeleteInstance_RemovesFromDatabase(t *testing.T) {
 		},
 	}
 
-	_, err = mgr.CreateInstance("test-instance", options)
+	_, err := mgr.CreateInstance("test-instance", options)
 	if err != nil {
 		t.Fatalf("CreateInstance failed: %v", err)
 	}
@@ -185,7 +189,589 @@ func TestConcurrentAccess(t *testing.T) {
 	}
 }
 
+func TestAutoStart_StartsInPriorityOrder(t *testing.T) {
+	tempDir := t.TempDir()
+	appConfig := createTestAppConfig(tempDir)
+	appConfig.Database.Path = tempDir + "/test.db"
+	// exec so the stop signal reaches sleep directly and shutdown is quick
+	appConfig.Backends.LlamaCpp.Args = []string{"-c", "exec sleep 999999"}
+	appConfig.Instances.AutoStartDelay = 2
+
+	db := database.NewMemoryStore()
+
+	// Persist two instances that should be running when llamactl starts
+	autoRestart := true
+	for i, tc := range []struct {
+		name     string
+		priority int
+	}{{"low", 1}, {"high", 10}} {
+		priority := tc.priority
+		inst := instance.New(tc.name, appConfig, &instance.Options{
+			AutoRestart:   &autoRestart,
+			StartPriority: &priority,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+					Port:  8100 + i,
+				},
+			},
+		}, nil)
+		inst.SetDesiredState(instance.DesiredRunning)
+		if err := db.Save(inst); err != nil {
+			t.Fatalf("Failed to persist instance %s: %v", tc.name, err)
+		}
+	}
+
+	mgr := manager.New(appConfig, db)
+	defer mgr.Shutdown()
+
+	low, err := mgr.GetInstance("low")
+	if err != nil {
+		t.Fatalf("GetInstance failed: %v", err)
+	}
+	high, err := mgr.GetInstance("high")
+	if err != nil {
+		t.Fatalf("GetInstance failed: %v", err)
+	}
+
+	waitFor := func(cond func() bool, timeout time.Duration) bool {
+		deadline := time.Now().Add(timeout)
+		for time.Now().Before(deadline) {
+			if cond() {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	if !waitFor(func() bool { return high.IsRunning() && !low.IsRunning() }, time.Second) {
+		t.Fatalf("Expected high priority instance to start first (high running: %v, low running: %v)", high.IsRunning(), low.IsRunning())
+	}
+	if !waitFor(low.IsRunning, 5*time.Second) {
+		t.Fatal("Expected low priority instance to start after the auto-start delay")
+	}
+}
+
+func TestShutdownWithContext_ForceKillsAfterDeadline(t *testing.T) {
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Instances.ShutdownParallelism = 1
+	// Ignore SIGINT so a graceful stop would only complete after the 30s
+	// force-kill timeout
+	appConfig.Backends.LlamaCpp.Args = []string{"-c", "trap '' INT; exec sleep 999999"}
+	db := database.NewMemoryStore()
+	mgr := manager.New(appConfig, db)
+
+	var started []*instance.Instance
+	for _, name := range []string{"first", "second"} {
+		_, err := mgr.CreateInstance(name, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CreateInstance failed: %v", err)
+		}
+		inst, err := mgr.StartInstance(name)
+		if err != nil {
+			t.Fatalf("StartInstance failed: %v", err)
+		}
+		started = append(started, inst)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	mgr.ShutdownWithContext(ctx)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Shutdown took %v, expected it to end shortly after the deadline", elapsed)
+	}
+
+	for _, inst := range started {
+		if inst.IsRunning() {
+			t.Errorf("Expected instance %s to be stopped after shutdown", inst.Name)
+		}
+	}
+}
+
+func TestFailedInstance_SendsWebhook(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode webhook payload: %v", err)
+		}
+		received <- payload
+	}))
+	defer srv.Close()
+
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Notifications.WebhookURL = srv.URL
+	appConfig.Backends.LlamaCpp.Args = []string{"-c", "sleep 0.2; exit 3"}
+	db := database.NewMemoryStore()
+	mgr := manager.New(appConfig, db)
+	defer mgr.Shutdown()
+
+	autoRestart := false
+	_, err := mgr.CreateInstance("crashy", &instance.Options{
+		AutoRestart: &autoRestart,
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if _, err := mgr.StartInstance("crashy"); err != nil {
+		t.Fatalf("StartInstance failed: %v", err)
+	}
+
+	select {
+	case payload := <-received:
+		if payload["event"] != "instance_failed" {
+			t.Errorf("Expected event instance_failed, got %v", payload["event"])
+		}
+		if payload["instance"] != "crashy" {
+			t.Errorf("Expected instance crashy, got %v", payload["instance"])
+		}
+		if payload["exit_code"] != float64(3) {
+			t.Errorf("Expected exit_code 3, got %v", payload["exit_code"])
+		}
+		if payload["error"] != "exit status 3" {
+			t.Errorf("Expected error 'exit status 3', got %v", payload["error"])
+		}
+		if _, ok := payload["timestamp"].(string); !ok {
+			t.Errorf("Expected timestamp in payload, got %v", payload["timestamp"])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected webhook notification for failed instance")
+	}
+}
+
+func TestRestartBudget_DefersRestarts(t *testing.T) {
+	// Every start appends a line, then the backend crashes right away
+	counter := filepath.Join(t.TempDir(), "starts")
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Instances.MaxRestartsPerMinute = 2
+	appConfig.Backends.LlamaCpp.Args = []string{"-c", fmt.Sprintf("echo start >> %s; exit 1", counter)}
+	db := database.NewMemoryStore()
+	mgr := manager.New(appConfig, db)
+	defer mgr.Shutdown()
+
+	names := []string{"crash-a", "crash-b", "crash-c"}
+	for _, name := range names {
+		_, err := mgr.CreateInstance(name, &instance.Options{
+			AutoRestart:  testutil.BoolPtr(true),
+			MaxRestarts:  testutil.IntPtr(5),
+			RestartDelay: testutil.IntPtr(0),
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CreateInstance(%s) failed: %v", name, err)
+		}
+		if _, err := mgr.StartInstance(name); err != nil {
+			t.Fatalf("StartInstance(%s) failed: %v", name, err)
+		}
+	}
+
+	// Three manual starts plus the two restarts the budget allows
+	countStarts := func() int {
+		data, _ := os.ReadFile(counter)
+		return strings.Count(string(data), "start")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for countStarts() < 5 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	if got := countStarts(); got != 5 {
+		t.Errorf("Expected 5 process starts within the budget, got %d", got)
+	}
+	for _, name := range names {
+		inst, err := mgr.GetInstance(name)
+		if err != nil {
+			t.Fatalf("GetInstance(%s) failed: %v", name, err)
+		}
+		if status := inst.GetStatus(); status != instance.Restarting {
+			t.Errorf("Expected %s to wait in restarting, got %v", name, status)
+		}
+	}
+}
+
+func TestProbeNode(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer node-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/nodes":
+			fmt.Fprint(w, `{}`)
+		case "/api/v1/version":
+			fmt.Fprint(w, `{"version":"v1.2.3"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer node.Close()
+
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Nodes = map[string]config.NodeConfig{
+		"main":      {},
+		"worker":    {Address: node.URL, APIKey: "node-key"},
+		"wrong-key": {Address: node.URL, APIKey: "other-key"},
+		"offline":   {Address: "http://127.0.0.1:1"},
+	}
+	db := database.NewMemoryStore()
+	mgr := manager.New(appConfig, db)
+	defer mgr.Shutdown()
+
+	t.Run("reachable with valid key", func(t *testing.T) {
+		result, err := mgr.ProbeNode("worker")
+		if err != nil {
+			t.Fatalf("ProbeNode failed: %v", err)
+		}
+		if !result.Reachable || !result.Authenticated || result.Version != "v1.2.3" || result.Error != "" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("rejected key", func(t *testing.T) {
+		result, _ := mgr.ProbeNode("wrong-key")
+		if !result.Reachable || result.Authenticated || result.Error == "" {
+			t.Errorf("Expected reachable but unauthenticated, got %+v", result)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		result, _ := mgr.ProbeNode("offline")
+		if result.Reachable || result.Error == "" {
+			t.Errorf("Expected unreachable with error, got %+v", result)
+		}
+	})
+
+	t.Run("local node", func(t *testing.T) {
+		result, _ := mgr.ProbeNode("main")
+		if !result.Local || !result.Reachable {
+			t.Errorf("Expected local node to be reachable, got %+v", result)
+		}
+	})
+
+	t.Run("unknown node", func(t *testing.T) {
+		if _, err := mgr.ProbeNode("missing"); err == nil {
+			t.Error("Expected error for unknown node")
+		}
+	})
+}
+
+func TestDesiredState_Reconciliation(t *testing.T) {
+	newConfig := func(t *testing.T) *config.AppConfig {
+		tempDir := t.TempDir()
+		appConfig := createTestAppConfig(tempDir)
+		appConfig.Database.Path = tempDir + "/test.db"
+		appConfig.Backends.LlamaCpp.Args = []string{"-c", "exec sleep 999999"}
+		return appConfig
+	}
+
+	// Each manager gets its own connection, like a fresh llamactl process
+	newManager := func(t *testing.T, appConfig *config.AppConfig) (manager.InstanceManager, database.InstanceStore) {
+		db, err := database.Open(&database.Config{
+			Path:               appConfig.Database.Path,
+			MaxOpenConnections: appConfig.Database.MaxOpenConnections,
+			MaxIdleConnections: appConfig.Database.MaxIdleConnections,
+			ConnMaxLifetime:    appConfig.Database.ConnMaxLifetime,
+		})
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		if err := database.RunMigrations(db); err != nil {
+			t.Fatalf("Failed to run migrations: %v", err)
+		}
+		mgr := manager.New(appConfig, db)
+		t.Cleanup(mgr.Shutdown)
+		return mgr, db
+	}
+
+	options := func() *instance.Options {
+		autoRestart := true
+		return &instance.Options{
+			AutoRestart: &autoRestart,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}
+	}
+
+	waitRunning := func(t *testing.T, mgr manager.InstanceManager, name string, timeout time.Duration) bool {
+		deadline := time.Now().Add(timeout)
+		for time.Now().Before(deadline) {
+			if inst, err := mgr.GetInstance(name); err == nil && inst.IsRunning() {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+
+	t.Run("running instance comes back after restart", func(t *testing.T) {
+		appConfig := newConfig(t)
+		mgr1, _ := newManager(t, appConfig)
+		if _, err := mgr1.CreateInstance("inst", options()); err != nil {
+			t.Fatalf("CreateInstance failed: %v", err)
+		}
+		if _, err := mgr1.StartInstance("inst"); err != nil {
+			t.Fatalf("StartInstance failed: %v", err)
+		}
+		mgr1.Shutdown()
+
+		mgr2, _ := newManager(t, appConfig)
+		if !waitRunning(t, mgr2, "inst", 3*time.Second) {
+			t.Error("Expected instance to be started again after restart")
+		}
+	})
+
+	t.Run("explicit stop is remembered", func(t *testing.T) {
+		appConfig := newConfig(t)
+		mgr1, _ := newManager(t, appConfig)
+		if _, err := mgr1.CreateInstance("inst", options()); err != nil {
+			t.Fatalf("CreateInstance failed: %v", err)
+		}
+		if _, err := mgr1.StartInstance("inst"); err != nil {
+			t.Fatalf("StartInstance failed: %v", err)
+		}
+		inst, err := mgr1.StopInstance("inst")
+		if err != nil {
+			t.Fatalf("StopInstance failed: %v", err)
+		}
+		if inst.GetDesiredState() != instance.DesiredStopped {
+			t.Errorf("Expected desired state stopped after stop, got %s", inst.GetDesiredState())
+		}
+		mgr1.Shutdown()
+
+		mgr2, _ := newManager(t, appConfig)
+		if waitRunning(t, mgr2, "inst", 200*time.Millisecond) {
+			t.Error("Expected explicitly stopped instance to stay stopped")
+		}
+	})
+
+	t.Run("crashed instance is recovered", func(t *testing.T) {
+		appConfig := newConfig(t)
+		mgr1, db := newManager(t, appConfig)
+		inst, err := mgr1.CreateInstance("inst", options())
+		if err != nil {
+			t.Fatalf("CreateInstance failed: %v", err)
+		}
+
+		// Simulate a crash: the process died (status failed) while the
+		// user still wanted it running
+		inst.SetDesiredState(instance.DesiredRunning)
+		inst.SetStatus(instance.Failed)
+		if err := db.Save(inst); err != nil {
+			t.Fatalf("Failed to persist instance: %v", err)
+		}
+		mgr1.Shutdown()
+
+		mgr2, _ := newManager(t, appConfig)
+		if !waitRunning(t, mgr2, "inst", 3*time.Second) {
+			t.Error("Expected crashed instance to be started to match its desired state")
+		}
+	})
+
+	t.Run("stale running status without intent stays stopped", func(t *testing.T) {
+		appConfig := newConfig(t)
+		mgr1, db := newManager(t, appConfig)
+		inst, err := mgr1.CreateInstance("inst", options())
+		if err != nil {
+			t.Fatalf("CreateInstance failed: %v", err)
+		}
+		inst.SetStatus(instance.Running)
+		if err := db.Save(inst); err != nil {
+			t.Fatalf("Failed to persist instance: %v", err)
+		}
+		inst.SetStatus(instance.Stopped)
+		mgr1.Shutdown()
+
+		mgr2, _ := newManager(t, appConfig)
+		loaded, err := mgr2.GetInstance("inst")
+		if err != nil {
+			t.Fatalf("GetInstance failed: %v", err)
+		}
+		if loaded.IsRunning() {
+			t.Error("Expected persisted running status to be treated as stale")
+		}
+		if waitRunning(t, mgr2, "inst", 200*time.Millisecond) {
+			t.Error("Expected instance without desired running state not to be started")
+		}
+	})
+}
+
+func TestCleanupOrphansOnStart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("orphan detection is not supported on Windows")
+	}
+
+	newManager := func(t *testing.T, appConfig *config.AppConfig) manager.InstanceManager {
+		db, err := database.Open(&database.Config{
+			Path:               appConfig.Database.Path,
+			MaxOpenConnections: appConfig.Database.MaxOpenConnections,
+			MaxIdleConnections: appConfig.Database.MaxIdleConnections,
+			ConnMaxLifetime:    appConfig.Database.ConnMaxLifetime,
+		})
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		if err := database.RunMigrations(db); err != nil {
+			t.Fatalf("Failed to run migrations: %v", err)
+		}
+		mgr := manager.New(appConfig, db)
+		t.Cleanup(mgr.Shutdown)
+		return mgr
+	}
+
+	// startOrphan starts an instance under a manager that is then abandoned
+	// without shutdown, like a llamactl that crashed
+	startOrphan := func(t *testing.T, appConfig *config.AppConfig) *instance.Instance {
+		mgr := newManager(t, appConfig)
+		autoRestart := false
+		_, err := mgr.CreateInstance("orphan", &instance.Options{
+			AutoRestart: &autoRestart,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CreateInstance failed: %v", err)
+		}
+		inst, err := mgr.StartInstance("orphan")
+		if err != nil {
+			t.Fatalf("StartInstance failed: %v", err)
+		}
+		return inst
+	}
+
+	newConfig := func(t *testing.T) *config.AppConfig {
+		tempDir := t.TempDir()
+		appConfig := createTestAppConfig(tempDir)
+		appConfig.Database.Path = tempDir + "/test.db"
+		appConfig.Instances.InstancesDir = tempDir
+		return appConfig
+	}
+
+	t.Run("kills leftover process when enabled", func(t *testing.T) {
+		appConfig := newConfig(t)
+		orphan := startOrphan(t, appConfig)
+
+		appConfig.Instances.CleanupOrphansOnStart = true
+		newManager(t, appConfig)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for orphan.IsRunning() {
+			if time.Now().After(deadline) {
+				t.Fatal("Expected orphaned backend process to be killed on startup")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("leaves leftover process alone when disabled", func(t *testing.T) {
+		appConfig := newConfig(t)
+		orphan := startOrphan(t, appConfig)
+
+		newManager(t, appConfig)
+
+		time.Sleep(200 * time.Millisecond)
+		if !orphan.IsRunning() {
+			t.Error("Expected orphaned backend process to keep running when cleanup is disabled")
+		}
+	})
+}
+
 // Helper functions for test configuration
+func TestListInstancesByStatus(t *testing.T) {
+	// The remote node reports its instance as stopped when it is created and
+	// running afterwards
+	var remoteStatus atomic.Value
+	remoteStatus.Store("stopped")
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"name":"remote","status":%q,"options":{"backend_type":"llama_cpp","backend_options":{"model":"/path/to/model.gguf"},"nodes":["worker"]}}`, remoteStatus.Load())
+	}))
+	defer node.Close()
+
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Nodes = map[string]config.NodeConfig{
+		"main":   {},
+		"worker": {Address: node.URL},
+	}
+	mgr := manager.New(appConfig, database.NewMemoryStore())
+	defer mgr.Shutdown()
+
+	for _, name := range []string{"local-running", "local-stopped"} {
+		if _, err := mgr.CreateInstance(name, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+			},
+		}); err != nil {
+			t.Fatalf("CreateInstance %s failed: %v", name, err)
+		}
+	}
+	if _, err := mgr.CreateInstance("remote", &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+		},
+		Nodes: map[string]struct{}{"worker": {}},
+	}); err != nil {
+		t.Fatalf("CreateInstance remote failed: %v", err)
+	}
+
+	running, _ := mgr.GetInstance("local-running")
+	running.SetStatus(instance.Running)
+	defer running.SetStatus(instance.Stopped)
+	remoteStatus.Store("running")
+
+	names := func(status instance.Status) []string {
+		instances, err := mgr.ListInstancesByStatus(status)
+		if err != nil {
+			t.Fatalf("ListInstancesByStatus failed: %v", err)
+		}
+		var names []string
+		for _, inst := range instances {
+			names = append(names, inst.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+
+	if got := names(instance.Running); !slices.Equal(got, []string{"local-running", "remote"}) {
+		t.Errorf("Expected running instances [local-running remote], got %v", got)
+	}
+	if got := names(instance.Stopped); !slices.Equal(got, []string{"local-stopped"}) {
+		t.Errorf("Expected stopped instances [local-stopped], got %v", got)
+	}
+	if got := names(instance.Failed); len(got) != 0 {
+		t.Errorf("Expected no failed instances, got %v", got)
+	}
+}
+
 func createTestAppConfig(instancesDir string) *config.AppConfig {
 	// Use 'sh -c "sleep 999999"' as a test command instead of 'llama-server'
 	// The shell ignores all additional arguments passed after the command
@@ -225,17 +811,102 @@ func createTestAppConfig(instancesDir string) *config.AppConfig {
 func createTestManager(t *testing.T) manager.InstanceManager {
 	tempDir := t.TempDir()
 	appConfig := createTestAppConfig(tempDir)
-	db, err := database.Open(&database.Config{
-		Path:               appConfig.Database.Path,
-		MaxOpenConnections: appConfig.Database.MaxOpenConnections,
-		MaxIdleConnections: appConfig.Database.MaxIdleConnections,
-		ConnMaxLifetime:    appConfig.Database.ConnMaxLifetime,
-	})
+	db := database.NewMemoryStore()
+	return manager.New(appConfig, db)
+}
+
+func TestReconcileDeclarative(t *testing.T) {
+	dir := t.TempDir()
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.DataDir = t.TempDir()
+	appConfig.Instances.DeclarativeDir = dir
+	mgr := manager.New(appConfig, database.NewMemoryStore())
+	defer mgr.Shutdown()
+
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reconcile := func() *manager.DeclarativeResult {
+		t.Helper()
+		result, err := mgr.ReconcileDeclarative()
+		if err != nil {
+			t.Fatalf("ReconcileDeclarative failed: %v", err)
+		}
+		return result
+	}
+
+	// An instance created through the API is not touched
+	if _, err := mgr.CreateInstance("manual", &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/manual.gguf"},
+		},
+	}); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	writeFile("chat.yaml", "backend_type: llama_cpp\nbackend_options:\n  model: /path/to/chat.gguf\n  ctx_size: 4096\n")
+	writeFile("embed.yml", "backend_type: llama_cpp\nbackend_options:\n  model: /path/to/embed.gguf\n")
+	writeFile("README.md", "not an instance")
+
+	result := reconcile()
+	if !slices.Equal(result.Created, []string{"chat", "embed"}) || len(result.Updated)+len(result.Deleted) != 0 {
+		t.Fatalf("Expected chat and embed to be created, got %+v", result)
+	}
+	chat, err := mgr.GetInstance("chat")
 	if err != nil {
-		t.Fatalf("Failed to open database: %v", err)
+		t.Fatalf("GetInstance failed: %v", err)
 	}
-	if err := database.RunMigrations(db); err != nil {
-		t.Fatalf("Failed to run migrations: %v", err)
+	if got := chat.GetOptions().BackendOptions.LlamaServerOptions.CtxSize; got != 4096 {
+		t.Errorf("Expected ctx_size 4096 from the file, got %d", got)
 	}
-	return manager.New(appConfig, db)
+
+	t.Run("unchanged files are not applied again", func(t *testing.T) {
+		result := reconcile()
+		if len(result.Created)+len(result.Updated)+len(result.Deleted) != 0 {
+			t.Errorf("Expected no changes, got %+v", result)
+		}
+	})
+
+	t.Run("changed file updates the instance", func(t *testing.T) {
+		writeFile("chat.yaml", "backend_type: llama_cpp\nbackend_options:\n  model: /path/to/chat.gguf\n  ctx_size: 8192\n")
+		result := reconcile()
+		if !slices.Equal(result.Updated, []string{"chat"}) {
+			t.Fatalf("Expected chat to be updated, got %+v", result)
+		}
+		chat, _ := mgr.GetInstance("chat")
+		if got := chat.GetOptions().BackendOptions.LlamaServerOptions.CtxSize; got != 8192 {
+			t.Errorf("Expected ctx_size 8192 after the update, got %d", got)
+		}
+	})
+
+	t.Run("invalid file keeps the instance", func(t *testing.T) {
+		writeFile("embed.yml", "backend_type: [")
+		result := reconcile()
+		if _, ok := result.Errors["embed"]; !ok {
+			t.Errorf("Expected an error for embed, got %+v", result)
+		}
+		if _, err := mgr.GetInstance("embed"); err != nil {
+			t.Errorf("Expected embed to be kept while its file is invalid: %v", err)
+		}
+	})
+
+	t.Run("removed file deletes only declared instances", func(t *testing.T) {
+		if err := os.Remove(filepath.Join(dir, "embed.yml")); err != nil {
+			t.Fatal(err)
+		}
+		result := reconcile()
+		if !slices.Equal(result.Deleted, []string{"embed"}) {
+			t.Fatalf("Expected embed to be deleted, got %+v", result)
+		}
+		if _, err := mgr.GetInstance("embed"); err == nil {
+			t.Error("Expected embed to be gone")
+		}
+		if _, err := mgr.GetInstance("manual"); err != nil {
+			t.Errorf("Expected the API-created instance to be kept: %v", err)
+		}
+	})
 }
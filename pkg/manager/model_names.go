@@ -0,0 +1,57 @@
+package manager
+
+import (
+	"llamactl/pkg/apierrors"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/instance"
+	"slices"
+)
+
+// servedModelNames returns the model names clients can put in the model field
+// of an OpenAI request to reach an instance with these options, other than
+// the instance name itself: the llama.cpp alias and any router models.
+func servedModelNames(options *instance.Options) []string {
+	var names []string
+	if options.BackendOptions.BackendType == backends.BackendTypeLlamaCpp {
+		if lo := options.BackendOptions.LlamaServerOptions; lo != nil && lo.Alias != "" {
+			names = append(names, lo.Alias)
+		}
+	}
+	for _, m := range options.RouterModels {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// validateModelNames rejects options that would make OpenAI routing by model
+// ambiguous: a served model name already served by another instance, a served
+// model name equal to another instance's name, or an instance name that
+// another instance already serves as a model.
+func (im *instanceManager) validateModelNames(name string, options *instance.Options) error {
+	served := servedModelNames(options)
+
+	for _, other := range im.registry.list() {
+		if other.Name == name {
+			continue
+		}
+		otherOpts := other.GetOptions()
+		if otherOpts == nil {
+			continue
+		}
+		otherServed := servedModelNames(otherOpts)
+
+		for _, model := range served {
+			if model == other.Name {
+				return apierrors.Newf(apierrors.ErrInvalidOptions, "model name %q of instance %s conflicts with the name of instance %s", model, name, other.Name)
+			}
+			if slices.Contains(otherServed, model) {
+				return apierrors.Newf(apierrors.ErrInvalidOptions, "model name %q of instance %s is already served by instance %s", model, name, other.Name)
+			}
+		}
+		if slices.Contains(otherServed, name) {
+			return apierrors.Newf(apierrors.ErrInvalidOptions, "instance name %s conflicts with a model name served by instance %s", name, other.Name)
+		}
+	}
+
+	return nil
+}
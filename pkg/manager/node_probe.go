@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"llamactl/pkg/apierrors"
+	"net/http"
+	"time"
+)
+
+// nodeProbeTimeout bounds each request made while probing a node
+const nodeProbeTimeout = 10 * time.Second
+
+// NodeProbeResult reports whether a node is reachable with its configured
+// address and API key
+type NodeProbeResult struct {
+	Node string `json:"node"`
+	// Whether the node is this llamactl, which needs no probing
+	Local bool `json:"local,omitempty"`
+	// Whether the node answered at all
+	Reachable bool `json:"reachable"`
+	// Whether the node accepted the configured API key
+	Authenticated bool `json:"authenticated"`
+	// Round trip time of an authenticated request, in milliseconds
+	LatencyMS int64 `json:"latency_ms"`
+	// llamactl version the node reports
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ProbeNode checks the connection to a node by making authenticated requests
+// to its API, the same way instance operations on that node do
+func (im *instanceManager) ProbeNode(node string) (*NodeProbeResult, error) {
+	nodeConfig, ok := im.globalConfig.Nodes[node]
+	if !ok {
+		return nil, apierrors.Newf(apierrors.ErrNodeNotFound, "node %s not found", node)
+	}
+
+	result := &NodeProbeResult{Node: node}
+	if node == im.globalConfig.LocalNode {
+		result.Local, result.Reachable, result.Authenticated = true, true, true
+		return result, nil
+	}
+	if nodeConfig.Address == "" {
+		result.Error = "node has no address configured"
+		return result, nil
+	}
+
+	// Listing nodes is cheap and needs the management key, so its round
+	// trip time is a fair measure of latency
+	ctx, cancel := context.WithTimeout(context.Background(), nodeProbeTimeout)
+	defer cancel()
+	started := time.Now()
+	resp, err := im.remote.makeRemoteRequest(ctx, &nodeConfig, http.MethodGet, "/api/v1/nodes", nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	result.LatencyMS = time.Since(started).Milliseconds()
+	result.Reachable = true
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		result.Error = "node rejected the configured api_key"
+		return result, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		result.Error = "unexpected response status " + resp.Status
+		return result, nil
+	}
+	result.Authenticated = true
+
+	// The version is informational, so a failure here doesn't fail the probe
+	ctx, cancel = context.WithTimeout(context.Background(), nodeProbeTimeout)
+	defer cancel()
+	if resp, err := im.remote.makeRemoteRequest(ctx, &nodeConfig, http.MethodGet, "/api/v1/version", nil); err == nil {
+		var version struct {
+			Version string `json:"version"`
+		}
+		if parseRemoteResponse(resp, &version) == nil {
+			result.Version = version.Version
+		}
+	}
+
+	return result, nil
+}
@@ -2,13 +2,15 @@ package manager
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"llamactl/pkg/apierrors"
 	"llamactl/pkg/instance"
 	"log"
+	"slices"
+	"strings"
 )
 
-type MaxRunningInstancesError error
-
 // updateLocalInstanceFromRemote updates the local stub instance with data from the remote instance
 func (im *instanceManager) updateLocalInstanceFromRemote(localInst *instance.Instance, remoteInst *instance.Instance) {
 	if localInst == nil || remoteInst == nil {
@@ -23,6 +25,7 @@ func (im *instanceManager) updateLocalInstanceFromRemote(localInst *instance.Ins
 	// Update the local instance with all remote data
 	localInst.SetOptions(remoteOptions)
 	localInst.SetStatus(remoteInst.GetStatus())
+	localInst.SetStateTimestamps(remoteInst.GetStateTimestamps())
 	localInst.Created = remoteInst.Created
 }
 
@@ -49,21 +52,93 @@ func (im *instanceManager) ListInstances() ([]*instance.Instance, error) {
 	return instances, nil
 }
 
+// ListInstancesByStatus returns the instances currently in the given status.
+// Remote instances are refreshed first, so they are filtered by the status
+// reported by their node.
+func (im *instanceManager) ListInstancesByStatus(status instance.Status) ([]*instance.Instance, error) {
+	instances, err := im.ListInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(instances, func(inst *instance.Instance) bool {
+		return inst.GetStatus() != status
+	}), nil
+}
+
 // CreateInstance creates a new instance with the given options and returns it.
 // The instance is initially in a "stopped" state.
 func (im *instanceManager) CreateInstance(name string, options *instance.Options) (*instance.Instance, error) {
 	if options == nil {
-		return nil, fmt.Errorf("instance options cannot be nil")
+		return nil, apierrors.Newf(apierrors.ErrInvalidOptions, "instance options cannot be nil")
+	}
+
+	if err := im.checkPlacement(options); err != nil {
+		return nil, err
+	}
+
+	return im.createInstance(name, options)
+}
+
+// createInstance creates the instance without checking whether its node is
+// draining, so a failed migration can put an instance back on its node
+func (im *instanceManager) createInstance(name string, options *instance.Options) (*instance.Instance, error) {
+	if options == nil {
+		return nil, apierrors.Newf(apierrors.ErrInvalidOptions, "instance options cannot be nil")
+	}
+
+	if err := im.resolveModelAliases(options); err != nil {
+		return nil, err
 	}
 
 	err := options.BackendOptions.ValidateInstanceOptions()
 	if err != nil {
+		return nil, apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	if err := im.validateChatTemplateRef(options); err != nil {
+		return nil, err
+	}
+
+	if err := im.validatePlacement(options); err != nil {
+		return nil, err
+	}
+
+	if err := im.validateWorkingDir(options); err != nil {
+		return nil, err
+	}
+
+	if err := options.ValidateSchedule(); err != nil {
+		return nil, apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	if err := options.ValidateRouterModels(); err != nil {
+		return nil, apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	if err := options.ValidateLabels(); err != nil {
+		return nil, apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	if err := options.ValidateStdinData(); err != nil {
+		return nil, apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	if err := im.validateDependencies(name, options); err != nil {
+		return nil, err
+	}
+
+	if err := im.validateDraftInstance(name, options); err != nil {
+		return nil, err
+	}
+
+	if err := im.validateModelNames(name, options); err != nil {
 		return nil, err
 	}
 
 	// Check if instance with this name already exists (must be globally unique)
 	if _, exists := im.registry.get(name); exists {
-		return nil, fmt.Errorf("instance with name %s already exists", name)
+		return nil, apierrors.Newf(apierrors.ErrInstanceExists, "instance with name %s already exists", name)
 	}
 
 	// Check if this is a remote instance (local node not in the Nodes set)
@@ -81,7 +156,7 @@ func (im *instanceManager) CreateInstance(name string, options *instance.Options
 		if !exists {
 			// Try to set the node if it doesn't exist yet
 			if err := im.remote.setInstanceNode(name, nodeName); err != nil {
-				return nil, fmt.Errorf("node %s not found", nodeName)
+				return nil, apierrors.Newf(apierrors.ErrNodeNotFound, "node %s not found", nodeName)
 			}
 			nodeConfig, _ = im.remote.getNodeForInstance(name)
 		}
@@ -119,6 +194,35 @@ func (im *instanceManager) CreateInstance(name string, options *instance.Options
 	}
 
 	// Local instance creation
+	inst, err := im.reserveLocalInstance(name, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// Persist instance (best-effort, don't fail if persistence fails)
+	if err := im.persistInstance(inst); err != nil {
+		log.Printf("Warning: failed to persist instance %s: %v", name, err)
+	}
+
+	return inst, nil
+}
+
+// reserveLocalInstance checks limits, allocates a port and adds a new local
+// instance to the registry as a single atomic step.
+func (im *instanceManager) reserveLocalInstance(name string, options *instance.Options) (*instance.Instance, error) {
+	// Serialize the limit check, port allocation and registry add, otherwise
+	// two concurrent creates can both pass the limit check or claim the same name
+	im.createMu.Lock()
+	defer im.createMu.Unlock()
+
+	if _, exists := im.registry.get(name); exists {
+		return nil, apierrors.Newf(apierrors.ErrInstanceExists, "instance with name %s already exists", name)
+	}
+	// Checked again here as a concurrent create may have claimed a model name
+	if err := im.validateModelNames(name, options); err != nil {
+		return nil, err
+	}
+
 	// Check max instances limit for local instances only
 	totalInstances := im.registry.count()
 	remoteCount := 0
@@ -129,7 +233,7 @@ func (im *instanceManager) CreateInstance(name string, options *instance.Options
 	}
 	localInstanceCount := totalInstances - remoteCount
 	if localInstanceCount >= im.globalConfig.Instances.MaxInstances && im.globalConfig.Instances.MaxInstances != -1 {
-		return nil, fmt.Errorf("maximum number of instances (%d) reached", im.globalConfig.Instances.MaxInstances)
+		return nil, apierrors.Newf(apierrors.ErrMaxInstances, "maximum number of instances (%d) reached", im.globalConfig.Instances.MaxInstances)
 	}
 
 	// Assign and validate port for backend-specific options
@@ -137,6 +241,7 @@ func (im *instanceManager) CreateInstance(name string, options *instance.Options
 	var allocatedPort int
 	if currentPort == 0 {
 		// Allocate a port if not specified
+		var err error
 		allocatedPort, err = im.ports.allocate(name)
 		if err != nil {
 			return nil, fmt.Errorf("failed to allocate port: %w", err)
@@ -145,7 +250,7 @@ func (im *instanceManager) CreateInstance(name string, options *instance.Options
 	} else {
 		// Use the specified port
 		if err := im.ports.allocateSpecific(currentPort, name); err != nil {
-			return nil, fmt.Errorf("port %d is already in use: %w", currentPort, err)
+			return nil, apierrors.Newf(apierrors.ErrPortInUse, "port %d is already in use: %w", currentPort, err)
 		}
 		allocatedPort = currentPort
 	}
@@ -155,6 +260,7 @@ func (im *instanceManager) CreateInstance(name string, options *instance.Options
 	}
 
 	inst := instance.New(name, im.globalConfig, options, statusCallback)
+	im.applyRestartBudget(inst)
 
 	// Add to registry
 	if err := im.registry.add(inst); err != nil {
@@ -163,11 +269,6 @@ func (im *instanceManager) CreateInstance(name string, options *instance.Options
 		return nil, fmt.Errorf("failed to add instance to registry: %w", err)
 	}
 
-	// Persist instance (best-effort, don't fail if persistence fails)
-	if err := im.persistInstance(inst); err != nil {
-		log.Printf("Warning: failed to persist instance %s: %v", name, err)
-	}
-
 	return inst, nil
 }
 
@@ -176,7 +277,7 @@ func (im *instanceManager) CreateInstance(name string, options *instance.Options
 func (im *instanceManager) GetInstance(name string) (*instance.Instance, error) {
 	inst, exists := im.registry.get(name)
 	if !exists {
-		return nil, fmt.Errorf("instance with name %s not found", name)
+		return nil, apierrors.Newf(apierrors.ErrInstanceNotFound, "instance with name %s not found", name)
 	}
 
 	// Check if instance is remote and fetch live state
@@ -197,20 +298,33 @@ func (im *instanceManager) GetInstance(name string) (*instance.Instance, error)
 	return inst, nil
 }
 
-// UpdateInstance updates the options of an existing instance and returns it.
-// If the instance is running, it will be restarted to apply the new options.
-func (im *instanceManager) UpdateInstance(name string, options *instance.Options) (*instance.Instance, error) {
+// GetInstanceByID retrieves an instance by its database ID, the identifier
+// API key permissions refer to. Remote instances are refreshed like in GetInstance.
+func (im *instanceManager) GetInstanceByID(id int) (*instance.Instance, error) {
+	for _, inst := range im.registry.list() {
+		if inst.ID == id {
+			return im.GetInstance(inst.Name)
+		}
+	}
+	return nil, apierrors.Newf(apierrors.ErrInstanceNotFound, "instance with id %d not found", id)
+}
+
+// UpdateInstance updates the options of an existing instance and returns it
+// together with the names of the options that changed. If the instance is
+// running, it will be restarted to apply the new options.
+func (im *instanceManager) UpdateInstance(name string, options *instance.Options) (*instance.Instance, []string, error) {
 	inst, exists := im.registry.get(name)
 	if !exists {
-		return nil, fmt.Errorf("instance with name %s not found", name)
+		return nil, nil, apierrors.Newf(apierrors.ErrInstanceNotFound, "instance with name %s not found", name)
 	}
 
 	// Check if instance is remote and delegate to remote operation
 	if node := im.getNodeForInstance(inst); node != nil {
+		oldOptions := inst.GetOptions()
 		ctx := context.Background()
 		remoteInst, err := im.remote.updateInstance(ctx, node, name, options)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// Update the local stub with all remote data (preserving Nodes)
@@ -218,19 +332,63 @@ func (im *instanceManager) UpdateInstance(name string, options *instance.Options
 
 		// Persist the updated remote instance locally
 		if err := im.persistInstance(inst); err != nil {
-			return nil, fmt.Errorf("failed to persist updated remote instance %s: %w", name, err)
+			return nil, nil, fmt.Errorf("failed to persist updated remote instance %s: %w", name, err)
 		}
 
-		return inst, nil
+		return inst, instance.DiffOptions(oldOptions, inst.GetOptions()), nil
 	}
 
 	if options == nil {
-		return nil, fmt.Errorf("instance options cannot be nil")
+		return nil, nil, apierrors.Newf(apierrors.ErrInvalidOptions, "instance options cannot be nil")
+	}
+
+	if err := im.resolveModelAliases(options); err != nil {
+		return nil, nil, err
 	}
 
 	err := options.BackendOptions.ValidateInstanceOptions()
 	if err != nil {
-		return nil, err
+		return nil, nil, apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	if err := im.validateChatTemplateRef(options); err != nil {
+		return nil, nil, err
+	}
+
+	if err := im.validatePlacement(options); err != nil {
+		return nil, nil, err
+	}
+
+	if err := im.validateWorkingDir(options); err != nil {
+		return nil, nil, err
+	}
+
+	if err := options.ValidateSchedule(); err != nil {
+		return nil, nil, apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	if err := options.ValidateRouterModels(); err != nil {
+		return nil, nil, apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	if err := options.ValidateLabels(); err != nil {
+		return nil, nil, apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	if err := options.ValidateStdinData(); err != nil {
+		return nil, nil, apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+
+	if err := im.validateDependencies(name, options); err != nil {
+		return nil, nil, err
+	}
+
+	if err := im.validateDraftInstance(name, options); err != nil {
+		return nil, nil, err
+	}
+
+	if err := im.validateModelNames(name, options); err != nil {
+		return nil, nil, err
 	}
 
 	// Lock this specific instance only
@@ -238,6 +396,8 @@ func (im *instanceManager) UpdateInstance(name string, options *instance.Options
 	lock.Lock()
 	defer lock.Unlock()
 
+	oldOptions := inst.GetOptions()
+
 	// Handle port changes
 	oldPort := inst.GetPort()
 	newPort := im.getPortFromOptions(options)
@@ -249,13 +409,13 @@ func (im *instanceManager) UpdateInstance(name string, options *instance.Options
 			// Auto-allocate new port
 			allocatedPort, err = im.ports.allocate(name)
 			if err != nil {
-				return nil, fmt.Errorf("failed to allocate new port: %w", err)
+				return nil, nil, fmt.Errorf("failed to allocate new port: %w", err)
 			}
 			im.setPortInOptions(options, allocatedPort)
 		} else {
 			// Use specified port
 			if err := im.ports.allocateSpecific(newPort, name); err != nil {
-				return nil, fmt.Errorf("failed to allocate port %d: %w", newPort, err)
+				return nil, nil, apierrors.Newf(apierrors.ErrPortInUse, "failed to allocate port %d: %w", newPort, err)
 			}
 			allocatedPort = newPort
 		}
@@ -265,43 +425,59 @@ func (im *instanceManager) UpdateInstance(name string, options *instance.Options
 			if err := im.ports.release(oldPort); err != nil {
 				// Rollback new port allocation
 				im.ports.release(allocatedPort)
-				return nil, fmt.Errorf("failed to release old port %d: %w", oldPort, err)
+				return nil, nil, fmt.Errorf("failed to release old port %d: %w", oldPort, err)
 			}
 		}
 	}
 
-	// Check if instance is running before updating options
-	wasRunning := inst.IsRunning()
+	// A running instance is restarted to apply the new options, unless only
+	// its description or labels changed
+	wasRunning := inst.IsRunning() && inst.RequiresRestart(options)
 
 	// If the instance is running, stop it first
 	if wasRunning {
 		if err := inst.Stop(); err != nil {
-			return nil, fmt.Errorf("failed to stop instance %s for update: %w", name, err)
+			return nil, nil, fmt.Errorf("failed to stop instance %s for update: %w", name, err)
 		}
 	}
 
 	// Now update the options while the instance is stopped
 	inst.SetOptions(options)
 
+	// Say what changed, so a restart caused by the update can be explained
+	changes := instance.DiffOptions(oldOptions, options)
+	if len(changes) > 0 {
+		inst.Logf(instance.LogLevelInfo, "Instance %s updated, changed options: %s", name, strings.Join(changes, ", "))
+	} else {
+		inst.Logf(instance.LogLevelInfo, "Instance %s updated without option changes", name)
+	}
+
 	// If it was running before, start it again with the new options
 	if wasRunning {
+		if err := im.ensureVRAMBudget(inst); err != nil {
+			return nil, nil, err
+		}
+		if err := im.resolveDraftInstance(inst); err != nil {
+			return nil, nil, err
+		}
 		if err := inst.Start(); err != nil {
-			return nil, fmt.Errorf("failed to start instance %s after update: %w", name, err)
+			return nil, nil, fmt.Errorf("failed to start instance %s after update: %w", name, err)
 		}
+		im.warmupAfterStart(inst)
 	}
 
 	if err := im.persistInstance(inst); err != nil {
-		return nil, fmt.Errorf("failed to persist updated instance %s: %w", name, err)
+		return nil, nil, fmt.Errorf("failed to persist updated instance %s: %w", name, err)
 	}
 
-	return inst, nil
+	return inst, changes, nil
 }
 
 // DeleteInstance removes stopped instance by its name.
 func (im *instanceManager) DeleteInstance(name string) error {
 	inst, exists := im.registry.get(name)
 	if !exists {
-		return fmt.Errorf("instance with name %s not found", name)
+		return apierrors.Newf(apierrors.ErrInstanceNotFound, "instance with name %s not found", name)
 	}
 
 	// Check if instance is remote and delegate to remote operation
@@ -324,6 +500,9 @@ func (im *instanceManager) DeleteInstance(name string) error {
 		return nil
 	}
 
+	// A start still pulling its image holds the lock, abort it
+	inst.CancelStart()
+
 	// Lock this specific instance and clean up the lock on completion
 	lock := im.lockInstance(name)
 	lock.Lock()
@@ -331,7 +510,12 @@ func (im *instanceManager) DeleteInstance(name string) error {
 
 	status := inst.GetStatus()
 	if status == instance.Running || status == instance.Restarting {
-		return fmt.Errorf("instance with name %s is still running, stop it before deleting", name)
+		return apierrors.Newf(apierrors.ErrInstanceRunning, "instance with name %s is still running, stop it before deleting", name)
+	}
+
+	// Deleting a dependency would leave its dependents unable to start
+	if dependents := im.dependentsOf(name); len(dependents) > 0 {
+		return apierrors.Newf(apierrors.ErrHasDependents, "instance %s is a dependency of %s, delete them or remove it from their depends_on first", name, strings.Join(dependents, ", "))
 	}
 
 	// Release port (use ReleaseByInstance for proper cleanup)
@@ -355,7 +539,7 @@ func (im *instanceManager) DeleteInstance(name string) error {
 func (im *instanceManager) StartInstance(name string) (*instance.Instance, error) {
 	inst, exists := im.registry.get(name)
 	if !exists {
-		return nil, fmt.Errorf("instance with name %s not found", name)
+		return nil, apierrors.Newf(apierrors.ErrInstanceNotFound, "instance with name %s not found", name)
 	}
 
 	// Check if instance is remote and delegate to remote operation
@@ -368,30 +552,54 @@ func (im *instanceManager) StartInstance(name string) (*instance.Instance, error
 
 		// Update the local stub with all remote data (preserving Nodes)
 		im.updateLocalInstanceFromRemote(inst, remoteInst)
+		im.setDesiredState(inst, instance.DesiredRunning)
 
 		return inst, nil
 	}
 
+	if err := im.startDependencies(inst); err != nil {
+		return nil, err
+	}
+
+	if err := im.startLocalInstance(inst); err != nil {
+		return nil, err
+	}
+
+	return inst, nil
+}
+
+// startLocalInstance starts a local instance unless it is already running.
+func (im *instanceManager) startLocalInstance(inst *instance.Instance) error {
 	// Lock this specific instance only
-	lock := im.lockInstance(name)
+	lock := im.lockInstance(inst.Name)
 	lock.Lock()
 	defer lock.Unlock()
 
 	// Idempotent: if already running, just return success
 	if inst.IsRunning() {
-		return inst, nil
+		return nil
+	}
+
+	if err := im.ensureVRAMBudget(inst); err != nil {
+		return err
+	}
+
+	if err := im.resolveDraftInstance(inst); err != nil {
+		return err
 	}
 
 	if err := inst.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start instance %s: %w", name, err)
+		return fmt.Errorf("failed to start instance %s: %w", inst.Name, err)
 	}
+	inst.SetDesiredState(instance.DesiredRunning)
+	im.warmupAfterStart(inst)
 
 	// Persist instance (best-effort, don't fail if persistence fails)
 	if err := im.persistInstance(inst); err != nil {
-		log.Printf("Warning: failed to persist instance %s: %v", name, err)
+		log.Printf("Warning: failed to persist instance %s: %v", inst.Name, err)
 	}
 
-	return inst, nil
+	return nil
 }
 
 func (im *instanceManager) AtMaxRunning() bool {
@@ -410,11 +618,69 @@ func (im *instanceManager) AtMaxRunning() bool {
 	return localRunningCount >= im.globalConfig.Instances.MaxRunningInstances
 }
 
+// declaredVRAM returns the GPU memory in MiB an instance declares via vram_mb
+func declaredVRAM(inst *instance.Instance) int {
+	opts := inst.GetOptions()
+	if opts == nil || opts.VRAMMB == nil {
+		return 0
+	}
+	return *opts.VRAMMB
+}
+
+// usedVRAM sums the declared VRAM of local running instances, excluding the named one
+func (im *instanceManager) usedVRAM(exclude string) int {
+	used := 0
+	for _, inst := range im.registry.listRunning() {
+		if inst.IsRemote() || inst.Name == exclude {
+			continue
+		}
+		used += declaredVRAM(inst)
+	}
+	return used
+}
+
+// ensureVRAMBudget checks that starting inst keeps the node within its VRAM budget.
+// With LRU eviction enabled it stops least recently used instances to make room.
+func (im *instanceManager) ensureVRAMBudget(inst *instance.Instance) error {
+	budget := im.globalConfig.Instances.VRAMBudgetMB
+	required := declaredVRAM(inst)
+	if budget <= 0 || required == 0 {
+		return nil
+	}
+
+	if required > budget {
+		return apierrors.Newf(apierrors.ErrVRAMBudgetExceeded,
+			"instance %s declares %d MiB of VRAM, which exceeds the node budget of %d MiB", inst.Name, required, budget)
+	}
+
+	for {
+		used := im.usedVRAM(inst.Name)
+		if used+required <= budget {
+			return nil
+		}
+
+		if !im.globalConfig.Instances.EnableLRUEviction {
+			return apierrors.Newf(apierrors.ErrVRAMBudgetExceeded,
+				"cannot start instance %s: needs %d MiB of VRAM but only %d of %d MiB is free", inst.Name, required, budget-used, budget)
+		}
+
+		// Only instances that declare VRAM free anything toward the budget,
+		// and a restart must not evict the instance being started
+		err := im.lifecycle.evictLRUMatching("declaring vram_mb", func(candidate *instance.Instance) bool {
+			return candidate.Name != inst.Name && declaredVRAM(candidate) > 0
+		})
+		if err != nil {
+			return apierrors.Newf(apierrors.ErrVRAMBudgetExceeded,
+				"cannot start instance %s: needs %d MiB of VRAM but only %d of %d MiB is free: %w", inst.Name, required, budget-used, budget, err)
+		}
+	}
+}
+
 // StopInstance stops a running instance and returns it.
 func (im *instanceManager) StopInstance(name string) (*instance.Instance, error) {
 	inst, exists := im.registry.get(name)
 	if !exists {
-		return nil, fmt.Errorf("instance with name %s not found", name)
+		return nil, apierrors.Newf(apierrors.ErrInstanceNotFound, "instance with name %s not found", name)
 	}
 
 	// Check if instance is remote and delegate to remote operation
@@ -427,23 +693,36 @@ func (im *instanceManager) StopInstance(name string) (*instance.Instance, error)
 
 		// Update the local stub with all remote data (preserving Nodes)
 		im.updateLocalInstanceFromRemote(inst, remoteInst)
+		im.setDesiredState(inst, instance.DesiredStopped)
 
 		return inst, nil
 	}
 
+	// Instances depending on this one go down first
+	if err := im.stopDependents(inst); err != nil {
+		return nil, err
+	}
+
+	// A start still pulling its image holds the lock, abort it
+	inst.CancelStart()
+
 	// Lock this specific instance only
 	lock := im.lockInstance(name)
 	lock.Lock()
 	defer lock.Unlock()
 
-	// Idempotent: if already stopped, just return success
+	// Idempotent: if already stopped, just return success. An explicit stop
+	// still clears the intent to run for an instance that already crashed,
+	// so it isn't brought back on the next boot.
 	if !inst.IsRunning() {
+		im.setDesiredState(inst, instance.DesiredStopped)
 		return inst, nil
 	}
 
 	if err := inst.Stop(); err != nil {
 		return nil, fmt.Errorf("failed to stop instance %s: %w", name, err)
 	}
+	inst.SetDesiredState(instance.DesiredStopped)
 
 	// Persist instance (best-effort, don't fail if persistence fails)
 	if err := im.persistInstance(inst); err != nil {
@@ -457,7 +736,7 @@ func (im *instanceManager) StopInstance(name string) (*instance.Instance, error)
 func (im *instanceManager) RestartInstance(name string) (*instance.Instance, error) {
 	inst, exists := im.registry.get(name)
 	if !exists {
-		return nil, fmt.Errorf("instance with name %s not found", name)
+		return nil, apierrors.Newf(apierrors.ErrInstanceNotFound, "instance with name %s not found", name)
 	}
 
 	// Check if instance is remote and delegate to remote operation
@@ -470,15 +749,30 @@ func (im *instanceManager) RestartInstance(name string) (*instance.Instance, err
 
 		// Update the local stub with all remote data (preserving Nodes)
 		im.updateLocalInstanceFromRemote(inst, remoteInst)
+		im.setDesiredState(inst, instance.DesiredRunning)
 
 		return inst, nil
 	}
 
+	if err := im.startDependencies(inst); err != nil {
+		return nil, err
+	}
+
 	// Lock this specific instance for the entire restart operation to ensure atomicity
 	lock := im.lockInstance(name)
 	lock.Lock()
 	defer lock.Unlock()
 
+	// Check the budget before stopping, so a restart that can't fit leaves
+	// the instance running
+	if err := im.ensureVRAMBudget(inst); err != nil {
+		return nil, err
+	}
+
+	if err := im.resolveDraftInstance(inst); err != nil {
+		return nil, err
+	}
+
 	// Stop the instance
 	if inst.IsRunning() {
 		if err := inst.Stop(); err != nil {
@@ -490,6 +784,8 @@ func (im *instanceManager) RestartInstance(name string) (*instance.Instance, err
 	if err := inst.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start instance %s: %w", name, err)
 	}
+	inst.SetDesiredState(instance.DesiredRunning)
+	im.warmupAfterStart(inst)
 
 	// Persist the restarted instance
 	if err := im.persistInstance(inst); err != nil {
@@ -503,7 +799,7 @@ func (im *instanceManager) RestartInstance(name string) (*instance.Instance, err
 func (im *instanceManager) GetInstanceLogs(name string, numLines int) (string, error) {
 	inst, exists := im.registry.get(name)
 	if !exists {
-		return "", fmt.Errorf("instance with name %s not found", name)
+		return "", apierrors.Newf(apierrors.ErrInstanceNotFound, "instance with name %s not found", name)
 	}
 
 	// Check if instance is remote and delegate to remote operation
@@ -516,6 +812,108 @@ func (im *instanceManager) GetInstanceLogs(name string, numLines int) (string, e
 	return inst.GetLogs(numLines)
 }
 
+// GetInstanceMetadata returns model metadata reported by a running instance's backend.
+func (im *instanceManager) GetInstanceMetadata(name string) (*instance.Metadata, error) {
+	inst, exists := im.registry.get(name)
+	if !exists {
+		return nil, apierrors.Newf(apierrors.ErrInstanceNotFound, "instance with name %s not found", name)
+	}
+
+	ctx := context.Background()
+	if node := im.getNodeForInstance(inst); node != nil {
+		return im.remote.getInstanceMetadata(ctx, node, name)
+	}
+
+	if !inst.IsRunning() {
+		return nil, apierrors.Newf(apierrors.ErrInstanceNotRunning, "instance %s is not running", name)
+	}
+
+	md, err := inst.GetMetadata(ctx)
+	if errors.Is(err, instance.ErrMetadataNotSupported) {
+		return nil, apierrors.Newf(apierrors.ErrUnsupportedBackend, "instance %s: %w", name, err)
+	}
+	if err != nil {
+		return nil, apierrors.Newf(apierrors.ErrBackendRequestFailed, "failed to get metadata for instance %s: %w", name, err)
+	}
+	return md, nil
+}
+
+// GetInstanceOpenAPISpec returns the OpenAPI document describing a running instance's backend API.
+func (im *instanceManager) GetInstanceOpenAPISpec(name string) (map[string]any, error) {
+	inst, exists := im.registry.get(name)
+	if !exists {
+		return nil, apierrors.Newf(apierrors.ErrInstanceNotFound, "instance with name %s not found", name)
+	}
+
+	ctx := context.Background()
+	if node := im.getNodeForInstance(inst); node != nil {
+		return im.remote.getInstanceOpenAPISpec(ctx, node, name)
+	}
+
+	if !inst.IsRunning() {
+		return nil, apierrors.Newf(apierrors.ErrInstanceNotRunning, "instance %s is not running", name)
+	}
+
+	spec, err := inst.GetOpenAPISpec(ctx)
+	if err != nil {
+		return nil, apierrors.Newf(apierrors.ErrBackendRequestFailed, "failed to get OpenAPI spec for instance %s: %w", name, err)
+	}
+	return spec, nil
+}
+
+// validateChatTemplateRef checks chat_template_ref against the local template
+// library. Remote instances are resolved by the node that runs them.
+func (im *instanceManager) validateChatTemplateRef(options *instance.Options) error {
+	if !im.isLocalOptions(options) {
+		return nil
+	}
+
+	if err := options.ValidateChatTemplateRef(im.globalConfig.ChatTemplates); err != nil {
+		return apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+	return nil
+}
+
+// validatePlacement checks cpu_affinity and nice for local instances.
+// Remote instances are checked by the node that runs them, which may be
+// on a different platform.
+func (im *instanceManager) validatePlacement(options *instance.Options) error {
+	if !im.isLocalOptions(options) {
+		return nil
+	}
+
+	if err := options.ValidatePlacement(&im.globalConfig.Backends); err != nil {
+		return apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+	return nil
+}
+
+// validateWorkingDir checks working_dir for local instances; a remote
+// instance's directory is on its own node
+func (im *instanceManager) validateWorkingDir(options *instance.Options) error {
+	if !im.isLocalOptions(options) {
+		return nil
+	}
+
+	if err := options.ValidateWorkingDir(&im.globalConfig.Backends, im.globalConfig.Instances.AutoCreateDirs); err != nil {
+		return apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+	return nil
+}
+
+// resolveModelAliases rewrites model aliases from the local model_aliases
+// library. Remote instances are resolved by the node that runs them.
+func (im *instanceManager) resolveModelAliases(options *instance.Options) error {
+	if !im.isLocalOptions(options) {
+		return nil
+	}
+
+	if err := options.ResolveModelAliases(im.globalConfig.ModelAliases); err != nil {
+		return apierrors.Wrap(apierrors.ErrInvalidOptions, err)
+	}
+	return nil
+}
+
 // getPortFromOptions extracts the port from backend-specific options
 func (im *instanceManager) getPortFromOptions(options *instance.Options) int {
 	return options.BackendOptions.GetPort()
@@ -548,3 +946,15 @@ func (im *instanceManager) CountRunningInGroup(group string) int {
 	}
 	return count
 }
+
+// setDesiredState records the desired state of an instance and persists it if
+// it changed (best-effort)
+func (im *instanceManager) setDesiredState(inst *instance.Instance, desired instance.DesiredState) {
+	if inst.GetDesiredState() == desired {
+		return
+	}
+	inst.SetDesiredState(desired)
+	if err := im.persistInstance(inst); err != nil {
+		log.Printf("Warning: failed to persist instance %s: %v", inst.Name, err)
+	}
+}
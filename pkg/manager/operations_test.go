@@ -1,12 +1,20 @@
 package manager_test
 
 import (
+	"errors"
+	"fmt"
+	"llamactl/pkg/apierrors"
 	"llamactl/pkg/backends"
 	"llamactl/pkg/config"
 	"llamactl/pkg/database"
 	"llamactl/pkg/instance"
 	"llamactl/pkg/manager"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -58,18 +66,7 @@ func TestCreateInstance_FailsWhenMaxInstancesReached(t *testing.T) {
 		LocalNode: "main",
 		Nodes:     map[string]config.NodeConfig{},
 	}
-	db, err := database.Open(&database.Config{
-		Path:               appConfig.Database.Path,
-		MaxOpenConnections: appConfig.Database.MaxOpenConnections,
-		MaxIdleConnections: appConfig.Database.MaxIdleConnections,
-		ConnMaxLifetime:    appConfig.Database.ConnMaxLifetime,
-	})
-	if err != nil {
-		t.Fatalf("Failed to open database: %v", err)
-	}
-	if err := database.RunMigrations(db); err != nil {
-		t.Fatalf("Failed to run migrations: %v", err)
-	}
+	db := database.NewMemoryStore()
 	limitedManager := manager.New(appConfig, db)
 
 	options := &instance.Options{
@@ -81,7 +78,7 @@ func TestCreateInstance_FailsWhenMaxInstancesReached(t *testing.T) {
 		},
 	}
 
-	_, err = limitedManager.CreateInstance("instance1", options)
+	_, err := limitedManager.CreateInstance("instance1", options)
 	if err != nil {
 		t.Fatalf("CreateInstance 1 failed: %v", err)
 	}
@@ -132,6 +129,155 @@ func TestCreateInstance_FailsWithPortConflict(t *testing.T) {
 	if !strings.Contains(err.Error(), "port") && !strings.Contains(err.Error(), "in use") {
 		t.Errorf("Expected port conflict error, got: %v", err)
 	}
+	if !strings.Contains(err.Error(), "instance1") {
+		t.Errorf("Expected port conflict error to name instance1, got: %v", err)
+	}
+}
+
+func TestCreateInstance_ConcurrentCreatesAreAtomic(t *testing.T) {
+	mngr := createTestManager(t) // MaxInstances: 10
+	defer mngr.Shutdown()
+
+	newOptions := func() *instance.Options {
+		return &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}
+	}
+
+	const attempts = 30
+	var wg sync.WaitGroup
+	results := make(chan *instance.Instance, attempts)
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if inst, err := mngr.CreateInstance(fmt.Sprintf("race-%d", i), newOptions()); err == nil {
+				results <- inst
+			} else if !errors.Is(err, apierrors.ErrMaxInstances) {
+				t.Errorf("Unexpected create error: %v", err)
+			}
+		}()
+	}
+
+	// Concurrent creates of the same name: exactly one may win
+	dupes := make(chan error, attempts)
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := mngr.CreateInstance("race-dup", newOptions())
+			dupes <- err
+		}()
+	}
+
+	wg.Wait()
+	close(results)
+	close(dupes)
+
+	ports := make(map[int]string)
+	created := 0
+	for inst := range results {
+		created++
+		port := inst.GetPort()
+		if other, taken := ports[port]; taken {
+			t.Errorf("Port %d assigned to both %s and %s", port, other, inst.Name)
+		}
+		ports[port] = inst.Name
+	}
+
+	dupCreated := 0
+	for err := range dupes {
+		if err == nil {
+			dupCreated++
+		}
+	}
+	if dupCreated > 1 {
+		t.Errorf("Expected at most one create of a duplicate name to succeed, got %d", dupCreated)
+	}
+
+	instances, err := mngr.ListInstances()
+	if err != nil {
+		t.Fatalf("ListInstances failed: %v", err)
+	}
+	if len(instances) != 10 {
+		t.Errorf("Expected max instances limit of 10 to hold, got %d instances", len(instances))
+	}
+	if created+dupCreated != len(instances) {
+		t.Errorf("Expected %d successful creates to match %d registered instances", created+dupCreated, len(instances))
+	}
+}
+
+func TestCreateInstance_PortAllocationStrategies(t *testing.T) {
+	newManager := func(t *testing.T, strategy string) manager.InstanceManager {
+		appConfig := createTestAppConfig(t.TempDir())
+		appConfig.Instances.PortRange = [2]int{8000, 8009}
+		appConfig.Instances.PortAllocation = strategy
+		appConfig.Instances.MaxInstances = -1
+		db := database.NewMemoryStore()
+		mngr := manager.New(appConfig, db)
+		t.Cleanup(mngr.Shutdown)
+		return mngr
+	}
+
+	// Fills the whole range and returns the ports in allocation order
+	allocateAll := func(t *testing.T, mngr manager.InstanceManager) []int {
+		var ports []int
+		for i := range 10 {
+			inst, err := mngr.CreateInstance(fmt.Sprintf("inst-%d", i), &instance.Options{
+				BackendOptions: backends.Options{
+					BackendType: backends.BackendTypeLlamaCpp,
+					LlamaServerOptions: &backends.LlamaServerOptions{
+						Model: "/path/to/model.gguf",
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("CreateInstance %d failed: %v", i, err)
+			}
+			ports = append(ports, inst.GetPort())
+		}
+
+		_, err := mngr.CreateInstance("overflow", &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		})
+		if !errors.Is(err, apierrors.ErrNoPortsAvailable) {
+			t.Errorf("Expected ErrNoPortsAvailable once the range is full, got %v", err)
+		}
+		return ports
+	}
+
+	t.Run("sequential", func(t *testing.T) {
+		ports := allocateAll(t, newManager(t, config.PortAllocationSequential))
+		for i, port := range ports {
+			if port != 8000+i {
+				t.Errorf("Expected sequential port %d, got %d", 8000+i, port)
+			}
+		}
+	})
+
+	t.Run("random", func(t *testing.T) {
+		ports := allocateAll(t, newManager(t, config.PortAllocationRandom))
+		seen := make(map[int]bool)
+		for _, port := range ports {
+			if port < 8000 || port > 8009 {
+				t.Errorf("Port %d outside of range [8000-8009]", port)
+			}
+			if seen[port] {
+				t.Errorf("Port %d allocated twice", port)
+			}
+			seen[port] = true
+		}
+	})
 }
 
 func TestInstanceOperations_FailWithNonExistentInstance(t *testing.T) {
@@ -156,12 +302,88 @@ func TestInstanceOperations_FailWithNonExistentInstance(t *testing.T) {
 		t.Errorf("Expected 'not found' error, got: %v", err)
 	}
 
-	_, err = manager.UpdateInstance("nonexistent", options)
+	_, _, err = manager.UpdateInstance("nonexistent", options)
 	if err == nil || !strings.Contains(err.Error(), "not found") {
 		t.Errorf("Expected 'not found' error, got: %v", err)
 	}
 }
 
+func TestOperations_ReturnTypedErrors(t *testing.T) {
+	mngr := createTestManager(t)
+	options := &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+				Port:  8080,
+			},
+		},
+	}
+
+	_, err := mngr.GetInstance("nonexistent")
+	if !errors.Is(err, apierrors.ErrInstanceNotFound) {
+		t.Errorf("Expected ErrInstanceNotFound, got: %v", err)
+	}
+
+	if _, err := mngr.CreateInstance("instance1", options); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	_, err = mngr.CreateInstance("instance1", options)
+	if !errors.Is(err, apierrors.ErrInstanceExists) {
+		t.Errorf("Expected ErrInstanceExists, got: %v", err)
+	}
+
+	_, err = mngr.CreateInstance("instance2", options)
+	if !errors.Is(err, apierrors.ErrPortInUse) {
+		t.Errorf("Expected ErrPortInUse, got: %v", err)
+	}
+
+	kind, ok := apierrors.As(err)
+	if !ok || kind.Code != apierrors.CodePortInUse {
+		t.Errorf("Expected error code %q, got: %v", apierrors.CodePortInUse, kind)
+	}
+}
+
+func TestGetInstanceByID(t *testing.T) {
+	mngr := createTestManager(t)
+	newOptions := func() *instance.Options {
+		return &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}
+	}
+
+	first, err := mngr.CreateInstance("first", newOptions())
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	second, err := mngr.CreateInstance("second", newOptions())
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if first.ID == 0 || first.ID == second.ID {
+		t.Fatalf("Expected distinct persisted IDs, got %d and %d", first.ID, second.ID)
+	}
+
+	inst, err := mngr.GetInstanceByID(second.ID)
+	if err != nil {
+		t.Fatalf("GetInstanceByID failed: %v", err)
+	}
+	if inst.Name != "second" {
+		t.Errorf("Expected instance 'second', got %q", inst.Name)
+	}
+
+	_, err = mngr.GetInstanceByID(second.ID + 100)
+	if !errors.Is(err, apierrors.ErrInstanceNotFound) {
+		t.Errorf("Expected ErrInstanceNotFound, got: %v", err)
+	}
+}
+
 func TestDeleteInstance_RunningInstanceFails(t *testing.T) {
 	mgr := createTestManager(t)
 	defer mgr.Shutdown()
@@ -225,10 +447,13 @@ func TestUpdateInstance(t *testing.T) {
 		},
 	}
 
-	updated, err := mgr.UpdateInstance("test-instance", newOptions)
+	updated, changes, err := mgr.UpdateInstance("test-instance", newOptions)
 	if err != nil {
 		t.Fatalf("UpdateInstance failed: %v", err)
 	}
+	if !slices.Equal(changes, []string{"backend_options.model"}) {
+		t.Errorf("Expected changes [backend_options.model], got %v", changes)
+	}
 
 	// Should be running after update (was running before, should be restarted)
 	if !updated.IsRunning() {
@@ -240,6 +465,47 @@ func TestUpdateInstance(t *testing.T) {
 	}
 }
 
+func TestUpdateInstance_LabelsOnlyKeepsInstanceRunning(t *testing.T) {
+	mgr := createTestManager(t)
+	defer mgr.Shutdown()
+
+	newOptions := func(labels map[string]string) *instance.Options {
+		return &instance.Options{
+			Description: "Chat model for the support team",
+			Labels:      labels,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+					Port:  8080,
+				},
+			},
+		}
+	}
+
+	inst, err := mgr.CreateInstance("test-instance", newOptions(map[string]string{"team": "support"}))
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if err := inst.Start(); err != nil {
+		t.Fatalf("Failed to start instance: %v", err)
+	}
+
+	if _, _, err := mgr.UpdateInstance("test-instance", newOptions(map[string]string{"team": "sales", "env": "prod"})); err != nil {
+		t.Fatalf("UpdateInstance failed: %v", err)
+	}
+	if !inst.IsRunning() || inst.GetStateTimestamps().LastStoppedAt != 0 {
+		t.Errorf("Expected a labels-only update not to restart the instance, status %v", inst.GetStatus())
+	}
+	if got := inst.GetOptions().Labels["team"]; got != "sales" {
+		t.Errorf("Expected label team=sales, got %q", got)
+	}
+
+	if _, _, err := mgr.UpdateInstance("test-instance", newOptions(map[string]string{"bad key": "x"})); !errors.Is(err, apierrors.ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for an invalid label key, got: %v", err)
+	}
+}
+
 func TestUpdateInstance_ReleasesOldPort(t *testing.T) {
 	mgr := createTestManager(t)
 	defer mgr.Shutdown()
@@ -274,7 +540,7 @@ func TestUpdateInstance_ReleasesOldPort(t *testing.T) {
 		},
 	}
 
-	updated, err := mgr.UpdateInstance("test-instance", newOptions)
+	updated, _, err := mgr.UpdateInstance("test-instance", newOptions)
 	if err != nil {
 		t.Fatalf("UpdateInstance failed: %v", err)
 	}
@@ -299,3 +565,787 @@ func TestUpdateInstance_ReleasesOldPort(t *testing.T) {
 		t.Errorf("Should be able to use old port 8080: %v", err)
 	}
 }
+
+func TestStartInstance_RefusesWhenVRAMBudgetExceeded(t *testing.T) {
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Instances.VRAMBudgetMB = 1000
+	appConfig.Instances.EnableLRUEviction = false
+
+	db := database.NewMemoryStore()
+	mngr := manager.New(appConfig, db)
+	defer mngr.Shutdown()
+
+	vram := 600
+	newOptions := func() *instance.Options {
+		return &instance.Options{
+			VRAMMB: &vram,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}
+	}
+
+	running, err := mngr.CreateInstance("running", newOptions())
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	running.SetStatus(instance.Running)
+	defer running.SetStatus(instance.Stopped)
+
+	if _, err := mngr.CreateInstance("second", newOptions()); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	_, err = mngr.StartInstance("second")
+	if !errors.Is(err, apierrors.ErrVRAMBudgetExceeded) {
+		t.Fatalf("Expected ErrVRAMBudgetExceeded, got: %v", err)
+	}
+
+	second, _ := mngr.GetInstance("second")
+	if second.IsRunning() {
+		t.Error("Instance should not be started when it would exceed the VRAM budget")
+	}
+}
+
+func TestRestartInstance_RefusesWhenVRAMBudgetExceeded(t *testing.T) {
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Instances.VRAMBudgetMB = 1000
+	appConfig.Instances.EnableLRUEviction = false
+
+	db := database.NewMemoryStore()
+	mngr := manager.New(appConfig, db)
+	defer mngr.Shutdown()
+
+	vram := 600
+	newOptions := func() *instance.Options {
+		return &instance.Options{
+			VRAMMB: &vram,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}
+	}
+
+	running, err := mngr.CreateInstance("running", newOptions())
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	running.SetStatus(instance.Running)
+	defer running.SetStatus(instance.Stopped)
+
+	if _, err := mngr.CreateInstance("stopped", newOptions()); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	// Restarting a stopped instance starts it, so it has to fit the budget
+	_, err = mngr.RestartInstance("stopped")
+	if !errors.Is(err, apierrors.ErrVRAMBudgetExceeded) {
+		t.Fatalf("Expected ErrVRAMBudgetExceeded, got: %v", err)
+	}
+
+	stopped, _ := mngr.GetInstance("stopped")
+	if stopped.IsRunning() {
+		t.Error("Instance should not be restarted when it would exceed the VRAM budget")
+	}
+}
+
+func TestStartInstance_EvictsOnlyVRAMInstancesForBudget(t *testing.T) {
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Instances.VRAMBudgetMB = 1000
+	appConfig.Instances.EnableLRUEviction = true
+
+	db := database.NewMemoryStore()
+	mngr := manager.New(appConfig, db)
+	defer mngr.Shutdown()
+
+	vram := 600
+	newOptions := func(vramMB *int) *instance.Options {
+		return &instance.Options{
+			VRAMMB: vramMB,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}
+	}
+
+	mockTime := NewMockTimeProvider(time.Now())
+
+	// The instance without vram_mb is least recently used, but stopping it
+	// would free nothing toward the budget
+	plain, err := mngr.CreateInstance("plain", newOptions(nil))
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	plain.SetTimeProvider(mockTime)
+	plain.SetStatus(instance.Running)
+	defer plain.SetStatus(instance.Stopped)
+	plain.UpdateLastRequestTime()
+
+	mockTime.SetTime(mockTime.Now().Add(time.Minute))
+	large, err := mngr.CreateInstance("large", newOptions(&vram))
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	large.SetTimeProvider(mockTime)
+	large.SetStatus(instance.Running)
+	defer func() {
+		if large.IsRunning() {
+			large.SetStatus(instance.Stopped)
+		}
+	}()
+	large.UpdateLastRequestTime()
+
+	if _, err := mngr.CreateInstance("second", newOptions(&vram)); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if _, err := mngr.StartInstance("second"); err != nil {
+		t.Fatalf("StartInstance failed: %v", err)
+	}
+
+	if !plain.IsRunning() {
+		t.Error("Instance without vram_mb should not be evicted for the VRAM budget")
+	}
+	if large.IsRunning() {
+		t.Error("Instance declaring vram_mb should be evicted to make room")
+	}
+}
+
+func TestCreateInstance_ValidatesDependencies(t *testing.T) {
+	mngr := createTestManager(t)
+	defer mngr.Shutdown()
+
+	newOptions := func(dependsOn ...string) *instance.Options {
+		return &instance.Options{
+			DependsOn: dependsOn,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}
+	}
+
+	if _, err := mngr.CreateInstance("a", newOptions("missing")); !errors.Is(err, apierrors.ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for unknown dependency, got: %v", err)
+	}
+	if _, err := mngr.CreateInstance("a", newOptions("a")); !errors.Is(err, apierrors.ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for self dependency, got: %v", err)
+	}
+
+	if _, err := mngr.CreateInstance("a", newOptions()); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if _, err := mngr.CreateInstance("b", newOptions("a")); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if _, err := mngr.CreateInstance("c", newOptions("b")); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	_, _, err := mngr.UpdateInstance("a", newOptions("c"))
+	if !errors.Is(err, apierrors.ErrInvalidOptions) || !strings.Contains(err.Error(), "a -> c -> b -> a") {
+		t.Errorf("Expected dependency cycle error, got: %v", err)
+	}
+}
+
+func TestCreateInstance_RejectsModelNameConflicts(t *testing.T) {
+	mngr := createTestManager(t)
+	defer mngr.Shutdown()
+
+	withAlias := func(alias string) *instance.Options {
+		return &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+					Alias: alias,
+				},
+			},
+		}
+	}
+
+	if _, err := mngr.CreateInstance("qwen-a", withAlias("qwen")); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		instance string
+		options  *instance.Options
+		wantMsg  string
+	}{
+		{"same alias", "qwen-b", withAlias("qwen"), "already served by instance qwen-a"},
+		{"alias equals instance name", "other", withAlias("qwen-a"), "conflicts with the name of instance qwen-a"},
+		{"instance name equals alias", "qwen", withAlias(""), "conflicts with a model name served by instance qwen-a"},
+		{"router model equals alias", "router", &instance.Options{
+			RouterModels: []instance.RouterModel{{Name: "qwen", Model: "/path/to/model.gguf"}},
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{},
+			},
+		}, "already served by instance qwen-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := mngr.CreateInstance(tt.instance, tt.options)
+			if !errors.Is(err, apierrors.ErrInvalidOptions) || !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantMsg, err)
+			}
+		})
+	}
+
+	// Keeping its own alias on update is not a conflict
+	if _, _, err := mngr.UpdateInstance("qwen-a", withAlias("qwen")); err != nil {
+		t.Errorf("UpdateInstance failed: %v", err)
+	}
+
+	if _, err := mngr.CreateInstance("llama", withAlias("llama-3")); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if _, _, err := mngr.UpdateInstance("llama", withAlias("qwen")); !errors.Is(err, apierrors.ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for alias taken on update, got: %v", err)
+	}
+}
+
+func TestCreateInstance_ValidatesSchedule(t *testing.T) {
+	mngr := createTestManager(t)
+	defer mngr.Shutdown()
+
+	newOptions := func(schedule *instance.Schedule) *instance.Options {
+		return &instance.Options{
+			Schedule: schedule,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}
+	}
+
+	_, err := mngr.CreateInstance("scheduled", newOptions(&instance.Schedule{StartCron: "0 25 * * *"}))
+	if !errors.Is(err, apierrors.ErrInvalidOptions) || !strings.Contains(err.Error(), "schedule.start_cron") {
+		t.Errorf("Expected invalid start_cron error, got: %v", err)
+	}
+
+	inst, err := mngr.CreateInstance("scheduled", newOptions(&instance.Schedule{StartCron: "0 9 * * 1-5", StopCron: "0 18 * * 1-5"}))
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if got := inst.GetOptions().Schedule; got == nil || got.StopCron != "0 18 * * 1-5" {
+		t.Errorf("Expected schedule to be kept, got %+v", got)
+	}
+
+	_, _, err = mngr.UpdateInstance("scheduled", newOptions(&instance.Schedule{StopCron: "every evening"}))
+	if !errors.Is(err, apierrors.ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for invalid stop_cron, got: %v", err)
+	}
+}
+
+func TestStartInstance_StartsDependenciesFirst(t *testing.T) {
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Instances.OnDemandStartTimeout = 5
+
+	// The dependency is health checked, so serve /health on a port in range
+	var listener net.Listener
+	for port := appConfig.Instances.PortRange[0]; port <= appConfig.Instances.PortRange[1]; port++ {
+		if l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port)); err == nil {
+			listener = l
+			break
+		}
+	}
+	if listener == nil {
+		t.Skip("no free port in range for the fake backend")
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	db := database.NewMemoryStore()
+	mngr := manager.New(appConfig, db)
+	defer mngr.Shutdown()
+
+	base, err := mngr.CreateInstance("base", &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+				Host:  "127.0.0.1",
+				Port:  listener.Addr().(*net.TCPAddr).Port,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	draft, err := mngr.CreateInstance("draft", &instance.Options{
+		DependsOn: []string{"base"},
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/draft.gguf",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	if _, err := mngr.StartInstance("draft"); err != nil {
+		t.Fatalf("StartInstance failed: %v", err)
+	}
+	if !base.IsRunning() || !draft.IsRunning() {
+		t.Fatalf("Expected dependency and dependent to be running, base=%v draft=%v", base.IsRunning(), draft.IsRunning())
+	}
+
+	if _, err := mngr.StopInstance("base"); err != nil {
+		t.Fatalf("StopInstance failed: %v", err)
+	}
+	if draft.IsRunning() {
+		t.Error("Expected dependent instance to be stopped with its dependency")
+	}
+}
+
+func TestDeleteInstance_RefusesWithDependents(t *testing.T) {
+	mngr := createTestManager(t)
+	defer mngr.Shutdown()
+
+	newOptions := func(dependsOn ...string) *instance.Options {
+		return &instance.Options{
+			DependsOn: dependsOn,
+			BackendOptions: backends.Options{
+				BackendType: backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{
+					Model: "/path/to/model.gguf",
+				},
+			},
+		}
+	}
+
+	for _, inst := range []struct {
+		name      string
+		dependsOn []string
+	}{{"base", nil}, {"draft", []string{"base"}}, {"other", []string{"base"}}} {
+		if _, err := mngr.CreateInstance(inst.name, newOptions(inst.dependsOn...)); err != nil {
+			t.Fatalf("CreateInstance %s failed: %v", inst.name, err)
+		}
+	}
+
+	err := mngr.DeleteInstance("base")
+	if !errors.Is(err, apierrors.ErrHasDependents) {
+		t.Fatalf("Expected ErrHasDependents, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "draft, other") {
+		t.Errorf("Expected the error to list the dependents, got: %v", err)
+	}
+	if _, err := mngr.GetInstance("base"); err != nil {
+		t.Errorf("Expected base to be kept, got: %v", err)
+	}
+
+	// Once the dependents are gone the dependency can be deleted
+	for _, name := range []string{"draft", "other", "base"} {
+		if err := mngr.DeleteInstance(name); err != nil {
+			t.Fatalf("DeleteInstance %s failed: %v", name, err)
+		}
+	}
+}
+
+func TestStartInstance_ResolvesDraftInstance(t *testing.T) {
+	appConfig := createTestAppConfig(t.TempDir())
+	// Echo the command line so the resolved draft flags show up in the logs
+	appConfig.Backends.LlamaCpp.Args = []string{"-c", `echo "$0 $*"; sleep 999999`}
+
+	db := database.NewMemoryStore()
+	mngr := manager.New(appConfig, db)
+	defer mngr.Shutdown()
+
+	_, err := mngr.CreateInstance("draft", &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model:     "/path/to/draft.gguf",
+				CtxSize:   4096,
+				GPULayers: 99,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	newMainOptions := func(draftInstance string, lo *backends.LlamaServerOptions) *instance.Options {
+		return &instance.Options{
+			DraftInstance: draftInstance,
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: lo,
+			},
+		}
+	}
+
+	if _, err := mngr.CreateInstance("main", newMainOptions("missing", &backends.LlamaServerOptions{Model: "/path/to/main.gguf"})); !errors.Is(err, apierrors.ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for unknown draft instance, got: %v", err)
+	}
+	if _, err := mngr.CreateInstance("main", newMainOptions("draft", &backends.LlamaServerOptions{Model: "/path/to/main.gguf", ModelDraft: "/other.gguf"})); !errors.Is(err, apierrors.ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions when model_draft is also set, got: %v", err)
+	}
+
+	// Explicit draft settings on the instance win over the draft instance's
+	if _, err := mngr.CreateInstance("main", newMainOptions("draft", &backends.LlamaServerOptions{Model: "/path/to/main.gguf", CtxSizeDraft: 2048})); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if _, err := mngr.StartInstance("main"); err != nil {
+		t.Fatalf("StartInstance failed: %v", err)
+	}
+
+	want := "--model-draft /path/to/draft.gguf --gpu-layers-draft 99"
+	deadline := time.Now().Add(5 * time.Second)
+	var logs string
+	for {
+		logs, _ = mngr.GetInstanceLogs("main", -1)
+		if strings.Contains(logs, want) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(logs, want) {
+		t.Errorf("Expected command line to contain %q, logs:\n%s", want, logs)
+	}
+	if !strings.Contains(logs, "--ctx-size-draft 2048") || strings.Contains(logs, "--ctx-size-draft 4096") {
+		t.Errorf("Expected the instance's own ctx_size_draft to be kept, logs:\n%s", logs)
+	}
+}
+
+func TestDrainNode_BlocksPlacement(t *testing.T) {
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Nodes = map[string]config.NodeConfig{"main": {}, "worker": {Address: "http://127.0.0.1:1"}}
+	db := database.NewMemoryStore()
+	mngr := manager.New(appConfig, db)
+	defer mngr.Shutdown()
+
+	options := &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+			},
+		},
+	}
+
+	if _, err := mngr.DrainNode("missing", manager.DrainOptions{}); !errors.Is(err, apierrors.ErrNodeNotFound) {
+		t.Errorf("Expected node not found error, got: %v", err)
+	}
+
+	status, err := mngr.DrainNode("main", manager.DrainOptions{})
+	if err != nil {
+		t.Fatalf("DrainNode failed: %v", err)
+	}
+	if status.State != manager.DrainStateDrained || status.FinishedAt == nil {
+		t.Errorf("Expected drain without migration to finish immediately, got %+v", status)
+	}
+
+	if _, err := mngr.CreateInstance("blocked", options); !errors.Is(err, apierrors.ErrNodeDraining) {
+		t.Errorf("Expected node draining error, got: %v", err)
+	}
+
+	if err := mngr.UndrainNode("main"); err != nil {
+		t.Fatalf("UndrainNode failed: %v", err)
+	}
+	if _, err := mngr.GetDrainStatus("main"); !errors.Is(err, apierrors.ErrNodeNotFound) {
+		t.Errorf("Expected no drain status after undrain, got: %v", err)
+	}
+	if _, err := mngr.CreateInstance("allowed", options); err != nil {
+		t.Errorf("Expected create to succeed after undrain, got: %v", err)
+	}
+}
+
+// createDrainTestManager returns a manager with stopped local instances a
+// and b on node main, and a worker node served by worker
+func createDrainTestManager(t *testing.T, worker http.Handler) manager.InstanceManager {
+	t.Helper()
+	node := httptest.NewServer(worker)
+	t.Cleanup(node.Close)
+
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Nodes = map[string]config.NodeConfig{"main": {}, "worker": {Address: node.URL}}
+	mngr := manager.New(appConfig, database.NewMemoryStore())
+	t.Cleanup(mngr.Shutdown)
+
+	for _, name := range []string{"a", "b"} {
+		if _, err := mngr.CreateInstance(name, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+			},
+		}); err != nil {
+			t.Fatalf("CreateInstance %s failed: %v", name, err)
+		}
+	}
+	return mngr
+}
+
+// writeRemoteInstance answers a remote create with the instance on worker
+func writeRemoteInstance(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/instances/"), "/")
+	fmt.Fprintf(w, `{"name":%q,"status":"stopped","options":{"backend_type":"llama_cpp","backend_options":{"model":"/path/to/model.gguf"},"nodes":["worker"]}}`, name)
+}
+
+func waitForDrain(t *testing.T, mngr manager.InstanceManager, node string) *manager.DrainStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := mngr.GetDrainStatus(node)
+		if err != nil {
+			t.Fatalf("GetDrainStatus failed: %v", err)
+		}
+		if status.State == manager.DrainStateDrained {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for the migration, status: %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDrainNode_MigratesInstances(t *testing.T) {
+	mngr := createDrainTestManager(t, http.HandlerFunc(writeRemoteInstance))
+
+	status, err := mngr.DrainNode("main", manager.DrainOptions{Migrate: true})
+	if err != nil {
+		t.Fatalf("DrainNode failed: %v", err)
+	}
+	if len(status.Instances) != 2 {
+		t.Fatalf("Expected 2 instances to migrate, got %+v", status.Instances)
+	}
+
+	status = waitForDrain(t, mngr, "main")
+	if status.FinishedAt == nil {
+		t.Error("Expected finished_at to be set")
+	}
+	for _, migration := range status.Instances {
+		if migration.Status != manager.MigrationMigrated || migration.TargetNode != "worker" {
+			t.Errorf("Expected %s to be migrated to worker, got %+v", migration.Name, migration)
+		}
+		inst, err := mngr.GetInstance(migration.Name)
+		if err != nil {
+			t.Fatalf("GetInstance %s failed: %v", migration.Name, err)
+		}
+		if !inst.IsRemote() {
+			t.Errorf("Expected %s to be on the worker node", migration.Name)
+		}
+	}
+}
+
+func TestDrainNode_RestoresInstanceWhenMigrationFails(t *testing.T) {
+	mngr := createDrainTestManager(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of capacity", http.StatusInternalServerError)
+	}))
+
+	if _, err := mngr.DrainNode("main", manager.DrainOptions{Migrate: true}); err != nil {
+		t.Fatalf("DrainNode failed: %v", err)
+	}
+
+	status := waitForDrain(t, mngr, "main")
+	for _, migration := range status.Instances {
+		if migration.Status != manager.MigrationFailed || migration.Error == "" {
+			t.Errorf("Expected %s to fail with an error, got %+v", migration.Name, migration)
+		}
+		inst, err := mngr.GetInstance(migration.Name)
+		if err != nil {
+			t.Fatalf("Expected %s to be restored: %v", migration.Name, err)
+		}
+		if inst.IsRemote() {
+			t.Errorf("Expected %s to be restored on the main node", migration.Name)
+		}
+	}
+}
+
+// createDependentInstances adds "user", which depends on "base", to mngr
+func createDependentInstances(t *testing.T, mngr manager.InstanceManager) {
+	t.Helper()
+	for _, name := range []string{"base", "user"} {
+		opts := &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+			},
+		}
+		if name == "user" {
+			opts.DependsOn = []string{"base"}
+		}
+		if _, err := mngr.CreateInstance(name, opts); err != nil {
+			t.Fatalf("CreateInstance %s failed: %v", name, err)
+		}
+	}
+}
+
+func TestDrainNode_MigratesDependencies(t *testing.T) {
+	mngr := createDrainTestManager(t, http.HandlerFunc(writeRemoteInstance))
+	createDependentInstances(t, mngr)
+
+	if _, err := mngr.DrainNode("main", manager.DrainOptions{Migrate: true}); err != nil {
+		t.Fatalf("DrainNode failed: %v", err)
+	}
+
+	status := waitForDrain(t, mngr, "main")
+	if len(status.Instances) != 4 {
+		t.Fatalf("Expected 4 instances to migrate, got %+v", status.Instances)
+	}
+	for _, migration := range status.Instances {
+		if migration.Status != manager.MigrationMigrated {
+			t.Errorf("Expected %s to be migrated, got %+v", migration.Name, migration)
+		}
+		inst, err := mngr.GetInstance(migration.Name)
+		if err != nil {
+			t.Fatalf("GetInstance %s failed: %v", migration.Name, err)
+		}
+		if !inst.IsRemote() {
+			t.Errorf("Expected %s to be on the worker node", migration.Name)
+		}
+	}
+}
+
+func TestDrainNode_RestoresDependenciesWhenMigrationFails(t *testing.T) {
+	mngr := createDrainTestManager(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of capacity", http.StatusInternalServerError)
+	}))
+	createDependentInstances(t, mngr)
+
+	if _, err := mngr.DrainNode("main", manager.DrainOptions{Migrate: true}); err != nil {
+		t.Fatalf("DrainNode failed: %v", err)
+	}
+
+	status := waitForDrain(t, mngr, "main")
+	for _, migration := range status.Instances {
+		if migration.Status != manager.MigrationFailed {
+			t.Errorf("Expected %s to fail, got %+v", migration.Name, migration)
+		}
+	}
+	for _, name := range []string{"base", "user"} {
+		inst, err := mngr.GetInstance(name)
+		if err != nil {
+			t.Fatalf("Expected %s to be restored: %v", name, err)
+		}
+		if inst.IsRemote() {
+			t.Errorf("Expected %s to be restored on the main node", name)
+		}
+	}
+	user, _ := mngr.GetInstance("user")
+	if deps := user.GetOptions().DependsOn; !slices.Equal(deps, []string{"base"}) {
+		t.Errorf("Expected user to still depend on base, got %v", deps)
+	}
+}
+
+func TestDrainNode_RejectsRedrainWhileMigrationRuns(t *testing.T) {
+	arrived := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	creates := make(map[string]int)
+	mngr := createDrainTestManager(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			mu.Lock()
+			creates[r.URL.Path]++
+			mu.Unlock()
+			arrived <- struct{}{}
+			<-release
+		}
+		writeRemoteInstance(w, r)
+	}))
+
+	if _, err := mngr.DrainNode("main", manager.DrainOptions{Migrate: true}); err != nil {
+		t.Fatalf("DrainNode failed: %v", err)
+	}
+	<-arrived
+
+	// Undrain stops the migration after the instance it is moving, but until
+	// then a new drain must not start a second migration
+	if err := mngr.UndrainNode("main"); err != nil {
+		t.Fatalf("UndrainNode failed: %v", err)
+	}
+	if _, err := mngr.DrainNode("main", manager.DrainOptions{Migrate: true}); !errors.Is(err, apierrors.ErrNodeDraining) {
+		t.Fatalf("Expected node draining error while the migration runs, got: %v", err)
+	}
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := mngr.DrainNode("main", manager.DrainOptions{Migrate: true})
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, apierrors.ErrNodeDraining) || time.Now().After(deadline) {
+			t.Fatalf("Expected drain to succeed once the migration finished, got: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	status := waitForDrain(t, mngr, "main")
+
+	// Only the instance left on main is migrated by the second drain
+	if len(status.Instances) != 1 || status.Instances[0].Status != manager.MigrationMigrated {
+		t.Errorf("Expected the second drain to migrate the remaining instance, got %+v", status.Instances)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for path, count := range creates {
+		if count != 1 {
+			t.Errorf("Expected %s to be created on the worker once, got %d", path, count)
+		}
+	}
+	if len(creates) != 2 {
+		t.Errorf("Expected both instances to be created on the worker, got %v", creates)
+	}
+}
+
+func TestStopAllInstances(t *testing.T) {
+	mngr := createTestManager(t)
+	defer mngr.Shutdown()
+
+	for _, name := range []string{"b", "a", "idle"} {
+		if _, err := mngr.CreateInstance(name, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+			},
+		}); err != nil {
+			t.Fatalf("CreateInstance %s failed: %v", name, err)
+		}
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := mngr.StartInstance(name); err != nil {
+			t.Fatalf("StartInstance %s failed: %v", name, err)
+		}
+	}
+
+	results, err := mngr.StopAllInstances()
+	if err != nil {
+		t.Fatalf("StopAllInstances failed: %v", err)
+	}
+
+	want := []manager.StopResult{
+		{Name: "a", Status: manager.StopResultStopped},
+		{Name: "b", Status: manager.StopResultStopped},
+	}
+	if !slices.Equal(results, want) {
+		t.Errorf("Expected results %+v, got %+v", want, results)
+	}
+	for _, name := range []string{"a", "b"} {
+		inst, _ := mngr.GetInstance(name)
+		if inst.IsRunning() {
+			t.Errorf("Expected instance %s to be stopped", name)
+		}
+	}
+}
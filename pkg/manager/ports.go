@@ -2,7 +2,10 @@ package manager
 
 import (
 	"fmt"
+	"llamactl/pkg/apierrors"
+	"llamactl/pkg/config"
 	"math/bits"
+	"math/rand/v2"
 	"sync"
 )
 
@@ -21,10 +24,14 @@ type portAllocator struct {
 	minPort   int
 	maxPort   int
 	rangeSize int
+
+	// Pick free ports at random instead of the lowest one
+	random bool
 }
 
-// newPortAllocator creates a new port allocator for the given port range.
-func newPortAllocator(minPort, maxPort int) *portAllocator {
+// newPortAllocator creates a new port allocator for the given port range
+// using the given allocation strategy.
+func newPortAllocator(minPort, maxPort int, strategy string) *portAllocator {
 	rangeSize := maxPort - minPort + 1
 	bitmapSize := (rangeSize + 63) / 64 // Round up to nearest uint64
 
@@ -34,10 +41,12 @@ func newPortAllocator(minPort, maxPort int) *portAllocator {
 		minPort:   minPort,
 		maxPort:   maxPort,
 		rangeSize: rangeSize,
+		random:    strategy == config.PortAllocationRandom,
 	}
 }
 
-// allocate finds and allocates the first available port for the given instance.
+// allocate finds and allocates an available port for the given instance: the
+// lowest free port, or a random free one with the random strategy.
 // Returns the allocated port or an error if no ports are available.
 func (p *portAllocator) allocate(instanceName string) (int, error) {
 	if instanceName == "" {
@@ -47,7 +56,13 @@ func (p *portAllocator) allocate(instanceName string) (int, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	port, err := p.findFirstFreeBit()
+	var port int
+	var err error
+	if p.random {
+		port, err = p.findRandomFreeBit()
+	} else {
+		port, err = p.findFirstFreeBit()
+	}
 	if err != nil {
 		return 0, err
 	}
@@ -72,7 +87,7 @@ func (p *portAllocator) allocateSpecific(port int, instanceName string) error {
 	defer p.mu.Unlock()
 
 	if p.isBitSet(port) {
-		return fmt.Errorf("port %d is already allocated", port)
+		return apierrors.Newf(apierrors.ErrPortInUse, "port %d is already allocated to instance %s", port, p.allocated[port])
 	}
 
 	p.setBit(port)
@@ -172,5 +187,21 @@ func (p *portAllocator) findFirstFreeBit() (int, error) {
 		}
 	}
 
-	return 0, fmt.Errorf("no available ports in range [%d-%d]", p.minPort, p.maxPort)
+	return 0, apierrors.Newf(apierrors.ErrNoPortsAvailable, "no available ports in range [%d-%d]", p.minPort, p.maxPort)
+}
+
+// findRandomFreeBit picks a random port in the range and returns the first
+// free port at or after it, wrapping around to the start of the range.
+// Spreading allocations avoids immediately reusing a port that was just
+// released and may still have sockets lingering in TIME_WAIT.
+func (p *portAllocator) findRandomFreeBit() (int, error) {
+	start := rand.IntN(p.rangeSize)
+	for i := range p.rangeSize {
+		port := p.minPort + (start+i)%p.rangeSize
+		if !p.isBitSet(port) {
+			return port, nil
+		}
+	}
+
+	return 0, apierrors.Newf(apierrors.ErrNoPortsAvailable, "no available ports in range [%d-%d]", p.minPort, p.maxPort)
 }
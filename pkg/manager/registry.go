@@ -2,6 +2,7 @@ package manager
 
 import (
 	"fmt"
+	"llamactl/pkg/apierrors"
 	"llamactl/pkg/instance"
 	"sync"
 )
@@ -68,7 +69,7 @@ func (r *instanceRegistry) add(inst *instance.Instance) error {
 	defer r.mu.Unlock()
 
 	if _, exists := r.instances[inst.Name]; exists {
-		return fmt.Errorf("instance %s already exists", inst.Name)
+		return apierrors.Newf(apierrors.ErrInstanceExists, "instance %s already exists", inst.Name)
 	}
 
 	r.instances[inst.Name] = inst
@@ -88,7 +89,7 @@ func (r *instanceRegistry) remove(name string) error {
 	defer r.mu.Unlock()
 
 	if _, exists := r.instances[name]; !exists {
-		return fmt.Errorf("instance %s not found", name)
+		return apierrors.Newf(apierrors.ErrInstanceNotFound, "instance %s not found", name)
 	}
 
 	delete(r.instances, name)
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"llamactl/pkg/apierrors"
 	"llamactl/pkg/config"
 	"llamactl/pkg/instance"
 	"net/http"
@@ -22,6 +23,7 @@ type remoteManager struct {
 	client         *http.Client
 	nodeMap        map[string]*config.NodeConfig // node name -> node config
 	instanceToNode map[string]*config.NodeConfig // instance name -> node config
+	selfAddresses  map[string]bool               // node addresses that lead back to this server
 }
 
 // newRemoteManager creates a new remote manager.
@@ -64,7 +66,7 @@ func (rm *remoteManager) setInstanceNode(instanceName, nodeName string) error {
 
 	node, exists := rm.nodeMap[nodeName]
 	if !exists {
-		return fmt.Errorf("node %s not found", nodeName)
+		return apierrors.Newf(apierrors.ErrNodeNotFound, "node %s not found", nodeName)
 	}
 
 	rm.instanceToNode[instanceName] = node
@@ -92,6 +94,10 @@ func (rm *remoteManager) makeRemoteRequest(ctx context.Context, nodeConfig *conf
 		reqBody = bytes.NewBuffer(jsonData)
 	}
 
+	if rm.selfAddresses[nodeConfig.Address] {
+		return nil, apierrors.Newf(apierrors.ErrRemoteRequestFailed, "node address %s points back at this server", nodeConfig.Address)
+	}
+
 	url := fmt.Sprintf("%s%s", nodeConfig.Address, path)
 	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
@@ -108,7 +114,7 @@ func (rm *remoteManager) makeRemoteRequest(ctx context.Context, nodeConfig *conf
 
 	resp, err := rm.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, apierrors.Newf(apierrors.ErrRemoteRequestFailed, "failed to execute request: %w", err)
 	}
 
 	return resp, nil
@@ -124,7 +130,7 @@ func parseRemoteResponse(resp *http.Response, result any) error {
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return apierrors.Newf(apierrors.ErrRemoteRequestFailed, "API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
 	if result != nil {
@@ -176,6 +182,44 @@ func (rm *remoteManager) getInstance(ctx context.Context, node *config.NodeConfi
 	return &inst, nil
 }
 
+// getInstanceMetadata retrieves backend metadata for an instance on a remote node.
+func (rm *remoteManager) getInstanceMetadata(ctx context.Context, node *config.NodeConfig, name string) (*instance.Metadata, error) {
+
+	escapedName := url.PathEscape(name)
+
+	path := fmt.Sprintf("%s%s/metadata", apiBasePath, escapedName)
+	resp, err := rm.makeRemoteRequest(ctx, node, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var md instance.Metadata
+	if err := parseRemoteResponse(resp, &md); err != nil {
+		return nil, err
+	}
+
+	return &md, nil
+}
+
+// getInstanceOpenAPISpec retrieves the backend OpenAPI document for an instance on a remote node.
+func (rm *remoteManager) getInstanceOpenAPISpec(ctx context.Context, node *config.NodeConfig, name string) (map[string]any, error) {
+
+	escapedName := url.PathEscape(name)
+
+	path := fmt.Sprintf("%s%s/openapi", apiBasePath, escapedName)
+	resp, err := rm.makeRemoteRequest(ctx, node, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec map[string]any
+	if err := parseRemoteResponse(resp, &spec); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
 // updateInstance updates an existing instance on a remote node.
 func (rm *remoteManager) updateInstance(ctx context.Context, node *config.NodeConfig, name string, opts *instance.Options) (*instance.Instance, error) {
 
@@ -285,7 +329,7 @@ func (rm *remoteManager) getInstanceLogs(ctx context.Context, node *config.NodeC
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return "", apierrors.Newf(apierrors.ErrRemoteRequestFailed, "API request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
 	// Logs endpoint returns plain text (Content-Type: text/plain)
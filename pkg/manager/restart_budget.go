@@ -0,0 +1,52 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+// restartBudgetWindow is the period max_restarts_per_minute is counted over
+const restartBudgetWindow = time.Minute
+
+// restartBudget limits auto-restarts across all local instances to max per
+// window. Restarts beyond the budget aren't dropped but pushed back to the
+// next free slot, in the order they were requested.
+type restartBudget struct {
+	mu     sync.Mutex
+	max    int
+	window time.Duration
+	slots  []time.Time // booked restart times, oldest first
+	now    func() time.Time
+}
+
+func newRestartBudget(max int) *restartBudget {
+	return &restartBudget{max: max, window: restartBudgetWindow, now: time.Now}
+}
+
+// Reserve implements instance.RestartLimiter
+func (b *restartBudget) Reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+
+	// Forget restarts that no longer count against the window
+	expired := 0
+	for expired < len(b.slots) && !b.slots[expired].After(now.Add(-b.window)) {
+		expired++
+	}
+	b.slots = b.slots[expired:]
+
+	at := now
+	if len(b.slots) > 0 && b.slots[len(b.slots)-1].After(at) {
+		at = b.slots[len(b.slots)-1]
+	}
+	if len(b.slots) >= b.max {
+		if next := b.slots[len(b.slots)-b.max].Add(b.window); next.After(at) {
+			at = next
+		}
+	}
+
+	b.slots = append(b.slots, at)
+	return at.Sub(now)
+}
@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"llamactl/pkg/apierrors"
+	"llamactl/pkg/instance"
+	"slices"
+	"sort"
+)
+
+// ResolveModel finds the instance that serves the given model name through
+// its llama.cpp alias or one of its router models, so clients can request
+// models by name alone. Creates and updates reject duplicate model names, but
+// instances loaded from older data may still share one; such a name is
+// ambiguous and must be requested as <instance>/<model> instead.
+func (im *instanceManager) ResolveModel(model string) (*instance.Instance, error) {
+	var matches []*instance.Instance
+	for _, inst := range im.registry.list() {
+		if inst.Alias() == model || slices.Contains(inst.RouterModelNames(), model) {
+			matches = append(matches, inst)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, apierrors.Newf(apierrors.ErrInstanceNotFound, "no instance or model named %s", model)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, inst := range matches {
+			names[i] = inst.Name
+		}
+		sort.Strings(names)
+		return nil, apierrors.Newf(apierrors.ErrInvalidOptions, "model %s is served by several instances (%v), use <instance>/%s", model, names, model)
+	}
+}
@@ -0,0 +1,107 @@
+package manager_test
+
+import (
+	"errors"
+	"llamactl/pkg/apierrors"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/database"
+	"llamactl/pkg/instance"
+	"llamactl/pkg/manager"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouterModels(t *testing.T) {
+	appConfig := createTestAppConfig(t.TempDir())
+	appConfig.Instances.InstancesDir = t.TempDir()
+	// Echo the command line so the preset flag shows up in the logs
+	appConfig.Backends.LlamaCpp.Args = []string{"-c", `echo "$0 $*"; sleep 999999`}
+
+	db := database.NewMemoryStore()
+	mngr := manager.New(appConfig, db)
+	defer mngr.Shutdown()
+
+	newRouterOptions := func(lo *backends.LlamaServerOptions, models ...instance.RouterModel) *instance.Options {
+		return &instance.Options{
+			RouterModels: models,
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: lo,
+			},
+		}
+	}
+	mistral := instance.RouterModel{Name: "mistral", Model: "/models/mistral.gguf", Args: map[string]string{"ctx_size": "8192"}}
+	qwen := instance.RouterModel{Name: "qwen", HFRepo: "Qwen/Qwen3-8B-GGUF:Q4_K_M"}
+
+	invalid := map[string]*instance.Options{
+		"model also set":   newRouterOptions(&backends.LlamaServerOptions{Model: "/models/main.gguf"}, mistral),
+		"duplicate name":   newRouterOptions(&backends.LlamaServerOptions{}, mistral, mistral),
+		"no source":        newRouterOptions(&backends.LlamaServerOptions{}, instance.RouterModel{Name: "empty"}),
+		"slash in name":    newRouterOptions(&backends.LlamaServerOptions{}, instance.RouterModel{Name: "a/b", Model: "/m.gguf"}),
+		"two sources":      newRouterOptions(&backends.LlamaServerOptions{}, instance.RouterModel{Name: "both", Model: "/m.gguf", HFRepo: "org/repo"}),
+		"injected section": newRouterOptions(&backends.LlamaServerOptions{}, instance.RouterModel{Name: "evil", Model: "/m.gguf\n[other]\nmodel = /x.gguf"}),
+		"bracket in model": newRouterOptions(&backends.LlamaServerOptions{}, instance.RouterModel{Name: "evil", Model: "[other]"}),
+		"injected option":  newRouterOptions(&backends.LlamaServerOptions{}, instance.RouterModel{Name: "evil", Model: "/m.gguf", Args: map[string]string{"[other]": "x"}}),
+	}
+	for name, opts := range invalid {
+		if _, err := mngr.CreateInstance("router", opts); !errors.Is(err, apierrors.ErrInvalidOptions) {
+			t.Errorf("%s: expected ErrInvalidOptions, got: %v", name, err)
+		}
+	}
+
+	_, err := mngr.CreateInstance("router", newRouterOptions(&backends.LlamaServerOptions{}, mistral, qwen))
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	presetPath := filepath.Join(appConfig.Instances.InstancesDir, "router", "preset.ini")
+	content, err := os.ReadFile(presetPath)
+	if err != nil {
+		t.Fatalf("Failed to read preset.ini: %v", err)
+	}
+	want := "[mistral]\nmodel = /models/mistral.gguf\nctx-size = 8192\n\n[qwen]\nhf-repo = Qwen/Qwen3-8B-GGUF:Q4_K_M\n"
+	if string(content) != want {
+		t.Errorf("Expected preset.ini %q, got %q", want, string(content))
+	}
+
+	// Declared models are found by name alone
+	if got, err := mngr.ResolveModel("qwen"); err != nil || got.Name != "router" {
+		t.Errorf("Expected qwen to resolve to router, got %v, %v", got, err)
+	}
+	if _, err := mngr.ResolveModel("llama"); !errors.Is(err, apierrors.ErrInstanceNotFound) {
+		t.Errorf("Expected ErrInstanceNotFound for undeclared model, got: %v", err)
+	}
+
+	// Updating the models rewrites preset.ini
+	if _, _, err := mngr.UpdateInstance("router", newRouterOptions(&backends.LlamaServerOptions{}, qwen)); err != nil {
+		t.Fatalf("UpdateInstance failed: %v", err)
+	}
+	if content, _ := os.ReadFile(presetPath); strings.Contains(string(content), "mistral") {
+		t.Errorf("Expected preset.ini to be rewritten, got %q", string(content))
+	}
+
+	// The same model name can't be declared by two routers
+	if _, err := mngr.CreateInstance("router2", newRouterOptions(&backends.LlamaServerOptions{}, qwen)); !errors.Is(err, apierrors.ErrInvalidOptions) {
+		t.Errorf("Expected ErrInvalidOptions for duplicate router model, got: %v", err)
+	}
+
+	if _, err := mngr.StartInstance("router"); err != nil {
+		t.Fatalf("StartInstance failed: %v", err)
+	}
+	wantArg := "--models-preset " + presetPath
+	deadline := time.Now().Add(5 * time.Second)
+	var logs string
+	for {
+		logs, _ = mngr.GetInstanceLogs("router", -1)
+		if strings.Contains(logs, wantArg) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(logs, wantArg) {
+		t.Errorf("Expected command line to contain %q, logs:\n%s", wantArg, logs)
+	}
+}
@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"llamactl/pkg/cron"
+	"log"
+	"sync"
+	"time"
+)
+
+// scheduler starts and stops local instances according to their
+// schedule.start_cron and schedule.stop_cron expressions. It wakes up at the
+// start of every minute; remote instances are scheduled by their own node.
+type scheduler struct {
+	registry *instanceRegistry
+	manager  InstanceManager // For calling Start/Stop operations
+
+	shutdownChan chan struct{}
+	shutdownDone chan struct{}
+	shutdownOnce sync.Once
+}
+
+func newScheduler(registry *instanceRegistry, manager InstanceManager) *scheduler {
+	return &scheduler{
+		registry:     registry,
+		manager:      manager,
+		shutdownChan: make(chan struct{}),
+		shutdownDone: make(chan struct{}),
+	}
+}
+
+func (s *scheduler) start() {
+	go s.loop()
+}
+
+// stop waits for the loop to exit, so no scheduled start races with shutdown
+func (s *scheduler) stop() {
+	s.shutdownOnce.Do(func() {
+		close(s.shutdownChan)
+		<-s.shutdownDone
+	})
+}
+
+func (s *scheduler) loop() {
+	defer close(s.shutdownDone)
+
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		select {
+		case fired := <-timer.C:
+			s.runDue(fired.Truncate(time.Minute))
+		case <-s.shutdownChan:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// runDue starts and stops the instances whose schedule selects minute t. If
+// both expressions match the same minute, stopping wins.
+func (s *scheduler) runDue(t time.Time) {
+	for _, inst := range s.registry.list() {
+		if inst.IsRemote() {
+			continue
+		}
+		opts := inst.GetOptions()
+		if opts == nil || opts.Schedule == nil {
+			continue
+		}
+
+		name := inst.Name
+		running := s.registry.isRunning(name)
+
+		switch {
+		case scheduleMatches(name, "stop_cron", opts.Schedule.StopCron, t):
+			if running {
+				// Each action runs on its own so a slow start doesn't delay the rest
+				go func() {
+					log.Printf("Stopping instance %s on schedule", name)
+					if _, err := s.manager.StopInstance(name); err != nil {
+						log.Printf("Scheduled stop of instance %s failed: %v", name, err)
+					}
+				}()
+			}
+		case scheduleMatches(name, "start_cron", opts.Schedule.StartCron, t):
+			if !running {
+				go func() {
+					log.Printf("Starting instance %s on schedule", name)
+					if _, err := s.manager.StartInstance(name); err != nil {
+						log.Printf("Scheduled start of instance %s failed: %v", name, err)
+					}
+				}()
+			}
+		}
+	}
+}
+
+// scheduleMatches reports whether expr selects minute t. Expressions are
+// validated on create and update, so a parse error here is only logged.
+func scheduleMatches(name, field, expr string, t time.Time) bool {
+	if expr == "" {
+		return false
+	}
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		log.Printf("Ignoring invalid schedule.%s of instance %s: %v", field, name, err)
+		return false
+	}
+	return schedule.Matches(t)
+}
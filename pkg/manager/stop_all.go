@@ -0,0 +1,82 @@
+package manager
+
+import (
+	"llamactl/pkg/instance"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Per-instance outcomes reported in StopResult.Status
+const (
+	StopResultStopped = "stopped"
+	StopResultFailed  = "failed"
+)
+
+// StopResult is the outcome of stopping one instance in a bulk stop
+type StopResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// StopAllInstances stops every running instance, local and remote, and
+// reports the outcome for each. Instances are stopped in batches so that
+// dependents go down before their dependencies, with at most
+// shutdown_parallelism stops in flight.
+func (im *instanceManager) StopAllInstances() ([]StopResult, error) {
+	running, err := im.ListInstancesByStatus(instance.Running)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(running, func(a, b *instance.Instance) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	results := make(map[string]StopResult, len(running))
+	var mu sync.Mutex
+	for _, batch := range shutdownBatches(running) {
+		im.forEachParallel(batch, func(inst *instance.Instance) {
+			result := StopResult{Name: inst.Name, Status: StopResultStopped}
+			if _, err := im.StopInstance(inst.Name); err != nil {
+				result.Status = StopResultFailed
+				result.Error = err.Error()
+			}
+			mu.Lock()
+			results[inst.Name] = result
+			mu.Unlock()
+		})
+	}
+
+	ordered := make([]StopResult, 0, len(running))
+	for _, inst := range running {
+		ordered = append(ordered, results[inst.Name])
+	}
+	return ordered, nil
+}
+
+// forEachParallel calls fn for every instance, running at most
+// shutdown_parallelism calls at once, and returns when all are done
+func (im *instanceManager) forEachParallel(insts []*instance.Instance, fn func(*instance.Instance)) {
+	workers := im.globalConfig.Instances.ShutdownParallelism
+	if workers <= 0 || workers > len(insts) {
+		workers = len(insts)
+	}
+
+	queue := make(chan *instance.Instance)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for inst := range queue {
+				fn(inst)
+			}
+		}()
+	}
+	for _, inst := range insts {
+		queue <- inst
+	}
+	close(queue)
+	wg.Wait()
+}
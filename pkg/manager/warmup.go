@@ -0,0 +1,39 @@
+package manager
+
+import (
+	"context"
+	"llamactl/pkg/instance"
+	"log"
+	"time"
+)
+
+// warmupTimeout bounds the warmup completion itself; first-request setup
+// such as CUDA graph capture can take a while on large models
+const warmupTimeout = 2 * time.Minute
+
+// warmupAfterStart warms up a freshly started local instance in the
+// background if it has warmup_on_start set. Start returns before the
+// backend is healthy, so waiting happens off the caller's path.
+func (im *instanceManager) warmupAfterStart(inst *instance.Instance) {
+	opts := inst.GetOptions()
+	if opts == nil || opts.WarmupOnStart == nil || !*opts.WarmupOnStart {
+		return
+	}
+
+	go func() {
+		if err := inst.WaitForHealthy(im.globalConfig.Instances.OnDemandStartTimeout); err != nil {
+			log.Printf("Skipping warmup of instance %s: %v", inst.Name, err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+		defer cancel()
+
+		start := time.Now()
+		if err := inst.Warmup(ctx); err != nil {
+			log.Printf("Warmup of instance %s failed: %v", inst.Name, err)
+			return
+		}
+		log.Printf("Warmed up instance %s in %s", inst.Name, time.Since(start).Round(time.Millisecond))
+	}()
+}
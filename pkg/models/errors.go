@@ -0,0 +1,10 @@
+package models
+
+import "errors"
+
+var (
+	ErrJobNotFound   = errors.New("job not found")
+	ErrCannotCancel  = errors.New("cannot cancel job")
+	ErrCannotDelete  = errors.New("cannot delete job")
+	ErrModelNotFound = errors.New("model not found")
+)
@@ -39,19 +39,21 @@ type Job struct {
 }
 
 type JobStore struct {
-	jobs   map[string]*Job
-	mutex  sync.RWMutex
-	ctx    context.Context
-	cancel context.CancelFunc
+	jobs        map[string]*Job
+	subscribers map[string]map[chan struct{}]struct{}
+	mutex       sync.RWMutex
+	ctx         context.Context
+	cancel      context.CancelFunc
 }
 
 func NewJobStore() *JobStore {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	s := &JobStore{
-		jobs:   make(map[string]*Job),
-		ctx:    ctx,
-		cancel: cancel,
+		jobs:        make(map[string]*Job),
+		subscribers: make(map[string]map[chan struct{}]struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
 	}
 
 	// Start background job cleanup
@@ -88,7 +90,7 @@ func (s *JobStore) Get(jobID string) (*Job, error) {
 
 	job, exists := s.jobs[jobID]
 	if !exists {
-		return nil, fmt.Errorf("job not found: %s", jobID)
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
 	}
 
 	jobCopy := *job
@@ -117,14 +119,15 @@ func (s *JobStore) Delete(jobID string) error {
 
 	job, exists := s.jobs[jobID]
 	if !exists {
-		return fmt.Errorf("job not found: %s", jobID)
+		return fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
 	}
 
 	if job.Status == JobStatusDownloading || job.Status == JobStatusQueued {
-		return fmt.Errorf("cannot delete job with status: %s", job.Status)
+		return fmt.Errorf("%w with status: %s", ErrCannotDelete, job.Status)
 	}
 
 	delete(s.jobs, jobID)
+	s.notifyLocked(jobID)
 	return nil
 }
 
@@ -134,6 +137,7 @@ func (s *JobStore) UpdateStatus(jobID string, status JobStatus) {
 
 	if job, ok := s.jobs[jobID]; ok {
 		job.Status = status
+		s.notifyLocked(jobID)
 	}
 }
 
@@ -145,6 +149,7 @@ func (s *JobStore) Complete(jobID string) {
 	if job, ok := s.jobs[jobID]; ok {
 		job.Status = JobStatusCompleted
 		job.CompletedAt = &now
+		s.notifyLocked(jobID)
 	}
 }
 
@@ -157,6 +162,7 @@ func (s *JobStore) Fail(jobID string, errMsg string) {
 		job.Status = JobStatusFailed
 		job.Error = errMsg
 		job.CompletedAt = &now
+		s.notifyLocked(jobID)
 	}
 }
 
@@ -166,11 +172,11 @@ func (s *JobStore) Cancel(jobID string) error {
 
 	job, exists := s.jobs[jobID]
 	if !exists {
-		return fmt.Errorf("job not found: %s", jobID)
+		return fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
 	}
 
 	if job.Status == JobStatusCompleted || job.Status == JobStatusFailed || job.Status == JobStatusCancelled {
-		return fmt.Errorf("cannot cancel job with status: %s", job.Status)
+		return fmt.Errorf("%w with status: %s", ErrCannotCancel, job.Status)
 	}
 
 	if job.CancelFunc != nil {
@@ -180,10 +186,50 @@ func (s *JobStore) Cancel(jobID string) error {
 	now := time.Now()
 	job.Status = JobStatusCancelled
 	job.CompletedAt = &now
+	s.notifyLocked(jobID)
 
 	return nil
 }
 
+// Subscribe returns a channel that receives a value whenever the job
+// changes. Notifications are coalesced, so a slow reader sees one pending
+// notification rather than every progress update; call Get for the current
+// state. The returned function unsubscribes.
+func (s *JobStore) Subscribe(jobID string) (<-chan struct{}, func(), error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.jobs[jobID]; !exists {
+		return nil, nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+
+	ch := make(chan struct{}, 1)
+	if s.subscribers[jobID] == nil {
+		s.subscribers[jobID] = make(map[chan struct{}]struct{})
+	}
+	s.subscribers[jobID][ch] = struct{}{}
+
+	unsubscribe := func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		delete(s.subscribers[jobID], ch)
+		if len(s.subscribers[jobID]) == 0 {
+			delete(s.subscribers, jobID)
+		}
+	}
+	return ch, unsubscribe, nil
+}
+
+// notifyLocked wakes the job's subscribers. Callers must hold s.mutex.
+func (s *JobStore) notifyLocked(jobID string) {
+	for ch := range s.subscribers[jobID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
 func (s *JobStore) generateJobID() (string, error) {
 	bytes := make([]byte, 8)
 	if _, err := rand.Read(bytes); err != nil {
@@ -230,6 +276,7 @@ func (s *JobStore) cleanupOldJobs() {
 		// Delete if older than retention duration
 		if job.CompletedAt.Before(cutoff) {
 			delete(s.jobs, id)
+			s.notifyLocked(id)
 		}
 	}
 }
@@ -2,6 +2,7 @@ package models
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -106,8 +107,8 @@ func TestJobStore_CannotCancelCompletedJob(t *testing.T) {
 	store.Complete(job.ID)
 
 	err := store.Cancel(job.ID)
-	if err == nil {
-		t.Error("expected error when cancelling completed job")
+	if !errors.Is(err, ErrCannotCancel) {
+		t.Errorf("expected ErrCannotCancel when cancelling completed job, got %v", err)
 	}
 }
 
@@ -119,8 +120,8 @@ func TestJobStore_CannotDeleteActiveJob(t *testing.T) {
 	store.UpdateStatus(job.ID, JobStatusDownloading)
 
 	err := store.Delete(job.ID)
-	if err == nil {
-		t.Error("expected error when deleting active job")
+	if !errors.Is(err, ErrCannotDelete) {
+		t.Errorf("expected ErrCannotDelete when deleting active job, got %v", err)
 	}
 
 	// Should still exist
@@ -130,6 +131,21 @@ func TestJobStore_CannotDeleteActiveJob(t *testing.T) {
 	}
 }
 
+func TestJobStore_UnknownJobReturnsNotFound(t *testing.T) {
+	store := NewJobStore()
+	defer store.Close()
+
+	if _, err := store.Get("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Get: expected ErrJobNotFound, got %v", err)
+	}
+	if err := store.Cancel("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Cancel: expected ErrJobNotFound, got %v", err)
+	}
+	if err := store.Delete("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Delete: expected ErrJobNotFound, got %v", err)
+	}
+}
+
 func TestJobStore_DeleteCompletedJob(t *testing.T) {
 	store := NewJobStore()
 	defer store.Close()
@@ -246,3 +262,66 @@ func TestJobStore_CleanupDoesNotDeleteRecentJobs(t *testing.T) {
 		t.Error("recent completed job should not be deleted by cleanup")
 	}
 }
+
+func TestJobStore_SubscribeNotifiesOnChange(t *testing.T) {
+	store := NewJobStore()
+	defer store.Close()
+	tracker := NewProgressTracker(store)
+
+	job, _ := store.Create("org/model", "Q4_K_M")
+	updates, unsubscribe, err := store.Subscribe(job.ID)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer unsubscribe()
+
+	expectUpdate := func(what string) {
+		t.Helper()
+		select {
+		case <-updates:
+		case <-time.After(time.Second):
+			t.Fatalf("no notification after %s", what)
+		}
+	}
+
+	store.UpdateStatus(job.ID, JobStatusDownloading)
+	expectUpdate("status change")
+
+	// Several progress updates before the subscriber reads coalesce into one
+	tracker.AddToTotalBytes(job.ID, 100)
+	tracker.UpdateCurrentFile(job.ID, "model.gguf")
+	expectUpdate("progress updates")
+	select {
+	case <-updates:
+		t.Fatal("progress updates should coalesce into a single notification")
+	default:
+	}
+
+	store.Complete(job.ID)
+	expectUpdate("completion")
+}
+
+func TestJobStore_SubscribeUnknownJob(t *testing.T) {
+	store := NewJobStore()
+	defer store.Close()
+
+	if _, _, err := store.Subscribe("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Subscribe error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestJobStore_Unsubscribe(t *testing.T) {
+	store := NewJobStore()
+	defer store.Close()
+
+	job, _ := store.Create("org/model", "Q4_K_M")
+	updates, unsubscribe, _ := store.Subscribe(job.ID)
+	unsubscribe()
+
+	store.UpdateStatus(job.ID, JobStatusDownloading)
+	select {
+	case <-updates:
+		t.Error("unsubscribed channel should not be notified")
+	default:
+	}
+}
@@ -200,6 +200,11 @@ func (m *Manager) CancelJob(jobID string) error {
 	return m.jobStore.Cancel(jobID)
 }
 
+// SubscribeJob notifies the returned channel whenever the job changes
+func (m *Manager) SubscribeJob(jobID string) (<-chan struct{}, func(), error) {
+	return m.jobStore.Subscribe(jobID)
+}
+
 func (m *Manager) ListJobs() []*Job {
 	return m.jobStore.List()
 }
@@ -15,6 +15,7 @@ func (pt *ProgressTracker) Track(jobID string, progress <-chan int64) {
 		pt.jobStore.mutex.Lock()
 		if j, ok := pt.jobStore.jobs[jobID]; ok {
 			j.Progress.BytesDownloaded += bytes
+			pt.jobStore.notifyLocked(jobID)
 		}
 		pt.jobStore.mutex.Unlock()
 	}
@@ -26,6 +27,7 @@ func (pt *ProgressTracker) AddToTotalBytes(jobID string, bytes int64) {
 
 	if job, ok := pt.jobStore.jobs[jobID]; ok {
 		job.Progress.TotalBytes += bytes
+		pt.jobStore.notifyLocked(jobID)
 	}
 }
 
@@ -35,5 +37,6 @@ func (pt *ProgressTracker) UpdateCurrentFile(jobID string, filename string) {
 
 	if job, ok := pt.jobStore.jobs[jobID]; ok {
 		job.Progress.CurrentFile = filename
+		pt.jobStore.notifyLocked(jobID)
 	}
 }
@@ -306,7 +306,7 @@ func (m *Manager) DeleteModel(repo, tag string) error {
 	repoDir := fileManager.HFRepoDir(repo)
 
 	if _, err := os.Stat(repoDir); os.IsNotExist(err) {
-		return fmt.Errorf("model not found: %s", repo)
+		return fmt.Errorf("%w: %s", ErrModelNotFound, repo)
 	}
 
 	if tag == "" {
@@ -316,12 +316,12 @@ func (m *Manager) DeleteModel(repo, tag string) error {
 	refPath := fileManager.HFRefPath(repo, tag)
 	commit, err := readRefFile(refPath)
 	if err != nil {
-		return fmt.Errorf("model not found: %s:%s", repo, tag)
+		return fmt.Errorf("%w: %s:%s", ErrModelNotFound, repo, tag)
 	}
 
 	snapshotDir := filepath.Join(repoDir, "snapshots", commit)
 	if _, err := os.Stat(snapshotDir); os.IsNotExist(err) {
-		return fmt.Errorf("model not found: %s:%s", repo, tag)
+		return fmt.Errorf("%w: %s:%s", ErrModelNotFound, repo, tag)
 	}
 
 	snapshotEntries, err := os.ReadDir(snapshotDir)
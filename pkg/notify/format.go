@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"llamactl/pkg/config"
+	"strconv"
+	"time"
+)
+
+// Colors used for the message sidebar in chat apps
+const (
+	slackColorDanger  = "danger"
+	discordColorError = 0xE74C3C
+)
+
+type slackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+type slackAttachment struct {
+	Color    string       `json:"color"`
+	Fallback string       `json:"fallback"`
+	Fields   []slackField `json:"fields"`
+	Ts       int64        `json:"ts"`
+}
+
+type slackMessage struct {
+	Text        string            `json:"text"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+type discordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title     string         `json:"title"`
+	Color     int            `json:"color"`
+	Fields    []discordField `json:"fields"`
+	Timestamp string         `json:"timestamp"`
+}
+
+type discordMessage struct {
+	Content string         `json:"content"`
+	Embeds  []discordEmbed `json:"embeds"`
+}
+
+// field is a format-neutral name/value pair rendered into chat messages
+type field struct {
+	name  string
+	value string
+	short bool
+}
+
+// formatPayload renders ev as the JSON body for the given format.
+// An empty format is treated as raw.
+func formatPayload(ev Event, format string) ([]byte, error) {
+	switch format {
+	case "", config.NotificationFormatRaw:
+		return json.Marshal(ev)
+	case config.NotificationFormatSlack:
+		return json.Marshal(slackPayload(ev))
+	case config.NotificationFormatDiscord:
+		return json.Marshal(discordPayload(ev))
+	default:
+		return nil, fmt.Errorf("unsupported notification format %q", format)
+	}
+}
+
+func slackPayload(ev Event) slackMessage {
+	fields := eventFields(ev)
+	attachment := slackAttachment{
+		Color:    slackColorDanger,
+		Fallback: summary(ev),
+		Fields:   make([]slackField, 0, len(fields)),
+		Ts:       ev.Timestamp.Unix(),
+	}
+	for _, f := range fields {
+		attachment.Fields = append(attachment.Fields, slackField{Title: f.name, Value: f.value, Short: f.short})
+	}
+	return slackMessage{Text: summary(ev), Attachments: []slackAttachment{attachment}}
+}
+
+func discordPayload(ev Event) discordMessage {
+	fields := eventFields(ev)
+	embed := discordEmbed{
+		Title:     summary(ev),
+		Color:     discordColorError,
+		Fields:    make([]discordField, 0, len(fields)),
+		Timestamp: ev.Timestamp.Format(time.RFC3339),
+	}
+	for _, f := range fields {
+		embed.Fields = append(embed.Fields, discordField{Name: f.name, Value: f.value, Inline: f.short})
+	}
+	return discordMessage{Content: summary(ev), Embeds: []discordEmbed{embed}}
+}
+
+// summary is the one-line headline shown in chat notifications
+func summary(ev Event) string {
+	switch ev.Event {
+	case EventInstanceFailed:
+		return fmt.Sprintf("llamactl: instance %s failed", ev.Instance)
+	default:
+		return fmt.Sprintf("llamactl: %s for instance %s", ev.Event, ev.Instance)
+	}
+}
+
+func eventFields(ev Event) []field {
+	fields := []field{{name: "Instance", value: ev.Instance, short: true}}
+	if ev.ExitCode != nil {
+		fields = append(fields, field{name: "Exit code", value: strconv.Itoa(*ev.ExitCode), short: true})
+	}
+	if ev.Error != "" {
+		fields = append(fields, field{name: "Error", value: ev.Error})
+	}
+	return fields
+}
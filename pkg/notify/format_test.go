@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"encoding/json"
+	"llamactl/pkg/config"
+	"testing"
+	"time"
+)
+
+func testEvent() Event {
+	exitCode := 1
+	return Event{
+		Event:     EventInstanceFailed,
+		Instance:  "llama",
+		Error:     "exit status 1",
+		ExitCode:  &exitCode,
+		Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestFormatPayload_Raw(t *testing.T) {
+	for _, format := range []string{"", config.NotificationFormatRaw} {
+		body, err := formatPayload(testEvent(), format)
+		if err != nil {
+			t.Fatalf("formatPayload(%q) failed: %v", format, err)
+		}
+
+		var got Event
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("failed to decode raw payload: %v", err)
+		}
+		if got.Instance != "llama" || got.Error != "exit status 1" || got.ExitCode == nil || *got.ExitCode != 1 {
+			t.Errorf("unexpected raw payload for format %q: %s", format, body)
+		}
+	}
+}
+
+func TestFormatPayload_Slack(t *testing.T) {
+	body, err := formatPayload(testEvent(), config.NotificationFormatSlack)
+	if err != nil {
+		t.Fatalf("formatPayload failed: %v", err)
+	}
+
+	var got slackMessage
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to decode slack payload: %v", err)
+	}
+	if got.Text != "llamactl: instance llama failed" {
+		t.Errorf("unexpected text %q", got.Text)
+	}
+	if len(got.Attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %d", len(got.Attachments))
+	}
+
+	att := got.Attachments[0]
+	if att.Color != slackColorDanger {
+		t.Errorf("expected color %q, got %q", slackColorDanger, att.Color)
+	}
+	if att.Ts != testEvent().Timestamp.Unix() {
+		t.Errorf("expected ts %d, got %d", testEvent().Timestamp.Unix(), att.Ts)
+	}
+	want := []slackField{
+		{Title: "Instance", Value: "llama", Short: true},
+		{Title: "Exit code", Value: "1", Short: true},
+		{Title: "Error", Value: "exit status 1"},
+	}
+	if len(att.Fields) != len(want) {
+		t.Fatalf("expected %d fields, got %d", len(want), len(att.Fields))
+	}
+	for i, f := range want {
+		if att.Fields[i] != f {
+			t.Errorf("field %d: expected %+v, got %+v", i, f, att.Fields[i])
+		}
+	}
+}
+
+func TestFormatPayload_Discord(t *testing.T) {
+	body, err := formatPayload(testEvent(), config.NotificationFormatDiscord)
+	if err != nil {
+		t.Fatalf("formatPayload failed: %v", err)
+	}
+
+	var got discordMessage
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to decode discord payload: %v", err)
+	}
+	if got.Content != "llamactl: instance llama failed" {
+		t.Errorf("unexpected content %q", got.Content)
+	}
+	if len(got.Embeds) != 1 {
+		t.Fatalf("expected 1 embed, got %d", len(got.Embeds))
+	}
+
+	embed := got.Embeds[0]
+	if embed.Color != discordColorError {
+		t.Errorf("expected color %d, got %d", discordColorError, embed.Color)
+	}
+	if embed.Timestamp != "2026-01-02T03:04:05Z" {
+		t.Errorf("unexpected timestamp %q", embed.Timestamp)
+	}
+	want := []discordField{
+		{Name: "Instance", Value: "llama", Inline: true},
+		{Name: "Exit code", Value: "1", Inline: true},
+		{Name: "Error", Value: "exit status 1"},
+	}
+	if len(embed.Fields) != len(want) {
+		t.Fatalf("expected %d fields, got %d", len(want), len(embed.Fields))
+	}
+	for i, f := range want {
+		if embed.Fields[i] != f {
+			t.Errorf("field %d: expected %+v, got %+v", i, f, embed.Fields[i])
+		}
+	}
+}
+
+func TestFormatPayload_OmitsMissingExitDetails(t *testing.T) {
+	ev := Event{Event: EventInstanceFailed, Instance: "llama", Timestamp: time.Now()}
+
+	body, err := formatPayload(ev, config.NotificationFormatSlack)
+	if err != nil {
+		t.Fatalf("formatPayload failed: %v", err)
+	}
+	var got slackMessage
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to decode slack payload: %v", err)
+	}
+	if fields := got.Attachments[0].Fields; len(fields) != 1 || fields[0].Title != "Instance" {
+		t.Errorf("expected only the instance field, got %+v", fields)
+	}
+}
+
+func TestFormatPayload_UnknownFormat(t *testing.T) {
+	if _, err := formatPayload(testEvent(), "teams"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
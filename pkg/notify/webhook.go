@@ -0,0 +1,110 @@
+// Package notify delivers instance lifecycle events to external endpoints
+// such as a user-configured webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"llamactl/pkg/config"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	EventInstanceFailed = "instance_failed"
+
+	defaultTimeout  = 5 * time.Second
+	defaultAttempts = 3
+	defaultBackoff  = time.Second
+)
+
+// Event is the JSON payload posted to the webhook
+type Event struct {
+	Event     string    `json:"event"`
+	Instance  string    `json:"instance"`
+	Error     string    `json:"error,omitempty"`
+	ExitCode  *int      `json:"exit_code,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Webhook posts events to a URL. Delivery happens in the background so a
+// slow or unreachable endpoint never blocks the caller.
+type Webhook struct {
+	url      string
+	format   string
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+// NewWebhook returns a webhook sender for the configured URL and payload
+// format, or nil if no URL is set. Sending on a nil *Webhook is a no-op.
+func NewWebhook(cfg config.NotificationsConfig) *Webhook {
+	if cfg.WebhookURL == "" {
+		return nil
+	}
+	return &Webhook{
+		url:      cfg.WebhookURL,
+		format:   cfg.Format,
+		client:   &http.Client{Timeout: defaultTimeout},
+		attempts: defaultAttempts,
+		backoff:  defaultBackoff,
+	}
+}
+
+// Send delivers the event asynchronously, retrying failed attempts
+func (w *Webhook) Send(ev Event) {
+	if w == nil {
+		return
+	}
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+	go func() {
+		if err := w.deliver(ev); err != nil {
+			log.Printf("Failed to deliver %s notification for instance %s: %v", ev.Event, ev.Instance, err)
+		}
+	}()
+}
+
+// deliver posts the event, retrying with exponential backoff
+func (w *Webhook) deliver(ev Event) error {
+	body, err := formatPayload(ev, w.format)
+	if err != nil {
+		return fmt.Errorf("failed to format event: %w", err)
+	}
+
+	backoff := w.backoff
+	for attempt := 1; ; attempt++ {
+		err = w.post(body)
+		if err == nil || attempt >= w.attempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (w *Webhook) post(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"encoding/json"
+	"llamactl/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhook_DeliverRetriesUntilSuccess(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan Event, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var ev Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		received <- ev
+	}))
+	defer srv.Close()
+
+	w := NewWebhook(config.NotificationsConfig{WebhookURL: srv.URL})
+	w.backoff = time.Millisecond
+
+	exitCode := 139
+	w.Send(Event{Event: EventInstanceFailed, Instance: "llama", Error: "signal: segmentation fault", ExitCode: &exitCode})
+
+	select {
+	case ev := <-received:
+		if ev.Instance != "llama" || ev.Event != EventInstanceFailed {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+		if ev.ExitCode == nil || *ev.ExitCode != 139 {
+			t.Errorf("expected exit code 139, got %v", ev.ExitCode)
+		}
+		if ev.Timestamp.IsZero() {
+			t.Error("expected timestamp to be set")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestWebhook_DeliverGivesUpAfterAttempts(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	w := NewWebhook(config.NotificationsConfig{WebhookURL: srv.URL})
+	w.backoff = time.Millisecond
+
+	if err := w.deliver(Event{Event: EventInstanceFailed, Instance: "llama"}); err == nil {
+		t.Fatal("expected delivery error")
+	}
+	if got := calls.Load(); got != defaultAttempts {
+		t.Errorf("expected %d attempts, got %d", defaultAttempts, got)
+	}
+}
+
+func TestNewWebhook_EmptyURLIsNoop(t *testing.T) {
+	w := NewWebhook(config.NotificationsConfig{})
+	if w != nil {
+		t.Fatal("expected nil webhook for empty URL")
+	}
+	// Must not panic
+	w.Send(Event{Event: EventInstanceFailed, Instance: "llama"})
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"llamactl/pkg/auth"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// auditActions names the mutating routes, keyed by method and chi route
+// pattern. Routes missing here are still audited under "METHOD pattern".
+var auditActions = map[string]string{
+	"POST /api/v1/instances/actions/import":               "instance.import",
+	"POST /api/v1/instances/{name}/":                      "instance.create",
+	"PUT /api/v1/instances/{name}/":                       "instance.update",
+	"DELETE /api/v1/instances/{name}/":                    "instance.delete",
+	"POST /api/v1/instances/{name}/start":                 "instance.start",
+	"POST /api/v1/instances/{name}/stop":                  "instance.stop",
+	"POST /api/v1/instances/{name}/restart":               "instance.restart",
+	"POST /api/v1/nodes/{name}/drain":                     "node.drain",
+	"DELETE /api/v1/nodes/{name}/drain":                   "node.undrain",
+	"POST /api/v1/auth/keys/":                             "key.create",
+	"DELETE /api/v1/auth/keys/{id}":                       "key.delete",
+	"POST /api/v1/models/download":                        "model.download",
+	"DELETE /api/v1/models/":                              "model.delete",
+	"DELETE /api/v1/models/jobs/{id}":                     "model.job.delete",
+	"POST /api/v1/llama-cpp/{name}/models/{model}/load":   "model.load",
+	"POST /api/v1/llama-cpp/{name}/models/{model}/unload": "model.unload",
+}
+
+// AuditMiddleware records successful mutating management API requests in the
+// audit log. It must run after ManagementAuthMiddleware so the caller is known.
+func (h *Handler) AuditMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isMutatingRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status < 200 || status >= 300 {
+				return
+			}
+
+			entry := auditEntryFor(r, status)
+			// The request context may already be cancelled once the client has its response
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+			defer cancel()
+			if err := h.authStore.RecordAudit(ctx, entry); err != nil {
+				log.Printf("Failed to record audit entry for %s %s: %v", r.Method, r.URL.Path, err)
+			}
+		})
+	}
+}
+
+// auditEntryFor builds the audit entry for a request that has been routed
+func auditEntryFor(r *http.Request, status int) *auth.AuditEntry {
+	entry := &auth.AuditEntry{
+		CreatedAt: time.Now().Unix(),
+		Actor:     "anonymous",
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Status:    status,
+	}
+
+	if key, ok := r.Context().Value(apiKeyContextKey).(*auth.APIKey); ok {
+		keyID := key.ID
+		entry.KeyID = &keyID
+		entry.Actor = key.Name
+	} else if actor, ok := r.Context().Value(managementActorContextKey).(string); ok {
+		entry.Actor = actor
+	}
+
+	pattern := r.Method + " " + r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		pattern = r.Method + " " + rctx.RoutePattern()
+
+		// Target is the routed URL parameters, e.g. "llama" or "llama/model.gguf"
+		var params []string
+		for i, key := range rctx.URLParams.Keys {
+			if key != "*" && rctx.URLParams.Values[i] != "" {
+				params = append(params, rctx.URLParams.Values[i])
+			}
+		}
+		entry.Target = strings.Join(params, "/")
+	}
+
+	entry.Action = pattern
+	if action, ok := auditActions[pattern]; ok {
+		entry.Action = action
+	}
+
+	return entry
+}
@@ -0,0 +1,86 @@
+package server_test
+
+import (
+	"context"
+	"llamactl/pkg/auth"
+	"llamactl/pkg/config"
+	"llamactl/pkg/server"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	db := openTestDB(t)
+
+	cfg := config.AppConfig{
+		Auth: config.AuthConfig{
+			RequireManagementAuth: true,
+			ManagementKeys:        []string{"sk-management-admin"},
+		},
+	}
+	handler := server.NewHandler(nil, nil, cfg, db)
+	authMiddleware := server.NewAPIAuthMiddleware(cfg.Auth, db)
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := chi.NewRouter()
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(authMiddleware.ManagementAuthMiddleware())
+		r.Use(handler.AuditMiddleware())
+		r.Route("/instances", func(r chi.Router) {
+			r.Route("/{name}", func(r chi.Router) {
+				r.Get("/", ok)
+				r.Post("/start", ok)
+				r.Post("/stop", func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusConflict)
+				})
+				r.HandleFunc("/proxy/*", ok)
+			})
+		})
+	})
+
+	for _, req := range []struct{ method, path string }{
+		{"GET", "/api/v1/instances/llama/"},                  // read, not audited
+		{"POST", "/api/v1/instances/llama/start"},            // audited
+		{"POST", "/api/v1/instances/llama/stop"},             // failed, not audited
+		{"POST", "/api/v1/instances/llama/proxy/completion"}, // inference, not audited
+	} {
+		httpReq := httptest.NewRequest(req.method, req.path, nil)
+		httpReq.Header.Set("Authorization", "Bearer sk-management-admin")
+		r.ServeHTTP(httptest.NewRecorder(), httpReq)
+	}
+
+	entries, err := db.ListAudit(context.Background(), auth.AuditFilter{})
+	if err != nil {
+		t.Fatalf("ListAudit failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 audit entry, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Action != "instance.start" || entry.Target != "llama" {
+		t.Errorf("Expected instance.start on llama, got %s on %q", entry.Action, entry.Target)
+	}
+	if entry.KeyID != nil || !strings.HasPrefix(entry.Actor, "management:") {
+		t.Errorf("Expected management key actor, got key_id=%v actor=%q", entry.KeyID, entry.Actor)
+	}
+	if strings.Contains(entry.Actor, "sk-management-admin") {
+		t.Error("Audit actor must not contain the key itself")
+	}
+	if entry.Status != http.StatusOK || entry.Path != "/api/v1/instances/llama/start" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	// Filters narrow the listing
+	filtered, err := db.ListAudit(context.Background(), auth.AuditFilter{Action: "instance.stop"})
+	if err != nil {
+		t.Fatalf("ListAudit failed: %v", err)
+	}
+	if len(filtered) != 0 {
+		t.Errorf("Expected no instance.stop entries, got %d", len(filtered))
+	}
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"llamactl/pkg/apierrors"
 	"llamactl/pkg/config"
 	"llamactl/pkg/database"
 	"llamactl/pkg/instance"
@@ -11,6 +12,7 @@ import (
 	"llamactl/pkg/validation"
 	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -31,6 +33,15 @@ func writeError(w http.ResponseWriter, status int, code, details string) {
 	}
 }
 
+// writeTypedError writes a JSON error response using the status and code carried by
+// a typed error from the apierrors package, falling back to the given status and code
+func writeTypedError(w http.ResponseWriter, err error, status int, code, details string) {
+	if kind, ok := apierrors.As(err); ok {
+		status, code = kind.Status, string(kind.Code)
+	}
+	writeError(w, status, code, details)
+}
+
 // writeJSON writes a JSON response with the specified HTTP status code
 func writeJSON(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
@@ -57,6 +68,15 @@ type Handler struct {
 	httpClient      *http.Client
 	authStore       database.AuthStore
 	authMiddleware  *APIAuthMiddleware
+
+	// Set once shutdown begins; proxy handlers reject new requests after that
+	shuttingDown atomic.Bool
+
+	// Confirmation tokens for stopping all instances
+	stopAllTokens *confirmTokens
+
+	// llama.cpp version reported by /version
+	llamaCppVersion versionCache
 }
 
 // NewHandler creates a new Handler instance with the provided instance manager and configuration
@@ -68,12 +88,29 @@ func NewHandler(im manager.InstanceManager, mm *models.Manager, cfg config.AppCo
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		authStore: authStore,
+		authStore:     authStore,
+		stopAllTokens: newConfirmTokens(),
 	}
 	handler.authMiddleware = NewAPIAuthMiddleware(cfg.Auth, authStore)
 	return handler
 }
 
+// BeginShutdown starts draining: new proxy requests get 503 and readiness
+// reports unavailable, while requests already in flight run to completion
+func (h *Handler) BeginShutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// rejectIfShuttingDown writes a 503 and returns true once shutdown has begun
+func (h *Handler) rejectIfShuttingDown(w http.ResponseWriter) bool {
+	if !h.shuttingDown.Load() {
+		return false
+	}
+	w.Header().Set("Connection", "close")
+	writeError(w, http.StatusServiceUnavailable, "server_shutting_down", "Server is shutting down")
+	return true
+}
+
 // getInstance retrieves an instance by name from request query parameters
 func (h *Handler) getInstance(r *http.Request) (*instance.Instance, error) {
 	name := chi.URLParam(r, "name")
@@ -123,7 +160,7 @@ func (h *Handler) ensureInstanceRunning(inst *instance.Instance) error {
 
 func (h *Handler) rejectIfAtCapacity() error {
 	if h.InstanceManager.AtMaxRunning() {
-		return fmt.Errorf("cannot start instance, maximum number of instances reached")
+		return apierrors.Newf(apierrors.ErrMaxRunningInstances, "cannot start instance, maximum number of instances reached")
 	}
 	return nil
 }
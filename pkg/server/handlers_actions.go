@@ -0,0 +1,131 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"llamactl/pkg/instance"
+	"llamactl/pkg/manager"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// confirmTokenTTL is how long a bulk action confirmation token stays valid
+const confirmTokenTTL = time.Minute
+
+// confirmTokens issues single-use tokens that a destructive bulk action must
+// echo back, so it can't be triggered by a single stray request
+type confirmTokens struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time // token -> expiry
+}
+
+func newConfirmTokens() *confirmTokens {
+	return &confirmTokens{tokens: make(map[string]time.Time)}
+}
+
+// issue returns a new token and when it expires
+func (c *confirmTokens) issue() (string, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for token, expiry := range c.tokens {
+		if now.After(expiry) {
+			delete(c.tokens, token)
+		}
+	}
+
+	token := rand.Text()
+	expiry := now.Add(confirmTokenTTL)
+	c.tokens[token] = expiry
+	return token, expiry
+}
+
+// consume reports whether token was issued and hasn't expired, and
+// invalidates it either way
+func (c *confirmTokens) consume(token string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for issued, expiry := range c.tokens {
+		if subtle.ConstantTimeCompare([]byte(issued), []byte(token)) == 1 {
+			delete(c.tokens, issued)
+			return time.Now().Before(expiry)
+		}
+	}
+	return false
+}
+
+// StopAllPreview lists what a stop-all would affect and the token to confirm it with
+type StopAllPreview struct {
+	ConfirmToken string    `json:"confirm_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Instances    []string  `json:"instances"` // Running instances, local and remote
+}
+
+// StopAllResponse reports the outcome of a stop-all for each instance
+type StopAllResponse struct {
+	Results []manager.StopResult `json:"results"`
+}
+
+// StopAllPreview godoc
+// @Summary Prepare stopping all instances
+// @Description Lists the running instances and returns a single-use token, valid for one minute, to pass to POST /api/v1/instances/actions/stop-all
+// @Tags Instances
+// @Security ApiKeyAuth
+// @Produces json
+// @Success 200 {object} StopAllPreview "Running instances and confirmation token"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/v1/instances/actions/stop-all [get]
+func (h *Handler) StopAllPreview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		running, err := h.InstanceManager.ListInstancesByStatus(instance.Running)
+		if err != nil {
+			writeTypedError(w, err, http.StatusInternalServerError, "list_failed", "Failed to list instances: "+err.Error())
+			return
+		}
+
+		names := make([]string, 0, len(running))
+		for _, inst := range running {
+			names = append(names, inst.Name)
+		}
+
+		token, expiresAt := h.stopAllTokens.issue()
+		writeJSON(w, http.StatusOK, StopAllPreview{
+			ConfirmToken: token,
+			ExpiresAt:    expiresAt,
+			Instances:    names,
+		})
+	}
+}
+
+// StopAll godoc
+// @Summary Stop all running instances
+// @Description Stops every running instance, local and remote, and reports the result for each.
+// @Description The confirm token must come from a GET to the same path and can only be used once.
+// @Tags Instances
+// @Security ApiKeyAuth
+// @Produces json
+// @Param confirm query string true "Token from GET /api/v1/instances/actions/stop-all"
+// @Success 200 {object} StopAllResponse "Per-instance results"
+// @Failure 400 {string} string "Missing, expired or unknown confirm token"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/v1/instances/actions/stop-all [post]
+func (h *Handler) StopAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.stopAllTokens.consume(r.URL.Query().Get("confirm")) {
+			writeError(w, http.StatusBadRequest, "confirmation_required",
+				"A valid confirm token is required, get one from GET /api/v1/instances/actions/stop-all")
+			return
+		}
+
+		results, err := h.InstanceManager.StopAllInstances()
+		if err != nil {
+			writeTypedError(w, err, http.StatusInternalServerError, "stop_failed", "Failed to stop instances: "+err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, StopAllResponse{Results: results})
+	}
+}
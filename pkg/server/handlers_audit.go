@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+	"llamactl/pkg/auth"
+	"net/http"
+	"strconv"
+)
+
+// maxAuditLimit caps how many audit entries a single request can return
+const maxAuditLimit = 1000
+
+// AuditEntryResponse represents an audit log entry in responses.
+type AuditEntryResponse struct {
+	ID        int    `json:"id"`
+	CreatedAt int64  `json:"created_at"`
+	KeyID     *int   `json:"key_id"`
+	Actor     string `json:"actor"`
+	Action    string `json:"action"`
+	Target    string `json:"target"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	Status    int    `json:"status"`
+}
+
+// ListAudit godoc
+// @Summary List audit log entries
+// @Description Returns successful mutating API requests, newest first.
+// @Description Config management keys appear as "management:<hash prefix>", database keys by name.
+// @Tags System
+// @Security ApiKeyAuth
+// @Produce json
+// @Param action query string false "Filter by action, e.g. instance.start"
+// @Param target query string false "Filter by target, e.g. an instance name"
+// @Param actor query string false "Filter by actor"
+// @Param since query int false "Only entries at or after this Unix timestamp"
+// @Param until query int false "Only entries at or before this Unix timestamp"
+// @Param limit query int false "Maximum number of entries (default 100, max 1000)"
+// @Success 200 {array} AuditEntryResponse "Audit log entries"
+// @Failure 400 {string} string "Invalid filter"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/v1/audit [get]
+func (h *Handler) ListAudit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := auth.AuditFilter{
+			Action: query.Get("action"),
+			Target: query.Get("target"),
+			Actor:  query.Get("actor"),
+		}
+
+		for _, param := range []struct {
+			name string
+			dest *int64
+		}{
+			{"since", &filter.Since},
+			{"until", &filter.Until},
+		} {
+			if value := query.Get(param.name); value != "" {
+				n, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || n < 0 {
+					writeError(w, http.StatusBadRequest, "invalid_parameter", param.name+" must be a Unix timestamp")
+					return
+				}
+				*param.dest = n
+			}
+		}
+
+		if value := query.Get("limit"); value != "" {
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit <= 0 || limit > maxAuditLimit {
+				writeError(w, http.StatusBadRequest, "invalid_parameter", fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit))
+				return
+			}
+			filter.Limit = limit
+		}
+
+		entries, err := h.authStore.ListAudit(r.Context(), filter)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "fetch_failed", fmt.Sprintf("Failed to fetch audit log: %v", err))
+			return
+		}
+
+		response := make([]AuditEntryResponse, 0, len(entries))
+		for _, entry := range entries {
+			response = append(response, AuditEntryResponse{
+				ID:        entry.ID,
+				CreatedAt: entry.CreatedAt,
+				KeyID:     entry.KeyID,
+				Actor:     entry.Actor,
+				Action:    entry.Action,
+				Target:    entry.Target,
+				Method:    entry.Method,
+				Path:      entry.Path,
+				Status:    entry.Status,
+			})
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
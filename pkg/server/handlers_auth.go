@@ -2,8 +2,10 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"llamactl/pkg/auth"
+	"llamactl/pkg/database"
 	"net/http"
 	"strconv"
 	"time"
@@ -17,6 +19,8 @@ type CreateKeyRequest struct {
 	PermissionMode auth.PermissionMode `json:"permission_mode"`
 	ExpiresAt      *int64              `json:"expires_at,omitempty"`
 	InstanceIDs    []int               `json:"instance_ids,omitempty"`
+	// Backend path patterns the key may request on its instances (per_instance only)
+	AllowedPaths []string `json:"allowed_paths,omitempty"`
 }
 
 // CreateKeyResponse represents the response returned when creating a new API key.
@@ -46,8 +50,25 @@ type KeyResponse struct {
 
 // KeyPermissionResponse represents the permissions for an API key on a specific instance.
 type KeyPermissionResponse struct {
-	InstanceID   int    `json:"instance_id"`
-	InstanceName string `json:"instance_name"`
+	InstanceID   int      `json:"instance_id"`
+	InstanceName string   `json:"instance_name"`
+	AllowedPaths []string `json:"allowed_paths,omitempty"`
+}
+
+// WhoAmIResponse describes the caller's credentials and what they may access.
+type WhoAmIResponse struct {
+	// "management" for config management keys, "api_key" for inference keys,
+	// "anonymous" when no key was presented and auth is disabled
+	Type           string              `json:"type"`
+	ID             *int                `json:"id,omitempty"`
+	Name           string              `json:"name,omitempty"`
+	PermissionMode auth.PermissionMode `json:"permission_mode,omitempty"`
+	ExpiresAt      *int64              `json:"expires_at,omitempty"`
+	// Management API access: "full", "read" or "none"
+	ManagementAccess string `json:"management_access"`
+	// True when every instance may be used for inference, including ones created later
+	AllInstances bool                    `json:"all_instances"`
+	Instances    []KeyPermissionResponse `json:"instances"`
 }
 
 // CreateKey godoc
@@ -78,14 +99,26 @@ func (h *Handler) CreateKey() http.HandlerFunc {
 			writeError(w, http.StatusBadRequest, "invalid_name", "Name must be 100 characters or less")
 			return
 		}
-		if req.PermissionMode != auth.PermissionModeAllowAll && req.PermissionMode != auth.PermissionModePerInstance {
-			writeError(w, http.StatusBadRequest, "invalid_permission_mode", "Permission mode must be 'allow_all' or 'per_instance'")
+		switch req.PermissionMode {
+		case auth.PermissionModeAllowAll, auth.PermissionModePerInstance, auth.PermissionModeReadOnly:
+		default:
+			writeError(w, http.StatusBadRequest, "invalid_permission_mode", "Permission mode must be 'allow_all', 'per_instance' or 'read_only'")
 			return
 		}
 		if req.PermissionMode == auth.PermissionModePerInstance && len(req.InstanceIDs) == 0 {
 			writeError(w, http.StatusBadRequest, "missing_permissions", "Instance IDs required when permission mode is 'per_instance'")
 			return
 		}
+		if len(req.AllowedPaths) > 0 && req.PermissionMode != auth.PermissionModePerInstance {
+			writeError(w, http.StatusBadRequest, "invalid_allowed_paths", "Allowed paths require permission mode 'per_instance'")
+			return
+		}
+		for _, pattern := range req.AllowedPaths {
+			if err := auth.ValidatePathPattern(pattern); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_allowed_paths", err.Error())
+				return
+			}
+		}
 		if req.ExpiresAt != nil && *req.ExpiresAt <= time.Now().Unix() {
 			writeError(w, http.StatusBadRequest, "invalid_expires_at", "Expiration time must be in future")
 			return
@@ -141,8 +174,9 @@ func (h *Handler) CreateKey() http.HandlerFunc {
 		var keyPermissions []auth.KeyPermission
 		for _, instanceID := range req.InstanceIDs {
 			keyPermissions = append(keyPermissions, auth.KeyPermission{
-				KeyID:      0, // Will be set by database after key creation
-				InstanceID: instanceID,
+				KeyID:        0, // Will be set by database after key creation
+				InstanceID:   instanceID,
+				AllowedPaths: req.AllowedPaths,
 			})
 		}
 
@@ -232,7 +266,7 @@ func (h *Handler) GetKey() http.HandlerFunc {
 
 		key, err := h.authStore.GetKeyByID(r.Context(), id)
 		if err != nil {
-			if err.Error() == "API key not found" {
+			if errors.Is(err, database.ErrKeyNotFound) {
 				writeError(w, http.StatusNotFound, "not_found", "API key not found")
 				return
 			}
@@ -279,7 +313,7 @@ func (h *Handler) DeleteKey() http.HandlerFunc {
 
 		err = h.authStore.DeleteKey(r.Context(), id)
 		if err != nil {
-			if err.Error() == "API key not found" {
+			if errors.Is(err, database.ErrKeyNotFound) {
 				writeError(w, http.StatusNotFound, "not_found", "API key not found")
 				return
 			}
@@ -315,7 +349,7 @@ func (h *Handler) GetKeyPermissions() http.HandlerFunc {
 		// Verify key exists
 		_, err = h.authStore.GetKeyByID(r.Context(), id)
 		if err != nil {
-			if err.Error() == "API key not found" {
+			if errors.Is(err, database.ErrKeyNotFound) {
 				writeError(w, http.StatusNotFound, "not_found", "API key not found")
 				return
 			}
@@ -345,6 +379,7 @@ func (h *Handler) GetKeyPermissions() http.HandlerFunc {
 			response = append(response, KeyPermissionResponse{
 				InstanceID:   perm.InstanceID,
 				InstanceName: instanceNameMap[perm.InstanceID],
+				AllowedPaths: perm.AllowedPaths,
 			})
 		}
 
@@ -352,3 +387,73 @@ func (h *Handler) GetKeyPermissions() http.HandlerFunc {
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// WhoAmI godoc
+// @Summary Describe the calling API key
+// @Description Returns the presented key's name, permission mode and the instances it can use. Accepts management keys and inference keys of any permission mode.
+// @Tags Keys
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} WhoAmIResponse "Caller details"
+// @Failure 401 {string} string "Missing or invalid API key"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/v1/auth/whoami [get]
+func (h *Handler) WhoAmI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := WhoAmIResponse{Type: "anonymous", ManagementAccess: "full", AllInstances: true}
+
+		apiKey, isKey := r.Context().Value(apiKeyContextKey).(*auth.APIKey)
+		if isKey {
+			id := apiKey.ID
+			response.Type = "api_key"
+			response.ID = &id
+			response.Name = apiKey.Name
+			response.PermissionMode = apiKey.PermissionMode
+			response.ExpiresAt = apiKey.ExpiresAt
+			response.AllInstances = apiKey.PermissionMode != auth.PermissionModePerInstance
+
+			switch {
+			case !h.cfg.Auth.RequireManagementAuth:
+				response.ManagementAccess = "full"
+			case apiKey.PermissionMode == auth.PermissionModeReadOnly:
+				response.ManagementAccess = "read"
+			default:
+				response.ManagementAccess = "none"
+			}
+		} else if _, ok := r.Context().Value(managementActorContextKey).(string); ok {
+			response.Type = "management"
+		}
+
+		instances, err := h.InstanceManager.ListInstances()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "fetch_instances_failed", fmt.Sprintf("Failed to fetch instances: %v", err))
+			return
+		}
+
+		response.Instances = make([]KeyPermissionResponse, 0, len(instances))
+		if response.AllInstances {
+			for _, inst := range instances {
+				response.Instances = append(response.Instances, KeyPermissionResponse{InstanceID: inst.ID, InstanceName: inst.Name})
+			}
+		} else {
+			permissions, err := h.authStore.GetPermissions(r.Context(), apiKey.ID)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "fetch_failed", fmt.Sprintf("Failed to fetch permissions: %v", err))
+				return
+			}
+			instanceNameMap := make(map[int]string)
+			for _, inst := range instances {
+				instanceNameMap[inst.ID] = inst.Name
+			}
+			for _, perm := range permissions {
+				response.Instances = append(response.Instances, KeyPermissionResponse{
+					InstanceID:   perm.InstanceID,
+					InstanceName: instanceNameMap[perm.InstanceID],
+					AllowedPaths: perm.AllowedPaths,
+				})
+			}
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
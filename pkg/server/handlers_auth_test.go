@@ -0,0 +1,33 @@
+package server_test
+
+import (
+	"llamactl/pkg/config"
+	"llamactl/pkg/server"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKeyHandlers_NotFound(t *testing.T) {
+	router := server.SetupRouter(newProxyTestHandler(t, config.AppConfig{}, http.NotFoundHandler()))
+
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/v1/auth/keys/999"},
+		{http.MethodDelete, "/api/v1/auth/keys/999"},
+		{http.MethodGet, "/api/v1/auth/keys/999/permissions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "not_found") {
+				t.Errorf("Expected 404 not_found, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
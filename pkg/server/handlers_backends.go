@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
 	"llamactl/pkg/instance"
 	"net/http"
 	"os/exec"
@@ -54,15 +55,18 @@ func (h *Handler) stripLlamaCppPrefix(r *http.Request, instName string) {
 // @Router /llama-cpp/{name}/ [get]
 func (h *Handler) LlamaCppUIProxy() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if h.rejectIfShuttingDown(w) {
+			return
+		}
 
 		inst, err := h.validateLlamaCppInstance(r)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid instance", err.Error())
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
 			return
 		}
 
 		if !inst.IsRemote() && !inst.IsRunning() {
-			writeError(w, http.StatusBadRequest, "instance is not running", "Instance is not running")
+			writeError(w, http.StatusBadRequest, "instance_not_running", "Instance is not running")
 			return
 		}
 
@@ -102,10 +106,13 @@ func (h *Handler) LlamaCppUIProxy() http.HandlerFunc {
 // @Router /llama-cpp/{name}/tokenize [post]
 func (h *Handler) LlamaCppProxy() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if h.rejectIfShuttingDown(w) {
+			return
+		}
 
 		inst, err := h.validateLlamaCppInstance(r)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid instance", err.Error())
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
 			return
 		}
 
@@ -114,6 +121,11 @@ func (h *Handler) LlamaCppProxy() http.HandlerFunc {
 			writeError(w, http.StatusForbidden, "permission_denied", err.Error())
 			return
 		}
+		backendPath := strings.TrimPrefix(r.URL.Path, fmt.Sprintf("/llama-cpp/%s", inst.Name))
+		if err := h.authMiddleware.CheckPathPermission(r.Context(), inst.ID, backendPath); err != nil {
+			writeError(w, http.StatusForbidden, "permission_denied", err.Error())
+			return
+		}
 
 		// Check if instance is shutting down before autostart logic
 		if inst.GetStatus() == instance.ShuttingDown {
@@ -124,7 +136,7 @@ func (h *Handler) LlamaCppProxy() http.HandlerFunc {
 		if !inst.IsRemote() && !inst.IsRunning() {
 			err := h.ensureInstanceRunning(inst)
 			if err != nil {
-				writeError(w, http.StatusInternalServerError, "instance start failed", err.Error())
+				writeTypedError(w, err, http.StatusInternalServerError, "instance_start_failed", err.Error())
 				return
 			}
 		}
@@ -255,13 +267,112 @@ func (h *Handler) ParseVllmCommand() http.HandlerFunc {
 	}
 }
 
+// ParseLlamaRpcCommand godoc
+// @Summary Parse rpc-server command
+// @Description Parses a llama.cpp rpc-server command string into instance options
+// @Tags Backends
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body ParseCommandRequest true "Command to parse"
+// @Success 200 {object} instance.Options "Parsed options"
+// @Failure 400 {object} map[string]string "Invalid request or command"
+// @Router /api/v1/backends/llama-rpc/parse-command [post]
+func (h *Handler) ParseLlamaRpcCommand() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parsedOptions, ok := parseHelper(w, r, &backends.LlamaRpcServerOptions{})
+		if !ok {
+			return
+		}
+
+		options := &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:           backends.BackendTypeLlamaRpc,
+				LlamaRpcServerOptions: parsedOptions.(*backends.LlamaRpcServerOptions),
+			},
+		}
+
+		writeJSON(w, http.StatusOK, options)
+	}
+}
+
+// BuildCommandRequest represents the request body for building a backend command
+type BuildCommandRequest struct {
+	BackendOptions  map[string]any `json:"backend_options"`
+	DockerEnabled   *bool          `json:"docker_enabled,omitempty"`
+	CommandOverride string         `json:"command_override,omitempty"`
+}
+
+// BuildCommandResponse is the command llamactl would run for the given options
+type BuildCommandResponse struct {
+	Command     string   `json:"command"`
+	Args        []string `json:"args"`
+	CommandLine string   `json:"command_line"`
+}
+
+// BuildBackendCommand godoc
+// @Summary Build backend command
+// @Description Builds the command line llamactl would run for the given backend options, using the configured backend command and default args
+// @Tags Backends
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body BuildCommandRequest true "Backend options"
+// @Success 200 {object} BuildCommandResponse "Built command"
+// @Failure 400 {object} map[string]string "Invalid request or options"
+// @Router /api/v1/backends/llama-cpp/build-command [post]
+// @Router /api/v1/backends/mlx/build-command [post]
+// @Router /api/v1/backends/vllm/build-command [post]
+// @Router /api/v1/backends/llama-rpc/build-command [post]
+func (h *Handler) BuildBackendCommand(backendType backends.BackendType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req BuildCommandRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
+			return
+		}
+
+		// Round trip through JSON so backend_options gets the same alias
+		// handling and extra_args collection as instance options
+		data, err := json.Marshal(map[string]any{
+			"backend_type":    backendType,
+			"backend_options": req.BackendOptions,
+		})
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+		var opts backends.Options
+		if err := json.Unmarshal(data, &opts); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_options", err.Error())
+			return
+		}
+		if err := opts.ValidateInstanceOptions(); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_options", err.Error())
+			return
+		}
+
+		command := opts.GetCommand(&h.cfg.Backends, req.DockerEnabled, req.CommandOverride)
+		args := opts.BuildCommandArgs(&h.cfg.Backends, req.DockerEnabled)
+		// Configured args and docker -e flags may carry tokens, which
+		// GET /config redacts as well
+		config.RedactArgs(args)
+
+		writeJSON(w, http.StatusOK, BuildCommandResponse{
+			Command:     command,
+			Args:        args,
+			CommandLine: backends.JoinCommandLine(append([]string{command}, args...)),
+		})
+	}
+}
+
 // executeLlamaServerCommand executes a llama-server command with the specified flag and returns the output
 func (h *Handler) executeLlamaServerCommand(flag, errorMsg string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		cmd := exec.Command("llama-server", flag)
 		output, err := cmd.CombinedOutput()
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "command failed", errorMsg+": "+err.Error())
+			writeError(w, http.StatusInternalServerError, "command_failed", errorMsg+": "+err.Error())
 			return
 		}
 		writeText(w, http.StatusOK, string(output))
@@ -294,6 +405,20 @@ func (h *Handler) LlamaServerVersionHandler() http.HandlerFunc {
 	return h.executeLlamaServerCommand("--version", "Failed to get version")
 }
 
+// CheckBackends godoc
+// @Summary Check backend commands
+// @Description Runs each configured backend command once (with --version or --help) to confirm it is installed and executable. For backends that run in Docker, the container runtime is checked instead.
+// @Tags Backends
+// @Security ApiKeyAuth
+// @Produces json
+// @Success 200 {array} backends.CommandCheck "Check result per backend"
+// @Router /api/v1/backends/check [get]
+func (h *Handler) CheckBackends() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, backends.CheckCommands(r.Context(), &h.cfg.Backends))
+	}
+}
+
 // LlamaServerListDevicesHandler godoc
 // @Summary List available devices for llama server
 // @Description Returns a list of available devices for the llama server
@@ -322,7 +447,7 @@ func (h *Handler) LlamaCppListModels() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		inst, err := h.validateLlamaCppInstance(r)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid instance", err.Error())
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
 			return
 		}
 
@@ -341,7 +466,7 @@ func (h *Handler) LlamaCppListModels() http.HandlerFunc {
 		if !inst.IsRemote() && !inst.IsRunning() {
 			err := h.ensureInstanceRunning(inst)
 			if err != nil {
-				writeError(w, http.StatusInternalServerError, "instance start failed", err.Error())
+				writeTypedError(w, err, http.StatusInternalServerError, "instance_start_failed", err.Error())
 				return
 			}
 		}
@@ -374,7 +499,7 @@ func (h *Handler) LlamaCppLoadModel() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		inst, err := h.validateLlamaCppInstance(r)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid instance", err.Error())
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
 			return
 		}
 
@@ -393,7 +518,7 @@ func (h *Handler) LlamaCppLoadModel() http.HandlerFunc {
 		if !inst.IsRemote() && !inst.IsRunning() {
 			err := h.ensureInstanceRunning(inst)
 			if err != nil {
-				writeError(w, http.StatusInternalServerError, "instance start failed", err.Error())
+				writeTypedError(w, err, http.StatusInternalServerError, "instance_start_failed", err.Error())
 				return
 			}
 		}
@@ -426,7 +551,7 @@ func (h *Handler) LlamaCppUnloadModel() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		inst, err := h.validateLlamaCppInstance(r)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid instance", err.Error())
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
 			return
 		}
 
@@ -445,7 +570,7 @@ func (h *Handler) LlamaCppUnloadModel() http.HandlerFunc {
 		if !inst.IsRemote() && !inst.IsRunning() {
 			err := h.ensureInstanceRunning(inst)
 			if err != nil {
-				writeError(w, http.StatusInternalServerError, "instance start failed", err.Error())
+				writeTypedError(w, err, http.StatusInternalServerError, "instance_start_failed", err.Error())
 				return
 			}
 		}
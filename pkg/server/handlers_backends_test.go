@@ -0,0 +1,116 @@
+package server_test
+
+import (
+	"encoding/json"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
+	"llamactl/pkg/server"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBuildBackendCommand(t *testing.T) {
+	cfg := config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: "/opt/llama/llama-server", Args: []string{"--log-timestamps"}},
+			VLLM:     config.BackendSettings{Command: "vllm", Args: []string{"serve"}},
+		},
+	}
+	handler := server.NewHandler(nil, nil, cfg, nil)
+
+	build := func(backendType backends.BackendType, body string) (*httptest.ResponseRecorder, server.BuildCommandResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.BuildBackendCommand(backendType).ServeHTTP(w, req)
+
+		var resp server.BuildCommandResponse
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return w, resp
+	}
+
+	t.Run("llama.cpp", func(t *testing.T) {
+		w, resp := build(backends.BackendTypeLlamaCpp, `{"backend_options": {"model": "/models/my model.gguf", "ngl": 99}}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if resp.Command != "/opt/llama/llama-server" {
+			t.Errorf("Expected configured command, got %q", resp.Command)
+		}
+		want := []string{"--log-timestamps", "--gpu-layers", "99", "--model", "/models/my model.gguf"}
+		if !slices.Equal(resp.Args, want) {
+			t.Errorf("Expected args %v, got %v", want, resp.Args)
+		}
+		if resp.CommandLine != "/opt/llama/llama-server --log-timestamps --gpu-layers 99 --model '/models/my model.gguf'" {
+			t.Errorf("Unexpected command line %q", resp.CommandLine)
+		}
+
+		// The command line parses back into the same options
+		parsed, err := (&backends.LlamaServerOptions{}).ParseCommand(resp.CommandLine)
+		if err != nil {
+			t.Fatalf("ParseCommand failed: %v", err)
+		}
+		if opts := parsed.(*backends.LlamaServerOptions); opts.Model != "/models/my model.gguf" || opts.GPULayers != 99 {
+			t.Errorf("Round trip mismatch: %+v", opts)
+		}
+	})
+
+	t.Run("command override", func(t *testing.T) {
+		_, resp := build(backends.BackendTypeVllm, `{"backend_options": {"model": "org/model"}, "command_override": "/venv/bin/vllm"}`)
+		if resp.Command != "/venv/bin/vllm" || !slices.Equal(resp.Args, []string{"serve", "org/model"}) {
+			t.Errorf("Unexpected command %q %v", resp.Command, resp.Args)
+		}
+	})
+
+	t.Run("redacts secrets", func(t *testing.T) {
+		secretCfg := config.AppConfig{
+			Backends: config.BackendConfig{
+				LlamaCpp: config.BackendSettings{
+					Command: "llama-server",
+					Args:    []string{"--api-key", "sk-backend"},
+					Docker: &config.DockerSettings{
+						Enabled: true,
+						Image:   "llama",
+						Args:    []string{"run", "--rm", "-e", "HF_TOKEN=hf_abc"},
+					},
+				},
+			},
+		}
+		secretHandler := server.NewHandler(nil, nil, secretCfg, nil)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"backend_options": {"model": "/m.gguf"}}`))
+		w := httptest.NewRecorder()
+		secretHandler.BuildBackendCommand(backends.BackendTypeLlamaCpp).ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if body := w.Body.String(); strings.Contains(body, "sk-backend") || strings.Contains(body, "hf_abc") {
+			t.Errorf("Expected secrets to be redacted, got %s", body)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		w, _ := build(backends.BackendTypeLlamaCpp, `{"backend_options": {"model": "/m.gguf", "mmap": true, "no_mmap": true}}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400, got %d", w.Code)
+		}
+	})
+}
+
+func TestCheckBackends_EmptyList(t *testing.T) {
+	handler := server.NewHandler(nil, nil, config.AppConfig{}, nil)
+
+	w := httptest.NewRecorder()
+	handler.CheckBackends().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != "[]" {
+		t.Errorf("Expected an empty JSON array without backends, got %s", got)
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"llamactl/pkg/config"
+	"llamactl/pkg/instance"
+	"llamactl/pkg/validation"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// instanceBundleFormatVersion is bumped whenever the bundle layout changes incompatibly
+const instanceBundleFormatVersion = 1
+
+// InstanceBundle is a portable, self-contained instance definition that can be
+// exported from one llamactl server and imported into another
+type InstanceBundle struct {
+	FormatVersion   int               `json:"format_version"`
+	Name            string            `json:"name"`
+	ExportedAt      time.Time         `json:"exported_at"`
+	LlamactlVersion string            `json:"llamactl_version,omitempty"`
+	Options         *instance.Options `json:"options"`
+}
+
+// portableOptions returns a deep copy of opts with server-specific settings
+// (the assigned port and node placement) removed. Unless includeSecrets is
+// set, stdin_data and environment variables that look like secrets are
+// left out as well.
+func portableOptions(opts *instance.Options, includeSecrets bool) (*instance.Options, error) {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	var portable instance.Options
+	if err := json.Unmarshal(data, &portable); err != nil {
+		return nil, err
+	}
+	portable.BackendOptions.SetPort(0)
+	portable.Nodes = nil
+
+	if !includeSecrets {
+		portable.StdinData = ""
+		for name := range portable.Environment {
+			if config.IsSensitiveName(name) {
+				delete(portable.Environment, name)
+			}
+		}
+	}
+	return &portable, nil
+}
+
+// ExportInstance godoc
+// @Summary Export an instance as a portable bundle
+// @Description Returns the instance's options together with export metadata.
+// @Description The server-assigned port and node placement are left out so the bundle can be imported elsewhere.
+// @Description stdin_data and environment variables that look like secrets are left out unless include_secrets is set.
+// @Tags Instances
+// @Security ApiKeyAuth
+// @Produces json
+// @Param name path string true "Instance Name"
+// @Param include_secrets query bool false "Include stdin_data and secret-looking environment variables"
+// @Success 200 {object} InstanceBundle "Instance bundle"
+// @Failure 400 {string} string "Invalid name format"
+// @Failure 403 {string} string "Read-only API key requested secrets"
+// @Failure 404 {string} string "Instance not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/v1/instances/{name}/export [get]
+func (h *Handler) ExportInstance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		validatedName, err := validation.ValidateInstanceName(name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_instance_name", err.Error())
+			return
+		}
+
+		inst, err := h.InstanceManager.GetInstance(validatedName)
+		if err != nil {
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
+			return
+		}
+
+		includeSecrets := r.URL.Query().Get("include_secrets") == "true"
+		if includeSecrets && isReadOnlyKey(r.Context()) {
+			writeError(w, http.StatusForbidden, "permission_denied", "Read-only API key cannot export secrets")
+			return
+		}
+		opts, err := portableOptions(inst.GetOptions(), includeSecrets)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "export_failed", "Failed to export instance: "+err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", validatedName+".json"))
+		writeJSON(w, http.StatusOK, InstanceBundle{
+			FormatVersion:   instanceBundleFormatVersion,
+			Name:            validatedName,
+			ExportedAt:      time.Now().UTC(),
+			LlamactlVersion: h.cfg.Version,
+			Options:         opts,
+		})
+	}
+}
+
+// ImportInstance godoc
+// @Summary Import an instance from a bundle
+// @Description Creates a new instance from a bundle produced by the export endpoint.
+// @Description The options are validated like a regular create and a fresh port is allocated.
+// @Tags Instances
+// @Security ApiKeyAuth
+// @Accept json
+// @Produces json
+// @Param name query string false "Create the instance under this name instead of the one in the bundle"
+// @Param bundle body InstanceBundle true "Instance bundle"
+// @Success 201 {object} instance.Instance "Created instance details"
+// @Failure 400 {string} string "Invalid bundle"
+// @Failure 409 {string} string "Instance already exists"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/v1/instances/actions/import [post]
+func (h *Handler) ImportInstance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var bundle InstanceBundle
+		if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
+			return
+		}
+
+		if bundle.FormatVersion != instanceBundleFormatVersion {
+			writeError(w, http.StatusBadRequest, "unsupported_bundle",
+				fmt.Sprintf("unsupported bundle format_version %d (expected %d)", bundle.FormatVersion, instanceBundleFormatVersion))
+			return
+		}
+		if bundle.Options == nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", "bundle has no options")
+			return
+		}
+
+		name := bundle.Name
+		if override := r.URL.Query().Get("name"); override != "" {
+			name = override
+		}
+		validatedName, err := validation.ValidateInstanceName(name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_instance_name", err.Error())
+			return
+		}
+
+		// A bundle may be hand-edited or come from an older export; never
+		// carry over a port or node placement from the source server
+		bundle.Options.BackendOptions.SetPort(0)
+		bundle.Options.Nodes = nil
+
+		inst, err := h.InstanceManager.CreateInstance(validatedName, bundle.Options)
+		if err != nil {
+			writeTypedError(w, err, http.StatusInternalServerError, "import_failed", "Failed to import instance: "+err.Error())
+			return
+		}
+
+		writeInstanceData(w, r, http.StatusCreated, inst)
+	}
+}
@@ -0,0 +1,127 @@
+package server_test
+
+import (
+	"encoding/json"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
+	"llamactl/pkg/instance"
+	"llamactl/pkg/server"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportInstance(t *testing.T) {
+	handler := newProxyTestHandler(t, config.AppConfig{}, http.NotFoundHandler())
+	if _, err := handler.InstanceManager.CreateInstance("source", &instance.Options{
+		Environment: map[string]string{"HF_TOKEN": "hf_secret", "CUDA_VISIBLE_DEVICES": "0"},
+		StdinData:   "api_key: secret\n",
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf", Port: 8123},
+		},
+	}); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	router := server.SetupRouter(handler)
+
+	export := func(query string) server.InstanceBundle {
+		t.Helper()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/instances/source/export"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var bundle server.InstanceBundle
+		if err := json.Unmarshal(w.Body.Bytes(), &bundle); err != nil {
+			t.Fatalf("Failed to decode bundle: %v", err)
+		}
+		return bundle
+	}
+
+	bundle := export("")
+	if bundle.Name != "source" || bundle.FormatVersion != 1 || bundle.Options == nil {
+		t.Fatalf("Unexpected bundle: %+v", bundle)
+	}
+	if port := bundle.Options.BackendOptions.GetPort(); port != 0 {
+		t.Errorf("Expected the port to be left out, got %d", port)
+	}
+	if _, ok := bundle.Options.Environment["HF_TOKEN"]; ok {
+		t.Error("Expected secret environment variables to be left out by default")
+	}
+	if bundle.Options.StdinData != "" {
+		t.Error("Expected stdin_data to be left out by default")
+	}
+	if bundle.Options.Environment["CUDA_VISIBLE_DEVICES"] != "0" {
+		t.Errorf("Expected other environment variables to be kept, got %v", bundle.Options.Environment)
+	}
+
+	bundle = export("?include_secrets=true")
+	if bundle.Options.Environment["HF_TOKEN"] != "hf_secret" {
+		t.Errorf("Expected include_secrets to keep secret environment variables, got %v", bundle.Options.Environment)
+	}
+	if bundle.Options.StdinData != "api_key: secret\n" {
+		t.Errorf("Expected include_secrets to keep stdin_data, got %q", bundle.Options.StdinData)
+	}
+
+	// The source instance itself keeps its secrets
+	inst, _ := handler.InstanceManager.GetInstance("source")
+	if inst.GetOptions().Environment["HF_TOKEN"] != "hf_secret" {
+		t.Error("Export modified the instance options")
+	}
+}
+
+func TestImportInstance(t *testing.T) {
+	handler := newProxyTestHandler(t, config.AppConfig{}, http.NotFoundHandler())
+	router := server.SetupRouter(handler)
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return w
+	}
+	bundle := `{"format_version": 1, "name": "imported", "options": {"backend_type": "llama_cpp", "backend_options": {"model": "/path/to/model.gguf", "port": 8123}, "nodes": ["elsewhere"]}}`
+
+	if w := post("/api/v1/instances/actions/import", bundle); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	inst, err := handler.InstanceManager.GetInstance("imported")
+	if err != nil {
+		t.Fatalf("Expected imported instance: %v", err)
+	}
+	if _, ok := inst.GetOptions().Nodes["elsewhere"]; ok {
+		t.Errorf("Expected node placement from the bundle to be dropped, got %v", inst.GetOptions().Nodes)
+	}
+
+	if w := post("/api/v1/instances/actions/import?name=renamed", bundle); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 for a renamed import, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := handler.InstanceManager.GetInstance("renamed"); err != nil {
+		t.Errorf("Expected instance under the new name: %v", err)
+	}
+
+	if w := post("/api/v1/instances/actions/import", bundle); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for an existing name, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := post("/api/v1/instances/actions/import", `{"format_version": 99, "name": "future", "options": {}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unsupported format version, got %d", w.Code)
+	}
+	if w := post("/api/v1/instances/actions/import", `{"format_version": 1, "name": "empty"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bundle without options, got %d", w.Code)
+	}
+}
+
+func TestImportInstance_DoesNotShadowInstanceNamedImport(t *testing.T) {
+	handler := newProxyTestHandler(t, config.AppConfig{}, http.NotFoundHandler())
+
+	w := httptest.NewRecorder()
+	body := `{"backend_type": "llama_cpp", "backend_options": {"model": "/path/to/model.gguf"}}`
+	server.SetupRouter(handler).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/instances/import", strings.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 creating an instance named import, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := handler.InstanceManager.GetInstance("import"); err != nil {
+		t.Errorf("Expected instance named import: %v", err)
+	}
+}
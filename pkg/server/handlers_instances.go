@@ -3,10 +3,13 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"llamactl/pkg/config"
 	"llamactl/pkg/instance"
-	"llamactl/pkg/manager"
 	"llamactl/pkg/validation"
+	"log"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -15,33 +18,78 @@ import (
 
 // ListInstances godoc
 // @Summary List all instances
-// @Description Returns a list of all instances managed by the server
+// @Description Returns a list of all instances managed by the server, optionally only those in the given status or with the given labels
 // @Tags Instances
 // @Security ApiKeyAuth
 // @Produces json
+// @Param status query string false "Only list instances in this status" Enums(stopped, running, failed, restarting, shutting_down)
+// @Param label.key query string false "Only list instances whose label key has this value; repeat with other keys to require several labels"
 // @Success 200 {array} instance.Instance "List of instances"
+// @Failure 400 {string} string "Invalid status"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /api/v1/instances [get]
 func (h *Handler) ListInstances() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		instances, err := h.InstanceManager.ListInstances()
+		var (
+			instances []*instance.Instance
+			err       error
+		)
+		if statusParam := r.URL.Query().Get("status"); statusParam != "" {
+			status, ok := instance.ParseStatus(statusParam)
+			if !ok {
+				writeError(w, http.StatusBadRequest, "invalid_status", fmt.Sprintf("Unknown instance status %q", statusParam))
+				return
+			}
+			instances, err = h.InstanceManager.ListInstancesByStatus(status)
+		} else {
+			instances, err = h.InstanceManager.ListInstances()
+		}
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "list_failed", "Failed to list instances: "+err.Error())
+			writeTypedError(w, err, http.StatusInternalServerError, "list_failed", "Failed to list instances: "+err.Error())
 			return
 		}
 
-		writeJSON(w, http.StatusOK, instances)
+		if selector := labelSelector(r.URL.Query()); len(selector) > 0 {
+			instances = slices.DeleteFunc(instances, func(inst *instance.Instance) bool {
+				return !inst.MatchesLabels(selector)
+			})
+		}
+
+		response := make([]any, 0, len(instances))
+		for _, inst := range instances {
+			data, err := apiInstance(inst, isReadOnlyKey(r.Context()))
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "encode_failed", "Failed to encode instance: "+err.Error())
+				return
+			}
+			response = append(response, data)
+		}
+		writeJSON(w, http.StatusOK, response)
 	}
 }
 
+// labelSelector collects the label.<key>=<value> query parameters
+func labelSelector(query url.Values) map[string]string {
+	selector := make(map[string]string)
+	for param, values := range query {
+		if key, ok := strings.CutPrefix(param, "label."); ok && key != "" && len(values) > 0 {
+			selector[key] = values[0]
+		}
+	}
+	return selector
+}
+
 // CreateInstance godoc
 // @Summary Create and start a new instance
-// @Description Creates a new instance with the provided configuration options
+// @Description Creates a new instance with the provided configuration options.
+// @Description With start=true the instance is also started, and removed again if starting fails.
+// @Description Non-fatal problems with the options, such as a ctx_size above instances.max_ctx_size, are listed in a "warnings" field.
 // @Tags Instances
 // @Security ApiKeyAuth
 // @Accept json
 // @Produces json
 // @Param name path string true "Instance Name"
+// @Param start query bool false "Start the instance after creating it"
 // @Param options body instance.Options true "Instance configuration options"
 // @Success 201 {object} instance.Instance "Created instance details"
 // @Failure 400 {string} string "Invalid request body"
@@ -56,6 +104,15 @@ func (h *Handler) CreateInstance() http.HandlerFunc {
 			return
 		}
 
+		start := false
+		if startParam := r.URL.Query().Get("start"); startParam != "" {
+			start, err = strconv.ParseBool(startParam)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid_parameter", "start must be a boolean")
+				return
+			}
+		}
+
 		var options instance.Options
 		if err := json.NewDecoder(r.Body).Decode(&options); err != nil {
 			writeError(w, http.StatusBadRequest, "invalid_request", "Invalid request body")
@@ -64,11 +121,215 @@ func (h *Handler) CreateInstance() http.HandlerFunc {
 
 		inst, err := h.InstanceManager.CreateInstance(validatedName, &options)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "create_failed", "Failed to create instance: "+err.Error())
+			writeTypedError(w, err, http.StatusInternalServerError, "create_failed", "Failed to create instance: "+err.Error())
 			return
 		}
 
-		writeJSON(w, http.StatusCreated, inst)
+		if start {
+			if _, err := h.InstanceManager.StartInstance(validatedName); err != nil {
+				h.rollbackCreate(validatedName)
+				writeTypedError(w, err, http.StatusInternalServerError, "start_failed", "Failed to start instance, creation rolled back: "+err.Error())
+				return
+			}
+		}
+
+		writeInstance(w, r, http.StatusCreated, inst)
+	}
+}
+
+// writeInstance writes the instance as JSON, adding a "warnings" list when its
+// options have non-fatal problems the client should know about
+func writeInstance(w http.ResponseWriter, r *http.Request, status int, inst *instance.Instance) {
+	writeInstanceWith(w, r, status, inst, nil)
+}
+
+// writeInstanceWith writes the instance like writeInstance, with extra
+// top-level fields added to the JSON object
+func writeInstanceWith(w http.ResponseWriter, r *http.Request, status int, inst *instance.Instance, extra map[string]any) {
+	warnings := inst.Warnings()
+	if len(warnings) == 0 && len(extra) == 0 {
+		writeInstanceData(w, r, status, inst)
+		return
+	}
+
+	fields, err := instanceFields(inst, isReadOnlyKey(r.Context()))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "encode_failed", "Failed to encode instance: "+err.Error())
+		return
+	}
+	for key, value := range extra {
+		if fields[key], err = json.Marshal(value); err != nil {
+			writeError(w, http.StatusInternalServerError, "encode_failed", "Failed to encode instance: "+err.Error())
+			return
+		}
+	}
+	if len(warnings) > 0 {
+		fields["warnings"], _ = json.Marshal(warnings)
+	}
+
+	for _, warning := range warnings {
+		log.Printf("Instance %s: %s", inst.Name, warning)
+	}
+	writeJSON(w, status, fields)
+}
+
+// writeInstanceData writes the instance as JSON without warnings, e.g. in
+// the response to a start or stop
+func writeInstanceData(w http.ResponseWriter, r *http.Request, status int, inst *instance.Instance) {
+	data, err := apiInstance(inst, isReadOnlyKey(r.Context()))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "encode_failed", "Failed to encode instance: "+err.Error())
+		return
+	}
+	writeJSON(w, status, data)
+}
+
+// apiInstance returns the instance as the API sends it. stdin_data often
+// carries secrets, so an instance with stdin_data is sent as its JSON object
+// with the data redacted. With redactEnv, environment variables that look
+// like secrets are redacted too, for callers that may only monitor.
+func apiInstance(inst *instance.Instance, redactEnv bool) (any, error) {
+	if opts := inst.GetOptions(); opts == nil || (opts.StdinData == "" && !(redactEnv && hasSensitiveEnvironment(opts))) {
+		return inst, nil
+	}
+	return instanceFields(inst, redactEnv)
+}
+
+// hasSensitiveEnvironment reports whether the options set an environment
+// variable whose name looks like a secret
+func hasSensitiveEnvironment(opts *instance.Options) bool {
+	for name := range opts.Environment {
+		if config.IsSensitiveName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// instanceFields returns the fields of the instance's JSON object, with
+// stdin_data redacted, and with redactEnv also secret-looking environment
+// variables
+func instanceFields(inst *instance.Instance, redactEnv bool) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(inst)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	opts := inst.GetOptions()
+	redactStdin := opts != nil && opts.StdinData != ""
+	redactEnv = redactEnv && opts != nil && hasSensitiveEnvironment(opts)
+	if !redactStdin && !redactEnv {
+		return fields, nil
+	}
+
+	var options map[string]json.RawMessage
+	if err := json.Unmarshal(fields["options"], &options); err != nil {
+		return nil, err
+	}
+	if redactStdin {
+		options["stdin_data"], _ = json.Marshal(config.RedactedValue)
+	}
+	if redactEnv {
+		environment := make(map[string]string, len(opts.Environment))
+		for name, value := range opts.Environment {
+			if config.IsSensitiveName(name) {
+				value = config.RedactedValue
+			}
+			environment[name] = value
+		}
+		options["environment"], _ = json.Marshal(environment)
+	}
+	if fields["options"], err = json.Marshal(options); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// ProxyPreflight answers OPTIONS requests to the backend proxies with the
+// configured CORS headers, without forwarding them to the backend
+func (h *Handler) ProxyPreflight() http.HandlerFunc {
+	return h.writePreflight
+}
+
+// writePreflight writes a 204 response to an OPTIONS request. Browser
+// preflights that the CORS middleware already answered never get here; this
+// covers the rest, e.g. from clients that leave out
+// Access-Control-Request-Method.
+func (h *Handler) writePreflight(w http.ResponseWriter, r *http.Request) {
+	headers := w.Header()
+	methods := strings.Join(corsAllowedMethods, ", ")
+	headers.Set("Allow", methods)
+
+	headers.Add("Vary", "Origin")
+	if origin := r.Header.Get("Origin"); origin != "" && h.originAllowed(origin) {
+		headers.Set("Access-Control-Allow-Origin", origin)
+		headers.Set("Access-Control-Allow-Methods", methods)
+		if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			if slices.Contains(h.cfg.Server.AllowedHeaders, "*") {
+				headers.Set("Access-Control-Allow-Headers", requested)
+			} else {
+				headers.Set("Access-Control-Allow-Headers", strings.Join(h.cfg.Server.AllowedHeaders, ", "))
+			}
+		}
+		headers.Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// originAllowed matches an origin against server.allowed_origins like the
+// CORS middleware does: an empty list or "*" allows all, and an entry may
+// contain one "*" wildcard
+func (h *Handler) originAllowed(origin string) bool {
+	allowed := h.cfg.Server.AllowedOrigins
+	if len(allowed) == 0 {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(pattern, "*"); ok &&
+			len(origin) >= len(prefix)+len(suffix) &&
+			strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripPathPrefix removes prefix from the URL's path, keeping the escaped
+// form in sync so encoded characters such as %2F reach the backend as sent.
+// An empty result becomes "/".
+func stripPathPrefix(u *url.URL, prefix string) {
+	u.Path = strings.TrimPrefix(u.Path, prefix)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if u.RawPath != "" {
+		u.RawPath = strings.TrimPrefix(u.RawPath, (&url.URL{Path: prefix}).EscapedPath())
+		if u.RawPath == "" {
+			u.RawPath = "/"
+		}
+	}
+}
+
+// rollbackCreate removes an instance that was created but failed to start,
+// so a failed create-and-start doesn't leave it (and its port) behind
+func (h *Handler) rollbackCreate(name string) {
+	if inst, err := h.InstanceManager.GetInstance(name); err == nil && inst.IsRunning() {
+		if _, err := h.InstanceManager.StopInstance(name); err != nil {
+			log.Printf("Failed to stop instance %s during rollback: %v", name, err)
+		}
+	}
+	if err := h.InstanceManager.DeleteInstance(name); err != nil {
+		log.Printf("Failed to remove instance %s after failed start: %v", name, err)
 	}
 }
 
@@ -94,11 +355,40 @@ func (h *Handler) GetInstance() http.HandlerFunc {
 
 		inst, err := h.InstanceManager.GetInstance(validatedName)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid_instance", err.Error())
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
 			return
 		}
 
-		writeJSON(w, http.StatusOK, inst)
+		writeInstanceData(w, r, http.StatusOK, inst)
+	}
+}
+
+// GetInstanceByID godoc
+// @Summary Get details of an instance by ID
+// @Description Returns the details of an instance by its numeric ID, as used in API key permissions
+// @Tags Instances
+// @Security ApiKeyAuth
+// @Produces json
+// @Param id path int true "Instance ID"
+// @Success 200 {object} instance.Instance "Instance details"
+// @Failure 400 {string} string "Invalid instance ID"
+// @Failure 404 {string} string "Instance not found"
+// @Router /api/v1/instances/by-id/{id} [get]
+func (h *Handler) GetInstanceByID() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid_id", "Invalid instance ID")
+			return
+		}
+
+		inst, err := h.InstanceManager.GetInstanceByID(id)
+		if err != nil {
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
+			return
+		}
+
+		writeInstanceData(w, r, http.StatusOK, inst)
 	}
 }
 
@@ -111,7 +401,7 @@ func (h *Handler) GetInstance() http.HandlerFunc {
 // @Produces json
 // @Param name path string true "Instance Name"
 // @Param options body instance.Options true "Instance configuration options"
-// @Success 200 {object} instance.Instance "Updated instance details"
+// @Success 200 {object} instance.Instance "Updated instance details, with the changed options in changes"
 // @Failure 400 {string} string "Invalid name format"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /api/v1/instances/{name} [put]
@@ -130,13 +420,24 @@ func (h *Handler) UpdateInstance() http.HandlerFunc {
 			return
 		}
 
-		inst, err := h.InstanceManager.UpdateInstance(validatedName, &options)
+		// stdin_data is redacted in responses; sending the placeholder back
+		// keeps the current data
+		if options.StdinData == config.RedactedValue {
+			if current, err := h.InstanceManager.GetInstance(validatedName); err == nil && current.GetOptions() != nil {
+				options.StdinData = current.GetOptions().StdinData
+			}
+		}
+
+		inst, changes, err := h.InstanceManager.UpdateInstance(validatedName, &options)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "update_failed", "Failed to update instance: "+err.Error())
+			writeTypedError(w, err, http.StatusInternalServerError, "update_failed", "Failed to update instance: "+err.Error())
 			return
 		}
 
-		writeJSON(w, http.StatusOK, inst)
+		if changes == nil {
+			changes = []string{}
+		}
+		writeInstanceWith(w, r, http.StatusOK, inst, map[string]any{"changes": changes})
 	}
 }
 
@@ -162,17 +463,11 @@ func (h *Handler) StartInstance() http.HandlerFunc {
 
 		inst, err := h.InstanceManager.StartInstance(validatedName)
 		if err != nil {
-			// Check if error is due to maximum running instances limit
-			if _, ok := err.(manager.MaxRunningInstancesError); ok {
-				writeError(w, http.StatusConflict, "max_instances_reached", err.Error())
-				return
-			}
-
-			writeError(w, http.StatusInternalServerError, "start_failed", "Failed to start instance: "+err.Error())
+			writeTypedError(w, err, http.StatusInternalServerError, "start_failed", "Failed to start instance: "+err.Error())
 			return
 		}
 
-		writeJSON(w, http.StatusOK, inst)
+		writeInstanceData(w, r, http.StatusOK, inst)
 	}
 }
 
@@ -198,11 +493,11 @@ func (h *Handler) StopInstance() http.HandlerFunc {
 
 		inst, err := h.InstanceManager.StopInstance(validatedName)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "stop_failed", "Failed to stop instance: "+err.Error())
+			writeTypedError(w, err, http.StatusInternalServerError, "stop_failed", "Failed to stop instance: "+err.Error())
 			return
 		}
 
-		writeJSON(w, http.StatusOK, inst)
+		writeInstanceData(w, r, http.StatusOK, inst)
 	}
 }
 
@@ -228,11 +523,11 @@ func (h *Handler) RestartInstance() http.HandlerFunc {
 
 		inst, err := h.InstanceManager.RestartInstance(validatedName)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "restart_failed", "Failed to restart instance: "+err.Error())
+			writeTypedError(w, err, http.StatusInternalServerError, "restart_failed", "Failed to restart instance: "+err.Error())
 			return
 		}
 
-		writeJSON(w, http.StatusOK, inst)
+		writeInstanceData(w, r, http.StatusOK, inst)
 	}
 }
 
@@ -256,7 +551,7 @@ func (h *Handler) DeleteInstance() http.HandlerFunc {
 		}
 
 		if err := h.InstanceManager.DeleteInstance(validatedName); err != nil {
-			writeError(w, http.StatusInternalServerError, "delete_failed", "Failed to delete instance: "+err.Error())
+			writeTypedError(w, err, http.StatusInternalServerError, "delete_failed", "Failed to delete instance: "+err.Error())
 			return
 		}
 
@@ -299,7 +594,7 @@ func (h *Handler) GetInstanceLogs() http.HandlerFunc {
 		// Use the instance manager which handles both local and remote instances
 		logs, err := h.InstanceManager.GetInstanceLogs(validatedName, numLines)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "logs_failed", "Failed to get logs: "+err.Error())
+			writeTypedError(w, err, http.StatusInternalServerError, "logs_failed", "Failed to get logs: "+err.Error())
 			return
 		}
 
@@ -307,6 +602,124 @@ func (h *Handler) GetInstanceLogs() http.HandlerFunc {
 	}
 }
 
+// GetInstanceMetadata godoc
+// @Summary Get model metadata from a specific instance
+// @Description Queries the running backend (llama.cpp /props, vLLM /v1/models) and returns normalized model metadata such as context size and chat template. The result is cached until the instance restarts.
+// @Tags Instances
+// @Security ApiKeyAuth
+// @Produces json
+// @Param name path string true "Instance Name"
+// @Success 200 {object} instance.Metadata "Instance metadata"
+// @Failure 400 {string} string "Invalid name format or unsupported backend"
+// @Failure 404 {string} string "Instance not found"
+// @Failure 409 {string} string "Instance is not running"
+// @Failure 502 {string} string "Backend request failed"
+// @Router /api/v1/instances/{name}/metadata [get]
+func (h *Handler) GetInstanceMetadata() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		validatedName, err := validation.ValidateInstanceName(name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_instance_name", err.Error())
+			return
+		}
+
+		md, err := h.InstanceManager.GetInstanceMetadata(validatedName)
+		if err != nil {
+			writeTypedError(w, err, http.StatusInternalServerError, "metadata_failed", "Failed to get metadata: "+err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, md)
+	}
+}
+
+// GetInstanceOpenAPISpec godoc
+// @Summary Get the OpenAPI spec of a specific instance's backend
+// @Description Returns the backend's own OpenAPI document (vLLM) or a minimal synthesized one (llama.cpp, MLX). The servers list points at the instance proxy so clients can call the documented endpoints through llamactl.
+// @Tags Instances
+// @Security ApiKeyAuth
+// @Produces json
+// @Param name path string true "Instance Name"
+// @Success 200 {object} map[string]any "OpenAPI document"
+// @Failure 400 {string} string "Invalid name format"
+// @Failure 404 {string} string "Instance not found"
+// @Failure 409 {string} string "Instance is not running"
+// @Failure 502 {string} string "Backend request failed"
+// @Router /api/v1/instances/{name}/openapi [get]
+func (h *Handler) GetInstanceOpenAPISpec() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		validatedName, err := validation.ValidateInstanceName(name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_instance_name", err.Error())
+			return
+		}
+
+		spec, err := h.InstanceManager.GetInstanceOpenAPISpec(validatedName)
+		if err != nil {
+			writeTypedError(w, err, http.StatusInternalServerError, "openapi_failed", "Failed to get OpenAPI spec: "+err.Error())
+			return
+		}
+
+		// A backend answering with a JSON null decodes to a nil map
+		if spec == nil {
+			spec = map[string]any{}
+		}
+
+		// Paths in the document are relative to the backend root, which is
+		// reachable through the instance proxy under the configured base path
+		spec["servers"] = []map[string]string{
+			{"url": h.cfg.Server.BasePath + "/api/v1/instances/" + url.PathEscape(validatedName) + "/proxy"},
+		}
+
+		writeJSON(w, http.StatusOK, spec)
+	}
+}
+
+// InstanceStats represents request statistics for an instance
+type InstanceStats struct {
+	InflightRequests      int32 `json:"inflight_requests"`
+	QueuedRequests        int32 `json:"queued_requests"`
+	MaxConcurrentRequests int   `json:"max_concurrent_requests"`
+	LastRequestTime       int64 `json:"last_request_time"`
+	// Proxied request totals since llamactl started
+	instance.RequestStats
+}
+
+// GetInstanceStats godoc
+// @Summary Get request statistics for a specific instance
+// @Description Returns inflight and queued request counts, request totals and a latency histogram for a specific instance by name
+// @Tags Instances
+// @Security ApiKeyAuth
+// @Produces json
+// @Param name path string true "Instance Name"
+// @Success 200 {object} InstanceStats "Instance request statistics"
+// @Failure 400 {string} string "Invalid name format"
+// @Failure 404 {string} string "Instance not found"
+// @Router /api/v1/instances/{name}/stats [get]
+func (h *Handler) GetInstanceStats() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inst, err := h.getInstance(r)
+		if err != nil {
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
+			return
+		}
+
+		stats := InstanceStats{
+			InflightRequests: inst.GetInflightRequests(),
+			QueuedRequests:   inst.GetQueuedRequests(),
+			LastRequestTime:  inst.LastRequestTime(),
+			RequestStats:     inst.GetRequestStats(),
+		}
+		if opts := inst.GetOptions(); opts != nil && opts.MaxConcurrentRequests != nil {
+			stats.MaxConcurrentRequests = *opts.MaxConcurrentRequests
+		}
+
+		writeJSON(w, http.StatusOK, stats)
+	}
+}
+
 // InstanceProxy godoc
 // @Summary Proxy requests to a specific instance, does not autostart instance if stopped
 // @Description Forwards HTTP requests to the llama-server instance running on a specific port
@@ -319,11 +732,25 @@ func (h *Handler) GetInstanceLogs() http.HandlerFunc {
 // @Failure 503 {string} string "Instance is not running"
 // @Router /api/v1/instances/{name}/proxy [get]
 // @Router /api/v1/instances/{name}/proxy [post]
+// @Router /api/v1/instances/{name}/proxy [put]
+// @Router /api/v1/instances/{name}/proxy [patch]
+// @Router /api/v1/instances/{name}/proxy [delete]
 func (h *Handler) InstanceProxy() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// OPTIONS requests carry no credentials, so they are answered here
+		// rather than forwarded to the backend
+		if r.Method == http.MethodOptions {
+			h.writePreflight(w, r)
+			return
+		}
+
+		if h.rejectIfShuttingDown(w) {
+			return
+		}
+
 		inst, err := h.getInstance(r)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid_instance", err.Error())
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
 			return
 		}
 
@@ -333,15 +760,25 @@ func (h *Handler) InstanceProxy() http.HandlerFunc {
 			return
 		}
 
+		// Strip the "/api/v1/instances/<name>/proxy" prefix from the request URL;
+		// any server.base_path was already removed when the router was mounted
+		prefix := fmt.Sprintf("/api/v1/instances/%s/proxy", inst.Name)
+		backendPath := strings.TrimPrefix(r.URL.Path, prefix)
+		if backendPath == "" {
+			backendPath = "/"
+		}
+		if err := h.authMiddleware.CheckPathPermission(r.Context(), inst.ID, backendPath); err != nil {
+			writeError(w, http.StatusForbidden, "permission_denied", err.Error())
+			return
+		}
+
 		if !inst.IsRunning() {
 			writeError(w, http.StatusServiceUnavailable, "instance_not_running", "Instance is not running")
 			return
 		}
 
 		if !inst.IsRemote() {
-			// Strip the "/api/v1/instances/<name>/proxy" prefix from the request URL
-			prefix := fmt.Sprintf("/api/v1/instances/%s/proxy", inst.Name)
-			r.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+			stripPathPrefix(r.URL, prefix)
 		}
 
 		// Set forwarded headers
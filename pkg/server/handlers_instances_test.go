@@ -0,0 +1,567 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"llamactl/pkg/auth"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
+	"llamactl/pkg/database"
+	"llamactl/pkg/instance"
+	"llamactl/pkg/manager"
+	"llamactl/pkg/server"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newProxyTestRouter returns a router with a running instance "llama" whose
+// backend is the given handler
+func newProxyTestRouter(t *testing.T, cfg config.AppConfig, backendHandler http.Handler) http.Handler {
+	t.Helper()
+	return server.SetupRouter(newProxyTestHandler(t, cfg, backendHandler))
+}
+
+// newProxyTestHandler is newProxyTestRouter without the router, for tests
+// that need to reach the handler itself
+func newProxyTestHandler(t *testing.T, cfg config.AppConfig, backendHandler http.Handler) *server.Handler {
+	t.Helper()
+	backend := httptest.NewServer(backendHandler)
+	t.Cleanup(backend.Close)
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	cfg.Instances = config.InstancesConfig{
+		PortRange:    [2]int{1024, 65535},
+		MaxInstances: 10,
+		LogsDir:      t.TempDir(),
+		InstancesDir: t.TempDir(),
+	}
+	cfg.LocalNode = "main"
+	cfg.Nodes = map[string]config.NodeConfig{}
+	im := manager.New(&cfg, database.NewMemoryStore())
+	t.Cleanup(im.Shutdown)
+
+	inst, err := im.CreateInstance("llama", &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+				Host:  backendURL.Hostname(),
+				Port:  port,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	// Pretend the backend is running, it is served by the test server
+	inst.SetStatus(instance.Running)
+	t.Cleanup(func() { inst.SetStatus(instance.Stopped) })
+
+	return server.NewHandler(im, nil, cfg, openTestDB(t))
+}
+
+func TestInstanceProxy_AnyMethodAndPath(t *testing.T) {
+	router := newProxyTestRouter(t, config.AppConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, r.Method+" "+r.URL.EscapedPath()+" "+string(body))
+	}))
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/api/v1/instances/llama/proxy", "GET / "},
+		{http.MethodGet, "/api/v1/instances/llama/proxy/", "GET / "},
+		{http.MethodPut, "/api/v1/instances/llama/proxy/lora-adapters", "PUT /lora-adapters body"},
+		{http.MethodPatch, "/api/v1/instances/llama/proxy/slots/0", "PATCH /slots/0 body"},
+		{http.MethodDelete, "/api/v1/instances/llama/proxy/models/a%2Fb", "DELETE /models/a%2Fb body"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			var body io.Reader
+			if tt.method != http.MethodGet {
+				body = strings.NewReader("body")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, body))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("Expected backend to see %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestInstanceProxy_AnswersOptions(t *testing.T) {
+	var backendHits atomic.Int32
+	cfg := config.AppConfig{
+		Server: config.ServerConfig{
+			AllowedOrigins: []string{"https://*.example.com"},
+			AllowedHeaders: []string{"Authorization", "Content-Type"},
+		},
+	}
+	router := newProxyTestRouter(t, cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendHits.Add(1)
+	}))
+
+	tests := []struct {
+		name       string
+		path       string
+		origin     string
+		wantOrigin string
+	}{
+		{"allowed origin", "/api/v1/instances/llama/proxy/v1/chat/completions", "https://app.example.com", "https://app.example.com"},
+		{"other origin", "/api/v1/instances/llama/proxy/v1/chat/completions", "https://evil.test", ""},
+		{"no origin", "/api/v1/instances/llama/proxy/", "", ""},
+		{"llama-cpp proxy", "/llama-cpp/llama/completion", "https://app.example.com", "https://app.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No Access-Control-Request-Method, so the CORS middleware
+			// passes the request on
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			req.Header.Set("Access-Control-Request-Headers", "authorization")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("Expected 204, got %d: %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.wantOrigin, got)
+			}
+			if tt.wantOrigin != "" && w.Header().Get("Access-Control-Allow-Headers") != "Authorization, Content-Type" {
+				t.Errorf("Expected configured allowed headers, got %q", w.Header().Get("Access-Control-Allow-Headers"))
+			}
+			if !strings.Contains(w.Header().Get("Allow"), "PATCH") {
+				t.Errorf("Expected Allow header to list methods, got %q", w.Header().Get("Allow"))
+			}
+		})
+	}
+
+	if n := backendHits.Load(); n != 0 {
+		t.Errorf("Expected OPTIONS requests not to reach the backend, got %d", n)
+	}
+}
+
+func TestInstanceProxy_DrainsOnShutdown(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := newProxyTestHandler(t, config.AppConfig{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(entered)
+			<-release
+		}
+		io.WriteString(w, "ok")
+	}))
+	router := server.SetupRouter(handler)
+
+	// Start a request before shutdown begins and hold it in the backend
+	inFlight := make(chan *httptest.ResponseRecorder)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/instances/llama/proxy/slow", nil))
+		inFlight <- w
+	}()
+	<-entered
+
+	handler.BeginShutdown()
+
+	for _, path := range []string{"/api/v1/instances/llama/proxy/health", "/llama-cpp/llama/props", "/readyz"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected 503 while draining, got %d: %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	// Management endpoints keep working during the drain
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/instances/llama", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected management API to answer while draining, got %d", w.Code)
+	}
+
+	close(release)
+	if w := <-inFlight; w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Errorf("Expected in-flight request to finish, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestListInstances_StatusFilter(t *testing.T) {
+	router := newProxyTestRouter(t, config.AppConfig{}, http.NotFoundHandler())
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantLen  int
+	}{
+		{"", http.StatusOK, 1},
+		{"?status=running", http.StatusOK, 1},
+		{"?status=stopped", http.StatusOK, 0},
+		{"?status=sleeping", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/instances/"+tt.query, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var instances []map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &instances); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(instances) != tt.wantLen {
+				t.Errorf("Expected %d instances, got %d", tt.wantLen, len(instances))
+			}
+		})
+	}
+}
+
+func TestListInstances_LabelFilter(t *testing.T) {
+	handler := newProxyTestHandler(t, config.AppConfig{}, http.NotFoundHandler())
+	router := server.SetupRouter(handler)
+
+	for name, labels := range map[string]map[string]string{
+		"chat-prod":  {"env": "prod", "team": "support"},
+		"chat-dev":   {"env": "dev", "team": "support"},
+		"embed-prod": {"env": "prod"},
+	} {
+		_, err := handler.InstanceManager.CreateInstance(name, &instance.Options{
+			Labels: labels,
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CreateInstance failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"?label.env=prod", []string{"chat-prod", "embed-prod"}},
+		{"?label.env=prod&label.team=support", []string{"chat-prod"}},
+		{"?label.team=sales", nil},
+		{"?label.env=prod&status=running", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/instances/"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var instances []struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &instances); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			var names []string
+			for _, inst := range instances {
+				names = append(names, inst.Name)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, names)
+			}
+		})
+	}
+}
+
+func TestStopAll_RequiresConfirmToken(t *testing.T) {
+	handler := newProxyTestHandler(t, config.AppConfig{}, http.NotFoundHandler())
+	router := server.SetupRouter(handler)
+
+	stopAll := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/instances/actions/stop-all?confirm="+url.QueryEscape(token), nil))
+		return w
+	}
+
+	if w := stopAll(""); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without a token, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := stopAll("made-up"); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 with an unknown token, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/instances/actions/stop-all", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from preview, got %d: %s", w.Code, w.Body.String())
+	}
+	var preview server.StopAllPreview
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("Failed to decode preview: %v", err)
+	}
+	if preview.ConfirmToken == "" || !slices.Equal(preview.Instances, []string{"llama"}) {
+		t.Fatalf("Unexpected preview: %+v", preview)
+	}
+
+	w = stopAll(preview.ConfirmToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 with a valid token, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp server.StopAllResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Name != "llama" || resp.Results[0].Status != manager.StopResultStopped {
+		t.Errorf("Unexpected results: %+v", resp.Results)
+	}
+
+	// Tokens are single-use
+	if w := stopAll(preview.ConfirmToken); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 when reusing a token, got %d", w.Code)
+	}
+}
+
+func TestStopAll_DoesNotShadowInstanceNamedActions(t *testing.T) {
+	handler := newProxyTestHandler(t, config.AppConfig{}, http.NotFoundHandler())
+	if _, err := handler.InstanceManager.CreateInstance("actions", &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+		},
+	}); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	server.SetupRouter(handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/instances/actions/", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"name":"actions"`) {
+		t.Errorf("Expected instance named actions, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateInstance_StartFailureRollsBack(t *testing.T) {
+	cfg := config.AppConfig{
+		Backends: config.BackendConfig{
+			LlamaCpp: config.BackendSettings{Command: filepath.Join(t.TempDir(), "missing-llama-server")},
+		},
+		Instances: config.InstancesConfig{
+			PortRange:           [2]int{8000, 9000},
+			MaxInstances:        10,
+			MaxRunningInstances: 10,
+			LogsDir:             t.TempDir(),
+			InstancesDir:        t.TempDir(),
+		},
+		LocalNode: "main",
+		Nodes:     map[string]config.NodeConfig{},
+	}
+	db := database.NewMemoryStore()
+	im := manager.New(&cfg, db)
+	t.Cleanup(im.Shutdown)
+	router := server.SetupRouter(server.NewHandler(im, nil, cfg, openTestDB(t)))
+
+	body := `{"backend_type": "llama_cpp", "backend_options": {"model": "/path/to/model.gguf"}}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/instances/fresh?start=true", strings.NewReader(body)))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "start_failed") {
+		t.Fatalf("Expected 500 start_failed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if _, err := im.GetInstance("fresh"); err == nil {
+		t.Error("Expected the instance to be removed from the registry")
+	}
+	saved, err := db.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	for _, inst := range saved {
+		if inst.Name == "fresh" {
+			t.Error("Expected the instance to be removed from the database")
+		}
+	}
+
+	// The name and port are free again
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/instances/fresh", strings.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected the name to be reusable after the rollback, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestInstanceResponses_RedactStdinData(t *testing.T) {
+	handler := newProxyTestHandler(t, config.AppConfig{}, http.NotFoundHandler())
+	if _, err := handler.InstanceManager.CreateInstance("piped", &instance.Options{
+		StdinData: "api_key: secret\n",
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+		},
+	}); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	router := server.SetupRouter(handler)
+
+	for _, path := range []string{"/api/v1/instances/piped/", "/api/v1/instances/"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), "secret") || !strings.Contains(w.Body.String(), `"stdin_data":"[REDACTED]"`) {
+			t.Errorf("GET %s: expected stdin_data to be redacted, got %s", path, w.Body.String())
+		}
+	}
+
+	// Sending the placeholder back keeps the stored data
+	body := `{"stdin_data": "[REDACTED]", "backend_type": "llama_cpp", "backend_options": {"model": "/path/to/other.gguf"}}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/instances/piped/", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("Expected stdin_data to be redacted in the update response, got %s", w.Body.String())
+	}
+	inst, _ := handler.InstanceManager.GetInstance("piped")
+	if got := inst.GetOptions().StdinData; got != "api_key: secret\n" {
+		t.Errorf("Expected the stored stdin_data to be kept, got %q", got)
+	}
+}
+
+func TestGetInstanceOpenAPISpec_NullDocument(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "null")
+	}))
+	defer backend.Close()
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	handler := newProxyTestHandler(t, config.AppConfig{}, http.NotFoundHandler())
+	inst, err := handler.InstanceManager.CreateInstance("vllm", &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeVllm,
+			VllmServerOptions: &backends.VllmServerOptions{
+				Model: "org/model",
+				Host:  backendURL.Hostname(),
+				Port:  port,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	inst.SetStatus(instance.Running)
+	defer inst.SetStatus(instance.Stopped)
+
+	w := httptest.NewRecorder()
+	server.SetupRouter(handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/instances/vllm/openapi", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), `"servers":[{"url":"/api/v1/instances/vllm/proxy"}]`) {
+		t.Errorf("expected a document with only the servers list, got %s", w.Body.String())
+	}
+}
+
+func TestReadOnlyKey_HidesSecrets(t *testing.T) {
+	db := openTestDB(t)
+	hash, err := auth.HashKey("sk-read-only")
+	if err != nil {
+		t.Fatalf("Failed to hash key: %v", err)
+	}
+	now := time.Now().Unix()
+	key := &auth.APIKey{KeyHash: hash, Name: "monitoring", UserID: "system", PermissionMode: auth.PermissionModeReadOnly, CreatedAt: now, UpdatedAt: now}
+	if err := db.CreateKey(context.Background(), key, nil); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	cfg := config.AppConfig{
+		Auth: config.AuthConfig{
+			RequireManagementAuth: true,
+			ManagementKeys:        []string{"sk-management-admin"},
+		},
+		Instances: config.InstancesConfig{
+			PortRange:    [2]int{1024, 65535},
+			MaxInstances: 10,
+			LogsDir:      t.TempDir(),
+			InstancesDir: t.TempDir(),
+		},
+		LocalNode: "main",
+		Nodes:     map[string]config.NodeConfig{},
+	}
+	im := manager.New(&cfg, database.NewMemoryStore())
+	t.Cleanup(im.Shutdown)
+	if _, err := im.CreateInstance("llama", &instance.Options{
+		Environment: map[string]string{"HF_TOKEN": "hf_secret", "CUDA_VISIBLE_DEVICES": "0"},
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+		},
+	}); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	router := server.SetupRouter(server.NewHandler(im, nil, cfg, db))
+
+	get := func(key, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/api/v1/instances/llama/", "/api/v1/instances/", "/api/v1/instances/by-id/1"} {
+		w := get("sk-read-only", path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", path, w.Code, w.Body.String())
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "hf_secret") || !strings.Contains(body, `"HF_TOKEN":"[REDACTED]"`) {
+			t.Errorf("GET %s: expected HF_TOKEN to be redacted for a read-only key, got %s", path, body)
+		}
+		if !strings.Contains(body, `"CUDA_VISIBLE_DEVICES":"0"`) {
+			t.Errorf("GET %s: expected other environment variables to be kept, got %s", path, body)
+		}
+	}
+
+	if w := get("sk-management-admin", "/api/v1/instances/llama/"); !strings.Contains(w.Body.String(), "hf_secret") {
+		t.Errorf("Expected management keys to see the environment, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := get("sk-read-only", "/api/v1/instances/llama/export?include_secrets=true"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected a read-only export with secrets to be forbidden, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := get("sk-read-only", "/api/v1/instances/llama/export"); w.Code != http.StatusOK || strings.Contains(w.Body.String(), "hf_secret") {
+		t.Errorf("Expected a read-only export without secrets, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := get("sk-management-admin", "/api/v1/instances/llama/export?include_secrets=true"); !strings.Contains(w.Body.String(), "hf_secret") {
+		t.Errorf("Expected management keys to export secrets, got %d: %s", w.Code, w.Body.String())
+	}
+}
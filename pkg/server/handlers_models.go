@@ -2,13 +2,16 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"llamactl/pkg/config"
 	"llamactl/pkg/models"
+	"llamactl/pkg/validation"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -56,6 +59,10 @@ func (h *Handler) forwardToNode(nodeName string, w http.ResponseWriter, r *http.
 		writeError(w, http.StatusNotFound, "node_not_found", "Node not found")
 		return false
 	}
+	if h.cfg.PointsToSelf(node.Address) {
+		writeError(w, http.StatusLoopDetected, "node_loop", fmt.Sprintf("Node %s points back at this server", nodeName))
+		return false
+	}
 
 	targetURL, err := url.Parse(node.Address)
 	if err != nil {
@@ -87,7 +94,7 @@ func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 // DownloadModel godoc
 // @Summary Download a model from a repository
-// @Description Initiates the download of a model from a specified repository and tag. Returns a job ID to track progress.
+// @Description Initiates the download of a model from a specified repository and tag, or from a model alias defined in the config. Returns a job ID to track progress.
 // @Tags Models
 // @Security ApiKeyAuth
 // @Accept json
@@ -125,22 +132,22 @@ func (h *Handler) DownloadModel() http.HandlerFunc {
 			format = models.FormatGGUF
 		}
 
-		repo := req.Repo
-		tag := ""
-		if colonIdx := strings.LastIndex(req.Repo, ":"); colonIdx != -1 {
-			repo = req.Repo[:colonIdx]
-			tag = req.Repo[colonIdx+1:]
+		// A model alias downloads the repository it stands for
+		repoRef := req.Repo
+		if target, ok := h.cfg.ModelAliases[strings.TrimSpace(req.Repo)]; ok {
+			repoRef = target
 		}
 
-		if format == models.FormatGGUF && !strings.Contains(repo, "/") {
-			writeError(w, http.StatusBadRequest, "invalid_request", "repo must be in format 'org/model' or 'org/model:tag'")
+		ref, err := validation.ParseHFRef(repoRef)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
 			return
 		}
-
-		if !strings.Contains(repo, "/") {
-			writeError(w, http.StatusBadRequest, "invalid_request", "repo must be in format 'org/model' or 'org/model:tag'")
+		if ref.Revision != "" {
+			writeError(w, http.StatusBadRequest, "invalid_request", "downloads do not support pinning a revision, use 'org/model' or 'org/model:tag'")
 			return
 		}
+		repo, tag := ref.Repo, ref.Tag
 
 		jobID, err := h.modelManager.StartDownload(repo, tag, format)
 		if err != nil {
@@ -195,7 +202,7 @@ func (h *Handler) ListModels() http.HandlerFunc {
 		allModels = append(allModels, localModels...)
 
 		for name, node := range h.cfg.Nodes {
-			if name == h.cfg.LocalNode {
+			if name == h.cfg.LocalNode || h.cfg.PointsToSelf(node.Address) {
 				continue
 			}
 
@@ -284,7 +291,7 @@ func (h *Handler) DeleteModel() http.HandlerFunc {
 
 		err := h.modelManager.DeleteModel(repo, tag)
 		if err != nil {
-			if strings.Contains(err.Error(), "model not found") {
+			if errors.Is(err, models.ErrModelNotFound) {
 				writeError(w, http.StatusNotFound, "model_not_found", err.Error())
 				return
 			}
@@ -327,7 +334,7 @@ func (h *Handler) GetJob() http.HandlerFunc {
 
 		job, err := h.modelManager.GetJob(jobID)
 		if err != nil {
-			if strings.Contains(err.Error(), "job not found") {
+			if errors.Is(err, models.ErrJobNotFound) {
 				writeError(w, http.StatusNotFound, "job_not_found", err.Error())
 				return
 			}
@@ -340,6 +347,93 @@ func (h *Handler) GetJob() http.HandlerFunc {
 	}
 }
 
+// jobStreamInterval is the minimum time between two progress events on a
+// job stream; progress updates arriving in between are merged
+const jobStreamInterval = 250 * time.Millisecond
+
+// StreamJob godoc
+// @Summary Stream model download job progress
+// @Description Streams job updates as Server-Sent Events. Each "job" event carries the full job, the current state is sent on connect, and the stream ends once the job completes, fails or is cancelled.
+// @Tags Models
+// @Security ApiKeyAuth
+// @Produce text/event-stream
+// @Param node query string false "Node name to forward the request to"
+// @Param id path string true "Job ID"
+// @Success 200 {object} JobResponse "Stream of job events"
+// @Failure 400 {string} string "Invalid request"
+// @Failure 404 {string} string "Job not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/v1/models/jobs/{id}/stream [get]
+func (h *Handler) StreamJob() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nodeName := r.URL.Query().Get("node")
+		if h.shouldForwardToNode(nodeName) {
+			if h.forwardToNode(nodeName, w, r) {
+				return
+			}
+			return
+		}
+
+		jobID := chi.URLParam(r, "id")
+		if jobID == "" {
+			writeError(w, http.StatusBadRequest, "invalid_request", "job ID is required")
+			return
+		}
+
+		updates, unsubscribe, err := h.modelManager.SubscribeJob(jobID)
+		if err != nil {
+			if errors.Is(err, models.ErrJobNotFound) {
+				writeError(w, http.StatusNotFound, "job_not_found", err.Error())
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+			return
+		}
+		defer unsubscribe()
+
+		rc := http.NewResponseController(w)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		for {
+			// A deleted job ends the stream like a finished one
+			job, err := h.modelManager.GetJob(jobID)
+			if err != nil {
+				return
+			}
+
+			data, err := json.Marshal(jobToResponse(job))
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: job\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+
+			switch job.Status {
+			case models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusCancelled:
+				return
+			}
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(jobStreamInterval):
+			}
+			select {
+			case <-r.Context().Done():
+				return
+			case <-updates:
+			}
+		}
+	}
+}
+
 // ListJobs godoc
 // @Summary List all model download jobs
 // @Description Returns a list of all model download jobs with their details
@@ -405,7 +499,7 @@ func (h *Handler) DeleteJob() http.HandlerFunc {
 
 		job, err := h.modelManager.GetJob(jobID)
 		if err != nil {
-			if strings.Contains(err.Error(), "job not found") {
+			if errors.Is(err, models.ErrJobNotFound) {
 				writeError(w, http.StatusNotFound, "job_not_found", err.Error())
 				return
 			}
@@ -421,6 +515,14 @@ func (h *Handler) DeleteJob() http.HandlerFunc {
 		}
 
 		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrJobNotFound):
+				writeError(w, http.StatusNotFound, "job_not_found", err.Error())
+				return
+			case errors.Is(err, models.ErrCannotCancel), errors.Is(err, models.ErrCannotDelete):
+				writeError(w, http.StatusConflict, "invalid_job_status", err.Error())
+				return
+			}
 			writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
 			return
 		}
@@ -1,6 +1,10 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"llamactl/pkg/manager"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -56,7 +60,7 @@ func (h *Handler) GetNode() http.HandlerFunc {
 
 		nodeConfig, exists := h.cfg.Nodes[name]
 		if !exists {
-			writeError(w, http.StatusNotFound, "not_found", "Node not found")
+			writeError(w, http.StatusNotFound, "node_not_found", "Node not found")
 			return
 		}
 
@@ -68,3 +72,101 @@ func (h *Handler) GetNode() http.HandlerFunc {
 		writeJSON(w, http.StatusOK, nodeResponse)
 	}
 }
+
+// DrainNode godoc
+// @Summary Drain a node
+// @Description Stops new instances from being placed on a node. With "migrate": true, the node's instances are re-created on another node in the background (target_node, or the node with the fewest instances) and started again if they were running. The request body is optional.
+// @Tags Nodes
+// @Security ApiKeyAuth
+// @Accept json
+// @Produces json
+// @Param name path string true "Node Name"
+// @Param options body manager.DrainOptions false "Drain options"
+// @Success 202 {object} manager.DrainStatus "Drain status"
+// @Failure 400 {string} string "Invalid request body"
+// @Failure 404 {string} string "Node not found"
+// @Failure 409 {string} string "Node is already being drained"
+// @Router /api/v1/nodes/{name}/drain [post]
+func (h *Handler) DrainNode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		var opts manager.DrainOptions
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
+			return
+		}
+
+		status, err := h.InstanceManager.DrainNode(name, opts)
+		if err != nil {
+			writeTypedError(w, err, http.StatusInternalServerError, "drain_failed", "Failed to drain node: "+err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, status)
+	}
+}
+
+// GetNodeDrain godoc
+// @Summary Get node drain status
+// @Description Returns the drain status of a node, including the progress of migrating its instances
+// @Tags Nodes
+// @Security ApiKeyAuth
+// @Produces json
+// @Param name path string true "Node Name"
+// @Success 200 {object} manager.DrainStatus "Drain status"
+// @Failure 404 {string} string "Node not found or not draining"
+// @Router /api/v1/nodes/{name}/drain [get]
+func (h *Handler) GetNodeDrain() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := h.InstanceManager.GetDrainStatus(chi.URLParam(r, "name"))
+		if err != nil {
+			writeTypedError(w, err, http.StatusInternalServerError, "drain_status_failed", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+// UndrainNode godoc
+// @Summary Undrain a node
+// @Description Lets a drained node accept new instances again. A migration in progress stops after the instance it is currently moving.
+// @Tags Nodes
+// @Security ApiKeyAuth
+// @Param name path string true "Node Name"
+// @Success 204 "No Content"
+// @Failure 404 {string} string "Node not found"
+// @Router /api/v1/nodes/{name}/drain [delete]
+func (h *Handler) UndrainNode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.InstanceManager.UndrainNode(chi.URLParam(r, "name")); err != nil {
+			writeTypedError(w, err, http.StatusInternalServerError, "undrain_failed", err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// TestNode godoc
+// @Summary Test the connection to a node
+// @Description Makes authenticated requests to the node with its configured address and API key, and reports whether it is reachable, whether the key is accepted, the round trip latency and the node's llamactl version. Connection problems are reported in the result, not as an error status.
+// @Tags Nodes
+// @Security ApiKeyAuth
+// @Produces json
+// @Param name path string true "Node Name"
+// @Success 200 {object} manager.NodeProbeResult "Probe result"
+// @Failure 404 {string} string "Node not found"
+// @Router /api/v1/nodes/{name}/test [post]
+func (h *Handler) TestNode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := h.InstanceManager.ProbeNode(chi.URLParam(r, "name"))
+		if err != nil {
+			writeTypedError(w, err, http.StatusInternalServerError, "node_test_failed", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	}
+}
@@ -46,7 +46,7 @@ type LlamaCppModelStatus struct {
 // fetchLlamaCppModels fetches models from a llama.cpp instance using the proxy
 func fetchLlamaCppModels(inst *instance.Instance) ([]LlamaCppModel, error) {
 	// Create a request to the instance's /models endpoint
-	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s:%d/models", inst.GetHost(), inst.GetPort()), nil)
+	req, err := http.NewRequest("GET", inst.BackendURL()+"/models", nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -94,6 +94,7 @@ func (h *Handler) OpenAIListInstances() http.HandlerFunc {
 
 		// For each llama.cpp instance, try to fetch models and add them as separate entries
 		for _, inst := range instances {
+			routerModels := inst.RouterModelNames()
 
 			if inst.GetBackendType() == backends.BackendTypeLlamaCpp && inst.IsRunning() {
 				// Try to fetch models from the instance
@@ -112,15 +113,28 @@ func (h *Handler) OpenAIListInstances() http.HandlerFunc {
 					})
 				}
 
-				if len(models) > 1 {
+				if len(models) > 1 || len(routerModels) > 0 {
 					// Skip adding the instance name if multiple models are present
 					continue
 				}
+			} else if len(routerModels) > 0 {
+				// A stopped router instance can't be asked for its models, so
+				// list the declared ones; requesting one starts it on demand
+				for _, model := range routerModels {
+					openaiInstances = append(openaiInstances, OpenAIInstance{
+						ID:      inst.Name + "/" + model,
+						Object:  "model",
+						Created: inst.Created,
+						OwnedBy: inst.Name,
+					})
+				}
+				continue
 			}
 
-			// Add instance name as single entry (for non-llama.cpp or if model fetch failed)
+			// Add a single entry under the alias or instance name (for
+			// non-llama.cpp or if model fetch failed)
 			openaiInstances = append(openaiInstances, OpenAIInstance{
-				ID:      inst.Name,
+				ID:      inst.ServedModelName(),
 				Object:  "model",
 				Created: inst.Created,
 				OwnedBy: "llamactl",
@@ -149,6 +163,9 @@ func (h *Handler) OpenAIListInstances() http.HandlerFunc {
 // @Router /v1/ [post]
 func (h *Handler) OpenAIProxy() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if h.rejectIfShuttingDown(w) {
+			return
+		}
 		// Read the entire body first
 		bodyBytes, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -185,16 +202,28 @@ func (h *Handler) OpenAIProxy() http.HandlerFunc {
 		}
 
 		// Validate instance name at the entry point
-		validatedName, err := validation.ValidateInstanceName(instanceName)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid_instance_name", err.Error())
-			return
+		var inst *instance.Instance
+		validatedName, nameErr := validation.ValidateInstanceName(instanceName)
+		if nameErr == nil {
+			// Route to the appropriate inst based on instance name
+			inst, err = h.InstanceManager.GetInstance(validatedName)
 		}
 
-		// Route to the appropriate inst based on instance name
-		inst, err := h.InstanceManager.GetInstance(validatedName)
+		// A bare name that isn't an instance may be an instance's alias or a
+		// model declared by a router instance
+		if inst == nil && !strings.Contains(reqModelName, "/") {
+			if routed, routeErr := h.InstanceManager.ResolveModel(reqModelName); routeErr == nil {
+				inst, nameErr, err = routed, nil, nil
+			} else if nameErr == nil {
+				err = routeErr
+			}
+		}
+		if nameErr != nil {
+			writeError(w, http.StatusBadRequest, "invalid_instance_name", nameErr.Error())
+			return
+		}
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid_instance", err.Error())
+			writeTypedError(w, err, http.StatusBadRequest, "invalid_instance", err.Error())
 			return
 		}
 
@@ -203,6 +232,10 @@ func (h *Handler) OpenAIProxy() http.HandlerFunc {
 			writeError(w, http.StatusForbidden, "permission_denied", err.Error())
 			return
 		}
+		if err := h.authMiddleware.CheckPathPermission(r.Context(), inst.ID, r.URL.Path); err != nil {
+			writeError(w, http.StatusForbidden, "permission_denied", err.Error())
+			return
+		}
 
 		// Check if instance is shutting down before autostart logic
 		if inst.GetStatus() == instance.ShuttingDown {
@@ -212,6 +245,8 @@ func (h *Handler) OpenAIProxy() http.HandlerFunc {
 
 		if inst.IsRemote() {
 			modelName = reqModelName
+		} else if alias := inst.Alias(); alias != "" && !strings.Contains(reqModelName, "/") {
+			modelName = alias
 		} else if !strings.Contains(reqModelName, "/") {
 			opts := inst.GetOptions()
 			if opts != nil {
@@ -225,7 +260,7 @@ func (h *Handler) OpenAIProxy() http.HandlerFunc {
 		if !inst.IsRemote() && !inst.IsRunning() {
 			err := h.ensureInstanceRunning(inst)
 			if err != nil {
-				writeError(w, http.StatusInternalServerError, "instance_start_failed", err.Error())
+				writeTypedError(w, err, http.StatusInternalServerError, "instance_start_failed", err.Error())
 				return
 			}
 		}
@@ -0,0 +1,151 @@
+package server_test
+
+import (
+	"encoding/json"
+	"io"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/config"
+	"llamactl/pkg/database"
+	"llamactl/pkg/instance"
+	"llamactl/pkg/manager"
+	"llamactl/pkg/server"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIListInstances_RouterModels(t *testing.T) {
+	db := openTestDB(t)
+	cfg := config.AppConfig{
+		Instances: config.InstancesConfig{
+			PortRange:    [2]int{8000, 9000},
+			MaxInstances: 10,
+			LogsDir:      t.TempDir(),
+			InstancesDir: t.TempDir(),
+		},
+		LocalNode: "main",
+		Nodes:     map[string]config.NodeConfig{},
+	}
+	im := manager.New(&cfg, db.(database.InstanceStore))
+	t.Cleanup(im.Shutdown)
+
+	_, err := im.CreateInstance("router", &instance.Options{
+		RouterModels: []instance.RouterModel{
+			{Name: "mistral", Model: "/models/mistral.gguf"},
+			{Name: "qwen", HFRepo: "Qwen/Qwen3-8B-GGUF"},
+		},
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	handler := server.NewHandler(im, nil, cfg, db)
+	w := httptest.NewRecorder()
+	handler.OpenAIListInstances().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp server.OpenAIListInstancesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	var ids []string
+	for _, m := range resp.Data {
+		ids = append(ids, m.ID)
+	}
+	if !slices.Equal(ids, []string{"router/mistral", "router/qwen"}) {
+		t.Errorf("Expected declared router models, got %v", ids)
+	}
+}
+
+func TestOpenAI_AliasRouting(t *testing.T) {
+	var backendModel string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		backendModel, _ = body["model"].(string)
+		io.WriteString(w, "{}")
+	}))
+	t.Cleanup(backend.Close)
+	backendURL, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(backendURL.Port())
+
+	cfg := config.AppConfig{
+		Instances: config.InstancesConfig{
+			PortRange:    [2]int{1024, 65535},
+			MaxInstances: 10,
+			LogsDir:      t.TempDir(),
+			InstancesDir: t.TempDir(),
+		},
+		LocalNode: "main",
+		Nodes:     map[string]config.NodeConfig{},
+	}
+	im := manager.New(&cfg, database.NewMemoryStore())
+	t.Cleanup(im.Shutdown)
+
+	inst, err := im.CreateInstance("llama-8b", &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType: backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{
+				Model: "/path/to/model.gguf",
+				Alias: "llama-3-8b",
+				Host:  backendURL.Hostname(),
+				Port:  port,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+	if _, err := im.CreateInstance("plain", &instance.Options{
+		BackendOptions: backends.Options{
+			BackendType:        backends.BackendTypeLlamaCpp,
+			LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/other.gguf"},
+		},
+	}); err != nil {
+		t.Fatalf("CreateInstance failed: %v", err)
+	}
+
+	handler := server.NewHandler(im, nil, cfg, openTestDB(t))
+
+	// Stopped instances are listed under their alias, or their name without one
+	w := httptest.NewRecorder()
+	handler.OpenAIListInstances().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	var resp server.OpenAIListInstancesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	var ids []string
+	for _, m := range resp.Data {
+		ids = append(ids, m.ID)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"llama-3-8b", "plain"}) {
+		t.Errorf("Expected alias and instance name, got %v", ids)
+	}
+
+	inst.SetStatus(instance.Running)
+	t.Cleanup(func() { inst.SetStatus(instance.Stopped) })
+
+	for _, model := range []string{"llama-3-8b", "llama-8b"} {
+		backendModel = ""
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"`+model+`"}`))
+		handler.OpenAIProxy().ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request for %s: expected 200, got %d: %s", model, w.Code, w.Body.String())
+		}
+		if backendModel != "llama-3-8b" {
+			t.Errorf("Request for %s: expected backend to get the alias, got %q", model, backendModel)
+		}
+	}
+}
@@ -1,29 +1,133 @@
 package server
 
 import (
-	"fmt"
+	"context"
+	"llamactl/pkg/backends"
+	"llamactl/pkg/database"
+	"llamactl/pkg/gpu"
+	"log"
 	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
 )
 
+// GPUsResponse represents the GPUs available on a node
+type GPUsResponse struct {
+	GPUs []gpu.Device `json:"gpus"`
+}
+
+// ReadinessResponse reports whether the server is ready and the state of
+// each dependency it checked
+type ReadinessResponse struct {
+	Status string            `json:"status"` // "ready", "unavailable" or "shutting_down"
+	Checks map[string]string `json:"checks"` // "ok" or the error
+}
+
+// readinessTimeout bounds each readiness check, so a hung database can't stall probes
+const readinessTimeout = 2 * time.Second
+
+// VersionResponse contains llamactl build information and detected backend versions
+type VersionResponse struct {
+	Version    string `json:"version"`
+	CommitHash string `json:"commit_hash"`
+	BuildTime  string `json:"build_time"`
+	GoVersion  string `json:"go_version"`
+	// Backend versions keyed by backend type, for backends that report one quickly
+	Backends map[string]string `json:"backends,omitempty"`
+}
+
 // VersionHandler godoc
 // @Summary Get llamactl version
-// @Description Returns the version of the llamactl command
+// @Description Returns llamactl build metadata and the llama.cpp version if the llama-server binary is available
 // @Tags System
 // @Security ApiKeyAuth
-// @Produces text/plain
-// @Success 200 {string} string "Version information"
+// @Produces json
+// @Success 200 {object} VersionResponse "Version information"
 // @Failure 500 {string} string "Internal Server Error"
 // @Router /api/v1/version [get]
 func (h *Handler) VersionHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		versionInfo := fmt.Sprintf("Version: %s\nCommit: %s\nBuild Time: %s\n", h.cfg.Version, h.cfg.CommitHash, h.cfg.BuildTime)
-		writeText(w, http.StatusOK, versionInfo)
+		resp := VersionResponse{
+			Version:    h.cfg.Version,
+			CommitHash: h.cfg.CommitHash,
+			BuildTime:  h.cfg.BuildTime,
+			GoVersion:  runtime.Version(),
+		}
+
+		if version := h.detectLlamaCppVersion(); version != "" {
+			resp.Backends = map[string]string{string(backends.BackendTypeLlamaCpp): version}
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+// versionCacheTTL is how long a detected backend version is reused, so an
+// upgraded binary shows up without a restart
+const versionCacheTTL = 5 * time.Minute
+
+// versionCache keeps a detected backend version, so the backend binary isn't
+// run for every request
+type versionCache struct {
+	mu        sync.Mutex
+	version   string
+	checkedAt time.Time
+}
+
+// get returns the cached version, calling detect when it is missing or
+// stale. Concurrent callers wait for the same detection.
+func (c *versionCache) get(detect func() string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checkedAt.IsZero() || time.Since(c.checkedAt) > versionCacheTTL {
+		c.version = detect()
+		c.checkedAt = time.Now()
 	}
+	return c.version
+}
+
+// detectLlamaCppVersion runs the configured llama-server with --version and
+// returns the reported version, or "" if it isn't available. vLLM and MLX are
+// skipped because starting their Python interpreters takes several seconds.
+// The result is cached for versionCacheTTL.
+func (h *Handler) detectLlamaCppVersion() string {
+	settings := h.cfg.Backends.LlamaCpp
+	if settings.Command == "" || (settings.Docker != nil && settings.Docker.Enabled) {
+		return ""
+	}
+	return h.llamaCppVersion.get(func() string {
+		return runLlamaCppVersion(settings.Command)
+	})
+}
+
+// runLlamaCppVersion runs command with --version and parses the version it
+// prints. It isn't bound to a request, so a client going away doesn't leave
+// an empty result in the cache.
+func runLlamaCppVersion(command string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, command, "--version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+
+	// Output is e.g. "version: 6123 (1a2b3c4d)", possibly after device init logs
+	for line := range strings.Lines(string(output)) {
+		if version, ok := strings.CutPrefix(strings.TrimSpace(line), "version:"); ok {
+			return strings.TrimSpace(version)
+		}
+	}
+	return ""
 }
 
 // ConfigHandler godoc
 // @Summary Get server configuration
-// @Description Returns the current server configuration (sanitized)
+// @Description Returns the effective server configuration (defaults, config file and environment overrides applied).
+// @Description Secrets such as management keys, node API keys and sensitive backend environment variables are replaced with "[REDACTED]".
 // @Tags System
 // @Security ApiKeyAuth
 // @Produces application/json
@@ -40,3 +144,98 @@ func (h *Handler) ConfigHandler() http.HandlerFunc {
 		writeJSON(w, http.StatusOK, sanitizedConfig)
 	}
 }
+
+// ListGPUs godoc
+// @Summary List GPUs on the node
+// @Description Returns memory usage and utilization for each GPU reported by nvidia-smi or rocm-smi. Returns an empty list on hosts without GPUs.
+// @Tags System
+// @Security ApiKeyAuth
+// @Produces json
+// @Param node query string false "Node name to forward the request to"
+// @Success 200 {object} GPUsResponse "GPU list"
+// @Failure 404 {string} string "Node not found"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/v1/system/gpus [get]
+func (h *Handler) ListGPUs() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nodeName := r.URL.Query().Get("node")
+		if h.shouldForwardToNode(nodeName) {
+			h.forwardToNode(nodeName, w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		devices, err := gpu.List(ctx)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "gpu_query_failed", err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, GPUsResponse{GPUs: devices})
+	}
+}
+
+// ConfigEnvVarsHandler godoc
+// @Summary List recognized environment variables
+// @Description Returns every LLAMACTL_* environment variable the server recognizes, the config setting it maps to,
+// @Description whether it is set, and the setting's effective value with secrets redacted
+// @Tags System
+// @Security ApiKeyAuth
+// @Produces application/json
+// @Success 200 {array} config.EnvVarInfo "Environment variables"
+// @Failure 500 {string} string "Internal Server Error"
+// @Router /api/v1/config/env-vars [get]
+func (h *Handler) ConfigEnvVarsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		envVars, err := h.cfg.EnvVars()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "sanitized_copy_error", "Failed to list environment variables")
+			return
+		}
+		writeJSON(w, http.StatusOK, envVars)
+	}
+}
+
+// ReadyzHandler godoc
+// @Summary Readiness check
+// @Description Returns 200 when the server can handle requests and 503 when a dependency, such as the database, is unreachable.
+// @Description It requires no authentication, so it can be used as a load balancer or Kubernetes readiness probe.
+// @Tags System
+// @Produces json
+// @Success 200 {object} ReadinessResponse "Server is ready"
+// @Failure 503 {object} ReadinessResponse "A dependency is unavailable"
+// @Router /readyz [get]
+func (h *Handler) ReadyzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := ReadinessResponse{Status: "ready", Checks: map[string]string{}}
+
+		// Fail readiness while draining so load balancers stop sending traffic
+		if h.shuttingDown.Load() {
+			resp.Status = "shutting_down"
+			writeJSON(w, http.StatusServiceUnavailable, resp)
+			return
+		}
+
+		if checker, ok := h.authStore.(database.HealthChecker); ok {
+			ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+			defer cancel()
+
+			resp.Checks["database"] = "ok"
+			if err := checker.HealthCheck(ctx); err != nil {
+				// The endpoint is unauthenticated, so the error, which may
+				// name hosts or paths, is only logged
+				log.Printf("Readiness check: database unavailable: %v", err)
+				resp.Status = "unavailable"
+				resp.Checks["database"] = "database unavailable"
+			}
+		}
+
+		status := http.StatusOK
+		if resp.Status != "ready" {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, resp)
+	}
+}
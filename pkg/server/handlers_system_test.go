@@ -0,0 +1,100 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"llamactl/pkg/config"
+	"llamactl/pkg/server"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadyz_ReportsDatabaseHealth(t *testing.T) {
+	db := openTestDB(t)
+	handler := server.NewHandler(nil, nil, config.AppConfig{}, db)
+
+	readyz := func() (int, server.ReadinessResponse) {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		handler.ReadyzHandler()(recorder, httptest.NewRequest("GET", "/readyz", nil))
+
+		var resp server.ReadinessResponse
+		if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return recorder.Code, resp
+	}
+
+	code, resp := readyz()
+	if code != http.StatusOK || resp.Status != "ready" || resp.Checks["database"] != "ok" {
+		t.Fatalf("Expected ready with a healthy database, got %d %+v", code, resp)
+	}
+
+	// A closed handle stands in for a database that went away
+	if closer, ok := db.(interface{ Close() error }); ok {
+		closer.Close()
+	}
+
+	code, resp = readyz()
+	if code != http.StatusServiceUnavailable || resp.Status != "unavailable" {
+		t.Errorf("Expected 503 unavailable after closing the database, got %d %+v", code, resp)
+	}
+	if resp.Checks["database"] != "database unavailable" {
+		t.Errorf("Expected a generic database error, got %q", resp.Checks["database"])
+	}
+
+	// A closed handle is a permanent error, so store calls fail without
+	// waiting through retries
+	start := time.Now()
+	if _, err := db.GetActiveKeys(context.Background()); err == nil {
+		t.Error("Expected GetActiveKeys to fail on a closed database")
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("Expected closed database to fail fast, took %v", elapsed)
+	}
+}
+
+func TestVersionHandler_CachesBackendVersion(t *testing.T) {
+	// A fake llama-server that counts how often it is run
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "runs")
+	command := filepath.Join(dir, "llama-server")
+	script := "#!/bin/sh\necho run >> " + counter + "\necho 'version: 6123 (1a2b3c4d)'\n"
+	if err := os.WriteFile(command, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.AppConfig{
+		Version:  "v1.2.3",
+		Backends: config.BackendConfig{LlamaCpp: config.BackendSettings{Command: command}},
+	}
+	handler := server.NewHandler(nil, nil, cfg, nil)
+
+	for range 3 {
+		recorder := httptest.NewRecorder()
+		handler.VersionHandler()(recorder, httptest.NewRequest("GET", "/api/v1/version", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", recorder.Code)
+		}
+		var resp server.VersionResponse
+		if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.Version != "v1.2.3" || resp.Backends["llama_cpp"] != "6123 (1a2b3c4d)" {
+			t.Errorf("Unexpected version response: %+v", resp)
+		}
+	}
+
+	runs, err := os.ReadFile(counter)
+	if err != nil {
+		t.Fatalf("Failed to read run counter: %v", err)
+	}
+	if got := strings.Count(string(runs), "run"); got != 1 {
+		t.Errorf("Expected llama-server to run once, ran %d times", got)
+	}
+}
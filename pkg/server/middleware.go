@@ -2,7 +2,9 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
 	"llamactl/pkg/auth"
 	"llamactl/pkg/config"
@@ -19,6 +21,8 @@ type contextKey string
 
 const (
 	apiKeyContextKey contextKey = "apiKey"
+	// Set to the audit actor name when a config management key is used
+	managementActorContextKey contextKey = "managementActor"
 )
 
 type APIAuthMiddleware struct {
@@ -87,27 +91,8 @@ func (a *APIAuthMiddleware) InferenceAuthMiddleware() func(http.Handler) http.Ha
 
 			// Try database authentication first
 			var foundKey *auth.APIKey
-			if a.requireInferenceAuth && a.authStore != nil {
-				activeKeys, err := a.authStore.GetActiveKeys(r.Context())
-				if err != nil {
-					log.Printf("Failed to get active inference keys: %v", err)
-					// Continue to management key fallback
-				} else {
-					for _, key := range activeKeys {
-						if auth.VerifyKey(apiKey, key.KeyHash) {
-							foundKey = key
-							// Async update last_used_at
-							go func(keyID int) {
-								ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-								defer cancel()
-								if err := a.authStore.TouchKey(ctx, keyID); err != nil {
-									log.Printf("Failed to update last used timestamp for key %d: %v", keyID, err)
-								}
-							}(key.ID)
-							break
-						}
-					}
-				}
+			if a.requireInferenceAuth {
+				foundKey = a.findDatabaseKey(r.Context(), apiKey)
 			}
 
 			// If no database key found, try management key authentication (config-based)
@@ -145,12 +130,60 @@ func (a *APIAuthMiddleware) ManagementAuthMiddleware() func(http.Handler) http.H
 			}
 
 			// Check if key exists in managementKeys map using constant-time comparison
-			if !a.isValidManagementKey(apiKey) {
+			if a.isValidManagementKey(apiKey) {
+				ctx := context.WithValue(r.Context(), managementActorContextKey, managementActor(apiKey))
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			// Read-only database keys may use the management API for reads
+			foundKey := a.findDatabaseKey(r.Context(), apiKey)
+			if foundKey == nil || foundKey.PermissionMode != auth.PermissionModeReadOnly {
 				a.unauthorized(w, "Invalid API key")
 				return
 			}
+			if isMutatingRequest(r) {
+				a.forbidden(w, "Read-only API key cannot modify resources")
+				return
+			}
 
-			next.ServeHTTP(w, r)
+			ctx := context.WithValue(r.Context(), apiKeyContextKey, foundKey)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// IdentifyMiddleware accepts any management key or active inference key and
+// records it in the request context, without restricting what it may access.
+// It is meant for endpoints that describe the caller. Requests without a key
+// pass through only when both inference and management auth are disabled.
+func (a *APIAuthMiddleware) IdentifyMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey := a.extractAPIKey(r)
+			if apiKey == "" {
+				if a.requireInferenceAuth || a.requireManagementAuth {
+					a.unauthorized(w, "Missing API key")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if a.isValidManagementKey(apiKey) {
+				ctx := context.WithValue(r.Context(), managementActorContextKey, managementActor(apiKey))
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			foundKey := a.findDatabaseKey(r.Context(), apiKey)
+			if foundKey == nil {
+				a.unauthorized(w, "Invalid API key")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), apiKeyContextKey, foundKey)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
@@ -164,8 +197,8 @@ func (a *APIAuthMiddleware) CheckInstancePermission(ctx context.Context, instanc
 		return nil
 	}
 
-	// If permission_mode == "allow_all", allow all
-	if apiKey.PermissionMode == auth.PermissionModeAllowAll {
+	// allow_all and read_only keys may use every instance
+	if apiKey.PermissionMode == auth.PermissionModeAllowAll || apiKey.PermissionMode == auth.PermissionModeReadOnly {
 		return nil
 	}
 
@@ -182,6 +215,87 @@ func (a *APIAuthMiddleware) CheckInstancePermission(ctx context.Context, instanc
 	return nil
 }
 
+// isReadOnlyKey reports whether the request was authenticated with a
+// read_only key, which must not see secrets such as environment values
+func isReadOnlyKey(ctx context.Context) bool {
+	apiKey, ok := ctx.Value(apiKeyContextKey).(*auth.APIKey)
+	return ok && apiKey.PermissionMode == auth.PermissionModeReadOnly
+}
+
+// CheckPathPermission checks that the authenticated key may request the
+// backend path on the instance. Only per-instance keys carry path allowlists;
+// call it after CheckInstancePermission has allowed the instance.
+func (a *APIAuthMiddleware) CheckPathPermission(ctx context.Context, instanceID int, backendPath string) error {
+	apiKey, ok := ctx.Value(apiKeyContextKey).(*auth.APIKey)
+	if !ok || apiKey.PermissionMode != auth.PermissionModePerInstance {
+		return nil
+	}
+
+	perm, err := a.authStore.GetPermission(ctx, apiKey.ID, instanceID)
+	if err != nil {
+		return fmt.Errorf("failed to check permission: %w", err)
+	}
+	if perm == nil || !perm.AllowsPath(backendPath) {
+		return fmt.Errorf("permission denied: key does not have access to %s on this instance", backendPath)
+	}
+
+	return nil
+}
+
+// findDatabaseKey returns the active database key matching providedKey, or
+// nil if there is none or the key store can't be read
+func (a *APIAuthMiddleware) findDatabaseKey(ctx context.Context, providedKey string) *auth.APIKey {
+	if a.authStore == nil {
+		return nil
+	}
+
+	activeKeys, err := a.authStore.GetActiveKeys(ctx)
+	if err != nil {
+		log.Printf("Failed to get active inference keys: %v", err)
+		return nil
+	}
+
+	for _, key := range activeKeys {
+		if auth.VerifyKey(providedKey, key.KeyHash) {
+			// Async update last_used_at
+			go func(keyID int) {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := a.authStore.TouchKey(ctx, keyID); err != nil {
+					log.Printf("Failed to update last used timestamp for key %d: %v", keyID, err)
+				}
+			}(key.ID)
+			return key
+		}
+	}
+	return nil
+}
+
+// isMutatingRequest reports whether a management API request changes state.
+// Reads are GET and HEAD; requests proxied to an instance count as inference
+// rather than management, whatever their method.
+func isMutatingRequest(r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return false
+	}
+	return !isInstanceProxyPath(r.URL.Path)
+}
+
+// isInstanceProxyPath matches /api/v1/instances/{name}/proxy and the paths
+// below it, comparing whole segments so /proxyfoo is not a proxy path
+func isInstanceProxyPath(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/api/v1/instances/")
+	if !ok {
+		return false
+	}
+	name, _, _ := strings.Cut(rest, "/")
+	if name == "" {
+		return false
+	}
+	prefix := "/api/v1/instances/" + name + "/proxy"
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
 // extractAPIKey extracts the API key from the request
 func (a *APIAuthMiddleware) extractAPIKey(r *http.Request) string {
 	// Check Authorization header: "Bearer sk-..."
@@ -215,6 +329,13 @@ func (a *APIAuthMiddleware) isValidManagementKey(providedKey string) bool {
 	return false
 }
 
+// managementActor identifies a config management key in the audit log by a
+// short hash, so keys can be told apart without being stored
+func managementActor(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "management:" + hex.EncodeToString(sum[:4])
+}
+
 // unauthorized sends an unauthorized response
 func (a *APIAuthMiddleware) unauthorized(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
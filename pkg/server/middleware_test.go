@@ -1,12 +1,26 @@
 package server_test
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"llamactl/pkg/auth"
+	"llamactl/pkg/backends"
 	"llamactl/pkg/config"
+	"llamactl/pkg/database"
+	"llamactl/pkg/instance"
+	"llamactl/pkg/manager"
 	"llamactl/pkg/server"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestInferenceAuthMiddleware(t *testing.T) {
@@ -178,6 +192,88 @@ func TestManagementAuthMiddleware(t *testing.T) {
 	}
 }
 
+// openTestDB opens a migrated database in a temporary directory
+func openTestDB(t *testing.T) database.AuthStore {
+	t.Helper()
+	db, err := database.Open(&database.Config{
+		Path:               filepath.Join(t.TempDir(), "llamactl.db"),
+		MaxOpenConnections: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return db
+}
+
+func TestManagementAuthMiddleware_ReadOnlyKey(t *testing.T) {
+	db := openTestDB(t)
+
+	createKey := func(plain string, mode auth.PermissionMode) {
+		hash, err := auth.HashKey(plain)
+		if err != nil {
+			t.Fatalf("Failed to hash key: %v", err)
+		}
+		now := time.Now().Unix()
+		key := &auth.APIKey{KeyHash: hash, Name: plain, UserID: "system", PermissionMode: mode, CreatedAt: now, UpdatedAt: now}
+		if err := db.CreateKey(context.Background(), key, nil); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+	createKey("sk-read-only", auth.PermissionModeReadOnly)
+	createKey("sk-inference", auth.PermissionModeAllowAll)
+
+	cfg := config.AuthConfig{
+		RequireManagementAuth: true,
+		ManagementKeys:        []string{"sk-management-admin"},
+	}
+	middleware := server.NewAPIAuthMiddleware(cfg, db)
+	handler := middleware.ManagementAuthMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		key            string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"read-only key can list instances", "sk-read-only", "GET", "/api/v1/instances/", http.StatusOK},
+		{"read-only key can read logs", "sk-read-only", "GET", "/api/v1/instances/llama/logs", http.StatusOK},
+		{"read-only key can proxy inference", "sk-read-only", "POST", "/api/v1/instances/llama/proxy/completion", http.StatusOK},
+		{"read-only key cannot start instances", "sk-read-only", "POST", "/api/v1/instances/llama/start", http.StatusForbidden},
+		{"read-only key cannot delete instances", "sk-read-only", "DELETE", "/api/v1/instances/llama/", http.StatusForbidden},
+		{"instance named proxy is not a proxy path", "sk-read-only", "POST", "/api/v1/instances/proxy/stop", http.StatusForbidden},
+		{"read-only key can proxy to the backend root", "sk-read-only", "POST", "/api/v1/instances/llama/proxy", http.StatusOK},
+		{"proxy must be a whole segment", "sk-read-only", "POST", "/api/v1/instances/llama/proxyfoo", http.StatusForbidden},
+		{"proxy path needs an instance name", "sk-read-only", "POST", "/api/v1/instances//proxy/completion", http.StatusForbidden},
+		{"read-only key cannot create keys", "sk-read-only", "POST", "/api/v1/auth/keys/", http.StatusForbidden},
+		{"inference key is rejected", "sk-inference", "GET", "/api/v1/instances/", http.StatusUnauthorized},
+		{"management key can mutate", "sk-management-admin", "POST", "/api/v1/instances/llama/start", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectedStatus {
+				t.Errorf("ManagementAuthMiddleware() status = %v, expected %v", recorder.Code, tt.expectedStatus)
+			}
+			if recorder.Code == http.StatusForbidden && !strings.Contains(recorder.Body.String(), `"type": "permission_denied"`) {
+				t.Errorf("Forbidden response missing proper error type: %v", recorder.Body.String())
+			}
+		})
+	}
+}
+
 func TestManagementKeyAutoGeneration(t *testing.T) {
 	// Test auto-generation for management keys
 	config := config.AuthConfig{
@@ -275,3 +371,358 @@ func TestAutoGenerationScenarios(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckPathPermission(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	// Key permissions reference instances, so insert one directly
+	execer, ok := db.(interface {
+		ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	})
+	if !ok {
+		t.Fatal("Test database does not expose ExecContext")
+	}
+	result, err := execer.ExecContext(ctx, `INSERT INTO instances (name, created_at, updated_at, options_json) VALUES ('llama', 0, 0, '{}')`)
+	if err != nil {
+		t.Fatalf("Failed to insert instance: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	instanceID := int(id)
+
+	createKey := func(plain string, allowedPaths []string) {
+		hash, err := auth.HashKey(plain)
+		if err != nil {
+			t.Fatalf("Failed to hash key: %v", err)
+		}
+		now := time.Now().Unix()
+		key := &auth.APIKey{KeyHash: hash, Name: plain, UserID: "system", PermissionMode: auth.PermissionModePerInstance, CreatedAt: now, UpdatedAt: now}
+		perms := []auth.KeyPermission{{InstanceID: instanceID, AllowedPaths: allowedPaths}}
+		if err := db.CreateKey(ctx, key, perms); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+	createKey("sk-embeddings", []string{"/v1/embeddings", "/v1/models/**"})
+	createKey("sk-unrestricted", nil)
+
+	middleware := server.NewAPIAuthMiddleware(config.AuthConfig{RequireInferenceAuth: true}, db)
+	handler := middleware.InferenceAuthMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := middleware.CheckPathPermission(r.Context(), instanceID, r.URL.Path); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		key            string
+		path           string
+		expectedStatus int
+	}{
+		{"allowed exact path", "sk-embeddings", "/v1/embeddings", http.StatusOK},
+		{"allowed subtree", "sk-embeddings", "/v1/models/llama", http.StatusOK},
+		{"path outside allowlist", "sk-embeddings", "/v1/completions", http.StatusForbidden},
+		{"dot segments are rejected", "sk-embeddings", "/v1/embeddings/../completions", http.StatusForbidden},
+		{"dot segments into an allowed path are rejected", "sk-embeddings", "/v1/completions/../embeddings", http.StatusForbidden},
+		{"repeated slashes are rejected", "sk-embeddings", "/v1//embeddings", http.StatusForbidden},
+		{"trailing slash is allowed", "sk-embeddings", "/v1/models/", http.StatusOK},
+		{"no allowlist allows every path", "sk-unrestricted", "/completion", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectedStatus {
+				t.Errorf("CheckPathPermission() status = %v, expected %v", recorder.Code, tt.expectedStatus)
+			}
+		})
+	}
+
+	perms, err := db.GetPermissions(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetPermissions failed: %v", err)
+	}
+	if len(perms) != 1 || len(perms[0].AllowedPaths) != 2 {
+		t.Errorf("Expected allowed paths to round-trip, got %+v", perms)
+	}
+}
+
+func TestFileKeys(t *testing.T) {
+	db, err := database.Open(&database.Config{
+		Path:               filepath.Join(t.TempDir(), "llamactl.db"),
+		MaxOpenConnections: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	ctx := context.Background()
+
+	result, err := db.ExecContext(ctx, `INSERT INTO instances (name, created_at, updated_at, options_json) VALUES ('llama', 0, 0, '{}')`)
+	if err != nil {
+		t.Fatalf("Failed to insert instance: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	instanceID := int(id)
+
+	hash := func(plain string) string {
+		h, err := auth.HashKey(plain)
+		if err != nil {
+			t.Fatalf("Failed to hash key: %v", err)
+		}
+		return h
+	}
+	keysFile := filepath.Join(t.TempDir(), "keys.yaml")
+	content := fmt.Sprintf(`keys:
+  - name: ci
+    key_hash: %q
+    permission_mode: per_instance
+    instances: [llama]
+    allowed_paths: [/v1/embeddings]
+  - name: other
+    key_hash: %q
+    permission_mode: per_instance
+    instances: [missing]
+  - name: expired
+    key_hash: %q
+    permission_mode: allow_all
+    expires_at: 1
+`, hash("sk-file-ci"), hash("sk-file-other"), hash("sk-file-expired"))
+	if err := os.WriteFile(keysFile, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write keys file: %v", err)
+	}
+
+	fileKeys, err := auth.LoadKeysFile(keysFile)
+	if err != nil {
+		t.Fatalf("LoadKeysFile failed: %v", err)
+	}
+	store := database.WithFileKeys(db, fileKeys)
+
+	middleware := server.NewAPIAuthMiddleware(config.AuthConfig{RequireInferenceAuth: true}, store)
+	handler := middleware.InferenceAuthMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := middleware.CheckInstancePermission(r.Context(), instanceID); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if err := middleware.CheckPathPermission(r.Context(), instanceID, r.URL.Path); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		key            string
+		path           string
+		expectedStatus int
+	}{
+		{"file key on named instance", "sk-file-ci", "/v1/embeddings", http.StatusOK},
+		{"file key outside allowed paths", "sk-file-ci", "/v1/completions", http.StatusForbidden},
+		{"file key for another instance", "sk-file-other", "/v1/embeddings", http.StatusForbidden},
+		{"expired file key", "sk-file-expired", "/v1/embeddings", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectedStatus {
+				t.Errorf("status = %v, expected %v", recorder.Code, tt.expectedStatus)
+			}
+		})
+	}
+
+	if err := store.DeleteKey(ctx, -1); err == nil {
+		t.Error("Expected deleting a file key to fail")
+	}
+	for _, id := range []int{-99, 99} {
+		if _, err := store.GetKeyByID(ctx, id); !errors.Is(err, database.ErrKeyNotFound) {
+			t.Errorf("Expected ErrKeyNotFound for key %d, got: %v", id, err)
+		}
+	}
+	if err := store.DeleteKey(ctx, 99); !errors.Is(err, database.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound deleting a missing key, got: %v", err)
+	}
+	keys, err := store.GetUserKeys(ctx, "system")
+	if err != nil {
+		t.Fatalf("GetUserKeys failed: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Expected file keys to be excluded from the listed keys, got %d keys", len(keys))
+	}
+}
+
+func TestKeyPermissions_SurviveInstanceRecreation(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	cfg := config.AppConfig{
+		Instances: config.InstancesConfig{
+			PortRange:    [2]int{8000, 9000},
+			MaxInstances: 10,
+			LogsDir:      t.TempDir(),
+		},
+		LocalNode: "main",
+		Nodes:     map[string]config.NodeConfig{},
+	}
+	im := manager.New(&cfg, db.(database.InstanceStore))
+	t.Cleanup(im.Shutdown)
+
+	create := func(name string) int {
+		inst, err := im.CreateInstance(name, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CreateInstance(%s) failed: %v", name, err)
+		}
+		return inst.ID
+	}
+
+	originalID := create("llama")
+	hash, err := auth.HashKey("sk-llama")
+	if err != nil {
+		t.Fatalf("Failed to hash key: %v", err)
+	}
+	now := time.Now().Unix()
+	key := &auth.APIKey{KeyHash: hash, Name: "llama", UserID: "system", PermissionMode: auth.PermissionModePerInstance, CreatedAt: now, UpdatedAt: now}
+	if err := db.CreateKey(ctx, key, []auth.KeyPermission{{InstanceID: originalID}}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	if err := im.DeleteInstance("llama"); err != nil {
+		t.Fatalf("DeleteInstance failed: %v", err)
+	}
+	otherID := create("mistral")
+	recreatedID := create("llama")
+
+	if recreatedID != originalID {
+		t.Errorf("Expected recreated instance to reuse ID %d, got %d", originalID, recreatedID)
+	}
+	if otherID == originalID {
+		t.Errorf("Instance with a different name must not reuse ID %d", originalID)
+	}
+	if ok, err := db.HasPermission(ctx, key.ID, recreatedID); err != nil || !ok {
+		t.Errorf("Expected key permission to apply to the recreated instance, got %v (err %v)", ok, err)
+	}
+	if ok, _ := db.HasPermission(ctx, key.ID, otherID); ok {
+		t.Error("Key permission must not apply to an unrelated instance")
+	}
+}
+
+func TestWhoAmI(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	cfg := config.AppConfig{
+		Auth: config.AuthConfig{
+			RequireInferenceAuth:  true,
+			RequireManagementAuth: true,
+			ManagementKeys:        []string{"sk-management-admin"},
+		},
+		Instances: config.InstancesConfig{
+			PortRange:    [2]int{8000, 9000},
+			MaxInstances: 10,
+			LogsDir:      t.TempDir(),
+		},
+		LocalNode: "main",
+		Nodes:     map[string]config.NodeConfig{},
+	}
+	im := manager.New(&cfg, db.(database.InstanceStore))
+	t.Cleanup(im.Shutdown)
+
+	var instanceID int
+	for _, name := range []string{"llama", "mistral"} {
+		inst, err := im.CreateInstance(name, &instance.Options{
+			BackendOptions: backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &backends.LlamaServerOptions{Model: "/path/to/model.gguf"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CreateInstance failed: %v", err)
+		}
+		if name == "llama" {
+			instanceID = inst.ID
+		}
+	}
+
+	createKey := func(plain string, mode auth.PermissionMode, perms []auth.KeyPermission) {
+		hash, err := auth.HashKey(plain)
+		if err != nil {
+			t.Fatalf("Failed to hash key: %v", err)
+		}
+		now := time.Now().Unix()
+		key := &auth.APIKey{KeyHash: hash, Name: plain, UserID: "system", PermissionMode: mode, CreatedAt: now, UpdatedAt: now}
+		if err := db.CreateKey(ctx, key, perms); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+	createKey("sk-per-instance", auth.PermissionModePerInstance, []auth.KeyPermission{{InstanceID: instanceID}})
+	createKey("sk-read-only", auth.PermissionModeReadOnly, nil)
+
+	router := server.SetupRouter(server.NewHandler(im, nil, cfg, db))
+
+	tests := []struct {
+		name             string
+		key              string
+		expectedStatus   int
+		expectedType     string
+		expectedAccess   string
+		expectedInstance []string
+	}{
+		{"per-instance key", "sk-per-instance", http.StatusOK, "api_key", "none", []string{"llama"}},
+		{"read-only key", "sk-read-only", http.StatusOK, "api_key", "read", []string{"llama", "mistral"}},
+		{"management key", "sk-management-admin", http.StatusOK, "management", "full", []string{"llama", "mistral"}},
+		{"invalid key", "sk-unknown", http.StatusUnauthorized, "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/auth/whoami", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.expectedStatus {
+				t.Fatalf("status = %v, expected %v: %s", recorder.Code, tt.expectedStatus, recorder.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response server.WhoAmIResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Type != tt.expectedType || response.ManagementAccess != tt.expectedAccess {
+				t.Errorf("Got type %q access %q, expected %q %q", response.Type, response.ManagementAccess, tt.expectedType, tt.expectedAccess)
+			}
+			var names []string
+			for _, inst := range response.Instances {
+				names = append(names, inst.InstanceName)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, tt.expectedInstance) {
+				t.Errorf("Got instances %v, expected %v", names, tt.expectedInstance)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"log"
+	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -9,9 +10,16 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	_ "llamactl/docs"
+	"llamactl/pkg/backends"
 	"llamactl/webui"
 )
 
+// corsAllowedMethods are the methods browsers may use cross-origin
+var corsAllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// corsMaxAge is how long browsers may cache a preflight response, in seconds
+const corsMaxAge = 300
+
 func SetupRouter(handler *Handler) *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
@@ -19,29 +27,47 @@ func SetupRouter(handler *Handler) *chi.Mux {
 	// Add CORS middleware
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   handler.cfg.Server.AllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   corsAllowedMethods,
 		AllowedHeaders:   handler.cfg.Server.AllowedHeaders,
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
-		MaxAge:           300,
+		MaxAge:           corsMaxAge,
 	}))
 
 	if handler.cfg.Server.EnableSwagger {
 		r.Get("/swagger/*", httpSwagger.Handler(
-			httpSwagger.URL("/swagger/doc.json"),
+			httpSwagger.URL(handler.cfg.Server.BasePath+"/swagger/doc.json"),
 		))
 	}
 
 	// Define routes
+
+	// Readiness probes come from load balancers and orchestrators without a key
+	r.Get("/readyz", handler.ReadyzHandler())
+
+	// Any key may ask who it is, so this sits outside the management auth below
+	if handler.authMiddleware != nil {
+		r.With(handler.authMiddleware.IdentifyMiddleware()).Get("/api/v1/auth/whoami", handler.WhoAmI())
+	}
+
 	r.Route("/api/v1", func(r chi.Router) {
 
 		if handler.authMiddleware != nil && handler.cfg.Auth.RequireManagementAuth {
 			r.Use(handler.authMiddleware.ManagementAuthMiddleware())
 		}
+		if handler.authStore != nil {
+			r.Use(handler.AuditMiddleware())
+			r.Get("/audit", handler.ListAudit())
+		}
 
 		r.Get("/version", handler.VersionHandler())
 
 		r.Get("/config", handler.ConfigHandler())
+		r.Get("/config/env-vars", handler.ConfigEnvVarsHandler())
+
+		r.Route("/system", func(r chi.Router) {
+			r.Get("/gpus", handler.ListGPUs())
+		})
 
 		// API key management endpoints
 		r.Route("/auth", func(r chi.Router) {
@@ -62,23 +88,33 @@ func SetupRouter(handler *Handler) *chi.Mux {
 			r.Route("/jobs", func(r chi.Router) {
 				r.Get("/", handler.ListJobs())
 				r.Get("/{id}", handler.GetJob())
+				r.Get("/{id}/stream", handler.StreamJob())
 				r.Delete("/{id}", handler.DeleteJob())
 			})
 		})
 
 		// Backend-specific endpoints
 		r.Route("/backends", func(r chi.Router) {
+			r.Get("/check", handler.CheckBackends())
+
 			r.Route("/llama-cpp", func(r chi.Router) {
 				r.Get("/help", handler.LlamaServerHelpHandler())
 				r.Get("/version", handler.LlamaServerVersionHandler())
 				r.Get("/devices", handler.LlamaServerListDevicesHandler())
 				r.Post("/parse-command", handler.ParseLlamaCommand())
+				r.Post("/build-command", handler.BuildBackendCommand(backends.BackendTypeLlamaCpp))
 			})
 			r.Route("/mlx", func(r chi.Router) {
 				r.Post("/parse-command", handler.ParseMlxCommand())
+				r.Post("/build-command", handler.BuildBackendCommand(backends.BackendTypeMlxLm))
 			})
 			r.Route("/vllm", func(r chi.Router) {
 				r.Post("/parse-command", handler.ParseVllmCommand())
+				r.Post("/build-command", handler.BuildBackendCommand(backends.BackendTypeVllm))
+			})
+			r.Route("/llama-rpc", func(r chi.Router) {
+				r.Post("/parse-command", handler.ParseLlamaRpcCommand())
+				r.Post("/build-command", handler.BuildBackendCommand(backends.BackendTypeLlamaRpc))
 			})
 		})
 
@@ -94,28 +130,47 @@ func SetupRouter(handler *Handler) *chi.Mux {
 			r.Get("/", handler.ListNodes()) // List all nodes
 
 			r.Route("/{name}", func(r chi.Router) {
-				r.Get("/", handler.GetNode()) // Get node details
+				r.Get("/", handler.GetNode())       // Get node details
+				r.Post("/test", handler.TestNode()) // Probe the node's address and API key
+
+				r.Post("/drain", handler.DrainNode())     // Stop placements, optionally migrate instances
+				r.Get("/drain", handler.GetNodeDrain())   // Drain progress
+				r.Delete("/drain", handler.UndrainNode()) // Accept placements again
 			})
 		})
 
 		// Instance management endpoints
 		r.Route("/instances", func(r chi.Router) {
-			r.Get("/", handler.ListInstances()) // List all instances
+			r.Get("/", handler.ListInstances())             // List all instances
+			r.Get("/by-id/{id}", handler.GetInstanceByID()) // Get instance details by ID
+
+			// Collection actions. Registered as full paths rather than a
+			// sub-router, so an instance named "actions" stays reachable, and
+			// kept under /actions so they don't shadow creating an instance.
+			// Bulk actions are confirmed with a token from the GET.
+			r.Get("/actions/stop-all", handler.StopAllPreview())
+			r.Post("/actions/stop-all", handler.StopAll())
+			r.Post("/actions/import", handler.ImportInstance()) // Create instance from an exported bundle
 
 			r.Route("/{name}", func(r chi.Router) {
 				// Instance management
-				r.Get("/", handler.GetInstance())             // Get instance details
-				r.Post("/", handler.CreateInstance())         // Create and start new instance
-				r.Put("/", handler.UpdateInstance())          // Update instance configuration
-				r.Delete("/", handler.DeleteInstance())       // Stop and remove instance
-				r.Post("/start", handler.StartInstance())     // Start stopped instance
-				r.Post("/stop", handler.StopInstance())       // Stop running instance
-				r.Post("/restart", handler.RestartInstance()) // Restart instance
-				r.Get("/logs", handler.GetInstanceLogs())     // Get instance logs
-
-				// Llama.cpp server proxy endpoints (proxied to the actual llama.cpp server)
+				r.Get("/", handler.GetInstance())                   // Get instance details
+				r.Post("/", handler.CreateInstance())               // Create and start new instance
+				r.Put("/", handler.UpdateInstance())                // Update instance configuration
+				r.Delete("/", handler.DeleteInstance())             // Stop and remove instance
+				r.Post("/start", handler.StartInstance())           // Start stopped instance
+				r.Post("/stop", handler.StopInstance())             // Stop running instance
+				r.Post("/restart", handler.RestartInstance())       // Restart instance
+				r.Get("/logs", handler.GetInstanceLogs())           // Get instance logs
+				r.Get("/stats", handler.GetInstanceStats())         // Get request statistics
+				r.Get("/metadata", handler.GetInstanceMetadata())   // Get backend model metadata
+				r.Get("/openapi", handler.GetInstanceOpenAPISpec()) // Get backend OpenAPI spec
+				r.Get("/export", handler.ExportInstance())          // Export portable instance bundle
+
+				// Backend proxy, any method and path (proxied to the backend server)
 				r.Route("/proxy", func(r chi.Router) {
-					r.HandleFunc("/*", handler.InstanceProxy()) // Proxy all llama.cpp server requests
+					r.HandleFunc("/", handler.InstanceProxy())  // Backend root
+					r.HandleFunc("/*", handler.InstanceProxy()) // Any other backend path
 				})
 			})
 		})
@@ -148,6 +203,9 @@ func SetupRouter(handler *Handler) *chi.Mux {
 		// Don't auto start the server since it can be accessed without an API key
 		r.Get("/", handler.LlamaCppUIProxy())
 
+		// Answer OPTIONS here instead of forwarding them unauthenticated
+		r.Options("/*", handler.ProxyPreflight())
+
 		// Private Routes
 		r.Group(func(r chi.Router) {
 
@@ -191,5 +249,24 @@ func SetupRouter(handler *Handler) *chi.Mux {
 		log.Printf("Failed to set up WebUI: %v\n", err)
 	}
 
-	return r
+	return mountBasePath(r, handler.cfg.Server.BasePath)
+}
+
+// mountBasePath serves r under basePath. The prefix is stripped before r sees
+// the request, so routes, proxy prefixes and the WebUI file server all work
+// with root-relative paths; only URLs handed back to clients need the prefix.
+func mountBasePath(r *chi.Mux, basePath string) *chi.Mux {
+	if basePath == "" {
+		return r
+	}
+
+	root := chi.NewRouter()
+	root.Mount(basePath, http.StripPrefix(basePath, r))
+
+	// The WebUI resolves its assets relative to the page, so the bare prefix
+	// has to end in a slash
+	root.Get(basePath, func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, basePath+"/", http.StatusMovedPermanently)
+	})
+	return root
 }
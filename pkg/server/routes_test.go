@@ -0,0 +1,71 @@
+package server_test
+
+import (
+	"io"
+	"llamactl/pkg/config"
+	"llamactl/pkg/server"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetupRouter_BasePath(t *testing.T) {
+	cfg := config.AppConfig{
+		Server: config.ServerConfig{BasePath: "/llamactl"},
+	}
+	router := newProxyTestRouter(t, cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Method+" "+r.URL.EscapedPath())
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"management API under base path", http.MethodGet, "/llamactl/api/v1/instances/llama", http.StatusOK, ""},
+		{"management API at root", http.MethodGet, "/api/v1/instances/llama", http.StatusNotFound, ""},
+		{"proxy strips base path and prefix", http.MethodPost, "/llamactl/api/v1/instances/llama/proxy/v1/chat/completions", http.StatusOK, "POST /v1/chat/completions"},
+		{"proxy backend root", http.MethodGet, "/llamactl/api/v1/instances/llama/proxy", http.StatusOK, "GET /"},
+		{"llama-cpp proxy strips base path", http.MethodGet, "/llamactl/llama-cpp/llama/props", http.StatusOK, "GET /props"},
+		{"bare base path redirects", http.MethodGet, "/llamactl", http.StatusMovedPermanently, ""},
+		{"sibling path is not matched", http.MethodGet, "/llamactl2/api/v1/instances/llama", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("Expected %d, got %d: %s", tt.wantCode, w.Code, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("Expected backend to see %q, got %q", tt.wantBody, w.Body.String())
+			}
+			if tt.wantCode == http.StatusMovedPermanently {
+				if got := w.Header().Get("Location"); got != "/llamactl/" {
+					t.Errorf("Expected redirect to /llamactl/, got %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestSetupRouter_AuditRequiresStore(t *testing.T) {
+	router := server.SetupRouter(server.NewHandler(nil, nil, config.AppConfig{}, nil))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for the audit log without a store, got %d", w.Code)
+	}
+
+	handler := newProxyTestHandler(t, config.AppConfig{}, http.NotFoundHandler())
+	w = httptest.NewRecorder()
+	server.SetupRouter(handler).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 for the audit log with a store, got %d: %s", w.Code, w.Body.String())
+	}
+}
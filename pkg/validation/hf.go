@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// hfNamePattern matches a Hugging Face namespace or repository name
+var hfNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// hfTagPattern matches a tag such as a quantization (Q4_K_M) or branch name
+var hfTagPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// hfRevisionPattern matches a branch, tag or commit, including refs like refs/pr/1
+var hfRevisionPattern = regexp.MustCompile(`^[a-zA-Z0-9._/-]+$`)
+
+// hfURLPrefixes are stripped from references copied from the browser
+var hfURLPrefixes = []string{"https://huggingface.co/", "http://huggingface.co/", "huggingface.co/", "hf.co/"}
+
+// HFRef is a parsed Hugging Face model reference of the form
+// org/model[:tag][@revision]
+type HFRef struct {
+	Repo     string // org/model
+	Tag      string // e.g. a quantization such as Q4_K_M
+	Revision string // branch, tag or commit
+}
+
+// String returns the reference in its normalized form
+func (r HFRef) String() string {
+	s := r.Repo
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Revision != "" {
+		s += "@" + r.Revision
+	}
+	return s
+}
+
+// ParseHFRef validates and normalizes a Hugging Face model reference.
+// Surrounding whitespace and a huggingface.co URL prefix are removed.
+func ParseHFRef(ref string) (HFRef, error) {
+	s := strings.TrimSpace(ref)
+	for _, prefix := range hfURLPrefixes {
+		if after, ok := strings.CutPrefix(s, prefix); ok {
+			s = after
+			break
+		}
+	}
+	s = strings.TrimSuffix(s, "/")
+
+	if s == "" {
+		return HFRef{}, ValidationError(fmt.Errorf("hugging face reference cannot be empty"))
+	}
+
+	var r HFRef
+	s, r.Revision, _ = strings.Cut(s, "@")
+	r.Repo, r.Tag, _ = strings.Cut(s, ":")
+
+	org, name, ok := strings.Cut(r.Repo, "/")
+	if !ok {
+		return HFRef{}, ValidationError(fmt.Errorf("invalid hugging face reference %q: expected org/model[:tag][@revision]", ref))
+	}
+	for _, part := range []string{org, name} {
+		if !hfNamePattern.MatchString(part) || strings.Contains(part, "--") || strings.Contains(part, "..") {
+			return HFRef{}, ValidationError(fmt.Errorf("invalid hugging face reference %q: %q is not a valid organization or model name", ref, part))
+		}
+	}
+	if len(r.Repo) > 96 {
+		return HFRef{}, ValidationError(fmt.Errorf("invalid hugging face reference %q: repository name too long (max 96 characters)", ref))
+	}
+
+	if strings.Contains(s, ":") && !hfTagPattern.MatchString(r.Tag) {
+		return HFRef{}, ValidationError(fmt.Errorf("invalid hugging face reference %q: invalid tag %q", ref, r.Tag))
+	}
+	if strings.Contains(ref, "@") && (!hfRevisionPattern.MatchString(r.Revision) || strings.Contains(r.Revision, "..")) {
+		return HFRef{}, ValidationError(fmt.Errorf("invalid hugging face reference %q: invalid revision %q", ref, r.Revision))
+	}
+
+	return r, nil
+}
@@ -0,0 +1,70 @@
+package validation_test
+
+import (
+	"llamactl/pkg/validation"
+	"strings"
+	"testing"
+)
+
+func TestParseHFRef(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    validation.HFRef
+		wantErr bool
+	}{
+		{"repo only", "Qwen/Qwen3-8B-GGUF", validation.HFRef{Repo: "Qwen/Qwen3-8B-GGUF"}, false},
+		{"with tag", "unsloth/gemma-3-4b-it-GGUF:Q4_K_M", validation.HFRef{Repo: "unsloth/gemma-3-4b-it-GGUF", Tag: "Q4_K_M"}, false},
+		{"with revision", "org/model@main", validation.HFRef{Repo: "org/model", Revision: "main"}, false},
+		{"with tag and revision", "org/model:Q8_0@0123abcd", validation.HFRef{Repo: "org/model", Tag: "Q8_0", Revision: "0123abcd"}, false},
+		{"pull request revision", "org/model@refs/pr/1", validation.HFRef{Repo: "org/model", Revision: "refs/pr/1"}, false},
+		{"dots and underscores", "my.org/model_v1.5", validation.HFRef{Repo: "my.org/model_v1.5"}, false},
+		{"surrounding whitespace", "  org/model:Q4_0\n", validation.HFRef{Repo: "org/model", Tag: "Q4_0"}, false},
+		{"browser url", "https://huggingface.co/org/model", validation.HFRef{Repo: "org/model"}, false},
+		{"short url with tag", "hf.co/org/model:Q4_K_M", validation.HFRef{Repo: "org/model", Tag: "Q4_K_M"}, false},
+		{"trailing slash", "org/model/", validation.HFRef{Repo: "org/model"}, false},
+
+		{"empty", "", validation.HFRef{}, true},
+		{"whitespace only", "   ", validation.HFRef{}, true},
+		{"missing org", "model", validation.HFRef{}, true},
+		{"empty org", "/model", validation.HFRef{}, true},
+		{"empty model", "org/", validation.HFRef{}, true},
+		{"too many segments", "org/model/file.gguf", validation.HFRef{}, true},
+		{"space in name", "org/my model", validation.HFRef{}, true},
+		{"leading dash", "org/-model", validation.HFRef{}, true},
+		{"double dash", "org/my--model", validation.HFRef{}, true},
+		{"double dot", "org/my..model", validation.HFRef{}, true},
+		{"shell metachar", "org/model;ls", validation.HFRef{}, true},
+		{"empty tag", "org/model:", validation.HFRef{}, true},
+		{"invalid tag", "org/model:Q4 K", validation.HFRef{}, true},
+		{"double colon", "org/model:a:b", validation.HFRef{}, true},
+		{"empty revision", "org/model@", validation.HFRef{}, true},
+		{"revision traversal", "org/model@../main", validation.HFRef{}, true},
+		{"double at", "org/model@a@b", validation.HFRef{}, true},
+		{"too long", "org/" + strings.Repeat("a", 96), validation.HFRef{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validation.ParseHFRef(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHFRef(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHFRef(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHFRef_String(t *testing.T) {
+	for _, input := range []string{"org/model", "org/model:Q4_K_M", "org/model@main", "org/model:Q4_K_M@v1.0"} {
+		ref, err := validation.ParseHFRef(input)
+		if err != nil {
+			t.Fatalf("ParseHFRef(%q) failed: %v", input, err)
+		}
+		if ref.String() != input {
+			t.Errorf("String() = %q, want %q", ref.String(), input)
+		}
+	}
+}
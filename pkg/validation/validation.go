@@ -2,11 +2,16 @@ package validation
 
 import (
 	"fmt"
+	"net"
 	"regexp"
+	"strings"
 )
 
 var validNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
 
+// hostnameLabelPattern matches a single RFC 1123 hostname label
+var hostnameLabelPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
 type ValidationError error
 
 func ValidateInstanceName(name string) (string, error) {
@@ -22,3 +27,21 @@ func ValidateInstanceName(name string) (string, error) {
 	}
 	return name, nil
 }
+
+// ValidateHost checks that host is an IP address or a valid hostname.
+// An empty host is allowed and leaves the choice to the backend.
+func ValidateHost(host string) error {
+	if host == "" || net.ParseIP(host) != nil {
+		return nil
+	}
+
+	if len(host) > 253 {
+		return ValidationError(fmt.Errorf("invalid host %q: too long (max 253 characters)", host))
+	}
+	for label := range strings.SplitSeq(strings.TrimSuffix(host, "."), ".") {
+		if !hostnameLabelPattern.MatchString(label) {
+			return ValidationError(fmt.Errorf("invalid host %q: must be an IP address or hostname", host))
+		}
+	}
+	return nil
+}
@@ -87,3 +87,86 @@ func TestValidateInstanceOptions_PortValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateInstanceOptions_HFRepo(t *testing.T) {
+	tests := []struct {
+		name    string
+		options backends.LlamaServerOptions
+		wantErr bool
+	}{
+		{"repo with quant", backends.LlamaServerOptions{HFRepo: "org/model:Q4_K_M"}, false},
+		{"draft repo", backends.LlamaServerOptions{HFRepoDraft: "org/draft-model"}, false},
+		{"missing org", backends.LlamaServerOptions{HFRepo: "model"}, true},
+		{"revision not supported", backends.LlamaServerOptions{HFRepo: "org/model@main"}, true},
+		{"invalid vocoder repo", backends.LlamaServerOptions{HFRepoV: "org/bad model"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := backends.Options{
+				BackendType:        backends.BackendTypeLlamaCpp,
+				LlamaServerOptions: &tt.options,
+			}
+
+			err := options.ValidateInstanceOptions()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateInstanceOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateInstanceOptions_NormalizesHFRepo(t *testing.T) {
+	llama := &backends.LlamaServerOptions{
+		HFRepo:      " https://huggingface.co/org/model:Q4_K_M ",
+		HFRepoDraft: "https://huggingface.co/org/draft-model/",
+	}
+	options := backends.Options{
+		BackendType:        backends.BackendTypeLlamaCpp,
+		LlamaServerOptions: llama,
+	}
+
+	if err := options.ValidateInstanceOptions(); err != nil {
+		t.Fatalf("ValidateInstanceOptions() error = %v", err)
+	}
+	if llama.HFRepo != "org/model:Q4_K_M" {
+		t.Errorf("expected hf_repo to be normalized, got %q", llama.HFRepo)
+	}
+	if llama.HFRepoDraft != "org/draft-model" {
+		t.Errorf("expected hf_repo_draft to be normalized, got %q", llama.HFRepoDraft)
+	}
+}
+
+func TestValidateHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		wantErr bool
+	}{
+		{"empty uses backend default", "", false},
+		{"IPv4 address", "192.168.1.10", false},
+		{"wildcard IPv4", "0.0.0.0", false},
+		{"IPv6 address", "fe80::1", false},
+		{"hostname", "localhost", false},
+		{"fully qualified hostname", "gpu-node-1.internal.example.com", false},
+		{"trailing dot", "example.com.", false},
+
+		{"bracketed IPv6", "[::1]", true},
+		{"with port", "localhost:8080", true},
+		{"with scheme", "http://localhost", true},
+		{"label starts with hyphen", "-bad.example.com", true},
+		{"empty label", "bad..example.com", true},
+		{"shell metachar", "host;ls", true},
+		{"with spaces", "my host", true},
+		{"label too long", strings.Repeat("a", 64) + ".com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validation.ValidateHost(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateHost(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -24,6 +24,7 @@ function CreateApiKeyDialog({ open, onOpenChange, onKeyCreated }: CreateApiKeyDi
   const [permissionMode, setPermissionMode] = useState<PermissionMode>(PermissionMode.AllowAll);
   const [expiresAt, setExpiresAt] = useState<string>("");
   const [instancePermissions, setInstancePermissions] = useState<Record<number, boolean>>({});
+  const [allowedPaths, setAllowedPaths] = useState("");
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
 
@@ -76,6 +77,17 @@ function CreateApiKeyDialog({ open, onOpenChange, onKeyCreated }: CreateApiKeyDi
       instance_ids: instanceIds,
     };
 
+    if (permissionMode === PermissionMode.PerInstance) {
+      const paths = allowedPaths.split(",").map(p => p.trim()).filter(Boolean);
+      if (paths.some(p => !p.startsWith("/"))) {
+        setError("Allowed paths must start with /");
+        return;
+      }
+      if (paths.length > 0) {
+        request.allowed_paths = paths;
+      }
+    }
+
     // Add expiration if provided
     if (expiresAt) {
       const expirationDate = new Date(expiresAt);
@@ -96,6 +108,7 @@ function CreateApiKeyDialog({ open, onOpenChange, onKeyCreated }: CreateApiKeyDi
       setPermissionMode(PermissionMode.AllowAll);
       setExpiresAt("");
       setInstancePermissions({});
+      setAllowedPaths("");
     } catch (err) {
       setError(err instanceof Error ? err.message : "Failed to create API key");
     } finally {
@@ -154,6 +167,12 @@ function CreateApiKeyDialog({ open, onOpenChange, onKeyCreated }: CreateApiKeyDi
                   Per-Instance Access
                 </Label>
               </div>
+              <div className="flex items-center space-x-2">
+                <RadioGroupItem value={PermissionMode.ReadOnly} id="read-only" />
+                <Label htmlFor="read-only" className="font-normal cursor-pointer">
+                  Read-Only
+                </Label>
+              </div>
             </RadioGroup>
 
             {permissionMode === PermissionMode.AllowAll && (
@@ -162,6 +181,12 @@ function CreateApiKeyDialog({ open, onOpenChange, onKeyCreated }: CreateApiKeyDi
               </p>
             )}
 
+            {permissionMode === PermissionMode.ReadOnly && (
+              <p className="text-sm text-muted-foreground">
+                This key can run inference on all instances and view the management API, but cannot change anything
+              </p>
+            )}
+
             {permissionMode === PermissionMode.PerInstance && (
               <div className="space-y-2 border rounded-lg p-4">
                 <Label className="text-sm font-semibold">Instance Permissions</Label>
@@ -195,6 +220,21 @@ function CreateApiKeyDialog({ open, onOpenChange, onKeyCreated }: CreateApiKeyDi
                     })}
                   </div>
                 )}
+                <div className="space-y-2 pt-2">
+                  <Label htmlFor="allowed-paths" className="text-sm font-semibold">
+                    Allowed Paths (Optional)
+                  </Label>
+                  <Input
+                    id="allowed-paths"
+                    value={allowedPaths}
+                    onChange={(e) => setAllowedPaths(e.target.value)}
+                    placeholder="/v1/embeddings, /v1/models/**"
+                    disabled={loading}
+                  />
+                  <p className="text-sm text-muted-foreground">
+                    Comma-separated backend paths this key may call. Leave empty to allow all paths.
+                  </p>
+                </div>
               </div>
             )}
           </div>
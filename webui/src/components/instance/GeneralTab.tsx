@@ -128,6 +128,14 @@ const GeneralTab: React.FC<GeneralTabProps> = ({
           description="Minutes before stopping an idle instance"
         />
 
+        <CheckboxInput
+          id="pinned"
+          label="Pinned"
+          value={formData.pinned}
+          onChange={(value) => onChange("pinned", value)}
+          description="Never stop automatically on idle timeout or LRU eviction"
+        />
+
         <CheckboxInput
           id="on_demand_start"
           label="On Demand Start"
@@ -135,6 +143,14 @@ const GeneralTab: React.FC<GeneralTabProps> = ({
           onChange={(value) => onChange("on_demand_start", value)}
           description="Start instance only when needed"
         />
+
+        <CheckboxInput
+          id="warmup_on_start"
+          label="Warmup On Start"
+          value={formData.warmup_on_start}
+          onChange={(value) => onChange("warmup_on_start", value)}
+          description="Send a one-token completion once the instance is healthy"
+        />
       </div>
 
       <AutoRestartConfiguration formData={formData} onChange={onChange} />
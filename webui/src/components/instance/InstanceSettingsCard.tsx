@@ -123,6 +123,14 @@ const InstanceSettingsCard: React.FC<InstanceSettingsCardProps> = ({
             description="Minutes before stopping an idle instance"
           />
 
+          <CheckboxInput
+            id="pinned"
+            label="Pinned"
+            value={formData.pinned}
+            onChange={(value) => onChange('pinned', value)}
+            description="Never stop automatically on idle timeout or LRU eviction"
+          />
+
           <CheckboxInput
             id="on_demand_start"
             label="On Demand Start"
@@ -130,6 +138,14 @@ const InstanceSettingsCard: React.FC<InstanceSettingsCardProps> = ({
             onChange={(value) => onChange('on_demand_start', value)}
             description="Start instance only when needed"
           />
+
+          <CheckboxInput
+            id="warmup_on_start"
+            label="Warmup On Start"
+            value={formData.warmup_on_start}
+            onChange={(value) => onChange('warmup_on_start', value)}
+            description="Send a one-token completion once the instance is healthy"
+          />
         </div>
       </CardContent>
     </Card>
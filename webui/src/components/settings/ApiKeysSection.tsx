@@ -193,6 +193,8 @@ function ApiKeysSection() {
                     <td className="p-3">
                       {key.permission_mode === PermissionMode.AllowAll ? (
                         <Badge variant="default">Full Access</Badge>
+                      ) : key.permission_mode === PermissionMode.ReadOnly ? (
+                        <Badge variant="outline">Read-Only</Badge>
                       ) : (
                         <Badge variant="secondary">Limited Access</Badge>
                       )}
@@ -231,6 +233,10 @@ function ApiKeysSection() {
                           <p className="text-sm text-muted-foreground">
                             This key has full access to all instances
                           </p>
+                        ) : key.permission_mode === PermissionMode.ReadOnly ? (
+                          <p className="text-sm text-muted-foreground">
+                            This key can run inference on all instances and has read-only access to the management API
+                          </p>
                         ) : loadingPermissions[key.id] ? (
                           <p className="text-sm text-muted-foreground">Loading permissions...</p>
                         ) : permissions[key.id] ? (
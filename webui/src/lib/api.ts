@@ -1,6 +1,6 @@
 import type { CreateInstanceOptions, Instance } from "@/types/instance";
 import type { AppConfig } from "@/types/config";
-import type { ApiKey, CreateKeyRequest, CreateKeyResponse, KeyPermissionResponse } from "@/types/apiKey";
+import type { ApiKey, CreateKeyRequest, CreateKeyResponse, KeyPermissionResponse, WhoAmIResponse } from "@/types/apiKey";
 import type { DownloadJob, CachedModel, ModelFormat } from "@/types/model";
 import { handleApiError } from "./errorUtils";
 
@@ -81,6 +81,19 @@ export const serverApi = {
   getConfig: () => apiCall<AppConfig>("/config"),
 };
 
+// Backend command builder types
+export interface BuildCommandRequest {
+  backend_options: Record<string, unknown>;
+  docker_enabled?: boolean;
+  command_override?: string;
+}
+
+export interface BuildCommandResponse {
+  command: string;
+  args: string[];
+  command_line: string;
+}
+
 // Backend API functions
 export const backendsApi = {
   llamaCpp: {
@@ -90,6 +103,12 @@ export const backendsApi = {
         method: 'POST',
         body: JSON.stringify({ command }),
       }),
+    // POST /backends/llama-cpp/build-command
+    buildCommand: (options: BuildCommandRequest) =>
+      apiCall<BuildCommandResponse>('/backends/llama-cpp/build-command', {
+        method: 'POST',
+        body: JSON.stringify(options),
+      }),
   },
   mlx: {
     // POST /backends/mlx/parse-command
@@ -98,6 +117,12 @@ export const backendsApi = {
         method: 'POST',
         body: JSON.stringify({ command }),
       }),
+    // POST /backends/mlx/build-command
+    buildCommand: (options: BuildCommandRequest) =>
+      apiCall<BuildCommandResponse>('/backends/mlx/build-command', {
+        method: 'POST',
+        body: JSON.stringify(options),
+      }),
   },
   vllm: {
     // POST /backends/vllm/parse-command
@@ -106,6 +131,12 @@ export const backendsApi = {
         method: 'POST',
         body: JSON.stringify({ command }),
       }),
+    // POST /backends/vllm/build-command
+    buildCommand: (options: BuildCommandRequest) =>
+      apiCall<BuildCommandResponse>('/backends/vllm/build-command', {
+        method: 'POST',
+        body: JSON.stringify(options),
+      }),
   },
 };
 
@@ -116,6 +147,16 @@ export interface NodeResponse {
 
 export type NodesMap = Record<string, NodeResponse>;
 
+export interface NodeProbeResult {
+  node: string;
+  local?: boolean;
+  reachable: boolean;
+  authenticated: boolean;
+  latency_ms: number;
+  version?: string;
+  error?: string;
+}
+
 // Node API functions
 export const nodesApi = {
   // GET /nodes - returns map of node name to NodeResponse
@@ -123,6 +164,12 @@ export const nodesApi = {
 
   // GET /nodes/{name}
   get: (name: string) => apiCall<NodeResponse>(`/nodes/${encodeURIComponent(name)}`),
+
+  // POST /nodes/{name}/test
+  test: (name: string) =>
+    apiCall<NodeProbeResult>(`/nodes/${encodeURIComponent(name)}/test`, {
+      method: "POST",
+    }),
 };
 
 // Instance API functions
@@ -133,6 +180,9 @@ export const instancesApi = {
   // GET /instances/{name}
   get: (name: string) => apiCall<Instance>(`/instances/${encodeURIComponent(name)}`),
 
+  // GET /instances/by-id/{id}
+  getById: (id: number) => apiCall<Instance>(`/instances/by-id/${id}`),
+
   // POST /instances/{name}
   create: (name: string, options: CreateInstanceOptions) =>
     apiCall<Instance>(`/instances/${encodeURIComponent(name)}`, {
@@ -205,6 +255,9 @@ export const apiKeysApi = {
   // GET /auth/keys/{id}/permissions
   getPermissions: (id: number) =>
     apiCall<KeyPermissionResponse[]>(`/auth/keys/${id}/permissions`),
+
+  // GET /auth/whoami
+  whoami: () => apiCall<WhoAmIResponse>("/auth/whoami"),
 };
 
 // Llama.cpp model management types
@@ -1,4 +1,5 @@
 // Re-export all backend schemas from one place
 export * from './llamacpp'
+export * from './llamarpc'
 export * from './mlx'
 export * from './vllm'
\ No newline at end of file
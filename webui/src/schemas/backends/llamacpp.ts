@@ -58,6 +58,7 @@ export const LlamaCppBackendOptionsSchema = z.object({
   split_mode: z.string().optional(), // -sm, --split-mode {none,layer,row}
   tensor_split: z.string().optional(), // -ts, --tensor-split N0,N1,N2,...
   main_gpu: z.number().optional(), // -mg, --main-gpu INDEX
+  rpc: z.array(z.string()).optional(), // --rpc SERVERS
   fit: z.string().optional(), // -fit, --fit [on|off]
   fit_target: z.string().optional(), // -fitt, --fit-target MiB0,MiB1,MiB2,...
   fit_ctx: z.number().optional(), // -fitc, --fit-ctx N
@@ -218,6 +219,9 @@ export const LlamaCppBackendOptionsSchema = z.object({
   tts_use_guide_tokens: z.boolean().optional(),
 
   // Default model params (ordered as in llama-cpp.md)
+  embd_bge_small_en_default: z.boolean().optional(), // --embd-bge-small-en-default
+  embd_e5_small_en_default: z.boolean().optional(), // --embd-e5-small-en-default
+  embd_gte_small_default: z.boolean().optional(), // --embd-gte-small-default
   embd_gemma_default: z.boolean().optional(), // --embd-gemma-default
   fim_qwen_1b: z.boolean().optional(), // --fim-qwen-1
   fim_qwen_1_5b_default: z.boolean().optional(), // --fim-qwen-1.5b-default
@@ -0,0 +1,16 @@
+import { z } from 'zod'
+
+// Define the llama.cpp rpc-server worker options schema
+export const LlamaRpcBackendOptionsSchema = z.object({
+  host: z.string().optional(),     // -H, --host
+  port: z.number().optional(),     // -p, --port
+  device: z.string().optional(),   // -d, --device
+  threads: z.number().optional(),  // -t, --threads
+  cache: z.boolean().optional(),   // -c, --cache
+
+  // Extra args
+  extra_args: z.record(z.string(), z.string()).optional(),
+})
+
+// Infer the TypeScript type from the schema
+export type LlamaRpcBackendOptions = z.infer<typeof LlamaRpcBackendOptionsSchema>
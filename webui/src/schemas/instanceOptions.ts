@@ -13,6 +13,7 @@ import {
   type MlxBackendOptions,
   getAllMlxFieldKeys,
   getMlxFieldType,
+  LlamaRpcBackendOptionsSchema,
   VllmBackendOptionsSchema,
   type VllmBackendOptions,
   getAllVllmFieldKeys,
@@ -24,6 +25,7 @@ export const BackendOptionsSchema = z.union([
   LlamaCppBackendOptionsSchema,
   MlxBackendOptionsSchema,
   VllmBackendOptionsSchema,
+  LlamaRpcBackendOptionsSchema,
 ])
 
 // Define the main create instance options schema
@@ -32,18 +34,49 @@ export const CreateInstanceOptionsSchema = z.object({
   auto_restart: z.boolean().optional(),
   max_restarts: z.number().optional(),
   restart_delay: z.number().optional(),
+  restart_on_exit_codes: z.array(z.number()).optional(),
+  no_restart_on_exit_codes: z.array(z.number()).optional(),
   idle_timeout: z.number().optional(),
+  pinned: z.boolean().optional(),
   on_demand_start: z.boolean().optional(),
+  warmup_on_start: z.boolean().optional(),
+  max_concurrent_requests: z.number().optional(),
+  queue_timeout: z.number().optional(),
+  max_request_duration: z.number().optional(),
+  compress_responses: z.boolean().optional(),
+  vram_mb: z.number().optional(),
+  start_priority: z.number().optional(),
+  mirror_logs_to_stdout: z.boolean().optional(),
+  debug_log_bodies: z.boolean().optional(),
+  log_level: z.enum(['info', 'warn', 'error', 'off']).optional(),
+
+  // Operator annotations
+  description: z.string().optional(),
+  labels: z.record(z.string(), z.string()).optional(),
 
   // Environment variables
   environment: z.record(z.string(), z.string()).optional(),
 
+  // Models served by a llama.cpp router instance
+  router_models: z.array(z.object({
+    name: z.string(),
+    model: z.string().optional(),
+    hf_repo: z.string().optional(),
+    args: z.record(z.string(), z.string()).optional(),
+  })).optional(),
+
+  // OS-level process placement (Linux only)
+  cpu_affinity: z.string().optional(),
+  nice: z.number().optional(),
+
   // Execution context overrides
   docker_enabled: z.boolean().optional(),
   command_override: z.string().optional(),
+  working_dir: z.string().optional(),
+  stdin_data: z.string().optional(),
 
   // Backend configuration
-  backend_type: z.enum([BackendType.LLAMA_CPP, BackendType.MLX_LM, BackendType.VLLM]).optional(),
+  backend_type: z.enum([BackendType.LLAMA_CPP, BackendType.MLX_LM, BackendType.VLLM, BackendType.LLAMA_RPC]).optional(),
   backend_options: BackendOptionsSchema.optional(),
 
   // Node configuration
@@ -52,8 +85,23 @@ export const CreateInstanceOptionsSchema = z.object({
   // Instance group for hierarchical eviction
   group: z.string().optional(),
 
+  // Instances that must be healthy before this one starts
+  depends_on: z.array(z.string()).optional(),
+
+  // llama.cpp instance providing the draft model for speculative decoding
+  draft_instance: z.string().optional(),
+
+  // Cron expressions for starting and stopping the instance automatically
+  schedule: z.object({
+    start_cron: z.string().optional(),
+    stop_cron: z.string().optional(),
+  }).optional(),
+
   // Preset configuration
   preset_ini: z.string().optional(),
+
+  // Name of a template from the server's chat_templates library
+  chat_template_ref: z.string().optional(),
 })
 
 // Re-export types and schemas from backend files
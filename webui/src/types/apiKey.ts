@@ -1,6 +1,7 @@
 export enum PermissionMode {
   AllowAll = "allow_all",
-  PerInstance = "per_instance"
+  PerInstance = "per_instance",
+  ReadOnly = "read_only"
 }
 
 export interface ApiKey {
@@ -19,6 +20,7 @@ export interface CreateKeyRequest {
   permission_mode: PermissionMode
   expires_at?: number
   instance_ids: number[]
+  allowed_paths?: string[] // Backend path patterns; per_instance keys only
 }
 
 export interface CreateKeyResponse extends ApiKey {
@@ -28,4 +30,16 @@ export interface CreateKeyResponse extends ApiKey {
 export interface KeyPermissionResponse {
   instance_id: number
   instance_name: string
+  allowed_paths?: string[]
+}
+
+export interface WhoAmIResponse {
+  type: "management" | "api_key" | "anonymous"
+  id?: number
+  name?: string
+  permission_mode?: PermissionMode
+  expires_at?: number
+  management_access: "full" | "read" | "none"
+  all_instances: boolean
+  instances: KeyPermissionResponse[]
 }
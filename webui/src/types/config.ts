@@ -2,30 +2,39 @@ export interface BackendSettings {
   command: string
   args: string[]
   environment?: Record<string, string>
+  stop_signal?: 'SIGINT' | 'SIGTERM'
+  invocation?: 'serve' | 'module' // vLLM only
   docker?: DockerSettings
   response_headers?: Record<string, string>
 }
 
 export interface DockerSettings {
   enabled: boolean
+  runtime?: string
   image: string
   args: string[]
   environment?: Record<string, string>
+  gpus?: string
+  volumes?: string[]
+  auto_pull?: boolean
 }
 
 export interface BackendConfig {
   'llama-cpp': BackendSettings
   vllm: BackendSettings
+  'llama-rpc'?: BackendSettings
   mlx: BackendSettings
 }
 
 export interface ServerConfig {
   host: string
   port: number
+  base_path: string
   allowed_origins: string[]
   allowed_headers: string[]
   enable_swagger: boolean
   response_headers?: Record<string, string>
+  shutdown_drain_period: number // nanoseconds
 }
 
 export interface InstancesConfig {
@@ -39,7 +48,10 @@ export interface InstancesConfig {
   default_auto_restart: boolean
   default_max_restarts: number
   default_restart_delay: number
+  max_restarts_per_minute?: number
   default_on_demand_start: boolean
+  default_ctx_size?: number
+  max_ctx_size?: number
   on_demand_start_timeout: number
   timeout_check_interval: number
 }
@@ -51,15 +63,37 @@ export interface DatabaseConfig {
   connection_max_lifetime: number
 }
 
+export interface ProxyConfig {
+  connect_timeout: number // Durations are in nanoseconds
+  response_header_timeout: number
+  idle_conn_timeout: number
+  stream_timeout: number
+  unavailable_retry_after: number
+  max_idle_conns_per_host: number
+  disable_keep_alives: boolean
+}
+
 export interface AuthConfig {
   require_inference_auth: boolean
   require_management_auth: boolean
-  management_keys: string[] // Will be empty in sanitized response
+  management_keys: string[] // Each key is "[REDACTED]" in sanitized response
+  keys_file?: string
+}
+
+export interface LoggingConfig {
+  file_template: string
+  mirror_to_stdout: boolean
+  max_line_length: number
+}
+
+export interface NotificationsConfig {
+  webhook_url?: string // Redacted in sanitized response
+  format?: 'raw' | 'slack' | 'discord'
 }
 
 export interface NodeConfig {
   address: string
-  api_key: string // Will be empty in sanitized response
+  api_key: string // "[REDACTED]" in sanitized response when set
 }
 
 export interface AppConfig {
@@ -67,9 +101,14 @@ export interface AppConfig {
   backends: BackendConfig
   instances: InstancesConfig
   database: DatabaseConfig
+  proxy?: ProxyConfig
   auth: AuthConfig
+  notifications?: NotificationsConfig
+  logging?: LoggingConfig
   local_node: string
   nodes: Record<string, NodeConfig>
+  chat_templates?: Record<string, string>
+  model_aliases?: Record<string, string>
   data_dir: string
   version?: string
   commit_hash?: string
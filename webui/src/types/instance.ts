@@ -6,6 +6,7 @@ export const BackendType = {
   LLAMA_CPP: 'llama_cpp',
   MLX_LM: 'mlx_lm',
   VLLM: 'vllm',
+  LLAMA_RPC: 'llama_rpc',
   // MLX_VLM: 'mlx_vlm',  // Future expansion
 } as const
 
@@ -27,5 +28,22 @@ export interface Instance {
   id: number;
   name: string;
   status: InstanceStatus;
+  port?: number;
+  desired_state?: 'running' | 'stopped';
+  last_started_at?: number; // Unix seconds
+  last_stopped_at?: number;
+  status_changed_at?: number;
+  uptime_seconds?: number; // Only while running
   options?: CreateInstanceOptions;
-}
\ No newline at end of file
+  changes?: string[]; // Set on update responses
+}
+export interface InstanceMetadata {
+  backend_type: BackendTypeValue;
+  model?: string;
+  context_size?: number;
+  n_ctx?: number;
+  total_slots?: number;
+  chat_template?: string;
+  modalities?: Record<string, boolean>;
+  build_info?: string;
+}